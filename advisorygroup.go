@@ -0,0 +1,127 @@
+package claircore
+
+import (
+	"sort"
+)
+
+// AdvisoryGroup is an advisory and every package in a VulnerabilityReport it
+// affects.
+//
+// An advisory is identified by its updater and name: the same name from
+// different updaters, like a CVE reported by two distributions, is a
+// different advisory.
+type AdvisoryGroup struct {
+	// Updater is the updater that reported the advisory.
+	Updater string `json:"updater"`
+	// Name is the advisory's name, like a CVE or vendor advisory ID.
+	Name string `json:"name"`
+	// NormalizedSeverity is the worst severity of the advisory's
+	// vulnerabilities in the report.
+	NormalizedSeverity Severity `json:"normalized_severity"`
+	// Severity is the vendor's severity of the vulnerability
+	// NormalizedSeverity came from.
+	Severity string `json:"severity"`
+	// VulnerabilityIDs are the advisory's vulnerabilities, as keys in the
+	// report's Vulnerabilities, sorted.
+	VulnerabilityIDs []string `json:"vulnerability_ids"`
+	// Packages are the affected packages, sorted by package ID.
+	Packages []AdvisoryPackage `json:"packages"`
+}
+
+// AdvisoryPackage is a package affected by an AdvisoryGroup.
+type AdvisoryPackage struct {
+	// PackageID is the package's key in the report's Packages.
+	PackageID string `json:"package_id"`
+	// VulnerabilityIDs are the advisory's vulnerabilities that affect the
+	// package, sorted.
+	VulnerabilityIDs []string `json:"vulnerability_ids"`
+}
+
+// GroupByAdvisory returns the findings in the report grouped by advisory,
+// sorted by updater and then name.
+//
+// Only the findings in the report's PackageVulnerabilities are grouped:
+// suppressed and indeterminate findings aren't. References to
+// vulnerabilities missing from the report are ignored, and a vulnerability
+// listed more than once for a package is counted once. The report isn't
+// modified.
+func GroupByAdvisory(r *VulnerabilityReport) []AdvisoryGroup {
+	if r == nil {
+		return nil
+	}
+	type key struct{ updater, name string }
+	type group struct {
+		worst   *Vulnerability
+		worstID string
+		vulns   map[string]struct{}
+		pkgs    map[string]map[string]struct{}
+	}
+	groups := make(map[key]*group)
+	for pkgID, ids := range r.PackageVulnerabilities {
+		for _, id := range ids {
+			v, ok := r.Vulnerabilities[id]
+			if !ok || v == nil {
+				continue
+			}
+			k := key{v.Updater, v.Name}
+			g, ok := groups[k]
+			if !ok {
+				g = &group{
+					vulns: make(map[string]struct{}),
+					pkgs:  make(map[string]map[string]struct{}),
+				}
+				groups[k] = g
+			}
+			g.vulns[id] = struct{}{}
+			if g.pkgs[pkgID] == nil {
+				g.pkgs[pkgID] = make(map[string]struct{})
+			}
+			g.pkgs[pkgID][id] = struct{}{}
+			// Ties go to the lowest ID, so the result doesn't depend on
+			// map order.
+			if w := g.worst; w == nil ||
+				v.NormalizedSeverity > w.NormalizedSeverity ||
+				(v.NormalizedSeverity == w.NormalizedSeverity && id < g.worstID) {
+				g.worst, g.worstID = v, id
+			}
+		}
+	}
+
+	out := make([]AdvisoryGroup, 0, len(groups))
+	for k, g := range groups {
+		ag := AdvisoryGroup{
+			Updater:            k.updater,
+			Name:               k.name,
+			NormalizedSeverity: g.worst.NormalizedSeverity,
+			Severity:           g.worst.Severity,
+			VulnerabilityIDs:   sortedKeys(g.vulns),
+			Packages:           make([]AdvisoryPackage, 0, len(g.pkgs)),
+		}
+		for pkgID, vs := range g.pkgs {
+			ag.Packages = append(ag.Packages, AdvisoryPackage{
+				PackageID:        pkgID,
+				VulnerabilityIDs: sortedKeys(vs),
+			})
+		}
+		sort.Slice(ag.Packages, func(i, j int) bool {
+			return ag.Packages[i].PackageID < ag.Packages[j].PackageID
+		})
+		out = append(out, ag)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Updater != out[j].Updater {
+			return out[i].Updater < out[j].Updater
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
@@ -0,0 +1,74 @@
+package claircore_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+// AdvisoryReport is a report with advisories that overlap in the ways
+// GroupByAdvisory has to handle.
+func advisoryReport() *claircore.VulnerabilityReport {
+	return &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1d-0"},
+			"2": {ID: "2", Name: "libssl1.1", Version: "1.1.1d-0"},
+			"3": {ID: "3", Name: "cryptography", Version: "3.3.1"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			// The same advisory recorded once per package.
+			"10": {ID: "10", Name: "DSA-4807-1", Updater: "debian/updater/buster", Severity: "Low", NormalizedSeverity: claircore.Low},
+			"11": {ID: "11", Name: "DSA-4807-1", Updater: "debian/updater/buster", Severity: "High", NormalizedSeverity: claircore.High},
+			// The same name from different updaters.
+			"20": {ID: "20", Name: "CVE-2020-1971", Updater: "debian/updater/buster", Severity: "Medium", NormalizedSeverity: claircore.Medium},
+			"21": {ID: "21", Name: "CVE-2020-1971", Updater: "osv/pypi", Severity: "HIGH", NormalizedSeverity: claircore.High},
+			// Equally severe; the lower ID provides the vendor severity.
+			"30": {ID: "30", Name: "CVE-2021-23840", Updater: "debian/updater/buster", Severity: "important", NormalizedSeverity: claircore.High},
+			"31": {ID: "31", Name: "CVE-2021-23840", Updater: "debian/updater/buster", Severity: "High", NormalizedSeverity: claircore.High},
+			// Only suppressed.
+			"40": {ID: "40", Name: "CVE-2021-3449", Updater: "debian/updater/buster"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			// "10" is listed twice, and "99" isn't in the report.
+			"1": {"10", "20", "30", "10", "99"},
+			"2": {"11", "20", "31"},
+			"3": {"21"},
+		},
+		Suppressed: map[string][]claircore.Suppression{
+			"1": {{VulnerabilityID: "40"}},
+		},
+	}
+}
+
+func TestGroupByAdvisory(t *testing.T) {
+	r := advisoryReport()
+	b, err := json.Marshal(claircore.GroupByAdvisory(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "advisorygroups.golden.json", b)
+
+	// The order of the report's slices doesn't matter.
+	rng := rand.New(rand.NewSource(1))
+	want := claircore.GroupByAdvisory(r)
+	for i := 0; i < 10; i++ {
+		for _, ids := range r.PackageVulnerabilities {
+			rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		}
+		if got := claircore.GroupByAdvisory(r); !cmp.Equal(got, want) {
+			t.Fatal(cmp.Diff(got, want))
+		}
+	}
+
+	// The report isn't modified.
+	if !cmp.Equal(r.PackageVulnerabilities["3"], []string{"21"}) || len(r.Packages) != 3 {
+		t.Error("report modified")
+	}
+	if got := claircore.GroupByAdvisory(nil); got != nil {
+		t.Errorf("got: %v, want: nil", got)
+	}
+}
@@ -1,6 +1,7 @@
 package claircore
 
 import (
+	"encoding/json"
 	"sort"
 	"sync"
 )
@@ -14,6 +15,10 @@ type AffectedManifests struct {
 	// map associating a list of vulnerability ids keyed by the
 	// manifest hash they affect.
 	VulnerableManifests map[string][]string `json:"vulnerable_manifests"`
+	// Enrichments is keyed by enrichment type, with the same contents as a
+	// VulnerabilityReport's Enrichments for the Vulnerabilities. It's only
+	// populated if the AffectedManifests have been enriched.
+	Enrichments map[string][]json.RawMessage `json:"enrichments,omitempty"`
 }
 
 // NewAffectedManifests initializes a new AffectedManifests struct.
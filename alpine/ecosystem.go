@@ -5,6 +5,7 @@ import (
 
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/pkg/purl"
 )
 
 // NewEcosystem provides the set of scanners and coalescers for the alpine ecosystem
@@ -20,7 +21,7 @@ func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 			return []indexer.RepositoryScanner{}, nil
 		},
 		Coalescer: func(ctx context.Context) (indexer.Coalescer, error) {
-			return linux.NewCoalescer(), nil
+			return linux.NewCoalescer(purl.APK), nil
 		},
 	}
 }
@@ -40,20 +40,24 @@ func (*Matcher) Query() []driver.MatchConstraint {
 }
 
 func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
-	v1, err := version.NewVersion(record.Package.Version)
-	if err != nil {
-		return false, nil
+	pv := record.Package.Version
+	if driver.EmptyVersion(pv) {
+		return false, driver.PackageVersionError(pv, driver.ErrEmptyVersion)
 	}
-
-	v2, err := version.NewVersion(vuln.FixedInVersion)
+	v1, err := version.NewVersion(pv)
 	if err != nil {
-		return false, nil
+		return false, driver.PackageVersionError(pv, err)
 	}
 
 	if vuln.FixedInVersion == "" {
 		return true, nil
 	}
 
+	v2, err := version.NewVersion(vuln.FixedInVersion)
+	if err != nil {
+		return false, driver.VulnerabilityVersionError(vuln.FixedInVersion, err)
+	}
+
 	if v1.LessThan(v2) {
 		return true, nil
 	}
@@ -0,0 +1,23 @@
+package alpine
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+)
+
+func TestBadVersions(t *testing.T) {
+	test.BadVersionTestcase{
+		Matcher: &Matcher{},
+		Record: func(v string) *claircore.IndexRecord {
+			return &claircore.IndexRecord{
+				Package: &claircore.Package{Name: "musl", Version: v},
+			}
+		},
+		Vulnerability: func(v string) *claircore.Vulnerability {
+			return &claircore.Vulnerability{FixedInVersion: v}
+		},
+		Good: "1.1.24-r2",
+	}.Run(t)
+}
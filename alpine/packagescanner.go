@@ -15,7 +15,7 @@ import (
 
 const (
 	pkgName    = `apk`
-	pkgVersion = `v0.0.1`
+	pkgVersion = `v0.0.2`
 	pkgKind    = `package`
 )
 
@@ -100,6 +100,10 @@ func (*Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.
 				p.RepositoryHint = l
 			case 'A':
 				p.Arch = l
+			case 'L':
+				// Recent packages use SPDX expressions, older ones may not.
+				// Either way, it's recorded as found.
+				p.License = l
 			case 'o':
 				if src, ok := srcs[l]; ok {
 					p.Source = src
@@ -1,12 +1,18 @@
 package alpine
 
 import (
+	"archive/tar"
 	"context"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -170,7 +176,62 @@ func TestScan(t *testing.T) {
 		t.Fatal(err)
 	}
 	t.Logf("found %d packages", len(got))
-	if !cmp.Equal(want, got) {
-		t.Fatal(cmp.Diff(want, got))
+	// The expected packages predate recording licenses, which are checked
+	// by TestScanLicense.
+	opt := cmpopts.IgnoreFields(claircore.Package{}, "License")
+	if !cmp.Equal(want, got, opt) {
+		t.Fatal(cmp.Diff(want, got, opt))
+	}
+}
+
+func TestScanLicense(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const db = "C:Q1dGhpcyBpc24ndCBhIHJlYWwgaGFzaA==\n" +
+		"P:musl\nV:1.2.3-r4\nA:x86_64\nL:MIT\no:musl\n\n" +
+		"P:ca-certificates-bundle\nV:20220614-r0\nA:x86_64\nL:MPL-2.0 AND MIT\no:ca-certificates\n\n" +
+		"P:nolicense\nV:1.0-r0\nA:x86_64\n\n"
+	f, err := ioutil.TempFile("", "alpine.layer.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	w := tar.NewWriter(f)
+	if err := w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     installedFile,
+		Size:     int64(len(db)),
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.License
+	}
+	want := map[string]string{
+		"musl":                   "MIT",
+		"ca-certificates-bundle": "MPL-2.0 AND MIT",
+		"nolicense":              "",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
 	}
 }
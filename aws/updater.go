@@ -100,6 +100,7 @@ func (u *Updater) Parse(ctx context.Context, contents io.ReadCloser) ([]*clairco
 			Description:        update.Description,
 			Issued:             issued,
 			Links:              refsToLinks(update),
+			Aliases:            refsToAliases(update),
 			Severity:           update.Severity,
 			NormalizedSeverity: NormalizeSeverity(update.Severity),
 			Dist:               dist,
@@ -139,3 +140,14 @@ func refsToLinks(u alas.Update) string {
 
 	return strings.Join(out, " ")
 }
+
+// refsToAliases takes an alas.Update and returns the CVEs it references.
+func refsToAliases(u alas.Update) []string {
+	var out []string
+	for _, ref := range u.References {
+		if strings.EqualFold(ref.Type, "cve") && ref.ID != "" {
+			out = append(out, ref.ID)
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package claircore
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/remind101/migrate"
+
+	indexmigrations "github.com/quay/claircore/libindex/migrations"
+	vulnmigrations "github.com/quay/claircore/libvuln/migrations"
+)
+
+// Report schema versions.
+//
+// These are incremented whenever a change to IndexReport or
+// VulnerabilityReport would surprise a consumer of the serialized form, such
+// as a removed or renamed field, or a field whose meaning changed. Added
+// fields don't change the version.
+const (
+	IndexReportSchema         = 1
+	VulnerabilityReportSchema = 1
+)
+
+// ModulePath is claircore's module path.
+const modulePath = `github.com/quay/claircore`
+
+// BuildInfo describes the claircore a program was built with, for support
+// requests and provenance in reports.
+type BuildInfo struct {
+	// Version is claircore's module version, such as "v1.0.0". It's
+	// "(devel)" if claircore is the main module or replaced by a local
+	// directory, and "(unknown)" if the program was built without module
+	// information.
+	Version string `json:"version"`
+	// IndexReportSchema and VulnerabilityReportSchema are the schema versions
+	// of the reports this claircore produces.
+	IndexReportSchema         int `json:"index_report_schema"`
+	VulnerabilityReportSchema int `json:"vulnerability_report_schema"`
+	// IndexerMigration and MatcherMigration are the database migrations the
+	// indexer's and matcher's stores are expected to be at.
+	IndexerMigration int `json:"indexer_migration"`
+	MatcherMigration int `json:"matcher_migration"`
+}
+
+var buildInfo struct {
+	sync.Once
+	BuildInfo
+}
+
+// ReadBuildInfo reports the claircore the running program was built with.
+func ReadBuildInfo() BuildInfo {
+	buildInfo.Do(func() {
+		buildInfo.BuildInfo = BuildInfo{
+			Version:                   moduleVersion(),
+			IndexReportSchema:         IndexReportSchema,
+			VulnerabilityReportSchema: VulnerabilityReportSchema,
+			IndexerMigration:          lastMigration(indexmigrations.Migrations),
+			MatcherMigration:          lastMigration(vulnmigrations.Migrations),
+		}
+	})
+	return buildInfo.BuildInfo
+}
+
+// ModuleVersion returns claircore's module version from the program's build
+// information.
+func moduleVersion() string {
+	const unknown = `(unknown)`
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return unknown
+	}
+	if bi.Main.Path == modulePath {
+		return bi.Main.Version
+	}
+	for _, m := range bi.Deps {
+		if m.Path != modulePath {
+			continue
+		}
+		if r := m.Replace; r != nil {
+			if r.Version == "" {
+				return `(devel)`
+			}
+			return r.Version
+		}
+		return m.Version
+	}
+	return unknown
+}
+
+// LastMigration returns the largest migration ID.
+func lastMigration(ms []migrate.Migration) int {
+	var max int
+	for _, m := range ms {
+		if m.ID > max {
+			max = m.ID
+		}
+	}
+	return max
+}
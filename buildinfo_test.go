@@ -0,0 +1,48 @@
+package claircore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	indexmigrations "github.com/quay/claircore/libindex/migrations"
+	vulnmigrations "github.com/quay/claircore/libvuln/migrations"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	bi := ReadBuildInfo()
+	t.Logf("%+v", bi)
+	// Tests are built with claircore as the main module.
+	if got, want := bi.Version, "(devel)"; got != want {
+		t.Errorf("version: got: %q, want: %q", got, want)
+	}
+	if bi.IndexReportSchema == 0 || bi.VulnerabilityReportSchema == 0 {
+		t.Error("missing report schema versions")
+	}
+	if got, want := bi.IndexerMigration, len(indexmigrations.Migrations); got != want {
+		t.Errorf("indexer migration: got: %d, want: %d", got, want)
+	}
+	if got, want := bi.MatcherMigration, len(vulnmigrations.Migrations); got != want {
+		t.Errorf("matcher migration: got: %d, want: %d", got, want)
+	}
+
+	b, err := json.Marshal(bi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"version":                     "(devel)",
+		"index_report_schema":         float64(IndexReportSchema),
+		"vulnerability_report_schema": float64(VulnerabilityReportSchema),
+		"indexer_migration":           float64(len(indexmigrations.Migrations)),
+		"matcher_migration":           float64(len(vulnmigrations.Migrations)),
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
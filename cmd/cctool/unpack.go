@@ -61,7 +61,7 @@ func Unpack(cmd context.Context, cfg *commonConfig, args []string) error {
 	defer done()
 
 	log.Printf("fetching layers")
-	f := fetcher.New(http.DefaultClient, "")
+	f := fetcher.New(http.DefaultClient, "", nil, nil, nil)
 	err = f.Fetch(ctx, m.Layers)
 	if err != nil {
 		return err
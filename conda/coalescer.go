@@ -0,0 +1,63 @@
+package conda
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
+)
+
+// NewCoalescer returns the coalescer for Conda environments.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// Each package is associated with the repository it was installed from. A
+// package reinstalled into the same environment in a later layer is reported
+// as the later version only.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	// Environment and package name to package ID.
+	installed := make(map[[2]string]string)
+	for _, l := range ls {
+		byURI := make(map[string]*claircore.Repository, len(l.Repos))
+		for _, r := range l.Repos {
+			byURI[r.URI] = r
+		}
+		for _, pkg := range l.Pkgs {
+			k := [2]string{pkg.PackageDB, pkg.Name}
+			if id, ok := installed[k]; ok && id != pkg.ID {
+				delete(ir.Packages, id)
+				delete(ir.Environments, id)
+			}
+			installed[k] = pkg.ID
+			env := &claircore.Environment{
+				PackageDB:    pkg.PackageDB,
+				IntroducedIn: l.Hash,
+			}
+			var rs []*claircore.Repository
+			if r, ok := byURI[pkg.RepositoryHint]; ok {
+				rs = append(rs, r)
+				ir.AddRepository(r, l.Hash)
+				env.RepositoryIDs = []string{r.ID}
+			}
+			if pkg.RepositoryHint == pypiRepository.URI {
+				pkg.PURL = purl.PyPI(pkg, nil, rs)
+			} else {
+				pkg.PURL = purl.Conda(pkg, nil, rs)
+			}
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = []*claircore.Environment{env}
+		}
+	}
+	return ir, nil
+}
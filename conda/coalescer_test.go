@@ -0,0 +1,75 @@
+package conda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/test"
+)
+
+func TestCoalesce(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	main := &claircore.Repository{ID: "1", Name: "conda", URI: "https://repo.anaconda.com/pkgs/main"}
+	pypi := &claircore.Repository{ID: "2", Name: "pypi", URI: "https://pypi.org/simple"}
+	ls := []*indexer.LayerArtifacts{
+		{
+			Hash: claircore.MustParseDigest("sha256:" + "1111111111111111111111111111111111111111111111111111111111111111"),
+			Pkgs: []*claircore.Package{
+				{ID: "1", Name: "zlib", Version: "1.2.11-h7b6447c_3", Arch: "linux-64", PackageDB: "conda:opt/conda", RepositoryHint: main.URI},
+				{ID: "2", Name: "six", Version: "1.15.0", PackageDB: "conda:opt/conda", RepositoryHint: pypi.URI},
+			},
+			Repos: []*claircore.Repository{main, pypi},
+		},
+		{
+			Hash: claircore.MustParseDigest("sha256:" + "2222222222222222222222222222222222222222222222222222222222222222"),
+			Pkgs: []*claircore.Package{
+				{ID: "3", Name: "zlib", Version: "1.2.11-h7f8727e_4", Arch: "linux-64", PackageDB: "conda:opt/conda", RepositoryHint: main.URI},
+			},
+			Repos: []*claircore.Repository{main},
+		},
+	}
+	c, err := NewCoalescer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Coalesce(ctx, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for id, p := range ir.Packages {
+		got[id] = p.PURL
+	}
+	want := map[string]string{
+		"2": "pkg:pypi/six@1.15.0",
+		"3": "pkg:conda/zlib@1.2.11?build=h7f8727e_4&channel=https://repo.anaconda.com/pkgs/main&subdir=linux-64",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for id, repo := range map[string]string{"2": pypi.ID, "3": main.ID} {
+		envs := ir.Environments[id]
+		if len(envs) != 1 || !cmp.Equal(envs[0].RepositoryIDs, []string{repo}) {
+			t.Errorf("package %s: got environments %+v, want repository %s", id, envs, repo)
+		}
+	}
+	if envs := ir.Environments["3"]; len(envs) == 1 && envs[0].IntroducedIn.String() != ls[1].Hash.String() {
+		t.Errorf("package 3: introduced in %v", envs[0].IntroducedIn)
+	}
+}
+
+func TestCoalescerKit(t *testing.T) {
+	ctx := context.Background()
+	tc := test.CoalescerTestcase{
+		Coalescer:  NewCoalescer,
+		PackageDB:  "conda:opt/conda",
+		Repository: &claircore.Repository{ID: "1", Name: "conda", URI: "https://repo.anaconda.com/pkgs/main"},
+	}
+	t.Run("Kit", tc.Run(ctx))
+}
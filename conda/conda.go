@@ -0,0 +1,165 @@
+// Package conda contains components for interrogating packages installed
+// into Conda environments in container layers.
+package conda
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// PypiChannel is the pseudo-channel Conda records packages installed by pip
+// under.
+const pypiChannel = "pypi"
+
+// DefaultChannelAlias is where Conda resolves channels given by name.
+const defaultChannelAlias = `https://conda.anaconda.org/`
+
+// PypiRepository is the repository reported for packages Conda records as
+// installed from PyPI. It's the same as python.Repository, so these packages
+// are matched like any other python package.
+var pypiRepository = claircore.Repository{
+	Name: "pypi",
+	URI:  "https://pypi.org/simple",
+}
+
+// Record is the subset of a "conda-meta/*.json" record used here.
+type record struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	Build   string   `json:"build"`
+	Channel string   `json:"channel"`
+	Subdir  string   `json:"subdir"`
+	Files   []string `json:"files"`
+
+	// Prefix is the environment the record was found in.
+	prefix string
+}
+
+// Pypi reports whether the record is for a package installed by pip.
+func (r *record) pypi() bool {
+	return r.Channel == pypiChannel
+}
+
+// ChannelURL returns the URL of the channel the package was installed from,
+// without the platform subdirectory.
+func (r *record) channelURL() string {
+	c := strings.TrimSuffix(r.Channel, "/")
+	if r.Subdir != "" {
+		c = strings.TrimSuffix(c, "/"+r.Subdir)
+	}
+	if c == "" {
+		return ""
+	}
+	if !strings.Contains(c, "://") {
+		c = defaultChannelAlias + c
+	}
+	return c
+}
+
+// Repository returns the repository the package was installed from.
+func (r *record) repository() *claircore.Repository {
+	if r.pypi() {
+		repo := pypiRepository
+		return &repo
+	}
+	return &claircore.Repository{
+		Name: "conda",
+		URI:  r.channelURL(),
+	}
+}
+
+// Records reads the installed package records of every Conda environment in
+// the layer.
+//
+// Records for packages that also installed python distribution metadata
+// present in the layer are left out: the python package scanner reports
+// those, so reporting them here would duplicate them.
+//
+// Only environments' "conda-meta" directories are read. The package cache
+// ("pkgs") holds packages that may or may not be installed anywhere, so it's
+// ignored.
+func records(ctx context.Context, layer *claircore.Layer) ([]*record, error) {
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rd, ok := r.(interface {
+		io.ReadCloser
+		io.Seeker
+	})
+	if !ok {
+		return nil, errors.New("conda: cannot seek on returned layer Reader")
+	}
+
+	var rs []*record
+	// Python distribution metadata, as found by the python scanner.
+	pymeta := make(map[string]struct{})
+	tr := tar.NewReader(rd)
+	var h *tar.Header
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(n, `.egg-info/PKG-INFO`), strings.HasSuffix(n, `.dist-info/METADATA`):
+			pymeta[n] = struct{}{}
+			continue
+		case path.Base(path.Dir(n)) == "conda-meta" && path.Ext(n) == ".json":
+		default:
+			continue
+		}
+		var rec record
+		if err := json.NewDecoder(tr).Decode(&rec); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("path", n).
+				Msg("unable to read conda record, skipping")
+			continue
+		}
+		if rec.Name == "" || rec.Version == "" {
+			zlog.Debug(ctx).
+				Str("path", n).
+				Msg("conda record missing name or version, skipping")
+			continue
+		}
+		rec.prefix = path.Dir(path.Dir(n))
+		rs = append(rs, &rec)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	out := rs[:0]
+Record:
+	for _, rec := range rs {
+		for _, f := range rec.Files {
+			p := path.Join(rec.prefix, f)
+			if _, ok := pymeta[p]; ok {
+				zlog.Debug(ctx).
+					Str("name", rec.Name).
+					Str("metadata", p).
+					Msg("package reported by python scanner, skipping")
+				continue Record
+			}
+		}
+		// The file list isn't needed past here, and can be large.
+		rec.Files = nil
+		out = append(out, rec)
+	}
+	return out, nil
+}
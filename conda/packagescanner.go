@@ -0,0 +1,98 @@
+package conda
+
+import (
+	"context"
+	"runtime/trace"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+var (
+	_ indexer.VersionedScanner = (*Scanner)(nil)
+	_ indexer.PackageScanner   = (*Scanner)(nil)
+)
+
+// Scanner implements the scanner.PackageScanner interface.
+//
+// It reads the records Conda keeps for every package installed into an
+// environment, in the environment's "conda-meta" directory.
+//
+// Packages Conda installed from a channel are reported with a version of the
+// form "version-build", as Conda names them. Packages Conda records as
+// installed by pip are reported as python packages, so they're matched by the
+// python matcher.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*Scanner) Name() string { return "conda" }
+
+// Version implements scanner.VersionedScanner.
+func (*Scanner) Version() string { return "0.1.1" }
+
+// Kind implements scanner.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Scan attempts to find Conda environments and record the packages installed
+// into them.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "conda/Scanner.Scan"),
+		label.String("version", ps.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rs, err := records(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*claircore.Package
+	for _, r := range rs {
+		pkg := &claircore.Package{
+			Name:           r.Name,
+			Version:        r.Version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "conda:" + r.prefix,
+			Arch:           r.Subdir,
+			RepositoryHint: r.repository().URI,
+		}
+		switch {
+		case r.pypi():
+			pkg.Name = strings.ToLower(r.Name)
+			pkg.Arch = ""
+			// Packages with unparseable versions are kept as found, so
+			// matching can report them as indeterminate.
+			v, err := pep440.Parse(r.Version)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("name", r.Name).
+					Str("version", r.Version).
+					Msg("unable to parse pip-installed package version")
+				break
+			}
+			pkg.Version = v.String()
+			pkg.NormalizedVersion = v.Version()
+		case r.Build != "":
+			pkg.Version += "-" + r.Build
+		}
+		ret = append(ret, pkg)
+	}
+	return ret, nil
+}
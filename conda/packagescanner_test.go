@@ -0,0 +1,209 @@
+package conda_test
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/conda"
+	"github.com/quay/claircore/pkg/pep440"
+	"github.com/quay/claircore/python"
+)
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/env")
+
+	ps, err := new(conda.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortPackages(ps)
+	six, err := pep440.Parse("1.15.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:           "openssl",
+			Version:        "1.1.1k-h7f98852_0",
+			Kind:           claircore.BINARY,
+			PackageDB:      "conda:opt/conda",
+			Arch:           "linux-64",
+			RepositoryHint: "https://conda.anaconda.org/conda-forge",
+		},
+		{
+			Name:              "six",
+			Version:           "1.15.0",
+			Kind:              claircore.BINARY,
+			PackageDB:         "conda:opt/conda",
+			RepositoryHint:    "https://pypi.org/simple",
+			NormalizedVersion: six.Version(),
+		},
+		{
+			Name:           "tzdata",
+			Version:        "2021a-he74cb21_0",
+			Kind:           claircore.BINARY,
+			PackageDB:      "conda:opt/conda",
+			Arch:           "noarch",
+			RepositoryHint: "https://conda.anaconda.org/conda-forge",
+		},
+		{
+			Name:           "zlib",
+			Version:        "1.2.11-h7b6447c_3",
+			Kind:           claircore.BINARY,
+			PackageDB:      "conda:opt/conda",
+			Arch:           "linux-64",
+			RepositoryHint: "https://repo.anaconda.com/pkgs/main",
+		},
+		{
+			Name:           "libgcc-ng",
+			Version:        "9.3.0-h5101ec6_17",
+			Kind:           claircore.BINARY,
+			PackageDB:      "conda:opt/conda/envs/ml",
+			Arch:           "linux-64",
+			RepositoryHint: "https://conda.anaconda.org/conda-forge",
+		},
+	}
+	if !cmp.Equal(ps, want) {
+		t.Error(cmp.Diff(ps, want))
+	}
+}
+
+func TestRepoScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/env")
+
+	rs, err := new(conda.RepoScanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].URI < rs[j].URI })
+	want := []*claircore.Repository{
+		{Name: "conda", URI: "https://conda.anaconda.org/conda-forge"},
+		{Name: "pypi", URI: "https://pypi.org/simple"},
+		{Name: "conda", URI: "https://repo.anaconda.com/pkgs/main"},
+	}
+	if !cmp.Equal(rs, want) {
+		t.Error(cmp.Diff(rs, want))
+	}
+}
+
+// TestPythonDedup checks that packages with python metadata the python
+// scanner reports aren't also reported by the conda scanner.
+func TestPythonDedup(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/env")
+
+	pyps, err := new(python.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cps, err := new(conda.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var py []string
+	for _, p := range pyps {
+		py = append(py, p.Name)
+	}
+	sort.Strings(py)
+	if want := []string{"numpy", "requests"}; !cmp.Equal(py, want) {
+		t.Fatal(cmp.Diff(py, want))
+	}
+	for _, p := range cps {
+		for _, n := range py {
+			if p.Name == n {
+				t.Errorf("%q reported by both scanners", n)
+			}
+		}
+	}
+}
+
+// TestMatcherRouting checks that only packages Conda installed from PyPI are
+// considered by the python matcher.
+func TestMatcherRouting(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/env")
+
+	ps, err := new(conda.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	m := new(python.Matcher)
+	for _, p := range ps {
+		if m.Filter(&claircore.IndexRecord{Package: p}) {
+			got = append(got, p.Name)
+		}
+	}
+	if want := []string{"six"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func sortPackages(ps []*claircore.Package) {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].PackageDB != ps[j].PackageDB {
+			return ps[i].PackageDB < ps[j].PackageDB
+		}
+		return ps[i].Name < ps[j].Name
+	})
+}
+
+// DirLayer writes the contents of the directory "dir" to a layer tarball.
+func dirLayer(t *testing.T, dir string) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "conda.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		n, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		h, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(n)
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
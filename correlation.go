@@ -0,0 +1,45 @@
+package claircore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+// CorrelationIDKey is the baggage label, and so the log field, that carries
+// the correlation ID of an Index or Scan operation.
+const CorrelationIDKey = "correlation_id"
+
+// WithCorrelationID returns a Context carrying "id" as its correlation ID. If
+// "id" is empty, a random one is generated.
+//
+// Libindex.Index and Libvuln.Scan include the correlation ID on every log line
+// written during the operation, including those written by the stores, so
+// output from concurrent operations can be told apart. They generate an ID if
+// the Context doesn't carry one; callers that want to report the ID, for
+// example in an API response, should set one before calling and read it back
+// with CorrelationID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return baggage.ContextWithValues(ctx, label.String(CorrelationIDKey, id))
+}
+
+// CorrelationID returns the Context's correlation ID, or the empty string if
+// it doesn't carry one.
+func CorrelationID(ctx context.Context) string {
+	return baggage.Value(ctx, label.Key(CorrelationIDKey)).AsString()
+}
+
+// EnsureCorrelationID returns a Context carrying a correlation ID, generating
+// one if "ctx" doesn't carry one already, along with the ID.
+func EnsureCorrelationID(ctx context.Context) (context.Context, string) {
+	if id := CorrelationID(ctx); id != "" {
+		return ctx, id
+	}
+	ctx = WithCorrelationID(ctx, "")
+	return ctx, CorrelationID(ctx)
+}
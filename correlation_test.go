@@ -0,0 +1,29 @@
+package claircore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	ctx := context.Background()
+	if got := CorrelationID(ctx); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+	if got, want := CorrelationID(WithCorrelationID(ctx, "req-1")), "req-1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	a := CorrelationID(WithCorrelationID(ctx, ""))
+	b := CorrelationID(WithCorrelationID(ctx, ""))
+	if a == "" || a == b {
+		t.Errorf("generated IDs not unique: %q, %q", a, b)
+	}
+
+	set := WithCorrelationID(ctx, "req-2")
+	if got, id := EnsureCorrelationID(set); got != set || id != "req-2" {
+		t.Errorf("existing ID replaced: got %q", id)
+	}
+	if ctx, id := EnsureCorrelationID(ctx); id == "" || CorrelationID(ctx) != id {
+		t.Errorf("got %q, Context has %q", id, CorrelationID(ctx))
+	}
+}
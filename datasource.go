@@ -0,0 +1,15 @@
+package claircore
+
+// DataSource describes where an updater's vulnerability or enrichment data
+// comes from, so the terms it's published under can be honored by whatever
+// displays it.
+type DataSource struct {
+	// Name is a human-readable name for the source, like "National
+	// Vulnerability Database".
+	Name string `json:"name"`
+	// License is the license or terms of use the data is published under,
+	// preferably as an SPDX identifier.
+	License string `json:"license,omitempty"`
+	// URL points at the source's attribution requirements or terms of use.
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,71 @@
+// Package datastore is the extension point for backing libvuln with a
+// vulnerability store other than the PostgreSQL one provided in
+// datastore/postgres.
+//
+// A store outside of this module implements MatcherStore and is passed to
+// libvuln by setting libvuln.Opts.Store. The types here are aliases of the
+// ones used internally, so an implementation written against this package
+// is interchangeable with the provided one. The MatcherStoreTestcase in the
+// test package checks an implementation against the behavior libvuln
+// expects.
+package datastore
+
+import (
+	"github.com/quay/claircore/internal/vulnstore"
+)
+
+// MatcherStore is everything libvuln needs from a vulnerability store.
+type MatcherStore = vulnstore.Store
+
+// Updater records update operations and the vulnerabilities and enrichments
+// they contain, and manages the recorded operations.
+type Updater = vulnstore.Updater
+
+// EnrichmentUpdater records enrichment update operations.
+type EnrichmentUpdater = vulnstore.EnrichmentUpdater
+
+// Vulnerability finds the vulnerabilities affecting packages.
+type Vulnerability = vulnstore.Vulnerability
+
+// GetOpts controls how Vulnerability.Get matches packages to
+// vulnerabilities.
+type GetOpts = vulnstore.GetOpts
+
+// EnrichmentGetter finds the enrichment records with a set of tags.
+type EnrichmentGetter = vulnstore.Enrichment
+
+// Exclusions stores the Exclusions used to suppress findings.
+type Exclusions = vulnstore.Exclusions
+
+// Stats reports the size of the store and the work pending for GC.
+type Stats = vulnstore.Stats
+
+// Optional interfaces a MatcherStore may implement. Libvuln checks for these
+// at run time and falls back to slower paths without them.
+type (
+	// VulnerabilityLookup finds vulnerabilities by name, rather than by the
+	// packages they affect.
+	VulnerabilityLookup = vulnstore.VulnerabilityLookup
+	// Staging lets a paginated update be recorded as it's fetched and
+	// resumed after an interruption.
+	Staging = vulnstore.Staging
+	// StagedUpdate describes an update in progress, as recorded by Staging.
+	StagedUpdate = vulnstore.StagedUpdate
+	// Importer records updates made elsewhere, such as the ones in a
+	// snapshot, all at once and keeping the dates they were made.
+	Importer = vulnstore.Importer
+	// ImportFunc records an Import, as passed to Importer's callback.
+	ImportFunc = vulnstore.ImportFunc
+	// Import is an update made elsewhere.
+	Import = vulnstore.Import
+	// Idempotent recognizes a retried update by a key the caller supplies,
+	// so an update whose commit succeeded isn't stored twice.
+	Idempotent = vulnstore.Idempotent
+	// AliasResolver finds the other names vulnerabilities are known by,
+	// from the Aliases of the vulnerabilities passed to
+	// UpdateVulnerabilities.
+	AliasResolver = vulnstore.AliasResolver
+	// SeverityBackfiller rewrites the NormalizedSeverity of stored
+	// vulnerabilities using claircore.NormalizeSeverity.
+	SeverityBackfiller = vulnstore.SeverityBackfiller
+)
@@ -0,0 +1,62 @@
+// Package postgres provides the PostgreSQL implementation of
+// datastore.MatcherStore.
+//
+// This is the store libvuln constructs from its ConnString option. Using it
+// directly is only needed to share a pool with other code, or to wrap it.
+// The database must have had the libvuln migrations applied.
+package postgres
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/internal/vulnstore/postgres"
+)
+
+// MatcherStore is a PostgreSQL-backed datastore.MatcherStore. It also
+// implements datastore.VulnerabilityLookup, datastore.Staging,
+// datastore.AliasResolver, and datastore.SeverityBackfiller.
+type MatcherStore = postgres.Store
+
+var (
+	_ datastore.MatcherStore        = (*MatcherStore)(nil)
+	_ datastore.VulnerabilityLookup = (*MatcherStore)(nil)
+	_ datastore.Staging             = (*MatcherStore)(nil)
+	_ datastore.AliasResolver       = (*MatcherStore)(nil)
+	_ datastore.SeverityBackfiller  = (*MatcherStore)(nil)
+)
+
+// Option configures a MatcherStore.
+type Option = postgres.Option
+
+// NewMatcherStore returns a MatcherStore using the provided pool.
+func NewMatcherStore(pool *pgxpool.Pool, opts ...Option) *MatcherStore {
+	return postgres.NewVulnStore(pool, opts...)
+}
+
+// DefaultNamespace is the namespace used by a MatcherStore constructed
+// without WithNamespace.
+const DefaultNamespace = postgres.DefaultNamespace
+
+// These are the Options a MatcherStore accepts. See the libvuln Opts for
+// what they do.
+var (
+	// WithNamespace isolates the MatcherStore's data from other namespaces in
+	// the same database, like libvuln's Namespace option.
+	WithNamespace = postgres.WithNamespace
+	// WithMigrationAssist enables the description migration phases, like
+	// libvuln's MigrationAssist option.
+	WithMigrationAssist = postgres.WithMigrationAssist
+	// WithRejectInvalidEnrichments makes recording an invalid enrichment,
+	// such as one with no tags or with data that isn't valid JSON, an error
+	// rather than a warning, like libvuln's RejectInvalidEnrichments option.
+	WithRejectInvalidEnrichments = postgres.WithRejectInvalidEnrichments
+	// WithMaxEnrichmentSize limits the size of a single enrichment record's
+	// data, like libvuln's MaxEnrichmentSize option.
+	WithMaxEnrichmentSize = postgres.WithMaxEnrichmentSize
+)
+
+// DefaultMaxEnrichmentSize is the largest enrichment data, in bytes, a
+// MatcherStore constructed without WithMaxEnrichmentSize accepts in a single
+// record.
+const DefaultMaxEnrichmentSize = postgres.DefaultMaxEnrichmentSize
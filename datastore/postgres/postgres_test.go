@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/internal/vulnstore/postgres"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestMatcherStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := context.Background()
+	tc := test.MatcherStoreTestcase{
+		New: func(ctx context.Context, t *testing.T) datastore.MatcherStore {
+			return NewMatcherStore(postgres.TestDB(ctx, t))
+		},
+	}
+	t.Run("Conformance", tc.Run(ctx))
+}
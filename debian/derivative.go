@@ -0,0 +1,143 @@
+package debian
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// DerivativeFactory is a MatcherFactory for DerivativeMatchers.
+//
+// Matching derivatives is opt-in: until the factory is configured with at
+// least one derivative, it returns no matchers.
+type DerivativeFactory struct {
+	m *DerivativeMatcher
+}
+
+var (
+	_ driver.MatcherFactory      = (*DerivativeFactory)(nil)
+	_ driver.MatcherConfigurable = (*DerivativeFactory)(nil)
+)
+
+// DerivativeConfig is the configuration for the DerivativeFactory.
+type DerivativeConfig struct {
+	Derivatives []Derivative `json:"derivatives" yaml:"derivatives"`
+}
+
+// Derivative maps a Debian-derived distribution to the Debian release whose
+// vulnerability data should be used for it.
+type Derivative struct {
+	// ID is the derivative's os-release "ID", such as "raspbian" or "kali".
+	ID string `json:"id" yaml:"id"`
+	// VersionCodeName is the derivative's os-release "VERSION_CODENAME". If
+	// empty, all versions of the derivative use the same release.
+	VersionCodeName string `json:"version_codename" yaml:"version_codename"`
+	// Release is the code name of the Debian release to use, such as
+	// "bookworm".
+	Release Release `json:"release" yaml:"release"`
+}
+
+// Configure implements driver.MatcherConfigurable.
+func (f *DerivativeFactory) Configure(ctx context.Context, cf driver.MatcherConfigUnmarshaler, _ *http.Client) error {
+	var cfg DerivativeConfig
+	if err := cf(&cfg); err != nil {
+		return err
+	}
+	if len(cfg.Derivatives) == 0 {
+		f.m = nil
+		return nil
+	}
+	m := &DerivativeMatcher{
+		rel: make(map[derivativeKey]Release, len(cfg.Derivatives)),
+	}
+	for _, d := range cfg.Derivatives {
+		if d.ID == "" {
+			return fmt.Errorf("debian: derivative missing id")
+		}
+		if _, ok := AllReleases[d.Release]; !ok {
+			return fmt.Errorf("debian: derivative %q: unknown release %q", d.ID, d.Release)
+		}
+		m.rel[derivativeKey{id: d.ID, codename: d.VersionCodeName}] = d.Release
+	}
+	f.m = m
+	return nil
+}
+
+// Matcher implements driver.MatcherFactory.
+func (f *DerivativeFactory) Matcher(_ context.Context) ([]driver.Matcher, error) {
+	if f.m == nil {
+		return nil, nil
+	}
+	return []driver.Matcher{f.m}, nil
+}
+
+// DerivativeMatcher matches packages on Debian-derived distributions that
+// don't have their own vulnerability data against a configured Debian
+// release's data.
+//
+// This is an approximation: derivatives may rebuild or patch packages. Every
+// vulnerability matched this way is annotated to say so.
+type DerivativeMatcher struct {
+	Matcher
+	rel map[derivativeKey]Release
+}
+
+type derivativeKey struct {
+	id, codename string
+}
+
+var (
+	_ driver.Matcher            = (*DerivativeMatcher)(nil)
+	_ driver.DistributionMapper = (*DerivativeMatcher)(nil)
+)
+
+// These are the annotation keys added to vulnerabilities matched by a
+// DerivativeMatcher.
+const (
+	AnnotationConfidence    = "confidence"
+	AnnotationApproximation = "approximation"
+)
+
+// Name implements driver.Matcher.
+func (*DerivativeMatcher) Name() string {
+	return "debian-derivative-matcher"
+}
+
+// Filter implements driver.Matcher.
+func (m *DerivativeMatcher) Filter(record *claircore.IndexRecord) bool {
+	_, ok := m.release(record.Distribution)
+	return ok
+}
+
+// MapDistribution implements driver.DistributionMapper.
+func (m *DerivativeMatcher) MapDistribution(record *claircore.IndexRecord) (*claircore.Distribution, map[string]string) {
+	r, ok := m.release(record.Distribution)
+	if !ok {
+		return nil, nil
+	}
+	name := record.Distribution.DID
+	if c := record.Distribution.VersionCodeName; c != "" {
+		name += " " + c
+	}
+	return releaseToDist(r), map[string]string{
+		AnnotationConfidence:    "approximate",
+		AnnotationApproximation: fmt.Sprintf("%s matched using Debian %s vulnerability data", name, r),
+	}
+}
+
+// Release reports the Debian release configured for the distribution, if any.
+//
+// A mapping for the exact code name is preferred over one for any version.
+func (m *DerivativeMatcher) release(d *claircore.Distribution) (Release, bool) {
+	if d == nil || d.DID == "" || d.DID == OSReleaseID {
+		return "", false
+	}
+	if r, ok := m.rel[derivativeKey{id: d.DID, codename: d.VersionCodeName}]; ok {
+		return r, true
+	}
+	r, ok := m.rel[derivativeKey{id: d.DID}]
+	return r, ok
+}
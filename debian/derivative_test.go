@@ -0,0 +1,209 @@
+package debian
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/matcher"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/osrelease"
+)
+
+// BookwormStore only has vulnerabilities for Debian bookworm.
+type bookwormStore struct {
+	vulns []*claircore.Vulnerability
+}
+
+func (s *bookwormStore) Get(_ context.Context, rs []*claircore.IndexRecord, _ vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range rs {
+		d := r.Distribution
+		if d.DID != OSReleaseID || d.VersionCodeName != string(Bookworm) {
+			continue
+		}
+		for _, v := range s.vulns {
+			if v.Package.Name == r.Package.Name {
+				out[r.Package.ID] = append(out[r.Package.ID], v)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (*bookwormStore) GetEnrichment(context.Context, string, []string) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+func (*bookwormStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return nil, nil
+}
+
+// OSReleaseLayer returns a layer containing the named os-release file from
+// testdata.
+func osReleaseLayer(t *testing.T, name string) *claircore.Layer {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "derivative.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	if err := w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "etc/os-release",
+		Size:     int64(len(b)),
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestDerivativeMatcher(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ds, err := (&osrelease.Scanner{}).Scan(ctx, osReleaseLayer(t, "raspbian-os-release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds) != 1 {
+		t.Fatalf("expected 1 distribution, got %d", len(ds))
+	}
+	dist := ds[0]
+	dist.ID = "1"
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "3.0.9-1+rpi1", Kind: claircore.BINARY},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": dist,
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	store := &bookwormStore{
+		vulns: []*claircore.Vulnerability{
+			{
+				ID:             "1",
+				Name:           "CVE-2023-4807",
+				Package:        &claircore.Package{Name: "openssl"},
+				Dist:           releaseToDist(Bookworm),
+				FixedInVersion: "3.0.11-1~deb12u1",
+			},
+		},
+	}
+
+	t.Run("Configured", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var f DerivativeFactory
+		err := f.Configure(ctx, func(v interface{}) error {
+			cfg := v.(*DerivativeConfig)
+			cfg.Derivatives = []Derivative{
+				{ID: "raspbian", Release: Bookworm},
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms, err := f.Matcher(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vr, err := matcher.EnrichedMatch(ctx, ir, ms, nil, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := vr.PackageVulnerabilities["1"]
+		if len(ids) != 1 {
+			t.Fatalf("expected 1 vulnerability, got %d", len(ids))
+		}
+		v := vr.Vulnerabilities[ids[0]]
+		t.Logf("annotations: %v", v.Annotations)
+		if got, want := v.Annotations[AnnotationConfidence], "approximate"; got != want {
+			t.Errorf("confidence: got: %q, want: %q", got, want)
+		}
+		if got, want := v.Annotations[AnnotationApproximation], "raspbian bookworm matched using Debian bookworm vulnerability data"; got != want {
+			t.Errorf("approximation: got: %q, want: %q", got, want)
+		}
+		if store.vulns[0].Annotations != nil {
+			t.Error("stored vulnerability modified")
+		}
+	})
+	t.Run("OtherDerivative", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var f DerivativeFactory
+		err := f.Configure(ctx, func(v interface{}) error {
+			cfg := v.(*DerivativeConfig)
+			cfg.Derivatives = []Derivative{
+				{ID: "kali", Release: Bookworm},
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms, err := f.Matcher(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vr, err := matcher.EnrichedMatch(ctx, ir, ms, nil, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(vr.Vulnerabilities); got != 0 {
+			t.Errorf("expected no vulnerabilities, got %d", got)
+		}
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var f DerivativeFactory
+		ms, err := f.Matcher(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ms) != 0 {
+			t.Errorf("expected no matchers, got %d", len(ms))
+		}
+	})
+	t.Run("BadRelease", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var f DerivativeFactory
+		err := f.Configure(ctx, func(v interface{}) error {
+			cfg := v.(*DerivativeConfig)
+			cfg.Derivatives = []Derivative{
+				{ID: "raspbian", Release: "trixie"},
+			}
+			return nil
+		}, nil)
+		if err == nil {
+			t.Error("expected error for unknown release")
+		}
+	})
+}
@@ -26,6 +26,14 @@ type debianRegex struct {
 }
 
 var debianRegexes = []debianRegex{
+	{
+		release: Bookworm,
+		regexp:  regexp.MustCompile(`(?is)debian gnu/linux 12`),
+	},
+	{
+		release: Bullseye,
+		regexp:  regexp.MustCompile(`(?is)debian gnu/linux 11`),
+	},
 	{
 		release: Buster,
 		regexp:  regexp.MustCompile(`(?is)debian gnu/linux 10`),
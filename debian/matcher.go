@@ -7,11 +7,15 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/debkernel"
 )
 
 type Matcher struct{}
 
-var _ driver.Matcher = (*Matcher)(nil)
+var (
+	_ driver.Matcher       = (*Matcher)(nil)
+	_ driver.PackageMapper = (*Matcher)(nil)
+)
 
 func (*Matcher) Name() string {
 	return "debian-matcher"
@@ -40,21 +44,35 @@ func (*Matcher) Query() []driver.MatchConstraint {
 	}
 }
 
+// MapPackage implements driver.PackageMapper.
+//
+// Kernel packages are queried as their kernel source package.
+func (*Matcher) MapPackage(record *claircore.IndexRecord) *claircore.Package {
+	return debkernel.Package(record.Package)
+}
+
 func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
-	v1, err := version.NewVersion(record.Package.Version)
-	if err != nil {
-		return false, nil
+	installed, fixed := record.Package.Version, vuln.FixedInVersion
+	if kv, kf, ok := debkernel.Versions(record, vuln); ok {
+		installed, fixed = kv, kf
 	}
-
-	v2, err := version.NewVersion(vuln.FixedInVersion)
+	if driver.EmptyVersion(installed) {
+		return false, driver.PackageVersionError(installed, driver.ErrEmptyVersion)
+	}
+	v1, err := version.NewVersion(installed)
 	if err != nil {
-		return false, err
+		return false, driver.PackageVersionError(installed, err)
 	}
 
-	if vuln.FixedInVersion == "" {
+	if fixed == "" {
 		return true, nil
 	}
 
+	v2, err := version.NewVersion(fixed)
+	if err != nil {
+		return false, driver.VulnerabilityVersionError(fixed, err)
+	}
+
 	if v2.String() == "0" {
 		return true, nil
 	}
@@ -0,0 +1,23 @@
+package debian
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+)
+
+func TestBadVersions(t *testing.T) {
+	test.BadVersionTestcase{
+		Matcher: &Matcher{},
+		Record: func(v string) *claircore.IndexRecord {
+			return &claircore.IndexRecord{
+				Package: &claircore.Package{Name: "bash", Version: v},
+			}
+		},
+		Vulnerability: func(v string) *claircore.Vulnerability {
+			return &claircore.Vulnerability{FixedInVersion: v}
+		},
+		Good: "5.0-4",
+	}.Run(t)
+}
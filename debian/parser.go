@@ -2,7 +2,6 @@ package debian
 
 import (
 	"context"
-	"encoding/xml"
 	"fmt"
 	"io"
 
@@ -20,8 +19,8 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		label.String("component", "debian/Updater.Parse"))
 	zlog.Info(ctx).Msg("starting parse")
 	defer r.Close()
-	root := oval.Root{}
-	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+	root, err := ovalutil.Decode(ctx, r)
+	if err != nil {
 		return nil, fmt.Errorf("debian: unable to decode OVAL document: %w", err)
 	}
 	zlog.Debug(ctx).Msg("xml decoded")
@@ -39,7 +38,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		vs = append(vs, v)
 		return vs, nil
 	}
-	vulns, err := ovalutil.DpkgDefsToVulns(ctx, &root, protoVulns)
+	vulns, err := ovalutil.DpkgDefsToVulns(ctx, root, protoVulns)
 	if err != nil {
 		return nil, err
 	}
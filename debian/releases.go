@@ -5,24 +5,48 @@ import "github.com/quay/claircore"
 type Release string
 
 const (
-	Buster  Release = "buster"
-	Jessie  Release = "jessie"
-	Stretch Release = "stretch"
-	Wheezy  Release = "wheezy"
+	Bookworm Release = "bookworm"
+	Bullseye Release = "bullseye"
+	Buster   Release = "buster"
+	Jessie   Release = "jessie"
+	Stretch  Release = "stretch"
+	Wheezy   Release = "wheezy"
 )
 
 var AllReleases = map[Release]struct{}{
-	Buster:  struct{}{},
-	Jessie:  struct{}{},
-	Stretch: struct{}{},
-	Wheezy:  struct{}{},
+	Bookworm: struct{}{},
+	Bullseye: struct{}{},
+	Buster:   struct{}{},
+	Jessie:   struct{}{},
+	Stretch:  struct{}{},
+	Wheezy:   struct{}{},
 }
 
 var ReleaseToVersionID = map[Release]string{
-	Buster:  "10",
-	Jessie:  "8",
-	Stretch: "9",
-	Wheezy:  "7",
+	Bookworm: "12",
+	Bullseye: "11",
+	Buster:   "10",
+	Jessie:   "8",
+	Stretch:  "9",
+	Wheezy:   "7",
+}
+
+var bookwormDist = &claircore.Distribution{
+	PrettyName:      "Debian GNU/Linux 12 (bookworm)",
+	Name:            "Debian GNU/Linux",
+	VersionID:       "12",
+	Version:         "12 (bookworm)",
+	VersionCodeName: "bookworm",
+	DID:             "debian",
+}
+
+var bullseyeDist = &claircore.Distribution{
+	PrettyName:      "Debian GNU/Linux 11 (bullseye)",
+	Name:            "Debian GNU/Linux",
+	VersionID:       "11",
+	Version:         "11 (bullseye)",
+	VersionCodeName: "bullseye",
+	DID:             "debian",
 }
 
 var busterDist = &claircore.Distribution{
@@ -61,6 +85,10 @@ var wheezyDist = &claircore.Distribution{
 
 func releaseToDist(r Release) *claircore.Distribution {
 	switch r {
+	case Bookworm:
+		return bookwormDist
+	case Bullseye:
+		return bullseyeDist
 	case Buster:
 		return busterDist
 	case Jessie:
@@ -7,6 +7,8 @@ import (
 )
 
 var debianReleases = []Release{
+	Bookworm,
+	Bullseye,
 	Buster,
 	Jessie,
 	Stretch,
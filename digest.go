@@ -6,8 +6,10 @@ import (
 	"crypto/sha512"
 	"database/sql/driver"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
+	"strings"
 )
 
 const (
@@ -56,12 +58,17 @@ func (d Digest) MarshalText() ([]byte, error) {
 }
 
 // UnmarshalText implements encoding.TextUnmarshaler.
+//
+// Surrounding whitespace is ignored and the algorithm and checksum may be in
+// any case; the resulting Digest is always in the canonical lowercase form, so
+// digests that differ only in case compare equal.
 func (d *Digest) UnmarshalText(t []byte) error {
+	t = bytes.TrimSpace(t)
 	i := bytes.IndexByte(t, ':')
 	if i == -1 {
 		return &DigestError{msg: "invalid digest format"}
 	}
-	d.algo = string(t[:i])
+	d.algo = string(bytes.ToLower(t[:i]))
 	t = t[i+1:]
 	b := make([]byte, hex.DecodedLen(len(t)))
 	if _, err := hex.Decode(b, t); err != nil {
@@ -73,6 +80,9 @@ func (d *Digest) UnmarshalText(t []byte) error {
 	return d.setChecksum(b)
 }
 
+// ErrInvalidDigest is matched by every DigestError, for use with errors.Is.
+var ErrInvalidDigest = errors.New("claircore: invalid digest")
+
 // DigestError is the concrete type backing errors returned from Digest's
 // methods.
 type DigestError struct {
@@ -80,6 +90,11 @@ type DigestError struct {
 	inner error
 }
 
+// Is enables errors.Is, reporting true for ErrInvalidDigest.
+func (e *DigestError) Is(target error) bool {
+	return target == ErrInvalidDigest
+}
+
 // Error implements error.
 func (e *DigestError) Error() string {
 	return e.msg
@@ -117,6 +132,16 @@ func (d *Digest) setChecksum(b []byte) error {
 	return nil
 }
 
+// Validate reports a *DigestError if the Digest is not well-formed, such as
+// the zero Digest.
+func (d Digest) Validate() error {
+	if d.repr == "" {
+		return &DigestError{msg: "empty digest"}
+	}
+	_, err := NewDigest(d.algo, d.checksum)
+	return err
+}
+
 // Scan implements sql.Scanner.
 func (d *Digest) Scan(i interface{}) error {
 	switch v := i.(type) {
@@ -138,7 +163,7 @@ func (d Digest) Value() (driver.Value, error) {
 // NewDigest constructs a Digest.
 func NewDigest(algo string, sum []byte) (Digest, error) {
 	d := Digest{
-		algo: algo,
+		algo: strings.ToLower(algo),
 	}
 	return d, d.setChecksum(sum)
 }
@@ -0,0 +1,50 @@
+package claircore_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestDigestCanonical(t *testing.T) {
+	const want = `sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`
+	for _, in := range []string{
+		want,
+		`SHA256:5F70BF18A086007016E948B04AED3B82103A36BEA41755B6CDDFAF10ACE3C6EF`,
+		`Sha256:5f70bf18a086007016e948b04aed3b82103a36BEA41755B6CDDFAF10ACE3C6EF`,
+		" \t" + want + "\n",
+	} {
+		d, err := claircore.ParseDigest(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if got := d.String(); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+		if err := d.Validate(); err != nil {
+			t.Errorf("%q: %v", in, err)
+		}
+	}
+}
+
+func TestDigestInvalid(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`sha256`,
+		`md5:d41d8cd98f00b204e9800998ecf8427e`,
+		`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6`,
+		`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6eg`,
+		`sha256: 5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`,
+	} {
+		_, err := claircore.ParseDigest(in)
+		if !errors.Is(err, claircore.ErrInvalidDigest) {
+			t.Errorf("%q: got error %v, want %v", in, err, claircore.ErrInvalidDigest)
+		}
+	}
+	var d claircore.Digest
+	if err := d.Validate(); !errors.Is(err, claircore.ErrInvalidDigest) {
+		t.Errorf("zero Digest: got error %v, want %v", err, claircore.ErrInvalidDigest)
+	}
+}
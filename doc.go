@@ -2,5 +2,11 @@
 Package claircore has foundational types for the claircore module.
 
 Additional documentation can be found at http://quay.github.io/claircore/
+
+The exported API of this package, along with libindex, libvuln, and
+libvuln/driver, is what programs embedding claircore build against, and is
+kept compatible between releases. Changes to it are checked against a
+snapshot in internal/apisurface; see the contributor documentation for how to
+update it.
 */
 package claircore
@@ -6,6 +6,7 @@ import (
 	"github.com/quay/claircore/debian"
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/pkg/purl"
 	"github.com/quay/claircore/ubuntu"
 )
 
@@ -25,7 +26,7 @@ func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 			return []indexer.RepositoryScanner{}, nil
 		},
 		Coalescer: func(ctx context.Context) (indexer.Coalescer, error) {
-			return linux.NewCoalescer(), nil
+			return linux.NewCoalescer(purl.Deb), nil
 		},
 	}
 }
@@ -0,0 +1,109 @@
+package dpkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/claircore/internal/license"
+)
+
+// CopyrightPackage reports the package whose copyright file "h" is, if it's
+// one in the documentation directory "docs".
+func copyrightPackage(docs string, h *tar.Header) (string, bool) {
+	if h.Typeflag != tar.TypeReg {
+		// Packages built from the same source often link to one file.
+		return "", false
+	}
+	n := filepath.Clean(h.Name)
+	if filepath.Base(n) != "copyright" || filepath.Dir(filepath.Dir(n)) != docs {
+		return "", false
+	}
+	return filepath.Base(filepath.Dir(n)), true
+}
+
+// CopyrightLicense returns the license declared in a machine-readable
+// copyright file, or an empty string if the file isn't machine-readable.
+//
+// See https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/ for
+// the format. The license of the header paragraph is used if it has one, and
+// the licenses of the "Files" paragraphs otherwise. Only the short names on
+// the first line of each "License" field are used, with the format's "and",
+// "or", and "with" operators written the SPDX way.
+func copyrightLicense(r io.Reader) string {
+	var (
+		header  string   // License of the header paragraph.
+		ls      []string // Licenses of the "Files" paragraphs.
+		para    int      // Index of the current paragraph.
+		started bool     // Whether the current paragraph has any fields.
+		format  bool     // Whether the header has a "Format" field.
+		files   bool     // Whether the current paragraph has a "Files" field.
+		lic     string   // License of the current paragraph.
+	)
+	end := func() {
+		switch {
+		case para == 0:
+			header = lic
+		case files && lic != "":
+			ls = append(ls, lic)
+		}
+		para++
+		started, files, lic = false, false, ""
+	}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			if started {
+				end()
+				if !format {
+					// Not machine-readable.
+					return ""
+				}
+			}
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			// Continuation lines hold the license text, which isn't used.
+			continue
+		}
+		started = true
+		i := strings.IndexByte(line, ':')
+		if i == -1 {
+			continue
+		}
+		switch strings.ToLower(line[:i]) {
+		case "format":
+			format = format || para == 0
+		case "files":
+			files = true
+		case "license":
+			lic = spdxOperators(line[i+1:])
+		}
+	}
+	if started {
+		end()
+	}
+	if s.Err() != nil || !format {
+		return ""
+	}
+	if header != "" {
+		return header
+	}
+	return license.Join(ls)
+}
+
+// SpdxOperators rewrites the lowercase operators the copyright format uses to
+// the SPDX ones.
+func spdxOperators(s string) string {
+	fs := strings.Fields(s)
+	for i, f := range fs {
+		switch f {
+		case "and", "or", "with":
+			fs[i] = strings.ToUpper(f)
+		}
+	}
+	return strings.Join(fs, " ")
+}
@@ -22,12 +22,13 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/debkernel"
 )
 
 const (
 	name    = "dpkg"
 	kind    = "package"
-	version = "v0.0.2"
+	version = "v0.0.4"
 )
 
 var (
@@ -168,7 +169,19 @@ Find:
 				Arch:      msg.Header.Get("Architecture"),
 				PackageDB: fn,
 			}
-			if src := msg.Header.Get("Source"); src != "" {
+			src := msg.Header.Get("Source")
+			switch ksrc, ok := debkernel.Source(name, src); {
+			case ok:
+				// Kernel packages have the ABI in their names and come from
+				// wrapper source packages, so record the kernel source
+				// package vulnerabilities are filed against instead.
+				p.Source = &claircore.Package{
+					Name:      ksrc,
+					Kind:      claircore.SOURCE,
+					Version:   debkernel.Version(v),
+					PackageDB: fn,
+				}
+			case src != "":
 				p.Source = &claircore.Package{
 					Name: src,
 					Kind: claircore.SOURCE,
@@ -194,7 +207,16 @@ Find:
 		tr = tar.NewReader(r)
 		prefix := filepath.Join(p, "info") + string(filepath.Separator)
 		const suffix = ".md5sums"
+		// The database is in "var/lib/dpkg", so the documentation directory
+		// is relative to three levels up.
+		docs := filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(p))), "usr", "share", "doc")
 		for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+			if n, ok := copyrightPackage(docs, h); ok {
+				if p, ok := found[n]; ok {
+					p.License = copyrightLicense(tr)
+				}
+				continue
+			}
 			if !strings.HasPrefix(h.Name, prefix) || !strings.HasSuffix(h.Name, suffix) {
 				continue
 			}
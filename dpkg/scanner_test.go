@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -820,8 +823,11 @@ func TestScanner(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(got, want) {
-		t.Fatal(cmp.Diff(got, want))
+	// The expected packages predate recording licenses, which are checked
+	// by TestLicense.
+	opt := cmpopts.IgnoreFields(claircore.Package{}, "License")
+	if !cmp.Equal(got, want, opt) {
+		t.Fatal(cmp.Diff(got, want, opt))
 	}
 }
 
@@ -965,3 +971,147 @@ Version: 1
 		t.Error(err)
 	}
 }
+
+func TestKernelPackages(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	type src struct {
+		Name, Version string
+	}
+	tt := []struct {
+		fixture string
+		want    map[string]src
+	}{
+		{
+			fixture: "generic",
+			want: map[string]src{
+				"linux-image-5.15.0-89-generic":   {"linux", "5.15.0-89.99"},
+				"linux-modules-5.15.0-89-generic": {"linux", "5.15.0-89.99"},
+				"linux-image-generic":             {"linux", "5.15.0-89"},
+				"linux-firmware":                  {},
+			},
+		},
+		{
+			fixture: "hwe",
+			want: map[string]src{
+				"linux-image-5.15.0-89-generic":   {"linux-hwe-5.15", "5.15.0-89.99~20.04.1"},
+				"linux-modules-5.15.0-89-generic": {"linux-hwe-5.15", "5.15.0-89.99~20.04.1"},
+				"linux-image-generic-hwe-20.04":   {"linux-hwe-5.15", "5.15.0-89"},
+			},
+		},
+		{
+			fixture: "aws",
+			want: map[string]src{
+				"linux-image-5.15.0-1049-aws":   {"linux-aws", "5.15.0-1049.54"},
+				"linux-modules-5.15.0-1049-aws": {"linux-aws", "5.15.0-1049.54"},
+				"linux-image-aws":               {"linux-aws", "5.15.0-1049"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.fixture, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			l := claircore.Layer{
+				Hash: claircore.MustParseDigest(`sha256:25fd87072f39aaebd1ee24dca825e61d9f5a0f87966c01551d31a4d8d79d37d8`),
+				URI:  "file:///dev/null",
+			}
+			l.SetLocal(statusLayer(t, filepath.Join("testdata", "kernel", tc.fixture)))
+
+			ps, err := new(Scanner).Scan(ctx, &l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]src)
+			for _, p := range ps {
+				var s src
+				if p.Source != nil {
+					s = src{p.Source.Name, p.Source.Version}
+				}
+				got[p.Name] = s
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+// StatusLayer writes a layer containing a dpkg database with the provided
+// status file, returning its path. The optional "files" map is of paths in
+// the layer to files whose contents are written there.
+func statusLayer(t *testing.T, status string, files ...map[string]string) string {
+	t.Helper()
+	read := func(n string) []byte {
+		b, err := ioutil.ReadFile(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	f, err := ioutil.TempFile("", "dpkg.layer.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	add := func(n string, b []byte) {
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     n,
+			Size:     int64(len(b)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("var/lib/dpkg/available", nil)
+	add("var/lib/dpkg/status", read(status))
+	for _, fs := range files {
+		for n, src := range fs {
+			add(n, read(src))
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLicense(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := claircore.Layer{
+		Hash: claircore.MustParseDigest(`sha256:25fd87072f39aaebd1ee24dca825e61d9f5a0f87966c01551d31a4d8d79d37d8`),
+		URI:  "file:///dev/null",
+	}
+	files := make(map[string]string)
+	for _, n := range []string{"base-files", "libssl3", "libgcc-s1", "tzdata"} {
+		files["usr/share/doc/"+n+"/copyright"] = filepath.Join("testdata", "license", "doc", n)
+	}
+	l.SetLocal(statusLayer(t, filepath.Join("testdata", "license", "status"), files))
+
+	ps, err := new(Scanner).Scan(ctx, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.License
+	}
+	want := map[string]string{
+		// Not machine-readable.
+		"base-files": "",
+		// The header's license is used.
+		"libssl3": "Apache-2.0",
+		// The "Files" paragraphs' licenses are joined, and stand-alone
+		// license paragraphs are ignored.
+		"tzdata":    "public-domain AND (GPL-2+ OR BSD-3-clause)",
+		"libgcc-s1": "(GPL-3+ WITH GCC-exception-3.1) AND LGPL-2.1+",
+		// No copyright file.
+		"libgcc-s1-compat": "",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 var (
 	_ driver.Enricher          = (*Enricher)(nil)
 	_ driver.EnrichmentUpdater = (*Enricher)(nil)
+	_ driver.DataSourcer       = (*Enricher)(nil)
 
 	defaultFeed *url.URL
 )
@@ -106,6 +108,17 @@ func gzURL(root *url.URL, yr int) (*url.URL, error) {
 // Name implements driver.Enricher and driver.EnrichmentUpdater.
 func (*Enricher) Name() string { return name }
 
+// DataSource implements driver.DataSourcer.
+//
+// NVD data isn't licensed, but its terms of use ask for a notice that the
+// product uses NVD data without being endorsed by it.
+func (*Enricher) DataSource() claircore.DataSource {
+	return claircore.DataSource{
+		Name: "National Vulnerability Database",
+		URL:  "https://nvd.nist.gov/developers/terms-of-use",
+	}
+}
+
 // FetchEnrichment implements driver.EnrichmentUpdater.
 func (e *Enricher) FetchEnrichment(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
 	ctx = baggage.ContextWithValues(ctx,
@@ -256,18 +269,39 @@ func (e *Enricher) Enrich(ctx context.Context, g driver.EnrichmentGetter, r *cla
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "enricher/cvss/Enricher/Enrich"))
 
-	// We return any CVSS blobs for CVEs mentioned in the free-form parts of the
-	// vulnerability.
+	// Vulnerabilities that weren't reported with their aliases have them
+	// looked up, if the store records them.
+	var resolved map[string][]string
+	if ar, ok := g.(driver.AliasResolver); ok {
+		var names []string
+		for _, v := range r.Vulnerabilities {
+			if len(v.Aliases) == 0 {
+				names = append(names, v.Name)
+			}
+		}
+		if len(names) != 0 {
+			var err error
+			resolved, err = ar.ResolveAliases(ctx, names)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	// We return any CVSS blobs for CVEs the vulnerability is known as. If it
+	// has no aliases, the CVEs mentioned in its free-form parts are used.
 	m := make(map[string][]json.RawMessage)
 	for id, v := range r.Vulnerabilities {
 		t := make(map[string]struct{})
 		ctx := baggage.ContextWithValues(ctx,
 			label.String("vuln", v.Name))
-		for _, elem := range []string{
-			v.Description,
-			v.Name,
-			v.Links,
-		} {
+		elems := []string{v.Name}
+		elems = append(elems, v.Aliases...)
+		elems = append(elems, resolved[v.Name]...)
+		if len(elems) == 1 {
+			elems = append(elems, v.Description, v.Links)
+		}
+		for _, elem := range elems {
 			for _, m := range cveRegexp.FindAllString(elem, -1) {
 				t[m] = struct{}{}
 			}
@@ -289,8 +323,18 @@ func (e *Enricher) Enrich(ctx context.Context, g driver.EnrichmentGetter, r *cla
 		zlog.Debug(ctx).
 			Int("count", len(rec)).
 			Msg("found records")
-		for _, r := range rec {
-			m[id] = append(m[id], r.Enrichment)
+		// A record carrying several of the CVEs is only reported once.
+		sort.Strings(ts)
+		byTag := driver.MergeEnrichments(rec, ts)
+		seen := make(map[string]struct{})
+		for _, t := range ts {
+			for _, b := range byTag[t] {
+				if _, ok := seen[string(b)]; ok {
+					continue
+				}
+				seen[string(b)] = struct{}{}
+				m[id] = append(m[id], b)
+			}
 		}
 	}
 	if len(m) == 0 {
@@ -363,3 +363,90 @@ func (f *fakeGetter) GetEnrichment(ctx context.Context, tags []string) ([]driver
 	}
 	return nil, nil
 }
+
+func TestEnrichAliases(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(nil, t)
+	feedIn, err := os.Open("testdata/feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := newItemFeed(2021, feedIn)
+	if err != nil {
+		t.Error(err)
+	}
+	g := &aliasGetter{
+		fakeGetter: &fakeGetter{itemFeed: f},
+		aliases: map[string][]string{
+			"GHSA-aaaa-bbbb-cccc": {"CVE-2021-32554"},
+		},
+	}
+	r := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			// The description is ignored, because the vulnerability has aliases.
+			"declared": &claircore.Vulnerability{
+				Name:        "RHSA-2021:0001",
+				Aliases:     []string{"CVE-2021-0498"},
+				Description: "This also mentions CVE-2021-32555.",
+			},
+			"resolved": &claircore.Vulnerability{
+				Name: "GHSA-aaaa-bbbb-cccc",
+			},
+		},
+	}
+	e := &Enricher{}
+	_, es, err := e.Enrich(ctx, g, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 {
+		t.Fatalf("got %d enrichments, want 1", len(es))
+	}
+	got := map[string][]map[string]interface{}{}
+	if err := json.Unmarshal(es[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	vector := func(id string) string {
+		for _, cve := range f.items {
+			if cve.CVE.Meta.ID == id && cve.Impact.V3.CVSS != nil {
+				var v struct {
+					VectorString string `json:"vectorString"`
+				}
+				if err := json.Unmarshal(cve.Impact.V3.CVSS, &v); err != nil {
+					t.Fatal(err)
+				}
+				return v.VectorString
+			}
+		}
+		t.Fatalf("no CVSS for %s", id)
+		return ""
+	}
+	for id, cve := range map[string]string{
+		"declared": "CVE-2021-0498",
+		"resolved": "CVE-2021-32554",
+	} {
+		if len(got[id]) != 1 {
+			t.Errorf("%s: got %d records, want 1", id, len(got[id]))
+			continue
+		}
+		if got, want := got[id][0]["vectorString"], vector(cve); got != want {
+			t.Errorf("%s: got: %q, want: %q", id, got, want)
+		}
+	}
+}
+
+// AliasGetter is a fakeGetter that also resolves aliases.
+type aliasGetter struct {
+	*fakeGetter
+	aliases map[string][]string
+}
+
+func (a *aliasGetter) ResolveAliases(ctx context.Context, names []string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, n := range names {
+		if as, ok := a.aliases[n]; ok {
+			out[n] = as
+		}
+	}
+	return out, nil
+}
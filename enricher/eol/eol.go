@@ -0,0 +1,248 @@
+// Package eol provides an enricher that flags distributions that are past
+// their end of life.
+//
+// A report for an image based on an end-of-life distribution can look clean
+// only because nobody publishes vulnerability data for it anymore. This
+// enricher makes that visible.
+package eol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Enricher          = (*Enricher)(nil)
+	_ driver.EnrichmentUpdater = (*Enricher)(nil)
+	_ driver.Configurable      = (*Enricher)(nil)
+)
+
+const (
+	// Type is the type of data returned from the Enricher's Enrich method.
+	//
+	// The data is a map of distribution ID to Entry.
+	Type = `message/vnd.clair.map.distribution; enricher=clair.eol`
+	// DefaultAPI is the default place to fetch end-of-life data from.
+	//
+	// The enricher expects the endoflife.date API: a JSON array of release
+	// cycles at "<product>.json".
+	DefaultAPI = `https://endoflife.date/api/`
+
+	name = `clair.eol`
+
+	// DateFormat is the layout of end-of-life dates.
+	dateFormat = `2006-01-02`
+)
+
+// Entry is reported for every distribution past its end of life.
+type Entry struct {
+	ID        string `json:"id"`
+	VersionID string `json:"version_id"`
+	// EOL is the end-of-life date, in YYYY-MM-DD form.
+	EOL string `json:"eol"`
+	// Warning is a human-readable explanation. There's deliberately no
+	// severity: this isn't a vulnerability.
+	Warning string `json:"warning"`
+}
+
+// Enricher reports distributions that are past their end of life.
+//
+// Configured overrides are consulted first, then the database, then a table
+// built into this package. The zero value can be used to enrich reports with
+// just the builtin table; Configure must be called before using it as an
+// EnrichmentUpdater.
+type Enricher struct {
+	driver.NoopUpdater
+	c         *http.Client
+	api       *url.URL
+	products  []string
+	overrides map[string]map[string]string
+	// Now is used in tests.
+	now func() time.Time
+}
+
+// Config is the configuration for Enricher.
+type Config struct {
+	// API is the root of an endoflife.date compatible API.
+	API *string `json:"api" yaml:"api"`
+	// Products is the list of products to fetch. The product names are
+	// expected to be the os-release "ID" of the distribution.
+	Products []string `json:"products" yaml:"products"`
+	// Overrides are keyed by os-release "ID", then "VERSION_ID". Values are
+	// dates in YYYY-MM-DD form. These take precedence over the database and
+	// the builtin table.
+	Overrides map[string]map[string]string `json:"overrides" yaml:"overrides"`
+}
+
+// Configure implements driver.Configurable.
+func (e *Enricher) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	var cfg Config
+	e.c = c
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	api := DefaultAPI
+	if cfg.API != nil {
+		api = *cfg.API
+		if !strings.HasSuffix(api, "/") {
+			return fmt.Errorf("URL missing trailing slash: %q", api)
+		}
+	}
+	u, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+	e.api = u
+	e.products = cfg.Products
+	if len(e.products) == 0 {
+		for id := range builtin {
+			e.products = append(e.products, id)
+		}
+		sort.Strings(e.products)
+	}
+	for id, vs := range cfg.Overrides {
+		for v, d := range vs {
+			if _, err := time.Parse(dateFormat, d); err != nil {
+				return fmt.Errorf("bad override for %s %s: %w", id, v, err)
+			}
+		}
+	}
+	e.overrides = cfg.Overrides
+	return nil
+}
+
+// Name implements driver.Enricher and driver.EnrichmentUpdater.
+func (*Enricher) Name() string { return name }
+
+// Record is the format of the stored enrichment records.
+type record struct {
+	ID        string `json:"id"`
+	VersionID string `json:"version_id"`
+	EOL       string `json:"eol"`
+}
+
+// Tag returns the tag used to store and query a release.
+func tag(id, version string) string {
+	return id + ":" + version
+}
+
+// Candidates returns the versions to look up for a VERSION_ID, most specific
+// first. Point releases fall back to their major release, so "8.4" checks "8"
+// and "3.12.1" checks "3.12" and "3".
+func candidates(version string) []string {
+	var ret []string
+	for v := version; v != ""; {
+		ret = append(ret, v)
+		i := strings.LastIndexByte(v, '.')
+		if i == -1 {
+			break
+		}
+		v = v[:i]
+	}
+	return ret
+}
+
+// Enrich implements driver.Enricher.
+func (e *Enricher) Enrich(ctx context.Context, g driver.EnrichmentGetter, r *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/eol/Enricher/Enrich"))
+
+	var tags []string
+	for _, d := range r.Distributions {
+		if d.DID == "" {
+			continue
+		}
+		for _, v := range candidates(d.VersionID) {
+			tags = append(tags, tag(d.DID, v))
+		}
+	}
+	if len(tags) == 0 {
+		return Type, nil, nil
+	}
+	recs, err := g.GetEnrichment(ctx, tags)
+	if err != nil {
+		return "", nil, err
+	}
+	stored := make(map[string]string, len(recs))
+	for _, rec := range recs {
+		var v record
+		if err := json.Unmarshal(rec.Enrichment, &v); err != nil {
+			return "", nil, err
+		}
+		stored[tag(v.ID, v.VersionID)] = v.EOL
+	}
+	zlog.Debug(ctx).
+		Int("count", len(stored)).
+		Msg("found records")
+
+	now := time.Now
+	if e.now != nil {
+		now = e.now
+	}
+	today := now().UTC().Format(dateFormat)
+	m := make(map[string]Entry)
+	for id, d := range r.Distributions {
+		if d.DID == "" {
+			continue
+		}
+		date, ok := e.lookup(stored, d)
+		if !ok || date > today {
+			continue
+		}
+		m[id] = Entry{
+			ID:        d.DID,
+			VersionID: d.VersionID,
+			EOL:       date,
+			Warning: fmt.Sprintf("%s reached end of life on %s; vulnerability data may no longer be published for it",
+				displayName(d), date),
+		}
+	}
+	if len(m) == 0 {
+		return Type, nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Type, nil, err
+	}
+	return Type, []json.RawMessage{b}, nil
+}
+
+// Lookup finds the end-of-life date for the distribution.
+func (e *Enricher) lookup(stored map[string]string, d *claircore.Distribution) (string, bool) {
+	for _, v := range candidates(d.VersionID) {
+		if date, ok := e.overrides[d.DID][v]; ok {
+			return date, true
+		}
+		if date, ok := stored[tag(d.DID, v)]; ok {
+			return date, true
+		}
+		if date, ok := builtin[d.DID][v]; ok {
+			return date, true
+		}
+	}
+	return "", false
+}
+
+func displayName(d *claircore.Distribution) string {
+	switch {
+	case d.PrettyName != "":
+		return d.PrettyName
+	case d.Name != "" && d.Version != "":
+		return d.Name + " " + d.Version
+	default:
+		return d.DID + " " + d.VersionID
+	}
+}
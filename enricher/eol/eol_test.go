@@ -0,0 +1,240 @@
+package eol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// FakeGetter serves the provided records by tag.
+type fakeGetter map[string]record
+
+func (g fakeGetter) GetEnrichment(_ context.Context, tags []string) ([]driver.EnrichmentRecord, error) {
+	var ret []driver.EnrichmentRecord
+	for _, t := range tags {
+		r, ok := g[t]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, driver.EnrichmentRecord{Tags: []string{t}, Enrichment: b})
+	}
+	return ret, nil
+}
+
+func TestEnrich(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	at := func(y int, m time.Month, d int) func() time.Time {
+		return func() time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	}
+	stretch := &claircore.Distribution{
+		DID:        "debian",
+		VersionID:  "9",
+		PrettyName: "Debian GNU/Linux 9 (stretch)",
+	}
+	bookworm := &claircore.Distribution{
+		DID:        "debian",
+		VersionID:  "12",
+		PrettyName: "Debian GNU/Linux 12 (bookworm)",
+	}
+	rhel := &claircore.Distribution{
+		DID:       "rhel",
+		VersionID: "8.4",
+		Name:      "Red Hat Enterprise Linux",
+		Version:   "8.4 (Ootpa)",
+	}
+	unknown := &claircore.Distribution{
+		DID:       "plan9",
+		VersionID: "4",
+	}
+
+	tt := []struct {
+		Name     string
+		Enricher Enricher
+		Getter   fakeGetter
+		Dists    map[string]*claircore.Distribution
+		Want     map[string]Entry
+	}{
+		{
+			Name:     "Builtin",
+			Enricher: Enricher{now: at(2024, 1, 1)},
+			Dists:    map[string]*claircore.Distribution{"1": stretch, "2": bookworm, "3": unknown},
+			Want: map[string]Entry{
+				"1": {
+					ID:        "debian",
+					VersionID: "9",
+					EOL:       "2022-06-30",
+					Warning:   "Debian GNU/Linux 9 (stretch) reached end of life on 2022-06-30; vulnerability data may no longer be published for it",
+				},
+			},
+		},
+		{
+			Name:     "Supported",
+			Enricher: Enricher{now: at(2024, 1, 1)},
+			Dists:    map[string]*claircore.Distribution{"2": bookworm},
+		},
+		{
+			Name:     "Stored",
+			Enricher: Enricher{now: at(2024, 1, 1)},
+			Getter: fakeGetter{
+				"debian:12": {ID: "debian", VersionID: "12", EOL: "2023-06-01"},
+			},
+			Dists: map[string]*claircore.Distribution{"2": bookworm},
+			Want: map[string]Entry{
+				"2": {
+					ID:        "debian",
+					VersionID: "12",
+					EOL:       "2023-06-01",
+					Warning:   "Debian GNU/Linux 12 (bookworm) reached end of life on 2023-06-01; vulnerability data may no longer be published for it",
+				},
+			},
+		},
+		{
+			Name: "Override",
+			Enricher: Enricher{
+				now:       at(2024, 1, 1),
+				overrides: map[string]map[string]string{"debian": {"9": "2030-01-01"}},
+			},
+			Getter: fakeGetter{
+				"debian:9": {ID: "debian", VersionID: "9", EOL: "2020-07-18"},
+			},
+			Dists: map[string]*claircore.Distribution{"1": stretch},
+		},
+		{
+			Name:     "PointRelease",
+			Enricher: Enricher{now: at(2030, 1, 1)},
+			Dists:    map[string]*claircore.Distribution{"1": rhel},
+			Want: map[string]Entry{
+				"1": {
+					ID:        "rhel",
+					VersionID: "8.4",
+					EOL:       "2029-05-31",
+					Warning:   "Red Hat Enterprise Linux 8.4 (Ootpa) reached end of life on 2029-05-31; vulnerability data may no longer be published for it",
+				},
+			},
+		},
+		{
+			Name:     "EOLDay",
+			Enricher: Enricher{now: at(2022, 6, 30)},
+			Dists:    map[string]*claircore.Distribution{"1": stretch},
+			Want: map[string]Entry{
+				"1": {
+					ID:        "debian",
+					VersionID: "9",
+					EOL:       "2022-06-30",
+					Warning:   "Debian GNU/Linux 9 (stretch) reached end of life on 2022-06-30; vulnerability data may no longer be published for it",
+				},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			vr := &claircore.VulnerabilityReport{Distributions: tc.Dists}
+			kind, es, err := tc.Enricher.Enrich(ctx, tc.Getter, vr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != Type {
+				t.Errorf("got: %q, want: %q", kind, Type)
+			}
+			if tc.Want == nil {
+				if len(es) != 0 {
+					t.Errorf("unexpected enrichments: %s", es)
+				}
+				return
+			}
+			if len(es) != 1 {
+				t.Fatalf("got %d enrichments, want 1", len(es))
+			}
+			var got map[string]Entry
+			if err := json.Unmarshal(es[0], &got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+func TestFetchParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := httptest.NewServer(http.StripPrefix("/api/", http.FileServer(http.Dir("testdata"))))
+	defer srv.Close()
+
+	var e Enricher
+	api := srv.URL + "/api/"
+	err := e.Configure(ctx, func(v interface{}) error {
+		cfg := v.(*Config)
+		cfg.API = &api
+		cfg.Products = []string{"alpine", "debian", "missing"}
+		return nil
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, fp, err := e.FetchEnrichment(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := e.ParseEnrichment(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range rs {
+		var v record
+		if err := json.Unmarshal(r.Enrichment, &v); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{tag(v.ID, v.VersionID)}; !cmp.Equal(r.Tags, want) {
+			t.Error(cmp.Diff(r.Tags, want))
+		}
+		got = append(got, tag(v.ID, v.VersionID)+"="+v.EOL)
+	}
+	sort.Strings(got)
+	want := []string{
+		"alpine:3.19=2025-11-01",
+		"alpine:3.20=2026-04-01",
+		"debian:10=2022-09-10",
+		"debian:11=2024-08-14",
+		"debian:12=2026-06-10",
+		"debian:9=2020-07-18",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, _, err := e.FetchEnrichment(ctx, fp); !errors.Is(err, driver.Unchanged) {
+		t.Errorf("got: %v, want: %v", err, driver.Unchanged)
+	}
+}
+
+func TestConfigureBadOverride(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var e Enricher
+	err := e.Configure(ctx, func(v interface{}) error {
+		cfg := v.(*Config)
+		cfg.Overrides = map[string]map[string]string{"debian": {"9": "June 2022"}}
+		return nil
+	}, http.DefaultClient)
+	if err == nil {
+		t.Error("expected error for malformed date")
+	}
+}
@@ -0,0 +1,131 @@
+package eol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// FetchEnrichment implements driver.EnrichmentUpdater.
+//
+// The data for every configured product is fetched and combined into a single
+// JSON object keyed by product. The Fingerprint is a digest of that object.
+func (e *Enricher) FetchEnrichment(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/eol/Enricher/FetchEnrichment"))
+	if e.api == nil || e.c == nil {
+		return nil, hint, fmt.Errorf("eol: enricher not configured")
+	}
+
+	all := make(map[string]json.RawMessage, len(e.products))
+	for _, p := range e.products {
+		u, err := e.api.Parse(p + ".json")
+		if err != nil {
+			return nil, hint, fmt.Errorf("bad URL: %w", err)
+		}
+		zlog.Debug(ctx).
+			Str("product", p).
+			Stringer("url", u).
+			Msg("fetching release cycles")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, hint, fmt.Errorf("unable to create request: %w", err)
+		}
+		res, err := e.c.Do(req)
+		if err != nil {
+			return nil, hint, fmt.Errorf("unable to do request: %w", err)
+		}
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, res.Body)
+		res.Body.Close() // Don't defer because we're in a loop.
+		if err != nil {
+			return nil, hint, err
+		}
+		switch res.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			zlog.Info(ctx).
+				Str("product", p).
+				Msg("no data for product")
+			continue
+		default:
+			return nil, hint, fmt.Errorf("eol: unexpected response for %q: %s", p, res.Status)
+		}
+		all[p] = json.RawMessage(buf.Bytes())
+	}
+
+	// Map keys are marshaled in sorted order, so this is stable.
+	b, err := json.Marshal(all)
+	if err != nil {
+		return nil, hint, err
+	}
+	sum := sha256.Sum256(b)
+	fp := driver.Fingerprint(hex.EncodeToString(sum[:]))
+	if fp == hint {
+		return nil, hint, driver.Unchanged
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), fp, nil
+}
+
+// Cycle is a release cycle as reported by the endoflife.date API.
+//
+// Both members may be strings, numbers, or booleans.
+type cycle struct {
+	Cycle json.RawMessage `json:"cycle"`
+	EOL   json.RawMessage `json:"eol"`
+}
+
+// String unquotes a JSON string, or returns the literal value.
+func str(m json.RawMessage) string {
+	if s, err := strconv.Unquote(string(m)); err == nil {
+		return s
+	}
+	return string(m)
+}
+
+// ParseEnrichment implements driver.EnrichmentUpdater.
+func (e *Enricher) ParseEnrichment(ctx context.Context, rc io.ReadCloser) ([]driver.EnrichmentRecord, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/eol/Enricher/ParseEnrichment"))
+	defer rc.Close()
+	var all map[string][]cycle
+	if err := json.NewDecoder(rc).Decode(&all); err != nil {
+		return nil, err
+	}
+	var ret []driver.EnrichmentRecord
+	for id, cs := range all {
+		for _, c := range cs {
+			v, d := str(c.Cycle), str(c.EOL)
+			// The "eol" member is "false" for supported releases, and may be
+			// "true" with no date. Neither is useful.
+			if v == "" || len(c.EOL) == 0 || c.EOL[0] != '"' {
+				continue
+			}
+			b, err := json.Marshal(record{ID: id, VersionID: v, EOL: d})
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, driver.EnrichmentRecord{
+				Tags:       []string{tag(id, v)},
+				Enrichment: b,
+			})
+		}
+	}
+	zlog.Debug(ctx).
+		Int("count", len(ret)).
+		Msg("decoded enrichments")
+	return ret, nil
+}
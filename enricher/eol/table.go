@@ -0,0 +1,48 @@
+package eol
+
+// Builtin is the end-of-life table used when the database has no data for a
+// distribution release.
+//
+// It's keyed by os-release "ID", then "VERSION_ID". Dates are the end of all
+// security support published by the distribution, including LTS programs,
+// in YYYY-MM-DD form.
+var builtin = map[string]map[string]string{
+	"alpine": {
+		"3.10": "2021-05-01",
+		"3.11": "2021-11-01",
+		"3.12": "2022-05-01",
+		"3.13": "2022-11-01",
+		"3.14": "2023-05-01",
+		"3.15": "2023-11-01",
+		"3.16": "2024-05-23",
+		"3.17": "2024-11-22",
+		"3.18": "2025-05-09",
+	},
+	"centos": {
+		"6": "2020-11-30",
+		"7": "2024-06-30",
+		"8": "2021-12-31",
+	},
+	"debian": {
+		"7":  "2018-05-31",
+		"8":  "2020-06-30",
+		"9":  "2022-06-30",
+		"10": "2024-06-30",
+		"11": "2026-08-31",
+		"12": "2028-06-30",
+	},
+	"rhel": {
+		"6": "2020-11-30",
+		"7": "2024-06-30",
+		"8": "2029-05-31",
+		"9": "2032-05-31",
+	},
+	"ubuntu": {
+		"14.04": "2019-04-30",
+		"16.04": "2021-04-30",
+		"18.04": "2023-05-31",
+		"20.04": "2025-05-29",
+		"22.04": "2027-06-01",
+		"24.04": "2029-05-31",
+	},
+}
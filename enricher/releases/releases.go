@@ -0,0 +1,215 @@
+// Package releases provides an enricher that reports whether other releases
+// of a distribution have a fix for a vulnerability.
+//
+// This answers questions like "is this fixed in the next LTS, even though my
+// release has no fix?" The enricher uses the vulnerability data already in
+// the store, so it has nothing to fetch.
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Enricher          = (*Enricher)(nil)
+	_ driver.EnrichmentUpdater = (*Enricher)(nil)
+)
+
+const (
+	// Type is the type of data returned from the Enricher's Enrich method.
+	//
+	// The data is a map of vulnerability ID to a list of Fix.
+	Type = `message/vnd.clair.map.vulnerability; enricher=clair.releases`
+
+	name = `clair.releases`
+)
+
+// Fix is reported for every later release of a distribution that has a fix
+// for a vulnerability.
+type Fix struct {
+	// VersionID is the os-release "VERSION_ID" of the release.
+	VersionID string `json:"version_id"`
+	// VersionCodeName is the os-release "VERSION_CODENAME" of the release,
+	// if known.
+	VersionCodeName string `json:"version_code_name,omitempty"`
+	// FixedInVersion is the version of the package that has the fix.
+	FixedInVersion string `json:"fixed_in_version"`
+}
+
+// Enricher reports fixes in later releases of a distribution for
+// vulnerabilities that have no fix in the affected release.
+//
+// The Enricher needs the EnrichmentGetter it's provided to also implement
+// driver.VulnerabilityGetter. The zero value is ready to use.
+type Enricher struct {
+	driver.NoopUpdater
+}
+
+// Name implements driver.Enricher and driver.EnrichmentUpdater.
+func (*Enricher) Name() string { return name }
+
+// FetchEnrichment implements driver.EnrichmentUpdater.
+//
+// There's no external data, so this always reports driver.Unchanged.
+func (*Enricher) FetchEnrichment(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", driver.Unchanged
+}
+
+// ParseEnrichment implements driver.EnrichmentUpdater.
+func (*Enricher) ParseEnrichment(context.Context, io.ReadCloser) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+// ErrNoVulnerabilityGetter is returned by Enrich if the provided
+// EnrichmentGetter can't look up vulnerabilities.
+var ErrNoVulnerabilityGetter = errors.New("releases: getter does not implement driver.VulnerabilityGetter")
+
+// Enrich implements driver.Enricher.
+func (*Enricher) Enrich(ctx context.Context, g driver.EnrichmentGetter, r *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/releases/Enricher/Enrich"))
+	vg, ok := g.(driver.VulnerabilityGetter)
+	if !ok {
+		return "", nil, ErrNoVulnerabilityGetter
+	}
+
+	// Collect the distinct queries for all unfixed vulnerabilities.
+	var qs []driver.VulnerabilityQuery
+	idx := make(map[driver.VulnerabilityQuery]int)
+	want := make(map[string]int) // vulnerability ID → query index
+	for id, v := range r.Vulnerabilities {
+		if v.FixedInVersion != "" || v.Package == nil || v.Dist == nil ||
+			v.Package.Name == "" || v.Dist.DID == "" {
+			continue
+		}
+		if _, ok := splitVersion(v.Dist.VersionID); !ok {
+			continue
+		}
+		q := driver.VulnerabilityQuery{
+			Name:           v.Name,
+			Package:        v.Package.Name,
+			DistributionID: v.Dist.DID,
+		}
+		i, ok := idx[q]
+		if !ok {
+			i = len(qs)
+			idx[q] = i
+			qs = append(qs, q)
+		}
+		want[id] = i
+	}
+	if len(qs) == 0 {
+		return Type, nil, nil
+	}
+	res, err := vg.GetVulnerabilities(ctx, qs)
+	if err != nil {
+		return "", nil, err
+	}
+	zlog.Debug(ctx).
+		Int("queries", len(qs)).
+		Msg("looked up vulnerabilities")
+
+	m := make(map[string][]Fix)
+	for id, i := range want {
+		if fs := laterFixes(r.Vulnerabilities[id], res[i]); len(fs) != 0 {
+			m[id] = fs
+		}
+	}
+	if len(m) == 0 {
+		return Type, nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Type, nil, err
+	}
+	return Type, []json.RawMessage{b}, nil
+}
+
+// LaterFixes returns the fixes for "v" in the releases in "rs" that are
+// newer than the release "v" affects, oldest release first.
+func laterFixes(v *claircore.Vulnerability, rs []*claircore.Vulnerability) []Fix {
+	cur, _ := splitVersion(v.Dist.VersionID)
+	type fix struct {
+		Fix
+		v []int
+	}
+	var fs []fix
+	seen := make(map[string]struct{})
+	for _, o := range rs {
+		if o.FixedInVersion == "" || o.Dist == nil {
+			continue
+		}
+		ov, ok := splitVersion(o.Dist.VersionID)
+		if !ok || compare(ov, cur) <= 0 {
+			continue
+		}
+		if _, ok := seen[o.Dist.VersionID]; ok {
+			continue
+		}
+		seen[o.Dist.VersionID] = struct{}{}
+		fs = append(fs, fix{
+			Fix: Fix{
+				VersionID:       o.Dist.VersionID,
+				VersionCodeName: o.Dist.VersionCodeName,
+				FixedInVersion:  o.FixedInVersion,
+			},
+			v: ov,
+		})
+	}
+	sort.Slice(fs, func(i, j int) bool { return compare(fs[i].v, fs[j].v) < 0 })
+	out := make([]Fix, len(fs))
+	for i := range fs {
+		out[i] = fs[i].Fix
+	}
+	return out
+}
+
+// SplitVersion parses a dotted numeric VERSION_ID like "20.04".
+func splitVersion(s string) ([]int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	fs := strings.Split(s, ".")
+	out := make([]int, len(fs))
+	for i, f := range fs {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// Compare compares two parsed versions, treating missing components as zero.
+func compare(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// FakeGetter serves vulnerabilities from a slice.
+type fakeGetter []*claircore.Vulnerability
+
+func (fakeGetter) GetEnrichment(context.Context, []string) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+func (g fakeGetter) GetVulnerabilities(_ context.Context, qs []driver.VulnerabilityQuery) ([][]*claircore.Vulnerability, error) {
+	out := make([][]*claircore.Vulnerability, len(qs))
+	for i, q := range qs {
+		for _, v := range g {
+			if v.Name == q.Name && v.Package.Name == q.Package && v.Dist.DID == q.DistributionID {
+				out[i] = append(out[i], v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// EnrichmentOnly can't look up vulnerabilities.
+type enrichmentOnly struct{}
+
+func (enrichmentOnly) GetEnrichment(context.Context, []string) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+func ubuntu(version, codename string) *claircore.Distribution {
+	return &claircore.Distribution{
+		DID:             "ubuntu",
+		VersionID:       version,
+		VersionCodeName: codename,
+	}
+}
+
+func vuln(id, name string, d *claircore.Distribution, fixed string) *claircore.Vulnerability {
+	return &claircore.Vulnerability{
+		ID:             id,
+		Name:           name,
+		Package:        &claircore.Package{Name: "openssl"},
+		Dist:           d,
+		FixedInVersion: fixed,
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var (
+		bionic = ubuntu("18.04", "bionic")
+		focal  = ubuntu("20.04", "focal")
+		jammy  = ubuntu("22.04", "jammy")
+		noble  = ubuntu("24.04", "noble")
+	)
+	store := fakeGetter{
+		vuln("1", "CVE-2022-0778", bionic, "1.1.1-1ubuntu2.1~18.04.15"),
+		vuln("2", "CVE-2022-0778", focal, ""),
+		vuln("3", "CVE-2022-0778", jammy, "3.0.2-0ubuntu1.1"),
+		vuln("4", "CVE-2022-0778", noble, "3.0.2-0ubuntu1.1"),
+		vuln("5", "CVE-2022-1292", focal, ""),
+		vuln("6", "CVE-2022-1292", jammy, ""),
+		vuln("7", "CVE-2022-2068", focal, "1.1.1f-1ubuntu2.16"),
+		vuln("8", "CVE-2022-2068", jammy, "3.0.2-0ubuntu1.6"),
+	}
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"2": store[1],
+			"5": store[4],
+			"7": store[6],
+		},
+	}
+
+	var e Enricher
+	kind, es, err := e.Enrich(ctx, store, vr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != Type {
+		t.Errorf("got: %q, want: %q", kind, Type)
+	}
+	if len(es) != 1 {
+		t.Fatalf("got %d enrichments, want 1", len(es))
+	}
+	var got map[string][]Fix
+	if err := json.Unmarshal(es[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	// Only the unfixed finding with fixes elsewhere is reported, and bionic
+	// is older than focal.
+	want := map[string][]Fix{
+		"2": {
+			{VersionID: "22.04", VersionCodeName: "jammy", FixedInVersion: "3.0.2-0ubuntu1.1"},
+			{VersionID: "24.04", VersionCodeName: "noble", FixedInVersion: "3.0.2-0ubuntu1.1"},
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestEnrichNoGetter(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var e Enricher
+	_, _, err := e.Enrich(ctx, enrichmentOnly{}, &claircore.VulnerabilityReport{})
+	if !errors.Is(err, ErrNoVulnerabilityGetter) {
+		t.Errorf("got: %v, want: %v", err, ErrNoVulnerabilityGetter)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tt := []struct {
+		A, B string
+		Want int
+	}{
+		{"20.04", "22.04", -1},
+		{"22.04", "20.04", 1},
+		{"10", "9", 1},
+		{"3.12", "3.12.0", 0},
+	}
+	for _, tc := range tt {
+		a, _ := splitVersion(tc.A)
+		b, _ := splitVersion(tc.B)
+		if got := compare(a, b); got != tc.Want {
+			t.Errorf("compare(%q, %q): got %d, want %d", tc.A, tc.B, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package rhcatalog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// PageSize is the number of images requested at a time.
+const pageSize = 100
+
+// Page is a page of the Catalog's image listing for a repository.
+type page struct {
+	Data     []image `json:"data"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
+	Total    int     `json:"total"`
+}
+
+// Image is the subset of a Catalog image used by this package.
+type image struct {
+	Architecture           string      `json:"architecture"`
+	TopLayerID             string      `json:"top_layer_id"`
+	UncompressedTopLayerID string      `json:"uncompressed_top_layer_id"`
+	FreshnessGrades        []grade     `json:"freshness_grades"`
+	Repositories           []imageRepo `json:"repositories"`
+}
+
+type grade struct {
+	Grade     string `json:"grade"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+type imageRepo struct {
+	Registry              string `json:"registry"`
+	Repository            string `json:"repository"`
+	ManifestSchema2Digest string `json:"manifest_schema2_digest"`
+	ImageAdvisoryID       string `json:"image_advisory_id"`
+	Published             bool   `json:"published"`
+}
+
+// FetchEnrichment implements driver.EnrichmentUpdater.
+//
+// The images of every configured repository are fetched and combined into a
+// single JSON object keyed by repository. The Fingerprint records a digest of
+// each repository's images, so data is only reprocessed when a repository's
+// images change or the configured repositories do.
+func (e *Enricher) FetchEnrichment(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/rhcatalog/Enricher/FetchEnrichment"))
+	if e.api == nil || e.c == nil {
+		return nil, hint, fmt.Errorf("rhcatalog: enricher not configured")
+	}
+
+	all := make(map[string][]image, len(e.repos))
+	sums := make(map[string]string, len(e.repos))
+	for _, repo := range e.repos {
+		imgs, err := e.fetchRepository(ctx, repo)
+		if err != nil {
+			return nil, hint, err
+		}
+		b, err := json.Marshal(imgs)
+		if err != nil {
+			return nil, hint, err
+		}
+		sum := sha256.Sum256(b)
+		sums[repo] = hex.EncodeToString(sum[:])
+		all[repo] = imgs
+	}
+
+	// Map keys are marshaled in sorted order, so this is stable.
+	fb, err := json.Marshal(sums)
+	if err != nil {
+		return nil, hint, err
+	}
+	fp := driver.Fingerprint(fb)
+	if fp == hint {
+		return nil, hint, driver.Unchanged
+	}
+	b, err := json.Marshal(all)
+	if err != nil {
+		return nil, hint, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), fp, nil
+}
+
+// FetchRepository fetches every page of images for the repository, in a
+// stable order.
+func (e *Enricher) fetchRepository(ctx context.Context, repo string) ([]image, error) {
+	u, err := e.api.Parse(path.Join("v1/repositories/registry", url.PathEscape(e.registry), "repository", repo, "images"))
+	if err != nil {
+		return nil, fmt.Errorf("bad URL: %w", err)
+	}
+	var ret []image
+	for n := 0; ; n++ {
+		q := url.Values{
+			"page":      {strconv.Itoa(n)},
+			"page_size": {strconv.Itoa(pageSize)},
+		}
+		u.RawQuery = q.Encode()
+		zlog.Debug(ctx).
+			Str("repository", repo).
+			Stringer("url", u).
+			Msg("fetching images")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+		res, err := e.c.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to do request: %w", err)
+		}
+		var p page
+		switch res.StatusCode {
+		case http.StatusOK:
+			err = json.NewDecoder(res.Body).Decode(&p)
+		case http.StatusNotFound:
+			zlog.Info(ctx).
+				Str("repository", repo).
+				Msg("no such repository")
+		default:
+			err = fmt.Errorf("rhcatalog: unexpected response for %q: %s", repo, res.Status)
+		}
+		res.Body.Close() // Don't defer because we're in a loop.
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, p.Data...)
+		if len(p.Data) == 0 || len(ret) >= p.Total {
+			break
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].TopLayerID != ret[j].TopLayerID {
+			return ret[i].TopLayerID < ret[j].TopLayerID
+		}
+		return ret[i].Architecture < ret[j].Architecture
+	})
+	return ret, nil
+}
+
+// ParseEnrichment implements driver.EnrichmentUpdater.
+//
+// A record is made for each image, tagged with its top layer's digests and
+// its manifest digest. Images not published in the fetched repository are
+// skipped.
+func (e *Enricher) ParseEnrichment(ctx context.Context, rc io.ReadCloser) ([]driver.EnrichmentRecord, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/rhcatalog/Enricher/ParseEnrichment"))
+	defer rc.Close()
+	var all map[string][]image
+	if err := json.NewDecoder(rc).Decode(&all); err != nil {
+		return nil, err
+	}
+	repos := make([]string, 0, len(all))
+	for r := range all {
+		repos = append(repos, r)
+	}
+	sort.Strings(repos)
+
+	var ret []driver.EnrichmentRecord
+	var skipped int
+	for _, repo := range repos {
+		for _, img := range all[repo] {
+			var ir *imageRepo
+			for i := range img.Repositories {
+				r := &img.Repositories[i]
+				if r.Repository == repo && r.Published {
+					ir = r
+					break
+				}
+			}
+			if ir == nil || img.TopLayerID == "" {
+				skipped++
+				continue
+			}
+			rec := record{
+				Registry:     ir.Registry,
+				Repository:   ir.Repository,
+				Architecture: img.Architecture,
+				Advisory:     ir.ImageAdvisoryID,
+			}
+			for _, g := range img.FreshnessGrades {
+				rec.Grades = append(rec.Grades, Grade{Grade: g.Grade, Start: g.StartDate, End: g.EndDate})
+			}
+			sort.SliceStable(rec.Grades, func(i, j int) bool {
+				return rec.Grades[i].Start < rec.Grades[j].Start
+			})
+			tags := []string{layerTag(img.TopLayerID)}
+			if img.UncompressedTopLayerID != "" {
+				tags = append(tags, layerTag(img.UncompressedTopLayerID))
+			}
+			if ir.ManifestSchema2Digest != "" {
+				tags = append(tags, manifestTag(ir.ManifestSchema2Digest))
+			}
+			b, err := json.Marshal(rec)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, driver.EnrichmentRecord{
+				Tags:       tags,
+				Enrichment: b,
+			})
+		}
+	}
+	zlog.Debug(ctx).
+		Int("count", len(ret)).
+		Int("skipped", skipped).
+		Msg("decoded enrichments")
+	return ret, nil
+}
@@ -0,0 +1,312 @@
+// Package rhcatalog provides an enricher that reports what the Red Hat
+// Container Catalog says about Red Hat images: the image's freshness grade and
+// the advisory it was shipped in.
+//
+// The Catalog grades an image by how long fixes for the vulnerabilities in it
+// have been available, regrading it as they age. So an "F" in the report
+// means a rebuilt image with the fixes has been published for a while, which
+// a list of matched vulnerabilities doesn't say by itself.
+//
+// Images are identified by their top layer, so an image is recognized no
+// matter which registry or tag it was pulled by.
+package rhcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Enricher            = (*Enricher)(nil)
+	_ driver.IndexReportEnricher = (*Enricher)(nil)
+	_ driver.EnrichmentUpdater   = (*Enricher)(nil)
+	_ driver.Configurable        = (*Enricher)(nil)
+)
+
+const (
+	// Type is the type of data returned from the Enricher's Enrich method.
+	//
+	// Each returned enrichment is an Entry for one Catalog repository the
+	// image is published in.
+	Type = `message/vnd.clair.image; enricher=clair.rhcatalog`
+	// DefaultAPI is the default place to fetch image data from.
+	DefaultAPI = `https://catalog.redhat.com/api/containers/`
+	// DefaultRegistry is the registry the default repositories are fetched
+	// for.
+	DefaultRegistry = `registry.access.redhat.com`
+
+	name = `clair.rhcatalog`
+
+	// ErrataURL is where advisories are published.
+	errataURL = `https://access.redhat.com/errata/`
+)
+
+// DefaultRepositories are the repositories fetched if none are configured.
+var DefaultRepositories = []string{
+	"ubi8/ubi",
+	"ubi8/ubi-minimal",
+	"ubi8/ubi-micro",
+	"ubi9/ubi",
+	"ubi9/ubi-minimal",
+	"ubi9/ubi-micro",
+}
+
+// Entry is reported for every Catalog repository an image is published in.
+type Entry struct {
+	Registry     string `json:"registry"`
+	Repository   string `json:"repository"`
+	Architecture string `json:"architecture"`
+	// Grade is the image's freshness grade at the time of the report, "A"
+	// through "F". It's empty if the Catalog hasn't graded the image.
+	Grade string `json:"grade"`
+	// GradeUntil is when the image is next regraded, in RFC 3339 form. It's
+	// empty if no change is scheduled.
+	GradeUntil string `json:"grade_until,omitempty"`
+	// Grades are every grade the Catalog has scheduled for the image, in
+	// order.
+	Grades []Grade `json:"grades,omitempty"`
+	// Advisory is the advisory the image was shipped in, if any.
+	Advisory *Advisory `json:"advisory,omitempty"`
+}
+
+// Grade is a freshness grade and the time it applies.
+type Grade struct {
+	Grade string `json:"grade"`
+	// Start and End are in RFC 3339 form. End is empty for the last grade.
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+}
+
+// Advisory is a reference to a Red Hat advisory.
+type Advisory struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Enricher reports the Catalog data for Red Hat images.
+//
+// Configure must be called before using it as an EnrichmentUpdater.
+type Enricher struct {
+	driver.NoopUpdater
+	c        *http.Client
+	api      *url.URL
+	registry string
+	repos    []string
+	// Now is used in tests.
+	now func() time.Time
+}
+
+// Config is the configuration for Enricher.
+type Config struct {
+	// API is the root of the Red Hat Container Catalog API.
+	API *string `json:"api" yaml:"api"`
+	// Registry is the registry the repositories are published in. Images are
+	// recognized regardless of the registry they're pulled from.
+	Registry *string `json:"registry" yaml:"registry"`
+	// Repositories is the list of repositories to fetch image data for, such
+	// as "ubi9/ubi".
+	Repositories []string `json:"repositories" yaml:"repositories"`
+}
+
+// Configure implements driver.Configurable.
+func (e *Enricher) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	var cfg Config
+	e.c = c
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	api := DefaultAPI
+	if cfg.API != nil {
+		api = *cfg.API
+		if !strings.HasSuffix(api, "/") {
+			return fmt.Errorf("URL missing trailing slash: %q", api)
+		}
+	}
+	u, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+	e.api = u
+	e.registry = DefaultRegistry
+	if cfg.Registry != nil {
+		e.registry = *cfg.Registry
+	}
+	e.repos = cfg.Repositories
+	if len(e.repos) == 0 {
+		e.repos = DefaultRepositories
+	}
+	for _, r := range e.repos {
+		if r == "" || strings.HasPrefix(r, "/") || strings.HasSuffix(r, "/") {
+			return fmt.Errorf("bad repository: %q", r)
+		}
+	}
+	return nil
+}
+
+// Name implements driver.Enricher and driver.EnrichmentUpdater.
+func (*Enricher) Name() string { return name }
+
+// Record is the format of the stored enrichment records.
+type record struct {
+	Registry     string  `json:"registry"`
+	Repository   string  `json:"repository"`
+	Architecture string  `json:"architecture"`
+	Grades       []Grade `json:"grades,omitempty"`
+	Advisory     string  `json:"advisory,omitempty"`
+}
+
+// LayerTag returns the tag used to store and query an image by one of its top
+// layer's digests.
+func layerTag(d string) string {
+	return "layer:" + d
+}
+
+// ManifestTag returns the tag used to store and query an image by its
+// manifest digest.
+func manifestTag(d string) string {
+	return "manifest:" + d
+}
+
+// Enrich implements driver.Enricher.
+//
+// Without the IndexReport, images can only be recognized by manifest digest,
+// so only images pulled from a registry serving the same manifest as the
+// Catalog are recognized.
+func (e *Enricher) Enrich(ctx context.Context, g driver.EnrichmentGetter, r *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/rhcatalog/Enricher/Enrich"))
+	if !isRedHat(r.Distributions) || r.Hash.String() == "" {
+		return Type, nil, nil
+	}
+	return e.enrich(ctx, g, []string{manifestTag(r.Hash.String())})
+}
+
+// EnrichWithIndexReport implements driver.IndexReportEnricher.
+func (e *Enricher) EnrichWithIndexReport(ctx context.Context, g driver.EnrichmentGetter, ir *claircore.IndexReport, r *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "enricher/rhcatalog/Enricher/EnrichWithIndexReport"))
+	if !isRedHat(ir.Distributions) {
+		return Type, nil, nil
+	}
+	var tags []string
+	if n := len(ir.Layers); n != 0 {
+		top := ir.Layers[n-1]
+		tags = append(tags, layerTag(top.Hash.String()))
+		if top.DiffID != nil {
+			tags = append(tags, layerTag(top.DiffID.String()))
+		}
+	}
+	if ir.Hash.String() != "" {
+		tags = append(tags, manifestTag(ir.Hash.String()))
+	}
+	if len(tags) == 0 {
+		return Type, nil, nil
+	}
+	return e.enrich(ctx, g, tags)
+}
+
+// IsRedHat reports whether any of the distributions is one the Catalog
+// publishes images of.
+func isRedHat(ds map[string]*claircore.Distribution) bool {
+	for _, d := range ds {
+		if d.DID == "rhel" {
+			return true
+		}
+	}
+	return false
+}
+
+// Enrich looks up the records for any of "tags" and turns them into Entries.
+func (e *Enricher) enrich(ctx context.Context, g driver.EnrichmentGetter, tags []string) (string, []json.RawMessage, error) {
+	recs, err := g.GetEnrichment(ctx, tags)
+	if err != nil {
+		return "", nil, err
+	}
+	zlog.Debug(ctx).
+		Strs("tags", tags).
+		Int("count", len(recs)).
+		Msg("found records")
+
+	now := time.Now
+	if e.now != nil {
+		now = e.now
+	}
+	at := now()
+	// An image is found by several tags, so records may be repeated.
+	seen := make(map[string]struct{}, len(recs))
+	var es []Entry
+	for _, rec := range recs {
+		var v record
+		if err := json.Unmarshal(rec.Enrichment, &v); err != nil {
+			return "", nil, err
+		}
+		k := v.Registry + "/" + v.Repository + "@" + v.Architecture
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ent := Entry{
+			Registry:     v.Registry,
+			Repository:   v.Repository,
+			Architecture: v.Architecture,
+			Grades:       v.Grades,
+		}
+		if g, ok := currentGrade(v.Grades, at); ok {
+			ent.Grade, ent.GradeUntil = g.Grade, g.End
+		}
+		if v.Advisory != "" {
+			ent.Advisory = &Advisory{ID: v.Advisory, URL: errataURL + v.Advisory}
+		}
+		es = append(es, ent)
+	}
+	if len(es) == 0 {
+		return Type, nil, nil
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].Registry != es[j].Registry {
+			return es[i].Registry < es[j].Registry
+		}
+		return es[i].Repository < es[j].Repository
+	})
+	ret := make([]json.RawMessage, len(es))
+	for i := range es {
+		b, err := json.Marshal(&es[i])
+		if err != nil {
+			return Type, nil, err
+		}
+		ret[i] = b
+	}
+	return Type, ret, nil
+}
+
+// CurrentGrade returns the grade in effect at "at". Grades with unparsable
+// times are ignored.
+func currentGrade(gs []Grade, at time.Time) (Grade, bool) {
+	for _, g := range gs {
+		start, err := time.Parse(time.RFC3339, g.Start)
+		if err != nil || at.Before(start) {
+			continue
+		}
+		if g.End != "" {
+			end, err := time.Parse(time.RFC3339, g.End)
+			if err != nil || !at.Before(end) {
+				continue
+			}
+		}
+		return g, true
+	}
+	return Grade{}, false
+}
@@ -0,0 +1,334 @@
+package rhcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Catalog serves the recorded responses in testdata, two images to a page.
+func catalog(t *testing.T) (*httptest.Server, *int) {
+	const prefix = "/api/v1/repositories/registry/registry.access.redhat.com/repository/"
+	fixtures := map[string]string{
+		prefix + "ubi8/ubi/images": "ubi8-ubi.json",
+	}
+	var reqs int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs++
+		f, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := ioutil.ReadFile(filepath.Join("testdata", f))
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var all struct {
+			Data  []json.RawMessage `json:"data"`
+			Total int               `json:"total"`
+		}
+		if err := json.Unmarshal(b, &all); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		const size = 2
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		data := []json.RawMessage{}
+		if lo := n * size; lo < len(all.Data) {
+			hi := lo + size
+			if hi > len(all.Data) {
+				hi = len(all.Data)
+			}
+			data = all.Data[lo:hi]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":      data,
+			"page":      n,
+			"page_size": size,
+			"total":     all.Total,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func configure(ctx context.Context, t *testing.T, srv *httptest.Server, repos ...string) *Enricher {
+	var e Enricher
+	api := srv.URL + "/api/"
+	err := e.Configure(ctx, func(v interface{}) error {
+		cfg := v.(*Config)
+		cfg.API = &api
+		cfg.Repositories = repos
+		return nil
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &e
+}
+
+// FakeGetter serves the provided records by tag.
+type fakeGetter []driver.EnrichmentRecord
+
+func (g fakeGetter) GetEnrichment(_ context.Context, tags []string) ([]driver.EnrichmentRecord, error) {
+	var ret []driver.EnrichmentRecord
+	for _, r := range g {
+	Tags:
+		for _, t := range tags {
+			for _, rt := range r.Tags {
+				if t == rt {
+					ret = append(ret, r)
+					break Tags
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+func TestFetchParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv, reqs := catalog(t)
+	e := configure(ctx, t, srv, "ubi8/ubi", "ubi9/ubi")
+
+	rc, fp, err := e.FetchEnrichment(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two pages for ubi8/ubi, and a miss for ubi9/ubi.
+	if got, want := *reqs, 3; got != want {
+		t.Errorf("got %d requests, want %d", got, want)
+	}
+	rs, err := e.ParseEnrichment(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The unpublished image is skipped.
+	if got, want := len(rs), 3; got != want {
+		t.Fatalf("got %d records, want %d", got, want)
+	}
+	for _, r := range rs {
+		if got, want := len(r.Tags), 3; got != want {
+			t.Errorf("got %d tags, want %d: %v", got, want, r.Tags)
+		}
+	}
+	var got record
+	if err := json.Unmarshal(rs[0].Enrichment, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := record{
+		Registry:     "registry.access.redhat.com",
+		Repository:   "ubi8/ubi",
+		Architecture: "amd64",
+		Advisory:     "RHBA-2023:3729",
+		Grades: []Grade{
+			{Grade: "A", Start: "2023-06-22T19:30:00+00:00", End: "2023-08-08T00:00:00+00:00"},
+			{Grade: "B", Start: "2023-08-08T00:00:00+00:00", End: "2023-09-07T00:00:00+00:00"},
+			{Grade: "F", Start: "2023-09-07T00:00:00+00:00"},
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	if _, _, err := e.FetchEnrichment(ctx, fp); !errors.Is(err, driver.Unchanged) {
+		t.Errorf("got: %v, want: %v", err, driver.Unchanged)
+	}
+	// A different set of repositories is a different fingerprint.
+	e = configure(ctx, t, srv, "ubi8/ubi")
+	if _, nfp, err := e.FetchEnrichment(ctx, fp); err != nil || nfp == fp {
+		t.Errorf("got: %q, %v; want a new fingerprint", nfp, err)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv, _ := catalog(t)
+	e := configure(ctx, t, srv, "ubi8/ubi")
+	rc, _, err := e.FetchEnrichment(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := e.ParseEnrichment(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := fakeGetter(rs)
+
+	at := func(y int, m time.Month, d int) func() time.Time {
+		return func() time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	}
+	digest := func(s string) claircore.Digest {
+		d, err := claircore.ParseDigest(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	diffID := digest("sha256:b5c0a9e1d2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9")
+	ubi := map[string]*claircore.Distribution{
+		"1": {DID: "rhel", VersionID: "8.8"},
+	}
+	grades := []Grade{
+		{Grade: "A", Start: "2023-06-22T19:30:00+00:00", End: "2023-08-08T00:00:00+00:00"},
+		{Grade: "B", Start: "2023-08-08T00:00:00+00:00", End: "2023-09-07T00:00:00+00:00"},
+		{Grade: "F", Start: "2023-09-07T00:00:00+00:00"},
+	}
+	entry := func(grade, until string) *Entry {
+		return &Entry{
+			Registry:     "registry.access.redhat.com",
+			Repository:   "ubi8/ubi",
+			Architecture: "amd64",
+			Grade:        grade,
+			GradeUntil:   until,
+			Grades:       grades,
+			Advisory: &Advisory{
+				ID:  "RHBA-2023:3729",
+				URL: "https://access.redhat.com/errata/RHBA-2023:3729",
+			},
+		}
+	}
+
+	tt := []struct {
+		Name   string
+		Now    func() time.Time
+		Report *claircore.IndexReport
+		Want   *Entry
+	}{
+		{
+			Name: "TopLayer",
+			Now:  at(2023, 7, 1),
+			Report: &claircore.IndexReport{
+				Hash:          digest("sha256:0000000000000000000000000000000000000000000000000000000000000001"),
+				Distributions: ubi,
+				Layers: []claircore.LayerIdentity{
+					{Hash: digest("sha256:5e4b1b4b1f2c0e6a8d1c9b2e7f3a4d5c6b7a8e9f0d1c2b3a4e5f6d7c8b9a0e1f"), DiffID: &diffID},
+				},
+			},
+			Want: entry("A", "2023-08-08T00:00:00+00:00"),
+		},
+		{
+			Name: "Regraded",
+			Now:  at(2024, 1, 1),
+			Report: &claircore.IndexReport{
+				Distributions: ubi,
+				Layers: []claircore.LayerIdentity{
+					{Hash: digest("sha256:5e4b1b4b1f2c0e6a8d1c9b2e7f3a4d5c6b7a8e9f0d1c2b3a4e5f6d7c8b9a0e1f")},
+				},
+			},
+			Want: entry("F", ""),
+		},
+		{
+			// A mirror that recompressed the layers only matches by diffID.
+			Name: "Mirrored",
+			Now:  at(2023, 8, 8),
+			Report: &claircore.IndexReport{
+				Distributions: ubi,
+				Layers: []claircore.LayerIdentity{
+					{Hash: digest("sha256:0000000000000000000000000000000000000000000000000000000000000002"), DiffID: &diffID},
+				},
+			},
+			Want: entry("B", "2023-09-07T00:00:00+00:00"),
+		},
+		{
+			Name: "Manifest",
+			Now:  at(2023, 7, 1),
+			Report: &claircore.IndexReport{
+				Hash:          digest("sha256:c8e1d8a4b2f7e3a9c5d0b6f1e2a7d3c8b4e9f0a5d1c6b2e7f3a8d4c9b5e0f1a6"),
+				Distributions: ubi,
+			},
+			Want: entry("A", "2023-08-08T00:00:00+00:00"),
+		},
+		{
+			Name: "NotRedHat",
+			Now:  at(2023, 7, 1),
+			Report: &claircore.IndexReport{
+				Distributions: map[string]*claircore.Distribution{
+					"1": {DID: "debian", VersionID: "12"},
+				},
+				Layers: []claircore.LayerIdentity{
+					{Hash: digest("sha256:5e4b1b4b1f2c0e6a8d1c9b2e7f3a4d5c6b7a8e9f0d1c2b3a4e5f6d7c8b9a0e1f")},
+				},
+			},
+		},
+		{
+			// Only the top layer identifies the image; this is an image
+			// built on top of it.
+			Name: "Derived",
+			Now:  at(2023, 7, 1),
+			Report: &claircore.IndexReport{
+				Distributions: ubi,
+				Layers: []claircore.LayerIdentity{
+					{Hash: digest("sha256:5e4b1b4b1f2c0e6a8d1c9b2e7f3a4d5c6b7a8e9f0d1c2b3a4e5f6d7c8b9a0e1f")},
+					{Hash: digest("sha256:0000000000000000000000000000000000000000000000000000000000000003")},
+				},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			e.now = tc.Now
+			vr := &claircore.VulnerabilityReport{
+				Hash:          tc.Report.Hash,
+				Distributions: tc.Report.Distributions,
+			}
+			kind, es, err := e.EnrichWithIndexReport(ctx, g, tc.Report, vr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != Type {
+				t.Errorf("got: %q, want: %q", kind, Type)
+			}
+			if tc.Want == nil {
+				if len(es) != 0 {
+					t.Errorf("unexpected enrichments: %s", es)
+				}
+				return
+			}
+			if len(es) != 1 {
+				t.Fatalf("got %d enrichments, want 1", len(es))
+			}
+			var got Entry
+			if err := json.Unmarshal(es[0], &got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(&got, tc.Want) {
+				t.Error(cmp.Diff(&got, tc.Want))
+			}
+		})
+	}
+
+	t.Run("WithoutIndexReport", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		e.now = at(2023, 7, 1)
+		vr := &claircore.VulnerabilityReport{
+			Hash:          digest("sha256:c8e1d8a4b2f7e3a9c5d0b6f1e2a7d3c8b4e9f0a5d1c6b2e7f3a8d4c9b5e0f1a6"),
+			Distributions: ubi,
+		}
+		_, es, err := e.Enrich(ctx, g, vr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(es) != 1 {
+			t.Fatalf("got %d enrichments, want 1", len(es))
+		}
+	})
+}
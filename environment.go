@@ -1,5 +1,10 @@
 package claircore
 
+import (
+	"sort"
+	"strings"
+)
+
 // Environment describes the surrounding environment a package was
 // discovered in.
 //
@@ -12,7 +17,59 @@ type Environment struct {
 	// the layer in which the associated package was introduced
 	IntroducedIn Digest `json:"introduced_in"`
 	// the ID of the distribution the package was discovered on
+	//
+	// This is set per package, so packages in one IndexReport may have
+	// different distributions, e.g. when a layer built on one distribution
+	// is copied onto another.
 	DistributionID string `json:"distribution_id"`
 	// the ID of the repository where this package was downloaded from (currently not used)
 	RepositoryIDs []string `json:"repository_ids"`
 }
+
+// SortEnvironments returns a copy of the provided map with the Environment
+// slices, and the RepositoryIDs within them, in a stable order.
+//
+// The provided map and its contents are not modified.
+func sortEnvironments(m map[string][]*Environment) map[string][]*Environment {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string][]*Environment, len(m))
+	for k, es := range m {
+		if es == nil {
+			out[k] = nil
+			continue
+		}
+		s := make([]*Environment, len(es))
+		for i, e := range es {
+			if e == nil {
+				continue
+			}
+			c := *e
+			if c.RepositoryIDs != nil {
+				c.RepositoryIDs = append([]string(nil), e.RepositoryIDs...)
+				sort.Strings(c.RepositoryIDs)
+			}
+			s[i] = &c
+		}
+		sort.SliceStable(s, func(i, j int) bool { return envLess(s[i], s[j]) })
+		out[k] = s
+	}
+	return out
+}
+
+// EnvLess orders Environments by all their members, with nil Environments
+// first.
+func envLess(a, b *Environment) bool {
+	switch {
+	case a == nil || b == nil:
+		return a == nil && b != nil
+	case a.PackageDB != b.PackageDB:
+		return a.PackageDB < b.PackageDB
+	case a.IntroducedIn.String() != b.IntroducedIn.String():
+		return a.IntroducedIn.String() < b.IntroducedIn.String()
+	case a.DistributionID != b.DistributionID:
+		return a.DistributionID < b.DistributionID
+	}
+	return strings.Join(a.RepositoryIDs, "\x00") < strings.Join(b.RepositoryIDs, "\x00")
+}
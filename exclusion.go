@@ -0,0 +1,97 @@
+package claircore
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Exclusion suppresses the findings of a vulnerability on a package, for
+// known false positives.
+//
+// Findings matched by an Exclusion are moved out of a VulnerabilityReport's
+// PackageVulnerabilities and into its Suppressed section.
+type Exclusion struct {
+	// ID is assigned when the Exclusion is stored.
+	ID uuid.UUID `json:"id"`
+	// Vulnerability is the exact name of the vulnerability, like
+	// "CVE-2021-3449".
+	Vulnerability string `json:"vulnerability"`
+	// Package is the exact name of the package.
+	Package string `json:"package"`
+	// Version, if set, is a pattern the package's version must match, using
+	// the syntax of path.Match: for example, "1.1.1k-*".
+	Version string `json:"version,omitempty"`
+	// Manifest, if set, limits the Exclusion to reports for the manifest.
+	Manifest *Digest `json:"manifest,omitempty"`
+	// Reason records why the findings are suppressed.
+	Reason string `json:"reason"`
+	// Author records who added the Exclusion.
+	Author string `json:"author,omitempty"`
+	// Created is set when the Exclusion is stored.
+	Created time.Time `json:"created"`
+	// Expires, if set, is when the Exclusion stops applying.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Suppression is a finding left out of a VulnerabilityReport's
+// PackageVulnerabilities by an Exclusion.
+type Suppression struct {
+	// VulnerabilityID is the vulnerability's key in the report's
+	// Vulnerabilities.
+	VulnerabilityID string `json:"vulnerability_id"`
+	// Exclusion is the ID of the matching Exclusion.
+	Exclusion uuid.UUID `json:"exclusion"`
+	// Reason is the matching Exclusion's reason.
+	Reason string `json:"reason"`
+}
+
+// ErrInvalidExclusion is returned, wrapped, for Exclusions that can't be
+// stored.
+var ErrInvalidExclusion = errors.New("claircore: invalid exclusion")
+
+// Validate reports an error wrapping ErrInvalidExclusion if the Exclusion is
+// missing a vulnerability, package, or reason, or has a malformed version
+// pattern.
+func (e *Exclusion) Validate() error {
+	switch {
+	case e.Vulnerability == "":
+		return fmt.Errorf("%w: missing vulnerability", ErrInvalidExclusion)
+	case e.Package == "":
+		return fmt.Errorf("%w: missing package", ErrInvalidExclusion)
+	case e.Reason == "":
+		return fmt.Errorf("%w: missing reason", ErrInvalidExclusion)
+	}
+	if e.Version != "" {
+		if _, err := path.Match(e.Version, ""); err != nil {
+			return fmt.Errorf("%w: version %q: %v", ErrInvalidExclusion, e.Version, err)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the Exclusion applies at the time "t".
+func (e *Exclusion) Active(t time.Time) bool {
+	return e.Expires == nil || t.Before(*e.Expires)
+}
+
+// Matches reports whether the Exclusion suppresses the vulnerability "v" on
+// the package "p" in the report for the manifest "m". Expiry isn't
+// considered; see Active.
+func (e *Exclusion) Matches(m Digest, p *Package, v *Vulnerability) bool {
+	if p == nil || v == nil || e.Vulnerability != v.Name || e.Package != p.Name {
+		return false
+	}
+	if e.Manifest != nil && e.Manifest.String() != m.String() {
+		return false
+	}
+	if e.Version != "" {
+		if ok, _ := path.Match(e.Version, p.Version); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package claircore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+func TestExclusionMatches(t *testing.T) {
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	other := claircore.MustParseDigest(`sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`)
+	pkg := &claircore.Package{Name: "openssl", Version: "1.1.1k-1"}
+	vuln := &claircore.Vulnerability{Name: "CVE-2021-3449"}
+	tt := []struct {
+		name string
+		e    claircore.Exclusion
+		want bool
+	}{
+		{name: "Exact", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl"}, want: true},
+		{name: "OtherVulnerability", e: claircore.Exclusion{Vulnerability: "CVE-2021-3450", Package: "openssl"}},
+		{name: "OtherPackage", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "libssl"}},
+		{name: "Version", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Version: "1.1.1k-1"}, want: true},
+		{name: "VersionPattern", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Version: "1.1.1?-*"}, want: true},
+		{name: "OtherVersion", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Version: "1.1.1j-*"}},
+		{name: "Manifest", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Manifest: &manifest}, want: true},
+		{name: "OtherManifest", e: claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Manifest: &other}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.e.Matches(manifest, pkg, vuln); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExclusionActive(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	e := claircore.Exclusion{}
+	if !e.Active(now) {
+		t.Error("exclusion without expiry not active")
+	}
+	e.Expires = &later
+	if !e.Active(now) {
+		t.Error("exclusion not active before expiry")
+	}
+	if e.Active(later) {
+		t.Error("exclusion active at expiry")
+	}
+}
+
+func TestExclusionValidate(t *testing.T) {
+	ok := claircore.Exclusion{Vulnerability: "CVE-2021-3449", Package: "openssl", Reason: "reason"}
+	if err := ok.Validate(); err != nil {
+		t.Error(err)
+	}
+	bad := ok
+	bad.Version = "["
+	if err := bad.Validate(); !errors.Is(err, claircore.ErrInvalidExclusion) {
+		t.Errorf("got error %v, want %v", err, claircore.ErrInvalidExclusion)
+	}
+}
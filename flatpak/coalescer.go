@@ -0,0 +1,56 @@
+package flatpak
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewCoalescer returns the coalescer for Flatpak installations.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// Each application and runtime is associated with the remote it was
+// installed from. A ref updated in a later layer is reported as the later
+// version only.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	// Package database, which is the ref, to package ID.
+	installed := make(map[string]string)
+	for _, l := range ls {
+		byKey := make(map[string]*claircore.Repository, len(l.Repos))
+		for _, r := range l.Repos {
+			if _, ok := byKey[r.Key]; !ok {
+				byKey[r.Key] = r
+			}
+		}
+		for _, pkg := range l.Pkgs {
+			if id, ok := installed[pkg.PackageDB]; ok && id != pkg.ID {
+				delete(ir.Packages, id)
+				delete(ir.Environments, id)
+			}
+			installed[pkg.PackageDB] = pkg.ID
+			env := &claircore.Environment{
+				PackageDB:    pkg.PackageDB,
+				IntroducedIn: l.Hash,
+			}
+			if r, ok := byKey[pkg.RepositoryHint]; ok && pkg.RepositoryHint != "" {
+				ir.AddRepository(r, l.Hash)
+				env.RepositoryIDs = []string{r.ID}
+			}
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = []*claircore.Environment{env}
+		}
+	}
+	return ir, nil
+}
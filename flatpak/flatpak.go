@@ -0,0 +1,299 @@
+// Package flatpak contains components for interrogating applications and
+// runtimes installed by Flatpak in container layers.
+package flatpak
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// Installation is the name of the directory Flatpak keeps an installation
+// in, like "/var/lib/flatpak" or "~/.local/share/flatpak".
+const installation = "flatpak"
+
+// Record is an installed application or runtime.
+type record struct {
+	// Kind is "app" or "runtime".
+	Kind    string
+	ID      string
+	Arch    string
+	Branch  string
+	Commit  string
+	Version string
+	// Origin is the name of the remote the ref was installed from.
+	Origin string
+
+	// Root is the installation the record was found in.
+	root string
+	// URL is the origin's URL, if the installation's configuration names
+	// it.
+	url string
+}
+
+// Repository returns the remote the ref was installed from, or nil if it
+// isn't known.
+func (r *record) repository() *claircore.Repository {
+	if r.Origin == "" {
+		return nil
+	}
+	return &claircore.Repository{
+		Name: "flatpak",
+		Key:  r.Origin,
+		URI:  r.url,
+	}
+}
+
+// Ref returns the directory of the ref in its installation, like
+// "var/lib/flatpak/app/org.gajim.Gajim/x86_64/stable".
+func (r *record) ref() string {
+	return path.Join(r.root, r.Kind, r.ID, r.Arch, r.Branch)
+}
+
+// Deployment is what's known about a deployed commit of a ref, keyed by its
+// directory.
+type deployment struct {
+	origin  string
+	version string
+}
+
+// Records reads the applications and runtimes of every Flatpak installation
+// in the layer, in ref order.
+//
+// Only the active deployment of each ref is reported. Its version is the
+// newest release listed in its AppStream metadata; runtimes without one are
+// reported with their branch, which is how runtimes are versioned.
+func records(ctx context.Context, layer *claircore.Layer) ([]*record, error) {
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rd, ok := r.(interface {
+		io.ReadCloser
+		io.Seeker
+	})
+	if !ok {
+		return nil, errors.New("flatpak: cannot seek on returned layer Reader")
+	}
+
+	var rs []*record
+	deploys := make(map[string]*deployment)
+	deploy := func(dir string) *deployment {
+		d, ok := deploys[dir]
+		if !ok {
+			d = new(deployment)
+			deploys[dir] = d
+		}
+		return d
+	}
+	// Installation to remote name to URL.
+	remotes := make(map[string]map[string]string)
+	tr := tar.NewReader(rd)
+	var h *tar.Header
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		p, ok := parsePath(n)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.file == "active" && h.Typeflag == tar.TypeSymlink:
+			commit := path.Base(h.Linkname)
+			rs = append(rs, &record{
+				Kind:   p.kind,
+				ID:     p.id,
+				Arch:   p.arch,
+				Branch: p.branch,
+				Commit: commit,
+				root:   p.root,
+			})
+		case h.Typeflag != tar.TypeReg:
+		case p.kind == "" && p.file == "repo/config":
+			remotes[p.root] = readRemotes(tr)
+		case p.file == "deploy":
+			origin, err := readOrigin(tr)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("path", n).
+					Msg("unable to read flatpak deploy data, skipping")
+				continue
+			}
+			deploy(p.deployment()).origin = origin
+		case p.metainfo():
+			v, err := readVersion(tr)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("path", n).
+					Msg("unable to read appstream metadata, skipping")
+				continue
+			}
+			if d := deploy(p.deployment()); d.version == "" {
+				d.version = v
+			}
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	for _, r := range rs {
+		if d, ok := deploys[path.Join(r.ref(), r.Commit)]; ok {
+			r.Origin = d.origin
+			r.Version = d.version
+		}
+		if r.Version == "" && r.Kind == "runtime" {
+			r.Version = r.Branch
+		}
+		r.url = remotes[r.root][r.Origin]
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].ref() < rs[j].ref()
+	})
+	return rs, nil
+}
+
+// FlatpakPath is a path inside an installation.
+type flatpakPath struct {
+	root   string
+	kind   string
+	id     string
+	arch   string
+	branch string
+	commit string
+	// File is the rest of the path: relative to the deployment if there's
+	// a kind, and the installation otherwise.
+	file string
+}
+
+// ParsePath reports where in an installation "n" is. Paths inside refs have
+// the form "<root>/<kind>/<id>/<arch>/<branch>/<commit>/<file>", where the
+// commit is "active" for the link naming the active deployment.
+func parsePath(n string) (flatpakPath, bool) {
+	var p flatpakPath
+	el := strings.Split(n, "/")
+	for i := range el {
+		if el[i] != installation || i+1 == len(el) {
+			continue
+		}
+		p.root = strings.Join(el[:i+1], "/")
+		rest := el[i+1:]
+		if rest[0] != "app" && rest[0] != "runtime" {
+			p.file = strings.Join(rest, "/")
+			return p, true
+		}
+		switch {
+		case len(rest) == 5 && rest[4] == "active":
+			p.file = "active"
+		case len(rest) > 5:
+			p.commit = rest[4]
+			p.file = strings.Join(rest[5:], "/")
+		default:
+			return p, false
+		}
+		p.kind, p.id, p.arch, p.branch = rest[0], rest[1], rest[2], rest[3]
+		return p, true
+	}
+	return p, false
+}
+
+// Deployment returns the directory of the deployment the path is in.
+func (p *flatpakPath) deployment() string {
+	return path.Join(p.root, p.kind, p.id, p.arch, p.branch, p.commit)
+}
+
+// Metainfo reports whether the path is the ref's AppStream metadata.
+func (p *flatpakPath) metainfo() bool {
+	if p.commit == "" {
+		return false
+	}
+	switch p.file {
+	case "files/share/metainfo/" + p.id + ".metainfo.xml",
+		"files/share/metainfo/" + p.id + ".appdata.xml",
+		"files/share/appdata/" + p.id + ".appdata.xml":
+		return true
+	}
+	return false
+}
+
+// ReadOrigin reads the name of the remote a deployment was installed from.
+//
+// The "deploy" file is a serialized GVariant of type "(ssasta{sv})", whose
+// first member is the origin. Strings are serialized NUL-terminated, and the
+// first member starts at the beginning of the data.
+func readOrigin(r io.Reader) (string, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return "", err
+	}
+	i := bytes.IndexByte(b, 0)
+	if i < 1 {
+		return "", errors.New("flatpak: malformed deploy data")
+	}
+	return string(b[:i]), nil
+}
+
+// ReadRemotes reads the remotes' URLs out of an installation's repository
+// configuration, a key file with a group per remote:
+//
+//	[remote "flathub"]
+//	url=https://dl.flathub.org/repo/
+func readRemotes(r io.Reader) map[string]string {
+	ret := make(map[string]string)
+	var remote string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(l, "["):
+			remote = ""
+			g := strings.TrimSuffix(strings.TrimPrefix(l, "["), "]")
+			if n := strings.TrimPrefix(g, "remote "); n != g {
+				remote = strings.Trim(n, `"`)
+			}
+		case remote != "" && strings.HasPrefix(l, "url="):
+			ret[remote] = strings.TrimSpace(strings.TrimPrefix(l, "url="))
+		}
+	}
+	return ret
+}
+
+// Component is the subset of an AppStream component used here.
+type component struct {
+	Releases []struct {
+		Version string `xml:"version,attr"`
+	} `xml:"releases>release"`
+}
+
+// ReadVersion returns the version of the newest release in AppStream
+// metadata. Releases are listed newest first.
+func readVersion(r io.Reader) (string, error) {
+	var c component
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		return "", err
+	}
+	for _, rel := range c.Releases {
+		if rel.Version != "" {
+			return rel.Version, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,70 @@
+package flatpak
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/appmap"
+	"github.com/quay/claircore/python"
+)
+
+var (
+	_ driver.Matcher       = (*Matcher)(nil)
+	_ driver.PackageMapper = (*Matcher)(nil)
+)
+
+// Applications is the default mapping of Flatpak application IDs to the
+// packages they're matched as.
+//
+// Only applications whose versions follow the project they package are
+// listed.
+var Applications = appmap.Table{
+	"org.gajim.Gajim": {Name: "gajim", Repository: python.Repository.URI},
+}
+
+// Matcher matches Flatpak applications that are known to package a project
+// with vulnerability data of its own.
+//
+// The zero value uses the Applications table.
+type Matcher struct {
+	// Applications overrides the default mapping, if not nil.
+	Applications appmap.Table
+}
+
+func (m *Matcher) table() appmap.Table {
+	if m.Applications != nil {
+		return m.Applications
+	}
+	return Applications
+}
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return "flatpak" }
+
+// Filter implements driver.Matcher.
+func (m *Matcher) Filter(record *claircore.IndexRecord) bool {
+	if !strings.HasPrefix(record.Package.PackageDB, "flatpak:") {
+		return false
+	}
+	_, ok := m.table().Lookup(record.Package.Name)
+	return ok
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{}
+}
+
+// MapPackage implements driver.PackageMapper.
+//
+// Applications are queried as the package they're mapped to.
+func (m *Matcher) MapPackage(record *claircore.IndexRecord) *claircore.Package {
+	return m.table().Package(record.Package)
+}
+
+// Vulnerable implements driver.Matcher.
+func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	return m.table().Vulnerable(ctx, record, vuln)
+}
@@ -0,0 +1,38 @@
+package flatpak
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestFilter(t *testing.T) {
+	var m Matcher
+	for _, tc := range []struct {
+		Name    string
+		Package *claircore.Package
+		Want    bool
+	}{
+		{
+			Name:    "Mapped",
+			Package: &claircore.Package{Name: "org.gajim.Gajim", PackageDB: "flatpak:var/lib/flatpak/app/org.gajim.Gajim/x86_64/stable"},
+			Want:    true,
+		},
+		{
+			Name:    "Unmapped",
+			Package: &claircore.Package{Name: "org.freedesktop.Platform", PackageDB: "flatpak:var/lib/flatpak/runtime/org.freedesktop.Platform/x86_64/23.08"},
+		},
+		{
+			Name:    "NotFlatpak",
+			Package: &claircore.Package{Name: "org.gajim.Gajim", PackageDB: "var/lib/dpkg/status"},
+		},
+	} {
+		if got := m.Filter(&claircore.IndexRecord{Package: tc.Package}); got != tc.Want {
+			t.Errorf("%s: got: %v, want: %v", tc.Name, got, tc.Want)
+		}
+	}
+	p := m.MapPackage(&claircore.IndexRecord{Package: &claircore.Package{ID: "1", Name: "org.gajim.Gajim", Version: "1.8.4"}})
+	if p == nil || p.Name != "gajim" || p.Version != "1.8.4" {
+		t.Errorf("unexpected mapping: %+v", p)
+	}
+}
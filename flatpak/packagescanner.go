@@ -0,0 +1,73 @@
+package flatpak
+
+import (
+	"context"
+	"runtime/trace"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner = (*Scanner)(nil)
+	_ indexer.PackageScanner   = (*Scanner)(nil)
+)
+
+// Scanner implements the scanner.PackageScanner interface.
+//
+// It reads the applications and runtimes deployed into Flatpak
+// installations, reported with the remote they were installed from as their
+// repository. Each ref is its own package database, so the same application
+// or runtime on different branches is reported separately.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*Scanner) Name() string { return "flatpak" }
+
+// Version implements scanner.VersionedScanner.
+func (*Scanner) Version() string { return "0.1.0" }
+
+// Kind implements scanner.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Scan attempts to find Flatpak installations and record the applications
+// and runtimes deployed into them.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "flatpak/Scanner.Scan"),
+		label.String("version", ps.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rs, err := records(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*claircore.Package
+	for _, r := range rs {
+		pkg := &claircore.Package{
+			Name:           r.ID,
+			Version:        r.Version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "flatpak:" + r.ref(),
+			Arch:           r.Arch,
+			RepositoryHint: r.Origin,
+		}
+		ret = append(ret, pkg)
+	}
+	return ret, nil
+}
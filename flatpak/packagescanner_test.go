@@ -0,0 +1,142 @@
+package flatpak_test
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/flatpak"
+)
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/install")
+
+	got, err := new(flatpak.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:           "org.example.Bundled",
+			Version:        "0.2.0",
+			Kind:           claircore.BINARY,
+			PackageDB:      "flatpak:home/user/.local/share/flatpak/app/org.example.Bundled/x86_64/master",
+			Arch:           "x86_64",
+			RepositoryHint: "org.example.Bundled-origin",
+		},
+		{
+			// Only the active deployment is reported.
+			Name:           "org.gajim.Gajim",
+			Version:        "1.8.4",
+			Kind:           claircore.BINARY,
+			PackageDB:      "flatpak:var/lib/flatpak/app/org.gajim.Gajim/x86_64/stable",
+			Arch:           "x86_64",
+			RepositoryHint: "flathub",
+		},
+		{
+			// Without metadata, a runtime's version is its branch.
+			Name:           "org.freedesktop.Platform",
+			Version:        "22.08",
+			Kind:           claircore.BINARY,
+			PackageDB:      "flatpak:var/lib/flatpak/runtime/org.freedesktop.Platform/x86_64/22.08",
+			Arch:           "x86_64",
+			RepositoryHint: "flathub",
+		},
+		{
+			Name:           "org.freedesktop.Platform",
+			Version:        "23.08.18",
+			Kind:           claircore.BINARY,
+			PackageDB:      "flatpak:var/lib/flatpak/runtime/org.freedesktop.Platform/x86_64/23.08",
+			Arch:           "x86_64",
+			RepositoryHint: "flathub",
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestRepoScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/install")
+
+	got, err := new(flatpak.RepoScanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Repository{
+		// Installed from a bundle, so there's no URL.
+		{Name: "flatpak", Key: "org.example.Bundled-origin"},
+		{Name: "flatpak", Key: "flathub", URI: "https://dl.flathub.org/repo/"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// DirLayer writes the contents of the directory "dir" to a layer tarball,
+// keeping symlinks as symlinks.
+func dirLayer(t *testing.T, dir string) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "flatpak.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		n, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		h, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(n)
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		if link != "" {
+			return nil
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("f", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
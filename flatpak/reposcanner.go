@@ -0,0 +1,71 @@
+package flatpak
+
+import (
+	"context"
+	"runtime/trace"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner  = (*RepoScanner)(nil)
+	_ indexer.RepositoryScanner = (*RepoScanner)(nil)
+)
+
+// RepoScanner reports the remotes applications and runtimes were installed
+// from, as repositories named "flatpak" keyed by the remote's name.
+//
+// The zero value is ready to use.
+type RepoScanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*RepoScanner) Name() string { return "flatpak-remote" }
+
+// Version implements scanner.VersionedScanner.
+func (*RepoScanner) Version() string { return "0.1.0" }
+
+// Kind implements scanner.VersionedScanner.
+func (*RepoScanner) Kind() string { return "repository" }
+
+// Scan attempts to find Flatpak installations and record the remotes their
+// applications and runtimes were installed from.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (rs *RepoScanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Repository, error) {
+	defer trace.StartRegion(ctx, "RepoScanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "flatpak/RepoScanner.Scan"),
+		label.String("version", rs.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	recs, err := records(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*claircore.Repository
+	seen := make(map[[2]string]struct{})
+	for _, r := range recs {
+		repo := r.repository()
+		if repo == nil {
+			continue
+		}
+		k := [2]string{repo.Key, repo.URI}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ret = append(ret, repo)
+	}
+	return ret, nil
+}
@@ -0,0 +1,238 @@
+package ghsa
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+// Query fetches a page of vulnerabilities, oldest update first, along with
+// the most recently updated one.
+//
+// See https://docs.github.com/en/graphql/reference/queries#securityvulnerabilities
+const query = `query($ecosystem: SecurityAdvisoryEcosystem!, $first: Int!, $after: String) {
+  latest: securityVulnerabilities(ecosystem: $ecosystem, first: 1, orderBy: {field: UPDATED_AT, direction: DESC}) {
+    nodes { updatedAt }
+  }
+  page: securityVulnerabilities(ecosystem: $ecosystem, first: $first, after: $after, orderBy: {field: UPDATED_AT, direction: ASC}) {
+    pageInfo { endCursor hasNextPage }
+    nodes {
+      updatedAt
+      severity
+      vulnerableVersionRange
+      firstPatchedVersion { identifier }
+      package { ecosystem name }
+      advisory {
+        ghsaId
+        summary
+        description
+        publishedAt
+        withdrawnAt
+        identifiers { type value }
+        references { url }
+      }
+    }
+  }
+}`
+
+type response struct {
+	Data   *result `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type result struct {
+	Latest struct {
+		Nodes []struct {
+			UpdatedAt string `json:"updatedAt"`
+		} `json:"nodes"`
+	} `json:"latest"`
+	Page struct {
+		PageInfo struct {
+			EndCursor   string `json:"endCursor"`
+			HasNextPage bool   `json:"hasNextPage"`
+		} `json:"pageInfo"`
+		Nodes []node `json:"nodes"`
+	} `json:"page"`
+}
+
+// Node is a SecurityVulnerability: one package's affected range in an
+// advisory.
+type node struct {
+	UpdatedAt              string `json:"updatedAt"`
+	Severity               string `json:"severity"`
+	VulnerableVersionRange string `json:"vulnerableVersionRange"`
+	FirstPatchedVersion    *struct {
+		Identifier string `json:"identifier"`
+	} `json:"firstPatchedVersion"`
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Advisory struct {
+		GHSAID      string  `json:"ghsaId"`
+		Summary     string  `json:"summary"`
+		Description string  `json:"description"`
+		PublishedAt string  `json:"publishedAt"`
+		WithdrawnAt *string `json:"withdrawnAt"`
+		Identifiers []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"identifiers"`
+		References []struct {
+			URL string `json:"url"`
+		} `json:"references"`
+	} `json:"advisory"`
+}
+
+// Vulnerabilities returns the Vulnerability described by the node, or none if
+// the advisory has been withdrawn or the range can't be understood.
+func (n *node) Vulnerabilities(ctx context.Context, repo *claircore.Repository) ([]*claircore.Vulnerability, error) {
+	a := &n.Advisory
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "ghsa/node.Vulnerabilities"),
+		label.String("advisory", a.GHSAID))
+	switch {
+	case a.WithdrawnAt != nil:
+		zlog.Debug(ctx).Msg("advisory withdrawn, skipping")
+		return nil, nil
+	case n.Package.Ecosystem != "PIP":
+		return nil, fmt.Errorf("ghsa: %s: unexpected ecosystem %q", a.GHSAID, n.Package.Ecosystem)
+	}
+	spec, r, err := parseRange(n.VulnerableVersionRange)
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Str("range", n.VulnerableVersionRange).
+			Msg("malformed version range in advisory")
+		return nil, nil
+	}
+
+	name := a.GHSAID
+	var cves []string
+	for _, id := range a.Identifiers {
+		if id.Type == "CVE" {
+			cves = append(cves, id.Value)
+		}
+	}
+	if len(cves) != 0 {
+		name += " (" + strings.Join(cves, ", ") + ")"
+	}
+	desc := a.Description
+	if desc == "" {
+		desc = a.Summary
+	}
+	links := make([]string, 0, len(a.References))
+	for _, r := range a.References {
+		links = append(links, r.URL)
+	}
+	var issued time.Time
+	if a.PublishedAt != "" {
+		issued, err = time.Parse(time.RFC3339, a.PublishedAt)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Msg("unparsable published date")
+		}
+	}
+
+	v := &claircore.Vulnerability{
+		Name:               name,
+		Updater:            "ghsa",
+		Description:        desc,
+		Issued:             issued,
+		Links:              strings.Join(links, " "),
+		Aliases:            append([]string{a.GHSAID}, cves...),
+		Severity:           n.Severity,
+		NormalizedSeverity: normalizeSeverity(n.Severity),
+		Package: &claircore.Package{
+			Name: strings.ToLower(n.Package.Name),
+			Kind: claircore.BINARY,
+			// Like pyupio, the "version" is a specifier the python matcher
+			// checks installed versions against.
+			Version: spec,
+		},
+		Repo:  repo,
+		Range: r,
+	}
+	if n.FirstPatchedVersion != nil {
+		v.FixedInVersion = n.FirstPatchedVersion.Identifier
+	}
+	return []*claircore.Vulnerability{v}, nil
+}
+
+func normalizeSeverity(s string) claircore.Severity {
+	switch s {
+	case "LOW":
+		return claircore.Low
+	case "MODERATE":
+		return claircore.Medium
+	case "HIGH":
+		return claircore.High
+	case "CRITICAL":
+		return claircore.Critical
+	default:
+		return claircore.Unknown
+	}
+}
+
+// ParseRange turns a GitHub version range, such as ">= 2.0, < 2.0.10", into a
+// PEP 440 specifier and the normalized version range.
+//
+// See https://docs.github.com/en/graphql/reference/objects#securityvulnerability
+func parseRange(s string) (string, *claircore.Range, error) {
+	r := claircore.Range{
+		Lower: claircore.Version{Kind: "pep440"},
+		Upper: claircore.Version{Kind: "pep440"},
+	}
+	// No upper bound, so everything later is affected.
+	r.Upper.V[0] = math.MaxInt32
+	var spec []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		i := strings.IndexByte(c, ' ')
+		if i == -1 {
+			return "", nil, fmt.Errorf("ghsa: malformed constraint %q", c)
+		}
+		op, ver := c[:i], strings.TrimSpace(c[i+1:])
+		pv, err := pep440.Parse(ver)
+		if err != nil {
+			return "", nil, err
+		}
+		v := pv.Version()
+		// The Range is half-open, so the least significant component is bumped
+		// to turn an exclusive lower bound or an inclusive upper bound into
+		// the other kind.
+		switch op {
+		case "=":
+			op = "=="
+			r.Lower = v
+			r.Upper = v
+			r.Upper.V[len(r.Upper.V)-1]++
+		case ">=":
+			r.Lower = v
+		case ">":
+			r.Lower = v
+			r.Lower.V[len(r.Lower.V)-1]++
+		case "<":
+			r.Upper = v
+		case "<=":
+			r.Upper = v
+			r.Upper.V[len(r.Upper.V)-1]++
+		default:
+			return "", nil, fmt.Errorf("ghsa: unknown operator in constraint %q", c)
+		}
+		spec = append(spec, op+ver)
+	}
+	return strings.Join(spec, ","), &r, nil
+}
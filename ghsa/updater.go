@@ -0,0 +1,292 @@
+// Package ghsa provides an updater for importing vulnerability information
+// from the GitHub Security Advisory database.
+//
+// The database is queried through the GitHub GraphQL API, which returns
+// results a page at a time, so the Updater is a driver.PaginatedUpdater.
+// Only the PyPI ecosystem is supported.
+package ghsa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+const (
+	defaultURL      = `https://api.github.com/graphql`
+	defaultPageSize = 100
+	// The API refuses to return more than this many nodes at once.
+	maxPageSize = 100
+)
+
+var (
+	_ driver.Updater          = (*Updater)(nil)
+	_ driver.PaginatedUpdater = (*Updater)(nil)
+	_ driver.Configurable     = (*Updater)(nil)
+
+	defaultRepo = claircore.Repository{
+		Name: "pypi",
+		URI:  "https://pypi.org/simple",
+	}
+)
+
+// Updater queries the GitHub Security Advisory database for vulnerabilities
+// in PyPI packages.
+//
+// The zero value is not safe to use.
+type Updater struct {
+	driver.NoopUpdater
+	url      *url.URL
+	client   *http.Client
+	repo     *claircore.Repository
+	token    string
+	pageSize int
+}
+
+// NewUpdater returns a configured Updater or reports an error.
+func NewUpdater(opt ...Option) (*Updater, error) {
+	u := Updater{}
+	for _, f := range opt {
+		if err := f(&u); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.url == nil {
+		var err error
+		u.url, err = url.Parse(defaultURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if u.client == nil {
+		u.client = http.DefaultClient // TODO(hank) Remove DefaultClient
+	}
+	if u.repo == nil {
+		u.repo = &defaultRepo
+	}
+	if u.pageSize == 0 {
+		u.pageSize = defaultPageSize
+	}
+
+	return &u, nil
+}
+
+// Option controls the configuration of an Updater.
+type Option func(*Updater) error
+
+// WithClient sets the http.Client that the updater should use for requests.
+//
+// If not passed to NewUpdater, http.DefaultClient will be used.
+func WithClient(c *http.Client) Option {
+	return func(u *Updater) error {
+		u.client = c
+		return nil
+	}
+}
+
+// WithRepo sets the repository information that will be associated with all the
+// vulnerabilities found.
+//
+// If not passed to NewUpdater, a default Repository will be used.
+func WithRepo(r *claircore.Repository) Option {
+	return func(u *Updater) error {
+		u.repo = r
+		return nil
+	}
+}
+
+// WithURL sets the GraphQL endpoint the updater should query.
+//
+// If not passed to NewUpdater, the public GitHub API will be used.
+func WithURL(uri string) Option {
+	u, err := url.Parse(uri)
+	return func(up *Updater) error {
+		if err != nil {
+			return err
+		}
+		up.url = u
+		return nil
+	}
+}
+
+// WithToken sets the token used to authenticate to the API.
+//
+// The GitHub API refuses unauthenticated GraphQL queries, so this is needed
+// unless a different endpoint is used.
+func WithToken(t string) Option {
+	return func(u *Updater) error {
+		u.token = t
+		return nil
+	}
+}
+
+// WithPageSize sets the number of records requested at a time.
+//
+// If not passed to NewUpdater, the API maximum of 100 is used.
+func WithPageSize(n int) Option {
+	return func(u *Updater) error {
+		if n < 1 || n > maxPageSize {
+			return fmt.Errorf("ghsa: invalid page size %d", n)
+		}
+		u.pageSize = n
+		return nil
+	}
+}
+
+// Config is the configuration for the updater.
+//
+// By convention, this is in a map called "ghsa".
+type Config struct {
+	URL      string `json:"url" yaml:"url"`
+	Token    string `json:"token" yaml:"token"`
+	PageSize int    `json:"page_size" yaml:"page_size"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "ghsa/Updater.Configure"))
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+
+	if cfg.URL != "" {
+		uri, err := url.Parse(cfg.URL)
+		if err != nil {
+			return err
+		}
+		u.url = uri
+		zlog.Info(ctx).
+			Msg("configured URL")
+	}
+	if cfg.Token != "" {
+		u.token = cfg.Token
+		zlog.Info(ctx).
+			Msg("configured token")
+	}
+	if cfg.PageSize != 0 {
+		if err := WithPageSize(cfg.PageSize)(u); err != nil {
+			return err
+		}
+		zlog.Info(ctx).
+			Int("size", u.pageSize).
+			Msg("configured page size")
+	}
+	u.client = c
+	zlog.Info(ctx).
+		Msg("configured HTTP client")
+	return nil
+}
+
+// Name implements driver.Updater.
+func (u *Updater) Name() string { return "ghsa" }
+
+// FetchPage implements driver.PaginatedUpdater.
+//
+// Records are requested in order of their last update, so a record changed
+// during a pass shows up again on a later page. The Fingerprint is the update
+// time of the most recently changed record.
+func (u *Updater) FetchPage(ctx context.Context, fp driver.Fingerprint, cursor string) (*driver.Page, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "ghsa/Updater.FetchPage"))
+	zlog.Debug(ctx).
+		Str("cursor", cursor).
+		Msg("fetching page")
+	res, err := u.query(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	var latest string
+	if ns := res.Latest.Nodes; len(ns) != 0 {
+		latest = ns[0].UpdatedAt
+	}
+	if cursor == "" && latest != "" && driver.Fingerprint(latest) == fp {
+		return nil, driver.Unchanged
+	}
+
+	p := driver.Page{
+		Fingerprint: driver.Fingerprint(latest),
+	}
+	for i := range res.Page.Nodes {
+		vs, err := res.Page.Nodes[i].Vulnerabilities(ctx, u.repo)
+		if err != nil {
+			return nil, err
+		}
+		p.Vulnerabilities = append(p.Vulnerabilities, vs...)
+	}
+	if res.Page.PageInfo.HasNextPage {
+		p.Next = res.Page.PageInfo.EndCursor
+		if p.Next == "" {
+			return nil, errors.New("ghsa: next page reported without a cursor")
+		}
+	}
+	zlog.Debug(ctx).
+		Int("nodes", len(res.Page.Nodes)).
+		Int("count", len(p.Vulnerabilities)).
+		Bool("last", p.Next == "").
+		Msg("fetched page")
+	return &p, nil
+}
+
+// Query runs the GraphQL query for the page after "cursor".
+func (u *Updater) query(ctx context.Context, cursor string) (*result, error) {
+	vars := map[string]interface{}{
+		"ecosystem": "PIP",
+		"first":     u.pageSize,
+	}
+	if cursor != "" {
+		vars["after"] = cursor
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "claircore/ghsa/Updater")
+	req.Header.Set("Content-Type", "application/json")
+	if u.token != "" {
+		req.Header.Set("Authorization", "bearer "+u.token)
+	}
+	res, err := u.client.Do(req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return nil, fmt.Errorf("ghsa: unexpected HTTP response: %d (%s): %q", res.StatusCode, res.Status, msg)
+	}
+	var r response
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("ghsa: unable to decode response: %w", err)
+	}
+	if len(r.Errors) != 0 {
+		return nil, fmt.Errorf("ghsa: query failed: %s", r.Errors[0].Message)
+	}
+	if r.Data == nil {
+		return nil, errors.New("ghsa: empty response")
+	}
+	return r.Data, nil
+}
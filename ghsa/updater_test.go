@@ -0,0 +1,306 @@
+package ghsa
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/jsonblob"
+	"github.com/quay/claircore/libvuln/updates"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+const testToken = "hunter2"
+
+// TestServer answers the GraphQL query with the nodes in the testdata,
+// failing once for each cursor in "fail".
+type testServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	nodes   []json.RawMessage
+	fail    map[string]bool
+	cursors []string
+}
+
+func newTestServer(t *testing.T, fail ...string) *testServer {
+	t.Helper()
+	b, err := ioutil.ReadFile("testdata/nodes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{fail: make(map[string]bool)}
+	if err := json.Unmarshal(b, &s.nodes); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range fail {
+		s.fail[c] = true
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("authorization") != "bearer "+testToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Variables struct {
+			First int    `json:"first"`
+			After string `json:"after"`
+		} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := req.Variables.After
+	s.cursors = append(s.cursors, c)
+	if s.fail[c] {
+		delete(s.fail, c)
+		http.Error(w, "injected failure", http.StatusBadGateway)
+		return
+	}
+	off := 0
+	if c != "" {
+		var err error
+		if off, err = strconv.Atoi(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	end := off + req.Variables.First
+	if end > len(s.nodes) {
+		end = len(s.nodes)
+	}
+
+	var res struct {
+		Data struct {
+			Latest struct {
+				Nodes []json.RawMessage `json:"nodes"`
+			} `json:"latest"`
+			Page struct {
+				PageInfo struct {
+					EndCursor   string `json:"endCursor"`
+					HasNextPage bool   `json:"hasNextPage"`
+				} `json:"pageInfo"`
+				Nodes []json.RawMessage `json:"nodes"`
+			} `json:"page"`
+		} `json:"data"`
+	}
+	res.Data.Latest.Nodes = s.nodes[len(s.nodes)-1:]
+	res.Data.Page.Nodes = s.nodes[off:end]
+	res.Data.Page.PageInfo.EndCursor = strconv.Itoa(end)
+	res.Data.Page.PageInfo.HasNextPage = end < len(s.nodes)
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(&res)
+}
+
+func (s *testServer) Updater(t *testing.T) *Updater {
+	t.Helper()
+	u, err := NewUpdater(
+		WithURL(s.URL),
+		WithClient(s.Client()),
+		WithToken(testToken),
+		WithPageSize(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func (s *testServer) Cursors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.cursors...)
+}
+
+func TestFetchPage(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := newTestServer(t)
+	u := srv.Updater(t)
+
+	var got []*claircore.Vulnerability
+	var fp driver.Fingerprint
+	cursor, pages := "", 0
+	for {
+		p, err := u.FetchPage(ctx, "", cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		got = append(got, p.Vulnerabilities...)
+		fp = p.Fingerprint
+		if p.Next == "" {
+			break
+		}
+		cursor = p.Next
+	}
+	if got, want := pages, 3; got != want {
+		t.Errorf("got %d pages, want %d", got, want)
+	}
+	if got, want := fp, driver.Fingerprint("2021-06-15T12:30:00Z"); got != want {
+		t.Errorf("got fingerprint %q, want %q", got, want)
+	}
+	// The withdrawn advisory is skipped.
+	if got, want := len(got), 4; got != want {
+		t.Fatalf("got %d vulnerabilities, want %d", got, want)
+	}
+	want := &claircore.Vulnerability{
+		Name:               "GHSA-aaaa-bbbb-cccc (CVE-2021-00001)",
+		Updater:            "ghsa",
+		Description:        "Examplepkg before 1.11.19 and 2.x before 2.0.10 allows remote attackers to do something bad.",
+		Issued:             time.Date(2021, 2, 20, 8, 15, 0, 0, time.UTC),
+		Links:              "https://example.com/advisories/1 https://github.com/example/examplepkg/commit/abc123",
+		Aliases:            []string{"GHSA-aaaa-bbbb-cccc", "CVE-2021-00001"},
+		Severity:           "HIGH",
+		NormalizedSeverity: claircore.High,
+		Package: &claircore.Package{
+			Name:    "examplepkg",
+			Kind:    claircore.BINARY,
+			Version: "<1.11.19",
+		},
+		Repo:           &defaultRepo,
+		Range:          got[0].Range,
+		FixedInVersion: "1.11.19",
+	}
+	if !cmp.Equal(got[0], want) {
+		t.Error(cmp.Diff(got[0], want))
+	}
+	if got, want := got[2].Description, "Otherpkg leaks secrets"; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+
+	// The source hasn't changed since the pass.
+	if _, err := u.FetchPage(ctx, fp, ""); err != driver.Unchanged {
+		t.Errorf("got error %v, want %v", err, driver.Unchanged)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tt := []struct {
+		In         string
+		Spec       string
+		Affected   []string
+		Unaffected []string
+	}{
+		{
+			In:         "< 1.11.19",
+			Spec:       "<1.11.19",
+			Affected:   []string{"0.1", "1.11.18"},
+			Unaffected: []string{"1.11.19", "2.0"},
+		},
+		{
+			In:         ">= 2.0, < 2.0.10",
+			Spec:       ">=2.0,<2.0.10",
+			Affected:   []string{"2.0", "2.0.9"},
+			Unaffected: []string{"1.9", "2.0.10"},
+		},
+		{
+			In:         "<= 1.4",
+			Spec:       "<=1.4",
+			Affected:   []string{"1.0", "1.4"},
+			Unaffected: []string{"1.5"},
+		},
+		{
+			In:         "> 1.0, <= 1.2",
+			Spec:       ">1.0,<=1.2",
+			Affected:   []string{"1.1", "1.2"},
+			Unaffected: []string{"1.0", "1.3"},
+		},
+		{
+			In:         "= 3.1.0",
+			Spec:       "==3.1.0",
+			Affected:   []string{"3.1.0"},
+			Unaffected: []string{"3.0.9", "3.1.1"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.In, func(t *testing.T) {
+			spec, r, err := parseRange(tc.In)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := spec, tc.Spec; got != want {
+				t.Errorf("got spec %q, want %q", got, want)
+			}
+			check := func(s string, want bool) {
+				t.Helper()
+				v, err := pep440.Parse(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				nv := v.Version()
+				if got := r.Contains(&nv); got != want {
+					t.Errorf("%s: got affected: %v, want: %v", s, got, want)
+				}
+			}
+			for _, s := range tc.Affected {
+				check(s, true)
+			}
+			for _, s := range tc.Unaffected {
+				check(s, false)
+			}
+		})
+	}
+	for _, in := range []string{"1.0", "~> 1.0", "< not-a-version"} {
+		if _, _, err := parseRange(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+// TestResume checks that an update interrupted by an API error picks up from
+// the page it stopped at.
+func TestResume(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := newTestServer(t, "4")
+	s, err := jsonblob.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := updates.NewManager(ctx, s, updates.LocalLockSource(), srv.Client(),
+		updates.WithEnabled([]string{}),
+		updates.WithOutOfTree([]driver.Updater{srv.Updater(t)}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Run(ctx); err == nil {
+		t.Fatal("expected error")
+	}
+	if got, want := len(s.Entries()), 0; got != want {
+		t.Fatalf("got %d update operations, want %d", got, want)
+	}
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := srv.Cursors(), []string{"", "2", "4", "4"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	es := s.Entries()
+	if got, want := len(es), 1; got != want {
+		t.Fatalf("got %d update operations, want %d", got, want)
+	}
+	for _, e := range es {
+		if got, want := len(e.Vuln), 4; got != want {
+			t.Errorf("got %d vulnerabilities, want %d", got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package ghsa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/python"
+)
+
+// UpdaterSet returns an UpdaterSet containing a GHSA Updater.
+//
+// This set isn't registered by default, because the GitHub API needs a token.
+// Pass it to libvuln with the "ghsa" name and configure the token through
+// Config.
+func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
+	us := driver.NewUpdaterSet()
+	repo := python.Repository
+	u, err := NewUpdater(WithRepo(&repo))
+	if err != nil {
+		return us, fmt.Errorf("failed to create ghsa updater: %v", err)
+	}
+	err = us.Add(u)
+	if err != nil {
+		return us, err
+	}
+	return us, nil
+}
@@ -26,6 +26,7 @@ require (
 	github.com/rs/zerolog v1.20.0
 	github.com/ulikunitz/xz v0.5.7
 	go.opentelemetry.io/otel v0.15.0
+	golang.org/x/mod v0.3.0
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
 	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
 	golang.org/x/tools v0.0.0-20200811032001-fd80f4dbb3ea
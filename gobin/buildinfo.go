@@ -0,0 +1,196 @@
+package gobin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// These are the markers the Go linker puts around the module information
+// embedded in binaries built in module mode. They're the same in every Go
+// release that records module information, and appear no matter the
+// executable format, so the information can be found without parsing the
+// binary.
+const (
+	infoStart = "0w\xaf\x0c\x92t\x08\x02A\xe1\xc1\x07\xe6\xd6\x18\xe6"
+	infoEnd   = "\xf92C1\x86\x18 r\x00\x82B\x10A\x16\xd8\xf2"
+)
+
+// The build information header starts with this magic number. Since Go 1.18,
+// the header is followed by the toolchain version and the module
+// information, each prefixed by its length as a uvarint; earlier releases
+// record pointers to them instead.
+const (
+	headerMagic  = "\xff Go buildinf:"
+	headerSize   = 32
+	headerInline = 0x2 // Flag for the strings following the header.
+	// MaxHeader bounds how far before the module information the header
+	// can start: the header, the toolchain version, and two lengths.
+	maxHeader = 256
+)
+
+// MaxInfo is the largest module information blob that will be read.
+const maxInfo = 1 << 20
+
+// ErrTooLarge is reported when the module information doesn't end within
+// maxInfo bytes.
+var errTooLarge = errors.New("gobin: module information too large")
+
+// FindInfo reads through "r" looking for embedded module information,
+// reporting the text between the markers and the version of the toolchain
+// that built the binary. The version is only known for binaries built by Go
+// 1.18 and later, and is the empty string otherwise.
+//
+// If there's no module information, ("", "", io.EOF) is returned.
+func findInfo(r io.Reader) (string, string, error) {
+	// Keep enough of the end of each read to catch a marker spanning reads,
+	// and the header before it.
+	const tail = maxHeader
+	br := bufio.NewReaderSize(r, 64*1024)
+	buf := make([]byte, 0, 64*1024+tail)
+	for {
+		n, err := io.ReadFull(br, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if i := bytes.Index(buf, []byte(infoStart)); i != -1 {
+			v := headerVersion(buf[:i])
+			info, err := readInfo(io.MultiReader(bytes.NewReader(buf[i+len(infoStart):]), br))
+			return info, v, err
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+			return "", "", io.EOF
+		default:
+			return "", "", err
+		}
+		buf = buf[:copy(buf, buf[len(buf)-tail:])]
+	}
+}
+
+// HeaderVersion returns the toolchain version from a build information
+// header that "b" ends with, up to the module information. If "b" doesn't end
+// with a header with the strings inline, the empty string is returned.
+func headerVersion(b []byte) string {
+	if len(b) > maxHeader {
+		b = b[len(b)-maxHeader:]
+	}
+	h := bytes.LastIndex(b, []byte(headerMagic))
+	if h == -1 || len(b) < h+headerSize || b[h+len(headerMagic)+1]&headerInline == 0 {
+		return ""
+	}
+	rest := b[h+headerSize:]
+	n, sz := binary.Uvarint(rest)
+	if sz <= 0 || n > uint64(len(rest)-sz) {
+		return ""
+	}
+	v, rest := string(rest[sz:sz+int(n)]), rest[sz+int(n):]
+	// All that's left should be the length of the module information.
+	if _, sz := binary.Uvarint(rest); sz <= 0 || sz != len(rest) {
+		return ""
+	}
+	return v
+}
+
+// ReadInfo reads up to the end marker.
+func readInfo(r io.Reader) (string, error) {
+	var b strings.Builder
+	lr := io.LimitReader(r, maxInfo)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := lr.Read(chunk)
+		b.Write(chunk[:n])
+		if i := strings.Index(b.String(), infoEnd); i != -1 {
+			return b.String()[:i], nil
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF):
+			if b.Len() >= maxInfo {
+				return "", errTooLarge
+			}
+			return "", io.ErrUnexpectedEOF
+		default:
+			return "", err
+		}
+	}
+}
+
+// BuildInfo is the subset of the module information in a binary that's
+// needed to identify it.
+type buildInfo struct {
+	// GoVersion is the version of the toolchain that built the binary, like
+	// "go1.21.5", if it's known.
+	GoVersion string
+	// Path is the package path of the main package.
+	Path string
+	// Main is the main module.
+	Main module
+	// Deps are the module dependencies.
+	Deps []module
+	// Settings are the build settings, such as "-ldflags". Binaries built
+	// with Go before 1.18 don't record these.
+	Settings map[string]string
+}
+
+// Module is a module and the version that was used.
+type module struct {
+	Path    string
+	Version string
+}
+
+// ParseInfo parses the text format of the module information.
+//
+// Lines are tab-separated fields: a "path" line, a "mod" line for the main
+// module, "dep" lines for dependencies each optionally followed by a "=>"
+// line for its replacement, and "build" lines for settings.
+func parseInfo(s string) (*buildInfo, error) {
+	bi := buildInfo{Settings: make(map[string]string)}
+	for _, l := range strings.Split(s, "\n") {
+		fs := strings.Split(l, "\t")
+		switch fs[0] {
+		case "path":
+			if len(fs) < 2 {
+				return nil, errors.New("gobin: malformed path line")
+			}
+			bi.Path = fs[1]
+		case "mod":
+			if len(fs) < 3 {
+				return nil, errors.New("gobin: malformed mod line")
+			}
+			bi.Main = module{Path: fs[1], Version: fs[2]}
+		case "dep":
+			if len(fs) < 3 {
+				return nil, errors.New("gobin: malformed dep line")
+			}
+			bi.Deps = append(bi.Deps, module{Path: fs[1], Version: fs[2]})
+		case "build":
+			if len(fs) < 2 {
+				return nil, errors.New("gobin: malformed build line")
+			}
+			kv := strings.Join(fs[1:], "\t")
+			i := strings.IndexByte(kv, '=')
+			if i == -1 {
+				return nil, errors.New("gobin: malformed build line")
+			}
+			k, v := kv[:i], kv[i+1:]
+			// Values containing spaces or quotes are quoted.
+			if strings.HasPrefix(v, `"`) {
+				uv, err := strconv.Unquote(v)
+				if err != nil {
+					return nil, fmt.Errorf("gobin: malformed build setting %q: %w", k, err)
+				}
+				v = uv
+			}
+			bi.Settings[k] = v
+		}
+	}
+	if bi.Main.Path == "" {
+		return nil, errors.New("gobin: no main module")
+	}
+	return &bi, nil
+}
@@ -0,0 +1,61 @@
+package gobin
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewCoalescer returns the coalescer for Go binaries.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// A binary replaced in a later layer is reported as the later version only,
+// along with the standard library it was built with.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	// Binary path to package IDs.
+	byPath := make(map[string][]string)
+	for _, l := range ls {
+		found := make(map[string][]string)
+		for _, pkg := range l.Pkgs {
+			found[pkg.PackageDB] = append(found[pkg.PackageDB], pkg.ID)
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = []*claircore.Environment{
+				{
+					PackageDB:    pkg.PackageDB,
+					IntroducedIn: l.Hash,
+				},
+			}
+		}
+		for db, ids := range found {
+			for _, id := range byPath[db] {
+				if !contains(ids, id) {
+					delete(ir.Packages, id)
+					delete(ir.Environments, id)
+				}
+			}
+			byPath[db] = ids
+		}
+	}
+	return ir, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
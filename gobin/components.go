@@ -0,0 +1,176 @@
+package gobin
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Component is a well-known project whose binaries are reported as packages.
+type component struct {
+	// Package is the name advisories for the project use. This is the Go
+	// module path the vulnerability databases key the project by.
+	Package string
+	// Modules are the main module paths of the project's binaries.
+	Modules []string
+	// Vars are linker-set variables the project stores its release version
+	// in, checked in order.
+	Vars []string
+	// Siblings are modules from the same project whose required versions
+	// track the project's release, for binaries built from within the
+	// project's repository where the main module has no version.
+	Siblings []string
+	// Staging means the modules are published with a v0 major version that
+	// lags the project's release: v0.21.3 is from v1.21.3.
+	Staging bool
+}
+
+// Components is the table of well-known projects.
+var components = []component{
+	{
+		Package: "k8s.io/kubernetes",
+		Modules: []string{"k8s.io/kubernetes"},
+		Vars: []string{
+			"k8s.io/component-base/version.gitVersion",
+			"k8s.io/client-go/pkg/version.gitVersion",
+			"k8s.io/kubernetes/pkg/version.gitVersion",
+		},
+	},
+	{
+		// These are the staging repositories: modules developed in the
+		// kubernetes repository and published separately. Some have main
+		// packages of their own.
+		Package: "k8s.io/kubernetes",
+		Modules: []string{
+			"k8s.io/apiextensions-apiserver",
+			"k8s.io/apiserver",
+			"k8s.io/cloud-provider",
+			"k8s.io/kube-aggregator",
+			"k8s.io/kube-controller-manager",
+			"k8s.io/kube-proxy",
+			"k8s.io/kube-scheduler",
+			"k8s.io/kubectl",
+			"k8s.io/kubelet",
+			"k8s.io/sample-apiserver",
+		},
+		Vars: []string{
+			"k8s.io/component-base/version.gitVersion",
+			"k8s.io/client-go/pkg/version.gitVersion",
+		},
+		Staging: true,
+	},
+	{
+		// etcd v3.4 and earlier.
+		Package: "go.etcd.io/etcd",
+		Modules: []string{"go.etcd.io/etcd"},
+	},
+	{
+		Package: "go.etcd.io/etcd/v3",
+		Modules: []string{
+			"go.etcd.io/etcd/v3",
+			"go.etcd.io/etcd/server/v3",
+			"go.etcd.io/etcd/etcdctl/v3",
+			"go.etcd.io/etcd/etcdutl/v3",
+		},
+		Siblings: []string{
+			"go.etcd.io/etcd/api/v3",
+			"go.etcd.io/etcd/client/pkg/v3",
+		},
+	},
+	{
+		Package: "helm.sh/helm/v3",
+		Modules: []string{"helm.sh/helm/v3"},
+		Vars:    []string{"helm.sh/helm/v3/internal/version.version"},
+	},
+	{
+		// Helm v2.
+		Package: "k8s.io/helm",
+		Modules: []string{"k8s.io/helm"},
+		Vars:    []string{"k8s.io/helm/pkg/version.Version"},
+	},
+}
+
+// Lookup finds the component for a main module path.
+func lookup(path string) (*component, bool) {
+	for i := range components {
+		for _, m := range components[i].Modules {
+			if m == path {
+				return &components[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Version reports the release version of the component that the binary was
+// built from, as a semver string with the leading "v".
+//
+// The main module's version is used if it's a release. Otherwise, the
+// linker-set variables and then the sibling modules are consulted.
+func (c *component) version(bi *buildInfo) (string, bool) {
+	if v := c.translate(bi.Main.Version); v != "" {
+		return v, true
+	}
+	flags := bi.Settings["-ldflags"]
+	for _, name := range c.Vars {
+		if v := c.translate(linkerVar(flags, name)); v != "" {
+			return v, true
+		}
+	}
+	for _, s := range c.Siblings {
+		for _, d := range bi.Deps {
+			if d.Path != s {
+				continue
+			}
+			if v := c.translate(d.Version); v != "" {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Translate returns the canonical release version for a module version, or
+// the empty string if it isn't a release. Pseudo-versions and "(devel)"
+// aren't releases.
+func (c *component) translate(v string) string {
+	// Incompatible modules use a "+incompatible" suffix, which semver treats
+	// as build metadata.
+	if !semver.IsValid(v) || isPseudo(v) {
+		return ""
+	}
+	v = strings.TrimSuffix(v, "+incompatible")
+	if c.Staging && semver.Major(v) == "v0" {
+		v = "v1" + strings.TrimPrefix(v, "v0")
+	}
+	return v
+}
+
+// PseudoVersion matches the timestamp and revision of a pseudo-version.
+var pseudoVersion = regexp.MustCompile(`(^|[.-])(0\.)?\d{14}-[0-9a-f]{12}(\+incompatible)?$`)
+
+func isPseudo(v string) bool {
+	return strings.Count(v, "-") >= 2 && pseudoVersion.MatchString(v)
+}
+
+// LinkerVar finds the value set for the named variable in "-X" linker flags.
+func linkerVar(flags, name string) string {
+	fs := strings.Fields(flags)
+	for i, f := range fs {
+		var kv string
+		switch {
+		case f == "-X" && i+1 < len(fs):
+			kv = fs[i+1]
+		case strings.HasPrefix(f, "-X="):
+			kv = strings.TrimPrefix(f, "-X=")
+		default:
+			continue
+		}
+		kv = strings.Trim(kv, `'"`)
+		if strings.HasPrefix(kv, name+"=") {
+			return strings.TrimPrefix(kv, name+"=")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package gobin
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tt := []struct {
+		Module  string
+		Package string
+	}{
+		{"k8s.io/kubernetes", "k8s.io/kubernetes"},
+		{"k8s.io/kubectl", "k8s.io/kubernetes"},
+		{"k8s.io/kubelet", "k8s.io/kubernetes"},
+		{"k8s.io/kube-proxy", "k8s.io/kubernetes"},
+		{"k8s.io/apiextensions-apiserver", "k8s.io/kubernetes"},
+		{"go.etcd.io/etcd", "go.etcd.io/etcd"},
+		{"go.etcd.io/etcd/v3", "go.etcd.io/etcd/v3"},
+		{"go.etcd.io/etcd/server/v3", "go.etcd.io/etcd/v3"},
+		{"go.etcd.io/etcd/etcdctl/v3", "go.etcd.io/etcd/v3"},
+		{"helm.sh/helm/v3", "helm.sh/helm/v3"},
+		{"k8s.io/helm", "k8s.io/helm"},
+		{"k8s.io/client-go", ""},
+		{"github.com/example/tool", ""},
+	}
+	for _, tc := range tt {
+		c, ok := lookup(tc.Module)
+		switch {
+		case tc.Package == "" && ok:
+			t.Errorf("%s: got %q, want no component", tc.Module, c.Package)
+		case tc.Package == "":
+		case !ok:
+			t.Errorf("%s: got no component, want %q", tc.Module, tc.Package)
+		case c.Package != tc.Package:
+			t.Errorf("%s: got %q, want %q", tc.Module, c.Package, tc.Package)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tt := []struct {
+		Name string
+		Info buildInfo
+		Want string
+	}{
+		{
+			Name: "KubernetesLdflags",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubernetes", Version: "(devel)"},
+				Settings: map[string]string{
+					"-ldflags": "-s -w -X 'k8s.io/component-base/version.buildDate=2022-05-03T13:38:19Z' -X 'k8s.io/component-base/version.gitVersion=v1.24.0'",
+				},
+			},
+			Want: "v1.24.0",
+		},
+		{
+			Name: "KubernetesClientGo",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubernetes", Version: "(devel)"},
+				Settings: map[string]string{
+					"-ldflags": "-X k8s.io/client-go/pkg/version.gitVersion=v1.20.15",
+				},
+			},
+			Want: "v1.20.15",
+		},
+		{
+			Name: "KubernetesModule",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubernetes", Version: "v1.23.6"},
+			},
+			Want: "v1.23.6",
+		},
+		{
+			Name: "Staging",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubectl", Version: "v0.21.3"},
+			},
+			Want: "v1.21.3",
+		},
+		{
+			Name: "StagingLdflags",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubelet", Version: "(devel)"},
+				Settings: map[string]string{
+					"-ldflags": "-X=k8s.io/component-base/version.gitVersion=v1.22.1",
+				},
+			},
+			Want: "v1.22.1",
+		},
+		{
+			Name: "EtcdSibling",
+			Info: buildInfo{
+				Main: module{Path: "go.etcd.io/etcd/server/v3", Version: "(devel)"},
+				Deps: []module{
+					{Path: "go.etcd.io/bbolt", Version: "v1.3.6"},
+					{Path: "go.etcd.io/etcd/api/v3", Version: "v3.5.4"},
+				},
+			},
+			Want: "v3.5.4",
+		},
+		{
+			Name: "Etcd34",
+			Info: buildInfo{
+				Main: module{Path: "go.etcd.io/etcd", Version: "v3.3.27+incompatible"},
+			},
+			Want: "v3.3.27",
+		},
+		{
+			Name: "Helm",
+			Info: buildInfo{
+				Main: module{Path: "helm.sh/helm/v3", Version: "(devel)"},
+				Settings: map[string]string{
+					"-ldflags": "-w -s -X helm.sh/helm/v3/internal/version.version=v3.9.0 -X helm.sh/helm/v3/internal/version.gitCommit=7ceeda6c585217a19a1131663d8cd1f7d641b2a7",
+				},
+			},
+			Want: "v3.9.0",
+		},
+		{
+			Name: "Pseudo",
+			Info: buildInfo{
+				Main: module{Path: "k8s.io/kubectl", Version: "v0.0.0-20220503133819-e5e1b3f1a9df"},
+			},
+		},
+		{
+			Name: "PseudoSibling",
+			Info: buildInfo{
+				Main: module{Path: "go.etcd.io/etcd/v3", Version: "(devel)"},
+				Deps: []module{
+					{Path: "go.etcd.io/etcd/api/v3", Version: "v3.5.0-alpha.0.0.20220503133819-e5e1b3f1a9df"},
+				},
+			},
+		},
+		{
+			Name: "Devel",
+			Info: buildInfo{
+				Main: module{Path: "helm.sh/helm/v3", Version: "(devel)"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, ok := lookup(tc.Info.Main.Path)
+			if !ok {
+				t.Fatalf("no component for %q", tc.Info.Main.Path)
+			}
+			got, ok := c.version(&tc.Info)
+			if tc.Want == "" {
+				if ok {
+					t.Errorf("got %q, want no version", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("got no version, want %q", tc.Want)
+			}
+			if got != tc.Want {
+				t.Errorf("got %q, want %q", got, tc.Want)
+			}
+		})
+	}
+}
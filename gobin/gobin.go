@@ -0,0 +1,261 @@
+// Package gobin contains components for finding well-known projects' Go
+// binaries in container layers.
+//
+// Infrastructure images commonly ship binaries like kubectl, etcd, or helm
+// without any package database recording them. Go binaries embed the module
+// information they were built with, which is enough to identify these
+// projects and their release versions.
+//
+// Binaries built by Go 1.18 and later also record the toolchain version,
+// which identifies the standard library they link. The Updater imports the Go
+// vulnerability database, which covers both modules and the standard library,
+// for the Matcher to check these packages against.
+package gobin
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"runtime/trace"
+	"strconv"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/mod/semver"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner = (*Scanner)(nil)
+	_ indexer.PackageScanner   = (*Scanner)(nil)
+)
+
+// Scanner implements the scanner.PackageScanner interface.
+//
+// It looks at executables in the layer for embedded Go module information,
+// and reports a package for each binary built from a well-known project. The
+// package is named the way vulnerability databases name the project, has the
+// project's release version, and has the binary's path as its PackageDB.
+//
+// For binaries built by Go 1.18 and later, which record the toolchain
+// version, a "stdlib" package is also reported for the standard library
+// linked into the binary.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*Scanner) Name() string { return "gobin" }
+
+// Version implements scanner.VersionedScanner.
+func (*Scanner) Version() string { return "0.2.0" }
+
+// Kind implements scanner.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Magic numbers for the executable formats Go produces.
+var magic = [][]byte{
+	[]byte("\x7fELF"),
+	[]byte("MZ"),               // PE
+	[]byte("\xfe\xed\xfa\xce"), // Mach-O 32-bit
+	[]byte("\xfe\xed\xfa\xcf"), // Mach-O 64-bit
+	[]byte("\xce\xfa\xed\xfe"), // Mach-O 32-bit, reversed
+	[]byte("\xcf\xfa\xed\xfe"), // Mach-O 64-bit, reversed
+}
+
+// Scan attempts to find Go binaries from well-known projects and report them
+// as packages.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/Scanner.Scan"),
+		label.String("version", ps.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scan(ctx, r)
+}
+
+func scan(ctx context.Context, r io.Reader) ([]*claircore.Package, error) {
+	var ret []*claircore.Package
+	tr := tar.NewReader(r)
+	hdr := make([]byte, 4)
+	var h *tar.Header
+	var err error
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg || h.Mode&0111 == 0 || h.Size < int64(len(hdr)) {
+			continue
+		}
+		if _, err := io.ReadFull(tr, hdr); err != nil {
+			return nil, err
+		}
+		if !isExecutable(hdr) {
+			continue
+		}
+		n := filepath.Join("/", h.Name)
+		info, gover, err := findInfo(tr)
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF):
+			// Not a Go binary, or one without module information.
+			continue
+		case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, errTooLarge):
+			zlog.Debug(ctx).
+				Str("file", n).
+				Err(err).
+				Msg("unable to read module information")
+			continue
+		default:
+			return nil, err
+		}
+		bi, err := parseInfo(info)
+		if err != nil {
+			zlog.Debug(ctx).
+				Str("file", n).
+				Err(err).
+				Msg("unable to parse module information")
+			continue
+		}
+		bi.GoVersion = gover
+		if p := componentPackage(ctx, n, bi); p != nil {
+			ret = append(ret, p)
+		}
+		if p := stdlibPackage(ctx, n, bi); p != nil {
+			ret = append(ret, p)
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ComponentPackage returns the package for the binary at "n" if it's from a
+// well-known project, or nil.
+func componentPackage(ctx context.Context, n string, bi *buildInfo) *claircore.Package {
+	c, ok := lookup(bi.Main.Path)
+	if !ok {
+		return nil
+	}
+	v, ok := c.version(bi)
+	if !ok {
+		zlog.Info(ctx).
+			Str("file", n).
+			Str("module", bi.Main.Path).
+			Msg("unable to determine release version")
+		return nil
+	}
+	zlog.Debug(ctx).
+		Str("file", n).
+		Str("module", bi.Main.Path).
+		Str("package", c.Package).
+		Str("version", v).
+		Msg("found binary")
+	return &claircore.Package{
+		Name:              c.Package,
+		Version:           v,
+		Kind:              claircore.BINARY,
+		PackageDB:         "go:" + n,
+		NormalizedVersion: normalize(v),
+	}
+}
+
+// Stdlib is the name vulnerability databases use for the Go standard
+// library.
+const stdlib = "stdlib"
+
+// StdlibPackage returns the package for the standard library linked into the
+// binary at "n", or nil if the toolchain version isn't known. The package's
+// version is the toolchain version, like "go1.21.5".
+func stdlibPackage(ctx context.Context, n string, bi *buildInfo) *claircore.Package {
+	v := goSemver(bi.GoVersion)
+	if v == "" {
+		if bi.GoVersion != "" {
+			zlog.Debug(ctx).
+				Str("file", n).
+				Str("go", bi.GoVersion).
+				Msg("unable to interpret toolchain version")
+		}
+		return nil
+	}
+	return &claircore.Package{
+		Name:              stdlib,
+		Version:           bi.GoVersion,
+		Kind:              claircore.BINARY,
+		PackageDB:         "go:" + n,
+		NormalizedVersion: normalize(v),
+	}
+}
+
+// GoSemver returns the semver string for a Go toolchain version, or the
+// empty string if it isn't a release. Release candidates and betas, like
+// "go1.22rc1", are pre-releases, and experiments, like
+// "go1.21.5 X:boringcrypto", are ignored.
+func goSemver(v string) string {
+	if !strings.HasPrefix(v, "go") {
+		return ""
+	}
+	v = strings.TrimPrefix(v, "go")
+	if i := strings.IndexByte(v, ' '); i != -1 {
+		v = v[:i]
+	}
+	var pre string
+	if i := strings.IndexAny(v, "br"); i != -1 {
+		v, pre = v[:i], v[i:]
+	}
+	// The semver package only fills in missing numbers for versions without
+	// a pre-release, so the two parts are put back together afterwards.
+	v = semver.Canonical("v" + v)
+	if v == "" || pre == "" {
+		return v
+	}
+	return semver.Canonical(v + "-" + pre)
+}
+
+func isExecutable(hdr []byte) bool {
+	for _, m := range magic {
+		if len(hdr) >= len(m) && string(hdr[:len(m)]) == string(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns the normalized form of a semver string: the major,
+// minor, and patch numbers. Pre-release and build information is dropped.
+func normalize(v string) claircore.Version {
+	out := claircore.Version{Kind: "semver"}
+	v = strings.TrimPrefix(semver.Canonical(v), "v")
+	if i := strings.IndexAny(v, "-+"); i != -1 {
+		v = v[:i]
+	}
+	for i, f := range strings.SplitN(v, ".", 3) {
+		n, err := strconv.ParseInt(f, 10, 32)
+		if err != nil {
+			return claircore.Version{}
+		}
+		out.V[i] = int32(n)
+	}
+	return out
+}
@@ -0,0 +1,252 @@
+package gobin
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// FakeBinary returns an ELF-looking file with the module information embedded
+// at the offset.
+func fakeBinary(info string, offset int) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x7fELF")
+	b.Write(make([]byte, offset))
+	b.WriteString(infoStart)
+	b.WriteString(info)
+	b.WriteString(infoEnd)
+	b.Write(make([]byte, 512))
+	return b.Bytes()
+}
+
+// FakeGoBinary is like fakeBinary, but the module information is preceded by
+// the build information header that Go 1.18 and later write, recording the
+// toolchain version "gover".
+func fakeGoBinary(gover, info string, offset int) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x7fELF")
+	b.Write(make([]byte, offset))
+	b.WriteString(headerMagic)
+	b.WriteByte(8) // Pointer size.
+	b.WriteByte(headerInline)
+	b.Write(make([]byte, headerSize-len(headerMagic)-2))
+	n := make([]byte, binary.MaxVarintLen64)
+	b.Write(n[:binary.PutUvarint(n, uint64(len(gover)))])
+	b.WriteString(gover)
+	mod := infoStart + info + infoEnd
+	b.Write(n[:binary.PutUvarint(n, uint64(len(mod)))])
+	b.WriteString(mod)
+	b.Write(make([]byte, 512))
+	return b.Bytes()
+}
+
+const kubectlInfo = "path\tk8s.io/kubernetes/cmd/kubectl\n" +
+	"mod\tk8s.io/kubernetes\t(devel)\t\n" +
+	"dep\tgithub.com/spf13/cobra\tv1.4.0\th1:y+wJpx64xcgO1V+RcnwW0LEHxTKRi2ZDPSBjWnrg88Q=\n" +
+	"dep\tk8s.io/kubectl\tv0.0.0\n" +
+	"=>\t./staging/src/k8s.io/kubectl\t(devel)\t\n" +
+	"build\t-compiler=gc\n" +
+	"build\t-ldflags=\"-s -w -X 'k8s.io/component-base/version.gitVersion=v1.24.0' -X 'k8s.io/component-base/version.gitTreeState=clean'\"\n" +
+	"build\tGOOS=linux\n"
+
+const etcdInfo = "path\tgo.etcd.io/etcd/server/v3\n" +
+	"mod\tgo.etcd.io/etcd/server/v3\t(devel)\t\n" +
+	"dep\tgo.etcd.io/etcd/api/v3\tv3.5.4\n" +
+	"=>\t./api\t(devel)\t\n"
+
+const helmInfo = "path\thelm.sh/helm/v3/cmd/helm\n" +
+	"mod\thelm.sh/helm/v3\tv3.13.3\th1:abc=\n"
+
+const otherInfo = "path\tgithub.com/example/tool\n" +
+	"mod\tgithub.com/example/tool\tv1.0.0\th1:abc=\n"
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	files := []struct {
+		Name string
+		Mode int64
+		Data []byte
+	}{
+		// Put the start marker across the buffer boundary.
+		{"usr/bin/kubectl", 0755, fakeBinary(kubectlInfo, 64*1024-8)},
+		{"usr/local/bin/etcd", 0755, fakeBinary(etcdInfo, 100)},
+		{"usr/local/bin/tool", 0755, fakeBinary(otherInfo, 100)},
+		{"usr/share/doc/kubectl", 0644, fakeBinary(kubectlInfo, 100)},
+		{"usr/bin/script", 0755, []byte("#!/bin/sh\n" + infoStart + kubectlInfo + infoEnd)},
+		{"usr/bin/truncated", 0755, fakeBinary(kubectlInfo, 100)[:200]},
+		// Built by a toolchain with known standard library vulnerabilities.
+		// Put the header before the buffer boundary and the module
+		// information after it.
+		{"usr/local/bin/helm", 0755, fakeGoBinary("go1.21.5", helmInfo, 64*1024-40)},
+		{"usr/local/bin/tool-rc", 0755, fakeGoBinary("go1.22rc1", otherInfo, 100)},
+		{"usr/local/bin/tool-devel", 0755, fakeGoBinary("devel go1.23-1234abcd", otherInfo, 100)},
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.Name,
+			Mode:     f.Mode,
+			Size:     int64(len(f.Data)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := scan(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:      "k8s.io/kubernetes",
+			Version:   "v1.24.0",
+			Kind:      claircore.BINARY,
+			PackageDB: "go:/usr/bin/kubectl",
+			NormalizedVersion: claircore.Version{
+				Kind: "semver",
+				V:    [...]int32{1, 24, 0, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		{
+			Name:      "go.etcd.io/etcd/v3",
+			Version:   "v3.5.4",
+			Kind:      claircore.BINARY,
+			PackageDB: "go:/usr/local/bin/etcd",
+			NormalizedVersion: claircore.Version{
+				Kind: "semver",
+				V:    [...]int32{3, 5, 4, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		{
+			Name:      "helm.sh/helm/v3",
+			Version:   "v3.13.3",
+			Kind:      claircore.BINARY,
+			PackageDB: "go:/usr/local/bin/helm",
+			NormalizedVersion: claircore.Version{
+				Kind: "semver",
+				V:    [...]int32{3, 13, 3, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		{
+			Name:      "stdlib",
+			Version:   "go1.21.5",
+			Kind:      claircore.BINARY,
+			PackageDB: "go:/usr/local/bin/helm",
+			NormalizedVersion: claircore.Version{
+				Kind: "semver",
+				V:    [...]int32{1, 21, 5, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		{
+			// Binaries from other projects only report the standard
+			// library.
+			Name:      "stdlib",
+			Version:   "go1.22rc1",
+			Kind:      claircore.BINARY,
+			PackageDB: "go:/usr/local/bin/tool-rc",
+			NormalizedVersion: claircore.Version{
+				Kind: "semver",
+				V:    [...]int32{1, 22, 0, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	bi, err := parseInfo(kubectlInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &buildInfo{
+		Path: "k8s.io/kubernetes/cmd/kubectl",
+		Main: module{Path: "k8s.io/kubernetes", Version: "(devel)"},
+		Deps: []module{
+			{Path: "github.com/spf13/cobra", Version: "v1.4.0"},
+			{Path: "k8s.io/kubectl", Version: "v0.0.0"},
+		},
+		Settings: map[string]string{
+			"-compiler": "gc",
+			"-ldflags":  "-s -w -X 'k8s.io/component-base/version.gitVersion=v1.24.0' -X 'k8s.io/component-base/version.gitTreeState=clean'",
+			"GOOS":      "linux",
+		},
+	}
+	if !cmp.Equal(bi, want, cmp.AllowUnexported(buildInfo{}, module{})) {
+		t.Error(cmp.Diff(bi, want, cmp.AllowUnexported(buildInfo{}, module{})))
+	}
+
+	if _, err := parseInfo("path\tcmd/tool\n"); err == nil {
+		t.Error("expected error for missing main module")
+	}
+}
+
+func TestFindInfo(t *testing.T) {
+	_, _, err := findInfo(strings.NewReader(strings.Repeat("\x00", 200*1024)))
+	if err == nil {
+		t.Error("expected error for missing module information")
+	}
+
+	for _, tc := range []struct {
+		Name string
+		Data []byte
+		Want string
+	}{
+		{"Inline", fakeGoBinary("go1.21.5", otherInfo, 100), "go1.21.5"},
+		{"NoHeader", fakeBinary(otherInfo, 100), ""},
+		{
+			// Go 1.17 and earlier record pointers instead of the strings.
+			Name: "Pointers",
+			Data: func() []byte {
+				b := fakeGoBinary("go1.17", otherInfo, 100)
+				b[4+100+len(headerMagic)+1] = 0
+				return b
+			}(),
+		},
+	} {
+		info, v, err := findInfo(bytes.NewReader(tc.Data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.Name, err)
+			continue
+		}
+		if info != otherInfo {
+			t.Errorf("%s: info: got: %q, want: %q", tc.Name, info, otherInfo)
+		}
+		if v != tc.Want {
+			t.Errorf("%s: version: got: %q, want: %q", tc.Name, v, tc.Want)
+		}
+	}
+}
+
+func TestGoSemver(t *testing.T) {
+	for in, want := range map[string]string{
+		"go1.21.5":                "v1.21.5",
+		"go1.20":                  "v1.20.0",
+		"go1.22rc1":               "v1.22.0-rc1",
+		"go1.9beta2":              "v1.9.0-beta2",
+		"go1.21.5 X:boringcrypto": "v1.21.5",
+		"devel go1.23-1234abcd":   "",
+		"":                        "",
+	} {
+		if got := goSemver(in); got != want {
+			t.Errorf("%q: got: %q, want: %q", in, got, want)
+		}
+	}
+}
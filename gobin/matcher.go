@@ -0,0 +1,88 @@
+package gobin
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Matcher = (*Matcher)(nil)
+)
+
+// Matcher attempts to correlate Go binaries found by the Scanner with
+// reported vulnerabilities.
+type Matcher struct{}
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return "gobin" }
+
+// Filter implements driver.Matcher.
+//
+// Go binaries are considered even if their version couldn't be normalized,
+// so that they're reported as indeterminate rather than dropped.
+func (*Matcher) Filter(record *claircore.IndexRecord) bool {
+	return record.Package.NormalizedVersion.Kind == "semver" ||
+		strings.HasPrefix(record.Package.PackageDB, "go:")
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{}
+}
+
+// Vulnerable implements driver.Matcher.
+//
+// A vulnerability with a semver range affects releases in the range.
+// Otherwise, a vulnerability with a fixed version affects releases before
+// it, and one with neither affects all releases. Advisories for the standard
+// library often have several ranges, one per supported Go release, so the
+// range is consulted first.
+func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	if vuln.Package == nil {
+		return false, nil
+	}
+	pv := record.Package.Version
+	if driver.EmptyVersion(pv) {
+		return false, driver.PackageVersionError(pv, driver.ErrEmptyVersion)
+	}
+	v := canonical(pv)
+	if v == "" {
+		return false, driver.PackageVersionError(pv, errNotSemver)
+	}
+	switch {
+	case vuln.Range != nil && (vuln.Range.Lower.Kind == "semver" || vuln.Range.Upper.Kind == "semver"):
+		return vuln.Range.Contains(&record.Package.NormalizedVersion), nil
+	case vuln.FixedInVersion != "":
+		fixed := canonical(vuln.FixedInVersion)
+		if fixed == "" {
+			return false, driver.VulnerabilityVersionError(vuln.FixedInVersion, errNotSemver)
+		}
+		return semver.Compare(v, fixed) < 0, nil
+	case vuln.Range != nil:
+		return false, nil
+	}
+	return true, nil
+}
+
+// ErrNotSemver is the cause of version errors for versions the semver package
+// rejects, as it doesn't report why.
+var errNotSemver = errors.New("not a semver version")
+
+// Canonical returns the semver string with the leading "v" that the semver
+// package wants, or the empty string if "v" isn't valid. Go toolchain
+// versions, like "go1.21.5", are accepted.
+func canonical(v string) string {
+	if strings.HasPrefix(v, "go") {
+		return goSemver(v)
+	}
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return semver.Canonical(v)
+}
@@ -0,0 +1,151 @@
+package gobin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+)
+
+func TestVulnerable(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	record := &claircore.IndexRecord{
+		Package: &claircore.Package{
+			Name:              "k8s.io/kubernetes",
+			Version:           "v1.24.0",
+			Kind:              claircore.BINARY,
+			NormalizedVersion: normalize("v1.24.0"),
+		},
+	}
+	semverRange := func(lo, hi string) *claircore.Range {
+		return &claircore.Range{Lower: normalize(lo), Upper: normalize(hi)}
+	}
+	tt := []struct {
+		Name string
+		Vuln *claircore.Vulnerability
+		Want bool
+	}{
+		{
+			Name: "FixedLater",
+			Vuln: &claircore.Vulnerability{Package: record.Package, FixedInVersion: "1.24.3"},
+			Want: true,
+		},
+		{
+			Name: "FixedEarlier",
+			Vuln: &claircore.Vulnerability{Package: record.Package, FixedInVersion: "v1.23.9"},
+			Want: false,
+		},
+		{
+			Name: "FixedSame",
+			Vuln: &claircore.Vulnerability{Package: record.Package, FixedInVersion: "v1.24.0"},
+			Want: false,
+		},
+		{
+			Name: "InRange",
+			Vuln: &claircore.Vulnerability{Package: record.Package, Range: semverRange("v1.24.0", "v1.24.2")},
+			Want: true,
+		},
+		{
+			Name: "OutOfRange",
+			Vuln: &claircore.Vulnerability{Package: record.Package, Range: semverRange("v1.22.0", "v1.23.8")},
+			Want: false,
+		},
+		{
+			Name: "Unfixed",
+			Vuln: &claircore.Vulnerability{Package: record.Package},
+			Want: true,
+		},
+		{
+			Name: "NoPackage",
+			Vuln: &claircore.Vulnerability{FixedInVersion: "v1.25.0"},
+			Want: false,
+		},
+	}
+	m := &Matcher{}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := m.Vulnerable(ctx, record, tc.Vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got %v, want %v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestBadVersions(t *testing.T) {
+	test.BadVersionTestcase{
+		Matcher: &Matcher{},
+		Record: func(v string) *claircore.IndexRecord {
+			return &claircore.IndexRecord{
+				Package: &claircore.Package{
+					Name:              "k8s.io/kubernetes",
+					Version:           v,
+					PackageDB:         "go:usr/bin/kubectl",
+					NormalizedVersion: normalize(v),
+				},
+			}
+		},
+		Vulnerability: func(v string) *claircore.Vulnerability {
+			return &claircore.Vulnerability{
+				Package:        &claircore.Package{Name: "k8s.io/kubernetes"},
+				FixedInVersion: v,
+			}
+		},
+		Good:  "v1.24.0",
+		Extra: []string{"v", "1:1.24.3-1", "3f2a9c1d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f", "1.0.0-"},
+	}.Run(t)
+}
+
+func TestVulnerableStdlib(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	stdlib := func(v string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{
+			Package: &claircore.Package{
+				Name:              "stdlib",
+				Version:           v,
+				Kind:              claircore.BINARY,
+				PackageDB:         "go:/usr/local/bin/helm",
+				NormalizedVersion: normalize(goSemver(v)),
+			},
+		}
+	}
+	// A standard library advisory is one Vulnerability per affected release
+	// branch, each with a range and the fixed version in that branch.
+	vulns := []*claircore.Vulnerability{
+		{
+			Package:        &claircore.Package{Name: "stdlib"},
+			FixedInVersion: "1.21.9",
+			Range:          &claircore.Range{Upper: normalize("v1.21.9")},
+		},
+		{
+			Package:        &claircore.Package{Name: "stdlib"},
+			FixedInVersion: "1.22.2",
+			Range:          &claircore.Range{Lower: normalize("v1.22.0"), Upper: normalize("v1.22.2")},
+		},
+	}
+	m := &Matcher{}
+	for v, want := range map[string]bool{
+		"go1.21.5": true,
+		"go1.21.9": false,
+		"go1.22.0": true,
+		"go1.22.2": false,
+	} {
+		var got bool
+		for _, vuln := range vulns {
+			ok, err := m.Vulnerable(ctx, stdlib(v), vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = got || ok
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package gobin
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/mod/semver"
+
+	"github.com/quay/claircore"
+)
+
+// Advisory is the subset of the OSV schema this package uses.
+//
+// See https://ossf.github.io/osv-schema/ for the full schema, and
+// https://go.dev/security/vuln/database for how the Go vulnerability database
+// uses it.
+type advisory struct {
+	ID         string      `json:"id"`
+	Published  string      `json:"published"`
+	Withdrawn  string      `json:"withdrawn"`
+	Aliases    []string    `json:"aliases"`
+	Summary    string      `json:"summary"`
+	Details    string      `json:"details"`
+	Affected   []affected  `json:"affected"`
+	References []reference `json:"references"`
+}
+
+type affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []affectedRange `json:"ranges"`
+}
+
+type affectedRange struct {
+	Type   string  `json:"type"`
+	Events []event `json:"events"`
+}
+
+type event struct {
+	Introduced string `json:"introduced"`
+	Fixed      string `json:"fixed"`
+}
+
+type reference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Interval is a span of affected versions, as semver strings without the
+// leading "v". An empty "upper" means there's no upper bound.
+type interval struct {
+	lower string
+	upper string
+}
+
+// Range returns the normalized version range for the interval, or nil if
+// either bound isn't a semver version.
+func (i interval) Range() *claircore.Range {
+	r := claircore.Range{
+		Lower: claircore.Version{Kind: "semver"},
+		Upper: claircore.Version{Kind: "semver"},
+	}
+	if i.lower != "0" {
+		if !semver.IsValid("v" + i.lower) {
+			return nil
+		}
+		r.Lower = normalize("v" + i.lower)
+	}
+	switch {
+	case i.upper == "":
+		// No fixed version, so everything later is affected.
+		r.Upper.V[0] = math.MaxInt32
+	case !semver.IsValid("v" + i.upper):
+		return nil
+	default:
+		r.Upper = normalize("v" + i.upper)
+	}
+	return &r
+}
+
+// Intervals returns the affected intervals described by the SEMVER ranges.
+func (a *affected) Intervals() []interval {
+	var ret []interval
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		var cur interval
+		open := false
+		for _, e := range r.Events {
+			switch {
+			case e.Introduced != "":
+				if open {
+					// Two introduced events in a row: the first is subsumed.
+					continue
+				}
+				cur, open = interval{lower: e.Introduced}, true
+			case e.Fixed != "" && open:
+				cur.upper = e.Fixed
+				ret, open = append(ret, cur), false
+			}
+		}
+		if open {
+			ret = append(ret, cur)
+		}
+	}
+	return ret
+}
+
+// Toolchain is the package the Go vulnerability database uses for the go
+// command and the rest of the toolchain, which isn't linked into binaries.
+const toolchain = "toolchain"
+
+// Vulnerabilities returns a Vulnerability for every affected interval of
+// every Go module, including the standard library, in the advisory.
+func (a *advisory) Vulnerabilities(ctx context.Context, updater string) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/advisory.Vulnerabilities"),
+		label.String("advisory", a.ID))
+	if a.Withdrawn != "" {
+		zlog.Debug(ctx).Msg("advisory withdrawn, skipping")
+		return nil, nil
+	}
+
+	name := a.ID
+	var cves []string
+	for _, id := range a.Aliases {
+		if strings.HasPrefix(id, "CVE-") {
+			cves = append(cves, id)
+		}
+	}
+	if len(cves) != 0 {
+		name += " (" + strings.Join(cves, ", ") + ")"
+	}
+	aliases := append([]string{a.ID}, a.Aliases...)
+	desc := a.Details
+	if desc == "" {
+		desc = a.Summary
+	}
+	links := make([]string, 0, len(a.References))
+	for _, r := range a.References {
+		links = append(links, r.URL)
+	}
+	var issued time.Time
+	if a.Published != "" {
+		var err error
+		issued, err = time.Parse(time.RFC3339, a.Published)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Msg("unparsable published date")
+		}
+	}
+
+	var ret []*claircore.Vulnerability
+	var mungeCt int
+	for i := range a.Affected {
+		af := &a.Affected[i]
+		if af.Package.Ecosystem != "Go" || af.Package.Name == toolchain {
+			continue
+		}
+		for _, iv := range af.Intervals() {
+			r := iv.Range()
+			if r == nil {
+				zlog.Warn(ctx).
+					Str("introduced", iv.lower).
+					Str("fixed", iv.upper).
+					Msg("malformed version in advisory")
+				mungeCt++
+				continue
+			}
+			ret = append(ret, &claircore.Vulnerability{
+				Name:           name,
+				Updater:        updater,
+				Description:    desc,
+				Issued:         issued,
+				Links:          strings.Join(links, " "),
+				Aliases:        aliases,
+				FixedInVersion: iv.upper,
+				Package: &claircore.Package{
+					Name: af.Package.Name,
+					Kind: claircore.BINARY,
+				},
+				Range: r,
+			})
+		}
+	}
+	if mungeCt > 0 {
+		zlog.Debug(ctx).
+			Int("count", mungeCt).
+			Msg("skipped some malformed ranges")
+	}
+	return ret, nil
+}
@@ -0,0 +1,253 @@
+package gobin
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+const defaultURL = `https://github.com/golang/vulndb/archive/master.tar.gz`
+
+var (
+	_ driver.Updater      = (*Updater)(nil)
+	_ driver.Configurable = (*Updater)(nil)
+)
+
+// Updater reads the Go vulnerability database for vulnerabilities in Go
+// modules and the standard library.
+//
+// The zero value is not safe to use.
+type Updater struct {
+	url    *url.URL
+	client *http.Client
+}
+
+// NewUpdater returns a configured Updater or reports an error.
+func NewUpdater(opt ...Option) (*Updater, error) {
+	u := Updater{}
+	for _, f := range opt {
+		if err := f(&u); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.url == nil {
+		var err error
+		u.url, err = url.Parse(defaultURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if u.client == nil {
+		u.client = http.DefaultClient // TODO(hank) Remove DefaultClient
+	}
+
+	return &u, nil
+}
+
+// Option controls the configuration of an Updater.
+type Option func(*Updater) error
+
+// WithClient sets the http.Client that the updater should use for requests.
+//
+// If not passed to NewUpdater, http.DefaultClient will be used.
+func WithClient(c *http.Client) Option {
+	return func(u *Updater) error {
+		u.client = c
+		return nil
+	}
+}
+
+// WithURL sets the URL the updater should fetch.
+//
+// The URL should point to a gzip compressed tarball containing OSV-format
+// JSON files in a "data/osv" directory.
+//
+// If not passed to NewUpdater, the master branch of github.com/golang/vulndb
+// will be fetched.
+func WithURL(uri string) Option {
+	u, err := url.Parse(uri)
+	return func(up *Updater) error {
+		if err != nil {
+			return err
+		}
+		up.url = u
+		return nil
+	}
+}
+
+// Config is the configuration for the updater.
+//
+// By convention, this is in a map called "govulndb".
+type Config struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/Updater.Configure"))
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+
+	if cfg.URL != "" {
+		uri, err := url.Parse(cfg.URL)
+		if err != nil {
+			return err
+		}
+		u.url = uri
+		zlog.Info(ctx).
+			Msg("configured URL")
+	}
+	u.client = c
+	zlog.Info(ctx).
+		Msg("configured HTTP client")
+	return nil
+}
+
+// Name implements driver.Updater.
+func (*Updater) Name() string { return "govulndb" }
+
+// Fetch implements driver.Updater.
+func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/Updater.Fetch"))
+	zlog.Info(ctx).Str("database", u.url.String()).Msg("starting fetch")
+	req := http.Request{
+		Method:     http.MethodGet,
+		Header:     http.Header{"User-Agent": {"claircore/gobin/Updater"}},
+		URL:        u.url,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Host:       u.url.Host,
+	}
+	if hint != "" {
+		zlog.Debug(ctx).
+			Str("hint", string(hint)).
+			Msg("using hint")
+		req.Header.Set("if-none-match", string(hint))
+	}
+
+	res, err := u.client.Do(req.WithContext(ctx))
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, hint, err
+	}
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return nil, hint, driver.Unchanged
+	case http.StatusOK:
+		// break
+	default:
+		return nil, hint, fmt.Errorf("gobin: fetcher got unexpected HTTP response: %d (%s)", res.StatusCode, res.Status)
+	}
+	zlog.Debug(ctx).Msg("request ok")
+
+	r, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return nil, hint, err
+	}
+
+	tf, err := tmp.NewFile("", "govulndb.")
+	if err != nil {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).
+		Str("path", tf.Name()).
+		Msg("using tempfile")
+	success := false
+	defer func() {
+		if !success {
+			zlog.Debug(ctx).Msg("unsuccessful, cleaning up tempfile")
+			if err := tf.Close(); err != nil {
+				zlog.Warn(ctx).Err(err).Msg("failed to close tempfile")
+			}
+		}
+	}()
+
+	if _, err := io.Copy(tf, r); err != nil {
+		return nil, hint, err
+	}
+	if o, err := tf.Seek(0, io.SeekStart); err != nil || o != 0 {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).Msg("decompressed and buffered database")
+
+	if t := res.Header.Get("etag"); t != "" {
+		zlog.Debug(ctx).
+			Str("hint", t).
+			Msg("using new hint")
+		hint = driver.Fingerprint(t)
+	}
+	success = true
+	return tf, hint, nil
+}
+
+// Parse implements driver.Updater.
+func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/Updater.Parse"))
+	zlog.Info(ctx).Msg("parse start")
+	defer r.Close()
+	defer zlog.Info(ctx).Msg("parse done")
+
+	var ret []*claircore.Vulnerability
+	var ct int
+	rec := driver.NewRecords(ctx)
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg ||
+			!strings.Contains(h.Name, "/data/osv/") ||
+			path.Ext(h.Name) != ".json" {
+			continue
+		}
+		ct++
+		var a advisory
+		if err := json.NewDecoder(tr).Decode(&a); err != nil {
+			rec.Malformed(h.Name, err)
+			continue
+		}
+		vs, err := a.Vulnerabilities(ctx, u.Name())
+		if err != nil {
+			rec.Malformed(h.Name, err)
+			continue
+		}
+		rec.Ok()
+		ret = append(ret, vs...)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("count", ct).
+		Int("skipped", len(rec.Errors())).
+		Msg("found raw entries")
+	if err := rec.Err(); err != nil {
+		return nil, fmt.Errorf("gobin: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("count", len(ret)).
+		Msg("found vulnerabilities")
+	return ret, nil
+}
@@ -0,0 +1,176 @@
+package gobin
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func TestParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ms, err := filepath.Glob(filepath.Join("testdata", "osv", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, ioutil.NopCloser(archive(t, ms)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		Name, Package, Fixed string
+	}
+	got := make([]want, len(vs))
+	for i, v := range vs {
+		got[i] = want{Name: v.Name, Package: v.Package.Name, Fixed: v.FixedInVersion}
+	}
+	sort.Slice(got, func(i, j int) bool {
+		if got[i].Name != got[j].Name {
+			return got[i].Name < got[j].Name
+		}
+		return got[i].Fixed < got[j].Fixed
+	})
+	// The withdrawn advisory and the toolchain entry are skipped.
+	wantVs := []want{
+		{Name: "GO-2023-1840 (CVE-2023-29403)", Package: "stdlib", Fixed: "1.19.10"},
+		{Name: "GO-2023-1840 (CVE-2023-29403)", Package: "stdlib", Fixed: "1.20.5"},
+		{Name: "GO-2024-2687 (CVE-2023-45288)", Package: "golang.org/x/net", Fixed: "0.23.0"},
+		{Name: "GO-2024-2687 (CVE-2023-45288)", Package: "stdlib", Fixed: "1.21.9"},
+		{Name: "GO-2024-2687 (CVE-2023-45288)", Package: "stdlib", Fixed: "1.22.2"},
+	}
+	if !cmp.Equal(got, wantVs) {
+		t.Error(cmp.Diff(got, wantVs))
+	}
+
+	for _, v := range vs {
+		if v.Package.Name != "stdlib" || v.FixedInVersion != "1.22.2" {
+			continue
+		}
+		want := &claircore.Vulnerability{
+			Name:           "GO-2024-2687 (CVE-2023-45288)",
+			Updater:        "govulndb",
+			Description:    "An attacker may cause an HTTP/2 endpoint to read arbitrary amounts of header data by sending an excessive number of CONTINUATION frames.",
+			Issued:         time.Date(2024, 4, 3, 21, 12, 1, 0, time.UTC),
+			Links:          "https://go.dev/issue/65051 https://groups.google.com/g/golang-announce/c/YgW0sx8mN3M",
+			Aliases:        []string{"GO-2024-2687", "CVE-2023-45288", "GHSA-4v7x-pqxf-cx7m"},
+			FixedInVersion: "1.22.2",
+			Package:        &claircore.Package{Name: "stdlib", Kind: claircore.BINARY},
+			Range:          &claircore.Range{Lower: normalize("v1.22.0"), Upper: normalize("v1.22.2")},
+		}
+		if !cmp.Equal(v, want) {
+			t.Error(cmp.Diff(v, want))
+		}
+	}
+}
+
+// TestStdlibMatch checks that the standard library reported for a binary
+// built by a vulnerable toolchain matches the advisories for it.
+func TestStdlibMatch(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ms, err := filepath.Glob(filepath.Join("testdata", "osv", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, ioutil.NopCloser(archive(t, ms)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Matcher{}
+	for gover, want := range map[string][]string{
+		"go1.21.5": {"GO-2024-2687 (CVE-2023-45288)"},
+		"go1.20.4": {"GO-2023-1840 (CVE-2023-29403)", "GO-2024-2687 (CVE-2023-45288)"},
+		"go1.22.2": nil,
+	} {
+		_, v, err := findInfo(bytes.NewReader(fakeGoBinary(gover, otherInfo, 100)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := &claircore.Package{
+			Name:              stdlib,
+			Version:           v,
+			Kind:              claircore.BINARY,
+			PackageDB:         "go:/usr/local/bin/tool",
+			NormalizedVersion: normalize(goSemver(v)),
+		}
+		record := &claircore.IndexRecord{Package: pkg}
+		if !m.Filter(record) {
+			t.Fatalf("%s: filtered out", gover)
+		}
+		var got []string
+		for _, vuln := range vs {
+			if vuln.Package.Name != pkg.Name {
+				continue
+			}
+			ok, err := m.Vulnerable(ctx, record, vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				got = append(got, vuln.Name)
+			}
+		}
+		sort.Strings(got)
+		if !cmp.Equal(got, want) {
+			t.Errorf("%s: %s", gover, cmp.Diff(got, want))
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	bad := filepath.Join("testdata", "osv", "malformed", "GO-2099-9999.json")
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Parse(ctx, ioutil.NopCloser(archive(t, []string{bad}))); err == nil {
+		t.Error("expected error for a malformed database")
+	}
+}
+
+// Archive lays the named fixtures out like the GitHub archive.
+func archive(t *testing.T, ms []string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, m := range ms {
+		b, err := ioutil.ReadFile(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     "vulndb-master/data/osv/" + filepath.Base(m),
+			Size:     int64(len(b)),
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
@@ -0,0 +1,23 @@
+package gobin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// UpdaterSet returns an UpdaterSet containing the Go vulnerability database
+// Updater.
+func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
+	us := driver.NewUpdaterSet()
+	u, err := NewUpdater()
+	if err != nil {
+		return us, fmt.Errorf("failed to create govulndb updater: %v", err)
+	}
+	err = us.Add(u)
+	if err != nil {
+		return us, err
+	}
+	return us, nil
+}
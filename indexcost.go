@@ -0,0 +1,45 @@
+package claircore
+
+import "time"
+
+// IndexCost is what an index operation cost to produce an IndexReport.
+//
+// The counts cover only the work the operation did itself: layers that were
+// already fetched or scanned, or a manifest that was already indexed, cost
+// little or nothing.
+type IndexCost struct {
+	// FetchedBytes is the number of bytes downloaded for layers.
+	FetchedBytes int64 `json:"fetched_bytes"`
+	// ScratchBytes is the number of bytes written to the scratch directory,
+	// which is the decompressed size of the fetched layers.
+	ScratchBytes int64 `json:"scratch_bytes"`
+	// StoreRows is the number of artifacts and index records written to
+	// the store.
+	StoreRows int64 `json:"store_rows"`
+	// Phases are the phases of the operation, in the order they ran.
+	Phases []PhaseCost `json:"phases,omitempty"`
+	// Scanners are the scanners that were run, sorted by kind and name.
+	Scanners []ScannerCost `json:"scanners,omitempty"`
+}
+
+// PhaseCost is the wall time of a phase of an index operation.
+type PhaseCost struct {
+	Phase   string        `json:"phase"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// ScannerCost is the time a scanner spent scanning layers for an index
+// operation.
+//
+// Scanners run concurrently, so the scanners' time can add up to more than
+// the wall time of the phase they ran in.
+type ScannerCost struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// Layers is the number of layers the scanner scanned.
+	Layers int64 `json:"layers"`
+	// Elapsed is the time spent in the scanner's Scan method, summed across
+	// layers.
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
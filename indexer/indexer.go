@@ -0,0 +1,77 @@
+// Package indexer is the extension point for adding support for new package
+// ecosystems to the indexer.
+//
+// An ecosystem outside of this module supplies its scanners and Coalescer by
+// constructing an Ecosystem and adding it to libindex.Opts.Ecosystems. The
+// types here are aliases of the ones used internally, so they're
+// interchangeable with the Ecosystems provided by the packages in this module.
+//
+// To add a scanner to one of this module's Ecosystems rather than write a
+// whole new one, use its WithAdditionalScanners method; the result keeps
+// tracking the upstream Ecosystem as it changes. A scanner that implements
+// ConfigurableScanner or RPCScanner is configured with the entry keyed by
+// its name in libindex.Opts.ScannerConfig, like the in-tree scanners.
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// Ecosystem groups together scanners and a Coalescer which are commonly used
+// together.
+//
+// The indexer scans layers with every scanner in its configured Ecosystems,
+// then hands the artifacts found by an Ecosystem's scanners to that
+// Ecosystem's Coalescer alone.
+type Ecosystem = indexer.Ecosystem
+
+// Coalescer computes the contents of a manifest from the artifacts found in
+// each of its layers.
+//
+// It's called with one LayerArtifacts per layer, in the manifest's layer
+// order, starting with the base layer. A Coalescer decides what a later layer
+// means for the results of an earlier one: for example, that a package
+// database found again in a later layer replaces the earlier contents.
+type Coalescer = indexer.Coalescer
+
+// LayerArtifacts is the artifacts an Ecosystem's scanners found in a single
+// layer.
+type LayerArtifacts = indexer.LayerArtifacts
+
+// VersionedScanner is the common interface of all scanners.
+type VersionedScanner = indexer.VersionedScanner
+
+// PackageScanner reports the packages found in a layer.
+type PackageScanner = indexer.PackageScanner
+
+// DistributionScanner reports the distribution a layer belongs to.
+type DistributionScanner = indexer.DistributionScanner
+
+// RepositoryScanner reports the repositories a layer's packages came from.
+type RepositoryScanner = indexer.RepositoryScanner
+
+// ConfigDeserializer decodes a scanner's configuration into the provided
+// value, like (*json.Decoder).Decode.
+type ConfigDeserializer = indexer.ConfigDeserializer
+
+// ConfigurableScanner is implemented by scanners that accept configuration.
+type ConfigurableScanner = indexer.ConfigurableScanner
+
+// RPCScanner is implemented by scanners that accept configuration and expect
+// to make network requests. They're left out when the indexer is configured
+// to be airgapped.
+type RPCScanner = indexer.RPCScanner
+
+// MergeEcosystems concatenates the sets of ecosystems, in order, for use as
+// libindex.Opts.Ecosystems. An Ecosystem appearing more than once is only
+// included the first time.
+//
+// Scanners are deduplicated by kind and name across all the configured
+// ecosystems, so MergeEcosystems reports an error if two ecosystems have
+// scanners with the same kind and name but different versions, rather than
+// letting one silently replace the other.
+func MergeEcosystems(ctx context.Context, sets ...[]*Ecosystem) ([]*Ecosystem, error) {
+	return indexer.MergeEcosystems(ctx, sets...)
+}
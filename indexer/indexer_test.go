@@ -0,0 +1,82 @@
+package indexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/indexer"
+	"github.com/quay/claircore/libindex"
+	"github.com/quay/claircore/test"
+)
+
+// Supersede is a Coalescer written only against this package, as one outside
+// the module would be. The last layer to report a package database replaces
+// whatever earlier layers reported for it.
+type supersede struct{}
+
+func (supersede) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+	}
+	// Package database to the layer that last reported it.
+	last := make(map[string]*indexer.LayerArtifacts)
+	for _, l := range ls {
+		for _, p := range l.Pkgs {
+			last[p.PackageDB] = l
+		}
+	}
+	for db, l := range last {
+		for _, p := range l.Pkgs {
+			if p.PackageDB != db {
+				continue
+			}
+			ir.Packages[p.ID] = p
+			ir.Environments[p.ID] = []*claircore.Environment{
+				{PackageDB: db, IntroducedIn: l.Hash},
+			}
+		}
+	}
+	return ir, nil
+}
+
+func TestOutOfTree(t *testing.T) {
+	ctx := context.Background()
+	tc := test.CoalescerTestcase{
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return supersede{}, nil
+		},
+		PackageDB: "requirements.txt",
+		Check: func(t *testing.T, set string, _ []*indexer.LayerArtifacts, ir *claircore.IndexReport) {
+			if set == "Removal" {
+				if _, ok := ir.Packages["b-1.0.0"]; ok {
+					t.Error("superseded package reported")
+				}
+			}
+		},
+	}
+	t.Run("Kit", tc.Run(ctx))
+}
+
+func ExampleEcosystem() {
+	ecosystem := &indexer.Ecosystem{
+		Name: "example",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return nil, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) {
+			return nil, nil
+		},
+		RepositoryScanners: func(context.Context) ([]indexer.RepositoryScanner, error) {
+			return nil, nil
+		},
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return supersede{}, nil
+		},
+	}
+	opts := &libindex.Opts{
+		Ecosystems: []*indexer.Ecosystem{ecosystem},
+	}
+	_ = opts
+}
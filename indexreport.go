@@ -1,5 +1,7 @@
 package claircore
 
+import "strconv"
+
 // IndexRecord is an entry in the IndexReport.
 //
 // IndexRecords provide full access to contextual package
@@ -33,9 +35,255 @@ type IndexReport struct {
 	Success bool `json:"success"`
 	// an error string in the case the index did not succeed
 	Err string `json:"err"`
+	// the state of the indexer that produced this IndexReport, as reported
+	// by Libindex.State
+	IndexerState string `json:"indexer_state,omitempty"`
+	// the scanners configured for the indexer that produced this
+	// IndexReport, sorted by kind and name
+	Scanners []ScannerDescription `json:"scanners,omitempty"`
+	// if not empty, the reason the index operation succeeded with limited
+	// support, such as the manifest being for a platform whose contents can
+	// only be partially indexed
+	LimitedSupport string `json:"limited_support,omitempty"`
+	// the layers of the manifest, in order, identified by both the digest
+	// they were fetched by and the digest of their uncompressed content
+	Layers []LayerIdentity `json:"layers,omitempty"`
+	// whether the indexer that produced this IndexReport was configured for
+	// inventory only, leaving out the artifacts needed for vulnerability
+	// matching, such as repositories
+	InventoryOnly bool `json:"inventory_only,omitempty"`
+	// anomalies that didn't stop the index operation, such as layers served
+	// with unsupported media types
+	Warnings []Warning `json:"warnings,omitempty"`
+	// the layer in which each distribution was first found, key'd by
+	// distribution id
+	DistributionsIntroducedIn map[string]Digest `json:"distributions_introduced_in,omitempty"`
+	// the layer in which each repository was first found, key'd by
+	// repository id
+	RepositoriesIntroducedIn map[string]Digest `json:"repositories_introduced_in,omitempty"`
+	// what the index operation that returned this IndexReport cost, if it
+	// was accounted for
+	Cost *IndexCost `json:"cost,omitempty"`
+	// how the IndexReport was redacted, if it was; see Redaction
+	Redaction *RedactionInfo `json:"redaction,omitempty"`
+}
+
+// AddDistribution records the Distribution "d" as found in the layer "in".
+//
+// If the Distribution was already recorded, the layer it was first found in
+// is kept, so calling this in layer order records the introducing layer.
+func (report *IndexReport) AddDistribution(d *Distribution, in Digest) {
+	if report.Distributions == nil {
+		report.Distributions = make(map[string]*Distribution)
+	}
+	report.Distributions[d.ID] = d
+	if report.DistributionsIntroducedIn == nil {
+		report.DistributionsIntroducedIn = make(map[string]Digest)
+	}
+	if _, ok := report.DistributionsIntroducedIn[d.ID]; !ok {
+		report.DistributionsIntroducedIn[d.ID] = in
+	}
+}
+
+// AddRepository records the Repository "r" as found in the layer "in".
+//
+// If the Repository was already recorded, the layer it was first found in is
+// kept, so calling this in layer order records the introducing layer.
+func (report *IndexReport) AddRepository(r *Repository, in Digest) {
+	if report.Repositories == nil {
+		report.Repositories = make(map[string]*Repository)
+	}
+	report.Repositories[r.ID] = r
+	if report.RepositoriesIntroducedIn == nil {
+		report.RepositoriesIntroducedIn = make(map[string]Digest)
+	}
+	if _, ok := report.RepositoriesIntroducedIn[r.ID]; !ok {
+		report.RepositoriesIntroducedIn[r.ID] = in
+	}
+}
+
+// LayerIdentity identifies a layer in an IndexReport.
+type LayerIdentity struct {
+	// Hash is the digest the layer was fetched by.
+	Hash Digest `json:"hash"`
+	// DiffID is the digest of the layer's uncompressed content. It's nil if
+	// the layer was indexed before diffIDs were recorded.
+	DiffID *Digest `json:"diff_id,omitempty"`
+}
+
+// ScannerDescription identifies a scanner used to produce an IndexReport.
+type ScannerDescription struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Slices in the report are encoded in a stable order, so identical reports
+// always encode to identical bytes.
+//
+// The encoding is the same as encoding/json would produce for the struct
+// with its Environments sorted, but the maps are written by hand to keep
+// allocations down for very large reports.
+func (report IndexReport) MarshalJSON() ([]byte, error) {
+	w := getJSONWriter()
+	defer w.put()
+	if err := report.writeJSON(w); err != nil {
+		return nil, err
+	}
+	return w.bytes(), nil
+}
+
+func (report *IndexReport) writeJSON(w *jsonWriter) error {
+	w.buf.WriteString(`{"manifest_hash":`)
+	if err := w.string(report.Hash.String()); err != nil {
+		return err
+	}
+	w.buf.WriteString(`,"state":`)
+	if err := w.string(report.State); err != nil {
+		return err
+	}
+
+	w.buf.WriteString(`,"packages":`)
+	if report.Packages == nil {
+		w.buf.WriteString("null")
+	} else {
+		w.sortKeys(func() {
+			for k := range report.Packages {
+				w.keys = append(w.keys, k)
+			}
+		})
+		w.buf.WriteByte('{')
+		for i, k := range w.keys {
+			if err := w.key(k, i == 0); err != nil {
+				return err
+			}
+			if err := w.value(report.Packages[k]); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte('}')
+	}
+
+	w.buf.WriteString(`,"distributions":`)
+	if report.Distributions == nil {
+		w.buf.WriteString("null")
+	} else {
+		w.sortKeys(func() {
+			for k := range report.Distributions {
+				w.keys = append(w.keys, k)
+			}
+		})
+		w.buf.WriteByte('{')
+		for i, k := range w.keys {
+			if err := w.key(k, i == 0); err != nil {
+				return err
+			}
+			if err := w.value(report.Distributions[k]); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte('}')
+	}
+
+	w.buf.WriteString(`,"repository":`)
+	if report.Repositories == nil {
+		w.buf.WriteString("null")
+	} else {
+		w.sortKeys(func() {
+			for k := range report.Repositories {
+				w.keys = append(w.keys, k)
+			}
+		})
+		w.buf.WriteByte('{')
+		for i, k := range w.keys {
+			if err := w.key(k, i == 0); err != nil {
+				return err
+			}
+			if err := w.value(report.Repositories[k]); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte('}')
+	}
+
+	w.buf.WriteString(`,"environments":`)
+	if err := w.environments(report.Environments); err != nil {
+		return err
+	}
+	w.buf.WriteString(`,"success":`)
+	w.buf.WriteString(strconv.FormatBool(report.Success))
+	w.buf.WriteString(`,"err":`)
+	if err := w.string(report.Err); err != nil {
+		return err
+	}
+	if report.IndexerState != "" {
+		w.buf.WriteString(`,"indexer_state":`)
+		if err := w.string(report.IndexerState); err != nil {
+			return err
+		}
+	}
+	if len(report.Scanners) != 0 {
+		w.buf.WriteString(`,"scanners":`)
+		if err := w.value(report.Scanners); err != nil {
+			return err
+		}
+	}
+	if report.LimitedSupport != "" {
+		w.buf.WriteString(`,"limited_support":`)
+		if err := w.string(report.LimitedSupport); err != nil {
+			return err
+		}
+	}
+	if len(report.Layers) != 0 {
+		w.buf.WriteString(`,"layers":`)
+		if err := w.value(report.Layers); err != nil {
+			return err
+		}
+	}
+	if report.InventoryOnly {
+		w.buf.WriteString(`,"inventory_only":true`)
+	}
+	if len(report.Warnings) != 0 {
+		w.buf.WriteString(`,"warnings":`)
+		if err := w.value(sortWarnings(report.Warnings)); err != nil {
+			return err
+		}
+	}
+	if len(report.DistributionsIntroducedIn) != 0 {
+		w.buf.WriteString(`,"distributions_introduced_in":`)
+		if err := w.value(report.DistributionsIntroducedIn); err != nil {
+			return err
+		}
+	}
+	if len(report.RepositoriesIntroducedIn) != 0 {
+		w.buf.WriteString(`,"repositories_introduced_in":`)
+		if err := w.value(report.RepositoriesIntroducedIn); err != nil {
+			return err
+		}
+	}
+	if report.Cost != nil {
+		w.buf.WriteString(`,"cost":`)
+		if err := w.value(report.Cost); err != nil {
+			return err
+		}
+	}
+	if report.Redaction != nil {
+		w.buf.WriteString(`,"redaction":`)
+		if err := w.value(report.Redaction); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte('}')
+	return nil
 }
 
 // IndexRecords returns a list of IndexRecords derived from the IndexReport
+//
+// Each record's Distribution is the one named by the package's Environment,
+// not a single distribution for the whole report. Matchers should use it to
+// filter records.
 func (report *IndexReport) IndexRecords() []*IndexRecord {
 	out := []*IndexRecord{}
 	for _, pkg := range report.Packages {
@@ -0,0 +1,225 @@
+package claircore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+func indexReport() *claircore.IndexReport {
+	return &claircore.IndexReport{
+		Hash:  claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		State: "IndexFinished",
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "bash", Version: "5.0-4"},
+			"2": {ID: "2", Name: "openssl", Version: "1.1.1d-0"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "debian", VersionID: "10"},
+		},
+		Repositories: map[string]*claircore.Repository{
+			"1": {ID: "1", Name: "main"},
+			"2": {ID: "2", Name: "contrib"},
+			"3": {ID: "3", Name: "non-free"},
+		},
+		Environments: reportEnvironments(),
+		Success:      true,
+	}
+}
+
+func TestIndexReportJSON(t *testing.T) {
+	want, err := json.Marshal(indexReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "indexreport.golden.json", want)
+
+	t.Run("Shuffled", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for i := 0; i < 100; i++ {
+			r := indexReport()
+			shuffleEnvironments(rng, r.Environments)
+			got, err := json.Marshal(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got: %s\nwant: %s", got, want)
+			}
+		}
+	})
+	t.Run("Scanners", func(t *testing.T) {
+		// Without Environments to sort, the report should encode the same
+		// as encoding/json would.
+		type plain claircore.IndexReport
+		r := indexReport()
+		r.Environments = nil
+		r.IndexerState = "0123456789abcdef"
+		r.Scanners = []claircore.ScannerDescription{
+			{Name: "dpkg", Version: "v0.0.3", Kind: "package"},
+			{Name: "debian", Version: "2", Kind: "distribution"},
+		}
+		r.LimitedSupport = "windows: only the distribution is indexed"
+		diffID := claircore.MustParseDigest(`sha256:` + strings.Repeat("b", 64))
+		r.Layers = []claircore.LayerIdentity{
+			{Hash: claircore.MustParseDigest(`sha256:` + strings.Repeat("a", 64)), DiffID: &diffID},
+			{Hash: claircore.MustParseDigest(`sha256:` + strings.Repeat("c", 64))},
+		}
+		r.InventoryOnly = true
+		r.Warnings = []claircore.Warning{
+			{Code: claircore.WarningUnsupportedMediaType, Subject: `sha256:` + strings.Repeat("a", 64), Message: "treated as gzip"},
+		}
+		r.DistributionsIntroducedIn = map[string]claircore.Digest{
+			"1": r.Layers[0].Hash,
+		}
+		r.RepositoriesIntroducedIn = map[string]claircore.Digest{
+			"2": r.Layers[1].Hash,
+			"1": r.Layers[0].Hash,
+		}
+		r.Cost = &claircore.IndexCost{
+			FetchedBytes: 1024,
+			ScratchBytes: 4096,
+			StoreRows:    3,
+			Phases:       []claircore.PhaseCost{{Phase: "FetchLayers", Elapsed: time.Second}},
+			Scanners:     []claircore.ScannerCost{{Name: "dpkg", Version: "v0.0.3", Kind: "package", Layers: 2, Elapsed: time.Millisecond}},
+		}
+		got, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal((*plain)(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got: %s\nwant: %s", got, want)
+		}
+	})
+	t.Run("RoundTrip", func(t *testing.T) {
+		var r claircore.IndexReport
+		if err := json.Unmarshal(want, &r); err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got: %s\nwant: %s", got, want)
+		}
+	})
+}
+
+// TestIndexReportJSONEscaping checks the hand-written parts of the encoding
+// against a golden file produced by plain encoding/json: strings that need
+// escaping, nil maps and slices, and nil entries.
+func TestIndexReportJSONEscaping(t *testing.T) {
+	r := &claircore.IndexReport{
+		Hash:  claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		State: "<IndexError> & \"more\"",
+		Packages: map[string]*claircore.Package{
+			"1":      {ID: "1", Name: "caf\u00e9", Version: "1.0\t\u2028", NormalizedVersion: claircore.Version{Kind: "semver", V: [10]int32{0, 1}}},
+			"2":      {ID: "2", Name: "openssl", CPE: cpe.MustUnbind("cpe:2.3:a:openssl:openssl:1.1.1d:*:*:*:*:*:*:*")},
+			"<&>":    nil,
+			"\x00\n": {ID: "\x00\n"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1":      {nil, {PackageDB: "a/<b>", RepositoryIDs: []string{}}, {PackageDB: "\u00e9", RepositoryIDs: []string{"b&", "a"}}},
+			"2":      nil,
+			"<&>":    {},
+			"\x00\n": {{}},
+		},
+		Err: "line one\nline two \x7f",
+	}
+	got, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "indexreport_escaping.golden.json", got)
+}
+
+// LargeIndexReport generates a report with n packages, shaped like a large
+// language-package heavy image: most packages share a handful of package
+// databases and layers, and some have source packages and repositories.
+func largeIndexReport(n int) *claircore.IndexReport {
+	layers := []claircore.Digest{
+		claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		claircore.MustParseDigest(`sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`),
+		claircore.MustParseDigest(`sha256:fc92eec5cac70b0c324cec2933cd7db1c0eae7c9e2649e42d02e77eb6da0d15f`),
+	}
+	r := &claircore.IndexReport{
+		Hash:     layers[0],
+		State:    "IndexFinished",
+		Packages: make(map[string]*claircore.Package, n),
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "debian", Name: "Debian GNU/Linux", VersionID: "10", VersionCodeName: "buster", PrettyName: "Debian GNU/Linux 10 (buster)"},
+		},
+		Repositories: map[string]*claircore.Repository{
+			"1": {ID: "1", Name: "main"},
+			"2": {ID: "2", Name: "contrib"},
+			"3": {ID: "3", Name: "non-free"},
+		},
+		Environments: make(map[string][]*claircore.Environment, n),
+		Success:      true,
+	}
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(i)
+		p := &claircore.Package{
+			ID:      id,
+			Name:    "package-" + id,
+			Version: "1." + strconv.Itoa(i%100) + ".0",
+			Kind:    claircore.BINARY,
+			Arch:    "amd64",
+			PURL:    "pkg:npm/package-" + id + "@1." + strconv.Itoa(i%100) + ".0",
+		}
+		env := &claircore.Environment{
+			PackageDB:      "srv/app/node_modules/package-" + id + "/package.json",
+			IntroducedIn:   layers[i%len(layers)],
+			DistributionID: "1",
+		}
+		if i%10 == 0 {
+			p.Source = &claircore.Package{ID: id + "-src", Name: "package-" + id, Kind: claircore.SOURCE}
+			p.NormalizedVersion = claircore.Version{Kind: "semver", V: [10]int32{0, 1, int32(i % 100)}}
+			env.PackageDB = "var/lib/dpkg/status"
+			env.RepositoryIDs = []string{"3", "1", "2"}
+		}
+		r.Packages[id] = p
+		r.Environments[id] = []*claircore.Environment{env}
+	}
+	return r
+}
+
+// BenchmarkIndexReportJSON reports the cost of encoding large reports, both
+// through encoding/json and by calling MarshalJSON directly. The former
+// includes the copy and compaction encoding/json does with the result of
+// MarshalJSON.
+func BenchmarkIndexReportJSON(b *testing.B) {
+	for _, n := range []int{1000, 150000} {
+		r := largeIndexReport(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.Run("Marshal", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := json.Marshal(r); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("MarshalJSON", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := r.MarshalJSON(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,287 @@
+// Package apisurface describes the exported API of a package, so that changes
+// to the packages claircore promises to keep stable can be caught in review.
+//
+// The description is built from the syntax tree alone: it doesn't type-check
+// the package, so it's fast enough to run as a unit test. The cost is that
+// methods promoted through embedding are reported as the embedded field
+// rather than as methods, and the types of untyped package variables are
+// reported as the expression they're initialized with.
+package apisurface
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Packages are the packages covered by the stability guarantee, as paths
+// relative to the module root.
+//
+// Adding a package here is a promise: its snapshot must be regenerated and
+// reviewed alongside any change to its exported API.
+var Packages = []string{
+	".",
+	"libindex",
+	"libvuln",
+	"libvuln/driver",
+}
+
+// SnapshotName returns the name of the snapshot file for the package at
+// "pkg", relative to the module root.
+func SnapshotName(pkg string) string {
+	if pkg == "." {
+		return "claircore.txt"
+	}
+	return strings.ReplaceAll(pkg, "/", "_") + ".txt"
+}
+
+// Surface returns the exported API of the package in "dir", one sorted line
+// per identifier, field, or method.
+//
+// Only the files that would be built for the current platform without extra
+// build tags are considered, and test files are ignored.
+func Surface(dir string) ([]string, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	w := &walker{fset: fset}
+	for _, n := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, n), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.GenDecl:
+				w.genDecl(d)
+			case *ast.FuncDecl:
+				w.funcDecl(d)
+			}
+		}
+	}
+	sort.Strings(w.out)
+	return w.out, nil
+}
+
+// Walker accumulates lines of the description.
+type walker struct {
+	fset *token.FileSet
+	out  []string
+}
+
+func (w *walker) emit(format string, args ...interface{}) {
+	w.out = append(w.out, fmt.Sprintf(format, args...))
+}
+
+// Node prints "n" on a single line.
+func (w *walker) node(n ast.Node) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, w.fset, n); err != nil {
+		panic(err) // Printing a parsed tree to a bytes.Buffer shouldn't fail.
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func (w *walker) genDecl(d *ast.GenDecl) {
+	switch d.Tok {
+	case token.CONST:
+		// Implicitly repeated specs reuse the type and value of the last
+		// explicit one, with iota advanced.
+		var typ ast.Expr
+		var vals []ast.Expr
+		for iota, s := range d.Specs {
+			s := s.(*ast.ValueSpec)
+			if s.Type != nil || len(s.Values) != 0 {
+				typ, vals = s.Type, s.Values
+			}
+			for i, n := range s.Names {
+				if !n.IsExported() {
+					continue
+				}
+				l := "const " + n.Name
+				if typ != nil {
+					l += " " + w.node(typ)
+				}
+				if i < len(vals) {
+					v := w.node(vals[i])
+					l += " = " + v
+					if usesIota(vals[i]) {
+						l += fmt.Sprintf(" (iota = %d)", iota)
+					}
+				}
+				w.emit("%s", l)
+			}
+		}
+	case token.VAR:
+		for _, s := range d.Specs {
+			s := s.(*ast.ValueSpec)
+			for i, n := range s.Names {
+				if !n.IsExported() {
+					continue
+				}
+				switch {
+				case s.Type != nil:
+					w.emit("var %s %s", n.Name, w.node(s.Type))
+				case i < len(s.Values) && len(s.Values) == len(s.Names):
+					w.emit("var %s %s", n.Name, w.varType(s.Values[i]))
+				default:
+					w.emit("var %s", n.Name)
+				}
+			}
+		}
+	case token.TYPE:
+		for _, s := range d.Specs {
+			s := s.(*ast.TypeSpec)
+			if !s.Name.IsExported() {
+				continue
+			}
+			w.typeSpec(s)
+		}
+	}
+}
+
+// VarType returns a description of the type of a variable initialized with
+// "e". Common forms are recognized; anything else is described by the
+// expression itself.
+func (w *walker) varType(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return w.node(e.Type)
+		}
+	case *ast.UnaryExpr:
+		if cl, ok := e.X.(*ast.CompositeLit); ok && e.Op == token.AND && cl.Type != nil {
+			return "*" + w.node(cl.Type)
+		}
+	case *ast.CallExpr:
+		switch w.node(e.Fun) {
+		case "errors.New", "fmt.Errorf":
+			return "error"
+		}
+	}
+	return "= " + w.node(e)
+}
+
+func (w *walker) typeSpec(s *ast.TypeSpec) {
+	name := s.Name.Name
+	if s.Assign.IsValid() {
+		w.emit("type %s = %s", name, w.node(s.Type))
+		return
+	}
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		w.emit("type %s struct", name)
+		for _, f := range t.Fields.List {
+			if len(f.Names) == 0 {
+				if embeddedName(f.Type).IsExported() {
+					w.emit("type %s struct, embedded %s", name, w.node(f.Type))
+				}
+				continue
+			}
+			for _, n := range f.Names {
+				if n.IsExported() {
+					w.emit("type %s struct, %s %s", name, n.Name, w.node(f.Type))
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		w.emit("type %s interface", name)
+		for _, m := range t.Methods.List {
+			if len(m.Names) == 0 {
+				w.emit("type %s interface, embedded %s", name, w.node(m.Type))
+				continue
+			}
+			for _, n := range m.Names {
+				// Unexported methods are part of the API too: they keep
+				// other packages from implementing the interface.
+				w.emit("type %s interface, %s%s", name, n.Name, w.signature(m.Type.(*ast.FuncType)))
+			}
+		}
+	default:
+		w.emit("type %s %s", name, w.node(s.Type))
+	}
+}
+
+func (w *walker) funcDecl(d *ast.FuncDecl) {
+	if !d.Name.IsExported() {
+		return
+	}
+	if d.Recv == nil {
+		w.emit("func %s%s", d.Name.Name, w.signature(d.Type))
+		return
+	}
+	recv := d.Recv.List[0].Type
+	base := recv
+	if s, ok := base.(*ast.StarExpr); ok {
+		base = s.X
+	}
+	if !embeddedName(base).IsExported() {
+		return
+	}
+	w.emit("method (%s) %s%s", w.node(recv), d.Name.Name, w.signature(d.Type))
+}
+
+// Signature describes a function's parameters and results by type alone, so
+// renaming a parameter isn't a change.
+func (w *walker) signature(t *ast.FuncType) string {
+	var b strings.Builder
+	b.WriteString(w.fields(t.Params))
+	if t.Results != nil && len(t.Results.List) != 0 {
+		rs := w.fields(t.Results)
+		if len(t.Results.List) == 1 && len(t.Results.List[0].Names) < 2 {
+			rs = rs[1 : len(rs)-1]
+		}
+		b.WriteByte(' ')
+		b.WriteString(rs)
+	}
+	return b.String()
+}
+
+func (w *walker) fields(fl *ast.FieldList) string {
+	var ts []string
+	if fl != nil {
+		for _, f := range fl.List {
+			t := w.node(f.Type)
+			n := len(f.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				ts = append(ts, t)
+			}
+		}
+	}
+	return "(" + strings.Join(ts, ", ") + ")"
+}
+
+// EmbeddedName returns the identifier naming the type "e".
+func embeddedName(e ast.Expr) *ast.Ident {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return ast.NewIdent("_")
+}
+
+func usesIota(e ast.Expr) (found bool) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
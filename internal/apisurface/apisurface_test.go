@@ -0,0 +1,116 @@
+package apisurface
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestSnapshot fails if the exported API of a covered package no longer
+// matches its snapshot.
+//
+// If the change is intentional, regenerate the snapshots with "go generate
+// ./internal/apisurface" and commit them, so the change is visible in review.
+func TestSnapshot(t *testing.T) {
+	for _, pkg := range Packages {
+		pkg := pkg
+		t.Run(SnapshotName(pkg), func(t *testing.T) {
+			got, err := Surface(filepath.Join("..", "..", pkg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadFile(filepath.Join("testdata", SnapshotName(pkg)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+			if !cmp.Equal(got, want) {
+				t.Errorf("exported API of %q changed (-snapshot, +current):\n%s", pkg, cmp.Diff(want, got))
+				t.Log(`if this is intentional, run "go generate ./internal/apisurface" and commit the result`)
+			}
+		})
+	}
+}
+
+func TestSurface(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apisurface.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const src = `package example
+
+import "errors"
+
+const (
+	A Kind = iota
+	B
+	c
+)
+
+const Name = "example"
+
+var ErrThing = errors.New("thing")
+
+var Default = &Config{}
+
+type Kind int
+
+type Alias = Config
+
+type Config struct {
+	Exported string
+	hidden   int
+	Kind
+	A, B     []byte
+}
+
+type Iface interface {
+	Do(ctx Kind, a, b string) (n int, err error)
+	seal()
+}
+
+func New(c *Config) (*Config, error) { return c, nil }
+
+func (c *Config) Method(a, b int) (err error) { return nil }
+
+func (c *Config) method() {}
+
+type private struct{}
+
+func (private) Exported() {}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "example.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Surface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"const A Kind = iota (iota = 0)",
+		"const B Kind = iota (iota = 1)",
+		`const Name = "example"`,
+		"func New(*Config) (*Config, error)",
+		"method (*Config) Method(int, int) error",
+		"type Alias = Config",
+		"type Config struct",
+		"type Config struct, A []byte",
+		"type Config struct, B []byte",
+		"type Config struct, Exported string",
+		"type Config struct, embedded Kind",
+		"type Iface interface",
+		"type Iface interface, Do(Kind, string, string) (int, error)",
+		"type Iface interface, seal()",
+		"type Kind int",
+		"var Default *Config",
+		"var ErrThing error",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
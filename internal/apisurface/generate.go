@@ -0,0 +1,3 @@
+package apisurface
+
+//go:generate go run ../../test/apisurface -root ../..
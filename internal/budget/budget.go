@@ -0,0 +1,229 @@
+// Package budget divides the time left before a Context's deadline across the
+// phases of a long operation, so that an early phase can't use all of it and
+// a failure reports which phase ran out of time.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase describes one phase of an operation.
+type Phase struct {
+	// Name identifies the phase in errors and Timings.
+	Name string
+	// Weight is the phase's share of the spare time, relative to the other
+	// phases that haven't started.
+	Weight int
+	// Floor is the least time the phase is allotted, as long as the
+	// deadline allows every remaining phase its floor.
+	Floor time.Duration
+}
+
+// Timing records how long a phase ran.
+type Timing struct {
+	Phase string
+	// Allotted is zero if the phase had no deadline.
+	Allotted time.Duration
+	Elapsed  time.Duration
+}
+
+// Overran reports whether the phase ran longer than it was allotted, leaving
+// less time for the phases after it.
+func (t Timing) Overran() bool {
+	return t.Allotted > 0 && t.Elapsed > t.Allotted
+}
+
+func (t Timing) String() string {
+	if t.Allotted == 0 {
+		return fmt.Sprintf("%s took %v", t.Phase, t.Elapsed.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%s took %v of %v",
+		t.Phase, t.Elapsed.Round(time.Millisecond), t.Allotted.Round(time.Millisecond))
+}
+
+// Budget allots time to the phases of one operation.
+//
+// Each phase's allotment is computed when it starts, from the time left
+// before the deadline, so time a phase doesn't use goes to the ones after
+// it. The spare time, after setting aside the floors of the phases that
+// haven't started, is divided by weight. If the floors don't fit, the time
+// left is divided by weight alone.
+//
+// Budget is safe for concurrent use.
+type Budget struct {
+	phases   []Phase
+	deadline time.Time
+	bounded  bool
+	now      func() time.Time
+
+	mu      sync.Mutex
+	started map[string]bool
+	timings []Timing
+}
+
+// New returns a Budget for an operation with the provided phases, in the
+// order they run, using the deadline of "ctx". If "ctx" has no deadline,
+// phases aren't limited but their Timings are still recorded.
+func New(ctx context.Context, phases ...Phase) *Budget {
+	b := &Budget{
+		phases:  phases,
+		now:     time.Now,
+		started: make(map[string]bool, len(phases)),
+	}
+	b.deadline, b.bounded = ctx.Deadline()
+	return b
+}
+
+// Start begins the named phase. The returned Context has the end of the
+// phase's allotment as its deadline. The returned function must be called
+// when the phase ends, with the error the phase returned, if any; it returns
+// that error, annotated with an *Error if the phase ran out of time.
+//
+// Phases not passed to New only have the operation's deadline.
+func (b *Budget) Start(ctx context.Context, name string) (context.Context, func(error) error) {
+	start := b.now()
+	allot := b.allot(name, start)
+	var pctx context.Context
+	var cancel context.CancelFunc
+	// An allotment running to the operation's deadline is left to the
+	// caller's Context, so the two don't race to expire.
+	if end := start.Add(allot); allot > 0 && end.Before(b.deadline) {
+		pctx, cancel = context.WithDeadline(ctx, end)
+	} else {
+		pctx, cancel = context.WithCancel(ctx)
+	}
+	region := trace.StartRegion(pctx, name)
+	return pctx, func(err error) error {
+		end := b.now()
+		t := Timing{Phase: name, Allotted: allot, Elapsed: end.Sub(start)}
+		expired := pctx.Err() == context.DeadlineExceeded
+		region.End()
+		cancel()
+		trace.Log(ctx, "budget", t.String())
+		b.mu.Lock()
+		b.timings = append(b.timings, t)
+		ts := append([]Timing(nil), b.timings...)
+		b.mu.Unlock()
+		if err == nil || !(expired || errors.Is(err, context.DeadlineExceeded)) {
+			return err
+		}
+		// Already attributed by a nested Budget.
+		var be *Error
+		if errors.As(err, &be) {
+			return err
+		}
+		return &Error{
+			Phase:   name,
+			Timings: ts,
+			Caller:  ctx.Err() != nil || (b.bounded && !end.Before(b.deadline)),
+			Err:     err,
+		}
+	}
+}
+
+// Allot computes the allotment for the named phase starting at "now". Zero
+// means no limit beyond the operation's deadline.
+func (b *Budget) allot(name string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	idx := -1
+	for i, p := range b.phases {
+		if p.Name == name {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 || !b.bounded {
+		return 0
+	}
+	b.started[name] = true
+	left := b.deadline.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+
+	cur := b.phases[idx]
+	var floors time.Duration
+	var weights int
+	for i, p := range b.phases {
+		if i != idx && (i < idx || b.started[p.Name]) {
+			continue
+		}
+		floors += p.Floor
+		weights += p.Weight
+	}
+	if weights == 0 {
+		// Nothing to divide by, so split evenly.
+		weights = 1
+		cur.Weight = 1
+	}
+	if left < floors {
+		return left * time.Duration(cur.Weight) / time.Duration(weights)
+	}
+	spare := left - floors
+	return cur.Floor + spare*time.Duration(cur.Weight)/time.Duration(weights)
+}
+
+// Timings returns the Timings of the phases that have ended, in the order
+// they ended.
+func (b *Budget) Timings() []Timing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Timing(nil), b.timings...)
+}
+
+// Timing returns the Timing of the named phase, if it's ended.
+func (b *Budget) Timing(name string) (Timing, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.timings) - 1; i >= 0; i-- {
+		if b.timings[i].Phase == name {
+			return b.timings[i], true
+		}
+	}
+	return Timing{}, false
+}
+
+// Error is returned by the function returned from Start when a phase ran out
+// of time.
+type Error struct {
+	// Phase is the phase that ran out of time.
+	Phase string
+	// Timings are those of every phase that has ended, including Phase.
+	Timings []Timing
+	// Caller is set if the operation's deadline passed, rather than only the
+	// phase's allotment.
+	Caller bool
+	Err    error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("budget: ")
+	if e.Caller {
+		b.WriteString("deadline exceeded during phase ")
+	} else {
+		b.WriteString("allotment exceeded by phase ")
+	}
+	b.WriteString(e.Phase)
+	b.WriteString(" (")
+	for i, t := range e.Timings {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(t.String())
+	}
+	b.WriteString("): ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
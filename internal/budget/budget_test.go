@@ -0,0 +1,189 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllot(t *testing.T) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	phases := []Phase{
+		{Name: "fetch", Weight: 4},
+		{Name: "scan", Weight: 4},
+		{Name: "store", Weight: 2, Floor: 10 * time.Second},
+	}
+	tt := []struct {
+		name string
+		left time.Duration
+		// Want is the allotment for each phase, assuming each one uses all
+		// of it.
+		want []time.Duration
+	}{
+		{
+			name: "Spare",
+			left: 110 * time.Second,
+			want: []time.Duration{40 * time.Second, 40 * time.Second, 30 * time.Second},
+		},
+		{
+			name: "Tight",
+			left: 5 * time.Second,
+			want: []time.Duration{2 * time.Second, 2 * time.Second, time.Second},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Budget{
+				phases:   phases,
+				deadline: base.Add(tc.left),
+				bounded:  true,
+				started:  make(map[string]bool),
+			}
+			now := base
+			for i, p := range phases {
+				got := b.allot(p.Name, now)
+				if want := tc.want[i]; got != want {
+					t.Errorf("%s: got %v, want %v", p.Name, got, want)
+				}
+				now = now.Add(got)
+			}
+		})
+	}
+
+	t.Run("Rollover", func(t *testing.T) {
+		b := &Budget{
+			phases:   phases,
+			deadline: base.Add(110 * time.Second),
+			bounded:  true,
+			started:  make(map[string]bool),
+		}
+		b.allot("fetch", base)
+		// Fetch finished early, so the rest is divided between scan and
+		// store.
+		if got, want := b.allot("scan", base.Add(10*time.Second)), 60*time.Second; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("Unbounded", func(t *testing.T) {
+		b := New(context.Background(), phases...)
+		if got := b.allot("fetch", base); got != 0 {
+			t.Errorf("got %v, want no allotment", got)
+		}
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		b := &Budget{
+			phases:   phases,
+			deadline: base.Add(time.Minute),
+			bounded:  true,
+			started:  make(map[string]bool),
+		}
+		if got := b.allot("other", base); got != 0 {
+			t.Errorf("got %v, want no allotment", got)
+		}
+	})
+}
+
+// TestAttribution runs phases against tight deadlines and checks the phase
+// that ran out of time is named in the error.
+func TestAttribution(t *testing.T) {
+	phases := []Phase{
+		{Name: "first", Weight: 1},
+		{Name: "second", Weight: 1},
+	}
+	// Block waits for the phase's Context to be done.
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	// Slow ignores the Context for a while.
+	slow := func(d time.Duration) func(context.Context) error {
+		return func(context.Context) error {
+			time.Sleep(d)
+			return nil
+		}
+	}
+
+	tt := []struct {
+		name   string
+		run    []func(context.Context) error
+		phase  string
+		caller bool
+	}{
+		{
+			name:  "First",
+			run:   []func(context.Context) error{block},
+			phase: "first",
+		},
+		{
+			name:  "Second",
+			run:   []func(context.Context) error{slow(time.Millisecond), block},
+			phase: "second",
+			// The second phase is the last one, so its allotment is the
+			// caller's deadline.
+			caller: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, done := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer done()
+			b := New(ctx, phases...)
+			var err error
+			for i, f := range tc.run {
+				pctx, end := b.Start(ctx, phases[i].Name)
+				if err = end(f(pctx)); err != nil {
+					break
+				}
+			}
+			var be *Error
+			if !errors.As(err, &be) {
+				t.Fatalf("got error %v, want *Error", err)
+			}
+			if got, want := be.Phase, tc.phase; got != want {
+				t.Errorf("got phase %q, want %q", got, want)
+			}
+			if got, want := be.Caller, tc.caller; got != want {
+				t.Errorf("got caller %v, want %v", got, want)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("error doesn't wrap deadline: %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.phase) {
+				t.Errorf("error doesn't name phase: %v", err)
+			}
+			if got, want := len(be.Timings), len(tc.run); got != want {
+				t.Errorf("got %d timings, want %d", got, want)
+			}
+			t.Log(err)
+		})
+	}
+
+	t.Run("Overran", func(t *testing.T) {
+		ctx, done := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer done()
+		b := New(ctx, phases...)
+		_, end := b.Start(ctx, "first")
+		if err := end(slow(150 * time.Millisecond)(ctx)); err != nil {
+			t.Fatal(err)
+		}
+		tm, ok := b.Timing("first")
+		if !ok {
+			t.Fatal("no timing recorded")
+		}
+		if !tm.Overran() {
+			t.Errorf("phase not reported as overrunning: %v", tm)
+		}
+	})
+	t.Run("OtherError", func(t *testing.T) {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		b := New(ctx, phases...)
+		want := errors.New("expected")
+		_, end := b.Start(ctx, "first")
+		if got := end(want); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
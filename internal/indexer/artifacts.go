@@ -0,0 +1,241 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/gzip"
+
+	"github.com/quay/claircore"
+)
+
+// LayerEnvelope is the scan results for a single layer, broken out by the
+// scanner that produced them.
+//
+// Envelopes let a layer be scanned by one indexer and the results imported
+// into another's Store, which then doesn't need to fetch or scan the layer.
+type LayerEnvelope struct {
+	// Hash is the layer's digest.
+	Hash claircore.Digest `json:"hash"`
+	// Artifacts holds an entry for every scanner that has scanned the layer,
+	// including scanners that found nothing.
+	Artifacts []*ScannerArtifacts `json:"artifacts"`
+}
+
+// ScannerArtifacts is what a single scanner found in a layer.
+//
+// ScannerArtifacts implements VersionedScanner, reporting the scanner that
+// produced it. Only the slice for the scanner's kind is populated.
+type ScannerArtifacts struct {
+	ScannerName    string
+	ScannerVersion string
+	ScannerKind    string
+
+	Packages      []*claircore.Package
+	Distributions []*claircore.Distribution
+	Repositories  []*claircore.Repository
+}
+
+var _ VersionedScanner = (*ScannerArtifacts)(nil)
+
+// Name implements VersionedScanner.
+func (a *ScannerArtifacts) Name() string { return a.ScannerName }
+
+// Version implements VersionedScanner.
+func (a *ScannerArtifacts) Version() string { return a.ScannerVersion }
+
+// Kind implements VersionedScanner.
+func (a *ScannerArtifacts) Kind() string { return a.ScannerKind }
+
+// ArtifactPackage is the JSON form of a Package in an envelope.
+//
+// The Package's JSON encoding omits the members describing where in the layer
+// a package was found, which an importing Store needs.
+type artifactPackage struct {
+	*claircore.Package
+	PackageDB      string `json:"package_db,omitempty"`
+	RepositoryHint string `json:"repository_hint,omitempty"`
+}
+
+type scannerArtifactsJSON struct {
+	Name          string                    `json:"name"`
+	Version       string                    `json:"version"`
+	Kind          string                    `json:"kind"`
+	Packages      []artifactPackage         `json:"packages,omitempty"`
+	Distributions []*claircore.Distribution `json:"distributions,omitempty"`
+	Repositories  []*claircore.Repository   `json:"repositories,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *ScannerArtifacts) MarshalJSON() ([]byte, error) {
+	out := scannerArtifactsJSON{
+		Name:          a.ScannerName,
+		Version:       a.ScannerVersion,
+		Kind:          a.ScannerKind,
+		Distributions: a.Distributions,
+		Repositories:  a.Repositories,
+	}
+	if len(a.Packages) != 0 {
+		out.Packages = make([]artifactPackage, len(a.Packages))
+		for i, p := range a.Packages {
+			out.Packages[i] = artifactPackage{
+				Package:        p,
+				PackageDB:      p.PackageDB,
+				RepositoryHint: p.RepositoryHint,
+			}
+		}
+	}
+	return json.Marshal(&out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *ScannerArtifacts) UnmarshalJSON(b []byte) error {
+	var in scannerArtifactsJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*a = ScannerArtifacts{
+		ScannerName:    in.Name,
+		ScannerVersion: in.Version,
+		ScannerKind:    in.Kind,
+		Distributions:  in.Distributions,
+		Repositories:   in.Repositories,
+	}
+	if len(in.Packages) != 0 {
+		a.Packages = make([]*claircore.Package, len(in.Packages))
+		for i, p := range in.Packages {
+			if p.Package == nil {
+				return fmt.Errorf("indexer: null package in artifacts for scanner %q", in.Name)
+			}
+			p.Package.PackageDB = p.PackageDB
+			p.Package.RepositoryHint = p.RepositoryHint
+			a.Packages[i] = p.Package
+		}
+	}
+	return nil
+}
+
+// EnvelopeMagic begins the binary encoding of a LayerEnvelope. The trailing
+// byte is the encoding version.
+const envelopeMagic = "claircore layer\x00\x01"
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The binary encoding is a header followed by the gzip-compressed JSON
+// encoding.
+func (e *LayerEnvelope) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := e.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (e *LayerEnvelope) UnmarshalBinary(b []byte) error {
+	return e.Decode(bytes.NewReader(b))
+}
+
+// Encode writes the binary encoding of the envelope to "w".
+func (e *LayerEnvelope) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, envelopeMagic); err != nil {
+		return err
+	}
+	z := gzip.NewWriter(w)
+	if err := json.NewEncoder(z).Encode(e); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// ErrBadEnvelope is reported when decoding something that isn't a
+// LayerEnvelope in a known encoding.
+var ErrBadEnvelope = errors.New("indexer: not a layer envelope")
+
+// Decode reads the binary encoding of an envelope from "r".
+func (e *LayerEnvelope) Decode(r io.Reader) error {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(envelopeMagic))
+	switch _, err := io.ReadFull(br, hdr); {
+	case err == nil:
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrBadEnvelope
+	default:
+		return err
+	}
+	if string(hdr) != envelopeMagic {
+		return ErrBadEnvelope
+	}
+	z, err := gzip.NewReader(br)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadEnvelope, err)
+	}
+	defer z.Close()
+	b, err := ioutil.ReadAll(z)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadEnvelope, err)
+	}
+	var out LayerEnvelope
+	if err := json.Unmarshal(b, &out); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadEnvelope, err)
+	}
+	*e = out
+	return nil
+}
+
+// Validate checks that the envelope is for the layer "hash" and that every
+// set of artifacts in it was produced by one of "scnrs", at the same version.
+//
+// An envelope is allowed to cover only some of the scanners; the layer is
+// scanned by the rest as it would be without an import.
+func (e *LayerEnvelope) Validate(hash claircore.Digest, scnrs VersionedScanners) error {
+	if e.Hash.String() != hash.String() {
+		return fmt.Errorf("indexer: envelope is for layer %q, not %q", e.Hash, hash)
+	}
+	type key struct{ name, kind string }
+	want := make(map[key]string, len(scnrs))
+	for _, s := range scnrs {
+		want[key{s.Name(), s.Kind()}] = s.Version()
+	}
+	seen := make(map[key]struct{}, len(e.Artifacts))
+	for _, a := range e.Artifacts {
+		if a == nil {
+			return errors.New("indexer: envelope has null artifacts")
+		}
+		k := key{a.Name(), a.Kind()}
+		if _, ok := seen[k]; ok {
+			return fmt.Errorf("indexer: envelope has duplicate artifacts for %s scanner %q", a.Kind(), a.Name())
+		}
+		seen[k] = struct{}{}
+		v, ok := want[k]
+		switch {
+		case !ok:
+			return fmt.Errorf("indexer: envelope has artifacts for unconfigured %s scanner %q", a.Kind(), a.Name())
+		case v != a.Version():
+			return fmt.Errorf("indexer: envelope has artifacts for %s scanner %q version %q, configured version is %q",
+				a.Kind(), a.Name(), a.Version(), v)
+		}
+		switch a.Kind() {
+		case "package":
+			if len(a.Distributions) != 0 || len(a.Repositories) != 0 {
+				return fmt.Errorf("indexer: package scanner %q has non-package artifacts", a.Name())
+			}
+		case "distribution":
+			if len(a.Packages) != 0 || len(a.Repositories) != 0 {
+				return fmt.Errorf("indexer: distribution scanner %q has non-distribution artifacts", a.Name())
+			}
+		case "repository":
+			if len(a.Packages) != 0 || len(a.Distributions) != 0 {
+				return fmt.Errorf("indexer: repository scanner %q has non-repository artifacts", a.Name())
+			}
+		default:
+			return fmt.Errorf("indexer: unknown scanner kind %q", a.Kind())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+var testLayer = claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+
+func testEnvelope() *LayerEnvelope {
+	return &LayerEnvelope{
+		Hash: testLayer,
+		Artifacts: []*ScannerArtifacts{
+			{
+				ScannerName:    "pkg",
+				ScannerVersion: "1",
+				ScannerKind:    "package",
+				Packages: []*claircore.Package{
+					{
+						Name:           "openssl",
+						Version:        "1.1.1k-1",
+						Kind:           claircore.BINARY,
+						PackageDB:      "var/lib/dpkg/status",
+						RepositoryHint: "main",
+						Source: &claircore.Package{
+							Name: "openssl",
+							Kind: claircore.SOURCE,
+						},
+						NormalizedVersion: claircore.Version{
+							Kind: "test",
+							V:    [...]int32{1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+						},
+					},
+				},
+			},
+			{
+				ScannerName:    "dist",
+				ScannerVersion: "2",
+				ScannerKind:    "distribution",
+				Distributions: []*claircore.Distribution{
+					{DID: "debian", VersionID: "11"},
+				},
+			},
+			{
+				ScannerName:    "repo",
+				ScannerVersion: "3",
+				ScannerKind:    "repository",
+			},
+		},
+	}
+}
+
+func testScanners() VersionedScanners {
+	return VersionedScanners{
+		&ScannerArtifacts{ScannerName: "pkg", ScannerVersion: "1", ScannerKind: "package"},
+		&ScannerArtifacts{ScannerName: "dist", ScannerVersion: "2", ScannerKind: "distribution"},
+		&ScannerArtifacts{ScannerName: "repo", ScannerVersion: "3", ScannerKind: "repository"},
+	}
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	want := testEnvelope()
+	opts := cmp.AllowUnexported(claircore.Digest{})
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got LayerEnvelope
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&got, want, opts) {
+			t.Error(cmp.Diff(&got, want, opts))
+		}
+	})
+	t.Run("Binary", func(t *testing.T) {
+		b, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got LayerEnvelope
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&got, want, opts) {
+			t.Error(cmp.Diff(&got, want, opts))
+		}
+	})
+	t.Run("Garbage", func(t *testing.T) {
+		for _, in := range []string{
+			"",
+			"claircore",
+			`{"hash":""}`,
+			envelopeMagic + "not gzip",
+		} {
+			var got LayerEnvelope
+			if err := got.Decode(bytes.NewReader([]byte(in))); !errors.Is(err, ErrBadEnvelope) {
+				t.Errorf("%q: got error %v, want %v", in, err, ErrBadEnvelope)
+			}
+		}
+	})
+}
+
+func TestEnvelopeValidate(t *testing.T) {
+	other := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	tt := []struct {
+		Name   string
+		Hash   claircore.Digest
+		Modify func(*LayerEnvelope)
+		Err    bool
+	}{
+		{
+			Name: "OK",
+			Hash: testLayer,
+		},
+		{
+			Name: "Partial",
+			Hash: testLayer,
+			Modify: func(e *LayerEnvelope) {
+				e.Artifacts = e.Artifacts[:1]
+			},
+		},
+		{
+			Name: "WrongLayer",
+			Hash: other,
+			Err:  true,
+		},
+		{
+			Name: "ScannerVersion",
+			Hash: testLayer,
+			Modify: func(e *LayerEnvelope) {
+				e.Artifacts[0].ScannerVersion = "0"
+			},
+			Err: true,
+		},
+		{
+			Name: "UnknownScanner",
+			Hash: testLayer,
+			Modify: func(e *LayerEnvelope) {
+				e.Artifacts[0].ScannerName = "other"
+			},
+			Err: true,
+		},
+		{
+			Name: "Duplicate",
+			Hash: testLayer,
+			Modify: func(e *LayerEnvelope) {
+				e.Artifacts = append(e.Artifacts, e.Artifacts[0])
+			},
+			Err: true,
+		},
+		{
+			Name: "WrongKind",
+			Hash: testLayer,
+			Modify: func(e *LayerEnvelope) {
+				e.Artifacts[1].Packages = e.Artifacts[0].Packages
+			},
+			Err: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			e := testEnvelope()
+			if tc.Modify != nil {
+				tc.Modify(e)
+			}
+			err := e.Validate(tc.Hash, testScanners())
+			t.Log(err)
+			if got, want := err != nil, tc.Err; got != want {
+				t.Errorf("got error %v, want error: %v", err, want)
+			}
+		})
+	}
+}
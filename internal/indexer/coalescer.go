@@ -6,7 +6,8 @@ import (
 	"github.com/quay/claircore"
 )
 
-// layerArifact aggregates the any artifacts found within a layer
+// LayerArtifacts aggregates the artifacts an Ecosystem's scanners found within
+// a layer.
 type LayerArtifacts struct {
 	Hash  claircore.Digest
 	Pkgs  []*claircore.Package
@@ -18,6 +19,10 @@ type LayerArtifacts struct {
 //
 // A coalesced IndexReport should provide only the packages present in the
 // final container image once all layers were applied.
+//
+// The artifacts are in the manifest's layer order, starting with the base
+// layer. Implementations may modify the artifacts' contents, such as by
+// populating a Package's PURL, but must not retain them.
 type Coalescer interface {
 	Coalesce(ctx context.Context, artifacts []*LayerArtifacts) (*claircore.IndexReport, error)
 }
@@ -103,6 +103,35 @@ func MergeSR(source *claircore.IndexReport, merge []*claircore.IndexReport) *cla
 		for k, v := range ir.Repositories {
 			source.Repositories[k] = v
 		}
+		source.DistributionsIntroducedIn = mergeIntroducedIn(source.Layers, source.DistributionsIntroducedIn, ir.DistributionsIntroducedIn)
+		source.RepositoriesIntroducedIn = mergeIntroducedIn(source.Layers, source.RepositoriesIntroducedIn, ir.RepositoriesIntroducedIn)
+		source.Warnings = append(source.Warnings, ir.Warnings...)
 	}
 	return source
 }
+
+// MergeIntroducedIn adds the entries of "src" to "dst", allocating it if
+// needed. When both have an entry for an ID, the layer earliest in "layers"
+// is kept, so the result doesn't depend on the order coalescers finish in.
+func mergeIntroducedIn(layers []claircore.LayerIdentity, dst, src map[string]claircore.Digest) map[string]claircore.Digest {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]claircore.Digest, len(src))
+	}
+	pos := func(d claircore.Digest) int {
+		for i, l := range layers {
+			if l.Hash.String() == d.String() {
+				return i
+			}
+		}
+		return len(layers)
+	}
+	for id, d := range src {
+		if cur, ok := dst[id]; !ok || pos(d) < pos(cur) {
+			dst[id] = d
+		}
+	}
+	return dst
+}
@@ -4,7 +4,17 @@ import (
 	"context"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/dpkg"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/ubuntu"
 )
 
 // TestCoalesce confirms when no error is encountered
@@ -42,3 +52,173 @@ func TestCoalesce(t *testing.T) {
 		})
 	}
 }
+
+// TestCoalesceMixedDistributions checks that a manifest with layers from two
+// distributions, such as an Alpine-built layer copied onto a Debian base, has
+// each package attributed to its own distribution and only passes its own
+// distribution's matcher.
+func TestCoalesceMixedDistributions(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+
+	debianDist := &claircore.Distribution{
+		ID:              "1",
+		DID:             "debian",
+		Name:            "Debian GNU/Linux",
+		VersionID:       "10",
+		VersionCodeName: "buster",
+	}
+	alpineDist := &claircore.Distribution{
+		ID:        "2",
+		DID:       "alpine",
+		Name:      "Alpine Linux",
+		VersionID: "3.12",
+	}
+	layers := []*claircore.Layer{
+		{Hash: test.RandomSHA256Digest(t)},
+		{Hash: test.RandomSHA256Digest(t)},
+	}
+	// The fixture is keyed by layer, then by scanner name.
+	type fixture struct {
+		pkgs  map[string][]*claircore.Package
+		dists map[string][]*claircore.Distribution
+	}
+	fixtures := map[string]fixture{
+		// Debian base.
+		layers[0].Hash.String(): {
+			pkgs: map[string][]*claircore.Package{
+				"dpkg": {
+					{ID: "10", Name: "libc6", Version: "2.28-10", PackageDB: "var/lib/dpkg/status"},
+					{ID: "11", Name: "openssl", Version: "1.1.1d-0+deb10u3", PackageDB: "var/lib/dpkg/status"},
+				},
+			},
+			dists: map[string][]*claircore.Distribution{
+				"debian": {debianDist},
+			},
+		},
+		// Alpine-built layer, copied in with its os-release and apk database.
+		layers[1].Hash.String(): {
+			pkgs: map[string][]*claircore.Package{
+				"apk": {
+					{ID: "20", Name: "musl", Version: "1.1.24-r9", PackageDB: "lib/apk/db/installed"},
+					{ID: "21", Name: "openssl", Version: "1.1.1g-r0", PackageDB: "lib/apk/db/installed"},
+				},
+			},
+			dists: map[string][]*claircore.Distribution{
+				"alpine": {alpineDist},
+			},
+		},
+	}
+
+	store := indexer.NewMockStore(ctrl)
+	store.EXPECT().
+		PackagesByLayer(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, h claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Package, error) {
+			var out []*claircore.Package
+			for _, v := range vs {
+				out = append(out, fixtures[h.String()].pkgs[v.Name()]...)
+			}
+			return out, nil
+		}).AnyTimes()
+	store.EXPECT().
+		DistributionsByLayer(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, h claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Distribution, error) {
+			var out []*claircore.Distribution
+			for _, v := range vs {
+				out = append(out, fixtures[h.String()].dists[v.Name()]...)
+			}
+			return out, nil
+		}).AnyTimes()
+	store.EXPECT().
+		RepositoriesByLayer(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).AnyTimes()
+
+	c := New(&indexer.Opts{
+		Store: store,
+		Ecosystems: []*indexer.Ecosystem{
+			alpine.NewEcosystem(ctx),
+			dpkg.NewEcosystem(ctx),
+		},
+	})
+	c.manifest = &claircore.Manifest{
+		Hash:   test.RandomSHA256Digest(t),
+		Layers: layers,
+	}
+	if _, err := coalesce(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(c.report.Distributions), 2; got != want {
+		t.Errorf("got %d distributions, want %d", got, want)
+	}
+	want := map[string]string{
+		"10": debianDist.ID,
+		"11": debianDist.ID,
+		"20": alpineDist.ID,
+		"21": alpineDist.ID,
+	}
+	for id, dist := range want {
+		envs := c.report.Environments[id]
+		if len(envs) != 1 {
+			t.Errorf("package %s: got %d environments, want 1", id, len(envs))
+			continue
+		}
+		if got := envs[0].DistributionID; got != dist {
+			t.Errorf("package %s: got distribution %q, want %q", id, got, dist)
+		}
+	}
+
+	matchers := map[string]driver.Matcher{
+		debianDist.ID: &debian.Matcher{},
+		alpineDist.ID: &alpine.Matcher{},
+	}
+	other := &ubuntu.Matcher{}
+	for _, r := range c.report.IndexRecords() {
+		for dist, m := range matchers {
+			if got, want := m.Filter(r), want[r.Package.ID] == dist; got != want {
+				t.Errorf("package %s (%s): %s filter: got %v, want %v", r.Package.ID, r.Package.Name, m.Name(), got, want)
+			}
+		}
+		if other.Filter(r) {
+			t.Errorf("package %s (%s): ubuntu filter matched", r.Package.ID, r.Package.Name)
+		}
+	}
+}
+
+// TestMergeIntroducedIn checks that when coalescers disagree about the layer
+// that introduced a distribution or repository, the earliest layer is kept,
+// whatever order the reports are merged in.
+func TestMergeIntroducedIn(t *testing.T) {
+	ls := []claircore.LayerIdentity{
+		{Hash: test.RandomSHA256Digest(t)},
+		{Hash: test.RandomSHA256Digest(t)},
+	}
+	early := &claircore.IndexReport{
+		DistributionsIntroducedIn: map[string]claircore.Digest{"1": ls[0].Hash},
+		RepositoriesIntroducedIn:  map[string]claircore.Digest{"1": ls[0].Hash},
+	}
+	late := &claircore.IndexReport{
+		DistributionsIntroducedIn: map[string]claircore.Digest{"1": ls[1].Hash},
+		RepositoriesIntroducedIn:  map[string]claircore.Digest{"1": ls[1].Hash, "2": ls[1].Hash},
+	}
+	for _, order := range [][]*claircore.IndexReport{{early, late}, {late, early}} {
+		ir := MergeSR(&claircore.IndexReport{
+			Environments:  map[string][]*claircore.Environment{},
+			Packages:      map[string]*claircore.Package{},
+			Distributions: map[string]*claircore.Distribution{},
+			Repositories:  map[string]*claircore.Repository{},
+			Layers:        ls,
+		}, order)
+		if got, want := ir.DistributionsIntroducedIn["1"], ls[0].Hash; got.String() != want.String() {
+			t.Errorf("distribution: got %v, want %v", got, want)
+		}
+		if got, want := ir.RepositoriesIntroducedIn["1"], ls[0].Hash; got.String() != want.String() {
+			t.Errorf("repository 1: got %v, want %v", got, want)
+		}
+		if got, want := ir.RepositoriesIntroducedIn["2"], ls[1].Hash; got.String() != want.String() {
+			t.Errorf("repository 2: got %v, want %v", got, want)
+		}
+	}
+}
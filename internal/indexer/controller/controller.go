@@ -3,12 +3,14 @@ package controller
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/budget"
 	"github.com/quay/claircore/internal/indexer"
 )
 
@@ -18,6 +20,18 @@ import (
 // see controller_test.go
 var startState State = CheckManifest
 
+// IndexPhases is how the deadline of an index is divided across the states.
+// Fetching and scanning grow with the size of the layers, so they get most of
+// it; the states after them have floors so a slow fetch can't leave no time
+// to record the results. States not listed only have the caller's deadline.
+var indexPhases = []budget.Phase{
+	{Name: FetchLayers.String(), Weight: 4},
+	{Name: ScanLayers.String(), Weight: 4},
+	{Name: Coalesce.String(), Weight: 1, Floor: time.Second},
+	{Name: IndexManifest.String(), Weight: 1, Floor: 2 * time.Second},
+	{Name: IndexFinished.String(), Weight: 1, Floor: time.Second},
+}
+
 // Controller is a control structure for scanning a manifest.
 //
 // Controller is implemented as an FSM.
@@ -34,6 +48,19 @@ type Controller struct {
 	report *claircore.IndexReport
 	// a fatal error halting the scanning process
 	err error
+	// the configured scanners. Vscnrs is trimmed to the scanners a manifest
+	// still needs, but the report records all of them.
+	configured indexer.VersionedScanners
+	// the number of leading manifest layers that were indexed as part of
+	// another manifest. Their artifacts are read from the store, but they
+	// aren't fetched or scanned.
+	shared int
+	// the division of the caller's deadline across the states.
+	budget *budget.Budget
+	// the index records written by the IndexManifest state.
+	rows int64
+	// the cost of the index as of the last state to run.
+	spent *claircore.IndexCost
 }
 
 // New constructs a controller given an Opts struct
@@ -53,6 +80,7 @@ func New(opts *indexer.Opts) *Controller {
 		currentState: startState,
 		report:       scanRes,
 		manifest:     &claircore.Manifest{},
+		configured:   opts.Vscnrs,
 	}
 
 	return s
@@ -74,14 +102,56 @@ func (s *Controller) Index(ctx context.Context, manifest *claircore.Manifest) *c
 	defer s.Fetcher.Close()
 	// setup our logger. all stateFuncs may use this to log with a log context
 	zlog.Info(ctx).Msg("starting scan")
+	s.budget = budget.New(ctx, indexPhases...)
 	s.run(ctx)
+	// The cost is of this operation, so it's attached to the returned
+	// report but never stored.
+	s.report.Cost = s.spent
+	ts := s.budget.Timings()
+	tstr := make([]string, len(ts))
+	for i, t := range ts {
+		tstr[i] = t.String()
+	}
+	zlog.Debug(ctx).
+		Strs("timings", tstr).
+		Msg("state timings")
 	return s.report
 }
 
+// IndexIncremental is like Index, but the first "shared" layers of the
+// manifest are assumed to have been fetched and scanned by every configured
+// scanner already, for example as the layers of a previously indexed
+// manifest. Only the remaining layers are fetched and scanned; the report
+// covers all of them.
+func (s *Controller) IndexIncremental(ctx context.Context, manifest *claircore.Manifest, shared int) *claircore.IndexReport {
+	if shared > len(manifest.Layers) {
+		shared = len(manifest.Layers)
+	}
+	s.shared = shared
+	return s.Index(ctx, manifest)
+}
+
+// newLayers returns the manifest layers that need to be fetched and scanned.
+func (s *Controller) newLayers() []*claircore.Layer {
+	return s.manifest.Layers[s.shared:]
+}
+
 // run executes each stateFunc and blocks until either an error occurs or
 // a Terminal state is encountered.
 func (s *Controller) run(ctx context.Context) {
-	state, err := stateToStateFunc[s.getState()](ctx, s)
+	cur := s.getState()
+	sctx, done := s.budget.Start(ctx, cur.String())
+	state, err := stateToStateFunc[cur](sctx, s)
+	err = done(err)
+	s.account(cur)
+	// A state that failed for lack of time is named in the error instead.
+	if t, ok := s.budget.Timing(cur.String()); ok && err == nil && t.Overran() {
+		s.report.Warnings = append(s.report.Warnings, claircore.Warning{
+			Code:    claircore.WarningPhaseOverrun,
+			Subject: cur.String(),
+			Message: t.String(),
+		})
+	}
 	if err != nil {
 		s.handleError(ctx, err)
 		return
@@ -101,6 +171,7 @@ func (s *Controller) run(ctx context.Context) {
 // handleError updates the IndexReport to communicate an error and attempts
 // to persist this information.
 func (s *Controller) handleError(ctx context.Context, err error) {
+	s.err = err
 	s.report.Success = false
 	s.report.Err = err.Error()
 	s.report.State = IndexError.String()
@@ -116,6 +187,11 @@ func (s *Controller) handleError(ctx context.Context, err error) {
 	}
 }
 
+// Err returns the error that halted the last Index, if any.
+func (s *Controller) Err() error {
+	return s.err
+}
+
 // setState is a helper method to transition the controller to the provided next state
 func (s *Controller) setState(state State) {
 	s.currentState = state
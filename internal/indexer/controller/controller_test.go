@@ -2,14 +2,18 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/budget"
 	"github.com/quay/claircore/internal/indexer"
 )
 
@@ -129,3 +133,90 @@ func TestControllerIndexFinished(t *testing.T) {
 		})
 	}
 }
+
+// TestControllerBudget runs the controller against a tight deadline and checks
+// that the state that ran out of time is named in the error, and that a state
+// running past its share is reported in the warnings.
+func TestControllerBudget(t *testing.T) {
+	defer func(s State) { startState = s }(startState)
+	startState = FetchLayers
+	// Block waits for the state's Context to be done.
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	var tt = []struct {
+		name    string
+		fetch   func(context.Context) error
+		scan    func(context.Context) error
+		phase   State
+		overran []string
+	}{
+		{
+			name:  "FetchLayers",
+			fetch: block,
+			phase: FetchLayers,
+		},
+		{
+			name: "ScanLayers",
+			// Ignores its deadline.
+			fetch: func(context.Context) error {
+				time.Sleep(250 * time.Millisecond)
+				return nil
+			},
+			scan:    block,
+			phase:   ScanLayers,
+			overran: []string{FetchLayers.String()},
+		},
+	}
+
+	for _, table := range tt {
+		t.Run(table.name, func(t *testing.T) {
+			ctx, done := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer done()
+			ctx = zlog.Test(ctx, t)
+			ctrl := gomock.NewController(t)
+			store := indexer.NewMockStore(ctrl)
+			fetcher := indexer.NewMockFetcher(ctrl)
+			ls := indexer.NewMockLayerScanner(ctrl)
+			store.EXPECT().SetIndexReport(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			store.EXPECT().LayersScanned(gomock.Any(), gomock.Any(), gomock.Any()).Return([][]bool{}, nil)
+			fetcher.EXPECT().Fetch(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _ []*claircore.Layer) error { return table.fetch(ctx) })
+			fetcher.EXPECT().Close()
+			if table.scan != nil {
+				ls.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ claircore.Digest, _ []*claircore.Layer) error { return table.scan(ctx) })
+			}
+			c := New(&indexer.Opts{
+				Store:        store,
+				Fetcher:      fetcher,
+				LayerScanner: ls,
+			})
+
+			ir := c.Index(ctx, &claircore.Manifest{})
+			if ir.Success {
+				t.Fatal("expected failure")
+			}
+			var be *budget.Error
+			if !errors.As(c.Err(), &be) {
+				t.Fatalf("got error %v, want *budget.Error", c.Err())
+			}
+			if got, want := be.Phase, table.phase.String(); got != want {
+				t.Errorf("got phase %q, want %q", got, want)
+			}
+			if !strings.Contains(ir.Err, table.phase.String()) {
+				t.Errorf("report error doesn't name state: %q", ir.Err)
+			}
+			var overran []string
+			for _, w := range ir.Warnings {
+				if w.Code == claircore.WarningPhaseOverrun {
+					overran = append(overran, w.Subject)
+				}
+			}
+			if !cmp.Equal(overran, table.overran) {
+				t.Error(cmp.Diff(overran, table.overran))
+			}
+		})
+	}
+}
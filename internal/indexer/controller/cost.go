@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	phaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "phase_duration_seconds",
+			Help:      "Wall time of each phase of an index operation.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+		[]string{"phase"},
+	)
+	phaseBytes = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "phase_bytes",
+			Help:      "Bytes fetched (\"fetched\") and written to the scratch directory (\"scratch\") by each phase of an index operation.",
+			Buckets:   prometheus.ExponentialBuckets(1<<10, 4, 12), // 1 KiB to 4 GiB
+		},
+		[]string{"phase", "kind"},
+	)
+	phaseRows = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "phase_store_rows",
+			Help:      "Artifacts and index records written to the store by each phase of an index operation.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+		},
+		[]string{"phase"},
+	)
+)
+
+// Cost returns the cost of the index so far, collected from the Fetcher and
+// LayerScanner if they're CostReporters.
+func (s *Controller) cost() *claircore.IndexCost {
+	c := &claircore.IndexCost{StoreRows: s.rows}
+	if cr, ok := s.Fetcher.(indexer.CostReporter); ok {
+		cr.AddCost(c)
+	}
+	if cr, ok := s.LayerScanner.(indexer.CostReporter); ok {
+		cr.AddCost(c)
+	}
+	if s.budget != nil {
+		for _, t := range s.budget.Timings() {
+			c.Phases = append(c.Phases, claircore.PhaseCost{Phase: t.Phase, Elapsed: t.Elapsed})
+		}
+	}
+	return c
+}
+
+// Account records the cost of the index after the state "cur", and
+// attributes what changed since the previous state to "cur" in the metrics.
+func (s *Controller) account(cur State) {
+	c := s.cost()
+	prev := s.spent
+	if prev == nil {
+		prev = &claircore.IndexCost{}
+	}
+	name := cur.String()
+	if t, ok := s.budget.Timing(name); ok {
+		phaseDuration.WithLabelValues(name).Observe(t.Elapsed.Seconds())
+	}
+	phaseBytes.WithLabelValues(name, "fetched").Observe(float64(c.FetchedBytes - prev.FetchedBytes))
+	phaseBytes.WithLabelValues(name, "scratch").Observe(float64(c.ScratchBytes - prev.ScratchBytes))
+	phaseRows.WithLabelValues(name).Observe(float64(c.StoreRows - prev.StoreRows))
+	s.spent = c
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
 )
 
 func fetchLayers(ctx context.Context, s *Controller) (State, error) {
@@ -14,7 +17,7 @@ func fetchLayers(ctx context.Context, s *Controller) (State, error) {
 		label.String("state", s.getState().String()))
 	zlog.Info(ctx).Msg("layers fetch start")
 	defer zlog.Info(ctx).Msg("layers fetch done")
-	toFetch, err := reduce(ctx, s.Store, s.Vscnrs, s.manifest.Layers)
+	toFetch, err := reduce(ctx, s.Store, s.Vscnrs, s.newLayers())
 	if err != nil {
 		return Terminal, fmt.Errorf("failed to determine layers to fetch: %w", err)
 	}
@@ -27,6 +30,54 @@ func fetchLayers(ctx context.Context, s *Controller) (State, error) {
 			Msg("layers fetch failure")
 		return Terminal, fmt.Errorf("failed to fetch layers: %w", err)
 	}
+	if wr, ok := s.Fetcher.(indexer.WarningReporter); ok {
+		s.report.Warnings = append(s.report.Warnings, wr.Warnings()...)
+	}
+	if err := layerIdentities(ctx, s, toFetch); err != nil {
+		return Terminal, fmt.Errorf("failed to verify layers: %w", err)
+	}
 	zlog.Info(ctx).Msg("layers fetch success")
 	return ScanLayers, nil
 }
+
+// LayerIdentities records the diffIDs the Fetcher computed for the fetched
+// layers, checks any diffIDs provided for the other layers against the ones
+// recorded when they were fetched, and lists the layers in the report.
+func layerIdentities(ctx context.Context, s *Controller, fetched []*claircore.Layer) error {
+	recorded := make(map[string]bool, len(fetched))
+	for _, l := range fetched {
+		// The Fetcher may have skipped a layer another fetch is working on.
+		if l.DiffID == nil {
+			continue
+		}
+		if err := s.Store.SetLayerDiffID(ctx, l.Hash, *l.DiffID); err != nil {
+			return err
+		}
+		recorded[l.Hash.String()] = true
+	}
+	ids := make([]claircore.LayerIdentity, len(s.manifest.Layers))
+	for i, l := range s.manifest.Layers {
+		ids[i].Hash = l.Hash
+		if recorded[l.Hash.String()] {
+			ids[i].DiffID = l.DiffID
+			continue
+		}
+		d, ok, err := s.Store.LayerDiffID(ctx, l.Hash)
+		switch {
+		case err != nil:
+			return err
+		case !ok:
+			if l.DiffID != nil {
+				zlog.Info(ctx).
+					Stringer("layer", l.Hash).
+					Msg("no diffID recorded for layer, unable to verify")
+			}
+			continue
+		case l.DiffID != nil && l.DiffID.String() != d.String():
+			return &claircore.DiffIDMismatchError{Layer: l.Hash, Want: *l.DiffID, Got: d}
+		}
+		ids[i].DiffID = &d
+	}
+	s.report.Layers = ids
+	return nil
+}
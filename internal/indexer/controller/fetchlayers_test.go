@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+func TestLayerIdentities(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	digest := func(c string) *claircore.Digest {
+		d := claircore.MustParseDigest("sha256:" + strings.Repeat(c, 64))
+		return &d
+	}
+	// Layer "a" is fetched, and the rest were fetched by an earlier index.
+	// Only "b" and "c" have diffIDs recorded.
+	layers := func() []*claircore.Layer {
+		return []*claircore.Layer{
+			{Hash: *digest("a"), DiffID: digest("1")},
+			{Hash: *digest("b")},
+			{Hash: *digest("c")},
+			{Hash: *digest("d")},
+		}
+	}
+	store := func(t *testing.T) *indexer.MockStore {
+		m := indexer.NewMockStore(gomock.NewController(t))
+		m.EXPECT().SetLayerDiffID(gomock.Any(), *digest("a"), *digest("1")).Return(nil)
+		m.EXPECT().LayerDiffID(gomock.Any(), *digest("b")).Return(*digest("2"), true, nil).AnyTimes()
+		m.EXPECT().LayerDiffID(gomock.Any(), *digest("c")).Return(*digest("3"), true, nil).AnyTimes()
+		m.EXPECT().LayerDiffID(gomock.Any(), *digest("d")).Return(claircore.Digest{}, false, nil).AnyTimes()
+		return m
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := New(&indexer.Opts{Store: store(t)})
+		ls := layers()
+		ls[1].DiffID = digest("2")
+		// Nothing is recorded for "d", so its diffID can't be checked.
+		ls[3].DiffID = digest("4")
+		s.manifest.Layers = ls
+		if err := layerIdentities(ctx, s, ls[:1]); err != nil {
+			t.Fatal(err)
+		}
+		want := []claircore.LayerIdentity{
+			{Hash: *digest("a"), DiffID: digest("1")},
+			{Hash: *digest("b"), DiffID: digest("2")},
+			{Hash: *digest("c"), DiffID: digest("3")},
+			{Hash: *digest("d")},
+		}
+		opt := cmp.AllowUnexported(claircore.Digest{})
+		if got := s.report.Layers; !cmp.Equal(got, want, opt) {
+			t.Error(cmp.Diff(got, want, opt))
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := New(&indexer.Opts{Store: store(t)})
+		ls := layers()
+		ls[2].DiffID = digest("f")
+		s.manifest.Layers = ls
+		err := layerIdentities(ctx, s, ls[:1])
+		var me *claircore.DiffIDMismatchError
+		if !errors.As(err, &me) {
+			t.Fatalf("got error %v, want %T", err, me)
+		}
+		t.Log(err)
+		if got, want := me.Layer.String(), digest("c").String(); got != want {
+			t.Errorf("got layer %q, want %q", got, want)
+		}
+	})
+}
+
+// WarningFetcher is a Fetcher that reports a fixed set of warnings.
+type warningFetcher struct {
+	*indexer.MockFetcher
+	warnings []claircore.Warning
+}
+
+func (f *warningFetcher) Warnings() []claircore.Warning { return f.warnings }
+
+// TestFetchLayersWarnings checks that warnings reported by the Fetcher end up
+// in the report.
+func TestFetchLayersWarnings(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := &claircore.Layer{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))}
+	want := []claircore.Warning{{
+		Code:    claircore.WarningUnsupportedMediaType,
+		Subject: l.Hash.String(),
+		Message: "treated as gzip",
+	}}
+	scnr := indexer.NewMockPackageScanner(ctrl)
+	scnr.EXPECT().Name().Return("test").AnyTimes()
+	scnr.EXPECT().Version().Return("1").AnyTimes()
+	scnr.EXPECT().Kind().Return("package").AnyTimes()
+	store := indexer.NewMockStore(ctrl)
+	store.EXPECT().LayersScanned(gomock.Any(), gomock.Any(), gomock.Any()).Return([][]bool{{false}}, nil)
+	store.EXPECT().LayerDiffID(gomock.Any(), l.Hash).Return(claircore.Digest{}, false, nil)
+	f := &warningFetcher{MockFetcher: indexer.NewMockFetcher(ctrl), warnings: want}
+	f.EXPECT().Fetch(gomock.Any(), []*claircore.Layer{l}).Return(nil)
+
+	s := New(&indexer.Opts{
+		Store:   store,
+		Fetcher: f,
+		Vscnrs:  indexer.VersionedScanners{scnr},
+	})
+	s.manifest.Layers = []*claircore.Layer{l}
+	if _, err := fetchLayers(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.report.Warnings; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
 )
 
 // indexFinished is the terminal stateFunc. once it transitions the
@@ -16,6 +19,10 @@ func indexFinished(ctx context.Context, s *Controller) (State, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("state", s.getState().String()))
 	s.report.Success = true
+	s.report.LimitedSupport = limitedSupport(s.configured, s.report)
+	s.report.IndexerState = s.State
+	s.report.Scanners = s.configured.Describe()
+	s.report.InventoryOnly = s.InventoryOnly
 	zlog.Info(ctx).Msg("finishing scan")
 
 	err := s.Store.SetIndexFinished(ctx, s.report, s.Vscnrs)
@@ -26,3 +33,21 @@ func indexFinished(ctx context.Context, s *Controller) (State, error) {
 	zlog.Info(ctx).Msg("manifest successfully scanned")
 	return Terminal, nil
 }
+
+// limitedSupport returns why the report is only partially complete, or the
+// empty string if it's not, as reported by the configured scanners
+// implementing indexer.LimitedScanner.
+func limitedSupport(vs indexer.VersionedScanners, r *claircore.IndexReport) string {
+	for _, v := range vs {
+		ls, ok := v.(indexer.LimitedScanner)
+		if !ok {
+			continue
+		}
+		for _, d := range r.Distributions {
+			if why := ls.LimitedSupport(d); why != "" {
+				return why
+			}
+		}
+	}
+	return ""
+}
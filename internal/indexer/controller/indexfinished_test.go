@@ -5,9 +5,15 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	"github.com/quay/zlog"
 
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/dpkg"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/windows"
 )
 
 func TestIndexFinished(t *testing.T) {
@@ -53,3 +59,85 @@ func TestIndexFinished(t *testing.T) {
 		})
 	}
 }
+
+// TestIndexFinishedScanners checks that the finished report lists every
+// scanner the indexer was configured with, even if the manifest only needed
+// some of them, along with the indexer state.
+func TestIndexFinishedScanners(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// A customized ecosystem: dpkg packages, but only Debian distributions
+	// and an extra package scanner.
+	eco := &indexer.Ecosystem{
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&dpkg.Scanner{}, &alpine.Scanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) {
+			return []indexer.DistributionScanner{&debian.DistributionScanner{}}, nil
+		},
+		RepositoryScanners: func(context.Context) ([]indexer.RepositoryScanner, error) {
+			return nil, nil
+		},
+	}
+	ps, ds, rs, err := indexer.EcosystemsToScanners(ctx, []*indexer.Ecosystem{eco}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vscnrs := indexer.MergeVS(ps, ds, rs)
+
+	ctrl := gomock.NewController(t)
+	store := indexer.NewMockStore(ctrl)
+	store.EXPECT().SetIndexFinished(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	c := New(&indexer.Opts{
+		Store:  store,
+		Vscnrs: vscnrs,
+		State:  "test-state",
+	})
+	// As if the manifest had already been scanned by everything but dpkg.
+	c.Vscnrs = vscnrs[:1]
+
+	if _, err := indexFinished(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	want := []claircore.ScannerDescription{
+		{Name: (&debian.DistributionScanner{}).Name(), Version: (&debian.DistributionScanner{}).Version(), Kind: (&debian.DistributionScanner{}).Kind()},
+		{Name: (&alpine.Scanner{}).Name(), Version: (&alpine.Scanner{}).Version(), Kind: (&alpine.Scanner{}).Kind()},
+		{Name: (&dpkg.Scanner{}).Name(), Version: (&dpkg.Scanner{}).Version(), Kind: (&dpkg.Scanner{}).Kind()},
+	}
+	if got := c.report.Scanners; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := c.report.IndexerState, "test-state"; got != want {
+		t.Errorf("got state %q, want %q", got, want)
+	}
+}
+
+// TestIndexFinishedLimitedSupport checks that a report with a Windows
+// distribution finishes successfully, marked as having limited support.
+func TestIndexFinishedLimitedSupport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ctrl := gomock.NewController(t)
+	store := indexer.NewMockStore(ctrl)
+	store.EXPECT().SetIndexFinished(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	c := New(&indexer.Opts{
+		Store:  store,
+		Vscnrs: indexer.VersionedScanners{&debian.DistributionScanner{}, &windows.DistributionScanner{}},
+	})
+
+	if _, err := indexFinished(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.report.LimitedSupport; got != "" {
+		t.Errorf("unexpected limited support: %q", got)
+	}
+
+	c.report.Distributions["1"] = &claircore.Distribution{ID: "1", DID: "windows", Name: "Windows"}
+	if _, err := indexFinished(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if !c.report.Success {
+		t.Error("report not successful")
+	}
+	if c.report.LimitedSupport == "" {
+		t.Error("report not marked as limited support")
+	}
+}
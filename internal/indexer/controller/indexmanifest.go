@@ -22,5 +22,6 @@ func indexManifest(ctx context.Context, c *Controller) (State, error) {
 	if err != nil {
 		return Terminal, fmt.Errorf("indexing manifest contents failed: %v", err)
 	}
+	c.rows += int64(len(c.report.IndexRecords()))
 	return IndexFinished, nil
 }
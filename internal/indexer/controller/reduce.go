@@ -8,15 +8,21 @@ import (
 )
 
 // reduce determines which layers should be fetched/scanned and returns these layers
+//
+// The store is queried once for all the layers and scanners.
 func reduce(ctx context.Context, store indexer.Store, scnrs indexer.VersionedScanners, layers []*claircore.Layer) ([]*claircore.Layer, error) {
+	hashes := make([]claircore.Digest, len(layers))
+	for i, l := range layers {
+		hashes[i] = l.Hash
+	}
+	scanned, err := store.LayersScanned(ctx, hashes, scnrs)
+	if err != nil {
+		return nil, err
+	}
 	do := []*claircore.Layer{}
-	for _, l := range layers {
-		for _, scnr := range scnrs {
-			ok, err := store.LayerScanned(ctx, l.Hash, scnr)
-			if err != nil {
-				return nil, err
-			}
-			if !ok {
+	for i, l := range layers {
+		for j := range scnrs {
+			if !scanned[i][j] {
 				do = append(do, l)
 				break
 			}
@@ -12,7 +12,7 @@ import (
 func scanLayers(ctx context.Context, c *Controller) (State, error) {
 	zlog.Info(ctx).Msg("layers scan start")
 	defer zlog.Info(ctx).Msg("layers scan done")
-	err := c.LayerScanner.Scan(ctx, c.manifest.Hash, c.manifest.Layers)
+	err := c.LayerScanner.Scan(ctx, c.manifest.Hash, c.newLayers())
 	if err != nil {
 		return Terminal, fmt.Errorf("failed to scan all layer contents: %v", err)
 	}
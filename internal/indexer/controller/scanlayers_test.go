@@ -2,11 +2,13 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/quay/zlog"
 
+	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
 )
 
@@ -55,3 +57,43 @@ func TestScanLayers(t *testing.T) {
 		})
 	}
 }
+
+// TestScanLayersShared checks that layers shared with a previously indexed
+// manifest are neither fetched nor scanned.
+func TestScanLayersShared(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	layers := []*claircore.Layer{
+		{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))},
+		{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))},
+		{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))},
+	}
+	ls := indexer.NewMockLayerScanner(ctrl)
+	s := indexer.NewMockStore(ctrl)
+	f := indexer.NewMockFetcher(ctrl)
+	ls.EXPECT().Scan(gomock.Any(), gomock.Any(), layers[2:]).Return(nil)
+	s.EXPECT().LayersScanned(gomock.Any(), []claircore.Digest{layers[2].Hash}, gomock.Any()).
+		Return([][]bool{{false}}, nil)
+	s.EXPECT().LayerDiffID(gomock.Any(), gomock.Any()).AnyTimes().
+		Return(claircore.Digest{}, false, nil)
+	f.EXPECT().Fetch(gomock.Any(), layers[2:]).Return(nil)
+	c := New(&indexer.Opts{
+		LayerScanner: ls,
+		Store:        s,
+		Fetcher:      f,
+		Vscnrs:       indexer.VersionedScanners{indexer.NewMockPackageScanner(ctrl)},
+	})
+	c.manifest = &claircore.Manifest{Layers: layers}
+	c.shared = 2
+
+	if _, err := fetchLayers(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(c.report.Layers), len(layers); got != want {
+		t.Errorf("got %d layer identities, want %d", got, want)
+	}
+	if _, err := scanLayers(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,6 +2,7 @@ package indexer
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -91,3 +92,243 @@ func EcosystemsToScanners(ctx context.Context, ecosystems []*Ecosystem, disallow
 	}
 	return ps, ds, rs, nil
 }
+
+// InventoryEcosystems returns copies of the ecosystems that only report
+// inventory: packages and distributions. Repository scanners exist to support
+// vulnerability matching (and some resolve repositories with network calls),
+// so they're left out. Package scanners implementing InventoryScanner are
+// replaced with their inventory-only versions.
+func InventoryEcosystems(es []*Ecosystem) []*Ecosystem {
+	out := make([]*Ecosystem, len(es))
+	for i, e := range es {
+		e := e
+		c := *e
+		c.PackageScanners = func(ctx context.Context) ([]PackageScanner, error) {
+			ps, err := e.PackageScanners(ctx)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]PackageScanner, len(ps))
+			for i, s := range ps {
+				if s, ok := s.(InventoryScanner); ok {
+					out[i] = s.InventoryOnly()
+					continue
+				}
+				out[i] = s
+			}
+			return out, nil
+		}
+		c.RepositoryScanners = func(context.Context) ([]RepositoryScanner, error) { return nil, nil }
+		out[i] = &c
+	}
+	return out
+}
+
+// WithAdditionalScanners returns a copy of the Ecosystem that also uses the
+// provided scanners, appended after its own. Each scanner must be a
+// PackageScanner, DistributionScanner, or RepositoryScanner.
+//
+// The copy keeps the Ecosystem's Coalescer, so the artifacts found by the
+// added scanners are coalesced along with the rest of the Ecosystem's. The
+// copy's scanner functions report an error if a scanner isn't one of the
+// known kinds or if two of its scanners of the same kind share a name.
+func (e *Ecosystem) WithAdditionalScanners(ss ...VersionedScanner) *Ecosystem {
+	var ps []PackageScanner
+	var ds []DistributionScanner
+	var rs []RepositoryScanner
+	var err error
+	for _, s := range ss {
+		switch s := s.(type) {
+		case PackageScanner:
+			ps = append(ps, s)
+		case DistributionScanner:
+			ds = append(ds, s)
+		case RepositoryScanner:
+			rs = append(rs, s)
+		default:
+			if err == nil {
+				err = fmt.Errorf("indexer: scanner %q has unknown kind %q", s.Name(), s.Kind())
+			}
+		}
+	}
+	c := *e
+	c.PackageScanners = func(ctx context.Context) ([]PackageScanner, error) {
+		if err != nil {
+			return nil, err
+		}
+		out, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out[:len(out):len(out)], ps...)
+		var vs VersionedScanners
+		vs.PStoVS(out)
+		return out, checkUnique(e.Name, vs)
+	}
+	c.DistributionScanners = func(ctx context.Context) ([]DistributionScanner, error) {
+		if err != nil {
+			return nil, err
+		}
+		out, err := e.DistributionScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out[:len(out):len(out)], ds...)
+		var vs VersionedScanners
+		vs.DStoVS(out)
+		return out, checkUnique(e.Name, vs)
+	}
+	c.RepositoryScanners = func(ctx context.Context) ([]RepositoryScanner, error) {
+		if err != nil {
+			return nil, err
+		}
+		out, err := e.RepositoryScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out[:len(out):len(out)], rs...)
+		var vs VersionedScanners
+		vs.RStoVS(out)
+		return out, checkUnique(e.Name, vs)
+	}
+	return &c
+}
+
+// WithoutScanners returns a copy of the Ecosystem that doesn't use the named
+// scanners, of any kind. Names the Ecosystem doesn't use are ignored.
+//
+// The copy keeps the Ecosystem's Coalescer.
+func (e *Ecosystem) WithoutScanners(names ...string) *Ecosystem {
+	drop := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		drop[n] = struct{}{}
+	}
+	keep := func(s VersionedScanner) bool {
+		_, ok := drop[s.Name()]
+		return !ok
+	}
+	c := *e
+	c.PackageScanners = func(ctx context.Context) ([]PackageScanner, error) {
+		in, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var out []PackageScanner
+		for _, s := range in {
+			if keep(s) {
+				out = append(out, s)
+			}
+		}
+		return out, nil
+	}
+	c.DistributionScanners = func(ctx context.Context) ([]DistributionScanner, error) {
+		in, err := e.DistributionScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var out []DistributionScanner
+		for _, s := range in {
+			if keep(s) {
+				out = append(out, s)
+			}
+		}
+		return out, nil
+	}
+	c.RepositoryScanners = func(ctx context.Context) ([]RepositoryScanner, error) {
+		in, err := e.RepositoryScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var out []RepositoryScanner
+		for _, s := range in {
+			if keep(s) {
+				out = append(out, s)
+			}
+		}
+		return out, nil
+	}
+	return &c
+}
+
+// MergeEcosystems concatenates the sets of ecosystems, in order, for use as a
+// single configuration. An Ecosystem appearing more than once is only
+// included the first time.
+//
+// Scanners are deduplicated by name when the merged ecosystems are used, so
+// a scanner with the same kind and name as one in another Ecosystem would be
+// silently dropped unless it's the same scanner. MergeEcosystems reports an
+// error if two ecosystems have scanners of the same kind and name but
+// different versions, or if an Ecosystem has two scanners of the same kind
+// and name.
+func MergeEcosystems(ctx context.Context, sets ...[]*Ecosystem) ([]*Ecosystem, error) {
+	var out []*Ecosystem
+	seen := make(map[*Ecosystem]struct{})
+	// Kind and name to the version first seen.
+	versions := make(map[[2]string]string)
+	for _, set := range sets {
+		for _, e := range set {
+			if _, ok := seen[e]; ok {
+				continue
+			}
+			seen[e] = struct{}{}
+			vs, err := ecosystemScanners(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkUnique(e.Name, vs); err != nil {
+				return nil, err
+			}
+			for _, s := range vs {
+				k := [2]string{s.Kind(), s.Name()}
+				v, ok := versions[k]
+				switch {
+				case !ok:
+					versions[k] = s.Version()
+				case v != s.Version():
+					return nil, fmt.Errorf("indexer: ecosystem %q: %s scanner %q version %q conflicts with version %q in another ecosystem",
+						e.Name, s.Kind(), s.Name(), s.Version(), v)
+				}
+			}
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// EcosystemScanners returns all of the Ecosystem's scanners.
+func ecosystemScanners(ctx context.Context, e *Ecosystem) (VersionedScanners, error) {
+	ps, err := e.PackageScanners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := e.DistributionScanners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := e.RepositoryScanners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var vs, tmp VersionedScanners
+	tmp.PStoVS(ps)
+	vs = append(vs, tmp...)
+	tmp.DStoVS(ds)
+	vs = append(vs, tmp...)
+	tmp.RStoVS(rs)
+	vs = append(vs, tmp...)
+	return vs, nil
+}
+
+// CheckUnique reports an error if two of the scanners have the same kind and
+// name.
+func checkUnique(ecosystem string, vs VersionedScanners) error {
+	seen := make(map[[2]string]struct{}, len(vs))
+	for _, s := range vs {
+		k := [2]string{s.Kind(), s.Name()}
+		if _, ok := seen[k]; ok {
+			return fmt.Errorf("indexer: ecosystem %q has duplicate %s scanner %q", ecosystem, s.Kind(), s.Name())
+		}
+		seen[k] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+type fakeScanner struct {
+	name, version, kind string
+}
+
+func (s *fakeScanner) Name() string    { return s.name }
+func (s *fakeScanner) Version() string { return s.version }
+func (s *fakeScanner) Kind() string    { return s.kind }
+
+type fakePackageScanner struct{ fakeScanner }
+
+func (*fakePackageScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error) {
+	return nil, nil
+}
+
+type fakeDistributionScanner struct{ fakeScanner }
+
+func (*fakeDistributionScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Distribution, error) {
+	return nil, nil
+}
+
+func newPS(name, version string) *fakePackageScanner {
+	return &fakePackageScanner{fakeScanner{name, version, "package"}}
+}
+
+func newDS(name, version string) *fakeDistributionScanner {
+	return &fakeDistributionScanner{fakeScanner{name, version, "distribution"}}
+}
+
+func newTestEcosystem(name string, ss ...VersionedScanner) *Ecosystem {
+	e := &Ecosystem{
+		Name:                 name,
+		PackageScanners:      func(context.Context) ([]PackageScanner, error) { return nil, nil },
+		DistributionScanners: func(context.Context) ([]DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]RepositoryScanner, error) { return nil, nil },
+		Coalescer:            func(context.Context) (Coalescer, error) { return nil, nil },
+	}
+	return e.WithAdditionalScanners(ss...)
+}
+
+func scannerNames(t *testing.T, e *Ecosystem) []string {
+	t.Helper()
+	vs, err := ecosystemScanners(context.Background(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, s := range vs {
+		out = append(out, s.Kind()+"/"+s.Name())
+	}
+	return out
+}
+
+func TestEcosystemComposition(t *testing.T) {
+	ctx := context.Background()
+	base := newTestEcosystem("base", newPS("a", "1"), newDS("b", "1"))
+
+	t.Run("WithAdditionalScanners", func(t *testing.T) {
+		e := base.WithAdditionalScanners(newPS("c", "1"))
+		want := []string{"package/a", "package/c", "distribution/b"}
+		if got := scannerNames(t, e); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		// The original is untouched.
+		want = []string{"package/a", "distribution/b"}
+		if got := scannerNames(t, base); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("WithAdditionalScannersDuplicate", func(t *testing.T) {
+		e := base.WithAdditionalScanners(newPS("a", "2"))
+		_, err := e.PackageScanners(ctx)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error")
+		}
+		// The same name is fine for a different kind.
+		e = base.WithAdditionalScanners(newDS("a", "1"))
+		if _, err := e.DistributionScanners(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("WithAdditionalScannersKind", func(t *testing.T) {
+		e := base.WithAdditionalScanners(&fakeScanner{"d", "1", "mystery"})
+		_, err := e.PackageScanners(ctx)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("WithoutScanners", func(t *testing.T) {
+		e := base.WithAdditionalScanners(newPS("c", "1")).WithoutScanners("a", "b", "nonexistent")
+		want := []string{"package/c"}
+		if got := scannerNames(t, e); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Coalescer", func(t *testing.T) {
+		called := false
+		b := *base
+		b.Coalescer = func(context.Context) (Coalescer, error) {
+			called = true
+			return nil, nil
+		}
+		e := b.WithAdditionalScanners(newPS("c", "1")).WithoutScanners("a")
+		if _, err := e.Coalescer(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !called {
+			t.Error("composed ecosystem doesn't use the original coalescer")
+		}
+	})
+	t.Run("MergeEcosystems", func(t *testing.T) {
+		other := newTestEcosystem("other", newPS("a", "1"), newPS("e", "1"))
+		es, err := MergeEcosystems(ctx, []*Ecosystem{base, other}, []*Ecosystem{base})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(es), 2; got != want {
+			t.Errorf("got %d ecosystems, want %d", got, want)
+		}
+		conflict := newTestEcosystem("conflict", newPS("a", "2"))
+		_, err = MergeEcosystems(ctx, []*Ecosystem{base}, []*Ecosystem{conflict})
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package indexer
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/quay/claircore"
 )
@@ -21,7 +22,85 @@ const (
 // Fetcher is responsible for downloading a layer, uncompressing
 // if necessary, and making the uncompressed tar contents available for
 // reading.
+//
+// A Fetcher may also implement WarningReporter, to have anomalies it worked
+// around included in the IndexReport, and CostReporter, to have what it
+// downloaded included in the IndexReport's cost summary.
 type Fetcher interface {
 	Fetch(ctx context.Context, layers []*claircore.Layer) error
 	Close() error
 }
+
+// Authorizer supplies credentials for the requests a Fetcher makes for layers
+// whose Headers don't carry any.
+//
+// Authorizers are only consulted for requests to the host in a Layer's URI,
+// not for the locations it redirects to.
+type Authorizer interface {
+	// Authorize adds credentials to the request, if any are known for it.
+	Authorize(ctx context.Context, req *http.Request) error
+	// Refresh is called with a response refusing a request for lack of
+	// credentials. It reports whether credentials for the request were
+	// acquired and the request should be made again.
+	Refresh(ctx context.Context, resp *http.Response) (bool, error)
+}
+
+// WarningReporter is implemented by components that record anomalies that
+// didn't cause an operation to fail.
+type WarningReporter interface {
+	// Warnings returns the warnings recorded so far.
+	Warnings() []claircore.Warning
+}
+
+// CostReporter is implemented by components that account for the resources
+// they use, to have them included in the IndexReport's cost summary.
+//
+// Implementations should use cheap counters, as accounting happens on every
+// index operation.
+type CostReporter interface {
+	// AddCost adds the resources used since the component was created to
+	// "c".
+	AddCost(c *claircore.IndexCost)
+}
+
+// LayerLimits bounds the resources a single layer may consume when it's
+// fetched and decompressed. Any zero-valued member is replaced with its
+// default.
+//
+// A layer exceeding any limit fails the fetch with an error wrapping
+// claircore.ErrLayerTooLarge.
+type LayerLimits struct {
+	// MaxSize is the maximum number of decompressed bytes in a layer.
+	MaxSize int64
+	// MaxRatio is the maximum ratio of decompressed to compressed bytes.
+	MaxRatio int64
+	// MaxEntries is the maximum number of entries in a layer's tar archive.
+	MaxEntries int
+	// MaxFileSize is the maximum size of any single file in a layer. Scanners
+	// may read files of up to this size into memory.
+	MaxFileSize int64
+}
+
+// These are the default LayerLimits.
+const (
+	DefaultLayerMaxSize     = 32 << 30 // 32 GiB
+	DefaultLayerMaxRatio    = 256
+	DefaultLayerMaxEntries  = 4 << 20
+	DefaultLayerMaxFileSize = 4 << 30 // 4 GiB
+)
+
+// Defaults fills in any unset members with their default values.
+func (l *LayerLimits) Defaults() {
+	if l.MaxSize == 0 {
+		l.MaxSize = DefaultLayerMaxSize
+	}
+	if l.MaxRatio == 0 {
+		l.MaxRatio = DefaultLayerMaxRatio
+	}
+	if l.MaxEntries == 0 {
+		l.MaxEntries = DefaultLayerMaxEntries
+	}
+	if l.MaxFileSize == 0 {
+		l.MaxFileSize = DefaultLayerMaxFileSize
+	}
+}
@@ -0,0 +1,272 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+const (
+	// OpPrefix is the prefix of every operation directory in an Arena's
+	// root. Nothing else in the root is touched.
+	opPrefix = "fetch."
+	// LeaseName is the name of the lease file in an operation directory.
+	leaseName = ".lease"
+)
+
+// Arena is a scratch directory shared by the fetchers for a series of
+// operations.
+//
+// Each fetcher made by an Arena writes into its own directory in the Arena's
+// root, along with a lease file naming the owning process. The Arena keeps
+// the leases of its live fetchers fresh, and periodically removes the
+// directories of operations that are no longer running: ones left behind by
+// a crashed process, for example. Multiple processes, on one or more hosts,
+// may share a root; a directory is only removed once its lease has lapsed
+// or its owner on this host has exited.
+type Arena struct {
+	root   string
+	maxAge time.Duration
+	pid    int
+	host   string
+
+	mu   sync.Mutex
+	live map[string]struct{}
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Lease is the contents of a lease file.
+type lease struct {
+	PID  int       `json:"pid"`
+	Host string    `json:"host"`
+	Time time.Time `json:"time"`
+}
+
+// NewArena creates the root directory if needed, removes any stale operation
+// directories in it, and starts reconciling the directory every quarter of
+// "maxAge" until Close is called.
+//
+// Operation directories without a live lease are removed once they're older
+// than "maxAge".
+func NewArena(ctx context.Context, root string, maxAge time.Duration) (*Arena, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/fetcher/NewArena"),
+		label.String("root", root))
+	if maxAge <= 0 {
+		return nil, fmt.Errorf("fetcher: invalid max age: %v", maxAge)
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, fmt.Errorf("fetcher: unable to create scratch directory: %w", err)
+	}
+	a := &Arena{
+		root:   root,
+		maxAge: maxAge,
+		pid:    os.Getpid(),
+		live:   make(map[string]struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	// An empty host name only means leases from other hosts can't be told
+	// apart, so they're trusted until they lapse.
+	a.host, _ = os.Hostname()
+	if err := a.Reap(ctx); err != nil {
+		return nil, err
+	}
+	go a.loop(ctx)
+	return a, nil
+}
+
+// Loop refreshes the live leases and reaps the root until Close is called.
+func (a *Arena) loop(ctx context.Context) {
+	defer close(a.done)
+	t := time.NewTicker(a.maxAge / 4)
+	defer t.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-t.C:
+		}
+		a.refresh(ctx)
+		if err := a.Reap(ctx); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("failed to reap scratch directory")
+		}
+	}
+}
+
+// Close stops the Arena's reconciliation and removes the directories of
+// fetchers that haven't been closed. Those fetchers fail to write any more
+// layers, but may still be closed. It's safe to call Close multiple times.
+func (a *Arena) Close() error {
+	a.stopOnce.Do(func() { close(a.stop) })
+	<-a.done
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []string
+	for dir := range a.live {
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err.Error())
+		}
+		delete(a.live, dir)
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("fetcher: unable to remove operation directories: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Fetcher returns an indexer.Fetcher for one operation, like New, that
+// writes into a new leased directory in the Arena. The directory is removed
+// when the fetcher is closed.
+func (a *Arena) Fetcher(client *http.Client, opt indexer.LayerFetchOpt, lim *indexer.LayerLimits, auth indexer.Authorizer, red *claircore.Redaction) (*fetcher, error) {
+	dir, err := ioutil.TempDir(a.root, opPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("fetcher: unable to create operation directory: %w", err)
+	}
+	if err := a.writeLease(dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	a.mu.Lock()
+	a.live[dir] = struct{}{}
+	a.mu.Unlock()
+	f := New(client, opt, lim, auth, red)
+	f.dir = dir
+	f.arena = a
+	return f, nil
+}
+
+// Release removes the directory of a closed fetcher.
+func (a *Arena) release(dir string) error {
+	a.mu.Lock()
+	delete(a.live, dir)
+	a.mu.Unlock()
+	return os.RemoveAll(dir)
+}
+
+// WriteLease replaces the lease in "dir" with a current one.
+func (a *Arena) writeLease(dir string) error {
+	b, err := json.Marshal(&lease{
+		PID:  a.pid,
+		Host: a.host,
+		Time: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	// Write and rename, so a reaper never sees a partial lease.
+	tmp := filepath.Join(dir, leaseName+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("fetcher: unable to write lease: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, leaseName)); err != nil {
+		return fmt.Errorf("fetcher: unable to write lease: %w", err)
+	}
+	return nil
+}
+
+// Refresh renews the leases of the live fetchers.
+func (a *Arena) refresh(ctx context.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for dir := range a.live {
+		if err := a.writeLease(dir); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("dir", dir).
+				Msg("failed to refresh lease")
+		}
+	}
+}
+
+// Reap removes the stale operation directories in the Arena's root.
+//
+// A directory is stale if it's older than the Arena's max age and its lease
+// is missing, unreadable, or lapsed, or if its lease names a process on this
+// host that has exited. Directories of this Arena's live fetchers are never
+// stale.
+func (a *Arena) Reap(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/fetcher/Arena.Reap"))
+	ents, err := ioutil.ReadDir(a.root)
+	if err != nil {
+		return fmt.Errorf("fetcher: unable to read scratch directory: %w", err)
+	}
+	now := time.Now()
+	var errs []string
+	for _, ent := range ents {
+		if !ent.IsDir() || !strings.HasPrefix(ent.Name(), opPrefix) {
+			continue
+		}
+		dir := filepath.Join(a.root, ent.Name())
+		a.mu.Lock()
+		_, live := a.live[dir]
+		a.mu.Unlock()
+		if live || !a.stale(dir, ent.ModTime(), now) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		zlog.Info(ctx).
+			Str("dir", dir).
+			Msg("removed stale operation directory")
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("fetcher: unable to remove stale directories: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Stale reports whether the operation directory "dir", last modified at
+// "mod", can be removed.
+func (a *Arena) stale(dir string, mod, now time.Time) bool {
+	b, err := ioutil.ReadFile(filepath.Join(dir, leaseName))
+	var l lease
+	if err != nil || json.Unmarshal(b, &l) != nil {
+		// The directory may be brand new, with the lease not yet written.
+		return now.Sub(mod) > a.maxAge
+	}
+	if a.host != "" && l.Host == a.host && l.PID != a.pid && !processAlive(l.PID) {
+		return true
+	}
+	return now.Sub(l.Time) > a.maxAge
+}
+
+// ProcessAlive reports whether the process "pid" on this host may be
+// running.
+func processAlive(pid int) bool {
+	if runtime.GOOS == "windows" {
+		// There's no portable check, so trust the lease's time.
+		return true
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
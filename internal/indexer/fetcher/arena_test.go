@@ -0,0 +1,185 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/test"
+)
+
+// TestArena simulates a crash by seeding a scratch directory with the
+// leftovers of dead operations, and checks that only those are removed.
+func TestArena(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root, err := ioutil.TempDir("", "arena.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	const maxAge = time.Hour
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Run a process to completion to get a PID that's no longer in use.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	deadPID := cmd.Process.Pid
+	old := time.Now().Add(-2 * maxAge)
+
+	seed := func(t *testing.T, name string, l *lease, mod time.Time) {
+		t.Helper()
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "sha256:00"), []byte("layer"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if l != nil {
+			b, err := json.Marshal(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, leaseName), b, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Chtimes(dir, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type entry struct {
+		name  string
+		lease *lease
+		mod   time.Time
+		keep  bool
+	}
+	entries := []entry{
+		{name: "fetch.nolease", mod: old},
+		{name: "fetch.lapsed", lease: &lease{PID: os.Getpid(), Host: "elsewhere", Time: old}, mod: old},
+		{name: "fetch.dead", lease: &lease{PID: deadPID, Host: host, Time: time.Now()}, mod: old},
+		{name: "fetch.new", mod: time.Now(), keep: true},
+		{name: "fetch.remote", lease: &lease{PID: deadPID, Host: "elsewhere", Time: time.Now()}, mod: old, keep: true},
+		// Not an operation directory, so never touched.
+		{name: "unrelated", mod: old, keep: true},
+	}
+	for _, e := range entries {
+		seed(t, e.name, e.lease, e.mod)
+	}
+
+	a, err := NewArena(ctx, root, maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := a.Fetcher(&testClient, "", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make the live operation's directory look old, and its lease lapsed, so
+	// only its registration keeps it around.
+	seedLease := &lease{PID: os.Getpid(), Host: host, Time: old}
+	b, err := json.Marshal(seedLease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(f.dir, leaseName), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(f.dir, old, old); err != nil {
+		t.Fatal(err)
+	}
+	layers := test.ServeLayers(ctx, t, 1)
+	if err := f.Fetch(ctx, layers); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reap(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		_, err := os.Stat(filepath.Join(root, e.name))
+		switch {
+		case e.keep && err != nil:
+			t.Errorf("%s: removed: %v", e.name, err)
+		case !e.keep && !os.IsNotExist(err):
+			t.Errorf("%s: not removed: %v", e.name, err)
+		}
+	}
+	if _, err := os.Stat(f.filename(layers[0])); err != nil {
+		t.Errorf("live layer removed: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(f.dir); !os.IsNotExist(err) {
+		t.Errorf("closed fetcher's directory not removed: %v", err)
+	}
+
+	// Closing the Arena removes the directories of fetchers left open.
+	open, err := a.Fetcher(&testClient, "", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(open.dir); !os.IsNotExist(err) {
+		t.Errorf("open fetcher's directory not removed: %v", err)
+	}
+	if err := open.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestArenaShared checks that a second instance sharing the scratch
+// directory doesn't remove a live operation's directory.
+func TestArenaShared(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root, err := ioutil.TempDir("", "arena.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	const maxAge = time.Hour
+
+	a, err := NewArena(ctx, root, maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	f, err := a.Fetcher(&testClient, "", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	old := time.Now().Add(-2 * maxAge)
+	if err := os.Chtimes(f.dir, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// The other instance has a different PID, as a separate process would.
+	b, err := NewArena(ctx, root, maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.pid++
+	if err := b.Reap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(f.dir); err != nil {
+		t.Errorf("live directory removed: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package fetcher
 
 import (
 	"bytes"
+	"strings"
 )
 
 type compression int
@@ -10,8 +11,21 @@ const (
 	cmpGzip compression = iota
 	cmpZstd
 	cmpNone
+	cmpUnknown
 )
 
+func (c compression) String() string {
+	switch c {
+	case cmpGzip:
+		return "gzip"
+	case cmpZstd:
+		return "zstd"
+	case cmpNone:
+		return "tar"
+	}
+	return "unknown"
+}
+
 var (
 	cmpHeaders = [...][]byte{
 		[]byte{0x1F, 0x8B, 0x08},
@@ -30,3 +44,45 @@ func detectCompression(b []byte) compression {
 	}
 	return cmpNone
 }
+
+// The "ustar" magic in a tar header, present in both POSIX and GNU archives.
+const (
+	tarMagicStart = 257
+	tarMagicEnd   = tarMagicStart + 5
+)
+
+// DetectFormat is like detectCompression, but only reports cmpNone if "b" is
+// the start of a tar archive, and cmpUnknown otherwise.
+func detectFormat(b []byte) compression {
+	if c := detectCompression(b); c != cmpNone {
+		return c
+	}
+	if len(b) >= tarMagicEnd && string(b[tarMagicStart:tarMagicEnd]) == "ustar" {
+		return cmpNone
+	}
+	return cmpUnknown
+}
+
+// MediaTypeCompression reports the compression of a layer served with the
+// media type "ct", and whether the media type is a supported layer type.
+func mediaTypeCompression(ct string) (compression, bool) {
+	switch {
+	case ct == "application/gzip" ||
+		ct == "application/vnd.docker.image.rootfs.diff.tar.gzip" ||
+		ct == "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip":
+		// Catch the old docker media types. Foreign layers are used for
+		// Windows base layers.
+		fallthrough
+	case strings.HasSuffix(ct, ".tar+gzip"):
+		return cmpGzip, true
+	case ct == "application/zstd":
+		fallthrough
+	case strings.HasSuffix(ct, ".tar+zstd"):
+		return cmpZstd, true
+	case ct == "application/x-tar":
+		fallthrough
+	case strings.HasSuffix(ct, ".tar"):
+		return cmpNone, true
+	}
+	return cmpUnknown, false
+}
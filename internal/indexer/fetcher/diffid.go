@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/quay/claircore"
+)
+
+// DiffIDHash returns a hash for computing the DiffID of a layer, using the
+// algorithm of the layer's DiffID if it has one.
+func diffIDHash(l *claircore.Layer) hash.Hash {
+	if l.DiffID != nil {
+		return l.DiffID.Hash()
+	}
+	return sha256.New()
+}
+
+// CheckDiffID compares the sum of "h", which should have been returned by
+// diffIDHash and fed the uncompressed layer, to the layer's DiffID. If the
+// layer has no DiffID, it's set instead.
+func checkDiffID(l *claircore.Layer, h hash.Hash) error {
+	algo := "sha256"
+	if l.DiffID != nil {
+		algo = l.DiffID.Algorithm()
+	}
+	got, err := claircore.NewDigest(algo, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	switch {
+	case l.DiffID == nil:
+		l.DiffID = &got
+	case l.DiffID.String() != got.String():
+		return &claircore.DiffIDMismatchError{Layer: l.Hash, Want: *l.DiffID, Got: got}
+	}
+	return nil
+}
+
+// LocalDiffID computes or checks the DiffID of a layer that's already been
+// fetched.
+func localDiffID(l *claircore.Layer) error {
+	rc, err := l.Reader()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	h := diffIDHash(l)
+	if _, err := io.Copy(h, rc); err != nil {
+		return fmt.Errorf("fetcher: unable to read layer %v: %w", l.Hash, err)
+	}
+	return checkDiffID(l, h)
+}
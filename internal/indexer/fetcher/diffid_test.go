@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+func TestDiffID(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	blob := bomb(t, 4, 1024)
+	gz, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarball, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(tarball)
+	diffID, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := claircore.MustParseDigest("sha256:" + strings.Repeat("f", 64))
+
+	t.Run("Computed", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		l := serveBlob(t, blob)
+		f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+		defer f.Close()
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+			t.Fatal(err)
+		}
+		if l.DiffID == nil {
+			t.Fatal("no diffID computed")
+		}
+		if got, want := l.DiffID.String(), diffID.String(); got != want {
+			t.Errorf("got diffID %q, want %q", got, want)
+		}
+	})
+	t.Run("Match", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		l := serveBlob(t, blob)
+		want := diffID
+		l.DiffID = &want
+		f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+		defer f.Close()
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		l := serveBlob(t, blob)
+		want := other
+		l.DiffID = &want
+		f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+		defer f.Close()
+		err := f.Fetch(ctx, []*claircore.Layer{l})
+		var me *claircore.DiffIDMismatchError
+		if !errors.As(err, &me) {
+			t.Fatalf("got error %v, want %T", err, me)
+		}
+		t.Log(err)
+		if got, want := me.Got.String(), diffID.String(); got != want {
+			t.Errorf("got diffID %q, want %q", got, want)
+		}
+		if got, want := me.Want.String(), other.String(); got != want {
+			t.Errorf("got expected diffID %q, want %q", got, want)
+		}
+	})
+	t.Run("Local", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		tf, err := ioutil.TempFile("", "diffid.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tf.Close()
+		f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+		defer f.Close()
+		f.cleanup(tf.Name())
+		if _, err := tf.Write(tarball); err != nil {
+			t.Fatal(err)
+		}
+		want := other
+		l := &claircore.Layer{Hash: diffID, DiffID: &want}
+		if err := l.SetLocal(tf.Name()); err != nil {
+			t.Fatal(err)
+		}
+		var me *claircore.DiffIDMismatchError
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); !errors.As(err, &me) {
+			t.Fatalf("got error %v, want %T", err, me)
+		}
+	})
+}
@@ -13,8 +13,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zstd"
@@ -29,20 +29,53 @@ import (
 
 // Fetcher is a private struct which implements indexer.Fetcher.
 type fetcher struct {
+	// Fetched and written count the bytes downloaded and written to dir by
+	// fetches. They're accessed atomically, so they're first to keep them
+	// aligned.
+	fetched int64
+	written int64
+
 	wc      *http.Client
+	auth    indexer.Authorizer
+	limits  indexer.LayerLimits
+	redact  *claircore.Redaction
 	cleanMu sync.Mutex
 	clean   []string
+	// Warnings are the anomalies recorded by fetches.
+	warnMu   sync.Mutex
+	warnings []claircore.Warning
+	// Dir is where layers are written, and arena is the Arena that owns it,
+	// if any.
+	dir   string
+	arena *Arena
 }
 
 // New creates a new indexer.Fetcher which downloads layers to temporary files.
 //
-// Fetcher is safe to share concurrently.
+// Fetcher is safe to share concurrently. Fetchers created with New write into
+// the system's temporary directory; use an Arena to have abandoned files
+// cleaned up.
 //
-// The provided LayerFetchOpt is currently ignored.
-func New(client *http.Client, _ indexer.LayerFetchOpt) *fetcher {
-	return &fetcher{
-		wc: client,
+// The provided LayerFetchOpt is currently ignored. If the provided
+// LayerLimits is nil, the defaults are used. The provided client's redirect
+// policy is replaced, as the fetcher follows redirects itself. If the
+// provided Authorizer is nil, requests only carry the credentials in each
+// Layer's Headers. If the provided Redaction is not nil, layer URIs are
+// redacted with it in logs.
+func New(client *http.Client, _ indexer.LayerFetchOpt, lim *indexer.LayerLimits, auth indexer.Authorizer, red *claircore.Redaction) *fetcher {
+	wc := *client
+	wc.CheckRedirect = noRedirect
+	f := &fetcher{
+		wc:     &wc,
+		auth:   auth,
+		redact: red,
+		dir:    os.TempDir(),
+	}
+	if lim != nil {
+		f.limits = *lim
 	}
+	f.limits.Defaults()
+	return f
 }
 
 // Fetch retrieves a layer from the provided claircore.Layer.URI field,
@@ -60,14 +93,13 @@ func (f *fetcher) Fetch(ctx context.Context, layers []*claircore.Layer) error {
 	}
 	// wait for any concurrent fetches to finish
 	if err := g.Wait(); err != nil {
-		return fmt.Errorf("encountered error while fetching a layer: %v", err)
+		return fmt.Errorf("encountered error while fetching a layer: %w", err)
 	}
 	return nil
 }
 
 func (f *fetcher) filename(l *claircore.Layer) string {
-	// TODO(hank) Make this configurable directly, instead of only via TMPDIR.
-	return filepath.Join(os.TempDir(), l.Hash.String())
+	return filepath.Join(f.dir, l.Hash.String())
 }
 
 // fetch is designed to be ran as a go routine. performs the logic for for
@@ -76,12 +108,12 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/indexer/fetcher/fetcher.fetch"),
 		label.String("layer", layer.Hash.String()),
-		label.String("uri", layer.URI))
+		label.String("uri", f.redact.URI(layer.URI)))
 	zlog.Debug(ctx).Msg("layer fetch start")
 	// It is valid and don't perform a fetch.
 	if layer.Fetched() {
 		zlog.Debug(ctx).Msg("layer fetch skipped: exists")
-		return nil
+		return localDiffID(layer)
 	}
 
 	// if no RemotePath was provided return error
@@ -123,17 +155,9 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 	defer fd.Close()
 	f.cleanup(fd.Name())
 
-	req := &http.Request{
-		ProtoMajor: 1,
-		ProtoMinor: 1,
-		Method:     http.MethodGet,
-		URL:        url,
-		Header:     layer.Headers,
-	}
-	req = req.WithContext(ctx)
-	resp, err := f.wc.Do(req)
+	resp, err := f.open(ctx, layer, url)
 	if err != nil {
-		return fmt.Errorf("fetcher: request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 	switch resp.StatusCode {
@@ -149,7 +173,9 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 		}
 		return fmt.Errorf("fetcher: unexpected status code: %s", resp.Status)
 	}
-	tr := io.TeeReader(resp.Body, vh)
+	cr := &countingReader{r: resp.Body}
+	defer func() { atomic.AddInt64(&f.fetched, cr.n) }()
+	tr := io.TeeReader(cr, vh)
 
 	br := bufio.NewReader(tr)
 	// Look at the content-type and optionally fix it up.
@@ -157,7 +183,9 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 	zlog.Debug(ctx).
 		Str("content-type", ct).
 		Msg("reported content-type")
+	c, ok := mediaTypeCompression(ct)
 	switch {
+	case ok:
 	case ct == "" ||
 		ct == "text/plain" ||
 		ct == "binary/octet-stream" ||
@@ -169,55 +197,62 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 		if err != nil {
 			return err
 		}
-		switch detectCompression(b) {
-		case cmpGzip:
-			ct = "application/gzip"
-		case cmpZstd:
-			ct = "application/zstd"
-		case cmpNone:
-			ct = "application/x-tar"
-		}
+		c = detectCompression(b)
 		zlog.Debug(ctx).
-			Str("format", ct).
+			Stringer("format", c).
 			Msg("guessed compression")
+	default:
+		// Registries occasionally serve layers with media types nobody
+		// expects. If the contents are recognizable, use them and say so in
+		// the report instead of failing the whole index.
+		b, err := br.Peek(tarMagicEnd)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		c = detectFormat(b)
+		if c == cmpUnknown {
+			return fmt.Errorf("fetcher: unknown content-type %q", ct)
+		}
+		msg := fmt.Sprintf("layer served with unsupported media type %q, treated as %v based on its contents", ct, c)
+		zlog.Warn(ctx).
+			Str("content-type", ct).
+			Stringer("format", c).
+			Msg("unsupported media type")
+		f.warn(claircore.Warning{
+			Code:    claircore.WarningUnsupportedMediaType,
+			Subject: layer.Hash.String(),
+			Message: msg,
+		})
 	}
 
 	var r io.Reader
-	switch {
-	case ct == "application/gzip" ||
-		ct == "application/vnd.docker.image.rootfs.diff.tar.gzip":
-		// Catch the old docker media type.
-		fallthrough
-	case strings.HasSuffix(ct, ".tar+gzip"):
+	switch c {
+	case cmpGzip:
 		g, err := gzip.NewReader(br)
 		if err != nil {
 			return err
 		}
 		defer g.Close()
 		r = g
-	case ct == "application/zstd":
-		fallthrough
-	case strings.HasSuffix(ct, ".tar+zstd"):
+	case cmpZstd:
 		s, err := zstd.NewReader(br)
 		if err != nil {
 			return err
 		}
 		defer s.Close()
 		r = s
-	case ct == "application/x-tar":
-		fallthrough
-	case strings.HasSuffix(ct, ".tar"):
+	case cmpNone:
 		r = br
-	default:
-		return fmt.Errorf("fetcher: unknown content-type %q", ct)
 	}
 
 	buf := bufio.NewWriter(fd)
 	defer buf.Flush()
-	n, err := io.Copy(buf, r)
+	dh := diffIDHash(layer)
+	n, err := copyLayer(io.MultiWriter(buf, dh), r, cr, &f.limits)
+	atomic.AddInt64(&f.written, n)
 	zlog.Debug(ctx).Int64("size", n).Msg("wrote file")
 	if err != nil {
-		return err
+		return fmt.Errorf("fetcher: layer %v: %w", layer.Hash, err)
 	}
 	if got := vh.Sum(nil); !bytes.Equal(got, want) {
 		err := fmt.Errorf("fetcher: validation failed: got %q, expected %q",
@@ -225,6 +260,9 @@ func (f *fetcher) fetch(ctx context.Context, layer *claircore.Layer) error {
 			hex.EncodeToString(want))
 		return err
 	}
+	if err := checkDiffID(layer, dh); err != nil {
+		return fmt.Errorf("fetcher: %w", err)
+	}
 
 	zlog.Debug(ctx).Msg("layer fetch ok")
 	return nil
@@ -235,13 +273,42 @@ func (f *fetcher) Close() (err error) {
 	f.cleanMu.Lock()
 	defer f.cleanMu.Unlock()
 	for _, n := range f.clean {
-		if e := os.Remove(n); e != nil {
+		// The Arena may have removed the directory already.
+		if e := os.Remove(n); e != nil && !errors.Is(e, os.ErrNotExist) {
+			err = e
+		}
+	}
+	f.clean = nil
+	if f.arena != nil {
+		if e := f.arena.release(f.dir); e != nil {
 			err = e
 		}
 	}
 	return err
 }
 
+// Warnings implements indexer.WarningReporter. The warnings are those recorded
+// since the fetcher was created.
+func (f *fetcher) Warnings() []claircore.Warning {
+	f.warnMu.Lock()
+	defer f.warnMu.Unlock()
+	return append([]claircore.Warning(nil), f.warnings...)
+}
+
+// AddCost implements indexer.CostReporter. The bytes counted are those
+// downloaded and written since the fetcher was created; layers that were
+// already fetched count for nothing.
+func (f *fetcher) AddCost(c *claircore.IndexCost) {
+	c.FetchedBytes += atomic.LoadInt64(&f.fetched)
+	c.ScratchBytes += atomic.LoadInt64(&f.written)
+}
+
+func (f *fetcher) warn(w claircore.Warning) {
+	f.warnMu.Lock()
+	defer f.warnMu.Unlock()
+	f.warnings = append(f.warnings, w)
+}
+
 func (f *fetcher) cleanup(name string) {
 	f.cleanMu.Lock()
 	defer f.cleanMu.Unlock()
@@ -1,6 +1,8 @@
 package fetcher
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"net/http"
 	"os"
@@ -43,7 +45,7 @@ func (tc testcase) Run(ctx context.Context) func(*testing.T) {
 			t.Logf("%+v", l)
 		}
 
-		fetcher := New(&testClient, indexer.LayerFetchOpt(""))
+		fetcher := New(&testClient, indexer.LayerFetchOpt(""), nil, nil, nil)
 		if err := fetcher.Fetch(ctx, layers); err != nil {
 			t.Error(err)
 		}
@@ -98,10 +100,128 @@ func TestInvalid(t *testing.T) {
 	for _, table := range tt {
 		t.Run(table.name, func(t *testing.T) {
 			ctx := zlog.Test(ctx, t)
-			fetcher := New(&testClient, indexer.InMem)
+			fetcher := New(&testClient, indexer.InMem, nil, nil, nil)
 			if err := fetcher.Fetch(ctx, table.layer); err == nil {
 				t.Fatal("expected error, got nil")
 			}
 		})
 	}
 }
+
+// TestMediaTypes checks that layers served with the media types used in
+// manifests, including the foreign and nondistributable types used for
+// Windows base layers, are decompressed.
+func TestMediaTypes(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	for _, ct := range []string{
+		"application/vnd.oci.image.layer.v1.tar+gzip",
+		"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip",
+		"application/vnd.docker.image.rootfs.diff.tar.gzip",
+		"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip",
+	} {
+		t.Run(ct, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			l := serveBlobType(t, bomb(t, 1, 16), ct)
+			f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+			defer f.Close()
+			if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestFetchCost checks that a fetcher accounts for the bytes it downloads
+// and writes, and that layers already fetched cost nothing.
+func TestFetchCost(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b := bomb(t, 1, 1<<20)
+	l := serveBlobType(t, b, "application/vnd.oci.image.layer.v1.tar+gzip")
+	f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+	defer f.Close()
+	if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+		t.Fatal(err)
+	}
+	var c claircore.IndexCost
+	f.AddCost(&c)
+	if got, want := c.FetchedBytes, int64(len(b)); got != want {
+		t.Errorf("fetched bytes: got: %d, want: %d", got, want)
+	}
+	// The tar archive is a header, the file, and the end-of-archive blocks.
+	if got, want := c.ScratchBytes, int64(1<<20); got <= want {
+		t.Errorf("scratch bytes: got: %d, want: >%d", got, want)
+	}
+
+	again := New(&testClient, indexer.OnDisk, nil, nil, nil)
+	defer again.Close()
+	if err := again.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+		t.Fatal(err)
+	}
+	c = claircore.IndexCost{}
+	again.AddCost(&c)
+	if c.FetchedBytes != 0 || c.ScratchBytes != 0 {
+		t.Errorf("repeat fetch: unexpected cost: %+v", c)
+	}
+}
+
+// TestUnsupportedMediaType checks that layers served with unsupported media
+// types are fetched if their format is recognizable, with a warning recorded,
+// and fail otherwise.
+func TestUnsupportedMediaType(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const ct = "application/x-unexpected"
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "file",
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		Name string
+		Blob func(*testing.T) []byte
+		Err  bool
+	}{
+		{Name: "Gzip", Blob: func(t *testing.T) []byte { return bomb(t, 1, 16) }},
+		{Name: "Tar", Blob: func(*testing.T) []byte { return tarball.Bytes() }},
+		{Name: "Garbage", Blob: func(*testing.T) []byte { return []byte("this isn't a layer") }, Err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			l := serveBlobType(t, tc.Blob(t), ct)
+			f := New(&testClient, indexer.OnDisk, nil, nil, nil)
+			defer f.Close()
+			err := f.Fetch(ctx, []*claircore.Layer{l})
+			ws := f.Warnings()
+			if tc.Err {
+				t.Log(err)
+				if err == nil {
+					t.Error("expected error")
+				}
+				if len(ws) != 0 {
+					t.Errorf("unexpected warnings: %v", ws)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ws) != 1 {
+				t.Fatalf("got %d warnings, want 1: %v", len(ws), ws)
+			}
+			t.Log(ws[0].Message)
+			if got, want := ws[0].Code, claircore.WarningUnsupportedMediaType; got != want {
+				t.Errorf("got code %q, want %q", got, want)
+			}
+			if got, want := ws[0].Subject, l.Hash.String(); got != want {
+				t.Errorf("got subject %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// RatioFloor is the number of decompressed bytes that must be read before the
+// compression ratio is checked. Small layers, especially ones consisting of
+// mostly empty files, can have wild ratios without being a real problem.
+//
+// This is a variable to allow tests to lower it.
+var ratioFloor int64 = 256 << 20 // 256 MiB
+
+// CountingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// LimitReader enforces the size and ratio limits on a decompressed stream.
+//
+// The compressed stream is consulted for the ratio check.
+type limitReader struct {
+	r   io.Reader
+	c   *countingReader
+	lim *indexer.LayerLimits
+	n   int64
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.n += int64(n)
+	switch {
+	case l.n > l.lim.MaxSize:
+		return n, fmt.Errorf("%w: more than %d bytes decompressed",
+			claircore.ErrLayerTooLarge, l.lim.MaxSize)
+	case l.n > ratioFloor && l.c.n > 0 && l.n/l.c.n > l.lim.MaxRatio:
+		return n, fmt.Errorf("%w: compression ratio exceeds %d",
+			claircore.ErrLayerTooLarge, l.lim.MaxRatio)
+	}
+	return n, err
+}
+
+// CopyLayer copies the decompressed layer "r" into "w", enforcing all the
+// limits in "lim". The reader "c" must be the compressed stream underlying
+// "r".
+//
+// The archive is walked as it's copied to enforce the limits on entries.
+// Archives that can't be parsed are copied as-is; it's up to the scanners to
+// report any problems with them.
+func copyLayer(w io.Writer, r io.Reader, c *countingReader, lim *indexer.LayerLimits) (int64, error) {
+	lr := &limitReader{r: r, c: c, lim: lim}
+	tee := io.TeeReader(lr, w)
+	rd := tar.NewReader(tee)
+	ct := 0
+	h, err := rd.Next()
+	for ; err == nil; h, err = rd.Next() {
+		ct++
+		if ct > lim.MaxEntries {
+			return lr.n, fmt.Errorf("%w: more than %d entries",
+				claircore.ErrLayerTooLarge, lim.MaxEntries)
+		}
+		if h.Size > lim.MaxFileSize {
+			return lr.n, fmt.Errorf("%w: file %q is %d bytes, more than %d",
+				claircore.ErrLayerTooLarge, h.Name, h.Size, lim.MaxFileSize)
+		}
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+	case errors.Is(err, claircore.ErrLayerTooLarge):
+		return lr.n, err
+	default:
+		// Not a tar we understand, so fall through and copy everything else.
+	}
+	// Copy any trailing padding, or the rest of an unparsable archive.
+	if _, err := io.Copy(ioutil.Discard, tee); err != nil {
+		return lr.n, err
+	}
+	return lr.n, nil
+}
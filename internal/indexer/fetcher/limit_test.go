@@ -0,0 +1,172 @@
+package fetcher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// Bomb constructs a gzipped tar containing "n" files of "sz" zero bytes each.
+//
+// The returned blob is small, no matter the arguments.
+func bomb(t *testing.T, n int, sz int64) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i < n; i++ {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     fmt.Sprintf("file%d", i),
+			Size:     sz,
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.CopyN(tw, zeros{}, sz); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+// ServeBlob serves the provided blob and returns a Layer pointing to it.
+func serveBlob(t *testing.T, b []byte) *claircore.Layer {
+	t.Helper()
+	return serveBlobType(t, b, "application/octet-stream")
+}
+
+// ServeBlobType is like serveBlob, but sets the content-type to "ct".
+func serveBlobType(t *testing.T, b []byte, ct string) *claircore.Layer {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", ct)
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	sum := sha256.Sum256(b)
+	d, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &claircore.Layer{
+		Hash: d,
+		URI:  srv.URL,
+	}
+}
+
+func TestLayerLimits(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	defer func(f int64) { ratioFloor = f }(ratioFloor)
+	ratioFloor = 1 << 10
+
+	tt := []struct {
+		Name  string
+		Blob  func(*testing.T) []byte
+		Limit indexer.LayerLimits
+		Err   bool
+	}{
+		{
+			Name: "OK",
+			Blob: func(t *testing.T) []byte { return bomb(t, 16, 1<<10) },
+			Limit: indexer.LayerLimits{
+				MaxSize:     1 << 20,
+				MaxEntries:  16,
+				MaxFileSize: 1 << 10,
+			},
+		},
+		{
+			Name:  "Size",
+			Blob:  func(t *testing.T) []byte { return bomb(t, 1, 64<<20) },
+			Limit: indexer.LayerLimits{MaxSize: 1 << 20},
+			Err:   true,
+		},
+		{
+			Name:  "Ratio",
+			Blob:  func(t *testing.T) []byte { return bomb(t, 1, 16<<20) },
+			Limit: indexer.LayerLimits{MaxRatio: 10},
+			Err:   true,
+		},
+		{
+			Name:  "Entries",
+			Blob:  func(t *testing.T) []byte { return bomb(t, 1024, 0) },
+			Limit: indexer.LayerLimits{MaxEntries: 100},
+			Err:   true,
+		},
+		{
+			Name:  "FileSize",
+			Blob:  func(t *testing.T) []byte { return bomb(t, 1, 1<<20) },
+			Limit: indexer.LayerLimits{MaxFileSize: 1 << 10},
+			Err:   true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			b := tc.Blob(t)
+			t.Logf("blob is %d bytes", len(b))
+			l := serveBlob(t, b)
+			f := New(&testClient, indexer.OnDisk, &tc.Limit, nil, nil)
+			defer f.Close()
+			err := f.Fetch(ctx, []*claircore.Layer{l})
+			t.Log(err)
+			switch {
+			case tc.Err && !errors.Is(err, claircore.ErrLayerTooLarge):
+				t.Errorf("got: %v, want: %v", err, claircore.ErrLayerTooLarge)
+			case !tc.Err && err != nil:
+				t.Error(err)
+			case !tc.Err:
+				// Check that the whole archive was written out.
+				gz, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := ioutil.ReadAll(gz)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rc, err := l.Reader()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer rc.Close()
+				got, err := ioutil.ReadAll(rc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("layer contents differ: got %d bytes, want %d bytes", len(got), len(want))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// Registries commonly answer blob requests with a redirect to a pre-signed
+// storage URL that expires after a short time. The fetcher follows redirects
+// itself so that the redirected URL is only ever used for the request it was
+// issued for: the Layer's URI stays the canonical registry URL, and a
+// storage URL rejected as expired is re-resolved through it.
+const (
+	// MaxRedirects is the number of redirects followed for a single request.
+	maxRedirects = 5
+	// MaxResolves is the number of times the Layer's URI is requested when
+	// redirect targets keep rejecting the request.
+	maxResolves = 3
+)
+
+// ErrTooManyRedirects is reported when a request is redirected more than
+// maxRedirects times.
+var errTooManyRedirects = errors.New("fetcher: too many redirects")
+
+// NoRedirect stops an http.Client from following redirects, so the fetcher
+// can follow them itself.
+func noRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// Open requests the layer's contents from "u", which should be the layer's
+// URI, following redirects.
+//
+// If the request was redirected and the final location refuses it in the way
+// storage services refuse expired signatures, the layer's URI is requested
+// again for a fresh redirect, up to maxResolves times in total. The last
+// response is returned whatever its status.
+func (f *fetcher) open(ctx context.Context, layer *claircore.Layer, u *url.URL) (*http.Response, error) {
+	for try := 1; ; try++ {
+		resp, redirected, err := f.follow(ctx, layer, u)
+		if err != nil {
+			return nil, err
+		}
+		if !redirected || !refused(resp) || try == maxResolves {
+			return resp, nil
+		}
+		zlog.Debug(ctx).
+			Str("location", redact(resp.Request.URL)).
+			Str("status", resp.Status).
+			Int("try", try).
+			Msg("redirect target refused request, re-resolving")
+		discard(resp)
+	}
+}
+
+// Follow requests "u", following up to maxRedirects redirects. It reports
+// whether the returned response came from a redirect.
+//
+// Credentials in the layer's headers are only sent to the URI's host, as
+// pre-signed URLs carry their own and storage services may reject requests
+// with both. The same goes for the fetcher's Authorizer, which is consulted
+// for requests to that host whose layer doesn't supply credentials. If such a
+// request is refused as unauthorized, the Authorizer is given one chance to
+// acquire credentials before the request is made again.
+func (f *fetcher) follow(ctx context.Context, layer *claircore.Layer, u *url.URL) (*http.Response, bool, error) {
+	host := u.Host
+	refreshed := false
+	for hop := 0; ; hop++ {
+		h := http.Header(layer.Headers).Clone()
+		if h == nil {
+			h = make(http.Header)
+		}
+		if u.Host != host {
+			h.Del("Authorization")
+			h.Del("Cookie")
+		}
+		req := &http.Request{
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Method:     http.MethodGet,
+			URL:        u,
+			Host:       u.Host,
+			Header:     h,
+		}
+		req = req.WithContext(ctx)
+		authorize := f.auth != nil && u.Host == host && h.Get("Authorization") == ""
+		if authorize {
+			if err := f.auth.Authorize(ctx, req); err != nil {
+				return nil, false, fmt.Errorf("fetcher: unable to authorize request: %w", err)
+			}
+		}
+		resp, err := f.wc.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("fetcher: request failed: %w", err)
+		}
+		if authorize && !refreshed && resp.StatusCode == http.StatusUnauthorized {
+			refreshed = true
+			ok, err := f.auth.Refresh(ctx, resp)
+			if err != nil {
+				discard(resp)
+				return nil, false, fmt.Errorf("fetcher: unable to acquire credentials: %w", err)
+			}
+			if ok {
+				zlog.Debug(ctx).
+					Str("location", redact(u)).
+					Msg("acquired credentials, retrying")
+				discard(resp)
+				hop--
+				continue
+			}
+		}
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+			http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return resp, hop != 0, nil
+		}
+		loc := resp.Header.Get("Location")
+		discard(resp)
+		if loc == "" {
+			return nil, false, fmt.Errorf("fetcher: redirect without location: %s", resp.Status)
+		}
+		if hop == maxRedirects {
+			return nil, false, errTooManyRedirects
+		}
+		next, err := u.Parse(loc)
+		if err != nil {
+			return nil, false, fmt.Errorf("fetcher: bad redirect location: %w", err)
+		}
+		zlog.Debug(ctx).
+			Str("location", redact(next)).
+			Int("hop", hop+1).
+			Msg("following redirect")
+		u = next
+	}
+}
+
+// Redact returns "u" without its query, which is where pre-signed URLs keep
+// their signatures.
+func redact(u *url.URL) string {
+	c := *u
+	c.RawQuery = ""
+	c.User = nil
+	return c.String()
+}
+
+// Refused reports whether the response is how storage services respond to an
+// expired or otherwise invalid signature. S3 responds with 403 and GCS with
+// 400; 401 is included for services that treat the signature as credentials.
+func refused(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// Discard drains a small amount of the response body, so the connection can
+// be reused, and closes it.
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
@@ -0,0 +1,315 @@
+package fetcher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// FakeRegistry serves a single blob the way registries backed by object
+// storage do: the registry requires credentials and redirects to a storage
+// URL with an expiring signature, and storage refuses expired signatures and
+// requests carrying credentials.
+type fakeRegistry struct {
+	t        *testing.T
+	blob     []byte
+	registry *httptest.Server
+	storage  *httptest.Server
+
+	// TTL returns the lifetime of the n'th issued signature, counting from 1.
+	ttl func(n int64) time.Duration
+	// Hops is the number of times the registry redirects to itself before
+	// redirecting to storage.
+	hops int
+
+	issued  int64
+	authed  int64
+	fetched int64
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	f := &fakeRegistry{
+		t:   t,
+		ttl: func(int64) time.Duration { return time.Minute },
+	}
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	if err := w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "./randomfile",
+		Size:     32,
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.CopyN(w, rand.Reader, 32); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.blob = buf.Bytes()
+	f.storage = httptest.NewServer(http.HandlerFunc(f.serveStorage))
+	f.registry = httptest.NewServer(http.HandlerFunc(f.serveRegistry))
+	t.Cleanup(f.registry.Close)
+	t.Cleanup(f.storage.Close)
+	return f
+}
+
+func (f *fakeRegistry) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer registry" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	atomic.AddInt64(&f.authed, 1)
+	hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+	if hop < f.hops {
+		// Relative redirects stay on the registry.
+		http.Redirect(w, r, "?hop="+strconv.Itoa(hop+1), http.StatusFound)
+		return
+	}
+	n := atomic.AddInt64(&f.issued, 1)
+	exp := time.Now().Add(f.ttl(n)).UnixNano()
+	http.Redirect(w, r, f.storage.URL+"/bucket/blob?expires="+strconv.FormatInt(exp, 10), http.StatusTemporaryRedirect)
+}
+
+func (f *fakeRegistry) serveStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "" {
+		http.Error(w, "only one auth mechanism allowed", http.StatusBadRequest)
+		return
+	}
+	exp, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().UnixNano() > exp {
+		http.Error(w, "request has expired", http.StatusForbidden)
+		return
+	}
+	atomic.AddInt64(&f.fetched, 1)
+	w.Header().Set("content-type", "application/vnd.oci.image.layer.v1.tar")
+	w.Write(f.blob)
+}
+
+func (f *fakeRegistry) layer() *claircore.Layer {
+	sum := sha256.Sum256(f.blob)
+	d, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return &claircore.Layer{
+		Hash: d,
+		URI:  f.registry.URL + "/v2/repo/blobs/" + d.String(),
+		Headers: map[string][]string{
+			"Authorization": {"Bearer registry"},
+		},
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	tt := []struct {
+		name  string
+		setup func(*fakeRegistry)
+		// Issued is the number of storage URLs the registry should hand out.
+		issued int64
+		err    func(error) bool
+	}{
+		{
+			name:   "Simple",
+			issued: 1,
+		},
+		{
+			name:   "Hops",
+			setup:  func(f *fakeRegistry) { f.hops = 3 },
+			issued: 1,
+		},
+		{
+			name: "Expired",
+			setup: func(f *fakeRegistry) {
+				f.ttl = func(n int64) time.Duration {
+					if n == 1 {
+						return -time.Second
+					}
+					return time.Minute
+				}
+			},
+			issued: 2,
+		},
+		{
+			name: "AlwaysExpired",
+			setup: func(f *fakeRegistry) {
+				f.ttl = func(int64) time.Duration { return -time.Second }
+			},
+			issued: maxResolves,
+			err:    func(err error) bool { return err != nil },
+		},
+		{
+			name:   "TooManyRedirects",
+			setup:  func(f *fakeRegistry) { f.hops = maxRedirects + 1 },
+			issued: 0,
+			err:    func(err error) bool { return errors.Is(err, errTooManyRedirects) },
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			reg := newFakeRegistry(t)
+			if tc.setup != nil {
+				tc.setup(reg)
+			}
+			l := reg.layer()
+			uri := l.URI
+
+			f := New(&testClient, indexer.LayerFetchOpt(""), nil, nil, nil)
+			defer func() {
+				if err := f.Close(); err != nil {
+					t.Error(err)
+				}
+			}()
+			err := f.Fetch(ctx, []*claircore.Layer{l})
+			switch {
+			case tc.err == nil && err != nil:
+				t.Error(err)
+			case tc.err != nil && !tc.err(err):
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got, want := atomic.LoadInt64(&reg.issued), tc.issued; got != want {
+				t.Errorf("issued %d storage URLs, want %d", got, want)
+			}
+			if l.URI != uri {
+				t.Errorf("layer URI changed: got %q, want %q", l.URI, uri)
+			}
+		})
+	}
+}
+
+// TestRedirectRefetch checks that a layer can be fetched again after the
+// storage URL it was first redirected to has expired.
+func TestRedirectRefetch(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	reg := newFakeRegistry(t)
+	reg.ttl = func(int64) time.Duration { return 50 * time.Millisecond }
+	l := reg.layer()
+
+	for i := 0; i < 2; i++ {
+		// Use a fresh Layer, as a retried index request would.
+		l := &claircore.Layer{Hash: l.Hash, URI: l.URI, Headers: l.Headers}
+		f := New(&testClient, indexer.LayerFetchOpt(""), nil, nil, nil)
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+			t.Error(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Error(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if got, want := atomic.LoadInt64(&reg.fetched), int64(2); got != want {
+		t.Errorf("fetched %d times, want %d", got, want)
+	}
+	if got, want := atomic.LoadInt64(&reg.authed), int64(2); got != want {
+		t.Errorf("registry answered %d requests, want %d", got, want)
+	}
+}
+
+// FakeAuthorizer hands out the credentials the fakeRegistry expects once it's
+// asked to refresh them.
+type fakeAuthorizer struct {
+	authorized int64
+	refreshed  int64
+	acquired   int32
+}
+
+func (a *fakeAuthorizer) Authorize(_ context.Context, req *http.Request) error {
+	atomic.AddInt64(&a.authorized, 1)
+	if atomic.LoadInt32(&a.acquired) != 0 {
+		req.Header.Set("Authorization", "Bearer registry")
+	}
+	return nil
+}
+
+func (a *fakeAuthorizer) Refresh(_ context.Context, resp *http.Response) (bool, error) {
+	atomic.AddInt64(&a.refreshed, 1)
+	atomic.StoreInt32(&a.acquired, 1)
+	return true, nil
+}
+
+// TestAuthorizer checks that the Authorizer is only consulted for requests to
+// the registry that don't already carry credentials, and that a refused
+// request is retried once credentials are acquired.
+func TestAuthorizer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("Acquire", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		reg := newFakeRegistry(t)
+		l := reg.layer()
+		l.Headers = nil
+		a := &fakeAuthorizer{}
+		f := New(&testClient, indexer.LayerFetchOpt(""), nil, a, nil)
+		defer f.Close()
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := atomic.LoadInt64(&a.refreshed), int64(1); got != want {
+			t.Errorf("refreshed %d times, want %d", got, want)
+		}
+		// Once for the refused request and once for the retry; never for
+		// storage.
+		if got, want := atomic.LoadInt64(&a.authorized), int64(2); got != want {
+			t.Errorf("authorized %d requests, want %d", got, want)
+		}
+		if got, want := atomic.LoadInt64(&reg.fetched), int64(1); got != want {
+			t.Errorf("fetched %d times, want %d", got, want)
+		}
+	})
+	t.Run("LayerHeaders", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		reg := newFakeRegistry(t)
+		a := &fakeAuthorizer{}
+		f := New(&testClient, indexer.LayerFetchOpt(""), nil, a, nil)
+		defer f.Close()
+		if err := f.Fetch(ctx, []*claircore.Layer{reg.layer()}); err != nil {
+			t.Fatal(err)
+		}
+		if got := atomic.LoadInt64(&a.authorized) + atomic.LoadInt64(&a.refreshed); got != 0 {
+			t.Errorf("authorizer consulted %d times, want 0", got)
+		}
+	})
+	t.Run("Refused", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		reg := newFakeRegistry(t)
+		l := reg.layer()
+		l.Headers = nil
+		// Always claims to have acquired credentials, but never supplies
+		// them.
+		a := &fakeAuthorizer{}
+		f := New(&testClient, indexer.LayerFetchOpt(""), nil, refreshOnly{a}, nil)
+		defer f.Close()
+		if err := f.Fetch(ctx, []*claircore.Layer{l}); err == nil {
+			t.Error("expected error")
+		}
+		if got, want := atomic.LoadInt64(&a.refreshed), int64(1); got != want {
+			t.Errorf("refreshed %d times, want %d", got, want)
+		}
+	})
+}
+
+// RefreshOnly is an Authorizer that never adds credentials.
+type refreshOnly struct{ *fakeAuthorizer }
+
+func (refreshOnly) Authorize(context.Context, *http.Request) error { return nil }
@@ -9,6 +9,10 @@ import (
 // LayerScanner is an interface for scanning a set of layer's contents and indexing
 // discovered items into the persistence layer. scanning mechanics (concurrency, ordering, etc...)
 // will be defined by implementations.
+//
+// A LayerScanner may also implement CostReporter, to have the time its
+// scanners took and the artifacts it stored included in the IndexReport's
+// cost summary.
 type LayerScanner interface {
 	Scan(ctx context.Context, manifest claircore.Digest, layers []*claircore.Layer) error
 }
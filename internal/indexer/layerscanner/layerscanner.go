@@ -4,7 +4,12 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
@@ -15,8 +20,22 @@ import (
 	"github.com/quay/claircore/internal/indexer"
 )
 
+var scansCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "claircore",
+		Subsystem: "indexer",
+		Name:      "layer_scans_total",
+		Help:      "Total number of (layer, scanner) pairs considered, by whether the store already had results (\"cached\") or the scanner was run (\"scanned\").",
+	},
+	[]string{"scanner", "result"},
+)
+
 // LayerScanner implements the indexer.LayerScanner interface.
 type layerScanner struct {
+	// Rows counts the artifacts stored by scans. It's accessed atomically,
+	// so it's first to keep it aligned.
+	rows int64
+
 	store indexer.Store
 
 	// Maximum allowed in-flight scanners per Scan call
@@ -26,6 +45,17 @@ type layerScanner struct {
 	ps []indexer.PackageScanner
 	ds []indexer.DistributionScanner
 	rs []indexer.RepositoryScanner
+	// Scnrs is every scanner, in the order Scan considers them, and cost
+	// is the cost of each.
+	scnrs indexer.VersionedScanners
+	cost  []scanCost
+}
+
+// ScanCost accumulates the cost of a scanner's scans. The members are
+// accessed atomically.
+type scanCost struct {
+	elapsed int64 // nanoseconds
+	layers  int64
 }
 
 // New is the constructor for a LayerScanner.
@@ -49,6 +79,7 @@ func New(ctx context.Context, concurrent int, opts *indexer.Opts) (indexer.Layer
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract scanners from ecosystems: %v", err)
 	}
+	warnUnknownConfig(ctx, opts, ps, ds, rs)
 	// Configure and filter the scanners
 	var i int
 	i = 0
@@ -76,15 +107,54 @@ func New(ctx context.Context, concurrent int, opts *indexer.Opts) (indexer.Layer
 	}
 	ds = ds[:i]
 
+	var scnrs indexer.VersionedScanners
+	for _, s := range ps {
+		scnrs = append(scnrs, s)
+	}
+	for _, s := range ds {
+		scnrs = append(scnrs, s)
+	}
+	for _, s := range rs {
+		scnrs = append(scnrs, s)
+	}
 	return &layerScanner{
 		store:    opts.Store,
 		inflight: int64(concurrent),
 		ps:       ps,
 		ds:       ds,
 		rs:       rs,
+		scnrs:    scnrs,
+		cost:     make([]scanCost, len(scnrs)),
 	}, nil
 }
 
+// WarnUnknownConfig logs the entries in the scanner configuration that don't
+// name any configured scanner. That's usually a misspelling, or a scanner
+// left out by the Airgap setting.
+func warnUnknownConfig(ctx context.Context, opts *indexer.Opts, ps []indexer.PackageScanner, ds []indexer.DistributionScanner, rs []indexer.RepositoryScanner) {
+	check := func(kind string, cfg map[string]func(interface{}) error, vs indexer.VersionedScanners) {
+		have := make(map[string]struct{}, len(vs))
+		for _, s := range vs {
+			have[s.Name()] = struct{}{}
+		}
+		for n := range cfg {
+			if _, ok := have[n]; !ok {
+				zlog.Warn(ctx).
+					Str("kind", kind).
+					Str("scanner", n).
+					Msg("configuration present for unknown or disallowed scanner")
+			}
+		}
+	}
+	var vs indexer.VersionedScanners
+	vs.PStoVS(ps)
+	check("package", opts.ScannerConfig.Package, vs)
+	vs.DStoVS(ds)
+	check("distribution", opts.ScannerConfig.Dist, vs)
+	vs.RStoVS(rs)
+	check("repository", opts.ScannerConfig.Repo, vs)
+}
+
 // ConfigAndFilter configures the provided scanner and reports if it should be
 // filtered out of the slice or not.
 func configAndFilter(ctx context.Context, opts *indexer.Opts, s indexer.VersionedScanner) bool {
@@ -144,6 +214,10 @@ func configAndFilter(ctx context.Context, opts *indexer.Opts, s indexer.Versione
 // Scan will launch all layer scan goroutines immediately and then only allow
 // the configured limit to proceed.
 //
+// The store is queried once up front for every (layer, scanner) pair, and
+// pairs it already has results for are skipped. This lets instances sharing a
+// database avoid scanning layers any of them has already scanned.
+//
 // The provided Context controls cancellation for all scanners. The first error
 // reported halts all work and is returned from Scan.
 func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, layers []*claircore.Layer) error {
@@ -160,28 +234,42 @@ func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, lay
 		}
 	}
 
+	scnrs := ls.scnrs
+	// Consult the store for every pair up front, so layers another instance
+	// sharing the database has already scanned are skipped.
+	hashes := make([]claircore.Digest, len(layersToScan))
+	for i, l := range layersToScan {
+		hashes[i] = l.Hash
+	}
+	scanned, err := ls.store.LayersScanned(ctx, hashes, scnrs)
+	if err != nil {
+		return err
+	}
+
 	sem := semaphore.NewWeighted(ls.inflight)
 	g, ctx := errgroup.WithContext(ctx)
 	// Launch is a closure to capture the loop variables and then call the
 	// scanLayer method.
-	launch := func(l *claircore.Layer, s indexer.VersionedScanner) func() error {
+	launch := func(l *claircore.Layer, j int) func() error {
 		return func() error {
 			if err := sem.Acquire(ctx, 1); err != nil {
 				return err
 			}
 			defer sem.Release(1)
-			return ls.scanLayer(ctx, l, s)
+			return ls.scanLayer(ctx, l, scnrs[j], &ls.cost[j])
 		}
 	}
-	for _, l := range layersToScan {
-		for _, s := range ls.ps {
-			g.Go(launch(l, s))
-		}
-		for _, s := range ls.ds {
-			g.Go(launch(l, s))
-		}
-		for _, s := range ls.rs {
-			g.Go(launch(l, s))
+	for i, l := range layersToScan {
+		for j, s := range scnrs {
+			if scanned[i][j] {
+				scansCounter.WithLabelValues(s.Name(), "cached").Inc()
+				zlog.Debug(ctx).
+					Str("scanner", s.Name()).
+					Str("layer", l.Hash.String()).
+					Msg("layer already scanned")
+				continue
+			}
+			g.Go(launch(l, j))
 		}
 	}
 
@@ -190,7 +278,7 @@ func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, lay
 
 // ScanLayer (along with the result type) handles an individual (scanner, layer)
 // pair.
-func (ls *layerScanner) scanLayer(ctx context.Context, l *claircore.Layer, s indexer.VersionedScanner) error {
+func (ls *layerScanner) scanLayer(ctx context.Context, l *claircore.Layer, s indexer.VersionedScanner, cost *scanCost) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/indexer/layerscannner/layerScanner.scan"),
 		label.String("scanner", s.Name()),
@@ -199,25 +287,55 @@ func (ls *layerScanner) scanLayer(ctx context.Context, l *claircore.Layer, s ind
 	zlog.Debug(ctx).Msg("scan start")
 	defer zlog.Debug(ctx).Msg("scan done")
 
-	ok, err := ls.store.LayerScanned(ctx, l.Hash, s)
+	var result result
+	start := time.Now()
+	err := result.Do(ctx, s, l)
+	atomic.AddInt64(&cost.elapsed, int64(time.Since(start)))
+	atomic.AddInt64(&cost.layers, 1)
 	if err != nil {
 		return err
 	}
-	if ok {
-		zlog.Debug(ctx).Msg("layer already scanned")
-		return nil
-	}
+	scansCounter.WithLabelValues(s.Name(), "scanned").Inc()
 
-	var result result
-	if err := result.Do(ctx, s, l); err != nil {
+	// The results are written before the layer is marked scanned, so another
+	// instance never skips a layer whose results aren't there yet. Both writes
+	// are idempotent, so instances racing to scan the same layer don't
+	// conflict.
+	if err := result.Store(ctx, ls.store, s, l); err != nil {
 		return err
 	}
-
-	if err = ls.store.SetLayerScanned(ctx, l.Hash, s); err != nil {
+	atomic.AddInt64(&ls.rows, int64(result.Len()))
+	if err := ls.store.SetLayerScanned(ctx, l.Hash, s); err != nil {
 		return fmt.Errorf("could not set layer scanned: %v", l)
 	}
+	return nil
+}
 
-	return result.Store(ctx, ls.store, s, l)
+// AddCost implements indexer.CostReporter. Scanners that haven't scanned any
+// layers, because the store already had their results, aren't included.
+func (ls *layerScanner) AddCost(c *claircore.IndexCost) {
+	for i, s := range ls.scnrs {
+		cost := &ls.cost[i]
+		n := atomic.LoadInt64(&cost.layers)
+		if n == 0 {
+			continue
+		}
+		c.Scanners = append(c.Scanners, claircore.ScannerCost{
+			Name:    s.Name(),
+			Version: s.Version(),
+			Kind:    s.Kind(),
+			Layers:  n,
+			Elapsed: time.Duration(atomic.LoadInt64(&cost.elapsed)),
+		})
+	}
+	sort.SliceStable(c.Scanners, func(i, j int) bool {
+		a, b := &c.Scanners[i], &c.Scanners[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	c.StoreRows += atomic.LoadInt64(&ls.rows)
 }
 
 // Result is a type that handles the kind-specific bits of the scan process.
@@ -245,6 +363,11 @@ func (r *result) Do(ctx context.Context, s indexer.VersionedScanner, l *claircor
 	return err
 }
 
+// Len reports the number of artifacts captured in the result.
+func (r *result) Len() int {
+	return len(r.pkgs) + len(r.dists) + len(r.repos)
+}
+
 // Store calls the properly typed store method on whatever value was captured in
 // the result.
 func (r *result) Store(ctx context.Context, store indexer.Store, s indexer.VersionedScanner, l *claircore.Layer) error {
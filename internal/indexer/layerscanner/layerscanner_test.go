@@ -1,8 +1,12 @@
 package layerscanner
 
 import (
+	"archive/tar"
 	"context"
 	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +14,7 @@ import (
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/dpkg"
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/test"
 )
@@ -21,6 +26,7 @@ func TestScanNoErrors(t *testing.T) {
 	defer done()
 	ctx = zlog.Test(ctx, t)
 	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
 	mock_ps := indexer.NewMockPackageScanner(ctrl)
 	mock_ds := indexer.NewMockDistributionScanner(ctrl)
@@ -32,39 +38,31 @@ func TestScanNoErrors(t *testing.T) {
 
 	mock_ps.EXPECT().Scan(gomock.Any(), layers[0]).Return([]*claircore.Package{}, nil)
 	mock_ps.EXPECT().Scan(gomock.Any(), layers[1]).Return([]*claircore.Package{}, nil)
-	mock_ps.EXPECT().Kind().AnyTimes()
-	mock_ps.EXPECT().Name().AnyTimes()
-	mock_ps.EXPECT().Version().AnyTimes()
+	mock_ps.EXPECT().Kind().AnyTimes().Return("package")
+	mock_ps.EXPECT().Name().AnyTimes().Return("ps")
+	mock_ps.EXPECT().Version().AnyTimes().Return("1")
 
 	mock_ds.EXPECT().Scan(gomock.Any(), layers[0]).Return([]*claircore.Distribution{}, nil)
 	mock_ds.EXPECT().Scan(gomock.Any(), layers[1]).Return([]*claircore.Distribution{}, nil)
-	mock_ds.EXPECT().Kind().AnyTimes()
-	mock_ds.EXPECT().Name().AnyTimes()
-	mock_ds.EXPECT().Version().AnyTimes()
+	mock_ds.EXPECT().Kind().AnyTimes().Return("distribution")
+	mock_ds.EXPECT().Name().AnyTimes().Return("ds")
+	mock_ds.EXPECT().Version().AnyTimes().Return("1")
 
 	mock_rs.EXPECT().Scan(gomock.Any(), layers[0]).Return([]*claircore.Repository{}, nil)
 	mock_rs.EXPECT().Scan(gomock.Any(), layers[1]).Return([]*claircore.Repository{}, nil)
-	mock_rs.EXPECT().Kind().AnyTimes()
-	mock_rs.EXPECT().Name().AnyTimes()
-	mock_rs.EXPECT().Version().AnyTimes()
+	mock_rs.EXPECT().Kind().AnyTimes().Return("repository")
+	mock_rs.EXPECT().Name().AnyTimes().Return("rs")
+	mock_rs.EXPECT().Version().AnyTimes().Return("1")
 
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[0].Hash, mock_ps).Return(true, nil)
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[1].Hash, mock_ps).Return(true, nil)
+	mock_store.EXPECT().LayersScanned(gomock.Any(), []claircore.Digest{layers[0].Hash, layers[1].Hash}, gomock.Any()).
+		Return([][]bool{{false, false, false}, {false, false, false}}, nil)
 
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[0].Hash, mock_ds).Return(true, nil)
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[1].Hash, mock_ds).Return(true, nil)
-
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[0].Hash, mock_rs).Return(true, nil)
-	mock_store.EXPECT().LayerScanned(gomock.Any(), layers[1].Hash, mock_rs).Return(true, nil)
-
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[0], mock_ps).Return(nil)
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[1], mock_ps).Return(nil)
-
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[0], mock_rs).Return(nil)
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[1], mock_rs).Return(nil)
-
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[0], mock_ds).Return(nil)
-	mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), layers[1], mock_ds).Return(nil)
+	for _, l := range layers {
+		mock_store.EXPECT().IndexPackages(gomock.Any(), gomock.Any(), l, mock_ps).Return(nil)
+		mock_store.EXPECT().IndexDistributions(gomock.Any(), gomock.Any(), l, mock_ds).Return(nil)
+		mock_store.EXPECT().IndexRepositories(gomock.Any(), gomock.Any(), l, mock_rs).Return(nil)
+		mock_store.EXPECT().SetLayerScanned(gomock.Any(), l.Hash, gomock.Any()).Times(3).Return(nil)
+	}
 
 	ecosystem := &indexer.Ecosystem{
 		Name: "test-ecosystem",
@@ -99,3 +97,248 @@ func TestScanNoErrors(t *testing.T) {
 		t.Fatalf("failed to scan test layers: %v", err)
 	}
 }
+
+// SharedStore is an in-memory store recording which layers have been scanned,
+// for use by several layerScanners at once. Calling other methods panics.
+type sharedStore struct {
+	indexer.Store
+	mu      sync.Mutex
+	queries int
+	scanned map[string]bool
+}
+
+func (s *sharedStore) key(h claircore.Digest, v indexer.VersionedScanner) string {
+	return h.String() + " " + v.Name() + "@" + v.Version()
+}
+
+func (s *sharedStore) LayersScanned(_ context.Context, hs []claircore.Digest, vs indexer.VersionedScanners) ([][]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	out := make([][]bool, len(hs))
+	for i, h := range hs {
+		out[i] = make([]bool, len(vs))
+		for j, v := range vs {
+			out[i][j] = s.scanned[s.key(h, v)]
+		}
+	}
+	return out, nil
+}
+
+func (s *sharedStore) SetLayerScanned(_ context.Context, h claircore.Digest, v indexer.VersionedScanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scanned[s.key(h, v)] = true
+	return nil
+}
+
+func (s *sharedStore) IndexPackages(context.Context, []*claircore.Package, *claircore.Layer, indexer.VersionedScanner) error {
+	return nil
+}
+
+func (s *sharedStore) IndexDistributions(context.Context, []*claircore.Distribution, *claircore.Layer, indexer.VersionedScanner) error {
+	return nil
+}
+
+func (s *sharedStore) IndexRepositories(context.Context, []*claircore.Repository, *claircore.Layer, indexer.VersionedScanner) error {
+	return nil
+}
+
+// CountingScanner is a package scanner that counts its Scan calls by layer.
+type countingScanner struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (*countingScanner) Name() string    { return "counting" }
+func (*countingScanner) Version() string { return "1" }
+func (*countingScanner) Kind() string    { return "package" }
+func (s *countingScanner) Scan(_ context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[l.Hash.String()]++
+	return []*claircore.Package{}, nil
+}
+
+// TestScanSharedStore checks that layerScanners sharing a store don't rescan
+// layers the other has scanned, and consult the store once per Scan call.
+func TestScanSharedStore(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	layers := test.ServeLayers(ctx, t, 3)
+	store := &sharedStore{scanned: make(map[string]bool)}
+	sc := &countingScanner{calls: make(map[string]int)}
+	newScanner := func() indexer.LayerScanner {
+		ls, err := New(ctx, 1, &indexer.Opts{
+			Store: store,
+			Ecosystems: []*indexer.Ecosystem{{
+				Name: "test-ecosystem",
+				PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+					return []indexer.PackageScanner{sc}, nil
+				},
+				DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+				RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ls
+	}
+	a, b := newScanner(), newScanner()
+
+	if err := a.Scan(ctx, test.RandomSHA256Digest(t), layers[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Scan(ctx, test.RandomSHA256Digest(t), layers[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Scan(ctx, test.RandomSHA256Digest(t), layers); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		if got, want := sc.calls[l.Hash.String()], 1; got != want {
+			t.Errorf("layer %v: scanned %d times, want %d", l.Hash, got, want)
+		}
+	}
+	if got, want := store.queries, 3; got != want {
+		t.Errorf("got %d store queries, want %d", got, want)
+	}
+}
+
+// PackagesScanner is a package scanner that finds the same packages in every
+// layer.
+type packagesScanner struct {
+	countingScanner
+}
+
+func (*packagesScanner) Name() string { return "packages" }
+func (s *packagesScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+	s.countingScanner.Scan(ctx, l)
+	return test.GenUniquePackages(2), nil
+}
+
+// TestScanCost checks the cost a layerScanner reports covers the scans it
+// ran, and nothing for layers the store already had results for.
+func TestScanCost(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	layers := test.ServeLayers(ctx, t, 3)
+	store := &sharedStore{scanned: make(map[string]bool)}
+	sc := &packagesScanner{countingScanner{calls: make(map[string]int)}}
+	newScanner := func() indexer.LayerScanner {
+		ls, err := New(ctx, 1, &indexer.Opts{
+			Store: store,
+			Ecosystems: []*indexer.Ecosystem{{
+				Name: "test-ecosystem",
+				PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+					return []indexer.PackageScanner{sc}, nil
+				},
+				DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+				RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ls
+	}
+	cost := func(ls indexer.LayerScanner) *claircore.IndexCost {
+		var c claircore.IndexCost
+		ls.(indexer.CostReporter).AddCost(&c)
+		return &c
+	}
+
+	a := newScanner()
+	if err := a.Scan(ctx, test.RandomSHA256Digest(t), layers); err != nil {
+		t.Fatal(err)
+	}
+	c := cost(a)
+	if got, want := c.StoreRows, int64(6); got != want {
+		t.Errorf("store rows: got: %d, want: %d", got, want)
+	}
+	if len(c.Scanners) != 1 {
+		t.Fatalf("scanners: got: %+v, want one", c.Scanners)
+	}
+	if got, want := c.Scanners[0], (claircore.ScannerCost{Name: "packages", Version: "1", Kind: "package", Layers: 3}); got.Elapsed <= 0 ||
+		got.Name != want.Name || got.Version != want.Version || got.Kind != want.Kind || got.Layers != want.Layers {
+		t.Errorf("scanner cost: got: %+v, want: %+v with some time elapsed", got, want)
+	}
+
+	b := newScanner()
+	if err := b.Scan(ctx, test.RandomSHA256Digest(t), layers); err != nil {
+		t.Fatal(err)
+	}
+	if c := cost(b); c.StoreRows != 0 || len(c.Scanners) != 0 {
+		t.Errorf("repeat scan: unexpected cost: %+v", c)
+	}
+}
+
+// ConfiguredScanner is a configurable countingScanner that records the
+// configuration it was given.
+type configuredScanner struct {
+	countingScanner
+	cfg struct {
+		Greeting string
+	}
+}
+
+func (*configuredScanner) Name() string { return "configured" }
+
+func (s *configuredScanner) Configure(_ context.Context, f indexer.ConfigDeserializer) error {
+	return f(&s.cfg)
+}
+
+// TestScanComposedEcosystem adds a scanner to an in-tree ecosystem and checks
+// that it's configured by name and run alongside the ecosystem's own scanners.
+func TestScanComposedEcosystem(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// The in-tree scanners open the layers, so they need to be on disk.
+	layers := make([]*claircore.Layer, 2)
+	for i := range layers {
+		f, err := ioutil.TempFile("", "layer.*.tar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(f.Name()) })
+		if err := tar.NewWriter(f).Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		layers[i] = &claircore.Layer{Hash: test.RandomSHA256Digest(t)}
+		if err := layers[i].SetLocal(f.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := &sharedStore{scanned: make(map[string]bool)}
+	sc := &configuredScanner{countingScanner: countingScanner{calls: make(map[string]int)}}
+	opts := &indexer.Opts{
+		Store:      store,
+		Ecosystems: []*indexer.Ecosystem{dpkg.NewEcosystem(ctx).WithAdditionalScanners(sc)},
+	}
+	opts.ScannerConfig.Package = map[string]func(interface{}) error{
+		"configured": func(v interface{}) error {
+			v.(*struct{ Greeting string }).Greeting = "hello"
+			return nil
+		},
+	}
+	ls, err := New(ctx, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sc.cfg.Greeting, "hello"; got != want {
+		t.Errorf("got config %q, want %q", got, want)
+	}
+	if err := ls.Scan(ctx, test.RandomSHA256Digest(t), layers); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		if got, want := sc.calls[l.Hash.String()], 1; got != want {
+			t.Errorf("layer %v: scanned %d times, want %d", l.Hash, got, want)
+		}
+		// The ecosystem's own scanners ran, too.
+		if !store.scanned[store.key(l.Hash, &dpkg.Scanner{})] {
+			t.Errorf("layer %v: not scanned by the dpkg scanner", l.Hash)
+		}
+	}
+}
@@ -6,16 +6,23 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
 )
 
 type Coalescer struct {
 	// the IndexReport this Coalescer is working on
 	ir *claircore.IndexReport
+	// generates package URLs, if not nil
+	purl purl.Generator
 }
 
 // NewCoalescer is a constructor for a Coalescer
-func NewCoalescer() *Coalescer {
+//
+// The provided Generator is used to populate the PURL field of packages, and
+// may be nil.
+func NewCoalescer(g purl.Generator) *Coalescer {
 	return &Coalescer{
+		purl: g,
 		ir: &claircore.IndexReport{
 			// we will only fill these fields
 			Environments:  map[string][]*claircore.Environment{},
@@ -33,10 +40,12 @@ func (c *Coalescer) Coalesce(ctx context.Context, layerArtifacts []*indexer.Laye
 	distSearcher := NewDistSearcher(layerArtifacts)
 	packageSearcher := NewPackageSearcher(layerArtifacts)
 
-	// get all dists the seacher knows about
-	dists := distSearcher.Dists()
-	for _, dist := range dists {
-		c.ir.Distributions[dist.ID] = dist
+	// record the dists in layer order, so the first layer each is found in
+	// is recorded as introducing it
+	for _, a := range layerArtifacts {
+		if len(a.Dist) > 0 {
+			c.ir.AddDistribution(a.Dist[0], a.Hash)
+		}
 	}
 
 	// walk layers backwards, grouping packages by package databases the first time we see them.
@@ -83,6 +92,9 @@ func (c *Coalescer) Coalesce(ctx context.Context, layerArtifacts []*indexer.Laye
 			}
 			env.IntroducedIn = *introDigest
 			env.PackageDB = db
+			if c.purl != nil {
+				pkg.PURL = c.purl(pkg, dist, nil)
+			}
 
 			// pack ir
 			c.ir.Packages[pkg.ID] = pkg
@@ -9,6 +9,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
 	"github.com/quay/claircore/test"
 )
 
@@ -85,4 +86,68 @@ func TestCoalescer(t *testing.T) {
 			t.Fatalf("expected distribution id %d but got %s", 2, environment.DistributionID)
 		}
 	}
+	// and each dist to be introduced in the layer it was found in
+	for id, l := range map[string]int{"1": 2, "2": 4} {
+		if got, want := ir.DistributionsIntroducedIn[id], layerArtifacts[l].Hash; got.String() != want.String() {
+			t.Errorf("distribution %s: introduced in %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestCoalescerPURL(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	coalescer := NewCoalescer(purl.Deb)
+	pkg := &claircore.Package{
+		ID:        "1",
+		Name:      "curl",
+		Version:   "7.64.0-4+deb10u1",
+		Arch:      "amd64",
+		PackageDB: "var/lib/dpkg/status",
+	}
+	dist := &claircore.Distribution{
+		ID:              "1",
+		DID:             "debian",
+		VersionID:       "10",
+		VersionCodeName: "buster",
+	}
+	layerArtifacts := []*indexer.LayerArtifacts{
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{pkg},
+			Dist: []*claircore.Distribution{dist},
+		},
+	}
+	ir, err := coalescer.Coalesce(ctx, layerArtifacts)
+	if err != nil {
+		t.Fatalf("received error from coalesce method: %v", err)
+	}
+	got, want := ir.Packages["1"].PURL, "pkg:deb/debian/curl@7.64.0-4%2Bdeb10u1?arch=amd64&distro=buster"
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestCoalescerKit(t *testing.T) {
+	ctx := context.Background()
+	tc := test.CoalescerTestcase{
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return NewCoalescer(purl.Deb), nil
+		},
+		PackageDB: "var/lib/dpkg/status",
+		Distribution: &claircore.Distribution{
+			ID:              "1",
+			DID:             "debian",
+			VersionID:       "10",
+			VersionCodeName: "buster",
+		},
+		// The newest copy of a package database is the only one reported.
+		Check: func(t *testing.T, set string, _ []*indexer.LayerArtifacts, ir *claircore.IndexReport) {
+			if set == "Removal" {
+				if _, ok := ir.Packages["b-1.0.0"]; ok {
+					t.Error("removed package reported")
+				}
+			}
+		},
+	}
+	t.Run("Kit", tc.Run(ctx))
 }
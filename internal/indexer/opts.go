@@ -8,15 +8,21 @@ import (
 
 // Opts are options to instantiate a indexer
 type Opts struct {
-	Store         Store
-	ScanLock      distlock.Locker
-	LayerScanner  LayerScanner
-	Fetcher       Fetcher
-	Ecosystems    []*Ecosystem
-	Vscnrs        VersionedScanners
+	Store        Store
+	ScanLock     distlock.Locker
+	LayerScanner LayerScanner
+	Fetcher      Fetcher
+	Ecosystems   []*Ecosystem
+	Vscnrs       VersionedScanners
+	// State is the indexer state recorded in IndexReports, as reported by
+	// Libindex.State.
+	State         string
 	Airgap        bool
 	Client        *http.Client
 	ScannerConfig struct {
 		Package, Dist, Repo map[string]func(interface{}) error
 	}
+	// InventoryOnly marks IndexReports as missing the artifacts needed for
+	// vulnerability matching. See InventoryEcosystems.
+	InventoryOnly bool
 }
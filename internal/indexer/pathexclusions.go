@@ -0,0 +1,55 @@
+package indexer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PathExclusions is a list of patterns naming files a scanner should skip,
+// such as test fixtures and documentation that happen to look like package
+// metadata.
+//
+// Patterns use the syntax of path.Match and are matched against paths
+// relative to the root of the layer, so "usr/share/doc" and "/usr/share/doc"
+// are the same pattern. A pattern matching a directory excludes everything
+// beneath it; "*/node_modules" excludes "srv/node_modules/a/package.json".
+//
+// The zero value excludes nothing.
+type PathExclusions []string
+
+// Validate reports an error if any of the patterns are malformed.
+func (p PathExclusions) Validate() error {
+	for _, pat := range p {
+		if _, err := path.Match(strings.TrimPrefix(pat, "/"), ""); err != nil {
+			return fmt.Errorf("indexer: bad exclusion pattern %q: %w", pat, err)
+		}
+	}
+	return nil
+}
+
+// Excluded reports whether the file "name", as named in a layer's tar
+// stream, matches one of the patterns.
+//
+// Malformed patterns never match.
+func (p PathExclusions) Excluded(name string) bool {
+	if len(p) == 0 {
+		return false
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	for _, pat := range p {
+		pat = strings.TrimPrefix(pat, "/")
+		// Check the name and each of its parent directories.
+		for rest := name; rest != ""; {
+			if ok, _ := path.Match(pat, rest); ok {
+				return true
+			}
+			i := strings.LastIndexByte(rest, '/')
+			if i < 0 {
+				break
+			}
+			rest = rest[:i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package indexer
+
+import "testing"
+
+func TestPathExclusions(t *testing.T) {
+	p := PathExclusions{
+		"/usr/share/doc",
+		"*/node_modules",
+		"opt/*/test/fixtures",
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		name string
+		want bool
+	}{
+		{"usr/share/doc/pkg/METADATA", true},
+		{"./usr/share/doc/pkg/METADATA", true},
+		{"/usr/share/doc", true},
+		{"usr/share/docs/pkg/METADATA", false},
+		{"srv/node_modules/a/package.json", true},
+		{"srv/app/node_modules/a/package.json", false},
+		{"opt/app/test/fixtures/lib.jar", true},
+		{"opt/app/lib/lib.jar", false},
+		{"usr/lib/python3.8/site-packages/a.dist-info/METADATA", false},
+	}
+	for _, tc := range tt {
+		if got := p.Excluded(tc.name); got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if PathExclusions(nil).Excluded("usr/share/doc") {
+		t.Error("zero value excluded a path")
+	}
+	if err := (PathExclusions{"usr/[share"}).Validate(); err == nil {
+		t.Error("malformed pattern accepted")
+	}
+}
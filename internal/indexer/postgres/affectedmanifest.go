@@ -96,6 +96,7 @@ FROM
 			manifest_index.manifest_id = manifest.id
 WHERE
 	package_id = $1
+	AND manifest.namespace = $4
 	AND (
 			CASE
 			WHEN $2::INT8 IS NULL THEN dist_id IS NULL
@@ -220,6 +221,7 @@ WHERE
 			record.Package.ID,
 			v[2],
 			v[3],
+			s.namespace,
 		)
 		switch {
 		case errors.Is(err, nil):
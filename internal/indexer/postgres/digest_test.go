@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestDigestCase checks that digests differing only in case or surrounding
+// whitespace resolve to the same stored manifest and layer.
+func TestDigestCase(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	s := NewStore(pool)
+
+	variants := []string{
+		`sha256:fc92eec5cac70b0c324cec2933cd7db1c0eae7c9e2649e42d02e77eb6da0d15f`,
+		`SHA256:FC92EEC5CAC70B0C324CEC2933CD7DB1C0EAE7C9E2649E42D02E77EB6DA0D15F`,
+		" sha256:FC92eec5cac70b0c324cec2933cd7db1c0eae7c9e2649e42d02e77eb6da0d15f\n",
+	}
+	layers := []string{
+		`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`,
+		`SHA256:5F70BF18A086007016E948B04AED3B82103A36BEA41755B6CDDFAF10ACE3C6EF`,
+		"\tsha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3C6EF ",
+	}
+	for i := range variants {
+		m := claircore.Manifest{
+			Hash:   claircore.MustParseDigest(variants[i]),
+			Layers: []*claircore.Layer{{Hash: claircore.MustParseDigest(layers[i])}},
+		}
+		if err := s.PersistManifest(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, table := range []string{"manifest", "layer"} {
+		var n int
+		if err := pool.QueryRow(ctx, `SELECT count(*) FROM `+table+`;`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("%s: got %d rows, want 1", table, n)
+		}
+	}
+
+	ir := &claircore.IndexReport{
+		Hash:  claircore.MustParseDigest(variants[1]),
+		State: "finished",
+	}
+	if err := s.SetIndexReport(ctx, ir); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range variants {
+		got, ok, err := s.IndexReport(ctx, claircore.MustParseDigest(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("%q: report not found", v)
+			continue
+		}
+		if got.Hash.String() != variants[0] {
+			t.Errorf("%q: got report for %v", v, got.Hash)
+		}
+	}
+}
@@ -58,7 +58,7 @@ func (s *store) DistributionsByLayer(ctx context.Context, hash claircore.Digest,
 			   dist.pretty_name
 		FROM dist_scanartifact
 				 LEFT JOIN dist ON dist_scanartifact.dist_id = dist.id
-				 JOIN layer ON layer.hash = $1
+				 JOIN layer ON layer.hash = $1 AND layer.namespace = $3
 		WHERE dist_scanartifact.layer_id = layer.id
 		  AND dist_scanartifact.scanner_id = ANY($2);
 		`
@@ -82,7 +82,7 @@ func (s *store) DistributionsByLayer(ctx context.Context, hash claircore.Digest,
 	}
 
 	start := time.Now()
-	rows, err := s.pool.Query(ctx, query, hash, scannerIDs)
+	rows, err := s.pool.Query(ctx, query, hash, scannerIDs, s.namespace)
 	switch {
 	case errors.Is(err, nil):
 	case errors.Is(err, pgx.ErrNoRows):
@@ -205,6 +205,7 @@ func (e *e2e) Run(t *testing.T) {
 		{"LayerScanned", e.LayerScanned},
 		{"LayerScannedNotExists", e.LayerScannedNotExists},
 		{"LayerScannedFalse", e.LayerScannedFalse},
+		{"LayersScanned", e.LayersScanned},
 		{"IndexReport", e.IndexReport},
 	}
 	for _, subtest := range subtests {
@@ -362,6 +363,35 @@ func (e *e2e) LayerScannedFalse(t *testing.T) {
 	}
 }
 
+// LayersScanned confirms the batched lookup agrees with LayerScanned,
+// including for unknown layers and scanners.
+func (e *e2e) LayersScanned(t *testing.T) {
+	ctx := zlog.Test(e.ctx, t)
+	hashes := []claircore.Digest{
+		claircore.MustParseDigest(`sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03`),
+		e.manifest.Layers[0].Hash,
+	}
+	scnrs := append(indexer.VersionedScanners{
+		mockScnr{name: "invalid", kind: "invalid", version: "invalid"},
+	}, e.scnrs...)
+
+	got, err := e.store.LayersScanned(ctx, hashes, scnrs)
+	if err != nil {
+		t.Fatalf("failed to query if layers are scanned: %v", err)
+	}
+	if len(got) != len(hashes) {
+		t.Fatalf("got %d results, want %d", len(got), len(hashes))
+	}
+	for i, h := range hashes {
+		for j, scnr := range scnrs {
+			want := i == 1 && j != 0
+			if got[i][j] != want {
+				t.Errorf("layer %v, scanner %v: got %v, want %v", h, scnr, got[i][j], want)
+			}
+		}
+	}
+}
+
 // IndexReport confirms the book keeping around index reports works
 // correctly.
 func (e *e2e) IndexReport(t *testing.T) {
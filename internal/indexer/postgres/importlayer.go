@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	importLayerCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "importlayer_total",
+			Help:      "Total number of database queries issued in the ImportLayer method.",
+		},
+		[]string{"query"},
+	)
+
+	importLayerDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "importlayer_duration_seconds",
+			Help:      "The duration of all queries issued in the ImportLayer method",
+		},
+		[]string{"query"},
+	)
+)
+
+// ImportLayer implements indexer.Setter.
+//
+// Each scanner's artifacts are indexed before the layer is marked scanned by
+// it, so an import that fails partway leaves the remaining scanners to scan
+// the layer normally.
+func (s *store) ImportLayer(ctx context.Context, e *indexer.LayerEnvelope) error {
+	const insertLayer = `
+INSERT INTO layer (hash, namespace)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/postgres/ImportLayer"),
+		label.String("layer", e.Hash.String()))
+
+	start := time.Now()
+	if _, err := s.pool.Exec(ctx, insertLayer, e.Hash, s.namespace); err != nil {
+		return fmt.Errorf("store:importLayer failed to insert layer: %w", err)
+	}
+	importLayerCounter.WithLabelValues("insertLayer").Add(1)
+	importLayerDuration.WithLabelValues("insertLayer").Observe(time.Since(start).Seconds())
+
+	l := &claircore.Layer{Hash: e.Hash}
+	for _, a := range e.Artifacts {
+		var err error
+		switch a.Kind() {
+		case "package":
+			if len(a.Packages) != 0 {
+				err = s.IndexPackages(ctx, a.Packages, l, a)
+			}
+		case "distribution":
+			if len(a.Distributions) != 0 {
+				err = s.IndexDistributions(ctx, a.Distributions, l, a)
+			}
+		case "repository":
+			if len(a.Repositories) != 0 {
+				err = s.IndexRepositories(ctx, a.Repositories, l, a)
+			}
+		default:
+			err = fmt.Errorf("unknown scanner kind %q", a.Kind())
+		}
+		if err != nil {
+			return fmt.Errorf("store:importLayer failed to index artifacts for scanner %q: %w", a.Name(), err)
+		}
+		if err := s.SetLayerScanned(ctx, e.Hash, a); err != nil {
+			return fmt.Errorf("store:importLayer: %w", err)
+		}
+		zlog.Debug(ctx).
+			Str("scanner", a.Name()).
+			Str("kind", a.Kind()).
+			Msg("imported artifacts")
+	}
+	return nil
+}
@@ -68,6 +68,7 @@ func (s *store) IndexDistributions(ctx context.Context, dists []*claircore.Distr
 				 SELECT id AS layer_id
 				 FROM layer
 				 WHERE layer.hash = $12
+				   AND layer.namespace = $13
 			 )
 		INSERT
 		INTO dist_scanartifact (layer_id, dist_id, scanner_id)
@@ -142,6 +143,7 @@ func (s *store) IndexDistributions(ctx context.Context, dists []*claircore.Distr
 			scnr.Version(),
 			scnr.Kind(),
 			layer.Hash,
+			s.namespace,
 		)
 		if err != nil {
 			return fmt.Errorf("batch insert failed for dist_scanartifact %v: %v", dist, err)
@@ -45,6 +45,7 @@ func (s *store) IndexManifest(ctx context.Context, ir *claircore.IndexReport) er
 			SELECT id AS manifest_id
 			FROM manifest
 			WHERE hash = $4
+			  AND namespace = $5
 		)
 		INSERT
 		INTO manifest_index(package_id, dist_id, repo_id, manifest_id)
@@ -100,6 +101,7 @@ func (s *store) IndexManifest(ctx context.Context, ir *claircore.IndexReport) er
 				v[2],
 				v[3],
 				hash,
+				s.namespace,
 			)
 			if err != nil {
 				return fmt.Errorf("batch insert failed for source package record %v: %v", record, err)
@@ -113,6 +115,7 @@ func (s *store) IndexManifest(ctx context.Context, ir *claircore.IndexReport) er
 			v[2],
 			v[3],
 			hash,
+			s.namespace,
 		)
 		if err != nil {
 			return fmt.Errorf("batch insert failed for package record %v: %v", record, err)
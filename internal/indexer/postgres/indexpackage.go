@@ -47,7 +47,10 @@ var (
 // source package.
 //
 // Scan artifacts are used to determine if a particular layer has been scanned by a
-// particular scanner. See the LayerScanned method for more details.
+// particular scanner. See the LayerScanned method for more details. They also
+// hold the files recorded for a package; if the same package is found more
+// than once in a package database, the files are merged, unless one of the
+// copies has none recorded, and the first license recorded is kept.
 func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, layer *claircore.Layer, scnr indexer.VersionedScanner) error {
 	const (
 		insert = ` 
@@ -86,16 +89,24 @@ func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, la
 				 SELECT id AS layer_id
 				 FROM layer
 				 WHERE layer.hash = $14
+				   AND layer.namespace = $17
 			 )
 		INSERT
-		INTO package_scanartifact (layer_id, package_db, repository_hint, package_id, source_id, scanner_id)
+		INTO package_scanartifact (layer_id, package_db, repository_hint, package_id, source_id, scanner_id, files, license)
 		VALUES ((SELECT layer_id FROM layer),
 				$15,
 				$16,
 				(SELECT package_id FROM binary_package),
 				(SELECT source_id FROM source_package),
-				(SELECT scanner_id FROM scanner))
-		ON CONFLICT DO NOTHING;
+				(SELECT scanner_id FROM scanner),
+				$18,
+				NULLIF($19, ''))
+		ON CONFLICT (layer_id, package_id, source_id, scanner_id, package_db, repository_hint) DO UPDATE
+		SET files = CASE
+			WHEN EXCLUDED.files IS NULL OR package_scanartifact.files IS NULL THEN NULL
+			ELSE ARRAY(SELECT DISTINCT unnest(package_scanartifact.files || EXCLUDED.files))
+			END,
+			license = COALESCE(package_scanartifact.license, EXCLUDED.license);
 		`
 	)
 
@@ -180,6 +191,9 @@ func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, la
 			layer.Hash,
 			pkg.PackageDB,
 			pkg.RepositoryHint,
+			s.namespace,
+			pkg.Files,
+			pkg.License,
 		)
 		if err != nil {
 			return fmt.Errorf("batch insert failed for package_scanartifact %v: %v", pkg, err)
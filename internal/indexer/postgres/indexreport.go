@@ -19,7 +19,7 @@ var (
 			Namespace: "claircore",
 			Subsystem: "indexer",
 			Name:      "indexreport_total",
-			Help:      "Total number of database queries issued in the IndexReport method.",
+			Help:      "Total number of database queries issued in the IndexReport and IndexReportState methods.",
 		},
 		[]string{"query"},
 	)
@@ -29,7 +29,7 @@ var (
 			Namespace: "claircore",
 			Subsystem: "indexer",
 			Name:      "indexreport_duration_seconds",
-			Help:      "The duration of all queries issued in the IndexReport method",
+			Help:      "The duration of all queries issued in the IndexReport and IndexReportState methods",
 		},
 		[]string{"query"},
 	)
@@ -39,7 +39,7 @@ func (s *store) IndexReport(ctx context.Context, hash claircore.Digest) (*clairc
 	const query = `
 	SELECT scan_result
 	FROM indexreport
-			 JOIN manifest ON manifest.hash = $1
+			 JOIN manifest ON manifest.hash = $1 AND manifest.namespace = $2
 	WHERE indexreport.manifest_id = manifest.id;
 	`
 	// we scan into a jsonbIndexReport which has value/scan method set
@@ -47,7 +47,7 @@ func (s *store) IndexReport(ctx context.Context, hash claircore.Digest) (*clairc
 	var jsr jsonbIndexReport
 
 	start := time.Now()
-	err := s.pool.QueryRow(ctx, query, hash).Scan(&jsr)
+	err := s.pool.QueryRow(ctx, query, hash, s.namespace).Scan(&jsr)
 	switch {
 	case errors.Is(err, nil):
 	case errors.Is(err, pgx.ErrNoRows):
@@ -62,3 +62,28 @@ func (s *store) IndexReport(ctx context.Context, hash claircore.Digest) (*clairc
 	sr = claircore.IndexReport(jsr)
 	return &sr, true, nil
 }
+
+func (s *store) IndexReportState(ctx context.Context, hash claircore.Digest) (string, bool, error) {
+	const query = `
+	SELECT COALESCE(scan_result->>'indexer_state', ''),
+		COALESCE((scan_result->>'success')::boolean, false) AND scan_result->>'state' = 'IndexFinished'
+	FROM indexreport
+			 JOIN manifest ON manifest.hash = $1 AND manifest.namespace = $2
+	WHERE indexreport.manifest_id = manifest.id;
+	`
+	var state string
+	var finished bool
+
+	start := time.Now()
+	err := s.pool.QueryRow(ctx, query, hash, s.namespace).Scan(&state, &finished)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, pgx.ErrNoRows):
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("store:indexReportState failed to retrieve index report state: %v", err)
+	}
+	indexReportCounter.WithLabelValues("state").Add(1)
+	indexReportDuration.WithLabelValues("state").Observe(time.Since(start).Seconds())
+	return state, finished, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestIndexReportState(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	s := NewStore(pool)
+
+	m := claircore.Manifest{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))}
+	if err := s.PersistManifest(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if state, finished, err := s.IndexReportState(ctx, m.Hash); err != nil || finished || state != "" {
+		t.Fatalf("got (%q, %v, %v), want no report", state, finished, err)
+	}
+
+	tt := []struct {
+		name     string
+		report   claircore.IndexReport
+		finished bool
+	}{
+		{name: "InProgress", report: claircore.IndexReport{State: "ScanLayers", IndexerState: "state"}},
+		{name: "Failed", report: claircore.IndexReport{State: "IndexError", IndexerState: "state"}},
+		{name: "Finished", report: claircore.IndexReport{State: "IndexFinished", Success: true, IndexerState: "state"}, finished: true},
+		{name: "NoState", report: claircore.IndexReport{State: "IndexFinished", Success: true}, finished: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			ir := tc.report
+			ir.Hash = m.Hash
+			if err := s.SetIndexReport(ctx, &ir); err != nil {
+				t.Fatal(err)
+			}
+			state, finished, err := s.IndexReportState(ctx, m.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := state, ir.IndexerState; got != want {
+				t.Errorf("state: got: %q, want: %q", got, want)
+			}
+			if got, want := finished, tc.finished; got != want {
+				t.Errorf("finished: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
@@ -62,6 +62,7 @@ func (s *store) IndexRepositories(ctx context.Context, repos []*claircore.Reposi
 				 SELECT id AS layer_id
 				 FROM layer
 				 WHERE layer.hash = $7
+				   AND layer.namespace = $8
 			 )
 		INSERT
 		INTO repo_scanartifact (layer_id, repo_id, scanner_id)
@@ -127,6 +128,7 @@ func (s *store) IndexRepositories(ctx context.Context, repos []*claircore.Reposi
 			scnr.Version(),
 			scnr.Kind(),
 			l.Hash,
+			s.namespace,
 		)
 		if err != nil {
 			return fmt.Errorf("batch insert failed for repo_scanartifact %v: %v", repo, err)
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore"
+)
+
+var (
+	layerDiffIDCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "layerdiffid_total",
+			Help:      "Total number of database queries issued in the LayerDiffID and SetLayerDiffID methods.",
+		},
+		[]string{"query"},
+	)
+
+	layerDiffIDDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "layerdiffid_duration_seconds",
+			Help:      "The duration of all queries issued in the LayerDiffID and SetLayerDiffID methods",
+		},
+		[]string{"query"},
+	)
+)
+
+func (s *store) SetLayerDiffID(ctx context.Context, hash, diffID claircore.Digest) error {
+	const query = `
+UPDATE layer SET diff_id = $3 WHERE hash = $1 AND namespace = $2;
+`
+	start := time.Now()
+	if _, err := s.pool.Exec(ctx, query, hash, s.namespace, diffID); err != nil {
+		return fmt.Errorf("store:setLayerDiffID layer %v: %w", hash, err)
+	}
+	layerDiffIDCounter.WithLabelValues("update").Add(1)
+	layerDiffIDDuration.WithLabelValues("update").Observe(time.Since(start).Seconds())
+	return nil
+}
+
+func (s *store) LayerDiffID(ctx context.Context, hash claircore.Digest) (claircore.Digest, bool, error) {
+	const query = `
+SELECT diff_id FROM layer WHERE hash = $1 AND namespace = $2 AND diff_id IS NOT NULL;
+`
+	start := time.Now()
+	var diffID claircore.Digest
+	err := s.pool.QueryRow(ctx, query, hash, s.namespace).Scan(&diffID)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, pgx.ErrNoRows):
+		return diffID, false, nil
+	default:
+		return diffID, false, fmt.Errorf("store:layerDiffID layer %v: %w", hash, err)
+	}
+	layerDiffIDCounter.WithLabelValues("select").Add(1)
+	layerDiffIDDuration.WithLabelValues("select").Observe(time.Since(start).Seconds())
+	return diffID, true, nil
+}
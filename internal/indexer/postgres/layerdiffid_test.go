@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestLayerDiffID(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	store := NewStore(pool)
+
+	layer := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	diffID := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	m := claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64)),
+		Layers: []*claircore.Layer{{Hash: layer}},
+	}
+	if err := store.PersistManifest(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.LayerDiffID(ctx, layer); err != nil || ok {
+		t.Fatalf("got (%v, %v), want no diffID", ok, err)
+	}
+	if err := store.SetLayerDiffID(ctx, layer, diffID); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := store.LayerDiffID(ctx, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("no diffID recorded")
+	}
+	if got.String() != diffID.String() {
+		t.Errorf("got diffID %q, want %q", got, diffID)
+	}
+}
@@ -59,6 +59,7 @@ SELECT
 					scanned_layer.layer_id = layer.id
 		WHERE
 			layer.hash = $1
+			AND layer.namespace = $3
 			AND scanned_layer.scanner_id = $2
 	);
 `
@@ -81,7 +82,7 @@ SELECT
 	var ok bool
 
 	start = time.Now()
-	err = s.pool.QueryRow(ctx, selectScanned, hash.String(), scannerID).
+	err = s.pool.QueryRow(ctx, selectScanned, hash.String(), scannerID, s.namespace).
 		Scan(&ok)
 	if err != nil {
 		return false, err
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	layersScannedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "layersscanned_total",
+			Help:      "Total number of database queries issued in the LayersScanned method.",
+		},
+		[]string{"query"},
+	)
+
+	layersScannedDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "layersscanned_duration_seconds",
+			Help:      "The duration of all queries issued in the LayersScanned method",
+		},
+		[]string{"query"},
+	)
+)
+
+func (s *store) LayersScanned(ctx context.Context, hashes []claircore.Digest, scnrs indexer.VersionedScanners) ([][]bool, error) {
+	// Only the pairs that have been scanned come back, as 1-based indexes
+	// into the arguments. Unknown layers and scanners simply don't match.
+	const query = `
+SELECT
+	l.ord, s.ord
+FROM
+	unnest($1::TEXT[]) WITH ORDINALITY AS l (hash, ord)
+	JOIN layer ON
+			layer.hash = l.hash AND layer.namespace = $5
+	CROSS JOIN unnest($2::TEXT[], $3::TEXT[], $4::TEXT[])
+			WITH ORDINALITY AS s (name, version, kind, ord)
+	JOIN scanner ON
+			scanner.name = s.name
+			AND scanner.version = s.version
+			AND scanner.kind = s.kind
+	JOIN scanned_layer ON
+			scanned_layer.layer_id = layer.id
+			AND scanned_layer.scanner_id = scanner.id;
+`
+	out := make([][]bool, len(hashes))
+	for i := range out {
+		out[i] = make([]bool, len(scnrs))
+	}
+	if len(hashes) == 0 || len(scnrs) == 0 {
+		return out, nil
+	}
+	hs := make([]string, len(hashes))
+	for i, h := range hashes {
+		hs[i] = h.String()
+	}
+	names, versions, kinds := make([]string, len(scnrs)), make([]string, len(scnrs)), make([]string, len(scnrs))
+	for i, v := range scnrs {
+		names[i], versions[i], kinds[i] = v.Name(), v.Version(), v.Kind()
+	}
+
+	start := time.Now()
+	rows, err := s.pool.Query(ctx, query, hs, names, versions, kinds, s.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("store:layersScanned: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l, v int64
+		if err := rows.Scan(&l, &v); err != nil {
+			return nil, fmt.Errorf("store:layersScanned: %w", err)
+		}
+		out[l-1][v-1] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store:layersScanned: %w", err)
+	}
+	layersScannedCounter.WithLabelValues("query").Add(1)
+	layersScannedDuration.WithLabelValues("query").Observe(time.Since(start).Seconds())
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestLicense checks that package licenses survive a round trip through the
+// store, and that the first license recorded for a package is kept.
+func TestLicense(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	store := NewStore(pool)
+
+	layer := &claircore.Layer{Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))}
+	m := claircore.Manifest{
+		Hash:   claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64)),
+		Layers: []*claircore.Layer{layer},
+	}
+	scnr := mockScnr{name: "test-license", kind: "package", version: "v0.0.1"}
+	if err := store.PersistManifest(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RegisterScanners(ctx, indexer.VersionedScanners{scnr}); err != nil {
+		t.Fatal(err)
+	}
+
+	mk := func(name, license string) *claircore.Package {
+		return &claircore.Package{
+			Name:      name,
+			Version:   "1.0",
+			Kind:      claircore.BINARY,
+			PackageDB: "var/lib/dpkg/status",
+			License:   license,
+		}
+	}
+	pkgs := []*claircore.Package{
+		mk("spdx", "MIT OR Apache-2.0"),
+		mk("text", "The Apache Software License, Version 2.0"),
+		mk("none", ""),
+	}
+	if err := store.IndexPackages(ctx, pkgs, layer, scnr); err != nil {
+		t.Fatal(err)
+	}
+	// Found again in another pass, with a different license.
+	if err := store.IndexPackages(ctx, []*claircore.Package{mk("spdx", "MIT")}, layer, scnr); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := store.PackagesByLayer(ctx, layer.Hash, indexer.VersionedScanners{scnr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.License
+	}
+	want := map[string]string{
+		"spdx": "MIT OR Apache-2.0",
+		"text": "The Apache Software License, Version 2.0",
+		"none": "",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+var (
+	manifestsByDistributionCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "manifestsbydistribution_total",
+			Help:      "Total number of database queries issued in the ManifestsByDistribution and ManifestCountByDistribution methods.",
+		},
+		[]string{"query"},
+	)
+
+	manifestsByDistributionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "manifestsbydistribution_duration_seconds",
+			Help:      "The duration of all queries issued in the ManifestsByDistribution and ManifestCountByDistribution methods",
+		},
+		[]string{"query"},
+	)
+)
+
+// ManifestsByDistribution returns up to "limit" manifest digests indexed with
+// a distribution matching the provided os-release ID and VERSION_ID, ordered
+// by digest. An empty versionID matches every version of the distribution.
+//
+// The returned cursor should be passed to subsequent calls to retrieve the
+// next page. An empty cursor starts from the beginning, and an empty cursor is
+// returned once there are no more results.
+func (s *store) ManifestsByDistribution(ctx context.Context, did, versionID string, limit int, cursor string) ([]claircore.Digest, string, error) {
+	const query = `
+SELECT
+	DISTINCT manifest.hash
+FROM
+	manifest_index
+	JOIN manifest ON manifest_index.manifest_id = manifest.id
+	JOIN dist ON manifest_index.dist_id = dist.id
+WHERE
+	dist.did = $1
+	AND ($2 = '' OR dist.version_id = $2)
+	AND manifest.hash > $3
+	AND manifest.namespace = $5
+ORDER BY
+	manifest.hash
+LIMIT
+	$4;
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/postgres/ManifestsByDistribution"))
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	start := time.Now()
+	rows, err := s.pool.Query(ctx, query, did, versionID, cursor, limit, s.namespace)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query manifests: %w", err)
+	}
+	defer rows.Close()
+	out := make([]claircore.Digest, 0, limit)
+	for rows.Next() {
+		var d claircore.Digest
+		if err := rows.Scan(&d); err != nil {
+			return nil, "", fmt.Errorf("failed to scan manifest digest: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to query manifests: %w", err)
+	}
+	manifestsByDistributionCounter.WithLabelValues("query").Add(1)
+	manifestsByDistributionDuration.WithLabelValues("query").Observe(time.Since(start).Seconds())
+
+	var next string
+	if len(out) == limit {
+		next = out[len(out)-1].String()
+	}
+	return out, next, nil
+}
+
+// ManifestCountByDistribution reports the number of manifests indexed with a
+// distribution matching the provided os-release ID and VERSION_ID. An empty
+// versionID matches every version of the distribution.
+func (s *store) ManifestCountByDistribution(ctx context.Context, did, versionID string) (int64, error) {
+	const query = `
+SELECT
+	count(DISTINCT manifest_index.manifest_id)
+FROM
+	manifest_index
+	JOIN manifest ON manifest_index.manifest_id = manifest.id
+	JOIN dist ON manifest_index.dist_id = dist.id
+WHERE
+	dist.did = $1
+	AND ($2 = '' OR dist.version_id = $2)
+	AND manifest.namespace = $3;
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/postgres/ManifestCountByDistribution"))
+
+	start := time.Now()
+	var ct int64
+	if err := s.pool.QueryRow(ctx, query, did, versionID, s.namespace).Scan(&ct); err != nil {
+		return 0, fmt.Errorf("failed to count manifests: %w", err)
+	}
+	manifestsByDistributionCounter.WithLabelValues("count").Add(1)
+	manifestsByDistributionDuration.WithLabelValues("count").Observe(time.Since(start).Seconds())
+	return ct, nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestManifestsByDistribution(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	store := NewStore(pool)
+
+	const (
+		insertDist     = `INSERT INTO dist (did, version_id, name) VALUES ($1, $2, $3) RETURNING id;`
+		insertPkg      = `INSERT INTO package (name, version) VALUES ($1, $2) RETURNING id;`
+		insertManifest = `INSERT INTO manifest (hash) VALUES ($1) RETURNING id;`
+		insertIndex    = `INSERT INTO manifest_index (package_id, dist_id, manifest_id) VALUES ($1, $2, $3);`
+	)
+	dists := []struct {
+		DID, VersionID string
+		Count          int
+	}{
+		{"ubuntu", "18.04", 7},
+		{"ubuntu", "20.04", 3},
+		{"debian", "10", 5},
+	}
+	var pkg int64
+	if err := pool.QueryRow(ctx, insertPkg, "bash", "5.0").Scan(&pkg); err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string][]claircore.Digest)
+	n := 0
+	for _, d := range dists {
+		var dist int64
+		if err := pool.QueryRow(ctx, insertDist, d.DID, d.VersionID, d.DID).Scan(&dist); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < d.Count; i++ {
+			sum := sha256.Sum256([]byte(fmt.Sprint(n)))
+			n++
+			digest, err := claircore.NewDigest("sha256", sum[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m int64
+			if err := pool.QueryRow(ctx, insertManifest, digest.String()).Scan(&m); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pool.Exec(ctx, insertIndex, pkg, dist, m); err != nil {
+				t.Fatal(err)
+			}
+			k := d.DID + ":" + d.VersionID
+			want[k] = append(want[k], digest)
+			want[d.DID+":"] = append(want[d.DID+":"], digest)
+		}
+	}
+	for _, ds := range want {
+		sort.Slice(ds, func(i, j int) bool { return ds[i].String() < ds[j].String() })
+	}
+
+	table := []struct {
+		DID, VersionID string
+		Limit          int
+	}{
+		{"ubuntu", "18.04", 2},
+		{"ubuntu", "18.04", 7},
+		{"ubuntu", "20.04", 100},
+		{"debian", "10", 1},
+		{"ubuntu", "", 3},
+		{"alpine", "3.12", 3},
+	}
+	for _, tc := range table {
+		name := fmt.Sprintf("%s:%s/%d", tc.DID, tc.VersionID, tc.Limit)
+		t.Run(name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			exp := want[tc.DID+":"+tc.VersionID]
+
+			ct, err := store.ManifestCountByDistribution(ctx, tc.DID, tc.VersionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ct, int64(len(exp)); got != want {
+				t.Errorf("count: got: %d, want: %d", got, want)
+			}
+
+			var got []claircore.Digest
+			var cursor string
+			for pages := 0; ; pages++ {
+				if pages > len(exp) {
+					t.Fatal("too many pages")
+				}
+				ds, next, err := store.ManifestsByDistribution(ctx, tc.DID, tc.VersionID, tc.Limit, cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ds) > tc.Limit {
+					t.Errorf("page too large: got: %d, limit: %d", len(ds), tc.Limit)
+				}
+				got = append(got, ds...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if !cmp.Equal(got, exp, cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })) {
+				t.Errorf("got: %v, want: %v", got, exp)
+			}
+		})
+	}
+}
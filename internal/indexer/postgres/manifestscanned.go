@@ -41,7 +41,8 @@ func (s *store) ManifestScanned(ctx context.Context, hash claircore.Digest, vs i
 		SELECT scanner_id
 		FROM scanned_manifest
 				 JOIN manifest ON scanned_manifest.manifest_id = manifest.id
-		WHERE manifest.hash = $1;
+		WHERE manifest.hash = $1
+		  AND manifest.namespace = $2;
 		`
 	)
 
@@ -55,7 +56,7 @@ func (s *store) ManifestScanned(ctx context.Context, hash claircore.Digest, vs i
 	var foundIDs = map[int64]struct{}{}
 
 	start := time.Now()
-	rows, err := s.pool.Query(ctx, selectScanned, hash)
+	rows, err := s.pool.Query(ctx, selectScanned, hash, s.namespace)
 	if err != nil {
 		return false, fmt.Errorf("store:manifestScanned failed to select scanner IDs for manifest: %v", err)
 	}
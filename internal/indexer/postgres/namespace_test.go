@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestNamespace checks that stores in different namespaces sharing a database
+// don't see each other's manifests, layers, or index reports.
+func TestNamespace(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	pool := TestDatabase(ctx, t)
+	a := NewStore(pool, WithNamespace("tenant-a"))
+	b := NewStore(pool, WithNamespace("tenant-b"))
+
+	scnrs := test.GenUniquePackageScanners(1)
+	if err := a.RegisterScanners(ctx, scnrs); err != nil {
+		t.Fatal(err)
+	}
+	layer := &claircore.Layer{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+	}
+	manifest := claircore.Manifest{
+		Hash:   claircore.MustParseDigest(`sha256:fc92eec5cac70b0c324cec2933cd7db1c0eae7c9e2649e42d02e77eb6da0d15f`),
+		Layers: []*claircore.Layer{layer},
+	}
+	// The same manifest in both namespaces must not conflict.
+	for _, s := range []*store{a, b} {
+		if err := s.PersistManifest(ctx, manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs := test.GenUniquePackages(10)
+	if err := a.IndexPackages(ctx, pkgs, layer, scnrs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetLayerScanned(ctx, layer.Hash, scnrs[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		s    *store
+		want int
+	}{
+		{a, len(pkgs)},
+		{b, 0},
+	} {
+		ok, err := tc.s.LayerScanned(ctx, layer.Hash, scnrs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tc.want != 0; ok != want {
+			t.Errorf("%s: layer scanned: got %v, want %v", tc.s.namespace, ok, want)
+		}
+		got, err := tc.s.PackagesByLayer(ctx, layer.Hash, scnrs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tc.want {
+			t.Errorf("%s: got %d packages, want %d", tc.s.namespace, len(got), tc.want)
+		}
+	}
+
+	ir := &claircore.IndexReport{
+		Hash:  manifest.Hash,
+		State: "tenant-a",
+	}
+	if err := a.SetIndexFinished(ctx, ir, scnrs); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := b.IndexReport(ctx, manifest.Hash); err != nil || ok {
+		t.Errorf("found report from another namespace (%v)", err)
+	}
+	if ok, err := b.ManifestScanned(ctx, manifest.Hash, scnrs); err != nil || ok {
+		t.Errorf("manifest scanned in another namespace (%v)", err)
+	}
+	if err := b.SetIndexReport(ctx, &claircore.IndexReport{Hash: manifest.Hash, State: "tenant-b"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*store{a, b} {
+		got, ok, err := s.IndexReport(ctx, manifest.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("%s: report not found", s.namespace)
+		}
+		if got.State != s.namespace {
+			t.Errorf("%s: got report %q", s.namespace, got.State)
+		}
+	}
+}
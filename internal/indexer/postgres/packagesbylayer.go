@@ -65,7 +65,9 @@ SELECT
 	source_package.module,
 	source_package.arch,
 	package_scanartifact.package_db,
-	package_scanartifact.repository_hint
+	package_scanartifact.repository_hint,
+	package_scanartifact.files,
+	COALESCE(package_scanartifact.license, '')
 FROM
 	package_scanartifact
 	LEFT JOIN package ON
@@ -73,7 +75,7 @@ FROM
 	LEFT JOIN package AS source_package ON
 			package_scanartifact.source_id
 			= source_package.id
-	JOIN layer ON layer.hash = $1
+	JOIN layer ON layer.hash = $1 AND layer.namespace = $3
 WHERE
 	package_scanartifact.layer_id = layer.id
 	AND package_scanartifact.scanner_id = ANY ($2);
@@ -98,7 +100,7 @@ WHERE
 	}
 
 	start := time.Now()
-	rows, err := s.pool.Query(ctx, query, hash, scannerIDs)
+	rows, err := s.pool.Query(ctx, query, hash, scannerIDs, s.namespace)
 	switch {
 	case errors.Is(err, nil):
 	case errors.Is(err, pgx.ErrNoRows):
@@ -137,6 +139,8 @@ WHERE
 
 			&pkg.PackageDB,
 			&pkg.RepositoryHint,
+			&pkg.Files,
+			&pkg.License,
 		)
 		pkg.ID = strconv.FormatInt(id, 10)
 		spkg.ID = strconv.FormatInt(srcID, 10)
@@ -35,13 +35,13 @@ var (
 func (s *store) PersistManifest(ctx context.Context, manifest claircore.Manifest) error {
 	const (
 		insertManifest = `
-		INSERT INTO manifest (hash)
-		VALUES ($1)
+		INSERT INTO manifest (hash, namespace)
+		VALUES ($1, $2)
 		ON CONFLICT DO NOTHING;
 		`
 		insertLayer = `
-		INSERT INTO layer (hash)
-		VALUES ($1)
+		INSERT INTO layer (hash, namespace)
+		VALUES ($1, $2)
 		ON CONFLICT DO NOTHING;
 		`
 		insertManifestLayer = `
@@ -49,11 +49,13 @@ func (s *store) PersistManifest(ctx context.Context, manifest claircore.Manifest
 			SELECT id AS manifest_id
 			FROM manifest
 			WHERE hash = $1
+			  AND namespace = $4
 		),
 			 layers AS (
 				 SELECT id AS layer_id
 				 FROM layer
 				 WHERE hash = $2
+				   AND namespace = $4
 			 )
 		INSERT
 		INTO manifest_layer (manifest_id, layer_id, i)
@@ -71,7 +73,7 @@ func (s *store) PersistManifest(ctx context.Context, manifest claircore.Manifest
 	defer tx.Rollback(ctx)
 
 	start := time.Now()
-	_, err = tx.Exec(ctx, insertManifest, manifest.Hash)
+	_, err = tx.Exec(ctx, insertManifest, manifest.Hash, s.namespace)
 	if err != nil {
 		return fmt.Errorf("postgres:persistManifest: failed to insert manifest: %v", err)
 	}
@@ -81,7 +83,7 @@ func (s *store) PersistManifest(ctx context.Context, manifest claircore.Manifest
 	for i, layer := range manifest.Layers {
 
 		start := time.Now()
-		_, err = tx.Exec(ctx, insertLayer, layer.Hash)
+		_, err = tx.Exec(ctx, insertLayer, layer.Hash, s.namespace)
 		if err != nil {
 			return fmt.Errorf("postgres:persistManifest: failed to insert layer: %v", err)
 		}
@@ -89,7 +91,7 @@ func (s *store) PersistManifest(ctx context.Context, manifest claircore.Manifest
 		persistManifestDuration.WithLabelValues("insertLayer").Observe(time.Since(start).Seconds())
 
 		start = time.Now()
-		_, err = tx.Exec(ctx, insertManifestLayer, manifest.Hash, layer.Hash, i)
+		_, err = tx.Exec(ctx, insertManifestLayer, manifest.Hash, layer.Hash, i, s.namespace)
 		if err != nil {
 			return fmt.Errorf("postgres:persistManifest: failed to insert manifest -> layer link: %v", err)
 		}
@@ -44,7 +44,7 @@ SELECT
 FROM
 	repo_scanartifact
 	LEFT JOIN repo ON repo_scanartifact.repo_id = repo.id
-	JOIN layer ON layer.hash = $1
+	JOIN layer ON layer.hash = $1 AND layer.namespace = $3
 WHERE
 	repo_scanartifact.layer_id = layer.id
 	AND repo_scanartifact.scanner_id = ANY ($2);
@@ -59,7 +59,7 @@ WHERE
 	}
 
 	start := time.Now()
-	rows, err := s.pool.Query(ctx, query, hash, scannerIDs)
+	rows, err := s.pool.Query(ctx, query, hash, scannerIDs, s.namespace)
 	switch {
 	case errors.Is(err, nil):
 	case errors.Is(err, pgx.ErrNoRows):
@@ -45,6 +45,7 @@ WITH
 				manifest
 			WHERE
 				hash = $1
+				AND namespace = $3
 		)
 INSERT
 INTO
@@ -62,6 +63,7 @@ WITH
 				manifest
 			WHERE
 				hash = $1
+				AND namespace = $3
 		)
 INSERT
 INTO
@@ -89,7 +91,7 @@ DO
 	// link extracted scanner IDs with incoming manifest
 	for _, id := range scannerIDs {
 		start := time.Now()
-		_, err := tx.Exec(ctx, insertManifestScanned, ir.Hash, id)
+		_, err := tx.Exec(ctx, insertManifestScanned, ir.Hash, id, s.namespace)
 		if err != nil {
 			return fmt.Errorf("store:storeManifest failed to link manifest with scanner list: %v", err)
 		}
@@ -102,7 +104,7 @@ DO
 	// implementations
 
 	start := time.Now()
-	_, err = tx.Exec(ctx, upsertIndexReport, ir.Hash, jsonbIndexReport(*ir))
+	_, err = tx.Exec(ctx, upsertIndexReport, ir.Hash, jsonbIndexReport(*ir), s.namespace)
 	if err != nil {
 		return fmt.Errorf("failed to upsert scan result: %v", err)
 	}
@@ -43,6 +43,7 @@ WITH
 				manifest
 			WHERE
 				hash = $1
+				AND namespace = $3
 		)
 INSERT
 INTO
@@ -58,7 +59,7 @@ DO
 	// implementations
 
 	start := time.Now()
-	_, err := s.pool.Exec(ctx, query, ir.Hash, jsonbIndexReport(*ir))
+	_, err := s.pool.Exec(ctx, query, ir.Hash, jsonbIndexReport(*ir), s.namespace)
 	if err != nil {
 		return fmt.Errorf("failed to upsert index report: %v", err)
 	}
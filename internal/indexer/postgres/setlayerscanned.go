@@ -45,7 +45,7 @@ WITH
 			WHERE
 				name = $2 AND version = $3 AND kind = $4
 		),
-	layer AS (SELECT id FROM layer WHERE hash = $1)
+	layer AS (SELECT id FROM layer WHERE hash = $1 AND namespace = $5)
 INSERT
 INTO
 	scanned_layer (layer_id, scanner_id)
@@ -61,7 +61,7 @@ DO
 `
 
 	start := time.Now()
-	_, err := s.pool.Exec(ctx, query, hash, vs.Name(), vs.Version(), vs.Kind())
+	_, err := s.pool.Exec(ctx, query, hash, vs.Name(), vs.Version(), vs.Kind(), s.namespace)
 	if err != nil {
 		return fmt.Errorf("store:setLayerScanned scanner %v: %v", vs, err)
 	}
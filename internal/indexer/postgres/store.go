@@ -16,12 +16,39 @@ var _ indexer.Store = (*store)(nil)
 // All the other exported methods live in their own files.
 type store struct {
 	pool *pgxpool.Pool
+	// Namespace is the value of the namespace column of every manifest and
+	// layer the store reads or writes.
+	namespace string
 }
 
-func NewStore(pool *pgxpool.Pool) *store {
-	return &store{
-		pool: pool,
+// DefaultNamespace is the namespace used by a store constructed without
+// WithNamespace. Rows written before namespaces existed are in it.
+const DefaultNamespace = ""
+
+// Option configures a store.
+type Option func(*store)
+
+// WithNamespace makes the store read and write only the manifests and layers
+// in the named namespace, so that several stores can share a database without
+// seeing each other's index reports.
+//
+// Packages, distributions, and repositories are stored once and shared, but
+// each store only finds the ones its own layers were indexed with.
+func WithNamespace(ns string) Option {
+	return func(s *store) {
+		s.namespace = ns
+	}
+}
+
+func NewStore(pool *pgxpool.Pool, opts ...Option) *store {
+	s := &store{
+		pool:      pool,
+		namespace: DefaultNamespace,
+	}
+	for _, o := range opts {
+		o(s)
 	}
+	return s
 }
 
 func (s *store) Close(_ context.Context) error {
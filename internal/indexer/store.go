@@ -28,6 +28,12 @@ type Setter interface {
 	//
 	// After this method is returned a call to Querier.LayerScanned with the same arguments must return true.
 	SetLayerScanned(ctx context.Context, hash claircore.Digest, scnr VersionedScanner) error
+	// SetLayerDiffID records the digest of the uncompressed content of a layer
+	// persisted with PersistManifest.
+	//
+	// After this method returns a call to Querier.LayerDiffID with the same
+	// layer hash must return the diffID.
+	SetLayerDiffID(ctx context.Context, hash, diffID claircore.Digest) error
 	// RegisterPackageScanners registers the provided scanners with the persistence layer.
 	RegisterScanners(ctx context.Context, scnrs VersionedScanners) error
 	// SetIndexReport persists the current state of the IndexReport.
@@ -44,6 +50,13 @@ type Setter interface {
 	// Also a call to Querier.IndexReport with the manifest hash represted in the provided IndexReport must return the IndexReport
 	// in finished state.
 	SetIndexFinished(ctx context.Context, sr *claircore.IndexReport, scnrs VersionedScanners) error
+	// ImportLayer persists scan results for a layer that were produced
+	// elsewhere, and marks the layer scanned by each scanner in the envelope.
+	//
+	// The envelope is expected to have been validated against the configured
+	// scanners. After this method returns, a call to Querier.LayerScanned for
+	// any of those scanners must return true.
+	ImportLayer(ctx context.Context, e *LayerEnvelope) error
 }
 
 // Querier interface provides the method set to ascertain indexed artifacts and query whether a layer
@@ -53,6 +66,14 @@ type Querier interface {
 	ManifestScanned(ctx context.Context, hash claircore.Digest, scnrs VersionedScanners) (bool, error)
 	// LayerScanned returns whether the given layer was scanned by the provided scanner.
 	LayerScanned(ctx context.Context, hash claircore.Digest, scnr VersionedScanner) (bool, error)
+	// LayersScanned reports, in one round trip, which of the provided
+	// scanners have scanned each of the given layers. The result is indexed
+	// by layer and then scanner, in the order provided. Unknown layers and
+	// scanners are reported as not scanned.
+	LayersScanned(ctx context.Context, hashes []claircore.Digest, scnrs VersionedScanners) ([][]bool, error)
+	// LayerDiffID returns the digest of the uncompressed content of a layer,
+	// if one was recorded.
+	LayerDiffID(ctx context.Context, hash claircore.Digest) (claircore.Digest, bool, error)
 	// PackagesByLayer gets all the packages found in a layer limited by the provided scanners.
 	PackagesByLayer(ctx context.Context, hash claircore.Digest, scnrs VersionedScanners) ([]*claircore.Package, error)
 	// DistributionsByLayer gets all the distributions found in a layer limited by the provided scanners.
@@ -61,9 +82,23 @@ type Querier interface {
 	RepositoriesByLayer(ctx context.Context, hash claircore.Digest, scnrs VersionedScanners) ([]*claircore.Repository, error)
 	// IndexReport attempts to retrieve a persisted IndexReport.
 	IndexReport(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error)
+	// IndexReportState reports the IndexerState recorded on a persisted
+	// IndexReport and whether the report is a successful, finished one,
+	// without retrieving the whole report.
+	IndexReportState(ctx context.Context, hash claircore.Digest) (string, bool, error)
 	// AffectedManifests returns a list of manifest digests which the target vulnerability
 	// affects.
 	AffectedManifests(ctx context.Context, v claircore.Vulnerability) ([]claircore.Digest, error)
+	// ManifestsByDistribution returns a page of at most "limit" manifest
+	// digests indexed with the distribution identified by the provided
+	// os-release ID and VERSION_ID, along with a cursor for the next page.
+	//
+	// An empty versionID matches all versions. An empty cursor requests the
+	// first page, and an empty cursor is returned after the last page.
+	ManifestsByDistribution(ctx context.Context, did, versionID string, limit int, cursor string) ([]claircore.Digest, string, error)
+	// ManifestCountByDistribution reports the number of manifests that
+	// ManifestsByDistribution would page through.
+	ManifestCountByDistribution(ctx context.Context, did, versionID string) (int64, error)
 }
 
 // Indexer interface provide the method set required for indexing layer and manifest contents into
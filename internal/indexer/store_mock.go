@@ -78,6 +78,20 @@ func (mr *MockStoreMockRecorder) DistributionsByLayer(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributionsByLayer", reflect.TypeOf((*MockStore)(nil).DistributionsByLayer), arg0, arg1, arg2)
 }
 
+// ImportLayer mocks base method
+func (m *MockStore) ImportLayer(arg0 context.Context, arg1 *LayerEnvelope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportLayer", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportLayer indicates an expected call of ImportLayer
+func (mr *MockStoreMockRecorder) ImportLayer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportLayer", reflect.TypeOf((*MockStore)(nil).ImportLayer), arg0, arg1)
+}
+
 // IndexDistributions mocks base method
 func (m *MockStore) IndexDistributions(arg0 context.Context, arg1 []*claircore.Distribution, arg2 *claircore.Layer, arg3 VersionedScanner) error {
 	m.ctrl.T.Helper()
@@ -136,6 +150,22 @@ func (mr *MockStoreMockRecorder) IndexReport(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IndexReport", reflect.TypeOf((*MockStore)(nil).IndexReport), arg0, arg1)
 }
 
+// IndexReportState mocks base method
+func (m *MockStore) IndexReportState(arg0 context.Context, arg1 claircore.Digest) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IndexReportState", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IndexReportState indicates an expected call of IndexReportState
+func (mr *MockStoreMockRecorder) IndexReportState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IndexReportState", reflect.TypeOf((*MockStore)(nil).IndexReportState), arg0, arg1)
+}
+
 // IndexRepositories mocks base method
 func (m *MockStore) IndexRepositories(arg0 context.Context, arg1 []*claircore.Repository, arg2 *claircore.Layer, arg3 VersionedScanner) error {
 	m.ctrl.T.Helper()
@@ -150,6 +180,22 @@ func (mr *MockStoreMockRecorder) IndexRepositories(arg0, arg1, arg2, arg3 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IndexRepositories", reflect.TypeOf((*MockStore)(nil).IndexRepositories), arg0, arg1, arg2, arg3)
 }
 
+// LayerDiffID mocks base method
+func (m *MockStore) LayerDiffID(arg0 context.Context, arg1 claircore.Digest) (claircore.Digest, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LayerDiffID", arg0, arg1)
+	ret0, _ := ret[0].(claircore.Digest)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// LayerDiffID indicates an expected call of LayerDiffID
+func (mr *MockStoreMockRecorder) LayerDiffID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LayerDiffID", reflect.TypeOf((*MockStore)(nil).LayerDiffID), arg0, arg1)
+}
+
 // LayerScanned mocks base method
 func (m *MockStore) LayerScanned(arg0 context.Context, arg1 claircore.Digest, arg2 VersionedScanner) (bool, error) {
 	m.ctrl.T.Helper()
@@ -165,6 +211,36 @@ func (mr *MockStoreMockRecorder) LayerScanned(arg0, arg1, arg2 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LayerScanned", reflect.TypeOf((*MockStore)(nil).LayerScanned), arg0, arg1, arg2)
 }
 
+// LayersScanned mocks base method
+func (m *MockStore) LayersScanned(arg0 context.Context, arg1 []claircore.Digest, arg2 VersionedScanners) ([][]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LayersScanned", arg0, arg1, arg2)
+	ret0, _ := ret[0].([][]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LayersScanned indicates an expected call of LayersScanned
+func (mr *MockStoreMockRecorder) LayersScanned(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LayersScanned", reflect.TypeOf((*MockStore)(nil).LayersScanned), arg0, arg1, arg2)
+}
+
+// ManifestCountByDistribution mocks base method
+func (m *MockStore) ManifestCountByDistribution(arg0 context.Context, arg1, arg2 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ManifestCountByDistribution", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ManifestCountByDistribution indicates an expected call of ManifestCountByDistribution
+func (mr *MockStoreMockRecorder) ManifestCountByDistribution(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManifestCountByDistribution", reflect.TypeOf((*MockStore)(nil).ManifestCountByDistribution), arg0, arg1, arg2)
+}
+
 // ManifestScanned mocks base method
 func (m *MockStore) ManifestScanned(arg0 context.Context, arg1 claircore.Digest, arg2 VersionedScanners) (bool, error) {
 	m.ctrl.T.Helper()
@@ -180,6 +256,22 @@ func (mr *MockStoreMockRecorder) ManifestScanned(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManifestScanned", reflect.TypeOf((*MockStore)(nil).ManifestScanned), arg0, arg1, arg2)
 }
 
+// ManifestsByDistribution mocks base method
+func (m *MockStore) ManifestsByDistribution(arg0 context.Context, arg1, arg2 string, arg3 int, arg4 string) ([]claircore.Digest, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ManifestsByDistribution", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]claircore.Digest)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ManifestsByDistribution indicates an expected call of ManifestsByDistribution
+func (mr *MockStoreMockRecorder) ManifestsByDistribution(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManifestsByDistribution", reflect.TypeOf((*MockStore)(nil).ManifestsByDistribution), arg0, arg1, arg2, arg3, arg4)
+}
+
 // PackagesByLayer mocks base method
 func (m *MockStore) PackagesByLayer(arg0 context.Context, arg1 claircore.Digest, arg2 VersionedScanners) ([]*claircore.Package, error) {
 	m.ctrl.T.Helper()
@@ -266,6 +358,20 @@ func (mr *MockStoreMockRecorder) SetIndexReport(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIndexReport", reflect.TypeOf((*MockStore)(nil).SetIndexReport), arg0, arg1)
 }
 
+// SetLayerDiffID mocks base method
+func (m *MockStore) SetLayerDiffID(arg0 context.Context, arg1, arg2 claircore.Digest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLayerDiffID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLayerDiffID indicates an expected call of SetLayerDiffID
+func (mr *MockStoreMockRecorder) SetLayerDiffID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLayerDiffID", reflect.TypeOf((*MockStore)(nil).SetLayerDiffID), arg0, arg1, arg2)
+}
+
 // SetLayerScanned mocks base method
 func (m *MockStore) SetLayerScanned(arg0 context.Context, arg1 claircore.Digest, arg2 VersionedScanner) error {
 	m.ctrl.T.Helper()
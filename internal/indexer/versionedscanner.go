@@ -3,6 +3,9 @@ package indexer
 import (
 	"context"
 	"net/http"
+	"sort"
+
+	"github.com/quay/claircore"
 )
 
 const (
@@ -39,6 +42,27 @@ type ConfigurableScanner interface {
 	Configure(context.Context, ConfigDeserializer) error
 }
 
+// InventoryScanner is an interface package scanners can implement if they
+// record artifacts that are only needed for vulnerability matching, such as
+// the files found with a package.
+//
+// InventoryOnly returns a scanner that skips that work, for use by
+// inventory-only indexers. Its results differ, so it must report a different
+// Version.
+type InventoryScanner interface {
+	InventoryOnly() PackageScanner
+}
+
+// LimitedScanner is an interface distribution scanners can implement if only
+// the distribution is indexed for the layers they detect.
+//
+// LimitedSupport reports why a report with the Distribution is only partially
+// complete, or the empty string if the Distribution isn't one the scanner
+// reports.
+type LimitedScanner interface {
+	LimitedSupport(*claircore.Distribution) string
+}
+
 // VersionedScanners implements a list with construction methods
 // not concurrency safe
 type VersionedScanners []VersionedScanner
@@ -124,3 +148,23 @@ func MergeVS(pscnr []PackageScanner, dscnr []DistributionScanner, rscnr []Reposi
 	}
 	return out
 }
+
+// Describe returns descriptions of the scanners, sorted by kind and then
+// name, for recording in an IndexReport.
+func (vs VersionedScanners) Describe() []claircore.ScannerDescription {
+	out := make([]claircore.ScannerDescription, len(vs))
+	for i, s := range vs {
+		out[i] = claircore.ScannerDescription{
+			Name:    s.Name(),
+			Version: s.Version(),
+			Kind:    s.Kind(),
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,102 @@
+// Package inflight tracks in-flight operations so that a service can shut
+// down without pulling resources out from under them.
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when an operation is started after Close has been
+// called.
+var ErrClosed = errors.New("claircore: closed")
+
+// Tracker tracks in-flight operations.
+//
+// The zero value is ready to use.
+type Tracker struct {
+	mu     sync.Mutex
+	closed bool
+	next   uint64
+	ops    map[uint64]context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start registers a new operation.
+//
+// The returned Context is canceled if the operation is still running when
+// Close gives up waiting for it. The returned function must be called when
+// the operation is done. If Close has already been called, ErrClosed is
+// returned.
+func (t *Tracker) Start(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrClosed
+	}
+	if t.ops == nil {
+		t.ops = make(map[uint64]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	id := t.next
+	t.next++
+	t.ops[id] = cancel
+	t.wg.Add(1)
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.ops, id)
+			t.mu.Unlock()
+			cancel()
+			t.wg.Done()
+		})
+	}, nil
+}
+
+// Close stops new operations from starting and waits up to "timeout" for
+// in-flight operations to finish. Any operations still running after that are
+// canceled, and Close waits for them to return.
+//
+// If the passed Context is canceled before all operations return, its error
+// is returned. It's safe to call Close multiple times.
+func (t *Tracker) Close(ctx context.Context, timeout time.Duration) error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	t.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel cancels all in-flight operations.
+func (t *Tracker) cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, f := range t.ops {
+		f()
+	}
+}
@@ -0,0 +1,87 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	var tr Tracker
+	var wg sync.WaitGroup
+	finished := make(chan struct{}, 4)
+	for i := 0; i < 4; i++ {
+		octx, done, err := tr.Start(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			select {
+			case <-time.After(10 * time.Millisecond):
+				finished <- struct{}{}
+			case <-octx.Done():
+			}
+		}()
+	}
+	if err := tr.Close(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if got, want := len(finished), 4; got != want {
+		t.Errorf("got %d finished operations, want %d", got, want)
+	}
+	if _, _, err := tr.Start(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("got: %v, want: %v", err, ErrClosed)
+	}
+	// Close is idempotent.
+	if err := tr.Close(ctx, time.Minute); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCancelStragglers(t *testing.T) {
+	ctx := context.Background()
+	var tr Tracker
+	octx, done, err := tr.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	returned := make(chan error, 1)
+	go func() {
+		defer done()
+		<-octx.Done()
+		returned <- octx.Err()
+	}()
+	if err := tr.Close(ctx, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-returned:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got: %v, want: %v", err, context.Canceled)
+		}
+	default:
+		t.Error("Close returned before the operation did")
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	var tr Tracker
+	_, done, err := tr.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	// The operation ignores cancellation, so Close can only give up.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tr.Close(ctx, time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
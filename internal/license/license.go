@@ -0,0 +1,145 @@
+// Package license has helpers for the license strings package scanners
+// record.
+package license
+
+import (
+	"strings"
+)
+
+// Join combines the licenses a package declares into a single string.
+//
+// If every license is an SPDX expression, the result is the conjunction of
+// the distinct ones, with compound expressions parenthesized. Otherwise, the
+// licenses are passed through verbatim, separated by "; ".
+func Join(ls []string) string {
+	out := make([]string, 0, len(ls))
+	seen := make(map[string]struct{}, len(ls))
+	spdx := true
+	for _, l := range ls {
+		l = strings.TrimSpace(l)
+		if _, ok := seen[l]; ok || l == "" {
+			continue
+		}
+		seen[l] = struct{}{}
+		out = append(out, l)
+		spdx = spdx && IsExpression(l)
+	}
+	if !spdx {
+		return strings.Join(out, "; ")
+	}
+	if len(out) > 1 {
+		for i, l := range out {
+			if strings.ContainsAny(l, " ") {
+				out[i] = "(" + l + ")"
+			}
+		}
+	}
+	return strings.Join(out, " AND ")
+}
+
+// IsExpression reports whether "s" is syntactically an SPDX license
+// expression, like "MIT" or "(GPL-2.0-only OR MIT) AND BSD-3-Clause".
+//
+// Identifiers aren't checked against the SPDX license list.
+func IsExpression(s string) bool {
+	p := parser{toks: tokenize(s)}
+	return len(p.toks) != 0 && p.expr() && p.pos == len(p.toks)
+}
+
+// Tokenize splits an expression into identifiers, operators, and
+// parentheses.
+func tokenize(s string) []string {
+	var toks []string
+	for _, f := range strings.Fields(s) {
+		for f != "" {
+			i := strings.IndexAny(f, "()")
+			switch {
+			case i == -1:
+				toks = append(toks, f)
+				f = ""
+			case i == 0:
+				toks = append(toks, f[:1])
+				f = f[1:]
+			default:
+				toks = append(toks, f[:i])
+				f = f[i:]
+			}
+		}
+	}
+	return toks
+}
+
+// Parser is a recursive-descent parser for the SPDX expression grammar.
+type parser struct {
+	toks []string
+	pos  int
+}
+
+// Expr consumes an expression: operands joined by "AND" or "OR".
+func (p *parser) expr() bool {
+	if !p.operand() {
+		return false
+	}
+	for p.pos < len(p.toks) {
+		switch p.toks[p.pos] {
+		case "AND", "OR":
+		default:
+			return true
+		}
+		p.pos++
+		if !p.operand() {
+			return false
+		}
+	}
+	return true
+}
+
+// Operand consumes a parenthesized expression or a license identifier,
+// optionally followed by "WITH" and an exception identifier.
+func (p *parser) operand() bool {
+	if p.pos == len(p.toks) {
+		return false
+	}
+	if p.toks[p.pos] == "(" {
+		p.pos++
+		if !p.expr() || p.pos == len(p.toks) || p.toks[p.pos] != ")" {
+			return false
+		}
+		p.pos++
+		return true
+	}
+	if !p.ident() {
+		return false
+	}
+	if p.pos < len(p.toks) && p.toks[p.pos] == "WITH" {
+		p.pos++
+		return p.ident()
+	}
+	return true
+}
+
+// Ident consumes an identifier: letters, digits, ".", "-", ":", and an
+// optional trailing "+".
+func (p *parser) ident() bool {
+	if p.pos == len(p.toks) {
+		return false
+	}
+	t := p.toks[p.pos]
+	switch t {
+	case "AND", "OR", "WITH", "(", ")":
+		return false
+	}
+	t = strings.TrimSuffix(t, "+")
+	if t == "" {
+		return false
+	}
+	for _, r := range t {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == ':':
+		default:
+			return false
+		}
+	}
+	p.pos++
+	return true
+}
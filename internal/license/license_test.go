@@ -0,0 +1,50 @@
+package license
+
+import "testing"
+
+func TestIsExpression(t *testing.T) {
+	tt := []struct {
+		in   string
+		want bool
+	}{
+		{"MIT", true},
+		{"GPL-2.0-or-later", true},
+		{"GPL-2+", true},
+		{"LicenseRef-Proprietary", true},
+		{"MIT OR Apache-2.0", true},
+		{"(GPL-2.0-only OR MIT) AND BSD-3-Clause", true},
+		{"GPL-2.0-only WITH Classpath-exception-2.0", true},
+		{"", false},
+		{"Apache 2.0", false},
+		{"The Apache Software License, Version 2.0", false},
+		{"MIT AND", false},
+		{"(MIT", false},
+		{"MIT)", false},
+		{"MIT WITH", false},
+		{"GPLv2+ and MIT", false},
+	}
+	for _, tc := range tt {
+		if got := IsExpression(tc.in); got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tt := []struct {
+		in   []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"MIT"}, "MIT"},
+		{[]string{"MIT", "BSD-3-Clause", "MIT"}, "MIT AND BSD-3-Clause"},
+		{[]string{"MIT", "GPL-2.0-only OR Apache-2.0"}, "MIT AND (GPL-2.0-only OR Apache-2.0)"},
+		{[]string{"Apache License, Version 2.0", "MIT"}, "Apache License, Version 2.0; MIT"},
+		{[]string{"", " Public Domain "}, "Public Domain"},
+	}
+	for _, tc := range tt {
+		if got := Join(tc.in); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+)
+
+// ResolveAliases sets the Aliases of every vulnerability in "vr" to the names
+// it's connected to in the store's alias table, along with any it already
+// had. The vulnerabilities are copied rather than modified.
+func resolveAliases(ctx context.Context, vr *claircore.VulnerabilityReport, r vulnstore.AliasResolver) error {
+	if len(vr.Vulnerabilities) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(vr.Vulnerabilities))
+	names := make([]string, 0, len(vr.Vulnerabilities))
+	for _, v := range vr.Vulnerabilities {
+		if _, ok := seen[v.Name]; ok {
+			continue
+		}
+		seen[v.Name] = struct{}{}
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	resolved, err := r.ResolveAliases(ctx, names)
+	if err != nil {
+		return fmt.Errorf("matcher: unable to resolve aliases: %w", err)
+	}
+	if len(resolved) == 0 {
+		return nil
+	}
+	for id, v := range vr.Vulnerabilities {
+		as, ok := resolved[v.Name]
+		if !ok {
+			continue
+		}
+		set := make(map[string]struct{}, len(as)+len(v.Aliases))
+		for _, a := range v.Aliases {
+			set[a] = struct{}{}
+		}
+		for _, a := range as {
+			set[a] = struct{}{}
+		}
+		out := make([]string, 0, len(set))
+		for a := range set {
+			out = append(out, a)
+		}
+		sort.Strings(out)
+		cp := *v
+		cp.Aliases = out
+		vr.Vulnerabilities[id] = &cp
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package matcher
 
 import (
 	"context"
+	"errors"
+	"path"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
@@ -20,6 +23,8 @@ type Controller struct {
 	m driver.Matcher
 	// a vulnstore.Vulnerability instance for querying vulnerabilities
 	store vulnstore.Vulnerability
+	// the update operations to limit queries to, if any
+	refs []uuid.UUID
 }
 
 // NewController is a constructor for a Controller
@@ -30,7 +35,29 @@ func NewController(m driver.Matcher, store vulnstore.Vulnerability) *Controller
 	}
 }
 
-func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecord) (map[string][]*claircore.Vulnerability, error) {
+// Result is the outcome of a Controller's Match.
+type Result struct {
+	// Vulnerabilities are the vulnerabilities affecting each package, keyed
+	// by package ID.
+	Vulnerabilities map[string][]*claircore.Vulnerability
+	// Indeterminate are the findings the Matcher couldn't evaluate, keyed by
+	// package ID.
+	Indeterminate map[string][]Indeterminate
+}
+
+// Indeterminate is a finding the Matcher couldn't evaluate, because a version
+// couldn't be parsed.
+type Indeterminate struct {
+	Vulnerability *claircore.Vulnerability
+	Err           *driver.VersionError
+}
+
+// Match reports the vulnerabilities affecting the records the Matcher is
+// interested in.
+//
+// If the Matcher's Vulnerable method returns a driver.VersionError, the
+// finding is reported as indeterminate rather than failing the match.
+func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecord) (*Result, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/matcher/Controller.Match"),
 		label.String("matcher", mc.m.Name()))
@@ -43,16 +70,16 @@ func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecor
 
 	// early return; do not call db at all
 	if len(interested) == 0 {
-		return map[string][]*claircore.Vulnerability{}, nil
+		return &Result{Vulnerabilities: map[string][]*claircore.Vulnerability{}}, nil
 	}
 
 	remoteMatcher, matchedVulns, err := mc.queryRemoteMatcher(ctx, interested)
 	if remoteMatcher {
 		if err != nil {
 			zlog.Error(ctx).Err(err).Msg("remote matcher error, returning empty results")
-			return map[string][]*claircore.Vulnerability{}, nil
+			return &Result{Vulnerabilities: map[string][]*claircore.Vulnerability{}}, nil
 		}
-		return matchedVulns, nil
+		return &Result{Vulnerabilities: matchedVulns}, nil
 	}
 
 	dbSide, authoritative := mc.dbFilter()
@@ -62,7 +89,8 @@ func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecor
 		Msg("version filter compatible?")
 
 	// query the vulnstore
-	vulns, err := mc.query(ctx, interested, dbSide)
+	queried, notes := mc.mapDistributions(mc.mapPackages(interested))
+	vulns, err := mc.query(ctx, queried, dbSide)
 	if err != nil {
 		return nil, err
 	}
@@ -70,18 +98,145 @@ func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecor
 		Int("vulnerabilities", len(vulns)).
 		Msg("query")
 
-	if authoritative {
-		return vulns, nil
+	var indeterminate map[string][]Indeterminate
+	if !authoritative {
+		// filter the vulns
+		vulns, indeterminate, err = mc.filter(ctx, interested, vulns)
+		if err != nil {
+			return nil, err
+		}
+		zlog.Debug(ctx).
+			Int("filtered", len(vulns)).
+			Int("indeterminate", len(indeterminate)).
+			Msg("filtered")
 	}
-	// filter the vulns
-	filteredVulns, err := mc.filter(ctx, interested, vulns)
-	if err != nil {
-		return nil, err
+	if n := mc.checkFiles(interested, vulns); n != 0 {
+		zlog.Debug(ctx).
+			Int("count", n).
+			Msg("required files not found")
+	}
+	annotate(vulns, notes)
+	return &Result{Vulnerabilities: vulns, Indeterminate: indeterminate}, nil
+}
+
+// CheckFiles removes the vulnerabilities whose required files weren't found
+// with the package, if the Matcher implements driver.FileMatcher, and reports
+// how many were removed.
+func (mc *Controller) checkFiles(interested []*claircore.IndexRecord, vulns map[string][]*claircore.Vulnerability) int {
+	fm, ok := mc.m.(driver.FileMatcher)
+	if !ok {
+		return 0
+	}
+	var ct int
+	done := make(map[string]struct{})
+	for _, r := range interested {
+		id := r.Package.ID
+		if _, ok := done[id]; ok || len(r.Package.Files) == 0 {
+			continue
+		}
+		done[id] = struct{}{}
+		vs := vulns[id]
+		if len(vs) == 0 {
+			continue
+		}
+		out := make([]*claircore.Vulnerability, 0, len(vs))
+		for _, v := range vs {
+			if hasFile(r.Package.Files, fm.RequiredFiles(r, v)) {
+				out = append(out, v)
+				continue
+			}
+			ct++
+		}
+		vulns[id] = out
+	}
+	return ct
+}
+
+// HasFile reports whether any of the files match any of the patterns, or if
+// there are no patterns.
+func hasFile(files, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		for _, f := range files {
+			if ok, _ := path.Match(p, f); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MapDistributions returns the records to query the vulnstore with and any
+// annotations for them, keyed by package ID, if the Matcher implements
+// driver.DistributionMapper. Otherwise, the records are returned unchanged.
+func (mc *Controller) mapDistributions(interested []*claircore.IndexRecord) ([]*claircore.IndexRecord, map[string]map[string]string) {
+	dm, ok := mc.m.(driver.DistributionMapper)
+	if !ok {
+		return interested, nil
+	}
+	out := make([]*claircore.IndexRecord, len(interested))
+	notes := make(map[string]map[string]string)
+	for i, r := range interested {
+		d, n := dm.MapDistribution(r)
+		if d == nil {
+			out[i] = r
+			continue
+		}
+		out[i] = &claircore.IndexRecord{
+			Package:      r.Package,
+			Distribution: d,
+			Repository:   r.Repository,
+		}
+		if len(n) != 0 {
+			notes[r.Package.ID] = n
+		}
+	}
+	return out, notes
+}
+
+// MapPackages returns the records to query the vulnstore with, if the Matcher
+// implements driver.PackageMapper. Otherwise, the records are returned
+// unchanged.
+func (mc *Controller) mapPackages(interested []*claircore.IndexRecord) []*claircore.IndexRecord {
+	pm, ok := mc.m.(driver.PackageMapper)
+	if !ok {
+		return interested
+	}
+	out := make([]*claircore.IndexRecord, len(interested))
+	for i, r := range interested {
+		p := pm.MapPackage(r)
+		if p == nil {
+			out[i] = r
+			continue
+		}
+		out[i] = &claircore.IndexRecord{
+			Package:      p,
+			Distribution: r.Distribution,
+			Repository:   r.Repository,
+		}
+	}
+	return out
+}
+
+// Annotate adds the annotations for each package to copies of the package's
+// vulnerabilities, so that the stored vulnerabilities are never modified.
+func annotate(vulns map[string][]*claircore.Vulnerability, notes map[string]map[string]string) {
+	for id, n := range notes {
+		vs := vulns[id]
+		for i, v := range vs {
+			nv := *v
+			nv.Annotations = make(map[string]string, len(v.Annotations)+len(n))
+			for k, v := range v.Annotations {
+				nv.Annotations[k] = v
+			}
+			for k, v := range n {
+				nv.Annotations[k] = v
+			}
+			vs[i] = &nv
+		}
 	}
-	zlog.Debug(ctx).
-		Int("filtered", len(filteredVulns)).
-		Msg("filtered")
-	return filteredVulns, nil
 }
 
 // If RemoteMatcher exists, it will call the matcher service which runs on a remote
@@ -126,6 +281,10 @@ func (mc *Controller) query(ctx context.Context, interested []*claircore.IndexRe
 		Matchers:         matchers,
 		Debug:            true,
 		VersionFiltering: dbSide,
+		UpdateRefs:       mc.refs,
+	}
+	if r, ok := mc.m.(driver.DistributionVersionRanger); ok {
+		getOpts.DistributionVersionOp = r.DistributionVersionOp()
 	}
 	matches, err := mc.store.Get(ctx, interested, getOpts)
 	if err != nil {
@@ -136,29 +295,77 @@ func (mc *Controller) query(ctx context.Context, interested []*claircore.IndexRe
 
 // Filter method asks the matcher if the given package is affected by the returned vulnerability. if so; its added to a result map where the key is the package ID
 // and the value is a Vulnerability. if not it is not added to the result.
-func (mc *Controller) filter(ctx context.Context, interested []*claircore.IndexRecord, vulns map[string][]*claircore.Vulnerability) (map[string][]*claircore.Vulnerability, error) {
+//
+// A package has a record for every environment it was found in, such as
+// each package database containing it. Each record is evaluated on its own,
+// and a vulnerability is reported for the package if any record is affected.
+// Findings that are indeterminate for some record and not affected for any
+// are returned in the second map.
+func (mc *Controller) filter(ctx context.Context, interested []*claircore.IndexRecord, vulns map[string][]*claircore.Vulnerability) (map[string][]*claircore.Vulnerability, map[string][]Indeterminate, error) {
 	filtered := map[string][]*claircore.Vulnerability{}
+	seen := make(map[string]map[string]struct{})
+	unknown := make(map[string][]Indeterminate)
+	unknownSeen := make(map[string]map[string]struct{})
 	for _, record := range interested {
-		match, err := filterVulns(ctx, mc.m, record, vulns[record.Package.ID])
+		id := record.Package.ID
+		match, indet, err := filterVulns(ctx, mc.m, record, vulns[id])
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if _, ok := filtered[id]; !ok {
+			filtered[id] = []*claircore.Vulnerability{}
+			seen[id] = make(map[string]struct{})
+			unknownSeen[id] = make(map[string]struct{})
+		}
+		for _, v := range match {
+			if _, ok := seen[id][v.ID]; ok {
+				continue
+			}
+			seen[id][v.ID] = struct{}{}
+			filtered[id] = append(filtered[id], v)
+		}
+		for _, i := range indet {
+			if _, ok := unknownSeen[id][i.Vulnerability.ID]; ok {
+				continue
+			}
+			unknownSeen[id][i.Vulnerability.ID] = struct{}{}
+			unknown[id] = append(unknown[id], i)
+		}
+	}
+	// A finding affected in any record isn't indeterminate.
+	var indeterminate map[string][]Indeterminate
+	for id, is := range unknown {
+		for _, i := range is {
+			if _, ok := seen[id][i.Vulnerability.ID]; ok {
+				continue
+			}
+			if indeterminate == nil {
+				indeterminate = make(map[string][]Indeterminate)
+			}
+			indeterminate[id] = append(indeterminate[id], i)
 		}
-		filtered[record.Package.ID] = match
 	}
-	return filtered, nil
+	return filtered, indeterminate, nil
 }
 
-// filter returns only the vulnerabilities affected by the provided package.
-func filterVulns(ctx context.Context, m driver.Matcher, record *claircore.IndexRecord, vulns []*claircore.Vulnerability) ([]*claircore.Vulnerability, error) {
+// filter returns only the vulnerabilities affected by the provided package,
+// and the ones the Matcher couldn't evaluate.
+func filterVulns(ctx context.Context, m driver.Matcher, record *claircore.IndexRecord, vulns []*claircore.Vulnerability) ([]*claircore.Vulnerability, []Indeterminate, error) {
 	filtered := []*claircore.Vulnerability{}
+	var indeterminate []Indeterminate
 	for _, vuln := range vulns {
 		match, err := m.Vulnerable(ctx, record, vuln)
-		if err != nil {
-			return nil, err
+		var verr *driver.VersionError
+		switch {
+		case errors.As(err, &verr):
+			indeterminate = append(indeterminate, Indeterminate{Vulnerability: vuln, Err: verr})
+			continue
+		case err != nil:
+			return nil, nil, err
 		}
 		if match {
 			filtered = append(filtered, vuln)
 		}
 	}
-	return filtered, nil
+	return filtered, indeterminate, nil
 }
@@ -3,9 +3,12 @@ package matcher
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"runtime"
+	"sort"
 	"sync/atomic"
 
+	"github.com/google/uuid"
 	"github.com/quay/zlog"
 	"golang.org/x/sync/errgroup"
 
@@ -15,7 +18,7 @@ import (
 )
 
 // Match receives an IndexReport and creates a VulnerabilityReport containing matched vulnerabilities
-func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Matcher, store vulnstore.Vulnerability) (*claircore.VulnerabilityReport, error) {
+func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Matcher, store vulnstore.Vulnerability, opts ...Option) (*claircore.VulnerabilityReport, error) {
 	// the vulnerability report we are creating
 	vr := &claircore.VulnerabilityReport{
 		Hash:                   ir.Hash,
@@ -25,13 +28,13 @@ func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Mat
 		Repositories:           ir.Repositories,
 		Vulnerabilities:        map[string]*claircore.Vulnerability{},
 		PackageVulnerabilities: map[string][]string{},
+		Warnings:               append([]claircore.Warning(nil), ir.Warnings...),
 	}
 
 	// extract IndexRecords from the IndexReport
 	records := ir.IndexRecords()
 	// a channel where concurrent controllers will deliver vulnerabilities affecting a package.
-	// maps a package id to a list of vulnerabilities.
-	ctrlC := make(chan map[string][]*claircore.Vulnerability, 1024)
+	ctrlC := make(chan *Result, 1024)
 	// a channel where controller errors will be reported
 	errorC := make(chan error, 1024)
 	// fan out all controllers, write their output to ctrlC, close ctrlC once all writers finish
@@ -56,31 +59,46 @@ func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Mat
 		}
 	}()
 	// loop ranges until ctrlC is closed and fully drained, ctrlC is guaranteed to close
-	for vulnsByPackage := range ctrlC {
-		for pkgID, vulns := range vulnsByPackage {
-			for _, vuln := range vulns {
-				vr.Vulnerabilities[vuln.ID] = vuln
-				vr.PackageVulnerabilities[pkgID] = append(vr.PackageVulnerabilities[pkgID], vuln.ID)
-			}
-		}
+	c := collector{vr: vr, opts: newOptions(opts)}
+	for res := range ctrlC {
+		c.add(res)
 	}
 	select {
 	case err := <-errorC:
 		return nil, err
 	default:
 	}
+	c.finish()
+	if r, ok := store.(vulnstore.AliasResolver); ok {
+		if err := resolveAliases(ctx, vr, r); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts.renormalize {
+		renormalizeSeverity(ctx, vr)
+	}
+	if c.opts.riskScorer != nil {
+		scoreRisk(ctx, vr, c.opts.riskScorer)
+	}
 	return vr, nil
 }
 
-// Store is the interface that can retrieve Enrichments and Vulnerabilities.
+// Store is the interface that can retrieve Enrichments and Vulnerabilities,
+// and report the update operations they came from.
 type Store interface {
 	vulnstore.Vulnerability
 	vulnstore.Enrichment
+	// GetLatestUpdateRefs reports the latest update operations for every
+	// updater of the provided kind, or all kinds if empty.
+	GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error)
 }
 
 // EnrichedMatch receives an IndexReport and creates a VulnerabilityReport
 // containing matched vulnerabilities and any relevant enrichments.
-func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.Matcher, es []driver.Enricher, s Store) (*claircore.VulnerabilityReport, error) {
+//
+// Warnings are added to the report for distributions the eol enricher
+// reported, and for stale data if WithStaleCutoff is used.
+func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.Matcher, es []driver.Enricher, s Store, opts ...Option) (*claircore.VulnerabilityReport, error) {
 	// the vulnerability report we are creating
 	vr := &claircore.VulnerabilityReport{
 		Hash:                   ir.Hash,
@@ -90,16 +108,30 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 		Repositories:           ir.Repositories,
 		Vulnerabilities:        map[string]*claircore.Vulnerability{},
 		PackageVulnerabilities: map[string][]string{},
+		Warnings:               append([]claircore.Warning(nil), ir.Warnings...),
 		// The Enrichments member isn't constructed here because it's
 		// constructed separately and then added.
 	}
+	// Snapshot the update operations before matching, so the report records
+	// what was current when matching ran. Queries are limited to those
+	// operations, so an update committed while matching doesn't show up in
+	// the report without being recorded in it.
+	md, sources, err := metadata(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	vr.Metadata = md
+	refs := make([]uuid.UUID, 0, len(md.UpdateOperations))
+	for _, op := range md.UpdateOperations {
+		refs = append(refs, op.Ref)
+	}
 	// extract IndexRecords from the IndexReport
 	records := ir.IndexRecords()
 	lim := runtime.GOMAXPROCS(0)
 
 	// Set up a pool to run matchers
 	mCh := make(chan driver.Matcher)
-	vCh := make(chan map[string][]*claircore.Vulnerability, lim)
+	vCh := make(chan *Result, lim)
 	mg, mctx := errgroup.WithContext(ctx) // match group, match context
 	for i := 0; i < lim; i++ {
 		mg.Go(func() error { // Worker
@@ -110,7 +142,9 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 					return mctx.Err()
 				default:
 				}
-				vs, err := NewController(m, s).Match(mctx, records)
+				mc := NewController(m, s)
+				mc.refs = refs
+				vs, err := mc.Match(mctx, records)
 				if err != nil {
 					zlog.Error(ctx).
 						Err(err).
@@ -140,24 +174,62 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 		}
 		return nil
 	})
+	c := collector{vr: vr, opts: newOptions(opts)}
 	vg.Go(func() error { // Collector
-		for pkgVuln := range vCh {
-			for pkg, vs := range pkgVuln {
-				for _, v := range vs {
-					vr.Vulnerabilities[v.ID] = v
-					vr.PackageVulnerabilities[pkg] = append(vr.PackageVulnerabilities[pkg], v.ID)
-				}
-			}
+		for res := range vCh {
+			c.add(res)
 		}
 		return nil
 	})
 	if err := vg.Wait(); err != nil {
 		return nil, err
 	}
+	// Filtering happens before enrichment, so enrichers only see what's
+	// reported. Aliases are resolved before it, so enrichers see them.
+	c.finish()
+	if r, ok := s.(vulnstore.AliasResolver); ok {
+		if err := resolveAliases(ctx, vr, r); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts.renormalize {
+		renormalizeSeverity(ctx, vr)
+	}
 
+	enriched, err := enrich(ctx, ir, vr, es, s)
+	if err != nil {
+		return nil, err
+	}
+	inheritSeverity(ctx, vr)
+	if c.opts.riskScorer != nil {
+		scoreRisk(ctx, vr, c.opts.riskScorer)
+	}
+	attribute(vr, sources, enriched)
+	eolWarnings(ctx, vr)
+	staleWarnings(vr, c.opts.staleCutoff)
+
+	return vr, nil
+}
+
+// Enrich runs the enrichers over the vulnerabilities in "vr" and sets its
+// Enrichments, using the same store lookups as EnrichedMatch. It's for
+// vulnerability sets that didn't come from matching an IndexReport, so
+// enrichers implementing driver.IndexReportEnricher are run with their Enrich
+// method.
+func Enrich(ctx context.Context, vr *claircore.VulnerabilityReport, es []driver.Enricher, s vulnstore.Enrichment) error {
+	_, err := enrich(ctx, nil, vr, es, s)
+	return err
+}
+
+// Enrich runs the enrichers over "vr", attaching their results, and returns
+// the names of the enrichers that reported something. The IndexReport may be
+// nil.
+func enrich(ctx context.Context, ir *claircore.IndexReport, vr *claircore.VulnerabilityReport, es []driver.Enricher, s vulnstore.Enrichment) (map[string]struct{}, error) {
+	lim := runtime.GOMAXPROCS(0)
 	// Set up a pool to run the enrichers and attach results to the report.
 	eCh := make(chan driver.Enricher)
 	type entry struct {
+		name string
 		kind string
 		msg  []json.RawMessage
 	}
@@ -175,10 +247,12 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 		close(eCh)
 		return nil
 	})
+	enriched := make(map[string]struct{})
 	eg.Go(func() error { // Collector
 		em := make(map[string][]json.RawMessage)
 		for e := range rCh {
 			em[e.kind] = append(em[e.kind], e.msg...)
+			enriched[e.name] = struct{}{}
 		}
 		vr.Enrichments = em
 		return nil
@@ -194,7 +268,14 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 			}()
 			var e driver.Enricher
 			for e = range eCh {
-				kind, msg, err := e.Enrich(ectx, getter(s, e.Name()), vr)
+				var kind string
+				var msg []json.RawMessage
+				var err error
+				if ie, ok := e.(driver.IndexReportEnricher); ok && ir != nil {
+					kind, msg, err = ie.EnrichWithIndexReport(ectx, getter(s, e.Name()), ir, vr)
+				} else {
+					kind, msg, err = e.Enrich(ectx, getter(s, e.Name()), vr)
+				}
 				if err != nil {
 					zlog.Error(ctx).
 						Err(err).
@@ -208,6 +289,7 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 					continue
 				}
 				res := entry{
+					name: e.Name(),
 					msg:  msg,
 					kind: kind,
 				}
@@ -223,13 +305,106 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 	if err := eg.Wait(); err != nil {
 		return nil, err
 	}
+	return enriched, nil
+}
 
-	return vr, nil
+// Metadata constructs the report metadata from the latest update operations in
+// the Store. The data sources declared in those operations are returned keyed
+// by updater name, for use with attribute.
+func metadata(ctx context.Context, s Store) (*claircore.ReportMetadata, map[string]claircore.DataSource, error) {
+	ops, err := s.GetLatestUpdateRefs(ctx, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("matcher: unable to get update operations: %w", err)
+	}
+	sources := make(map[string]claircore.DataSource)
+	bi := claircore.ReadBuildInfo()
+	md := claircore.ReportMetadata{
+		UpdateOperations: make(map[string]claircore.UpdateRef, len(ops)),
+		Claircore:        &bi,
+	}
+	for u, ops := range ops {
+		if len(ops) == 0 {
+			continue
+		}
+		// The first operation is the most recent.
+		md.UpdateOperations[u] = claircore.UpdateRef{
+			Ref:  ops[0].Ref,
+			Date: ops[0].Date,
+		}
+		if src := ops[0].Source; src != nil {
+			sources[u] = *src
+		}
+	}
+	return &md, sources, nil
+}
+
+// Attribute records the data sources of the updaters that contributed to the
+// report in its metadata: the updaters of every reported finding, and the
+// enrichers named in "enriched". Suppressed findings don't count.
+func attribute(vr *claircore.VulnerabilityReport, sources map[string]claircore.DataSource, enriched map[string]struct{}) {
+	if len(sources) == 0 {
+		return
+	}
+	used := make(map[string]struct{}, len(enriched))
+	for u := range enriched {
+		used[u] = struct{}{}
+	}
+	for _, ids := range vr.PackageVulnerabilities {
+		for _, id := range ids {
+			if v, ok := vr.Vulnerabilities[id]; ok {
+				used[v.Updater] = struct{}{}
+			}
+		}
+	}
+	// Many updaters can share a source, so only report each once.
+	seen := make(map[claircore.DataSource]struct{})
+	var out []claircore.DataSource
+	for u := range used {
+		src, ok := sources[u]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[src]; ok {
+			continue
+		}
+		seen[src] = struct{}{}
+		out = append(out, src)
+	}
+	if len(out) == 0 {
+		return
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		if out[i].License != out[j].License {
+			return out[i].License < out[j].License
+		}
+		return out[i].URL < out[j].URL
+	})
+	vr.Metadata.Attribution = out
 }
 
 // Getter returns a type implementing driver.EnrichmentGetter.
-func getter(s vulnstore.Enrichment, name string) *enrichmentGetter {
-	return &enrichmentGetter{s: s, name: name}
+//
+// If the Store supports them, the returned value also implements
+// driver.VulnerabilityGetter and driver.AliasResolver.
+func getter(s vulnstore.Enrichment, name string) driver.EnrichmentGetter {
+	eg := &enrichmentGetter{s: s, name: name}
+	l, lok := s.(vulnstore.VulnerabilityLookup)
+	r, rok := s.(vulnstore.AliasResolver)
+	switch {
+	case lok && rok:
+		return &aliasVulnerabilityGetter{
+			vulnerabilityGetter: &vulnerabilityGetter{enrichmentGetter: eg, l: l},
+			aliasResolver:       aliasResolver{r: r},
+		}
+	case lok:
+		return &vulnerabilityGetter{enrichmentGetter: eg, l: l}
+	case rok:
+		return &aliasGetter{enrichmentGetter: eg, aliasResolver: aliasResolver{r: r}}
+	}
+	return eg
 }
 
 type enrichmentGetter struct {
@@ -242,3 +417,39 @@ var _ driver.EnrichmentGetter = (*enrichmentGetter)(nil)
 func (e *enrichmentGetter) GetEnrichment(ctx context.Context, tags []string) ([]driver.EnrichmentRecord, error) {
 	return e.s.GetEnrichment(ctx, e.name, tags)
 }
+
+type vulnerabilityGetter struct {
+	*enrichmentGetter
+	l vulnstore.VulnerabilityLookup
+}
+
+var _ driver.VulnerabilityGetter = (*vulnerabilityGetter)(nil)
+
+func (v *vulnerabilityGetter) GetVulnerabilities(ctx context.Context, qs []driver.VulnerabilityQuery) ([][]*claircore.Vulnerability, error) {
+	return v.l.GetVulnerabilities(ctx, qs)
+}
+
+type aliasResolver struct {
+	r vulnstore.AliasResolver
+}
+
+func (a aliasResolver) ResolveAliases(ctx context.Context, names []string) (map[string][]string, error) {
+	return a.r.ResolveAliases(ctx, names)
+}
+
+type aliasGetter struct {
+	*enrichmentGetter
+	aliasResolver
+}
+
+var _ driver.AliasResolver = (*aliasGetter)(nil)
+
+type aliasVulnerabilityGetter struct {
+	*vulnerabilityGetter
+	aliasResolver
+}
+
+var (
+	_ driver.VulnerabilityGetter = (*aliasVulnerabilityGetter)(nil)
+	_ driver.AliasResolver       = (*aliasVulnerabilityGetter)(nil)
+)
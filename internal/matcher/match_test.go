@@ -0,0 +1,1094 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/enricher/cvss"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/risk"
+)
+
+// EmptyStore is a Store that never returns any results.
+type emptyStore struct{}
+
+func (emptyStore) Get(context.Context, []*claircore.IndexRecord, vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	return map[string][]*claircore.Vulnerability{}, nil
+}
+
+func (emptyStore) GetEnrichment(context.Context, string, []string) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+func (emptyStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return map[string][]driver.UpdateOperation{}, nil
+}
+
+// ReportEnricher reports the number of packages in the VulnerabilityReport.
+type reportEnricher struct{}
+
+func (reportEnricher) Name() string { return "report" }
+
+func (reportEnricher) Enrich(_ context.Context, _ driver.EnrichmentGetter, vr *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	return "test/report", []json.RawMessage{json.RawMessage(fmt.Sprintf(`{"packages":%d}`, len(vr.Packages)))}, nil
+}
+
+// DistEnricher annotates the report with the distributions detected in the
+// IndexReport, demonstrating driver.IndexReportEnricher.
+type distEnricher struct{}
+
+var _ driver.IndexReportEnricher = distEnricher{}
+
+func (distEnricher) Name() string { return "dist" }
+
+func (distEnricher) Enrich(context.Context, driver.EnrichmentGetter, *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	return "", nil, fmt.Errorf("Enrich called on an IndexReportEnricher")
+}
+
+func (distEnricher) EnrichWithIndexReport(_ context.Context, _ driver.EnrichmentGetter, ir *claircore.IndexReport, _ *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	var out []json.RawMessage
+	for _, d := range ir.Distributions {
+		out = append(out, json.RawMessage(fmt.Sprintf(`{"did":%q,"version_id":%q}`, d.DID, d.VersionID)))
+	}
+	return "test/dist", out, nil
+}
+
+func TestIndexReportEnricher(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "bash"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "debian", VersionID: "10"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	es := []driver.Enricher{reportEnricher{}, distEnricher{}}
+	vr, err := EnrichedMatch(ctx, ir, nil, es, emptyStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]json.RawMessage{
+		"test/report": {json.RawMessage(`{"packages":1}`)},
+		"test/dist":   {json.RawMessage(`{"did":"debian","version_id":"10"}`)},
+	}
+	if got := vr.Enrichments; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// SeverityStore returns the contained vulnerabilities for every package, the
+// contained CVSS records for matching tags, and the contained update
+// operations.
+type severityStore struct {
+	vulns []*claircore.Vulnerability
+	cvss  map[string]string
+	ops   map[string][]driver.UpdateOperation
+}
+
+func (s *severityStore) Get(_ context.Context, rs []*claircore.IndexRecord, _ vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range rs {
+		out[r.Package.ID] = s.vulns
+	}
+	return out, nil
+}
+
+func (s *severityStore) GetEnrichment(_ context.Context, _ string, tags []string) ([]driver.EnrichmentRecord, error) {
+	var out []driver.EnrichmentRecord
+	for _, t := range tags {
+		if e, ok := s.cvss[t]; ok {
+			out = append(out, driver.EnrichmentRecord{
+				Tags:       []string{t},
+				Enrichment: json.RawMessage(e),
+			})
+		}
+	}
+	return out, nil
+}
+
+func (s *severityStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return s.ops, nil
+}
+
+func TestInheritSeverity(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	vulns := []*claircore.Vulnerability{
+		{
+			ID:                 "1",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2020-28928",
+			FixedInVersion:     "1.1.24-r10",
+			NormalizedSeverity: claircore.Unknown,
+		},
+		{
+			ID:                 "2",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2019-14697",
+			FixedInVersion:     "1.1.24-r3",
+			NormalizedSeverity: claircore.Unknown,
+		},
+		{
+			ID:                 "3",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2020-0001",
+			FixedInVersion:     "1.1.24-r4",
+			NormalizedSeverity: claircore.Low,
+		},
+	}
+	s := &severityStore{
+		vulns: vulns,
+		cvss: map[string]string{
+			"CVE-2020-28928": `{"version":"3.1","baseScore":5.5,"baseSeverity":"MEDIUM"}`,
+			"CVE-2020-0001":  `{"version":"3.1","baseScore":9.8,"baseSeverity":"CRITICAL"}`,
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+
+	t.Run("Enriched", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		es := []driver.Enricher{&cvss.Enricher{}}
+		vr, err := EnrichedMatch(ctx, ir, ms, es, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]claircore.Severity{
+			"1": claircore.Medium,  // Inherited.
+			"2": claircore.Unknown, // No CVSS data.
+			"3": claircore.Low,     // Vendor severity is kept.
+		}
+		for id, sev := range want {
+			if got := vr.Vulnerabilities[id].NormalizedSeverity; got != sev {
+				t.Errorf("%s: got: %v, want: %v", id, got, sev)
+			}
+		}
+		if got, want := vulns[0].NormalizedSeverity, claircore.Unknown; got != want {
+			t.Errorf("stored vulnerability modified: got: %v, want: %v", got, want)
+		}
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, nil, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.Vulnerabilities["1"].NormalizedSeverity, claircore.Unknown; got != want {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	})
+}
+
+// TestRenormalizedSeverity checks that, with the option set, reports carry
+// the severity the shared table assigns rather than the stale stored one.
+func TestRenormalizedSeverity(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	vulns := []*claircore.Vulnerability{
+		{
+			ID:                 "1",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2020-28928",
+			FixedInVersion:     "1.1.24-r10",
+			Severity:           "Important",
+			NormalizedSeverity: claircore.Medium, // Stale.
+		},
+		{
+			ID:                 "2",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2019-14697",
+			FixedInVersion:     "1.1.24-r3",
+			Severity:           "moderate",
+			NormalizedSeverity: claircore.Medium,
+		},
+		{
+			ID:                 "3",
+			Updater:            "alpine-main-v3.12-updater",
+			Name:               "CVE-2020-0001",
+			FixedInVersion:     "1.1.24-r4",
+			Severity:           "7.5",
+			NormalizedSeverity: claircore.High, // Not in the table.
+		},
+	}
+	s := &severityStore{vulns: vulns}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+
+	tt := []struct {
+		name string
+		opts []Option
+		want map[string]claircore.Severity
+	}{
+		{
+			name: "Stored",
+			want: map[string]claircore.Severity{
+				"1": claircore.Medium,
+				"2": claircore.Medium,
+				"3": claircore.High,
+			},
+		},
+		{
+			name: "Renormalized",
+			opts: []Option{WithRenormalizedSeverity()},
+			want: map[string]claircore.Severity{
+				"1": claircore.High,
+				"2": claircore.Medium,
+				"3": claircore.High,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			vr, err := EnrichedMatch(ctx, ir, ms, nil, s, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]claircore.Severity, len(vr.Vulnerabilities))
+			for id, v := range vr.Vulnerabilities {
+				got[id] = v.NormalizedSeverity
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+			if got, want := vulns[0].NormalizedSeverity, claircore.Medium; got != want {
+				t.Errorf("stored vulnerability modified: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReportMetadata(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	alpineOp := driver.UpdateOperation{
+		Ref:     uuid.New(),
+		Updater: "alpine-main-v3.12-updater",
+		Date:    time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+		Kind:    driver.VulnerabilityKind,
+	}
+	cvssOp := driver.UpdateOperation{
+		Ref:     uuid.New(),
+		Updater: "clair.cvss",
+		Date:    time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+		Kind:    driver.EnrichmentKind,
+	}
+	// The debian updater has run, but has no data for this manifest.
+	debianOp := driver.UpdateOperation{
+		Ref:     uuid.New(),
+		Updater: "debian-buster-updater",
+		Date:    time.Date(2020, 11, 3, 0, 0, 0, 0, time.UTC),
+		Kind:    driver.VulnerabilityKind,
+	}
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{
+				ID:             "1",
+				Updater:        alpineOp.Updater,
+				Name:           "CVE-2020-28928",
+				FixedInVersion: "1.1.24-r10",
+			},
+		},
+		ops: map[string][]driver.UpdateOperation{
+			alpineOp.Updater: {alpineOp},
+			cvssOp.Updater:   {cvssOp},
+			debianOp.Updater: {debianOp},
+			"photon-updater": {},
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+	es := []driver.Enricher{&cvss.Enricher{}}
+
+	vr, err := EnrichedMatch(ctx, ir, ms, es, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi := claircore.ReadBuildInfo()
+	want := &claircore.ReportMetadata{
+		UpdateOperations: map[string]claircore.UpdateRef{
+			alpineOp.Updater: {Ref: alpineOp.Ref, Date: alpineOp.Date},
+			cvssOp.Updater:   {Ref: cvssOp.Ref, Date: cvssOp.Date},
+			debianOp.Updater: {Ref: debianOp.Ref, Date: debianOp.Date},
+		},
+		Claircore: &bi,
+	}
+	if got := vr.Metadata; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for id, v := range vr.Vulnerabilities {
+		if _, ok := vr.Metadata.UpdateOperations[v.Updater]; !ok {
+			t.Errorf("vulnerability %s: no update operation for updater %q", id, v.Updater)
+		}
+	}
+}
+
+// UpdatingStore is a Store that commits an update operation, replacing the
+// vulnerabilities, the first time Get is called. Get honors UpdateRefs.
+type updatingStore struct {
+	emptyStore
+	mu    sync.Mutex
+	ops   []driver.UpdateOperation
+	vulns map[uuid.UUID][]*claircore.Vulnerability
+	next  driver.UpdateOperation
+	added []*claircore.Vulnerability
+}
+
+func (s *updatingStore) Get(_ context.Context, rs []*claircore.IndexRecord, opts vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.added != nil {
+		s.ops = append([]driver.UpdateOperation{s.next}, s.ops...)
+		s.vulns[s.next.Ref] = s.added
+		s.added = nil
+	}
+	refs := opts.UpdateRefs
+	if len(refs) == 0 {
+		refs = []uuid.UUID{s.ops[0].Ref}
+	}
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range rs {
+		for _, ref := range refs {
+			out[r.Package.ID] = append(out[r.Package.ID], s.vulns[ref]...)
+		}
+	}
+	return out, nil
+}
+
+func (s *updatingStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string][]driver.UpdateOperation{
+		s.ops[0].Updater: append([]driver.UpdateOperation(nil), s.ops...),
+	}, nil
+}
+
+// TestPinnedRefs checks that an update committed after the report's update
+// operations are read, but before the store is queried, doesn't change the
+// report.
+func TestPinnedRefs(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	const updater = "alpine-main-v3.12-updater"
+	first := driver.UpdateOperation{
+		Ref:     uuid.New(),
+		Updater: updater,
+		Date:    time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+		Kind:    driver.VulnerabilityKind,
+	}
+	second := driver.UpdateOperation{
+		Ref:     uuid.New(),
+		Updater: updater,
+		Date:    time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+		Kind:    driver.VulnerabilityKind,
+	}
+	s := &updatingStore{
+		ops: []driver.UpdateOperation{first},
+		vulns: map[uuid.UUID][]*claircore.Vulnerability{
+			first.Ref: {{ID: "1", Updater: updater, Name: "CVE-2020-28928", FixedInVersion: "1.1.24-r10"}},
+		},
+		next:  second,
+		added: []*claircore.Vulnerability{{ID: "2", Updater: updater, Name: "CVE-2020-28929", FixedInVersion: "1.1.24-r10"}},
+	}
+
+	vr, err := EnrichedMatch(ctx, ir, []driver.Matcher{&alpine.Matcher{}}, nil, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vr.Metadata.UpdateOperations[updater].Ref, first.Ref; got != want {
+		t.Errorf("got ref %v, want %v", got, want)
+	}
+	var got []string
+	for _, v := range vr.Vulnerabilities {
+		got = append(got, v.Name)
+	}
+	if want := []string{"CVE-2020-28928"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// DistMatcher reports every vulnerability as affecting records on the
+// distribution with ID "vulnerable".
+type distMatcher struct{}
+
+func (distMatcher) Name() string                       { return "dist" }
+func (distMatcher) Filter(*claircore.IndexRecord) bool { return true }
+func (distMatcher) Query() []driver.MatchConstraint    { return nil }
+func (distMatcher) Vulnerable(_ context.Context, r *claircore.IndexRecord, _ *claircore.Vulnerability) (bool, error) {
+	return r.Distribution.ID == "vulnerable", nil
+}
+
+// TestFilterEnvironments checks that a package found in more than one package
+// database has each database's record evaluated on its own.
+func TestFilterEnvironments(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	pkg := &claircore.Package{ID: "1", Name: "openssl", PackageDB: "/var/lib/rpm"}
+	vuln := &claircore.Vulnerability{ID: "1", Name: "CVE-2021-3449"}
+	vulns := map[string][]*claircore.Vulnerability{pkg.ID: {vuln}}
+	host := &claircore.IndexRecord{Package: pkg, Distribution: &claircore.Distribution{ID: "vulnerable"}}
+	chroot := &claircore.IndexRecord{Package: pkg, Distribution: &claircore.Distribution{ID: "fixed"}}
+
+	tt := []struct {
+		Name    string
+		Records []*claircore.IndexRecord
+		Want    []*claircore.Vulnerability
+	}{
+		{Name: "AffectedFirst", Records: []*claircore.IndexRecord{host, chroot}, Want: []*claircore.Vulnerability{vuln}},
+		{Name: "AffectedLast", Records: []*claircore.IndexRecord{chroot, host}, Want: []*claircore.Vulnerability{vuln}},
+		{Name: "Duplicate", Records: []*claircore.IndexRecord{host, host}, Want: []*claircore.Vulnerability{vuln}},
+		{Name: "Unaffected", Records: []*claircore.IndexRecord{chroot, chroot}, Want: []*claircore.Vulnerability{}},
+	}
+	mc := NewController(distMatcher{}, emptyStore{})
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			got, _, err := mc.filter(ctx, tc.Records, vulns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got[pkg.ID], tc.Want) {
+				t.Error(cmp.Diff(got[pkg.ID], tc.Want))
+			}
+		})
+	}
+}
+
+// LookupStore is an emptyStore that can look up vulnerabilities.
+type lookupStore struct{ emptyStore }
+
+func (lookupStore) GetVulnerabilities(_ context.Context, qs []driver.VulnerabilityQuery) ([][]*claircore.Vulnerability, error) {
+	return make([][]*claircore.Vulnerability, len(qs)), nil
+}
+
+func TestGetter(t *testing.T) {
+	if _, ok := getter(emptyStore{}, "test").(driver.VulnerabilityGetter); ok {
+		t.Error("getter for a store without lookups implements driver.VulnerabilityGetter")
+	}
+	if _, ok := getter(lookupStore{}, "test").(driver.VulnerabilityGetter); !ok {
+		t.Error("getter for a store with lookups doesn't implement driver.VulnerabilityGetter")
+	}
+}
+
+func TestIssuedCutoff(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+			"2": {ID: "2", Name: "musl-utils", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{DistributionID: "1"}},
+		},
+	}
+	cutoff := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "old", Name: "CVE-2010-0001", FixedInVersion: "1.1.24-r10", Issued: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: "new", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10", Issued: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: "cutoff", Name: "CVE-2015-0001", FixedInVersion: "1.1.24-r10", Issued: cutoff},
+			// Records without an issued date are always kept.
+			{ID: "undated", Name: "CVE-0000-0001", FixedInVersion: "1.1.24-r10"},
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		want     []string
+		cutoff   *time.Time
+		filtered int
+	}{
+		{
+			name: "Default",
+			want: []string{"cutoff", "new", "old", "undated"},
+		},
+		{
+			name: "Zero",
+			opts: []Option{WithIssuedCutoff(time.Time{})},
+			want: []string{"cutoff", "new", "old", "undated"},
+		},
+		{
+			name:     "Cutoff",
+			opts:     []Option{WithIssuedCutoff(cutoff)},
+			want:     []string{"cutoff", "new", "undated"},
+			cutoff:   &cutoff,
+			filtered: 2, // One vulnerability, for two packages.
+		},
+	}
+	check := func(t *testing.T, vr *claircore.VulnerabilityReport, want []string, cutoff *time.Time, filtered int) {
+		t.Helper()
+		var got []string
+		for id := range vr.Vulnerabilities {
+			got = append(got, id)
+		}
+		sort.Strings(got)
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		for pkg, ids := range vr.PackageVulnerabilities {
+			if len(ids) != len(want) {
+				t.Errorf("package %s: got %d findings, want %d", pkg, len(ids), len(want))
+			}
+		}
+		var gotCutoff *time.Time
+		var gotFiltered int
+		if vr.Metadata != nil {
+			gotCutoff, gotFiltered = vr.Metadata.IssuedCutoff, vr.Metadata.FilteredByAge
+		}
+		if !cmp.Equal(gotCutoff, cutoff) {
+			t.Error(cmp.Diff(gotCutoff, cutoff))
+		}
+		if gotFiltered != filtered {
+			t.Errorf("got %d filtered findings, want %d", gotFiltered, filtered)
+		}
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("Match", func(t *testing.T) {
+				ctx := zlog.Test(ctx, t)
+				vr, err := Match(ctx, ir, ms, s, tc.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				check(t, vr, tc.want, tc.cutoff, tc.filtered)
+			})
+			t.Run("EnrichedMatch", func(t *testing.T) {
+				ctx := zlog.Test(ctx, t)
+				vr, err := EnrichedMatch(ctx, ir, ms, nil, s, tc.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				check(t, vr, tc.want, tc.cutoff, tc.filtered)
+			})
+		})
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	other := claircore.MustParseDigest(`sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`)
+	ir := &claircore.IndexReport{
+		Hash: manifest,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+			"2": {ID: "2", Name: "musl-utils", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{DistributionID: "1"}},
+		},
+	}
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10"},
+			{ID: "b", Name: "CVE-2020-0002", FixedInVersion: "1.1.24-r10"},
+			{ID: "c", Name: "CVE-2020-0003", FixedInVersion: "1.1.24-r10"},
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+	es := []claircore.Exclusion{
+		// Only for musl, not musl-utils.
+		{ID: uuid.New(), Vulnerability: "CVE-2020-0001", Package: "musl", Reason: "not reachable"},
+		// Version pattern.
+		{ID: uuid.New(), Vulnerability: "CVE-2020-0002", Package: "musl-utils", Version: "1.1.24-*", Reason: "patched"},
+		// Doesn't match the version.
+		{ID: uuid.New(), Vulnerability: "CVE-2020-0003", Package: "musl", Version: "1.2.*", Reason: "patched"},
+		// Scoped to another manifest.
+		{ID: uuid.New(), Vulnerability: "CVE-2020-0003", Package: "musl-utils", Manifest: &other, Reason: "other image"},
+	}
+	wantFindings := map[string][]string{
+		"1": {"b", "c"},
+		"2": {"a", "c"},
+	}
+	wantSuppressed := map[string][]claircore.Suppression{
+		"1": {{VulnerabilityID: "a", Exclusion: es[0].ID, Reason: "not reachable"}},
+		"2": {{VulnerabilityID: "b", Exclusion: es[1].ID, Reason: "patched"}},
+	}
+	check := func(t *testing.T, vr *claircore.VulnerabilityReport) {
+		t.Helper()
+		for _, ids := range vr.PackageVulnerabilities {
+			sort.Strings(ids)
+		}
+		if got, want := vr.PackageVulnerabilities, wantFindings; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		if got, want := vr.Suppressed, wantSuppressed; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		// Suppressed vulnerabilities can still be looked up.
+		for _, id := range []string{"a", "b", "c"} {
+			if _, ok := vr.Vulnerabilities[id]; !ok {
+				t.Errorf("vulnerability %q missing", id)
+			}
+		}
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := Match(ctx, ir, ms, s, WithExclusions(es))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, vr)
+	})
+	t.Run("EnrichedMatch", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, nil, s, WithExclusions(es))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, vr)
+	})
+}
+
+// TestAttribution checks that only the data sources of updaters contributing
+// findings or enrichments are listed in the report's metadata.
+func TestAttribution(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	secdb := claircore.DataSource{Name: "Alpine SecDB", URL: "https://secdb.alpinelinux.org/"}
+	nvd := claircore.DataSource{Name: "NVD", URL: "https://nvd.nist.gov/developers/terms-of-use"}
+	osv := claircore.DataSource{Name: "OSV", License: "CC-BY-4.0", URL: "https://osv.dev/"}
+	debian := claircore.DataSource{Name: "Debian Security Tracker", URL: "https://security-tracker.debian.org/"}
+	op := func(name string, src *claircore.DataSource) []driver.UpdateOperation {
+		return []driver.UpdateOperation{{Ref: uuid.New(), Updater: name, Source: src}}
+	}
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "1", Updater: "alpine-main-v3.12-updater", Name: "CVE-2020-28928", FixedInVersion: "1.1.24-r10"},
+			// Shares a source with the above.
+			{ID: "2", Updater: "alpine-community-v3.12-updater", Name: "CVE-2019-14697", FixedInVersion: "1.1.24-r3"},
+			// Suppressed, so doesn't contribute.
+			{ID: "3", Updater: "osv", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r4"},
+			// No declared source.
+			{ID: "4", Updater: "secret-updater", Name: "CVE-2020-0002", FixedInVersion: "1.1.24-r4"},
+		},
+		cvss: map[string]string{
+			"CVE-2020-28928": `{"version":"3.1","baseScore":5.5,"baseSeverity":"MEDIUM"}`,
+		},
+		ops: map[string][]driver.UpdateOperation{
+			"alpine-main-v3.12-updater":      op("alpine-main-v3.12-updater", &secdb),
+			"alpine-community-v3.12-updater": op("alpine-community-v3.12-updater", &secdb),
+			"osv":                            op("osv", &osv),
+			"secret-updater":                 op("secret-updater", nil),
+			"debian-buster-updater":          op("debian-buster-updater", &debian),
+			(&cvss.Enricher{}).Name():        op((&cvss.Enricher{}).Name(), &nvd),
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+	exclude := WithExclusions([]claircore.Exclusion{
+		{ID: uuid.New(), Vulnerability: "CVE-2020-0001", Package: "musl", Reason: "test"},
+	})
+
+	t.Run("Enriched", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, []driver.Enricher{&cvss.Enricher{}}, s, exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []claircore.DataSource{secdb, nvd}
+		if got := vr.Metadata.Attribution; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("NotEnriched", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, nil, s, exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []claircore.DataSource{secdb}
+		if got := vr.Metadata.Attribution; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+}
+
+// TestIndeterminate checks that findings a Matcher can't evaluate because of
+// an unparseable version are reported in their own section, or dropped if
+// asked.
+func TestIndeterminate(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+			"2": {ID: "2", Name: "busybox", Version: "latest"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{DistributionID: "1"}},
+		},
+	}
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10"},
+			{ID: "b", Name: "CVE-2020-0002", FixedInVersion: "fixed-soon"},
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+	wantFindings := map[string][]string{
+		"1": {"a"},
+	}
+	wantIndeterminate := map[string][]claircore.Indeterminate{
+		"1": {
+			{VulnerabilityID: "b", Version: "fixed-soon", Reason: `unparseable vulnerability version "fixed-soon": invalid version`},
+		},
+		"2": {
+			{VulnerabilityID: "a", Version: "latest", Reason: `unparseable package version "latest": invalid version`},
+			{VulnerabilityID: "b", Version: "latest", Reason: `unparseable package version "latest": invalid version`},
+		},
+	}
+	sortIndeterminate := func(m map[string][]claircore.Indeterminate) {
+		for _, is := range m {
+			sort.Slice(is, func(i, j int) bool { return is[i].VulnerabilityID < is[j].VulnerabilityID })
+		}
+	}
+
+	for _, tc := range []struct {
+		Name  string
+		Match func(context.Context, ...Option) (*claircore.VulnerabilityReport, error)
+	}{
+		{
+			Name: "Match",
+			Match: func(ctx context.Context, opts ...Option) (*claircore.VulnerabilityReport, error) {
+				return Match(ctx, ir, ms, s, opts...)
+			},
+		},
+		{
+			Name: "EnrichedMatch",
+			Match: func(ctx context.Context, opts ...Option) (*claircore.VulnerabilityReport, error) {
+				return EnrichedMatch(ctx, ir, ms, nil, s, opts...)
+			},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			vr, err := tc.Match(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := vr.PackageVulnerabilities, wantFindings; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			sortIndeterminate(vr.Indeterminate)
+			if got, want := vr.Indeterminate, wantIndeterminate; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			for _, id := range []string{"a", "b"} {
+				if _, ok := vr.Vulnerabilities[id]; !ok {
+					t.Errorf("vulnerability %q missing", id)
+				}
+			}
+
+			vr, err = tc.Match(ctx, WithDropIndeterminate())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := vr.PackageVulnerabilities, wantFindings; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			if len(vr.Indeterminate) != 0 {
+				t.Errorf("unexpected indeterminate findings: %v", vr.Indeterminate)
+			}
+			if got, want := vr.Metadata.DroppedIndeterminate, 3; got != want {
+				t.Errorf("got %d dropped, want %d", got, want)
+			}
+		})
+	}
+}
+
+// TestSettleIndeterminate checks that a finding reported as affecting a
+// package by one Matcher isn't also reported as indeterminate by another.
+func TestSettleIndeterminate(t *testing.T) {
+	v := &claircore.Vulnerability{ID: "a"}
+	verr := &driver.VersionError{Version: "latest", Err: driver.ErrEmptyVersion}
+	c := collector{
+		vr: &claircore.VulnerabilityReport{
+			Vulnerabilities:        map[string]*claircore.Vulnerability{},
+			PackageVulnerabilities: map[string][]string{},
+		},
+		opts: newOptions(nil),
+	}
+	c.add(&Result{Indeterminate: map[string][]Indeterminate{"1": {{Vulnerability: v, Err: verr}}}})
+	c.add(&Result{Indeterminate: map[string][]Indeterminate{"1": {{Vulnerability: v, Err: verr}}}})
+	c.add(&Result{Vulnerabilities: map[string][]*claircore.Vulnerability{"2": {v}}})
+	c.add(&Result{Indeterminate: map[string][]Indeterminate{"2": {{Vulnerability: v, Err: verr}}}})
+	c.finish()
+	if got, want := len(c.vr.Indeterminate["1"]), 1; got != want {
+		t.Errorf("got %d indeterminate findings, want %d", got, want)
+	}
+	if _, ok := c.vr.Indeterminate["2"]; ok {
+		t.Error("affected finding also reported as indeterminate")
+	}
+}
+
+// EpssEnricher reports the contained EPSS entries, keyed by vulnerability
+// name, for the vulnerabilities in the report.
+type epssEnricher map[string]string
+
+func (epssEnricher) Name() string { return "epss" }
+
+func (e epssEnricher) Enrich(_ context.Context, _ driver.EnrichmentGetter, vr *claircore.VulnerabilityReport) (string, []json.RawMessage, error) {
+	m := make(map[string]json.RawMessage)
+	for id, v := range vr.Vulnerabilities {
+		if r, ok := e[v.Name]; ok {
+			m[id] = json.RawMessage(r)
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", nil, err
+	}
+	return risk.EPSSType, []json.RawMessage{b}, nil
+}
+
+func TestRiskHints(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	s := &severityStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "cvss", Name: "CVE-2020-28928", FixedInVersion: "1.1.24-r10"},
+			{ID: "epss", Name: "CVE-2019-14697", FixedInVersion: "1.1.24-r3"},
+			{ID: "both", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r4", NormalizedSeverity: claircore.Low},
+			{ID: "neither", Name: "CVE-2020-0002", FixedInVersion: "1.1.24-r5", NormalizedSeverity: claircore.High},
+		},
+		cvss: map[string]string{
+			"CVE-2020-28928": `{"version":"3.1","baseScore":5.5,"baseSeverity":"MEDIUM"}`,
+			"CVE-2020-0001":  `{"version":"3.1","baseScore":9.8,"baseSeverity":"CRITICAL"}`,
+		},
+	}
+	ms := []driver.Matcher{&alpine.Matcher{}}
+	es := []driver.Enricher{
+		&cvss.Enricher{},
+		epssEnricher{
+			"CVE-2019-14697": `{"epss":"0.50000","percentile":"0.97"}`,
+			"CVE-2020-0001":  `[{"epss":0.97}]`,
+		},
+	}
+	sev := func(s claircore.Severity) claircore.RiskInput {
+		return claircore.RiskInput{Name: risk.InputSeverity, Value: float64(s)}
+	}
+
+	t.Run("Requested", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, es, s, WithRiskScorer(risk.Default{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hint := func(score float64, in ...claircore.RiskInput) claircore.RiskHint {
+			return claircore.RiskHint{
+				Score:   score,
+				Derived: true,
+				Scorer:  risk.Default{}.Name(),
+				Inputs:  in,
+			}
+		}
+		want := map[string]claircore.RiskHint{
+			"cvss": hint(550003,
+				claircore.RiskInput{Name: risk.InputCVSS, Value: 5.5, Enrichment: cvss.Type},
+				sev(claircore.Medium)), // Inherited from the CVSS data.
+			"epss": hint(5000,
+				claircore.RiskInput{Name: risk.InputEPSS, Value: 0.5, Enrichment: risk.EPSSType},
+				sev(claircore.Unknown)),
+			"both": hint(989692,
+				claircore.RiskInput{Name: risk.InputCVSS, Value: 9.8, Enrichment: cvss.Type},
+				claircore.RiskInput{Name: risk.InputEPSS, Value: 0.97, Enrichment: risk.EPSSType},
+				sev(claircore.Low)),
+			"neither": hint(4, sev(claircore.High)),
+		}
+		if got := vr.RiskHints; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("NotRequested", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		vr, err := EnrichedMatch(ctx, ir, ms, es, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vr.RiskHints != nil {
+			t.Errorf("unexpected hints: %v", vr.RiskHints)
+		}
+	})
+}
+
+// FileMatcher reports every vulnerability as affecting every record, but
+// requires a class file for the vulnerability named "needs-class".
+type fileMatcher struct{ distMatcher }
+
+func (fileMatcher) Vulnerable(context.Context, *claircore.IndexRecord, *claircore.Vulnerability) (bool, error) {
+	return true, nil
+}
+
+func (fileMatcher) RequiredFiles(_ *claircore.IndexRecord, v *claircore.Vulnerability) []string {
+	if v.Name == "needs-class" {
+		return []string{"com/example/*.class"}
+	}
+	return nil
+}
+
+// TestFileMatcher checks that vulnerabilities are dropped when the files a
+// FileMatcher requires weren't recorded with the package.
+func TestFileMatcher(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	plain := &claircore.Vulnerability{ID: "1", Name: "plain"}
+	needs := &claircore.Vulnerability{ID: "2", Name: "needs-class"}
+	store := &severityStore{vulns: []*claircore.Vulnerability{plain, needs}}
+	rs := []*claircore.IndexRecord{
+		// Nothing's known about the files, so everything is reported.
+		{Package: &claircore.Package{ID: "unknown"}},
+		{Package: &claircore.Package{ID: "present", Files: []string{"pom.properties", "com/example/Bad.class"}}},
+		{Package: &claircore.Package{ID: "absent", Files: []string{"pom.properties", "com/example/sub/Bad.class"}}},
+	}
+	want := map[string][]*claircore.Vulnerability{
+		"unknown": {plain, needs},
+		"present": {plain, needs},
+		"absent":  {plain},
+	}
+
+	res, err := NewController(fileMatcher{}, store).Match(ctx, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Vulnerabilities; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// MapResolver resolves aliases from a map.
+type mapResolver map[string][]string
+
+func (m mapResolver) ResolveAliases(_ context.Context, names []string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, n := range names {
+		if as, ok := m[n]; ok {
+			out[n] = as
+		}
+	}
+	return out, nil
+}
+
+func TestResolveAliases(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	orig := &claircore.Vulnerability{
+		Name:    "RHSA-2021:0001",
+		Aliases: []string{"CVE-2021-0002"},
+	}
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": orig,
+			"2": {Name: "CVE-2021-0003"},
+		},
+	}
+	r := mapResolver{
+		"RHSA-2021:0001": {"CVE-2021-0001", "GHSA-aaaa-bbbb-cccc"},
+	}
+	if err := resolveAliases(ctx, vr, r); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"CVE-2021-0001", "CVE-2021-0002", "GHSA-aaaa-bbbb-cccc"}
+	if got := vr.Vulnerabilities["1"].Aliases; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got := vr.Vulnerabilities["2"].Aliases; got != nil {
+		t.Errorf("got aliases %v for unaliased vulnerability", got)
+	}
+	// The vulnerability may be shared with other reports.
+	if got, want := orig.Aliases, []string{"CVE-2021-0002"}; !cmp.Equal(got, want) {
+		t.Errorf("original modified: %v", cmp.Diff(got, want))
+	}
+}
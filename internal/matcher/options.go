@@ -0,0 +1,219 @@
+package matcher
+
+import (
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Option configures optional behavior of Match and EnrichedMatch.
+type Option func(*options)
+
+type options struct {
+	cutoff            time.Time
+	exclusions        []claircore.Exclusion
+	dropIndeterminate bool
+	riskScorer        driver.RiskScorer
+	staleCutoff       time.Time
+	renormalize       bool
+}
+
+// WithIssuedCutoff drops findings for vulnerabilities issued before "t" from
+// the report. Vulnerabilities without an issued date are kept.
+//
+// The number of dropped findings is recorded in the report's metadata. A zero
+// Time disables filtering.
+func WithIssuedCutoff(t time.Time) Option {
+	return func(o *options) {
+		o.cutoff = t
+	}
+}
+
+// WithExclusions moves findings matched by any of the provided Exclusions from
+// the report's PackageVulnerabilities to its Suppressed section. Expiry isn't
+// checked; the caller should only pass Exclusions that are active.
+func WithExclusions(es []claircore.Exclusion) Option {
+	return func(o *options) {
+		o.exclusions = es
+	}
+}
+
+// WithDropIndeterminate leaves findings that couldn't be evaluated, because a
+// version couldn't be parsed, out of the report instead of listing them in its
+// Indeterminate section.
+//
+// The number of dropped findings is recorded in the report's metadata.
+func WithDropIndeterminate() Option {
+	return func(o *options) {
+		o.dropIndeterminate = true
+	}
+}
+
+// WithRiskScorer records a RiskHint computed by "rs" for every vulnerability
+// in the report, after enrichments and inherited severities are in place.
+func WithRiskScorer(rs driver.RiskScorer) Option {
+	return func(o *options) {
+		o.riskScorer = rs
+	}
+}
+
+// WithStaleCutoff adds a warning to the report for every updater whose latest
+// update operation is before "t". It only has an effect on EnrichedMatch,
+// which records the update operations. A zero Time disables the warnings.
+func WithStaleCutoff(t time.Time) Option {
+	return func(o *options) {
+		o.staleCutoff = t
+	}
+}
+
+// WithRenormalizedSeverity re-derives every reported vulnerability's
+// NormalizedSeverity from its Severity using claircore.NormalizeSeverity,
+// instead of using the value stored when its updater ran. It's applied before
+// severities are inherited from enrichments.
+func WithRenormalizedSeverity() Option {
+	return func(o *options) {
+		o.renormalize = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	var o options
+	for _, f := range opts {
+		f(&o)
+	}
+	return &o
+}
+
+// Collector adds matched vulnerabilities to a report, applying any filters.
+//
+// Collectors aren't safe for concurrent use.
+type collector struct {
+	vr       *claircore.VulnerabilityReport
+	opts     *options
+	filtered int
+	dropped  int
+}
+
+// Add adds the vulnerabilities affecting each package, and the indeterminate
+// findings, to the report.
+func (c *collector) add(res *Result) {
+	for pkg, vs := range res.Vulnerabilities {
+		for _, v := range vs {
+			if c.tooOld(v) {
+				c.filtered++
+				continue
+			}
+			c.vr.Vulnerabilities[v.ID] = v
+			if c.suppress(pkg, v) {
+				continue
+			}
+			c.vr.PackageVulnerabilities[pkg] = append(c.vr.PackageVulnerabilities[pkg], v.ID)
+		}
+	}
+	for pkg, is := range res.Indeterminate {
+		for _, i := range is {
+			v := i.Vulnerability
+			if c.tooOld(v) {
+				c.filtered++
+				continue
+			}
+			if c.opts.dropIndeterminate {
+				c.dropped++
+				continue
+			}
+			c.vr.Vulnerabilities[v.ID] = v
+			if c.suppress(pkg, v) {
+				continue
+			}
+			if c.vr.Indeterminate == nil {
+				c.vr.Indeterminate = make(map[string][]claircore.Indeterminate)
+			}
+			c.vr.Indeterminate[pkg] = append(c.vr.Indeterminate[pkg], claircore.Indeterminate{
+				VulnerabilityID: v.ID,
+				Version:         i.Err.Version,
+				Reason:          i.Err.Error(),
+			})
+		}
+	}
+}
+
+// Suppress records the vulnerability on the package with the ID "pkg" as
+// suppressed if an Exclusion matches it, and reports whether one did.
+func (c *collector) suppress(pkg string, v *claircore.Vulnerability) bool {
+	e := c.excluded(pkg, v)
+	if e == nil {
+		return false
+	}
+	if c.vr.Suppressed == nil {
+		c.vr.Suppressed = make(map[string][]claircore.Suppression)
+	}
+	c.vr.Suppressed[pkg] = append(c.vr.Suppressed[pkg], claircore.Suppression{
+		VulnerabilityID: v.ID,
+		Exclusion:       e.ID,
+		Reason:          e.Reason,
+	})
+	return true
+}
+
+func (c *collector) tooOld(v *claircore.Vulnerability) bool {
+	return !c.opts.cutoff.IsZero() && !v.Issued.IsZero() && v.Issued.Before(c.opts.cutoff)
+}
+
+// Excluded returns the first Exclusion matching the vulnerability on the
+// package with the ID "pkg", or nil.
+func (c *collector) excluded(pkg string, v *claircore.Vulnerability) *claircore.Exclusion {
+	p := c.vr.Packages[pkg]
+	for i := range c.opts.exclusions {
+		e := &c.opts.exclusions[i]
+		if e.Matches(c.vr.Hash, p, v) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Finish settles findings reported by more than one Matcher and records what
+// was filtered in the report's metadata.
+func (c *collector) finish() {
+	c.settle()
+	if c.opts.cutoff.IsZero() && c.dropped == 0 {
+		return
+	}
+	if c.vr.Metadata == nil {
+		c.vr.Metadata = &claircore.ReportMetadata{}
+	}
+	if !c.opts.cutoff.IsZero() {
+		cutoff := c.opts.cutoff
+		c.vr.Metadata.IssuedCutoff = &cutoff
+		c.vr.Metadata.FilteredByAge = c.filtered
+	}
+	c.vr.Metadata.DroppedIndeterminate = c.dropped
+}
+
+// Settle removes indeterminate findings that another Matcher reported as
+// affecting the package, or that are duplicated.
+func (c *collector) settle() {
+	for pkg, is := range c.vr.Indeterminate {
+		seen := make(map[string]struct{}, len(is)+len(c.vr.PackageVulnerabilities[pkg]))
+		for _, id := range c.vr.PackageVulnerabilities[pkg] {
+			seen[id] = struct{}{}
+		}
+		out := is[:0]
+		for _, i := range is {
+			if _, ok := seen[i.VulnerabilityID]; ok {
+				continue
+			}
+			seen[i.VulnerabilityID] = struct{}{}
+			out = append(out, i)
+		}
+		if len(out) == 0 {
+			delete(c.vr.Indeterminate, pkg)
+			continue
+		}
+		c.vr.Indeterminate[pkg] = out
+	}
+	if len(c.vr.Indeterminate) == 0 {
+		c.vr.Indeterminate = nil
+	}
+}
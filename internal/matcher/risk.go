@@ -0,0 +1,62 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// VulnMapType is the prefix of enrichment types whose messages are maps keyed
+// by vulnerability ID.
+const vulnMapType = `message/vnd.clair.map.vulnerability`
+
+// ScoreRisk records a RiskHint computed by "rs" for every vulnerability in the
+// report.
+func scoreRisk(ctx context.Context, vr *claircore.VulnerabilityReport, rs driver.RiskScorer) {
+	// Split the enrichments up by vulnerability.
+	byVuln := make(map[string]map[string][]json.RawMessage)
+	for kind, msgs := range vr.Enrichments {
+		if !strings.HasPrefix(kind, vulnMapType) {
+			continue
+		}
+		for _, msg := range msgs {
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal(msg, &m); err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("kind", kind).
+					Msg("unable to decode enrichment")
+				continue
+			}
+			for id, e := range m {
+				if _, ok := vr.Vulnerabilities[id]; !ok {
+					continue
+				}
+				es, ok := byVuln[id]
+				if !ok {
+					es = make(map[string][]json.RawMessage)
+					byVuln[id] = es
+				}
+				es[kind] = append(es[kind], e)
+			}
+		}
+	}
+	name := rs.Name()
+	for id, v := range vr.Vulnerabilities {
+		h, ok := rs.Score(ctx, v, byVuln[id])
+		if !ok {
+			continue
+		}
+		h.Derived = true
+		h.Scorer = name
+		if vr.RiskHints == nil {
+			vr.RiskHints = make(map[string]claircore.RiskHint, len(vr.Vulnerabilities))
+		}
+		vr.RiskHints[id] = h
+	}
+}
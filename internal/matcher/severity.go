@@ -0,0 +1,126 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/enricher/cvss"
+)
+
+// InheritSeverity fills in the NormalizedSeverity for vulnerabilities in the
+// report that don't have one, using the CVSS enrichment data if the CVSS
+// enricher is configured.
+//
+// Some sources (Alpine's secdb, for example) carry no severity at all, and
+// without this findings from them are reported as Unknown.
+//
+// The stored records are never modified: a vulnerability that gains a
+// severity is copied and the copy is placed in the report.
+func inheritSeverity(ctx context.Context, vr *claircore.VulnerabilityReport) {
+	// The CVSS enricher only reports data for vulnerabilities that mention a
+	// CVE ID by name or in their links, so the presence of an entry is the
+	// CVE check.
+	var m map[string][]cvssV3
+	for _, msg := range vr.Enrichments[cvss.Type] {
+		if err := json.Unmarshal(msg, &m); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("unable to decode cvss enrichment")
+			return
+		}
+	}
+	for id, cs := range m {
+		v, ok := vr.Vulnerabilities[id]
+		if !ok || v.NormalizedSeverity != claircore.Unknown {
+			continue
+		}
+		// If there are multiple records, take the most severe one.
+		sev := claircore.Unknown
+		for _, c := range cs {
+			if s := c.Severity(); s > sev {
+				sev = s
+			}
+		}
+		if sev == claircore.Unknown {
+			continue
+		}
+		zlog.Debug(ctx).
+			Str("vuln", v.Name).
+			Stringer("severity", sev).
+			Msg("inherited severity from cvss")
+		nv := *v
+		nv.NormalizedSeverity = sev
+		vr.Vulnerabilities[id] = &nv
+	}
+}
+
+// CvssV3 is the subset of the NVD cvss-v3.x schema needed to determine a
+// severity.
+type cvssV3 struct {
+	BaseScore    *float64 `json:"baseScore"`
+	BaseSeverity string   `json:"baseSeverity"`
+}
+
+// Severity reports the normalized severity for the CVSS object, falling back
+// to the qualitative rating scale in the CVSS v3 spec if the severity is
+// not present.
+func (c *cvssV3) Severity() claircore.Severity {
+	switch strings.ToUpper(c.BaseSeverity) {
+	case "NONE":
+		return claircore.Negligible
+	case "LOW":
+		return claircore.Low
+	case "MEDIUM":
+		return claircore.Medium
+	case "HIGH":
+		return claircore.High
+	case "CRITICAL":
+		return claircore.Critical
+	}
+	if c.BaseScore == nil {
+		return claircore.Unknown
+	}
+	switch s := *c.BaseScore; {
+	case s == 0:
+		return claircore.Negligible
+	case s < 4:
+		return claircore.Low
+	case s < 7:
+		return claircore.Medium
+	case s < 9:
+		return claircore.High
+	default:
+		return claircore.Critical
+	}
+}
+
+// RenormalizeSeverity re-derives the NormalizedSeverity of every
+// vulnerability in the report from its Severity, using the shared table in
+// claircore.NormalizeSeverity. Stored vulnerabilities keep the value they
+// were normalized to when their updater ran, so this makes reports that mix
+// old and new data consistent. Severities the table doesn't know are left
+// alone.
+//
+// As with inheritSeverity, changed vulnerabilities are copied.
+func renormalizeSeverity(ctx context.Context, vr *claircore.VulnerabilityReport) {
+	var ct int
+	for id, v := range vr.Vulnerabilities {
+		sev, ok := claircore.NormalizeSeverity(v.Severity)
+		if !ok || sev == v.NormalizedSeverity {
+			continue
+		}
+		nv := *v
+		nv.NormalizedSeverity = sev
+		vr.Vulnerabilities[id] = &nv
+		ct++
+	}
+	if ct != 0 {
+		zlog.Debug(ctx).
+			Int("count", ct).
+			Msg("renormalized stored severities")
+	}
+}
@@ -0,0 +1,52 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/enricher/eol"
+)
+
+// EOLWarnings adds a warning to the report for every distribution the
+// end-of-life enricher reported.
+func eolWarnings(ctx context.Context, vr *claircore.VulnerabilityReport) {
+	for _, msg := range vr.Enrichments[eol.Type] {
+		var m map[string]eol.Entry
+		if err := json.Unmarshal(msg, &m); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("unable to decode eol enrichment")
+			return
+		}
+		for id, e := range m {
+			vr.Warnings = append(vr.Warnings, claircore.Warning{
+				Code:    claircore.WarningEOLDistribution,
+				Subject: id,
+				Message: e.Warning,
+			})
+		}
+	}
+}
+
+// StaleWarnings adds a warning to the report for every updater in its
+// metadata whose latest update operation is before "cutoff".
+func staleWarnings(vr *claircore.VulnerabilityReport, cutoff time.Time) {
+	if cutoff.IsZero() || vr.Metadata == nil {
+		return
+	}
+	for u, ref := range vr.Metadata.UpdateOperations {
+		if !ref.Date.Before(cutoff) {
+			continue
+		}
+		vr.Warnings = append(vr.Warnings, claircore.Warning{
+			Code:    claircore.WarningStaleData,
+			Subject: u,
+			Message: fmt.Sprintf("vulnerability data last updated %s", ref.Date.UTC().Format(time.RFC3339)),
+		})
+	}
+}
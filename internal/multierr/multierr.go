@@ -0,0 +1,74 @@
+// Package multierr combines independent errors into one, so that every
+// problem found can be reported at once.
+//
+// It works like errors.Join from newer Go releases, and additionally
+// implements Is and As so that errors.Is and errors.As see the combined errors
+// on every supported Go release.
+package multierr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Join returns an error combining the non-nil errors in "errs", or nil if
+// there are none. Errors returned by Join are flattened into the result, so
+// joining joined errors doesn't nest them.
+//
+// The error's message is the messages of the combined errors, one per line.
+func Join(errs ...error) error {
+	var out joined
+	for _, err := range errs {
+		switch err := err.(type) {
+		case nil:
+		case joined:
+			out = append(out, err...)
+		default:
+			out = append(out, err)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+type joined []error
+
+// Error implements error.
+func (j joined) Error() string {
+	var b strings.Builder
+	for i, err := range j {
+		if i != 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the combined errors, for errors.Is and errors.As on Go
+// releases that support multiple wrapped errors.
+func (j joined) Unwrap() []error {
+	return j
+}
+
+// Is reports whether any of the combined errors is "target".
+func (j joined) Is(target error) bool {
+	for _, err := range j {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the combined errors that matches "target".
+func (j joined) As(target interface{}) bool {
+	for _, err := range j {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
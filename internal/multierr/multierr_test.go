@@ -0,0 +1,37 @@
+package multierr
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type pathError struct{ path string }
+
+func (e *pathError) Error() string { return "bad path: " + e.path }
+
+func TestJoin(t *testing.T) {
+	if err := Join(nil, nil); err != nil {
+		t.Errorf("got: %v, want: nil", err)
+	}
+
+	a := errors.New("a")
+	b := &pathError{"b"}
+	err := Join(Join(a, nil), b, os.ErrNotExist)
+	if got, want := err.Error(), "a\nbad path: b\nfile does not exist"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if j := err.(joined); len(j) != 3 {
+		t.Errorf("got %d errors, want 3: joined errors weren't flattened", len(j))
+	}
+	if !errors.Is(err, a) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("errors.Is didn't find a combined error")
+	}
+	if errors.Is(err, os.ErrExist) {
+		t.Error("errors.Is found an error that wasn't combined")
+	}
+	var pe *pathError
+	if !errors.As(err, &pe) || pe != b {
+		t.Error("errors.As didn't find a combined error")
+	}
+}
@@ -15,6 +15,13 @@ type EnrichmentUpdater interface {
 	// EnrichmentRecord(s), and ensures enrichments from previous updates are not
 	// queries by clients.
 	UpdateEnrichments(ctx context.Context, kind string, fingerprint driver.Fingerprint, enrichments []driver.EnrichmentRecord) (uuid.UUID, error)
+	// EnrichmentExists reports whether the record with the provided hash, as
+	// computed by driver.HashEnrichment, is in the named updater's most recent
+	// update.
+	EnrichmentExists(ctx context.Context, updater, hashKind string, hash []byte) (bool, error)
+	// MissingEnrichments returns the provided hashes whose records aren't in
+	// the named updater's most recent update, in the order provided.
+	MissingEnrichments(ctx context.Context, updater, hashKind string, hashes [][]byte) ([][]byte, error)
 }
 
 // Enrichment is an interface for querying enrichments from the store.
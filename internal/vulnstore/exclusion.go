@@ -0,0 +1,21 @@
+package vulnstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore"
+)
+
+// Exclusions is an interface for storing and querying Exclusions.
+type Exclusions interface {
+	// AddExclusion validates and stores the provided Exclusion, returning it
+	// with its ID and creation time set.
+	AddExclusion(ctx context.Context, e claircore.Exclusion) (*claircore.Exclusion, error)
+	// ListExclusions returns every stored Exclusion, including expired ones,
+	// oldest first.
+	ListExclusions(ctx context.Context) ([]claircore.Exclusion, error)
+	// DeleteExclusion removes an Exclusion, reporting whether it existed.
+	DeleteExclusion(ctx context.Context, id uuid.UUID) (bool, error)
+}
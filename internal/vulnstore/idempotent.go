@@ -0,0 +1,27 @@
+package vulnstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Idempotent is an interface for stores that can recognize a retried update,
+// so that retrying an update whose commit succeeded, but whose caller didn't
+// learn of it, doesn't store the same data twice.
+//
+// The caller supplies a key that's the same for the same update, such as a
+// hash of the updater's name, the Fingerprint, and the data.
+type Idempotent interface {
+	// UpdateVulnerabilitiesOnce is UpdateVulnerabilities, except that if the
+	// updater's latest update operation was created with "key", its
+	// reference is returned and nothing is written.
+	UpdateVulnerabilitiesOnce(ctx context.Context, key string, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error)
+	// UpdateEnrichmentsOnce is UpdateEnrichments, except that if the
+	// updater's latest update operation was created with "key", its
+	// reference is returned and nothing is written.
+	UpdateEnrichmentsOnce(ctx context.Context, key string, kind string, fingerprint driver.Fingerprint, enrichments []driver.EnrichmentRecord) (uuid.UUID, error)
+}
@@ -0,0 +1,35 @@
+package vulnstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Importer is an interface for stores that can import updates made elsewhere,
+// such as the ones in a snapshot, keeping the dates they were made.
+type Importer interface {
+	// ImportUpdates calls "f" with a function that records an Import as an
+	// update operation. Every operation "f" records is kept if it returns
+	// nil, and none are otherwise.
+	ImportUpdates(ctx context.Context, f func(ImportFunc) error) error
+}
+
+// ImportFunc records an Import, reporting the new update operation's
+// reference.
+type ImportFunc func(context.Context, *Import) (uuid.UUID, error)
+
+// Import is an update made elsewhere.
+type Import struct {
+	Updater     string
+	Fingerprint driver.Fingerprint
+	// Date is the date of the update. It's used as the update operation's
+	// date, and as the date its vulnerabilities were seen.
+	Date   time.Time
+	Source *claircore.DataSource
+	Vulns  []*claircore.Vulnerability
+}
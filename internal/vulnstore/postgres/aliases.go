@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/microbatch"
+)
+
+var (
+	resolveAliasesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "resolvealiases_total",
+			Help:      "Total number of database queries issued in the ResolveAliases method.",
+		},
+		[]string{"query"},
+	)
+	resolveAliasesDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "resolvealiases_duration_seconds",
+			Help:      "The duration of all queries issued in the ResolveAliases method",
+		},
+		[]string{"query"},
+	)
+)
+
+// ReplaceAliases removes the aliases recorded by the updater's previous
+// update and queues inserts for the ones declared by "vulns".
+func replaceAliases(ctx context.Context, tx pgx.Tx, b *microbatch.Insert, ns, updater string, vulns []*claircore.Vulnerability) error {
+	const (
+		clear  = `DELETE FROM vuln_alias WHERE namespace = $1 AND updater = $2;`
+		insert = `
+		INSERT INTO vuln_alias (namespace, updater, name, alias) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;`
+	)
+	if _, err := tx.Exec(ctx, clear, ns, updater); err != nil {
+		return fmt.Errorf("failed to remove previous aliases: %w", err)
+	}
+	// Updaters usually report an advisory once per affected package, so only
+	// queue each pair once.
+	type pair struct{ name, alias string }
+	seen := make(map[pair]struct{})
+	for _, v := range vulns {
+		for _, a := range v.Aliases {
+			if a == "" || a == v.Name {
+				continue
+			}
+			p := pair{v.Name, a}
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			if err := b.Queue(ctx, insert, ns, updater, p.name, p.alias); err != nil {
+				return fmt.Errorf("failed to queue alias: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveAliases implements vulnstore.AliasResolver.
+//
+// Aliases are followed in both directions and through any number of names,
+// regardless of which updater declared them.
+func (s *Store) ResolveAliases(ctx context.Context, names []string) (map[string][]string, error) {
+	const query = `
+WITH RECURSIVE
+	closure (name, alias)
+		AS (
+			SELECT n, n FROM unnest($1::text[]) AS n
+			UNION
+				SELECT
+					c.name,
+					CASE WHEN a.name = c.alias THEN a.alias ELSE a.name END
+				FROM
+					closure AS c
+					JOIN vuln_alias AS a ON
+							a.namespace = $2
+							AND (a.name = c.alias OR a.alias = c.alias)
+		)
+SELECT name, alias FROM closure WHERE name <> alias;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/ResolveAliases"))
+	if len(names) == 0 {
+		return nil, nil
+	}
+	start := time.Now()
+	rows, err := s.pool.Query(ctx, query, names, s.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve aliases: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string][]string)
+	for rows.Next() {
+		var name, alias string
+		if err := rows.Scan(&name, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		out[name] = append(out[name], alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve aliases: %w", err)
+	}
+	resolveAliasesCounter.WithLabelValues("query").Add(1)
+	resolveAliasesDuration.WithLabelValues("query").Observe(time.Since(start).Seconds())
+	for _, as := range out {
+		sort.Strings(as)
+	}
+	return out, nil
+}
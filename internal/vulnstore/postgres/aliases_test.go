@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestResolveAliases checks that aliases declared by different updaters are
+// joined, and that an update replaces the updater's previous aliases.
+func TestResolveAliases(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+
+	mk := func(updater, name string, aliases ...string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater: updater,
+			Name:    name,
+			Aliases: aliases,
+			Package: &claircore.Package{Name: "openssl", Kind: claircore.SOURCE},
+			Dist:    &claircore.Distribution{},
+			Repo:    &claircore.Repository{},
+		}
+	}
+	update := func(t *testing.T, updater string, vs ...*claircore.Vulnerability) {
+		t.Helper()
+		if _, err := s.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolve := func(t *testing.T, name string, want []string) {
+		t.Helper()
+		got, err := s.ResolveAliases(ctx, []string{name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(got[name], want) {
+			t.Errorf("%s: %v", name, cmp.Diff(got[name], want))
+		}
+	}
+
+	update(t, "rhel", mk("rhel", "RHSA-2022:0001", "RHSA-2022:0001", "CVE-2022-0778"))
+	// Declared once per package, which must not conflict.
+	update(t, "ghsa",
+		mk("ghsa", "GHSA-aaaa-bbbb-cccc", "CVE-2022-0778"),
+		mk("ghsa", "GHSA-aaaa-bbbb-cccc", "CVE-2022-0778"))
+	update(t, "aws", mk("aws", "ALAS-2022-1", "CVE-2022-1292"))
+
+	t.Run("MultiHop", func(t *testing.T) {
+		resolve(t, "RHSA-2022:0001", []string{"CVE-2022-0778", "GHSA-aaaa-bbbb-cccc"})
+		resolve(t, "CVE-2022-0778", []string{"GHSA-aaaa-bbbb-cccc", "RHSA-2022:0001"})
+		resolve(t, "ALAS-2022-1", []string{"CVE-2022-1292"})
+		resolve(t, "CVE-2022-9999", nil)
+	})
+	t.Run("Replaced", func(t *testing.T) {
+		update(t, "ghsa", mk("ghsa", "GHSA-aaaa-bbbb-cccc", "CVE-2022-1292"))
+		resolve(t, "RHSA-2022:0001", []string{"CVE-2022-0778"})
+		resolve(t, "GHSA-aaaa-bbbb-cccc", []string{"ALAS-2022-1", "CVE-2022-1292"})
+	})
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestCompression seeds the same Ubuntu-OVAL-shaped database into a store in
+// the old description phase and one in the new phase, and checks that the
+// new layout takes meaningfully less space.
+//
+// Ubuntu advisories list every binary package built from a source package,
+// each as its own vulnerability with the same long description and links, so
+// that's what's generated here.
+func TestCompression(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		updater     = "test-compression"
+		nAdvisories = 200
+		nPackages   = 15
+		// Threshold is the minimum fraction of the old size saved.
+		threshold = 0.3
+	)
+	vs := make([]*claircore.Vulnerability, 0, nAdvisories*nPackages)
+	dist := &claircore.Distribution{
+		DID:             "ubuntu",
+		Name:            "Ubuntu",
+		Version:         "20.04 LTS (Focal Fossa)",
+		VersionCodeName: "focal",
+		VersionID:       "20.04",
+		PrettyName:      "Ubuntu 20.04 LTS",
+	}
+	for i := 0; i < nAdvisories; i++ {
+		name := fmt.Sprintf("CVE-2020-%04d", i)
+		desc := strings.Repeat(fmt.Sprintf("In package %d, a crafted input could cause a buffer overflow. ", i), 20)
+		links := fmt.Sprintf("https://people.canonical.com/~ubuntu-security/cve/2020/%[1]s.html "+
+			"https://cve.mitre.org/cgi-bin/cvename.cgi?name=%[1]s "+
+			"https://nvd.nist.gov/vuln/detail/%[1]s "+
+			"https://launchpad.net/bugs/%[2]d", name, 1000000+i)
+		for j := 0; j < nPackages; j++ {
+			vs = append(vs, &claircore.Vulnerability{
+				Updater:            updater,
+				Name:               name,
+				Description:        desc,
+				Links:              links,
+				Severity:           "Medium",
+				NormalizedSeverity: claircore.Medium,
+				Package: &claircore.Package{
+					Name: fmt.Sprintf("src%d-bin%d", i, j),
+					Kind: claircore.BINARY,
+				},
+				Dist:           dist,
+				FixedInVersion: "1.2.3-4ubuntu0.1",
+			})
+		}
+	}
+
+	size := func(pool *pgxpool.Pool) int64 {
+		t.Helper()
+		var n int64
+		if err := pool.QueryRow(ctx, `SELECT pg_total_relation_size('vuln') + pg_total_relation_size('description');`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	oldPool := TestDB(ctx, t)
+	old := NewVulnStore(oldPool)
+	if _, err := old.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs); err != nil {
+		t.Fatal(err)
+	}
+	before := size(oldPool)
+
+	newPool := TestDB(ctx, t)
+	s := NewVulnStore(newPool, WithMigrationAssist(time.Millisecond))
+	for _, p := range []DescriptionPhase{DescriptionDualWrite, DescriptionShadowRead, DescriptionNew} {
+		if err := s.SetDescriptionPhase(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Let the store notice the phase.
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs); err != nil {
+		t.Fatal(err)
+	}
+	after := size(newPool)
+
+	saved := 1 - float64(after)/float64(before)
+	t.Logf("old: %d bytes, new: %d bytes (%.1f%% saved)", before, after, saved*100)
+	if saved < threshold {
+		t.Errorf("saved %.1f%%, want at least %.1f%%", saved*100, threshold*100)
+	}
+
+	// And everything should still read back.
+	got, err := s.GetVulnerabilities(ctx, []driver.VulnerabilityQuery{
+		{Name: "CVE-2020-0007", Package: "src7-bin3", DistributionID: "ubuntu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0]) != 1 {
+		t.Fatalf("got %v, want 1 result", got)
+	}
+	if got, want := got[0][0].Links, vs[7*nPackages+3].Links; got != want {
+		t.Errorf("got links %q, want %q", got, want)
+	}
+	if got, want := got[0][0].Description, vs[7*nPackages+3].Description; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+}
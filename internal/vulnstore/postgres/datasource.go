@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+// SetDataSource implements vulnstore.Updater.
+func (s *Store) SetDataSource(ctx context.Context, ref uuid.UUID, src claircore.DataSource) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/SetDataSource"))
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := setDataSource(ctx, tx, s.namespace, ref, src); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// setDataSource records "src" as the data source of the update operation
+// "ref" in the namespace "ns".
+func setDataSource(ctx context.Context, tx pgx.Tx, ns string, ref uuid.UUID, src claircore.DataSource) error {
+	const query = `UPDATE update_operation SET source = $1 WHERE ref = $2 AND namespace = $3;`
+	tag, err := tx.Exec(ctx, query, &src, ref, ns)
+	if err != nil {
+		return fmt.Errorf("failed to set data source: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to set data source: no update operation %v", ref)
+	}
+	return nil
+}
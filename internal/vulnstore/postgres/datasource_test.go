@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestDataSource(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	src := claircore.DataSource{
+		Name:    "Test Source",
+		License: "CC-BY-4.0",
+		URL:     "https://example.com/terms",
+	}
+
+	attributed, err := s.UpdateVulnerabilities(ctx, "attributed", driver.Fingerprint(uuid.New().String()), test.GenUniqueVulnerabilities(5, "attributed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDataSource(ctx, attributed, src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateVulnerabilities(ctx, "anonymous", driver.Fingerprint(uuid.New().String()), test.GenUniqueVulnerabilities(5, "anonymous")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDataSource(ctx, uuid.New(), src); err == nil {
+		t.Error("set data source for a nonexistent update operation")
+	}
+
+	check := func(t *testing.T, ops map[string][]driver.UpdateOperation) {
+		t.Helper()
+		got := ops["attributed"]
+		if len(got) != 1 || got[0].Source == nil {
+			t.Fatalf("missing data source: %+v", got)
+		}
+		if !cmp.Equal(*got[0].Source, src) {
+			t.Error(cmp.Diff(*got[0].Source, src))
+		}
+		if got := ops["anonymous"]; len(got) != 1 || got[0].Source != nil {
+			t.Errorf("unexpected data source: %+v", got)
+		}
+	}
+	t.Run("Latest", func(t *testing.T) {
+		ops, err := s.GetLatestUpdateRefs(ctx, driver.VulnerabilityKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, ops)
+	})
+	t.Run("All", func(t *testing.T) {
+		ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, ops)
+	})
+}
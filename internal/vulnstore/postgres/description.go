@@ -0,0 +1,369 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+// DescriptionPhase is a step in migrating vulnerability descriptions out of
+// the vuln table and into the deduplicated description table.
+//
+// Phases are taken in order, one step at a time, so that every Store sharing
+// a database is in the same or an adjacent phase:
+//
+//	old:         write the vuln column, read the vuln column
+//	dual-write:  write both, read the vuln column
+//	shadow-read: write both, read the description table falling back to the vuln column
+//	new:         write the description table, read the description table
+//
+// The new phase is final: rows written in it have no description in the vuln
+// table for an earlier phase to read.
+//
+// Existing rows should be moved with BackfillDescriptions while in the
+// dual-write or shadow-read phase; the new phase can't be entered until
+// every row has been backfilled.
+type DescriptionPhase string
+
+// These are the description migration phases.
+const (
+	DescriptionOld        DescriptionPhase = "old"
+	DescriptionDualWrite  DescriptionPhase = "dual-write"
+	DescriptionShadowRead DescriptionPhase = "shadow-read"
+	DescriptionNew        DescriptionPhase = "new"
+)
+
+var descriptionPhases = []DescriptionPhase{
+	DescriptionOld,
+	DescriptionDualWrite,
+	DescriptionShadowRead,
+	DescriptionNew,
+}
+
+func (p DescriptionPhase) index() int {
+	for i, q := range descriptionPhases {
+		if p == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// WriteOld reports whether the phase writes the vuln table's column.
+func (p DescriptionPhase) writeOld() bool { return p != DescriptionNew }
+
+// WriteNew reports whether the phase writes the description table.
+func (p DescriptionPhase) writeNew() bool { return p != DescriptionOld }
+
+// DescriptionExpr returns the SQL expression for a vulnerability's description
+// in queries on the vuln table.
+func (p DescriptionPhase) descriptionExpr() string {
+	const subquery = `(SELECT d.text FROM description AS d WHERE d.id = vuln.description_id)`
+	switch p {
+	case DescriptionShadowRead:
+		return `COALESCE(` + subquery + `, vuln.description, '')`
+	case DescriptionNew:
+		return `COALESCE(` + subquery + `, '')`
+	}
+	return `vuln.description`
+}
+
+// LinksExpr returns the SQL expression for a vulnerability's links in queries
+// on the vuln table.
+//
+// Links are written to the description table in the same phases as
+// descriptions, but always fall back to the vuln column: rows written before
+// the table could hold links, or backfilled before then, only have links in
+// the column.
+func (p DescriptionPhase) linksExpr() string {
+	const subquery = `(SELECT d.text FROM description AS d WHERE d.id = vuln.links_id)`
+	switch p {
+	case DescriptionShadowRead, DescriptionNew:
+		return `COALESCE(` + subquery + `, vuln.links, '')`
+	}
+	return `vuln.links`
+}
+
+// DescriptionPhaseKey is the key for the phase in the settings table.
+const descriptionPhaseKey = `description_phase`
+
+// DefaultPhaseRefresh is how long a Store caches the phase read from the
+// settings table when no interval is configured.
+const DefaultPhaseRefresh = 30 * time.Second
+
+var (
+	descriptionCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "description_total",
+			Help:      "Total number of database queries issued in the description migration methods.",
+		},
+		[]string{"query"},
+	)
+	descriptionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "description_duration_seconds",
+			Help:      "The duration of all queries issued in the description migration methods.",
+		},
+		[]string{"query"},
+	)
+)
+
+// Phase reports the description phase the Store reads and writes with.
+//
+// Stores without migration assist are always in the old phase. Otherwise, the
+// phase is read from the settings table, and cached for the configured
+// refresh interval.
+func (s *Store) phase(ctx context.Context) (DescriptionPhase, error) {
+	if !s.assist {
+		return DescriptionOld, nil
+	}
+	s.phaseMu.Lock()
+	defer s.phaseMu.Unlock()
+	if !s.phaseAt.IsZero() && time.Since(s.phaseAt) < s.phaseRefresh {
+		return s.curPhase, nil
+	}
+	p, err := s.DescriptionPhase(ctx)
+	if err != nil {
+		return "", err
+	}
+	if p != s.curPhase {
+		zlog.Info(ctx).
+			Str("component", "internal/vulnstore/postgres/Store.phase").
+			Str("from", string(s.curPhase)).
+			Str("to", string(p)).
+			Msg("description phase changed")
+	}
+	s.curPhase, s.phaseAt = p, time.Now()
+	return p, nil
+}
+
+// DescriptionPhase reports the description phase recorded in the database.
+func (s *Store) DescriptionPhase(ctx context.Context) (DescriptionPhase, error) {
+	const query = `SELECT value FROM settings WHERE key = $1;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/DescriptionPhase"))
+
+	start := time.Now()
+	var v string
+	switch err := s.pool.QueryRow(ctx, query, descriptionPhaseKey).Scan(&v); {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		return DescriptionOld, nil
+	default:
+		return "", fmt.Errorf("failed to read description phase: %w", err)
+	}
+	descriptionCounter.WithLabelValues("get_phase").Add(1)
+	descriptionDuration.WithLabelValues("get_phase").Observe(time.Since(start).Seconds())
+	p := DescriptionPhase(v)
+	if p.index() == -1 {
+		return "", fmt.Errorf("unknown description phase %q", v)
+	}
+	return p, nil
+}
+
+// SetDescriptionPhase records a new description phase in the database, for
+// Stores with migration assist to pick up within their refresh interval.
+//
+// The phase can only move one step forward or back, and the new phase, once
+// entered, can't be left. Operators should wait at least the refresh interval
+// between steps, so that no Store lags by more than one phase. The new phase
+// can't be entered while there are rows that haven't been backfilled.
+func (s *Store) SetDescriptionPhase(ctx context.Context, p DescriptionPhase) error {
+	const (
+		lock = `SELECT value FROM settings WHERE key = $1 FOR UPDATE;`
+		set  = `
+INSERT INTO settings (key, value)
+VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value;`
+		pending = `SELECT EXISTS(SELECT 1 FROM vuln WHERE description_id IS NULL);`
+	)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/SetDescriptionPhase"))
+	if p.index() == -1 {
+		return fmt.Errorf("unknown description phase %q", p)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	cur := DescriptionOld
+	var v string
+	switch err := tx.QueryRow(ctx, lock, descriptionPhaseKey).Scan(&v); {
+	case err == nil:
+		cur = DescriptionPhase(v)
+	case errors.Is(err, pgx.ErrNoRows):
+	default:
+		return fmt.Errorf("failed to read description phase: %w", err)
+	}
+	descriptionCounter.WithLabelValues("lock_phase").Add(1)
+	descriptionDuration.WithLabelValues("lock_phase").Observe(time.Since(start).Seconds())
+	if p == cur {
+		return nil
+	}
+	if cur == DescriptionNew {
+		// Rows written in the new phase have nothing in the vuln column to
+		// fall back to.
+		return fmt.Errorf("cannot move description phase from %q", cur)
+	}
+	if d := p.index() - cur.index(); cur.index() == -1 || d < -1 || d > 1 {
+		return fmt.Errorf("cannot move description phase from %q to %q", cur, p)
+	}
+	if p == DescriptionNew {
+		start := time.Now()
+		var ok bool
+		if err := tx.QueryRow(ctx, pending).Scan(&ok); err != nil {
+			return fmt.Errorf("failed to check for rows to backfill: %w", err)
+		}
+		descriptionCounter.WithLabelValues("pending").Add(1)
+		descriptionDuration.WithLabelValues("pending").Observe(time.Since(start).Seconds())
+		if ok {
+			return fmt.Errorf("cannot move description phase to %q: rows remain to be backfilled", p)
+		}
+	}
+
+	start = time.Now()
+	if _, err := tx.Exec(ctx, set, descriptionPhaseKey, string(p)); err != nil {
+		return fmt.Errorf("failed to set description phase: %w", err)
+	}
+	descriptionCounter.WithLabelValues("set_phase").Add(1)
+	descriptionDuration.WithLabelValues("set_phase").Observe(time.Since(start).Seconds())
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	zlog.Info(ctx).
+		Str("from", string(cur)).
+		Str("to", string(p)).
+		Msg("description phase set")
+
+	if s.assist {
+		s.phaseMu.Lock()
+		s.curPhase, s.phaseAt = p, time.Now()
+		s.phaseMu.Unlock()
+	}
+	return nil
+}
+
+// BackfillDescriptions moves the descriptions, and links, of up to "limit"
+// vulnerabilities that don't reference the description table into it,
+// reporting the number of rows updated. Callers should call it until it
+// reports 0.
+//
+// It's only useful in the dual-write and shadow-read phases: before them new
+// rows keep needing a backfill, and after them there's nothing to do.
+func (s *Store) BackfillDescriptions(ctx context.Context, limit int) (int64, error) {
+	const (
+		selectBatch = `
+SELECT
+	id
+FROM
+	vuln
+WHERE
+	description_id IS NULL
+LIMIT $1
+FOR UPDATE SKIP LOCKED;`
+		insertDescriptions = `
+INSERT INTO description (hash_kind, hash, text)
+SELECT DISTINCT
+	'md5', decode(md5(text), 'hex'), text
+FROM
+	(
+		SELECT COALESCE(description, '') AS text FROM vuln WHERE id = ANY ($1)
+		UNION ALL
+		SELECT links FROM vuln WHERE id = ANY ($1) AND links <> ''
+	) AS texts
+ON CONFLICT (hash_kind, hash) DO NOTHING;`
+		updateVulns = `
+UPDATE
+	vuln
+SET
+	description_id = d.id,
+	links_id = (
+		SELECT l.id
+		FROM description AS l
+		WHERE
+			vuln.links <> ''
+			AND l.hash_kind = 'md5'
+			AND l.hash = decode(md5(vuln.links), 'hex')
+	)
+FROM
+	description AS d
+WHERE
+	vuln.id = ANY ($1)
+	AND d.hash_kind = 'md5'
+	AND d.hash = decode(md5(COALESCE(vuln.description, '')), 'hex');`
+	)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/BackfillDescriptions"))
+	if limit < 1 {
+		return 0, fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	rows, err := tx.Query(ctx, selectBatch, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select vulnerabilities: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to select vulnerabilities: %w", err)
+	}
+	descriptionCounter.WithLabelValues("backfill_select").Add(1)
+	descriptionDuration.WithLabelValues("backfill_select").Observe(time.Since(start).Seconds())
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	start = time.Now()
+	if _, err := tx.Exec(ctx, insertDescriptions, ids); err != nil {
+		return 0, fmt.Errorf("failed to insert descriptions: %w", err)
+	}
+	descriptionCounter.WithLabelValues("backfill_insert").Add(1)
+	descriptionDuration.WithLabelValues("backfill_insert").Observe(time.Since(start).Seconds())
+
+	start = time.Now()
+	tag, err := tx.Exec(ctx, updateVulns, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update vulnerabilities: %w", err)
+	}
+	descriptionCounter.WithLabelValues("backfill_update").Add(1)
+	descriptionDuration.WithLabelValues("backfill_update").Observe(time.Since(start).Seconds())
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int64("count", tag.RowsAffected()).
+		Msg("backfilled descriptions")
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestDescriptionPhases walks the description migration through every phase
+// while vulnerabilities are written and read, checking that descriptions read
+// back correctly throughout.
+func TestDescriptionPhases(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool, WithMigrationAssist(time.Millisecond))
+
+	const (
+		seedUpdater  = "test-description-seed"
+		writeUpdater = "test-description-write"
+		nSeed        = 30
+	)
+	desc := func(i int) string { return fmt.Sprintf("description %d", i%3) }
+	mk := func(name string, i int) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Name:        name,
+			Description: desc(i),
+			Package:     &claircore.Package{Name: "pkg", Kind: claircore.SOURCE},
+			Dist:        &claircore.Distribution{DID: "test"},
+			Repo:        &claircore.Repository{},
+		}
+	}
+	seed := make([]*claircore.Vulnerability, nSeed)
+	qs := make([]driver.VulnerabilityQuery, nSeed)
+	for i := range seed {
+		n := fmt.Sprintf("CVE-0000-%04d", i)
+		seed[i] = mk(n, i)
+		seed[i].Updater = seedUpdater
+		qs[i] = driver.VulnerabilityQuery{Name: n, Package: "pkg", DistributionID: "test"}
+	}
+	if _, err := s.UpdateVulnerabilities(ctx, seedUpdater, driver.Fingerprint(uuid.New().String()), seed); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check rejected transitions before starting any load.
+	if err := s.SetDescriptionPhase(ctx, DescriptionShadowRead); err == nil {
+		t.Error("able to skip the dual-write phase")
+	}
+	if err := s.SetDescriptionPhase(ctx, DescriptionPhase("bogus")); err == nil {
+		t.Error("able to set an unknown phase")
+	}
+
+	check := func(ctx context.Context) error {
+		got, err := s.GetVulnerabilities(ctx, qs)
+		if err != nil {
+			return err
+		}
+		for i, vs := range got {
+			if len(vs) != 1 {
+				return fmt.Errorf("%s: got %d results, want 1", qs[i].Name, len(vs))
+			}
+			if got, want := vs[0].Description, desc(i); got != want {
+				return fmt.Errorf("%s: got description %q, want %q", qs[i].Name, got, want)
+			}
+		}
+		return nil
+	}
+	var ops []uuid.UUID
+	write := func(ctx context.Context, n int) error {
+		vs := make([]*claircore.Vulnerability, 10)
+		for i := range vs {
+			vs[i] = mk(fmt.Sprintf("CVE-9999-%04d-%02d", n, i), i)
+			vs[i].Updater = writeUpdater
+		}
+		ref, err := s.UpdateVulnerabilities(ctx, writeUpdater, driver.Fingerprint(uuid.New().String()), vs)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, ref)
+		return nil
+	}
+
+	done := make(chan struct{})
+	eg, ectx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		for n := 0; ; n++ {
+			select {
+			case <-done:
+				return nil
+			case <-ectx.Done():
+				return ectx.Err()
+			default:
+			}
+			if err := write(ectx, n); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+	})
+	for i := 0; i < 4; i++ {
+		eg.Go(func() error {
+			for {
+				select {
+				case <-done:
+					return nil
+				case <-ectx.Done():
+					return ectx.Err()
+				default:
+				}
+				if err := check(ectx); err != nil {
+					return fmt.Errorf("read: %w", err)
+				}
+			}
+		})
+	}
+
+	pause := func() { time.Sleep(100 * time.Millisecond) }
+	backfill := func() {
+		t.Helper()
+		var total int64
+		for {
+			n, err := s.BackfillDescriptions(ctx, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 0 {
+				break
+			}
+			total += n
+		}
+		t.Logf("backfilled %d rows", total)
+	}
+	step := func(p DescriptionPhase) {
+		t.Helper()
+		if err := s.SetDescriptionPhase(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.DescriptionPhase(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Fatalf("got phase %q, want %q", got, p)
+		}
+		pause()
+	}
+
+	pause()
+	step(DescriptionDualWrite)
+	if err := s.SetDescriptionPhase(ctx, DescriptionNew); err == nil {
+		t.Error("able to skip the shadow-read phase")
+	}
+	step(DescriptionShadowRead)
+	if err := s.SetDescriptionPhase(ctx, DescriptionNew); err == nil {
+		t.Error("able to enter the new phase with rows to backfill")
+	}
+	backfill()
+	step(DescriptionNew)
+	if err := s.SetDescriptionPhase(ctx, DescriptionShadowRead); err == nil {
+		t.Error("able to leave the new phase")
+	}
+	close(done)
+	if err := eg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(ctx); err != nil {
+		t.Error(err)
+	}
+
+	if n, err := s.BackfillDescriptions(ctx, 100); err != nil || n != 0 {
+		t.Errorf("backfill after migration: got (%d, %v), want (0, <nil>)", n, err)
+	}
+	var ct int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM description;`).Scan(&ct); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ct, 3; got != want {
+		t.Errorf("got %d descriptions, want %d", got, want)
+	}
+
+	// Rows written in the old and new phases should show up with their
+	// descriptions in update diffs.
+	if len(ops) < 2 {
+		t.Fatalf("only %d write operations", len(ops))
+	}
+	diff, err := s.GetUpdateDiff(ctx, ops[0], ops[len(ops)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vs := range [][]claircore.Vulnerability{diff.Added, diff.Removed} {
+		for _, v := range vs {
+			if v.Description == "" {
+				t.Errorf("%s: missing description", v.Name)
+			}
+		}
+	}
+}
@@ -2,9 +2,9 @@ package postgres
 
 import (
 	"context"
-	"crypto/md5"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"sort"
 	"time"
 
@@ -56,21 +56,160 @@ var (
 		},
 		[]string{"query"},
 	)
+	invalidEnrichmentsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "updateenrichments_invalid_total",
+			Help:      "Total number of invalid records skipped in the UpdateEnrichments method.",
+		},
+		[]string{"reason"},
+	)
 )
 
+// ErrInvalidEnrichment is returned when an EnrichmentRecord can never be
+// returned by GetEnrichment, because it has no usable tags, or when its data
+// is too large or isn't valid JSON.
+var ErrInvalidEnrichment = errors.New("invalid enrichment record")
+
+// DefaultMaxEnrichmentSize is the largest enrichment data, in bytes, a Store
+// constructed without WithMaxEnrichmentSize accepts in a single record.
+const DefaultMaxEnrichmentSize = 1 << 20
+
+// Reasons an EnrichmentRecord is invalid, used as metric labels.
+const (
+	reasonNoTags      = "no_tags"
+	reasonEmptyTag    = "empty_tag"
+	reasonTooLarge    = "too_large"
+	reasonInvalidJSON = "invalid_json"
+)
+
+// InvalidEnrichment reports why the record is invalid, or the empty string if
+// it's valid. Records with more than "max" bytes of data are invalid, unless
+// "max" is not positive.
+func invalidEnrichment(r *driver.EnrichmentRecord, max int) string {
+	if len(r.Tags) == 0 {
+		return reasonNoTags
+	}
+	for _, t := range r.Tags {
+		if t == "" {
+			return reasonEmptyTag
+		}
+	}
+	if max > 0 && len(r.Enrichment) > max {
+		return reasonTooLarge
+	}
+	if !json.Valid(r.Enrichment) {
+		return reasonInvalidJSON
+	}
+	return ""
+}
+
+// ValidEnrichments returns the valid records in "es", using "max" as the
+// largest allowed record data.
+//
+// If "reject" is set, an error is returned for the first invalid record.
+// Otherwise, invalid records are logged and counted, and the returned slice
+// omits them.
+func validEnrichments(ctx context.Context, name string, es []driver.EnrichmentRecord, max int, reject bool) ([]driver.EnrichmentRecord, error) {
+	var out []driver.EnrichmentRecord
+	skipped := make(map[string]int)
+	for i := range es {
+		why := invalidEnrichment(&es[i], max)
+		if why == "" {
+			if out != nil {
+				out = append(out, es[i])
+			}
+			continue
+		}
+		if reject {
+			return nil, fmt.Errorf("%w: record %d: %s", ErrInvalidEnrichment, i, why)
+		}
+		invalidEnrichmentsCounter.WithLabelValues(why).Add(1)
+		skipped[why]++
+		zlog.Debug(ctx).
+			Str("updater", name).
+			Int("index", i).
+			Str("reason", why).
+			Int("size", len(es[i].Enrichment)).
+			Msg("skipping invalid enrichment record")
+		if out == nil {
+			out = make([]driver.EnrichmentRecord, i, len(es)-1)
+			copy(out, es[:i])
+		}
+	}
+	if out == nil {
+		return es, nil
+	}
+	ev := zlog.Warn(ctx).
+		Str("updater", name).
+		Int("total", len(es)).
+		Int("kept", len(out))
+	for _, why := range []string{reasonNoTags, reasonEmptyTag, reasonTooLarge, reasonInvalidJSON} {
+		if n := skipped[why]; n != 0 {
+			ev = ev.Int(why, n)
+		}
+	}
+	ev.Msg("skipped invalid enrichment records")
+	return out, nil
+}
+
 // UpdateEnrichments creates a new UpdateOperation, inserts the provided
 // EnrichmentRecord(s), and ensures enrichments from previous updates are not
 // queried by clients.
+//
+// Only records not present in the updater's previous operation are queued for
+// insertion. Associations for unchanged and new records are then created in a
+// single statement, so an update that changes only a handful of records costs
+// a handful of inserts.
+//
+// Records with no tags, or with an empty tag, can never be returned by
+// GetEnrichment, and records whose data is larger than the Store's limit or
+// isn't valid JSON would break readers. These are skipped and counted, or
+// cause an error if the Store was constructed with
+// WithRejectInvalidEnrichments.
 func (s *Store) UpdateEnrichments(ctx context.Context, name string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
+	return s.updateEnrichments(ctx, "", name, fp, es)
+}
+
+// UpdateEnrichments is UpdateEnrichments with an optional idempotency key, as
+// described by createOperation.
+func (s *Store) updateEnrichments(ctx context.Context, key string, name string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
 	const (
-		create = `
-INSERT
-INTO
-	update_operation (updater, fingerprint, kind)
-VALUES
-	($1, $2, 'enrichment')
-RETURNING
-	id, ref;`
+		// Diff reports the (1-indexed) positions of the provided hashes that
+		// are not associated with the updater's previous operation in the
+		// namespace $5.
+		diff = `
+WITH
+	prev
+		AS (
+			SELECT
+				max(id) AS id
+			FROM
+				update_operation
+			WHERE
+				updater = $1
+				AND kind = 'enrichment'
+				AND id < $2
+				AND namespace = $5
+		)
+SELECT
+	n.idx
+FROM
+	unnest($4::bytea[]) WITH ORDINALITY AS n (hash, idx)
+WHERE
+	NOT EXISTS(
+			SELECT
+				1
+			FROM
+				enrichment AS e
+				JOIN uo_enrich AS uo ON uo.enrich = e.id,
+				prev
+			WHERE
+				uo.uo = prev.id
+				AND e.hash_kind = $3
+				AND e.hash = n.hash
+		);`
 		insert = `
 INSERT
 INTO
@@ -85,22 +224,14 @@ DO
 INSERT
 INTO
 	uo_enrich (enrich, updater, uo, date)
-VALUES
-	(
-		(
-			SELECT
-				id
-			FROM
-				enrichment
-			WHERE
-				hash_kind = $1
-				AND hash = $2
-				AND updater = $3
-		),
-		$3,
-		$4,
-		transaction_timestamp()
-	)
+SELECT
+	id, $3, $4, transaction_timestamp()
+FROM
+	enrichment
+WHERE
+	hash_kind = $1
+	AND hash = ANY ($2::bytea[])
+	AND updater = $3
 ON CONFLICT
 DO
 	NOTHING;`
@@ -108,41 +239,78 @@ DO
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/UpdateEnrichments"))
 
+	es, err := validEnrichments(ctx, name, es, s.maxEnrichment, s.rejectInvalid)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var hashKind string
+	hashes := make([][]byte, len(es))
+	for i := range es {
+		hashKind, hashes[i] = hashEnrichment(&es[i])
+	}
+
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("unable to start transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	var id uint64
-	var ref uuid.UUID
+	if err := checkOperationKind(ctx, tx, s.namespace, name, driver.EnrichmentKind); err != nil {
+		return uuid.Nil, err
+	}
 
 	start := time.Now()
 
-	if err := s.pool.QueryRow(ctx, create, name, string(fp)).Scan(&id, &ref); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create update_operation: %w", err)
+	id, ref, _, created, err := createOperation(ctx, tx, s.namespace, key, name, fp, driver.EnrichmentKind, nil)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
 	updateEnrichmentsCounter.WithLabelValues("create").Add(1)
 	updateEnrichmentsDuration.WithLabelValues("create").Observe(time.Since(start).Seconds())
 
+	if !created {
+		zlog.Info(ctx).
+			Str("ref", ref.String()).
+			Msg("update_operation already exists for this data")
+		return ref, nil
+	}
+
 	zlog.Debug(ctx).
 		Str("ref", ref.String()).
 		Msg("update_operation created")
 
+	start = time.Now()
+	rows, err := tx.Query(ctx, diff, name, id, hashKind, hashes, s.namespace)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to compute enrichment diff: %w", err)
+	}
+	var changed []int
+	for rows.Next() {
+		var idx int
+		if err := rows.Scan(&idx); err != nil {
+			rows.Close()
+			return uuid.Nil, fmt.Errorf("failed to scan enrichment diff: %w", err)
+		}
+		changed = append(changed, idx-1)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to compute enrichment diff: %w", err)
+	}
+	updateEnrichmentsCounter.WithLabelValues("diff").Add(1)
+	updateEnrichmentsDuration.WithLabelValues("diff").Observe(time.Since(start).Seconds())
+
 	batch := microbatch.NewInsert(tx, 2000, time.Minute)
 	start = time.Now()
-	for i := range es {
-		hashKind, hash := hashEnrichment(&es[i])
+	for _, i := range changed {
 		err := batch.Queue(ctx, insert,
-			hashKind, hash, name, es[i].Tags, es[i].Enrichment,
+			hashKind, hashes[i], name, es[i].Tags, es[i].Enrichment,
 		)
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("failed to queue enrichment: %w", err)
 		}
-		if err := batch.Queue(ctx, assoc, hashKind, hash, name, id); err != nil {
-			return uuid.Nil, fmt.Errorf("failed to queue association: %w", err)
-		}
 	}
 	if err := batch.Done(ctx); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to finish batch enrichment insert: %w", err)
@@ -150,28 +318,51 @@ DO
 	updateEnrichmentsCounter.WithLabelValues("insert_batch").Add(1)
 	updateEnrichmentsDuration.WithLabelValues("insert_batch").Observe(time.Since(start).Seconds())
 
+	start = time.Now()
+	if _, err := tx.Exec(ctx, assoc, hashKind, hashes, name, id); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to associate enrichments: %w", err)
+	}
+	updateEnrichmentsCounter.WithLabelValues("assoc").Add(1)
+	updateEnrichmentsDuration.WithLabelValues("assoc").Observe(time.Since(start).Seconds())
+
 	if err := tx.Commit(ctx); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	zlog.Debug(ctx).
 		Stringer("ref", ref).
-		Int("inserted", len(es)).
+		Int("inserted", len(changed)).
+		Int("unchanged", len(es)-len(changed)).
 		Msg("update_operation committed")
 	return ref, nil
 }
 
+// HashEnrichment sorts the record's tags, so they're stored in a stable
+// order, and returns its hash.
 func hashEnrichment(r *driver.EnrichmentRecord) (k string, d []byte) {
-	h := md5.New()
 	sort.Strings(r.Tags)
-	for _, t := range r.Tags {
-		io.WriteString(h, t)
-		h.Write([]byte("\x00"))
+	return driver.HashEnrichment(r)
+}
+
+// EnrichmentExists reports whether the record with the provided hash is in
+// the named updater's most recent update. Hashes are computed with
+// driver.HashEnrichment.
+func (s *Store) EnrichmentExists(ctx context.Context, name, hashKind string, hash []byte) (bool, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/EnrichmentExists"))
+	missing, err := s.MissingEnrichments(ctx, name, hashKind, [][]byte{hash})
+	if err != nil {
+		return false, err
 	}
-	h.Write(r.Enrichment)
-	return "md5", h.Sum(nil)
+	return len(missing) == 0, nil
 }
 
-func (s *Store) GetEnrichment(ctx context.Context, name string, tags []string) ([]driver.EnrichmentRecord, error) {
+// MissingEnrichments returns the provided hashes whose records aren't in the
+// named updater's most recent update, in the order provided. Hashes are
+// computed with driver.HashEnrichment.
+func (s *Store) MissingEnrichments(ctx context.Context, name, hashKind string, hashes [][]byte) ([][]byte, error) {
+	// Query reports the (1-indexed) positions of the provided hashes that
+	// are not associated with the updater's latest operation in the
+	// namespace $2.
 	const query = `
 WITH
 	latest
@@ -182,20 +373,62 @@ WITH
 				update_operation
 			WHERE
 				updater = $1
+				AND kind = 'enrichment'
+				AND namespace = $2
 		)
 SELECT
-	e.tags, e.data
+	n.idx
 FROM
-	enrichment AS e,
-	uo_enrich AS uo,
-	latest
+	unnest($4::bytea[]) WITH ORDINALITY AS n (hash, idx)
 WHERE
-	uo.uo = latest.id
-	AND uo.enrich = e.id
-	AND e.tags && $2::text[];`
+	NOT EXISTS(
+			SELECT
+				1
+			FROM
+				enrichment AS e
+				JOIN uo_enrich AS uo ON uo.enrich = e.id,
+				latest
+			WHERE
+				uo.uo = latest.id
+				AND e.hash_kind = $3
+				AND e.hash = n.hash
+		)
+ORDER BY
+	n.idx;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/MissingEnrichments"))
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	rows, err := s.pool.Query(ctx, query, name, s.namespace, hashKind, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check enrichments: %w", err)
+	}
+	defer rows.Close()
+	var missing [][]byte
+	for rows.Next() {
+		var idx int
+		if err := rows.Scan(&idx); err != nil {
+			return nil, fmt.Errorf("failed to scan enrichment check: %w", err)
+		}
+		missing = append(missing, hashes[idx-1])
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check enrichments: %w", err)
+	}
+	return missing, nil
+}
 
+// GetEnrichment returns the enrichment records from the named updater's most
+// recent update that have any of the provided tags.
+//
+// It's an error to provide no tags.
+func (s *Store) GetEnrichment(ctx context.Context, name string, tags []string) ([]driver.EnrichmentRecord, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/GetEnrichment"))
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%s: no tags provided", name)
+	}
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -203,7 +436,7 @@ WHERE
 	defer tx.Rollback(ctx)
 
 	results := make([]driver.EnrichmentRecord, 0, 8) // Guess at capacity.
-	rows, err := s.pool.Query(ctx, query, name, tags)
+	rows, err := s.pool.Query(ctx, getEnrichmentQuery, name, s.namespace, tags)
 	if err != nil {
 		return nil, err
 	}
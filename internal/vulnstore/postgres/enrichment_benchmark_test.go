@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/generator"
+	"github.com/quay/claircore/test/integration"
+)
+
+func Benchmark_UpdateEnrichments(b *testing.B) {
+	integration.NeedDB(b)
+	ctx := context.Background()
+	for _, n := range []int{1000, 10000, 100000} {
+		c := generator.Config{Seed: 1, Enrichments: n}
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			ctx := zlog.Test(ctx, b)
+			pool := TestDB(ctx, b)
+			store := NewVulnStore(pool)
+			d := generator.Generate(c)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// After the first run the records are already present, as
+				// when an upstream hasn't changed between updates.
+				fp := driver.Fingerprint(strconv.Itoa(i))
+				if _, err := store.UpdateEnrichments(ctx, generator.EnrichmentUpdater, fp, d.Enrichments); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/microbatch"
+	"github.com/quay/claircore/test/integration"
+)
+
+// StmtCounter is a pgx.Logger that counts the statements issued on a
+// connection, including statements sent as part of a batch.
+type stmtCounter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *stmtCounter) Log(_ context.Context, _ pgx.LogLevel, _ string, data map[string]interface{}) {
+	if _, ok := data["sql"]; !ok {
+		return
+	}
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func (c *stmtCounter) Reset() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.n
+	c.n = 0
+	return n
+}
+
+// CountingPool returns a pool connected to the same database as the provided
+// pool that reports every statement to the returned counter.
+func countingPool(ctx context.Context, t *testing.T, p *pgxpool.Pool) (*pgxpool.Pool, *stmtCounter) {
+	var c stmtCounter
+	cfg := p.Config()
+	cfg.ConnConfig.LogLevel = pgx.LogLevelInfo
+	cfg.ConnConfig.Logger = &c
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool, &c
+}
+
+// NaiveUpdateEnrichments is the original UpdateEnrichments implementation,
+// which inserts every record and association individually. It's used as the
+// reference for the diffing implementation.
+func naiveUpdateEnrichments(ctx context.Context, pool *pgxpool.Pool, name string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
+	const (
+		create = `INSERT INTO update_operation (updater, fingerprint, kind) VALUES ($1, $2, 'enrichment') RETURNING id, ref;`
+		insert = `INSERT INTO enrichment (hash_kind, hash, updater, tags, data) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (hash_kind, hash) DO NOTHING;`
+		assoc  = `INSERT INTO uo_enrich (enrich, updater, uo, date) VALUES ((SELECT id FROM enrichment WHERE hash_kind = $1 AND hash = $2 AND updater = $3), $3, $4, transaction_timestamp()) ON CONFLICT DO NOTHING;`
+	)
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+	var id uint64
+	var ref uuid.UUID
+	if err := tx.QueryRow(ctx, create, name, string(fp)).Scan(&id, &ref); err != nil {
+		return uuid.Nil, err
+	}
+	batch := microbatch.NewInsert(tx, 2000, time.Minute)
+	for i := range es {
+		hashKind, hash := hashEnrichment(&es[i])
+		if err := batch.Queue(ctx, insert, hashKind, hash, name, es[i].Tags, es[i].Enrichment); err != nil {
+			return uuid.Nil, err
+		}
+		if err := batch.Queue(ctx, assoc, hashKind, hash, name, id); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if err := batch.Done(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	return ref, tx.Commit(ctx)
+}
+
+// Associated reports the enrichments associated with the referenced update
+// operation, in a stable order.
+func associated(ctx context.Context, t *testing.T, pool *pgxpool.Pool, ref uuid.UUID) []string {
+	const query = `
+SELECT
+	e.tags, e.data
+FROM
+	enrichment AS e
+	JOIN uo_enrich AS uo ON uo.enrich = e.id
+	JOIN update_operation AS op ON uo.uo = op.id
+WHERE
+	op.ref = $1;`
+	rows, err := pool.Query(ctx, query, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var tags []string
+		var data json.RawMessage
+		if err := rows.Scan(&tags, &data); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, fmt.Sprintf("%v\x00%s", tags, data))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// GenEnrichments creates n records, of which every "every"th record has
+// contents dependent on the generation "gen".
+func genEnrichments(n, every, gen int) []driver.EnrichmentRecord {
+	es := make([]driver.EnrichmentRecord, n)
+	for i := range es {
+		v := 0
+		if i%every == 0 {
+			v = gen
+		}
+		es[i] = driver.EnrichmentRecord{
+			Tags:       []string{fmt.Sprintf("CVE-2021-%05d", i)},
+			Enrichment: json.RawMessage(fmt.Sprintf(`{"score":%d,"gen":%d}`, i, v)),
+		}
+	}
+	return es
+}
+
+// TestUpdateEnrichmentsDiff checks that the diffing UpdateEnrichments produces
+// the same associations as the naive implementation while issuing fewer
+// statements.
+func TestUpdateEnrichmentsDiff(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		name  = "test-enrichment"
+		sz    = 1000
+		every = 100
+		gens  = 3
+	)
+
+	naivePool, naiveCt := countingPool(ctx, t, TestDB(ctx, t))
+	diffPool, diffCt := countingPool(ctx, t, TestDB(ctx, t))
+	s := NewVulnStore(diffPool)
+
+	for gen := 0; gen < gens; gen++ {
+		fp := driver.Fingerprint(uuid.New().String())
+		naiveCt.Reset()
+		naiveRef, err := naiveUpdateEnrichments(ctx, naivePool, name, fp, genEnrichments(sz, every, gen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		naiveN := naiveCt.Reset()
+
+		diffCt.Reset()
+		diffRef, err := s.UpdateEnrichments(ctx, name, fp, genEnrichments(sz, every, gen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diffN := diffCt.Reset()
+		t.Logf("generation %d: naive issued %d statements, diff issued %d", gen, naiveN, diffN)
+
+		want := associated(ctx, t, naivePool, naiveRef)
+		got := associated(ctx, t, diffPool, diffRef)
+		if len(got) != sz {
+			t.Errorf("got %d associations, want %d", len(got), sz)
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		if gen != 0 && diffN >= naiveN {
+			t.Errorf("diff path issued %d statements, naive path %d", diffN, naiveN)
+		}
+	}
+}
+
+func TestValidEnrichments(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	good := driver.EnrichmentRecord{
+		Tags:       []string{"CVE-2021-00001"},
+		Enrichment: json.RawMessage(`{}`),
+	}
+	noTags := driver.EnrichmentRecord{
+		Enrichment: json.RawMessage(`{}`),
+	}
+	emptyTag := driver.EnrichmentRecord{
+		Tags:       []string{"CVE-2021-00002", ""},
+		Enrichment: json.RawMessage(`{}`),
+	}
+	const max = 64
+	large := driver.EnrichmentRecord{
+		Tags:       []string{"CVE-2021-00003"},
+		Enrichment: json.RawMessage(`"` + strings.Repeat("x", max) + `"`),
+	}
+	badJSON := driver.EnrichmentRecord{
+		Tags:       []string{"CVE-2021-00004"},
+		Enrichment: json.RawMessage(`{"score":`),
+	}
+	tt := []struct {
+		Name   string
+		In     []driver.EnrichmentRecord
+		Max    int
+		Reject bool
+		Want   int
+		Err    bool
+	}{
+		{Name: "Valid", In: []driver.EnrichmentRecord{good, good}, Want: 2},
+		{Name: "Empty", In: nil, Want: 0},
+		{Name: "SkipNoTags", In: []driver.EnrichmentRecord{good, noTags, good}, Want: 2},
+		{Name: "SkipEmptyTag", In: []driver.EnrichmentRecord{emptyTag, good}, Want: 1},
+		{Name: "SkipAll", In: []driver.EnrichmentRecord{noTags, emptyTag}, Want: 0},
+		{Name: "RejectNoTags", In: []driver.EnrichmentRecord{good, noTags}, Reject: true, Err: true},
+		{Name: "RejectEmptyTag", In: []driver.EnrichmentRecord{emptyTag}, Reject: true, Err: true},
+		{Name: "RejectValid", In: []driver.EnrichmentRecord{good}, Reject: true, Want: 1},
+		{Name: "SkipTooLarge", In: []driver.EnrichmentRecord{good, large}, Max: max, Want: 1},
+		{Name: "NoLimit", In: []driver.EnrichmentRecord{good, large}, Want: 2},
+		{Name: "SkipInvalidJSON", In: []driver.EnrichmentRecord{badJSON, good}, Want: 1},
+		{Name: "SkipMixed", In: []driver.EnrichmentRecord{noTags, large, badJSON, good}, Max: max, Want: 1},
+		{Name: "RejectTooLarge", In: []driver.EnrichmentRecord{large}, Max: max, Reject: true, Err: true},
+		{Name: "RejectInvalidJSON", In: []driver.EnrichmentRecord{good, badJSON}, Reject: true, Err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			got, err := validEnrichments(ctx, "test", tc.In, tc.Max, tc.Reject)
+			switch {
+			case tc.Err && !errors.Is(err, ErrInvalidEnrichment):
+				t.Fatalf("got: %v, want: %v", err, ErrInvalidEnrichment)
+			case tc.Err:
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+			if len(got) != tc.Want {
+				t.Errorf("got %d records, want %d", len(got), tc.Want)
+			}
+			for i := range got {
+				if r := invalidEnrichment(&got[i], tc.Max); r != "" {
+					t.Errorf("record %d: invalid record returned: %s", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEnrichmentNoTags(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// No pool: the store must not get as far as issuing a query.
+	s := NewVulnStore(nil)
+	if _, err := s.GetEnrichment(ctx, "test", nil); err == nil {
+		t.Error("expected error for empty tag list")
+	}
+}
+
+// TestUpdateEnrichmentsInvalid checks the handling of invalid records against
+// a database.
+func TestUpdateEnrichmentsInvalid(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	es := []driver.EnrichmentRecord{
+		{Tags: []string{"CVE-2021-00001"}, Enrichment: json.RawMessage(`{"score":1}`)},
+		{Tags: nil, Enrichment: json.RawMessage(`{"score":2}`)},
+		{Tags: []string{""}, Enrichment: json.RawMessage(`{"score":3}`)},
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		pool := TestDB(ctx, t)
+		s := NewVulnStore(pool)
+		ref, err := s.UpdateEnrichments(ctx, "test-invalid", driver.Fingerprint(uuid.New().String()), es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := associated(ctx, t, pool, ref); len(got) != 1 {
+			t.Errorf("got %d associations, want 1: %v", len(got), got)
+		}
+		n, err := unreachableEnrichments(ctx, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("got %d unreachable enrichments, want 0", n)
+		}
+	})
+	t.Run("Reject", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := NewVulnStore(TestDB(ctx, t), WithRejectInvalidEnrichments())
+		_, err := s.UpdateEnrichments(ctx, "test-invalid", driver.Fingerprint(uuid.New().String()), es)
+		if !errors.Is(err, ErrInvalidEnrichment) {
+			t.Errorf("got: %v, want: %v", err, ErrInvalidEnrichment)
+		}
+	})
+	t.Run("Payload", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		pool := TestDB(ctx, t)
+		s := NewVulnStore(pool, WithMaxEnrichmentSize(16))
+		es := []driver.EnrichmentRecord{
+			{Tags: []string{"CVE-2021-00001"}, Enrichment: json.RawMessage(`{"score":1}`)},
+			{Tags: []string{"CVE-2021-00002"}, Enrichment: json.RawMessage(`{"score":`)},
+			{Tags: []string{"CVE-2021-00003"}, Enrichment: json.RawMessage(`{"vector":"AV:N/AC:L/Au:N"}`)},
+		}
+		ref, err := s.UpdateEnrichments(ctx, "test-payload", driver.Fingerprint(uuid.New().String()), es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := associated(ctx, t, pool, ref); len(got) != 1 {
+			t.Errorf("got %d associations, want 1: %v", len(got), got)
+		}
+	})
+	t.Run("Unreachable", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		pool := TestDB(ctx, t)
+		// Write the invalid records the way older versions did.
+		if _, err := naiveUpdateEnrichments(ctx, pool, "test-invalid", driver.Fingerprint(uuid.New().String()), es); err != nil {
+			t.Fatal(err)
+		}
+		n, err := unreachableEnrichments(ctx, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Errorf("got %d unreachable enrichments, want 2", n)
+		}
+	})
+}
+
+func TestEnrichmentExists(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := NewVulnStore(TestDB(ctx, t))
+	const name = "test-exists"
+	es := []driver.EnrichmentRecord{
+		{Tags: []string{"CVE-2021-00002", "CVE-2021-00001"}, Enrichment: json.RawMessage(`{"score":1}`)},
+		{Tags: []string{"CVE-2021-00003"}, Enrichment: json.RawMessage(`{"score":2}`)},
+	}
+	absent := driver.EnrichmentRecord{Tags: []string{"CVE-2021-00004"}, Enrichment: json.RawMessage(`{"score":3}`)}
+	if _, err := s.UpdateEnrichments(ctx, name, driver.Fingerprint(uuid.New().String()), es); err != nil {
+		t.Fatal(err)
+	}
+	// Hash a copy with the tags in another order, to check callers don't
+	// need to sort them.
+	present := driver.EnrichmentRecord{Tags: []string{"CVE-2021-00001", "CVE-2021-00002"}, Enrichment: es[0].Enrichment}
+	kind, hash := driver.HashEnrichment(&present)
+	_, other := driver.HashEnrichment(&es[1])
+	_, missing := driver.HashEnrichment(&absent)
+
+	tt := []struct {
+		Name    string
+		Updater string
+		Hash    []byte
+		Want    bool
+	}{
+		{Name: "Present", Updater: name, Hash: hash, Want: true},
+		{Name: "Absent", Updater: name, Hash: missing, Want: false},
+		{Name: "WrongUpdater", Updater: "test-other", Hash: hash, Want: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			got, err := s.EnrichmentExists(ctx, tc.Updater, kind, tc.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got %v, want %v", got, tc.Want)
+			}
+		})
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		got, err := s.MissingEnrichments(ctx, name, kind, [][]byte{missing, hash, other})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := [][]byte{missing}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		got, err = s.MissingEnrichments(ctx, "test-other", kind, [][]byte{hash, other})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := [][]byte{hash, other}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+
+	t.Run("Superseded", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		if _, err := s.UpdateEnrichments(ctx, name, driver.Fingerprint(uuid.New().String()), es[1:]); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := s.EnrichmentExists(ctx, name, kind, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("record from a previous update reported present")
+		}
+	})
+}
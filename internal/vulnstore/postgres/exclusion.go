@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+)
+
+var _ vulnstore.Exclusions = (*Store)(nil)
+
+// AddExclusion implements vulnstore.Exclusions.
+func (s *Store) AddExclusion(ctx context.Context, e claircore.Exclusion) (*claircore.Exclusion, error) {
+	const query = `
+INSERT INTO exclusion
+	(namespace, vulnerability, package, version, manifest, reason, author, expires)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING ref, created;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/AddExclusion"))
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	var manifest string
+	if e.Manifest != nil {
+		manifest = e.Manifest.String()
+	}
+	err := s.pool.QueryRow(ctx, query,
+		s.namespace, e.Vulnerability, e.Package, e.Version, manifest, e.Reason, e.Author, e.Expires,
+	).Scan(&e.ID, &e.Created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add exclusion: %w", err)
+	}
+	return &e, nil
+}
+
+// ListExclusions implements vulnstore.Exclusions.
+func (s *Store) ListExclusions(ctx context.Context) ([]claircore.Exclusion, error) {
+	const query = `
+SELECT
+	ref, vulnerability, package, version, manifest, reason, author, created, expires
+FROM exclusion
+WHERE namespace = $1
+ORDER BY id;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/ListExclusions"))
+	rows, err := s.pool.Query(ctx, query, s.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exclusions: %w", err)
+	}
+	defer rows.Close()
+	var out []claircore.Exclusion
+	for rows.Next() {
+		var e claircore.Exclusion
+		var manifest string
+		var expires *time.Time
+		if err := rows.Scan(
+			&e.ID, &e.Vulnerability, &e.Package, &e.Version, &manifest,
+			&e.Reason, &e.Author, &e.Created, &expires,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan exclusion: %w", err)
+		}
+		if manifest != "" {
+			d, err := claircore.ParseDigest(manifest)
+			if err != nil {
+				return nil, fmt.Errorf("exclusion %v: bad manifest: %w", e.ID, err)
+			}
+			e.Manifest = &d
+		}
+		e.Expires = expires
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list exclusions: %w", err)
+	}
+	return out, nil
+}
+
+// DeleteExclusion implements vulnstore.Exclusions.
+func (s *Store) DeleteExclusion(ctx context.Context, id uuid.UUID) (bool, error) {
+	const query = `DELETE FROM exclusion WHERE ref = $1 AND namespace = $2;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/DeleteExclusion"))
+	tag, err := s.pool.Exec(ctx, query, id, s.namespace)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete exclusion: %w", err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestExclusions(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+	in := []claircore.Exclusion{
+		{
+			Vulnerability: "CVE-2021-3449",
+			Package:       "openssl",
+			Reason:        "renegotiation is disabled",
+			Author:        "secteam",
+		},
+		{
+			Vulnerability: "CVE-2019-18276",
+			Package:       "bash",
+			Version:       "5.0-*",
+			Manifest:      &manifest,
+			Reason:        "not setuid",
+			Expires:       &expires,
+		},
+	}
+	// Digests and times need some help to compare.
+	opts := cmp.Options{
+		cmp.AllowUnexported(claircore.Digest{}),
+		cmpopts.EquateApproxTime(time.Millisecond),
+	}
+
+	var added []claircore.Exclusion
+	for _, e := range in {
+		got, err := s.AddExclusion(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID == uuid.Nil || got.Created.IsZero() {
+			t.Errorf("ID or creation time not set: %+v", got)
+		}
+		added = append(added, *got)
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		for _, e := range []claircore.Exclusion{
+			{Package: "openssl", Reason: "reason"},
+			{Vulnerability: "CVE-2021-3449", Reason: "reason"},
+			{Vulnerability: "CVE-2021-3449", Package: "openssl"},
+			{Vulnerability: "CVE-2021-3449", Package: "openssl", Reason: "reason", Version: "1.1.1["},
+		} {
+			_, err := s.AddExclusion(ctx, e)
+			if !errors.Is(err, claircore.ErrInvalidExclusion) {
+				t.Errorf("%+v: got error %v, want %v", e, err, claircore.ErrInvalidExclusion)
+			}
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		got, err := s.ListExclusions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(got, added, opts) {
+			t.Error(cmp.Diff(got, added, opts))
+		}
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		other := NewVulnStore(pool, WithNamespace("test-exclusions"))
+		got, err := other.ListExclusions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("other namespace sees exclusions: %+v", got)
+		}
+		ok, err := other.DeleteExclusion(ctx, added[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("other namespace deleted an exclusion")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		ok, err := s.DeleteExclusion(ctx, added[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Error("exclusion not deleted")
+		}
+		ok, err = s.DeleteExclusion(ctx, added[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("exclusion deleted twice")
+		}
+		got, err := s.ListExclusions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := added[1:]; !cmp.Equal(got, want, opts) {
+			t.Error(cmp.Diff(got, want, opts))
+		}
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -36,6 +37,14 @@ var (
 		},
 		[]string{"query"},
 	)
+	unreachableEnrichmentsGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "unreachable_enrichments",
+			Help:      "The number of enrichment records that can never be returned, as of the last GC.",
+		},
+	)
 )
 
 // GC is split into two phases, first it will identify any update operations
@@ -44,6 +53,11 @@ var (
 // Next it will perform chunked deletions of any vulns from the vuln table
 // which are not longer referenced by update operations.
 //
+// Finally, it reports any enrichment records that can never be returned
+// because they have no usable tags. These are logged rather than deleted.
+//
+// Only update operations and vulns in the Store's namespace are collected.
+//
 // The GC is throttled to not overload the database with cascade deletes.
 // If a full GC is required run this method until the returned int64 value
 // is 0.
@@ -55,7 +69,7 @@ func (s *Store) GC(ctx context.Context, keep int) (int64, error) {
 	)
 
 	// obtain update operations which need deletin'
-	ops, totalOps, err := eligibleUpdateOpts(ctx, s.pool, keep)
+	ops, totalOps, err := eligibleUpdateOpts(ctx, s.pool, s.namespace, keep)
 	if err != nil {
 		return 0, err
 	}
@@ -71,7 +85,7 @@ func (s *Store) GC(ctx context.Context, keep int) (int64, error) {
 	}
 
 	// get all updaters we know about.
-	updaters, err := distinctUpdaters(ctx, s.pool)
+	updaters, err := distinctUpdaters(ctx, s.pool, s.namespace)
 	if err != nil {
 		return totalOps - deletedOps, err
 	}
@@ -90,7 +104,7 @@ func (s *Store) GC(ctx context.Context, keep int) (int64, error) {
 		}
 		go func(u string) {
 			defer sem.Release(1)
-			err := chunkedCleanup(ctx, s.pool, u)
+			err := chunkedCleanup(ctx, s.pool, s.namespace, u)
 			if err != nil {
 				errC <- err
 			}
@@ -102,6 +116,17 @@ func (s *Store) GC(ctx context.Context, keep int) (int64, error) {
 	// all in-flight go routines are guarantee to release their sems.
 	sem.Acquire(context.Background(), cpus)
 
+	switch n, err := unreachableEnrichments(ctx, s.pool); {
+	case err != nil:
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to check for unreachable enrichments")
+	case n > 0:
+		zlog.Warn(ctx).
+			Int64("count", n).
+			Msg("found unreachable enrichment records")
+	}
+
 	close(errC)
 	if len(errC) > 0 {
 		b := strings.Builder{}
@@ -115,15 +140,15 @@ func (s *Store) GC(ctx context.Context, keep int) (int64, error) {
 }
 
 // distinctUpdaters returns all updaters which have registered an update
-// operation.
-func distinctUpdaters(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+// operation in the namespace.
+func distinctUpdaters(ctx context.Context, pool *pgxpool.Pool, ns string) ([]string, error) {
 	const (
 		// will always contain at least two update operations
 		selectUpdaters = `
-SELECT DISTINCT(updater) FROM update_operation;
+SELECT DISTINCT(updater) FROM update_operation WHERE namespace = $1;
 `
 	)
-	rows, err := pool.Query(ctx, selectUpdaters)
+	rows, err := pool.Query(ctx, selectUpdaters, ns)
 	if err != nil {
 		return nil, fmt.Errorf("error selecting distinct updaters: %v", err)
 	}
@@ -147,15 +172,15 @@ SELECT DISTINCT(updater) FROM update_operation;
 	return updaters, nil
 }
 
-// eligibleUpdateOpts returns a list of update operation refs which exceed the specified
-// keep value.
-func eligibleUpdateOpts(ctx context.Context, pool *pgxpool.Pool, keep int) ([]uuid.UUID, int64, error) {
+// eligibleUpdateOpts returns a list of update operation refs in the namespace
+// which exceed the specified keep value.
+func eligibleUpdateOpts(ctx context.Context, pool *pgxpool.Pool, ns string, keep int) ([]uuid.UUID, int64, error) {
 	const (
 		// this query will return rows of UUID arrays.
 		// each returned array are the UUIDs which exceed the provided keep value
 		updateOps = `
 WITH ordered_ops AS (
-    SELECT array_agg(ref ORDER BY date DESC) AS refs FROM update_operation GROUP BY updater
+    SELECT array_agg(ref ORDER BY date DESC) AS refs FROM update_operation WHERE namespace = $3 GROUP BY updater
 )
 SELECT ordered_ops.refs[$1:]
 FROM ordered_ops
@@ -169,7 +194,7 @@ WHERE array_length(ordered_ops.refs, 1) > $2;
 	m := []uuid.UUID{}
 
 	start := time.Now()
-	rows, err := pool.Query(ctx, updateOps, keep+1, keep)
+	rows, err := pool.Query(ctx, updateOps, keep+1, keep, ns)
 	switch err {
 	case nil:
 	default:
@@ -197,10 +222,10 @@ WHERE array_length(ordered_ops.refs, 1) > $2;
 	return m, int64(len(m)), nil
 }
 
-func chunkedCleanup(ctx context.Context, pool *pgxpool.Pool, updater string) error {
+func chunkedCleanup(ctx context.Context, pool *pgxpool.Pool, ns, updater string) error {
 	const (
 		paginatedSelect = `
-SELECT id FROM vuln WHERE vuln.updater = $1 AND id > $2 ORDER BY id ASC LIMIT 10000;
+SELECT id FROM vuln WHERE vuln.updater = $1 AND vuln.namespace = $3 AND id > $2 ORDER BY id ASC LIMIT 10000;
 `
 		shouldDelete = `
 SELECT NOT EXISTS(SELECT 1 FROM uo_vuln WHERE vuln = $1);
@@ -223,7 +248,7 @@ DELETE FROM vuln WHERE id = $1;
 		err := func() error {
 			start := time.Now()
 
-			rows, err := pool.Query(ctx, paginatedSelect, updater, largestID)
+			rows, err := pool.Query(ctx, paginatedSelect, updater, largestID, ns)
 			if err != nil {
 				return err
 			}
@@ -320,3 +345,27 @@ DELETE FROM vuln WHERE id = $1;
 		}
 	}
 }
+
+// UnreachableEnrichments reports the number of enrichment records that
+// GetEnrichment can never return: those with no tags or an empty tag.
+func unreachableEnrichments(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	const query = `
+SELECT
+	count(*)
+FROM
+	enrichment
+WHERE
+	tags IS NULL
+	OR cardinality(tags) = 0
+	OR '' = ANY (tags);`
+
+	start := time.Now()
+	var n int64
+	if err := pool.QueryRow(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("error counting unreachable enrichments: %w", err)
+	}
+	gcCounter.WithLabelValues("unreachableEnrichments").Add(1)
+	gcDuration.WithLabelValues("unreachableEnrichments").Observe(time.Since(start).Seconds())
+	unreachableEnrichmentsGauge.Set(float64(n))
+	return n, nil
+}
@@ -42,6 +42,10 @@ var (
 func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/Get"))
+	phase, err := s.phase(ctx)
+	if err != nil {
+		return nil, err
+	}
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -50,7 +54,7 @@ func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts
 	// start a batch
 	batch := &pgx.Batch{}
 	for _, record := range records {
-		query, err := buildGetQuery(record, &opts)
+		query, err := buildGetQuery(record, &opts, phase, s.namespace)
 		if err != nil {
 			// if we cannot build a query for an individual record continue to the next
 			zlog.Debug(ctx).
@@ -119,6 +123,8 @@ func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts
 				&v.Repo.URI,
 				&v.FixedInVersion,
 				&v.Updater,
+				&v.FirstSeen,
+				&v.LastSeen,
 			)
 			v.ID = strconv.FormatInt(id, 10)
 			if err != nil {
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/generator"
+	"github.com/quay/claircore/test/integration"
+)
+
+func Benchmark_Get(b *testing.B) {
+	integration.NeedDB(b)
+	ctx := context.Background()
+	benchmarks := []generator.Config{
+		{Seed: 1, Updaters: 2, Vulnerabilities: 1000, Manifests: 10},
+		{Seed: 1, Updaters: 4, Vulnerabilities: 10000, Manifests: 10},
+		{Seed: 1, Updaters: 8, Vulnerabilities: 50000, Manifests: 10},
+	}
+	opts := vulnstore.GetOpts{
+		Matchers: []driver.MatchConstraint{
+			driver.DistributionDID,
+			driver.DistributionVersionID,
+		},
+	}
+	for _, c := range benchmarks {
+		name := fmt.Sprintf("%d updaters, %d vulnerabilities", c.Updaters, c.Vulnerabilities)
+		b.Run(name, func(b *testing.B) {
+			ctx := zlog.Test(ctx, b)
+			pool := TestDB(ctx, b)
+			store := NewVulnStore(pool)
+			d := generator.Generate(c)
+			if err := generator.LoadVulnerabilities(ctx, store, d); err != nil {
+				b.Fatal(err)
+			}
+			rs := make([][]*claircore.IndexRecord, len(d.Reports))
+			for i, r := range d.Reports {
+				rs[i] = r.IndexRecords()
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Get(ctx, rs[i%len(rs)], opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
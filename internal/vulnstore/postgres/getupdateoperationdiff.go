@@ -61,20 +61,20 @@ func (s *Store) GetUpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver
 	// of the incoming refs is not of kind = 'vulnerability'.
 	const confirmRefs = `
 SELECT 1
-WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref = $1 OR ref = $2);
+WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE (ref = $1 OR ref = $2) AND namespace = $3);
 `
 	// Query takes two update IDs and returns rows that only exist in first
 	// argument's set of vulnerabilities.
-	const query = `WITH
-		lhs AS (SELECT id, updater FROM update_operation WHERE ref = $1),
-		rhs AS (SELECT id, updater  FROM update_operation WHERE ref = $2)
+	const queryFmt = `WITH
+		lhs AS (SELECT id, updater FROM update_operation WHERE ref = $1 AND namespace = $3),
+		rhs AS (SELECT id, updater  FROM update_operation WHERE ref = $2 AND namespace = $3)
 	SELECT
 		id,
 		name,
 		updater,
-		description,
+		%s,
 		issued,
-		links,
+		%s,
 		severity,
 		normalized_severity,
 		package_name,
@@ -94,7 +94,9 @@ WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref =
 		repo_name,
 		repo_key,
 		repo_uri,
-		fixed_in_version
+		fixed_in_version,
+		first_seen,
+		last_seen
 	FROM vuln
 	WHERE
 		vuln.id IN (
@@ -111,10 +113,15 @@ WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref =
 	if cur == uuid.Nil {
 		return nil, errors.New("nil uuid is invalid as \"current\" endpoint")
 	}
+	phase, err := s.phase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(queryFmt, phase.descriptionExpr(), phase.linksExpr())
 
 	// confirm both refs are of type == 'vulnerability'
 	start := time.Now()
-	rows, err := s.pool.Query(ctx, confirmRefs, cur, prev)
+	rows, err := s.pool.Query(ctx, confirmRefs, cur, prev, s.namespace)
 	switch err {
 	case nil:
 		rows.Close()
@@ -128,11 +135,11 @@ WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref =
 
 	// Retrieve added first.
 	var diff driver.UpdateDiff
-	if err := populateRefs(ctx, &diff, s.pool, prev, cur); err != nil {
+	if err := populateRefs(ctx, &diff, s.pool, s.namespace, prev, cur); err != nil {
 		return nil, err
 	}
 
-	rows, err = s.pool.Query(ctx, query, cur, prev)
+	rows, err = s.pool.Query(ctx, query, cur, prev, s.namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve added vulnerabilities: %w", err)
 	}
@@ -163,7 +170,7 @@ WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref =
 	if prev == uuid.Nil {
 		return &diff, nil
 	}
-	rows, err = s.pool.Query(ctx, query, prev, cur)
+	rows, err = s.pool.Query(ctx, query, prev, cur, s.namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve removed vulnerabilities: %w", err)
 	}
@@ -187,14 +194,15 @@ WHERE ROW ('vulnerability') = ALL (SELECT kind FROM update_operation WHERE ref =
 }
 
 // PopulateRefs fills in the provided UpdateDiff with the details of the
-// operations indicated by the two refs.
-func populateRefs(ctx context.Context, diff *driver.UpdateDiff, pool *pgxpool.Pool, prev, cur uuid.UUID) error {
-	const query = `SELECT updater, fingerprint, date FROM update_operation WHERE ref = $1;`
+// operations indicated by the two refs. Operations outside the namespace "ns"
+// are reported as not existing.
+func populateRefs(ctx context.Context, diff *driver.UpdateDiff, pool *pgxpool.Pool, ns string, prev, cur uuid.UUID) error {
+	const query = `SELECT updater, fingerprint, date FROM update_operation WHERE ref = $1 AND namespace = $2;`
 	var err error
 
 	diff.Cur.Ref = cur
 	start := time.Now()
-	err = pool.QueryRow(ctx, query, cur).Scan(
+	err = pool.QueryRow(ctx, query, cur, ns).Scan(
 		&diff.Cur.Updater,
 		&diff.Cur.Fingerprint,
 		&diff.Cur.Date,
@@ -215,7 +223,7 @@ func populateRefs(ctx context.Context, diff *driver.UpdateDiff, pool *pgxpool.Po
 	diff.Prev.Ref = prev
 
 	start = time.Now()
-	err = pool.QueryRow(ctx, query, prev).Scan(
+	err = pool.QueryRow(ctx, query, prev, ns).Scan(
 		&diff.Prev.Updater,
 		&diff.Prev.Fingerprint,
 		&diff.Prev.Date,
@@ -78,9 +78,9 @@ var (
 // GetLatestUpdateRef implements driver.Updater.
 func (s *Store) GetLatestUpdateRef(ctx context.Context, kind driver.UpdateKind) (uuid.UUID, error) {
 	const (
-		query              = `SELECT ref FROM update_operation ORDER BY id USING > LIMIT 1;`
-		queryEnrichment    = `SELECT ref FROM update_operation WHERE kind = 'enrichment' ORDER BY id USING > LIMIT 1;`
-		queryVulnerability = `SELECT ref FROM update_operation WHERE kind = 'vulnerability' ORDER BY id USING > LIMIT 1;`
+		query              = `SELECT ref FROM update_operation WHERE namespace = $1 ORDER BY id USING > LIMIT 1;`
+		queryEnrichment    = `SELECT ref FROM update_operation WHERE namespace = $1 AND kind = 'enrichment' ORDER BY id USING > LIMIT 1;`
+		queryVulnerability = `SELECT ref FROM update_operation WHERE namespace = $1 AND kind = 'vulnerability' ORDER BY id USING > LIMIT 1;`
 	)
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/getLatestRef"))
@@ -101,7 +101,7 @@ func (s *Store) GetLatestUpdateRef(ctx context.Context, kind driver.UpdateKind)
 
 	var ref uuid.UUID
 	start := time.Now()
-	if err := s.pool.QueryRow(ctx, q).Scan(&ref); err != nil {
+	if err := s.pool.QueryRow(ctx, q, s.namespace).Scan(&ref); err != nil {
 		return uuid.Nil, err
 	}
 	getLatestUpdateRefCounter.WithLabelValues(label).Add(1)
@@ -111,9 +111,9 @@ func (s *Store) GetLatestUpdateRef(ctx context.Context, kind driver.UpdateKind)
 
 func (s *Store) GetLatestUpdateRefs(ctx context.Context, kind driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
 	const (
-		query              = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date FROM update_operation ORDER BY updater, id USING >;`
-		queryEnrichment    = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date FROM update_operation WHERE kind = 'enrichment' ORDER BY updater, id USING >;`
-		queryVulnerability = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date FROM update_operation WHERE kind = 'vulnerability' ORDER BY updater, id USING >;`
+		query              = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date, source FROM update_operation WHERE namespace = $1 ORDER BY updater, id USING >;`
+		queryEnrichment    = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date, source FROM update_operation WHERE namespace = $1 AND kind = 'enrichment' ORDER BY updater, id USING >;`
+		queryVulnerability = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date, source FROM update_operation WHERE namespace = $1 AND kind = 'vulnerability' ORDER BY updater, id USING >;`
 	)
 
 	var q string
@@ -132,7 +132,7 @@ func (s *Store) GetLatestUpdateRefs(ctx context.Context, kind driver.UpdateKind)
 
 	start := time.Now()
 
-	rows, err := s.pool.Query(ctx, q)
+	rows, err := s.pool.Query(ctx, q, s.namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -152,6 +152,7 @@ func (s *Store) GetLatestUpdateRefs(ctx context.Context, kind driver.UpdateKind)
 			&uo.Ref,
 			&uo.Fingerprint,
 			&uo.Date,
+			&uo.Source,
 		)
 		if err != nil {
 			rows.Close()
@@ -165,14 +166,14 @@ func (s *Store) GetLatestUpdateRefs(ctx context.Context, kind driver.UpdateKind)
 	return ret, nil
 }
 
-func getLatestRefs(ctx context.Context, pool *pgxpool.Pool) (map[string][]driver.UpdateOperation, error) {
-	const query = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date FROM update_operation ORDER BY updater, id USING >;`
+func getLatestRefs(ctx context.Context, pool *pgxpool.Pool, ns string) (map[string][]driver.UpdateOperation, error) {
+	const query = `SELECT DISTINCT ON (updater) updater, ref, fingerprint, date, source FROM update_operation WHERE namespace = $1 ORDER BY updater, id USING >;`
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/getLatestRefs"))
 
 	start := time.Now()
 
-	rows, err := pool.Query(ctx, query)
+	rows, err := pool.Query(ctx, query, ns)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +193,7 @@ func getLatestRefs(ctx context.Context, pool *pgxpool.Pool) (map[string][]driver
 			&uo.Ref,
 			&uo.Fingerprint,
 			&uo.Date,
+			&uo.Source,
 		)
 		if err != nil {
 			rows.Close()
@@ -207,10 +209,10 @@ func getLatestRefs(ctx context.Context, pool *pgxpool.Pool) (map[string][]driver
 
 func (s *Store) GetUpdateOperations(ctx context.Context, kind driver.UpdateKind, updater ...string) (map[string][]driver.UpdateOperation, error) {
 	const (
-		query              = `SELECT ref, updater, fingerprint, date FROM update_operation WHERE updater = ANY($1) ORDER BY id DESC;`
-		queryVulnerability = `SELECT ref, updater, fingerprint, date FROM update_operation WHERE updater = ANY($1) AND kind = 'vulnerability' ORDER BY id DESC;`
-		queryEnrichment    = `SELECT ref, updater, fingerprint, date FROM update_operation WHERE updater = ANY($1) AND kind = 'enrichment' ORDER BY id DESC;`
-		getUpdaters        = `SELECT DISTINCT(updater) FROM update_operation;`
+		query              = `SELECT ref, updater, fingerprint, date, source FROM update_operation WHERE updater = ANY($1) AND namespace = $2 ORDER BY id DESC;`
+		queryVulnerability = `SELECT ref, updater, fingerprint, date, source FROM update_operation WHERE updater = ANY($1) AND namespace = $2 AND kind = 'vulnerability' ORDER BY id DESC;`
+		queryEnrichment    = `SELECT ref, updater, fingerprint, date, source FROM update_operation WHERE updater = ANY($1) AND namespace = $2 AND kind = 'enrichment' ORDER BY id DESC;`
+		getUpdaters        = `SELECT DISTINCT(updater) FROM update_operation WHERE namespace = $1;`
 	)
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/getUpdateOperations"))
@@ -228,7 +230,7 @@ func (s *Store) GetUpdateOperations(ctx context.Context, kind driver.UpdateKind,
 
 		start := time.Now()
 
-		rows, err := tx.Query(ctx, getUpdaters)
+		rows, err := tx.Query(ctx, getUpdaters, s.namespace)
 		switch {
 		case err == nil:
 		case errors.Is(err, pgx.ErrNoRows):
@@ -271,7 +273,7 @@ func (s *Store) GetUpdateOperations(ctx context.Context, kind driver.UpdateKind,
 	}
 
 	start := time.Now()
-	rows, err := tx.Query(ctx, q, updater)
+	rows, err := tx.Query(ctx, q, updater, s.namespace)
 	switch {
 	case err == nil:
 	case errors.Is(err, pgx.ErrNoRows):
@@ -289,6 +291,7 @@ func (s *Store) GetUpdateOperations(ctx context.Context, kind driver.UpdateKind,
 			&uo.Updater,
 			&uo.Fingerprint,
 			&uo.Date,
+			&uo.Source,
 		)
 		if err != nil {
 			rows.Close()
@@ -298,3 +301,23 @@ func (s *Store) GetUpdateOperations(ctx context.Context, kind driver.UpdateKind,
 	}
 	return out, nil
 }
+
+// CheckOperationKind reports an error if the updater "name" already has
+// update operations of a kind other than "kind" in the namespace "ns".
+//
+// An updater's operations should all share a kind. Latest operations are
+// resolved by updater name, so mixing kinds means one updater's data is
+// silently read as the other's. This usually means two differently-kinded
+// updaters were configured with the same name.
+func checkOperationKind(ctx context.Context, tx pgx.Tx, ns, name string, kind driver.UpdateKind) error {
+	const query = `SELECT kind FROM update_operation WHERE namespace = $1 AND updater = $2 AND kind <> $3 LIMIT 1;`
+	var other string
+	err := tx.QueryRow(ctx, query, ns, name, string(kind)).Scan(&other)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check update operation kind: %w", err)
+	}
+	return fmt.Errorf("updater %q has existing %s update operations, refusing to add a %s operation", name, other, kind)
+}
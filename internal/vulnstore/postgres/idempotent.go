@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ vulnstore.Idempotent = (*Store)(nil)
+
+// UpdateVulnerabilitiesOnce implements vulnstore.Idempotent.
+func (s *Store) UpdateVulnerabilitiesOnce(ctx context.Context, key string, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+	if key == "" {
+		return uuid.Nil, errors.New("empty idempotency key")
+	}
+	p, err := s.phase(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return updateVulnerabilites(ctx, s.pool, p, s.namespace, key, updater, fingerprint, vulns)
+}
+
+// UpdateEnrichmentsOnce implements vulnstore.Idempotent.
+func (s *Store) UpdateEnrichmentsOnce(ctx context.Context, key string, name string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
+	if key == "" {
+		return uuid.Nil, errors.New("empty idempotency key")
+	}
+	return s.updateEnrichments(ctx, key, name, fp, es)
+}
+
+// CreateOperation creates an update operation of kind "kind" for "updater" in
+// the namespace "ns", reporting its ID, reference, and date.
+//
+// If "key" is not empty and the updater's latest update operation was created
+// with it, that operation is reported instead and "created" is false. An
+// earlier operation holding "key" gives it up to the new one, so that an
+// updater returning to data it had before gets a new operation.
+//
+// If "at" is not nil, it's the date of a created operation. Otherwise, it's
+// the time of the transaction.
+func createOperation(ctx context.Context, tx pgx.Tx, ns, key, updater string, fp driver.Fingerprint, kind driver.UpdateKind, at *time.Time) (id uint64, ref uuid.UUID, date time.Time, created bool, err error) {
+	const (
+		// Latest finds the updater's latest operation, if it holds the key.
+		latest = `
+SELECT id, ref, date
+FROM update_operation
+WHERE namespace = $1
+	AND idempotency_key = $2
+	AND id = (SELECT max(id) FROM update_operation WHERE namespace = $1 AND updater = $3);`
+		release = `UPDATE update_operation SET idempotency_key = NULL WHERE namespace = $1 AND idempotency_key = $2;`
+		// Create makes a new update operation and returns the reference and
+		// ID. It does nothing if a concurrent update committed an operation
+		// with the same key first.
+		create = `
+INSERT INTO update_operation (updater, fingerprint, kind, namespace, idempotency_key, date)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), COALESCE($6::timestamptz, now()))
+ON CONFLICT (namespace, idempotency_key) DO NOTHING
+RETURNING id, ref, date;`
+		existing = `SELECT id, ref, date FROM update_operation WHERE namespace = $1 AND idempotency_key = $2;`
+	)
+	if key != "" {
+		err = tx.QueryRow(ctx, latest, ns, key, updater).Scan(&id, &ref, &date)
+		switch {
+		case err == nil:
+			return id, ref, date, false, nil
+		case errors.Is(err, pgx.ErrNoRows):
+		default:
+			return 0, uuid.Nil, time.Time{}, false, fmt.Errorf("failed to look up update_operation: %w", err)
+		}
+		if _, err := tx.Exec(ctx, release, ns, key); err != nil {
+			return 0, uuid.Nil, time.Time{}, false, fmt.Errorf("failed to release idempotency key: %w", err)
+		}
+	}
+	err = tx.QueryRow(ctx, create, updater, string(fp), string(kind), ns, key, at).Scan(&id, &ref, &date)
+	switch {
+	case err == nil:
+		return id, ref, date, true, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		if err := tx.QueryRow(ctx, existing, ns, key).Scan(&id, &ref, &date); err != nil {
+			return 0, uuid.Nil, time.Time{}, false, fmt.Errorf("failed to look up update_operation: %w", err)
+		}
+		return id, ref, date, false, nil
+	}
+	return 0, uuid.Nil, time.Time{}, false, fmt.Errorf("failed to create update_operation: %w", err)
+}
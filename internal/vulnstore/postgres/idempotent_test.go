@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestIdempotent simulates a retry of an update whose first attempt committed
+// without the caller learning of it.
+func TestIdempotent(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	const updater = "idempotent"
+	fp := driver.Fingerprint(uuid.New().String())
+	vs := test.GenUniqueVulnerabilities(5, updater)
+	count := func(t *testing.T, name string, kind driver.UpdateKind) int {
+		t.Helper()
+		ops, err := s.GetUpdateOperations(ctx, kind, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(ops[name])
+	}
+
+	first, err := s.UpdateVulnerabilitiesOnce(ctx, "a", updater, fp, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := s.UpdateVulnerabilitiesOnce(ctx, "a", updater, fp, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry != first {
+		t.Errorf("retry: got: %v, want: %v", retry, first)
+	}
+	if got, want := count(t, updater, driver.VulnerabilityKind), 1; got != want {
+		t.Errorf("got %d update operations, want %d", got, want)
+	}
+
+	// New data, then a return to the first data, makes new operations.
+	second, err := s.UpdateVulnerabilitiesOnce(ctx, "b", updater, fp, vs[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := s.UpdateVulnerabilitiesOnce(ctx, "a", updater, fp, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || third == first || third == second {
+		t.Errorf("expected distinct refs: %v, %v, %v", first, second, third)
+	}
+	if got, want := count(t, updater, driver.VulnerabilityKind), 3; got != want {
+		t.Errorf("got %d update operations, want %d", got, want)
+	}
+	// UpdateVulnerabilities never deduplicates.
+	if _, err := s.UpdateVulnerabilities(ctx, updater, fp, vs); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count(t, updater, driver.VulnerabilityKind), 4; got != want {
+		t.Errorf("got %d update operations, want %d", got, want)
+	}
+
+	t.Run("Enrichment", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		const name = "idempotent-enrichment"
+		es := []driver.EnrichmentRecord{
+			{Tags: []string{"CVE-2021-0001"}, Enrichment: json.RawMessage(`{"score":1}`)},
+			{Tags: []string{"CVE-2021-0002"}, Enrichment: json.RawMessage(`{"score":2}`)},
+		}
+		first, err := s.UpdateEnrichmentsOnce(ctx, "a", name, fp, es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry, err := s.UpdateEnrichmentsOnce(ctx, "a", name, fp, es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry != first {
+			t.Errorf("retry: got: %v, want: %v", retry, first)
+		}
+		if got, want := count(t, name, driver.EnrichmentKind), 1; got != want {
+			t.Errorf("got %d update operations, want %d", got, want)
+		}
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		// The same key in another namespace is another update.
+		other := NewVulnStore(pool, WithNamespace("other"))
+		ref, err := other.UpdateVulnerabilitiesOnce(ctx, "a", updater, fp, vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref == third {
+			t.Errorf("namespaces share update operation %v", ref)
+		}
+	})
+}
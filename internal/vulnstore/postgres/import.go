@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/internal/vulnstore"
+)
+
+var _ vulnstore.Importer = (*Store)(nil)
+
+// ImportUpdates implements vulnstore.Importer.
+//
+// Every update is written in a single transaction.
+func (s *Store) ImportUpdates(ctx context.Context, f func(vulnstore.ImportFunc) error) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/ImportUpdates"))
+	p, err := s.phase(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	add := func(ctx context.Context, im *vulnstore.Import) (uuid.UUID, error) {
+		var at *time.Time
+		if !im.Date.IsZero() {
+			at = &im.Date
+		}
+		ref, _, err := writeVulnerabilities(ctx, tx, p, s.namespace, "", im.Updater, im.Fingerprint, at, im.Vulns)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if im.Source != nil {
+			if err := setDataSource(ctx, tx, s.namespace, ref, *im.Source); err != nil {
+				return uuid.Nil, err
+			}
+		}
+		return ref, nil
+	}
+	if err := f(add); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
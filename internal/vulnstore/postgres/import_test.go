@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestImportUpdates(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	date := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	src := claircore.DataSource{Name: "Test Source", License: "CC-BY-4.0"}
+
+	// A failed import leaves nothing behind.
+	errStop := errors.New("stop")
+	err := s.ImportUpdates(ctx, func(add vulnstore.ImportFunc) error {
+		if _, err := add(ctx, &vulnstore.Import{
+			Updater:     "failed",
+			Fingerprint: driver.Fingerprint(uuid.New().String()),
+			Date:        date,
+			Vulns:       test.GenUniqueVulnerabilities(5, "failed"),
+		}); err != nil {
+			return err
+		}
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got: %v, want: %v", err, errStop)
+	}
+	if ok, err := s.Initialized(ctx); err != nil || ok {
+		t.Fatalf("initialized by a failed import: %v, %v", ok, err)
+	}
+
+	err = s.ImportUpdates(ctx, func(add vulnstore.ImportFunc) error {
+		for _, u := range []string{"a", "b"} {
+			if _, err := add(ctx, &vulnstore.Import{
+				Updater:     u,
+				Fingerprint: driver.Fingerprint(uuid.New().String()),
+				Date:        date,
+				Source:      &src,
+				Vulns:       test.GenUniqueVulnerabilities(5, u),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ops["failed"]; ok {
+		t.Error("found update operation from the failed import")
+	}
+	for _, u := range []string{"a", "b"} {
+		us := ops[u]
+		if len(us) != 1 {
+			t.Fatalf("%s: got %d update operations, want 1", u, len(us))
+		}
+		if got := us[0].Date; !got.Equal(date) {
+			t.Errorf("%s: got date %v, want %v", u, got, date)
+		}
+		if got := us[0].Source; got == nil || !cmp.Equal(*got, src) {
+			t.Errorf("%s: got source %+v, want %+v", u, got, src)
+		}
+	}
+
+	// The vulnerabilities were first seen when the update was made.
+	var n int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM vuln WHERE updater IN ('a', 'b') AND first_seen = $1 AND last_seen = $1;`, date).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("got %d vulnerabilities seen at %v, want 10", n, date)
+	}
+}
@@ -7,14 +7,14 @@ import (
 
 func (s *Store) Initialized(ctx context.Context) (bool, error) {
 	const query = `
-SELECT EXISTS(SELECT 1 FROM vuln LIMIT 1);
+SELECT EXISTS(SELECT 1 FROM vuln WHERE namespace = $1 LIMIT 1);
 `
 	ok := atomic.LoadUint32(&s.initialized) != 0
 	if ok {
 		return true, nil
 	}
 
-	if err := s.pool.QueryRow(ctx, query).Scan(&ok); err != nil {
+	if err := s.pool.QueryRow(ctx, query, s.namespace).Scan(&ok); err != nil {
 		return false, err
 	}
 	// There were no rows when we looked, so report that. Don't update the bool,
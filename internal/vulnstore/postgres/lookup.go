@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	lookupVulnerabilitiesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "lookupvulnerabilities_total",
+			Help:      "Total number of database queries issued in the GetVulnerabilities method.",
+		},
+		[]string{"query"},
+	)
+	lookupVulnerabilitiesDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "lookupvulnerabilities_duration_seconds",
+			Help:      "The duration of all queries issued in the GetVulnerabilities method",
+		},
+		[]string{"query"},
+	)
+)
+
+// GetVulnerabilities implements vulnstore.VulnerabilityLookup.
+func (s *Store) GetVulnerabilities(ctx context.Context, qs []driver.VulnerabilityQuery) ([][]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/GetVulnerabilities"))
+	if len(qs) == 0 {
+		return nil, nil
+	}
+	phase, err := s.phase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := lookupQuery(phase)
+	batch := &pgx.Batch{}
+	for _, q := range qs {
+		batch.Queue(query, q.Name, q.Package, q.DistributionID, s.namespace)
+	}
+	start := time.Now()
+	res := tx.SendBatch(ctx, batch)
+	out := make([][]*claircore.Vulnerability, len(qs))
+	if err := func() error {
+		defer res.Close()
+		for i := range qs {
+			rows, err := res.Query()
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				v := &claircore.Vulnerability{
+					Package: &claircore.Package{},
+					Dist:    &claircore.Distribution{},
+					Repo:    &claircore.Repository{},
+				}
+				if err := scanVulnerability(v, rows); err != nil {
+					rows.Close()
+					return fmt.Errorf("failed to scan vulnerability: %w", err)
+				}
+				out[i] = append(out[i], v)
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+	lookupVulnerabilitiesCounter.WithLabelValues("query_batch").Add(1)
+	lookupVulnerabilitiesDuration.WithLabelValues("query_batch").Observe(time.Since(start).Seconds())
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("queries", len(qs)).
+		Msg("looked up vulnerabilities")
+	return out, nil
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestGetVulnerabilities checks looking up a vulnerability across the releases
+// of a distribution, and that the results carry the dates they were seen.
+func TestGetVulnerabilities(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := NewVulnStore(TestDB(ctx, t))
+
+	mk := func(name, pkg, did, version, fixed string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater:        "test-lookup",
+			Name:           name,
+			Package:        &claircore.Package{Name: pkg, Kind: claircore.SOURCE},
+			Dist:           &claircore.Distribution{DID: did, VersionID: version},
+			Repo:           &claircore.Repository{},
+			FixedInVersion: fixed,
+		}
+	}
+	vs := []*claircore.Vulnerability{
+		mk("CVE-2022-0778", "openssl", "ubuntu", "20.04", ""),
+		mk("CVE-2022-0778", "openssl", "ubuntu", "22.04", "3.0.2-0ubuntu1.1"),
+		mk("CVE-2022-0778", "openssl", "debian", "11", "1.1.1k-1+deb11u2"),
+		mk("CVE-2022-0778", "nodejs", "ubuntu", "22.04", ""),
+		mk("CVE-2022-1292", "openssl", "ubuntu", "22.04", ""),
+	}
+	update := func(t *testing.T, vs []*claircore.Vulnerability) time.Time {
+		ref, err := s.UpdateVulnerabilities(ctx, "test-lookup", driver.Fingerprint(uuid.New().String()), vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, "test-lookup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, op := range ops["test-lookup"] {
+			if op.Ref == ref {
+				return op.Date
+			}
+		}
+		t.Fatalf("no update operation %v", ref)
+		panic("unreachable")
+	}
+	first := update(t, vs)
+
+	qs := []driver.VulnerabilityQuery{
+		{Name: "CVE-2022-0778", Package: "openssl", DistributionID: "ubuntu"},
+		{Name: "CVE-2022-0778", Package: "openssl", DistributionID: "alpine"},
+	}
+	got, err := s.GetVulnerabilities(ctx, qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(qs) {
+		t.Fatalf("got %d results, want %d", len(got), len(qs))
+	}
+	fixed := make(map[string]string)
+	for _, v := range got[0] {
+		fixed[v.Dist.VersionID] = v.FixedInVersion
+	}
+	want := map[string]string{
+		"20.04": "",
+		"22.04": "3.0.2-0ubuntu1.1",
+	}
+	if len(fixed) != len(want) {
+		t.Errorf("got: %v, want: %v", fixed, want)
+	}
+	for k, v := range want {
+		if got, ok := fixed[k]; !ok || got != v {
+			t.Errorf("%s: got: %q, want: %q", k, got, v)
+		}
+	}
+	if len(got[1]) != 0 {
+		t.Errorf("unexpected results: %v", got[1])
+	}
+
+	// The second operation drops the 20.04 record, so it's last seen in the
+	// first one.
+	second := update(t, vs[1:])
+	got, err = s.GetVulnerabilities(ctx, qs[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string][2]time.Time{
+		"20.04": {first, first},
+		"22.04": {first, second},
+	}
+	if len(got[0]) != len(seen) {
+		t.Errorf("got %d results, want %d", len(got[0]), len(seen))
+	}
+	for _, v := range got[0] {
+		w := seen[v.Dist.VersionID]
+		if v.FirstSeen == nil || v.LastSeen == nil ||
+			!v.FirstSeen.Equal(w[0]) || !v.LastSeen.Equal(w[1]) {
+			t.Errorf("%s: got seen dates %v, %v; want %v", v.Dist.VersionID, v.FirstSeen, v.LastSeen, w)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestNamespace checks that Stores in different namespaces sharing a database
+// don't see each other's update operations, vulnerabilities, or enrichments.
+func TestNamespace(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	a := NewVulnStore(pool, WithNamespace("tenant-a"))
+	b := NewVulnStore(pool, WithNamespace("tenant-b"))
+	const updater = "test-namespace"
+
+	mk := func(name string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater: updater,
+			Name:    name,
+			Package: &claircore.Package{Name: "openssl", Kind: claircore.SOURCE},
+			Dist:    &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+			Repo:    &claircore.Repository{},
+		}
+	}
+	// Both namespaces get the same vulnerability, which must not conflict.
+	refA, err := a.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()),
+		[]*claircore.Vulnerability{mk("CVE-2022-0778")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refB, err := b.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()),
+		[]*claircore.Vulnerability{mk("CVE-2022-0778"), mk("CVE-2022-1292")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("UpdateOperations", func(t *testing.T) {
+		for _, tc := range []struct {
+			s    *Store
+			want uuid.UUID
+		}{
+			{a, refA},
+			{b, refB},
+		} {
+			ops, err := tc.s.GetUpdateOperations(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(ops[updater]); got != 1 {
+				t.Fatalf("%s: got %d operations, want 1", tc.s.namespace, got)
+			}
+			if got := ops[updater][0].Ref; got != tc.want {
+				t.Errorf("%s: got ref %v, want %v", tc.s.namespace, got, tc.want)
+			}
+			latest, err := tc.s.GetLatestUpdateRef(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest != tc.want {
+				t.Errorf("%s: got latest ref %v, want %v", tc.s.namespace, latest, tc.want)
+			}
+		}
+		if _, err := a.GetUpdateDiff(ctx, uuid.Nil, refB); err == nil {
+			t.Error("diffed an operation from another namespace")
+		}
+		if n, err := a.DeleteUpdateOperations(ctx, refB); err != nil || n != 0 {
+			t.Errorf("deleted %d operations from another namespace (%v)", n, err)
+		}
+	})
+
+	t.Run("Vulnerabilities", func(t *testing.T) {
+		rec := &claircore.IndexRecord{
+			Package: &claircore.Package{
+				ID:     "1",
+				Name:   "openssl",
+				Kind:   claircore.SOURCE,
+				Source: &claircore.Package{},
+			},
+			Distribution: &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+			Repository:   &claircore.Repository{},
+		}
+		opts := vulnstore.GetOpts{
+			Matchers: []driver.MatchConstraint{driver.DistributionDID},
+		}
+		for _, tc := range []struct {
+			s    *Store
+			want int
+		}{
+			{a, 1},
+			{b, 2},
+		} {
+			got, err := tc.s.Get(ctx, []*claircore.IndexRecord{rec}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(got[rec.Package.ID]); n != tc.want {
+				t.Errorf("%s: got %d vulnerabilities, want %d", tc.s.namespace, n, tc.want)
+			}
+			vs, err := tc.s.GetVulnerabilities(ctx, []driver.VulnerabilityQuery{
+				{Name: "CVE-2022-1292", Package: "openssl", DistributionID: "ubuntu"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(vs[0]); n != tc.want-1 {
+				t.Errorf("%s: looked up %d vulnerabilities, want %d", tc.s.namespace, n, tc.want-1)
+			}
+		}
+		c := NewVulnStore(pool, WithNamespace("tenant-c"))
+		if ok, err := c.Initialized(ctx); err != nil || ok {
+			t.Errorf("empty namespace reported initialized (%v)", err)
+		}
+	})
+
+	t.Run("Enrichments", func(t *testing.T) {
+		const name = "test-namespace-enrichment"
+		shared := driver.EnrichmentRecord{Tags: []string{"shared"}, Enrichment: []byte(`{"shared":true}`)}
+		onlyB := driver.EnrichmentRecord{Tags: []string{"only-b"}, Enrichment: []byte(`{"b":true}`)}
+		if _, err := a.UpdateEnrichments(ctx, name, "", []driver.EnrichmentRecord{shared}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.UpdateEnrichments(ctx, name, "", []driver.EnrichmentRecord{shared, onlyB}); err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range []struct {
+			s    *Store
+			want int
+		}{
+			{a, 1},
+			{b, 2},
+		} {
+			got, err := tc.s.GetEnrichment(ctx, name, []string{"shared", "only-b"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tc.want {
+				t.Errorf("%s: got %d enrichments, want %d", tc.s.namespace, len(got), tc.want)
+			}
+		}
+	})
+
+	t.Run("GC", func(t *testing.T) {
+		// Keeping nothing in one namespace leaves the other's data alone.
+		for {
+			n, err := a.GC(ctx, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 0 {
+				break
+			}
+		}
+		ops, err := b.GetUpdateOperations(ctx, "", updater)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(ops[updater]); got != 1 {
+			t.Errorf("got %d operations after GC, want 1", got)
+		}
+	})
+}
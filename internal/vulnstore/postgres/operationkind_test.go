@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestOperationKind checks that an updater can't record operations of both
+// kinds, in either order, but that the same name can be used for both kinds
+// in different namespaces.
+func TestOperationKind(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	fp := func() driver.Fingerprint { return driver.Fingerprint(uuid.New().String()) }
+	vs := []*claircore.Vulnerability{{
+		Name:    "CVE-0000-0000",
+		Package: &claircore.Package{Name: "pkg", Kind: claircore.SOURCE},
+		Dist:    &claircore.Distribution{},
+		Repo:    &claircore.Repository{},
+	}}
+	es := []driver.EnrichmentRecord{{Tags: []string{"tag"}, Enrichment: []byte(`{}`)}}
+
+	if _, err := s.UpdateVulnerabilities(ctx, "test-kind-vuln", fp(), vs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateVulnerabilities(ctx, "test-kind-vuln", fp(), vs); err != nil {
+		t.Errorf("second vulnerability operation: %v", err)
+	}
+	if _, err := s.UpdateEnrichments(ctx, "test-kind-vuln", fp(), es); err == nil {
+		t.Error("able to add an enrichment operation to a vulnerability updater")
+	} else {
+		t.Log(err)
+	}
+
+	if _, err := s.UpdateEnrichments(ctx, "test-kind-enrich", fp(), es); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateVulnerabilities(ctx, "test-kind-enrich", fp(), vs); err == nil {
+		t.Error("able to add a vulnerability operation to an enrichment updater")
+	}
+
+	other := NewVulnStore(pool, WithNamespace("test-kind"))
+	if _, err := other.UpdateEnrichments(ctx, "test-kind-vuln", fp(), es); err != nil {
+		t.Errorf("other namespace: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestGetUpdateRefs checks that Get only returns vulnerabilities from the
+// requested update operations, even once a newer one has been committed.
+func TestGetUpdateRefs(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := NewVulnStore(TestDB(ctx, t))
+	const updater = "test-pinned"
+
+	mk := func(name string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater: updater,
+			Name:    name,
+			Package: &claircore.Package{Name: "openssl", Kind: claircore.SOURCE},
+			Dist:    &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+			Repo:    &claircore.Repository{},
+		}
+	}
+	update := func(t *testing.T, vs ...*claircore.Vulnerability) uuid.UUID {
+		ref, err := s.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+	rec := &claircore.IndexRecord{
+		Package: &claircore.Package{
+			ID:     "1",
+			Name:   "openssl",
+			Kind:   claircore.SOURCE,
+			Source: &claircore.Package{},
+		},
+		Distribution: &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+		Repository:   &claircore.Repository{},
+	}
+	get := func(t *testing.T, refs ...uuid.UUID) map[string]bool {
+		t.Helper()
+		res, err := s.Get(ctx, []*claircore.IndexRecord{rec}, vulnstore.GetOpts{
+			Matchers:   []driver.MatchConstraint{driver.DistributionDID},
+			UpdateRefs: refs,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, v := range res[rec.Package.ID] {
+			got[v.Name] = true
+		}
+		return got
+	}
+
+	first := update(t, mk("CVE-2022-0778"))
+	// The second operation is committed after the first was read.
+	second := update(t, mk("CVE-2022-0778"), mk("CVE-2022-1292"))
+
+	if got := get(t, first); len(got) != 1 || !got["CVE-2022-0778"] {
+		t.Errorf("pinned to the first operation: got %v", got)
+	}
+	if got := get(t, second); len(got) != 2 {
+		t.Errorf("pinned to the second operation: got %v", got)
+	}
+	if got := get(t); len(got) != 2 {
+		t.Errorf("unpinned: got %v", got)
+	}
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/test/integration"
+)
+
+// PlanNode is the subset of a node in "EXPLAIN (FORMAT JSON)" output that the
+// plan checks look at.
+type planNode struct {
+	NodeType string     `json:"Node Type"`
+	Relation string     `json:"Relation Name"`
+	Index    string     `json:"Index Name"`
+	Plans    []planNode `json:"Plans"`
+}
+
+// Walk calls f for the node and all its children, depth first.
+func (n *planNode) walk(f func(*planNode)) {
+	f(n)
+	for i := range n.Plans {
+		n.Plans[i].walk(f)
+	}
+}
+
+func (n *planNode) String() string {
+	var b strings.Builder
+	var write func(*planNode, int)
+	write = func(n *planNode, depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(n.NodeType)
+		if n.Relation != "" {
+			b.WriteString(" on ")
+			b.WriteString(n.Relation)
+		}
+		if n.Index != "" {
+			b.WriteString(" using ")
+			b.WriteString(n.Index)
+		}
+		b.WriteByte('\n')
+		for i := range n.Plans {
+			write(&n.Plans[i], depth+1)
+		}
+	}
+	write(n, 0)
+	return b.String()
+}
+
+// PlanCheck is the set of structural properties a statement's plan must have.
+type planCheck struct {
+	// Args are the statement's parameters.
+	Args []interface{}
+	// UsesIndex is a list of indexes that must appear in the plan.
+	UsesIndex []string
+	// NoSeqScan maps a table name to a row count. The plan must not contain
+	// a sequential scan of the table if the table has more rows than that.
+	NoSeqScan map[string]int64
+}
+
+// PlanFixtures seeds the database with enough rows that the planner picks the
+// plans it would pick for a production-sized database.
+//
+// There are 100 updaters with 100 update operations each. "updater-0" is an
+// enrichment updater whose two most recent operations are both associated
+// with all 50,000 enrichment records, the way a cvss-like updater looks before
+// GC runs. There are 100,000 vulnerabilities spread over 10,000 package names.
+const planFixtures = `
+INSERT INTO update_operation (updater, fingerprint, kind, date)
+SELECT
+	'updater-' || (n % 100),
+	n::text,
+	CASE WHEN n % 100 = 0 THEN 'enrichment' ELSE 'vulnerability' END,
+	now() - (10000 - n) * interval '1 minute'
+FROM
+	generate_series(1, 10000) AS n;
+
+INSERT INTO enrichment (hash_kind, hash, updater, tags, data)
+SELECT
+	'md5',
+	decode(md5(n::text), 'hex'),
+	'updater-0',
+	ARRAY['tag-' || n, 'group-' || (n % 5000)],
+	jsonb_build_object('n', n)
+FROM
+	generate_series(1, 50000) AS n;
+
+INSERT INTO uo_enrich (uo, enrich, updater, fingerprint, date)
+SELECT
+	uo.id, e.id, 'updater-0', uo.fingerprint, uo.date
+FROM
+	enrichment AS e,
+	(
+		SELECT
+			id, fingerprint, date
+		FROM
+			update_operation
+		WHERE
+			updater = 'updater-0'
+		ORDER BY
+			id DESC
+		LIMIT 2
+	)
+		AS uo;
+
+INSERT INTO vuln
+	(
+		hash_kind,
+		hash,
+		updater,
+		name,
+		package_name,
+		package_kind,
+		dist_id,
+		dist_version_id,
+		version_kind,
+		vulnerable_range
+	)
+SELECT
+	'md5',
+	decode(md5(n::text), 'hex'),
+	'updater-' || (n % 99 + 1),
+	'CVE-' || n,
+	CASE WHEN n % 10000 = 0 THEN 'openssl' ELSE 'pkg-' || (n % 10000) END,
+	'source',
+	CASE WHEN n % 2 = 0 THEN 'rhel' ELSE 'debian' END,
+	(n % 3 + 7)::text,
+	'rpm',
+	VersionRange(
+		'{0,0,0,0,0,0,0,0,0,0}'::int[],
+		('{0,' || (n % 5 + 1) || ',0,0,0,0,0,0,0,0}')::int[],
+		'[)'
+	)
+FROM
+	generate_series(1, 100000) AS n;
+
+ANALYZE;
+`
+
+func seedPlanFixtures(ctx context.Context, t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	if _, err := pool.Exec(ctx, planFixtures); err != nil {
+		t.Fatalf("failed to seed fixtures: %v", err)
+	}
+}
+
+// TestQueryPlans checks the plans for the Store's hot statements against a
+// seeded database, so that schema or query changes that regress them are
+// caught before they show up as slow reports.
+func TestQueryPlans(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	seedPlanFixtures(ctx, t, pool)
+
+	checks := map[string]planCheck{
+		StatementGetEnrichment: {
+			Args:      []interface{}{"updater-0", DefaultNamespace, []string{"tag-1", "tag-2"}},
+			UsesIndex: []string{"enrichment_tags_idx"},
+			NoSeqScan: map[string]int64{
+				"enrichment":       1000,
+				"uo_enrich":        1000,
+				"update_operation": 1000,
+			},
+		},
+		StatementLatestOperation: {
+			Args: []interface{}{"updater-1", DefaultNamespace},
+			NoSeqScan: map[string]int64{
+				"update_operation": 1000,
+			},
+		},
+		StatementGet: {
+			UsesIndex: []string{"vuln_lookup_idx"},
+			NoSeqScan: map[string]int64{
+				"vuln": 1000,
+			},
+		},
+		StatementLookup: {
+			Args:      []interface{}{"CVE-10000", "openssl", "rhel", DefaultNamespace},
+			UsesIndex: []string{"vuln_lookup_idx"},
+			NoSeqScan: map[string]int64{
+				"vuln": 1000,
+			},
+		},
+	}
+
+	stmts, err := Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]struct{}, len(stmts))
+	for _, s := range stmts {
+		s := s
+		seen[s.Name] = struct{}{}
+		c, ok := checks[s.Name]
+		if !ok {
+			t.Errorf("no plan check for statement %q", s.Name)
+			continue
+		}
+		t.Run(s.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			plan := explain(ctx, t, pool, s.SQL, c.Args)
+			t.Logf("plan:\n%v", plan)
+
+			used := make(map[string]bool)
+			seq := make(map[string]bool)
+			plan.walk(func(n *planNode) {
+				if n.Index != "" {
+					used[n.Index] = true
+				}
+				if n.NodeType == "Seq Scan" {
+					seq[n.Relation] = true
+				}
+			})
+			for _, idx := range c.UsesIndex {
+				if !used[idx] {
+					t.Errorf("plan does not use index %q", idx)
+				}
+			}
+			for table, max := range c.NoSeqScan {
+				if !seq[table] {
+					continue
+				}
+				var ct int64
+				// Table names come from the checks above, not user input.
+				if err := pool.QueryRow(ctx, `SELECT count(*) FROM `+table+`;`).Scan(&ct); err != nil {
+					t.Fatal(err)
+				}
+				if ct > max {
+					t.Errorf("plan has a sequential scan on %q (%d rows, limit %d)", table, ct, max)
+				}
+			}
+		})
+	}
+	for name := range checks {
+		if _, ok := seen[name]; !ok {
+			t.Errorf("plan check for unknown statement %q", name)
+		}
+	}
+}
+
+// Explain returns the root of the plan for the statement.
+func explain(ctx context.Context, t *testing.T, pool *pgxpool.Pool, sql string, args []interface{}) *planNode {
+	t.Helper()
+	var b []byte
+	if err := pool.QueryRow(ctx, `EXPLAIN (FORMAT JSON) `+sql, args...).Scan(&b); err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	var out []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("got %d plans, want 1", len(out))
+	}
+	return &out[0].Plan
+}
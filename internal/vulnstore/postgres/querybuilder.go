@@ -14,7 +14,10 @@ import (
 )
 
 // getQueryBuilder validates a IndexRecord and creates a query string for vulnerability matching
-func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (string, error) {
+//
+// The description and links are selected the way the description phase
+// "phase" says to, and only vulnerabilities in the namespace "ns" are matched.
+func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts, phase DescriptionPhase, ns string) (string, error) {
 	matchers := opts.Matchers
 	psql := goqu.Dialect("postgres")
 	exps := []goqu.Expression{}
@@ -48,7 +51,7 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 		if _, ok := seen[m]; ok {
 			continue
 		}
-		var ex goqu.Ex
+		var ex goqu.Expression
 		switch m {
 		case driver.PackageModule:
 			ex = goqu.Ex{"package_module": record.Package.Module}
@@ -57,9 +60,9 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 		case driver.DistributionName:
 			ex = goqu.Ex{"dist_name": record.Distribution.Name}
 		case driver.DistributionVersionID:
-			ex = goqu.Ex{"dist_version_id": record.Distribution.VersionID}
+			ex = distVersion("dist_version_id", record.Distribution.VersionID, opts.DistributionVersionOp)
 		case driver.DistributionVersion:
-			ex = goqu.Ex{"dist_version": record.Distribution.Version}
+			ex = distVersion("dist_version", record.Distribution.Version, opts.DistributionVersionOp)
 		case driver.DistributionVersionCodeName:
 			ex = goqu.Ex{"dist_version_code_name": record.Distribution.VersionCodeName}
 		case driver.DistributionPrettyName:
@@ -70,6 +73,8 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 			ex = goqu.Ex{"dist_arch": record.Distribution.Arch}
 		case driver.RepositoryName:
 			ex = goqu.Ex{"repo_name": record.Repository.Name}
+		case driver.RepositoryCPEProduct:
+			ex = cpeProduct("repo_name", record.Repository.Name)
 		default:
 			return "", fmt.Errorf("was provided unknown matcher: %v", m)
 		}
@@ -94,12 +99,32 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 		))
 	}
 
+	if len(opts.UpdateRefs) != 0 {
+		refs := make([]interface{}, len(opts.UpdateRefs))
+		for i, ref := range opts.UpdateRefs {
+			refs[i] = ref.String()
+		}
+		ops := psql.From("update_operation").
+			Select("id").
+			Where(goqu.C("ref").In(refs...), goqu.Ex{"namespace": ns})
+		exps = append(exps, goqu.C("id").In(
+			psql.From("uo_vuln").Select("vuln").Where(goqu.C("uo").In(ops)),
+		))
+	}
+
+	exps = append(exps, goqu.Ex{"namespace": ns})
+
+	var desc, links interface{} = "description", "links"
+	if phase != DescriptionOld && phase != DescriptionDualWrite {
+		desc = goqu.L(phase.descriptionExpr()).As("description")
+		links = goqu.L(phase.linksExpr()).As("links")
+	}
 	query := psql.Select(
 		"id",
 		"name",
-		"description",
+		desc,
 		"issued",
-		"links",
+		links,
 		"severity",
 		"normalized_severity",
 		"package_name",
@@ -121,6 +146,8 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 		"repo_uri",
 		"fixed_in_version",
 		"updater",
+		"first_seen",
+		"last_seen",
 	).From("vuln").Where(exps...)
 
 	sql, _, err := query.ToSQL()
@@ -129,3 +156,52 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 	}
 	return sql, nil
 }
+
+// DistVersion returns the expression comparing the distribution version
+// column "col" to "v" according to "op".
+//
+// This mirrors driver.DistributionVersionOp.Match.
+func distVersion(col, v string, op driver.DistributionVersionOp) goqu.Expression {
+	eq := goqu.Ex{col: v}
+	switch op {
+	case driver.DistributionVersionSeries:
+		c := goqu.I(col)
+		return goqu.Or(
+			eq,
+			goqu.L("strpos(?, ? || '.') = 1", v, c),
+			c.Like(likeEscape.Replace(v)+".%"),
+		)
+	default:
+		return eq
+	}
+}
+
+// cpeProduct returns the expression matching the CPE column "col" to CPEs
+// with the same part, vendor, and product as the CPE "name", in either the
+// URI or formatted string binding. If "name" isn't a CPE with all three, the
+// column is compared to it.
+func cpeProduct(col, name string) goqu.Expression {
+	n := 4 // "cpe", "/part", "vendor", "product"
+	if strings.HasPrefix(name, "cpe:2.3:") {
+		n = 5
+	}
+	fs := strings.SplitN(name, ":", n+1)
+	if len(fs) < n || !strings.HasPrefix(name, "cpe:") {
+		return goqu.Ex{col: name}
+	}
+	for _, f := range fs[n-3 : n] {
+		switch strings.TrimPrefix(f, "/") {
+		case "", "*", "-":
+			return goqu.Ex{col: name}
+		}
+	}
+	p := strings.Join(fs[:n], ":")
+	return goqu.Or(
+		goqu.Ex{col: p},
+		goqu.I(col).Like(likeEscape.Replace(p)+":%"),
+	)
+}
+
+// LikeEscape escapes the LIKE metacharacters, using the default escape
+// character.
+var likeEscape = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/vulnstore"
@@ -20,11 +21,13 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		"id", "name", "description", "issued", "links", "severity", "normalized_severity", "package_name", "package_version",
 		"package_module", "package_arch", "package_kind", "dist_id", "dist_name", "dist_version", "dist_version_code_name",
 		"dist_version_id", "dist_arch", "dist_cpe", "dist_pretty_name", "arch_operation", "repo_name", "repo_key",
-		"repo_uri", "fixed_in_version", "updater"
+		"repo_uri", "fixed_in_version", "updater", "first_seen", "last_seen"
 		FROM "vuln"
 		WHERE `
 		both     = `(((("package_name" = 'package-0') AND ("package_kind" = 'binary')) OR (("package_name" = 'source-package-0') AND ("package_kind" = 'source'))) AND `
 		noSource = `((("package_name" = 'package-0') AND  ("package_kind" = 'binary')) AND `
+		// InNamespace ends every query, restricting it to the Store's namespace.
+		inNamespace = `("namespace" = ''))`
 	)
 	var table = []struct {
 		// name of test
@@ -34,13 +37,19 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		// the match expressions which contrain the query
 		matchExps []driver.MatchConstraint
 		dbFilter  bool
+		// how distribution versions are compared
+		versionOp driver.DistributionVersionOp
+		// the namespace to query
+		namespace string
+		// the update operations to limit the query to
+		refs []uuid.UUID
 		// a method to returning the indexRecord for the getQueryBuilder method
 		indexRecord func() *claircore.IndexRecord
 	}{
 		{
 			name: "NoSource,id",
 			expectedQuery: preamble + noSource +
-				`("dist_id" = 'did-0'))`,
+				`("dist_id" = 'did-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{driver.DistributionDID},
 			indexRecord: func() *claircore.IndexRecord {
 				pkgs := test.GenUniquePackages(1)
@@ -52,10 +61,41 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "id,namespace",
+			expectedQuery: preamble + both +
+				`("dist_id" = 'did-0') AND ("namespace" = 'tenant-a'))`,
+			matchExps: []driver.MatchConstraint{driver.DistributionDID},
+			namespace: "tenant-a",
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				dists := test.GenUniqueDistributions(1)
+				return &claircore.IndexRecord{
+					Package:      pkgs[0],
+					Distribution: dists[0],
+				}
+			},
+		},
+		{
+			name: "id,refs",
+			expectedQuery: preamble + both +
+				`("dist_id" = 'did-0') AND ("id" IN ((SELECT "vuln" FROM "uo_vuln" WHERE ("uo" IN ((SELECT "id" FROM "update_operation"
+				WHERE (("ref" IN ('00000000-0000-0000-0000-000000000001')) AND ("namespace" = '')))))))) AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{driver.DistributionDID},
+			refs:      []uuid.UUID{uuid.MustParse("00000000-0000-0000-0000-000000000001")},
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				dists := test.GenUniqueDistributions(1)
+				return &claircore.IndexRecord{
+					Package:      pkgs[0],
+					Distribution: dists[0],
+				}
+			},
+		},
 		{
 			name: "id",
 			expectedQuery: preamble + both +
-				`("dist_id" = 'did-0'))`,
+				`("dist_id" = 'did-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{driver.DistributionDID},
 			indexRecord: func() *claircore.IndexRecord {
 				pkgs := test.GenUniquePackages(1)
@@ -70,7 +110,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 			name: "id,version",
 			expectedQuery: preamble + both +
 				`("dist_id" = 'did-0') AND
-				("dist_version" = 'version-0'))`,
+				("dist_version" = 'version-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{
 				driver.DistributionDID,
 				driver.DistributionVersion,
@@ -89,7 +129,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 			expectedQuery: preamble + both +
 				`("dist_id" = 'did-0') AND
 				("dist_version" = 'version-0') AND
-				("dist_version_id" = 'version-id-0'))`,
+				("dist_version_id" = 'version-id-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{
 				driver.DistributionDID,
 				driver.DistributionVersion,
@@ -110,7 +150,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 				`("dist_id" = 'did-0') AND
 				("dist_version" = 'version-0') AND
 				("dist_version_id" = 'version-id-0') AND
-				("dist_version_code_name" = 'version-code-name-0'))`,
+				("dist_version_code_name" = 'version-code-name-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{
 				driver.DistributionDID,
 				driver.DistributionVersion,
@@ -126,11 +166,48 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "id,version_id,series",
+			expectedQuery: preamble + both +
+				`("dist_id" = 'did-0') AND
+				(("dist_version_id" = 'version-id-0') OR
+				strpos('version-id-0', "dist_version_id" || '.') = 1 OR
+				("dist_version_id" LIKE 'version-id-0.%')) AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{
+				driver.DistributionDID,
+				driver.DistributionVersionID,
+			},
+			versionOp: driver.DistributionVersionSeries,
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				dists := test.GenUniqueDistributions(1)
+				return &claircore.IndexRecord{
+					Package:      pkgs[0],
+					Distribution: dists[0],
+				}
+			},
+		},
+		{
+			name: "version_id,series,escaped",
+			expectedQuery: preamble + both +
+				`(("dist_version_id" = '8_6') OR
+				strpos('8_6', "dist_version_id" || '.') = 1 OR
+				("dist_version_id" LIKE '8\_6.%')) AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{driver.DistributionVersionID},
+			versionOp: driver.DistributionVersionSeries,
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				return &claircore.IndexRecord{
+					Package:      pkgs[0],
+					Distribution: &claircore.Distribution{VersionID: "8_6"},
+				}
+			},
+		},
 		{
 			name: "DatabaseFilter",
 			expectedQuery: preamble + both +
 				`(("version_kind" = '') AND
-				vulnerable_range @> '{0,0,0,0,0,0,0,0,0,0}'::int[]))`,
+				vulnerable_range @> '{0,0,0,0,0,0,0,0,0,0}'::int[]) AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{},
 			dbFilter:  true,
 			indexRecord: func() *claircore.IndexRecord {
@@ -146,7 +223,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 			name: "DatabaseFilterPython",
 			expectedQuery: preamble + both +
 				`(("version_kind" = 'pep440') AND
-				vulnerable_range @> '{0,1,20,3,0,0,0,0,0,0}'::int[]))`,
+				vulnerable_range @> '{0,1,20,3,0,0,0,0,0,0}'::int[]) AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{},
 			dbFilter:  true,
 			indexRecord: func() *claircore.IndexRecord {
@@ -166,7 +243,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		{
 			name: "module-filter",
 			expectedQuery: preamble + noSource +
-				`("package_module" = 'module:0'))`,
+				`("package_module" = 'module:0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{driver.PackageModule},
 			indexRecord: func() *claircore.IndexRecord {
 				pkgs := test.GenUniquePackages(1)
@@ -181,7 +258,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		{
 			name: "repo_name",
 			expectedQuery: preamble + noSource +
-				`("repo_name" = 'repository-0'))`,
+				`("repo_name" = 'repository-0') AND ` + inNamespace,
 			matchExps: []driver.MatchConstraint{driver.RepositoryName},
 			indexRecord: func() *claircore.IndexRecord {
 				pkgs := test.GenUniquePackages(1)
@@ -195,6 +272,51 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "repo_cpe_product",
+			expectedQuery: preamble + noSource +
+				`(("repo_name" = 'cpe:/o:redhat:enterprise_linux') OR
+				("repo_name" LIKE 'cpe:/o:redhat:enterprise\_linux:%')) AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{driver.RepositoryCPEProduct},
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				pkgs[0].Source = &claircore.Package{} // clear source field
+				return &claircore.IndexRecord{
+					Package:    pkgs[0],
+					Repository: &claircore.Repository{Name: "cpe:/o:redhat:enterprise_linux:8::baseos"},
+				}
+			},
+		},
+		{
+			name: "repo_cpe_product_fs",
+			expectedQuery: preamble + noSource +
+				`(("repo_name" = 'cpe:2.3:o:redhat:enterprise_linux') OR
+				("repo_name" LIKE 'cpe:2.3:o:redhat:enterprise\_linux:%')) AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{driver.RepositoryCPEProduct},
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				pkgs[0].Source = &claircore.Package{} // clear source field
+				return &claircore.IndexRecord{
+					Package:    pkgs[0],
+					Repository: &claircore.Repository{Name: "cpe:2.3:o:redhat:enterprise_linux:8:*:baseos:*:*:*:*:*"},
+				}
+			},
+		},
+		{
+			name: "repo_cpe_product_not_cpe",
+			expectedQuery: preamble + noSource +
+				`("repo_name" = 'repository-0') AND ` + inNamespace,
+			matchExps: []driver.MatchConstraint{driver.RepositoryCPEProduct},
+			indexRecord: func() *claircore.IndexRecord {
+				pkgs := test.GenUniquePackages(1)
+				pkgs[0].Source = &claircore.Package{} // clear source field
+				repos := test.GenUniqueRepositories(1)
+				return &claircore.IndexRecord{
+					Package:    pkgs[0],
+					Repository: repos[0],
+				}
+			},
+		},
 	}
 
 	// This is safe to do because SQL doesn't care about what whitespace is
@@ -207,10 +329,12 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ir := tt.indexRecord()
 			opts := vulnstore.GetOpts{
-				Matchers:         tt.matchExps,
-				VersionFiltering: tt.dbFilter,
+				Matchers:              tt.matchExps,
+				VersionFiltering:      tt.dbFilter,
+				DistributionVersionOp: tt.versionOp,
+				UpdateRefs:            tt.refs,
 			}
-			query, err := buildGetQuery(ir, &opts)
+			query, err := buildGetQuery(ir, &opts, DescriptionOld, tt.namespace)
 			if err != nil {
 				t.Fatalf("failed to create query: %v", err)
 			}
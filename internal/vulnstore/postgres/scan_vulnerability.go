@@ -39,6 +39,8 @@ func scanVulnerability(v *claircore.Vulnerability, row scanner) error {
 		&v.Repo.Key,
 		&v.Repo.URI,
 		&v.FixedInVersion,
+		&v.FirstSeen,
+		&v.LastSeen,
 	); err != nil {
 		return err
 	}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestSeen checks that the first and last seen dates of vulnerabilities are
+// kept across update operations, including once the first operation is
+// garbage collected.
+func TestSeen(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	s := NewVulnStore(TestDB(ctx, t))
+	const updater = "test-seen"
+
+	mk := func(name, fixed string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater:        updater,
+			Name:           name,
+			Package:        &claircore.Package{Name: "openssl", Kind: claircore.SOURCE},
+			Dist:           &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+			Repo:           &claircore.Repository{},
+			FixedInVersion: fixed,
+		}
+	}
+	update := func(t *testing.T, vs ...*claircore.Vulnerability) time.Time {
+		ref, err := s.UpdateVulnerabilities(ctx, updater, driver.Fingerprint(uuid.New().String()), vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, updater)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, op := range ops[updater] {
+			if op.Ref == ref {
+				return op.Date
+			}
+		}
+		t.Fatalf("no update operation %v", ref)
+		panic("unreachable")
+	}
+	// Lookup returns the seen dates of the revisions of the named
+	// vulnerability, keyed by fixed version.
+	lookup := func(t *testing.T, name string) map[string][2]time.Time {
+		got, err := s.GetVulnerabilities(ctx, []driver.VulnerabilityQuery{
+			{Name: name, Package: "openssl", DistributionID: "ubuntu"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := make(map[string][2]time.Time)
+		for _, v := range got[0] {
+			if v.FirstSeen == nil || v.LastSeen == nil {
+				t.Fatalf("%s: missing seen dates: %v, %v", v.Name, v.FirstSeen, v.LastSeen)
+			}
+			ret[v.FixedInVersion] = [2]time.Time{*v.FirstSeen, *v.LastSeen}
+		}
+		return ret
+	}
+	check := func(t *testing.T, name string, want map[string][2]time.Time) {
+		t.Helper()
+		got := lookup(t, name)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %d revisions, want %d", name, len(got), len(want))
+		}
+		for fixed, w := range want {
+			g, ok := got[fixed]
+			switch {
+			case !ok:
+				t.Errorf("%s@%q: missing", name, fixed)
+			case !g[0].Equal(w[0]) || !g[1].Equal(w[1]):
+				t.Errorf("%s@%q: got: %v, want: %v", name, fixed, g, w)
+			}
+		}
+	}
+
+	first := update(t, mk("CVE-2022-0778", "3.0.2-0ubuntu1.1"), mk("CVE-2022-1292", ""))
+	check(t, "CVE-2022-0778", map[string][2]time.Time{"3.0.2-0ubuntu1.1": {first, first}})
+	check(t, "CVE-2022-1292", map[string][2]time.Time{"": {first, first}})
+
+	// The same record is seen again; the changed one is a new revision.
+	second := update(t, mk("CVE-2022-0778", "3.0.2-0ubuntu1.1"), mk("CVE-2022-1292", "3.0.2-0ubuntu1.6"))
+	check(t, "CVE-2022-0778", map[string][2]time.Time{"3.0.2-0ubuntu1.1": {first, second}})
+	check(t, "CVE-2022-1292", map[string][2]time.Time{
+		"":                 {first, first},
+		"3.0.2-0ubuntu1.6": {second, second},
+	})
+
+	third := update(t, mk("CVE-2022-0778", "3.0.2-0ubuntu1.1"), mk("CVE-2022-1292", "3.0.2-0ubuntu1.6"))
+	for {
+		n, err := s.GC(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	// The first operation is gone, but not the date it first saw the
+	// vulnerability.
+	check(t, "CVE-2022-0778", map[string][2]time.Time{"3.0.2-0ubuntu1.1": {first, third}})
+	check(t, "CVE-2022-1292", map[string][2]time.Time{"3.0.2-0ubuntu1.6": {second, third}})
+
+	// Matched vulnerabilities carry the dates, too.
+	rec := &claircore.IndexRecord{
+		Package: &claircore.Package{
+			ID:     "1",
+			Name:   "openssl",
+			Kind:   claircore.SOURCE,
+			Source: &claircore.Package{},
+		},
+		Distribution: &claircore.Distribution{DID: "ubuntu", VersionID: "22.04"},
+		Repository:   &claircore.Repository{},
+	}
+	got, err := s.Get(ctx, []*claircore.IndexRecord{rec}, vulnstore.GetOpts{
+		Matchers: []driver.MatchConstraint{driver.DistributionDID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(got[rec.Package.ID]); n != 2 {
+		t.Fatalf("got %d vulnerabilities, want 2", n)
+	}
+	for _, v := range got[rec.Package.ID] {
+		if v.FirstSeen == nil || v.LastSeen == nil || !v.LastSeen.Equal(third) {
+			t.Errorf("%s: got seen dates %v, %v", v.Name, v.FirstSeen, v.LastSeen)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+var (
+	backfillSeverityCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "backfillseverity_total",
+			Help:      "Total number of database queries issued in the BackfillSeverity method.",
+		},
+		[]string{"query"},
+	)
+	backfillSeverityDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "backfillseverity_duration_seconds",
+			Help:      "The duration of all queries issued in the BackfillSeverity method",
+		},
+		[]string{"query"},
+	)
+)
+
+// BackfillSeverity implements vulnstore.SeverityBackfiller.
+//
+// Each batch is updated in its own transaction, so an interrupted backfill
+// keeps the work done so far and can be run again. The normalized severity
+// isn't part of a vulnerability's hash, so rows are updated in place.
+func (s *Store) BackfillSeverity(ctx context.Context, batch int) (int64, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/BackfillSeverity"))
+	if batch < 1 {
+		return 0, fmt.Errorf("invalid batch size: %d", batch)
+	}
+
+	var total, last int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, changed, next, err := s.backfillSeverityBatch(ctx, last, batch)
+		total += changed
+		if err != nil {
+			return total, err
+		}
+		zlog.Debug(ctx).
+			Int64("through", next).
+			Int64("changed", changed).
+			Msg("backfilled batch")
+		if n < batch {
+			return total, nil
+		}
+		last = next
+	}
+}
+
+// BackfillSeverityBatch updates the normalized severity of up to "batch"
+// vulnerabilities with ids after "after", in one transaction. It reports the
+// number of rows examined, the number changed, and the last id examined.
+func (s *Store) backfillSeverityBatch(ctx context.Context, after int64, batch int) (int, int64, int64, error) {
+	const (
+		selectBatch = `
+SELECT id, severity, normalized_severity FROM vuln
+WHERE namespace = $1 AND id > $2
+ORDER BY id ASC LIMIT $3;`
+		update = `
+UPDATE vuln SET normalized_severity = u.sev
+FROM unnest($1::bigint[], $2::text[]) AS u (id, sev)
+WHERE vuln.id = u.id;`
+	)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, after, fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	rows, err := tx.Query(ctx, selectBatch, s.namespace, after, batch)
+	if err != nil {
+		return 0, 0, after, fmt.Errorf("failed to select vulnerabilities: %w", err)
+	}
+	var n int
+	var ids []int64
+	var sevs []string
+	last := after
+	for rows.Next() {
+		var id int64
+		var sev, cur string
+		if err := rows.Scan(&id, &sev, &cur); err != nil {
+			rows.Close()
+			return 0, 0, after, fmt.Errorf("failed to scan vulnerability: %w", err)
+		}
+		n++
+		last = id
+		ns, ok := claircore.NormalizeSeverity(sev)
+		if !ok || ns.String() == cur {
+			continue
+		}
+		ids = append(ids, id)
+		sevs = append(sevs, ns.String())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, after, fmt.Errorf("failed to select vulnerabilities: %w", err)
+	}
+	backfillSeverityCounter.WithLabelValues("select").Add(1)
+	backfillSeverityDuration.WithLabelValues("select").Observe(time.Since(start).Seconds())
+	if len(ids) == 0 {
+		return n, 0, last, nil
+	}
+
+	start = time.Now()
+	tag, err := tx.Exec(ctx, update, ids, sevs)
+	if err != nil {
+		return 0, 0, after, fmt.Errorf("failed to update severities: %w", err)
+	}
+	backfillSeverityCounter.WithLabelValues("update").Add(1)
+	backfillSeverityDuration.WithLabelValues("update").Observe(time.Since(start).Seconds())
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, after, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return n, tag.RowsAffected(), last, nil
+}
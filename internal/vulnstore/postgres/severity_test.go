@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestBackfillSeverity checks that stale normalized severities are rewritten
+// across several batches, unknown severity strings are left alone, and a
+// second run changes nothing.
+func TestBackfillSeverity(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+
+	mk := func(name, sev string, ns claircore.Severity) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Updater:            "test",
+			Name:               name,
+			Severity:           sev,
+			NormalizedSeverity: ns,
+			Package:            &claircore.Package{Name: "openssl", Kind: claircore.SOURCE},
+			Dist:               &claircore.Distribution{},
+			Repo:               &claircore.Repository{},
+		}
+	}
+	vs := []*claircore.Vulnerability{
+		mk("CVE-2022-0001", "Important", claircore.Medium),
+		mk("CVE-2022-0002", "moderate", claircore.Medium),
+		mk("CVE-2022-0003", "unimportant", claircore.Unknown),
+		mk("CVE-2022-0004", "7.5", claircore.High),
+		mk("CVE-2022-0005", "CRITICAL", claircore.High),
+	}
+	if _, err := s.UpdateVulnerabilities(ctx, "test", driver.Fingerprint(uuid.New().String()), vs); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.BackfillSeverity(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(3); got != want {
+		t.Errorf("got %d changed, want %d", got, want)
+	}
+	n, err = s.BackfillSeverity(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(0); got != want {
+		t.Errorf("second run: got %d changed, want %d", got, want)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT name, normalized_severity FROM vuln WHERE updater = 'test';`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	got := make(map[string]string)
+	for rows.Next() {
+		var name, sev string
+		if err := rows.Scan(&name, &sev); err != nil {
+			t.Fatal(err)
+		}
+		got[name] = sev
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"CVE-2022-0001": "High",
+		"CVE-2022-0002": "Medium",
+		"CVE-2022-0003": "Negligible",
+		"CVE-2022-0004": "High",
+		"CVE-2022-0005": "Critical",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
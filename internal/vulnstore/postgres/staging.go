@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ vulnstore.Staging = (*Store)(nil)
+
+// StagedUpdate implements vulnstore.Staging.
+func (s *Store) StagedUpdate(ctx context.Context, updater string) (*vulnstore.StagedUpdate, error) {
+	const query = `
+SELECT prev, cursor, pages, count
+FROM update_stage
+WHERE namespace = $1 AND updater = $2;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/StagedUpdate"))
+	var u vulnstore.StagedUpdate
+	var prev string
+	err := s.pool.QueryRow(ctx, query, s.namespace, updater).
+		Scan(&prev, &u.Cursor, &u.Pages, &u.Count)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get staged update: %w", err)
+	}
+	u.Prev = driver.Fingerprint(prev)
+	return &u, nil
+}
+
+// StagePage implements vulnstore.Staging.
+func (s *Store) StagePage(ctx context.Context, updater string, prev driver.Fingerprint, next string, vulns []*claircore.Vulnerability) error {
+	const (
+		upsert = `
+INSERT INTO update_stage (namespace, updater, prev, cursor, pages, count)
+VALUES ($1, $2, $3, $4, 1, $5)
+ON CONFLICT (namespace, updater) DO UPDATE SET
+	cursor = EXCLUDED.cursor,
+	pages = update_stage.pages + 1,
+	count = update_stage.count + EXCLUDED.count,
+	updated = transaction_timestamp()
+RETURNING id, pages;`
+		insert = `INSERT INTO update_stage_vuln (stage, page, vuln) VALUES ($1, $2, $3);`
+	)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/StagePage"))
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	var id int64
+	var page int
+	if err := tx.QueryRow(ctx, upsert, s.namespace, updater, string(prev), next, len(vulns)).Scan(&id, &page); err != nil {
+		return fmt.Errorf("failed to stage page: %w", err)
+	}
+	var b pgx.Batch
+	for _, v := range vulns {
+		js, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode vulnerability %q: %w", v.Name, err)
+		}
+		b.Queue(insert, id, page, js)
+	}
+	res := tx.SendBatch(ctx, &b)
+	for range vulns {
+		if _, err := res.Exec(); err != nil {
+			res.Close()
+			return fmt.Errorf("failed to stage vulnerability: %w", err)
+		}
+	}
+	if err := res.Close(); err != nil {
+		return fmt.Errorf("failed to stage page: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit staged page: %w", err)
+	}
+	return nil
+}
+
+// StagedVulnerabilities implements vulnstore.Staging.
+func (s *Store) StagedVulnerabilities(ctx context.Context, updater string) ([]*claircore.Vulnerability, error) {
+	const query = `
+SELECT v.vuln
+FROM update_stage_vuln v
+JOIN update_stage s ON s.id = v.stage
+WHERE s.namespace = $1 AND s.updater = $2
+ORDER BY v.id;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/StagedVulnerabilities"))
+	rows, err := s.pool.Query(ctx, query, s.namespace, updater)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged update: %w", err)
+	}
+	defer rows.Close()
+	var out []*claircore.Vulnerability
+	for rows.Next() {
+		var js []byte
+		if err := rows.Scan(&js); err != nil {
+			return nil, fmt.Errorf("failed to scan staged vulnerability: %w", err)
+		}
+		var v claircore.Vulnerability
+		if err := json.Unmarshal(js, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode staged vulnerability: %w", err)
+		}
+		out = append(out, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read staged update: %w", err)
+	}
+	return out, nil
+}
+
+// DiscardStaged implements vulnstore.Staging.
+func (s *Store) DiscardStaged(ctx context.Context, updater string) error {
+	const query = `DELETE FROM update_stage WHERE namespace = $1 AND updater = $2;`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/DiscardStaged"))
+	if _, err := s.pool.Exec(ctx, query, s.namespace, updater); err != nil {
+		return fmt.Errorf("failed to discard staged update: %w", err)
+	}
+	return nil
+}
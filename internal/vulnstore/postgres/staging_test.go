@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestStaging(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	s := NewVulnStore(pool)
+	other := NewVulnStore(pool, WithNamespace("other"))
+	const updater = "paginated-updater"
+	vulns := test.GenUniqueVulnerabilities(4, updater)
+
+	got, err := s.StagedUpdate(ctx, updater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Fatalf("unexpected staged update: %+v", got)
+	}
+
+	if err := s.StagePage(ctx, updater, "prev", "page-2", vulns[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.StagePage(ctx, updater, "ignored", "page-3", vulns[2:4]); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.StagedUpdate(ctx, updater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &vulnstore.StagedUpdate{Prev: "prev", Cursor: "page-3", Pages: 2, Count: 4}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	// Staged updates are namespaced.
+	if got, err := other.StagedUpdate(ctx, updater); err != nil || got != nil {
+		t.Errorf("other namespace: got %+v, %v", got, err)
+	}
+
+	staged, err := s.StagedVulnerabilities(ctx, updater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotNames, wantNames []string
+	for _, v := range staged {
+		gotNames = append(gotNames, v.Name)
+	}
+	for _, v := range vulns {
+		wantNames = append(wantNames, v.Name)
+	}
+	if !cmp.Equal(gotNames, wantNames) {
+		t.Error(cmp.Diff(gotNames, wantNames))
+	}
+	if got, err := other.StagedVulnerabilities(ctx, updater); err != nil || len(got) != 0 {
+		t.Errorf("other namespace: got %d vulnerabilities, %v", len(got), err)
+	}
+
+	t.Run("Discard", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		if err := s.DiscardStaged(ctx, updater); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.StagedUpdate(ctx, updater); err != nil || got != nil {
+			t.Errorf("got %+v, %v", got, err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Statement is a named query the Store issues on a hot path.
+type Statement struct {
+	// Name identifies the statement. It's stable across releases.
+	Name string
+	// SQL is the statement text, possibly with positional parameters.
+	SQL string
+}
+
+// Names of the statements returned by Statements.
+const (
+	StatementGetEnrichment   = "get_enrichment"
+	StatementLatestOperation = "latest_update_operation"
+	StatementGet             = "get"
+	StatementLookup          = "lookup_vulnerabilities"
+)
+
+// LatestUpdateOperation selects the id of the most recent update operation
+// for the updater named by $1 in the namespace $2.
+//
+// It's used as the "latest" CTE in other queries.
+const latestUpdateOperation = `
+SELECT
+	max(id) AS id
+FROM
+	update_operation
+WHERE
+	updater = $1
+	AND namespace = $2`
+
+// GetEnrichmentQuery selects the enrichment records associated with the most
+// recent update operation of the updater named by $1 in the namespace $2 that
+// have any of the tags in $3.
+const getEnrichmentQuery = `
+WITH
+	latest
+		AS (` + latestUpdateOperation + `
+		)
+SELECT
+	e.tags, e.data
+FROM
+	enrichment AS e,
+	uo_enrich AS uo,
+	latest
+WHERE
+	uo.uo = latest.id
+	AND uo.enrich = e.id
+	AND e.tags && $3::text[];`
+
+// LookupQuery returns the statement selecting the vulnerabilities named $1
+// affecting the package named $2 in any release of the distribution with the
+// ID $3 in the namespace $4, reading descriptions and links as the phase says
+// to.
+//
+// The columns are in the order scanVulnerability expects.
+func lookupQuery(p DescriptionPhase) string {
+	return fmt.Sprintf(lookupQueryFmt, p.descriptionExpr(), p.linksExpr())
+}
+
+const lookupQueryFmt = `
+SELECT
+	id,
+	name,
+	updater,
+	%s,
+	issued,
+	%s,
+	severity,
+	normalized_severity,
+	package_name,
+	package_version,
+	package_module,
+	package_arch,
+	package_kind,
+	dist_id,
+	dist_name,
+	dist_version,
+	dist_version_code_name,
+	dist_version_id,
+	dist_arch,
+	dist_cpe,
+	dist_pretty_name,
+	arch_operation,
+	repo_name,
+	repo_key,
+	repo_uri,
+	fixed_in_version,
+	first_seen,
+	last_seen
+FROM
+	vuln
+WHERE
+	name = $1
+	AND package_name = $2
+	AND dist_id = $3
+	AND namespace = $4;`
+
+// StatementRecord and statementOpts are what the Get statement is rendered
+// for. The matcher query is built per-record with the values inlined, so the
+// catalog can only show a representative instance: a source package matched
+// on distribution and version range, which is the shape most matchers ask for.
+var (
+	statementRecord = claircore.IndexRecord{
+		Package: &claircore.Package{
+			Name: "openssl-libs",
+			Kind: claircore.BINARY,
+			Source: &claircore.Package{
+				Name: "openssl",
+				Kind: claircore.SOURCE,
+			},
+			NormalizedVersion: claircore.Version{
+				Kind: "rpm",
+				V:    [...]int32{0, 1, 1, 1, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		Distribution: &claircore.Distribution{
+			DID:       "rhel",
+			VersionID: "8",
+		},
+		Repository: &claircore.Repository{},
+	}
+	statementOpts = vulnstore.GetOpts{
+		Matchers: []driver.MatchConstraint{
+			driver.DistributionDID,
+			driver.DistributionVersionID,
+		},
+		VersionFiltering: true,
+	}
+)
+
+// Statements returns the catalog of statements the Store issues on hot paths,
+// for use in checking query plans.
+func Statements() ([]Statement, error) {
+	get, err := buildGetQuery(&statementRecord, &statementOpts, DescriptionOld, DefaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+	return []Statement{
+		{Name: StatementGetEnrichment, SQL: getEnrichmentQuery},
+		{Name: StatementLatestOperation, SQL: latestUpdateOperation + ";"},
+		{Name: StatementGet, SQL: get},
+		{Name: StatementLookup, SQL: lookupQuery(DescriptionOld)},
+	}, nil
+}
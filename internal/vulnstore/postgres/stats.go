@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ vulnstore.Stats = (*Store)(nil)
+
+var (
+	statsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "stats_total",
+			Help:      "Total number of database queries issued in the Stats method.",
+		},
+		[]string{"query"},
+	)
+	statsDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "stats_duration_seconds",
+			Help:      "The duration of all queries issued in the Stats method.",
+		},
+		[]string{"query"},
+	)
+)
+
+// StatsTables are the tables reported by Stats: every table created by the
+// libvuln migrations.
+var statsTables = []string{
+	"description",
+	"enrichment",
+	"exclusion",
+	"settings",
+	"update_operation",
+	"update_stage",
+	"update_stage_vuln",
+	"uo_enrich",
+	"uo_vuln",
+	"vuln",
+	"vuln_alias",
+}
+
+// Stats implements vulnstore.Stats.
+//
+// The table numbers come from the statistics collector and the relation size
+// functions, so they don't scan the tables. Counting orphaned vulnerabilities
+// does, using the index on uo_vuln.
+func (s *Store) Stats(ctx context.Context, keep int) (*driver.StoreStats, error) {
+	const (
+		tables = `
+SELECT
+	relname,
+	n_live_tup,
+	n_dead_tup,
+	pg_total_relation_size(relid),
+	pg_indexes_size(relid),
+	GREATEST(last_vacuum, last_autovacuum)
+FROM pg_stat_user_tables
+WHERE
+	schemaname = current_schema()
+	AND relname = ANY ($1)
+ORDER BY relname;`
+		expired = `
+SELECT
+	COALESCE(sum(n - $2), 0)::bigint
+FROM
+	(SELECT count(*) AS n FROM update_operation WHERE namespace = $1 GROUP BY updater) AS c
+WHERE n > $2;`
+		orphaned = `
+SELECT
+	count(*)
+FROM vuln
+WHERE
+	namespace = $1
+	AND NOT EXISTS (SELECT 1 FROM uo_vuln WHERE uo_vuln.vuln = vuln.id);`
+	)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/Stats"))
+
+	var out driver.StoreStats
+	start := time.Now()
+	rows, err := s.pool.Query(ctx, tables, statsTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table statistics: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t driver.TableStats
+		if err := rows.Scan(&t.Name, &t.Rows, &t.DeadRows, &t.TotalBytes, &t.IndexBytes, &t.LastVacuum); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		out.Tables = append(out.Tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	statsCounter.WithLabelValues("tables").Add(1)
+	statsDuration.WithLabelValues("tables").Observe(time.Since(start).Seconds())
+
+	// A keep value below 1 means GC is disabled, so nothing is expired.
+	if keep > 0 {
+		start = time.Now()
+		if err := s.pool.QueryRow(ctx, expired, s.namespace, keep).Scan(&out.ExpiredUpdateOperations); err != nil {
+			return nil, fmt.Errorf("failed to count expired update operations: %w", err)
+		}
+		statsCounter.WithLabelValues("expired").Add(1)
+		statsDuration.WithLabelValues("expired").Observe(time.Since(start).Seconds())
+	}
+
+	start = time.Now()
+	if err := s.pool.QueryRow(ctx, orphaned, s.namespace).Scan(&out.OrphanedVulnerabilities); err != nil {
+		return nil, fmt.Errorf("failed to count orphaned vulnerabilities: %w", err)
+	}
+	statsCounter.WithLabelValues("orphaned").Add(1)
+	statsDuration.WithLabelValues("orphaned").Observe(time.Since(start).Seconds())
+
+	out.UnreachableEnrichments, err = unreachableEnrichments(ctx, s.pool)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/updates"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestStats populates a store and checks that Stats reports every table with
+// plausible sizes, and counts the rows GC would remove.
+func TestStats(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	store := NewVulnStore(pool)
+	const (
+		updateOps = 6
+		keep      = 2
+	)
+	// As in TestGC, every update operation has exactly one vulnerability.
+	mock := &updaterMock{
+		_name: func() string { return "MockUpdater" },
+		_fetch: func(_ context.Context, _ driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+			return nil, "", nil
+		},
+		_parse: func(ctx context.Context, contents io.ReadCloser) ([]*claircore.Vulnerability, error) {
+			return []*claircore.Vulnerability{
+				{
+					Name:    randString(t),
+					Updater: "MockUpdater",
+					Package: test.GenUniquePackages(1)[0],
+				},
+			}, nil
+		},
+	}
+	locks, err := updates.PoolLockSource(pool, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := updates.NewManager(ctx, store, locks, http.DefaultClient,
+		updates.WithEnabled([]string{}),
+		updates.WithOutOfTree([]driver.Updater{mock}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < updateOps; i++ {
+		if err := mgr.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Deleting an update operation directly leaves its vulnerability
+	// unreferenced, for GC to find.
+	ops, err := store.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := ops["MockUpdater"][len(ops["MockUpdater"])-1]
+	if _, err := store.DeleteUpdateOperations(ctx, oldest.Ref); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, `ANALYZE;`); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := store.Stats(ctx, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.ExpiredUpdateOperations, int64(updateOps-1-keep); got != want {
+		t.Errorf("got %d expired update operations, want %d", got, want)
+	}
+	if got, want := st.OrphanedVulnerabilities, int64(1); got != want {
+		t.Errorf("got %d orphaned vulnerabilities, want %d", got, want)
+	}
+	if got, want := len(st.Tables), len(statsTables); got != want {
+		t.Errorf("got %d tables, want %d: %+v", got, want, st.Tables)
+	}
+	for i, tbl := range st.Tables {
+		t.Logf("%+v", tbl)
+		if i > 0 && st.Tables[i-1].Name >= tbl.Name {
+			t.Errorf("tables out of order: %q before %q", st.Tables[i-1].Name, tbl.Name)
+		}
+		switch {
+		case tbl.Rows < 0, tbl.DeadRows < 0:
+			t.Errorf("%s: negative row count", tbl.Name)
+		case tbl.IndexBytes <= 0, tbl.TotalBytes < tbl.IndexBytes:
+			// Every table has at least a primary key.
+			t.Errorf("%s: implausible sizes: total %d, index %d", tbl.Name, tbl.TotalBytes, tbl.IndexBytes)
+		}
+	}
+
+	// The statistics collector reports asynchronously, so wait for the
+	// estimates to catch up with the inserted rows.
+	rows := func(st *driver.StoreStats, name string) int64 {
+		for _, tbl := range st.Tables {
+			if tbl.Name == name {
+				return tbl.Rows
+			}
+		}
+		return -1
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for rows(st, "vuln") < updateOps && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		if st, err = store.Stats(ctx, keep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := rows(st, "vuln"), int64(updateOps); got != want {
+		t.Errorf("got %d estimated vuln rows, want %d", got, want)
+	}
+	if got, want := rows(st, "update_operation"), int64(updateOps-1); got < want {
+		t.Errorf("got %d estimated update_operation rows, want at least %d", got, want)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		st, err := store.Stats(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := st.ExpiredUpdateOperations; got != 0 {
+			t.Errorf("got %d expired update operations with GC disabled, want 0", got)
+		}
+	})
+}
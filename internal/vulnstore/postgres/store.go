@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -17,29 +19,111 @@ import (
 // store implements all interfaces in the vulnstore package
 type Store struct {
 	pool *pgxpool.Pool
+	// Namespace is the value of the namespace column of every update
+	// operation and vulnerability the Store reads or writes.
+	namespace string
 	// Initialized is used as an atomic bool for tracking initialization.
 	initialized uint32
+	// RejectInvalid makes UpdateEnrichments fail on invalid records instead
+	// of skipping them.
+	rejectInvalid bool
+	// MaxEnrichment is the largest enrichment data UpdateEnrichments
+	// accepts, or not positive for no limit.
+	maxEnrichment int
+
+	// Assist enables the description migration phases. The current phase is
+	// cached in curPhase, as of phaseAt.
+	assist       bool
+	phaseRefresh time.Duration
+	phaseMu      sync.Mutex
+	curPhase     DescriptionPhase
+	phaseAt      time.Time
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// DefaultNamespace is the namespace used by a Store constructed without
+// WithNamespace. Rows written before namespaces existed are in it.
+const DefaultNamespace = ""
+
+// WithNamespace makes the Store read and write only the update operations and
+// vulnerabilities in the named namespace, so that several Stores can share a
+// database without seeing each other's data.
+//
+// Enrichment records are stored once and shared, but each Store only sees the
+// ones associated with its own update operations.
+func WithNamespace(ns string) Option {
+	return func(s *Store) {
+		s.namespace = ns
+	}
+}
+
+// WithRejectInvalidEnrichments makes UpdateEnrichments return an error when
+// passed an invalid record, such as one with no tags or with data that isn't
+// valid JSON. By default, such records are skipped with a warning.
+func WithRejectInvalidEnrichments() Option {
+	return func(s *Store) {
+		s.rejectInvalid = true
+	}
 }
 
-func NewVulnStore(pool *pgxpool.Pool) *Store {
-	return &Store{
-		pool: pool,
+// WithMaxEnrichmentSize sets the largest enrichment data, in bytes,
+// UpdateEnrichments accepts in a single record. If "n" is not positive,
+// records of any size are accepted. The default is DefaultMaxEnrichmentSize.
+func WithMaxEnrichmentSize(n int) Option {
+	return func(s *Store) {
+		s.maxEnrichment = n
 	}
 }
 
+// WithMigrationAssist makes the Store read and write vulnerability
+// descriptions according to the migration phase recorded in the database,
+// re-reading the phase at the provided interval. If the interval is not
+// positive, DefaultPhaseRefresh is used.
+//
+// See DescriptionPhase for the phases. Without this option, the Store always
+// uses the old phase.
+func WithMigrationAssist(refresh time.Duration) Option {
+	return func(s *Store) {
+		if refresh <= 0 {
+			refresh = DefaultPhaseRefresh
+		}
+		s.assist = true
+		s.phaseRefresh = refresh
+	}
+}
+
+func NewVulnStore(pool *pgxpool.Pool, opts ...Option) *Store {
+	s := &Store{
+		pool:          pool,
+		namespace:     DefaultNamespace,
+		maxEnrichment: DefaultMaxEnrichmentSize,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
 var (
-	_ vulnstore.Updater       = (*Store)(nil)
-	_ vulnstore.Vulnerability = (*Store)(nil)
+	_ vulnstore.Updater             = (*Store)(nil)
+	_ vulnstore.Vulnerability       = (*Store)(nil)
+	_ vulnstore.VulnerabilityLookup = (*Store)(nil)
 )
 
 // UpdateVulnerabilities implements vulnstore.Updater.
 func (s *Store) UpdateVulnerabilities(ctx context.Context, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
-	return updateVulnerabilites(ctx, s.pool, updater, fingerprint, vulns)
+	p, err := s.phase(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return updateVulnerabilites(ctx, s.pool, p, s.namespace, "", updater, fingerprint, vulns)
 }
 
 // DeleteUpdateOperations implements vulnstore.Updater.
 func (s *Store) DeleteUpdateOperations(ctx context.Context, id ...uuid.UUID) (int64, error) {
-	const query = `DELETE FROM update_operation WHERE ref = ANY($1::uuid[]);`
+	const query = `DELETE FROM update_operation WHERE ref = ANY($1::uuid[]) AND namespace = $2;`
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/deleteUpdateOperations"))
 	if len(id) == 0 {
@@ -52,7 +136,7 @@ func (s *Store) DeleteUpdateOperations(ctx context.Context, id ...uuid.UUID) (in
 	for i := range id {
 		refStr[i] = id[i].String()
 	}
-	tag, err := s.pool.Exec(ctx, query, refStr)
+	tag, err := s.pool.Exec(ctx, query, refStr, s.namespace)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete: %w", err)
 	}
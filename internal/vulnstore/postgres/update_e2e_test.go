@@ -165,7 +165,7 @@ func (e *e2e) GetUpdateOperations(ctx context.Context) func(*testing.T) {
 }
 
 var vulnCmp = cmp.Options{
-	cmpopts.IgnoreFields(claircore.Vulnerability{}, "ID", "Package.ID", "Dist.ID", "Repo.ID"),
+	cmpopts.IgnoreFields(claircore.Vulnerability{}, "ID", "Package.ID", "Dist.ID", "Repo.ID", "FirstSeen", "LastSeen"),
 }
 
 func orNoIndex(a int) string {
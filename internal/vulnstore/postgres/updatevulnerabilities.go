@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -51,11 +52,44 @@ var (
 // UpdateVulnerabilities creates a new UpdateOperation for this update call,
 // inserts the provided vulnerabilities and computes a diff comprising the
 // removed and added vulnerabilities for this UpdateOperation.
-func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+//
+// Descriptions are written where the description phase "phase" says to, and
+// everything is written in the namespace "ns". If "key" is not empty, it's
+// the idempotency key for the update operation, as described by
+// createOperation.
+func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, phase DescriptionPhase, ns string, key string, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/updateVulnerabilities"))
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ref, created, err := writeVulnerabilities(ctx, tx, phase, ns, key, updater, fingerprint, nil, vulns)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !created {
+		return ref, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	zlog.Debug(ctx).
+		Str("ref", ref.String()).
+		Msg("update_operation committed")
+	return ref, nil
+}
+
+// writeVulnerabilities does the work of updateVulnerabilites in the
+// transaction "tx", reporting whether an update operation was created. If
+// "date" is not nil, it's the date of the update operation.
+func writeVulnerabilities(ctx context.Context, tx pgx.Tx, phase DescriptionPhase, ns string, key string, updater string, fingerprint driver.Fingerprint, date *time.Time, vulns []*claircore.Vulnerability) (uuid.UUID, bool, error) {
 	const (
-		// Create makes a new update operation and returns the reference and ID.
-		create = `INSERT INTO update_operation (updater, fingerprint, kind) VALUES ($1, $2, 'vulnerability') RETURNING id, ref;`
-		// Insert attempts to create a new vulnerability. It fails silently.
+		// Insert attempts to create a new vulnerability, seen first and last
+		// in this update operation. It fails silently.
 		insert = `
 		INSERT INTO vuln (
 			hash_kind, hash,
@@ -63,45 +97,92 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 			package_name, package_version, package_module, package_arch, package_kind,
 			dist_id, dist_name, dist_version, dist_version_code_name, dist_version_id, dist_arch, dist_cpe, dist_pretty_name,
 			repo_name, repo_key, repo_uri,
-			fixed_in_version, arch_operation, version_kind, vulnerable_range
+			fixed_in_version, arch_operation, version_kind, vulnerable_range,
+			namespace,
+			first_seen, last_seen
 		) VALUES (
 		  $1, $2,
 		  $3, $4, $5, $6, $7, $8, $9,
 		  $10, $11, $12, $13, $14,
 		  $15, $16, $17, $18, $19, $20, $21, $22,
 		  $23, $24, $25,
-		  $26, $27, $28, VersionRange($29, $30)
+		  $26, $27, $28, VersionRange($29, $30),
+		  $31,
+		  $32, $32
 		)
+		ON CONFLICT (namespace, hash_kind, hash) DO NOTHING;`
+		// InsertDescription attempts to create a new description. It fails
+		// silently.
+		insertDescription = `
+		INSERT INTO description (hash_kind, hash, text) VALUES ($1, $2, $3)
 		ON CONFLICT (hash_kind, hash) DO NOTHING;`
+		// InsertWithDescription is insert, but also referencing the
+		// description and links inserted by insertDescription. Empty links
+		// aren't inserted, so leave links_id NULL.
+		insertWithDescription = `
+		INSERT INTO vuln (
+			hash_kind, hash,
+			name, updater, description, issued, links, severity, normalized_severity,
+			package_name, package_version, package_module, package_arch, package_kind,
+			dist_id, dist_name, dist_version, dist_version_code_name, dist_version_id, dist_arch, dist_cpe, dist_pretty_name,
+			repo_name, repo_key, repo_uri,
+			fixed_in_version, arch_operation, version_kind, vulnerable_range,
+			namespace,
+			first_seen, last_seen,
+			description_id, links_id
+		) VALUES (
+		  $1, $2,
+		  $3, $4, $5, $6, $7, $8, $9,
+		  $10, $11, $12, $13, $14,
+		  $15, $16, $17, $18, $19, $20, $21, $22,
+		  $23, $24, $25,
+		  $26, $27, $28, VersionRange($29, $30),
+		  $31,
+		  $32, $32,
+		  (SELECT id FROM description WHERE hash_kind = $33 AND hash = $34),
+		  (SELECT id FROM description WHERE hash_kind = $35 AND hash = $36)
+		)
+		ON CONFLICT (namespace, hash_kind, hash) DO NOTHING;`
 		// Assoc associates an update operation and a vulnerability. It fails
 		// silently.
 		assoc = `
 		INSERT INTO uo_vuln (uo, vuln) VALUES (
 			$3,
-			(SELECT id FROM vuln WHERE hash_kind = $1 AND hash = $2))
+			(SELECT id FROM vuln WHERE hash_kind = $1 AND hash = $2 AND namespace = $4))
 		ON CONFLICT DO NOTHING;`
+		// Seen moves the last seen date of the vulnerabilities associated
+		// with an update operation forward to its date. Rows inserted by the
+		// operation already have it. The first seen date is only filled in
+		// for rows that predate tracking it.
+		seen = `
+		UPDATE vuln
+		SET last_seen = $2, first_seen = COALESCE(vuln.first_seen, $2)
+		FROM uo_vuln
+		WHERE uo_vuln.uo = $1
+			AND uo_vuln.vuln = vuln.id
+			AND (vuln.last_seen IS NULL OR vuln.last_seen < $2);`
 	)
-	ctx = baggage.ContextWithValues(ctx,
-		label.String("component", "internal/vulnstore/postgres/updateVulnerabilities"))
-
-	tx, err := pool.Begin(ctx)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("unable to start transaction: %w", err)
+	if err := checkOperationKind(ctx, tx, ns, updater, driver.VulnerabilityKind); err != nil {
+		return uuid.Nil, false, err
 	}
-	defer tx.Rollback(ctx)
-
-	var id uint64
-	var ref uuid.UUID
 
 	start := time.Now()
 
-	if err := pool.QueryRow(ctx, create, updater, string(fingerprint)).Scan(&id, &ref); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create update_operation: %w", err)
+	id, ref, opDate, created, err := createOperation(ctx, tx, ns, key, updater, fingerprint, driver.VulnerabilityKind, date)
+	if err != nil {
+		return uuid.Nil, false, err
 	}
 
 	updateVulnerabilitiesCounter.WithLabelValues("create").Add(1)
 	updateVulnerabilitiesDuration.WithLabelValues("create").Observe(time.Since(start).Seconds())
 
+	if !created {
+		zlog.Info(ctx).
+			Str("ref", ref.String()).
+			Msg("update_operation already exists for this data")
+		return ref, false, nil
+	}
+
 	zlog.Debug(ctx).
 		Str("ref", ref.String()).
 		Msg("update_operation created")
@@ -112,6 +193,9 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 	start = time.Now()
 
 	mBatcher := microbatch.NewInsert(tx, 2000, time.Minute)
+	if err := replaceAliases(ctx, tx, mBatcher, ns, updater, vulns); err != nil {
+		return uuid.Nil, false, err
+	}
 	for _, vuln := range vulns {
 		if vuln.Package == nil || vuln.Package.Name == "" {
 			skipCt++
@@ -129,39 +213,64 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 		}
 		hashKind, hash := md5Vuln(vuln)
 		vKind, vrLower, vrUpper := rangefmt(vuln.Range)
-
-		err := mBatcher.Queue(ctx, insert,
+		var desc, links *string
+		if phase.writeOld() {
+			desc, links = &vuln.Description, &vuln.Links
+		}
+		args := []interface{}{
 			hashKind, hash,
-			vuln.Name, vuln.Updater, vuln.Description, vuln.Issued, vuln.Links, vuln.Severity, vuln.NormalizedSeverity,
+			vuln.Name, vuln.Updater, desc, vuln.Issued, links, vuln.Severity, vuln.NormalizedSeverity,
 			pkg.Name, pkg.Version, pkg.Module, pkg.Arch, pkg.Kind,
 			dist.DID, dist.Name, dist.Version, dist.VersionCodeName, dist.VersionID, dist.Arch, dist.CPE, dist.PrettyName,
 			repo.Name, repo.Key, repo.URI,
 			vuln.FixedInVersion, vuln.ArchOperation, vKind, vrLower, vrUpper,
-		)
-		if err != nil {
-			return uuid.Nil, fmt.Errorf("failed to queue vulnerability: %w", err)
+			ns,
+			opDate,
+		}
+
+		q := insert
+		if phase.writeNew() {
+			dKind, dHash := md5Description(vuln.Description)
+			if err := mBatcher.Queue(ctx, insertDescription, dKind, dHash, vuln.Description); err != nil {
+				return uuid.Nil, false, fmt.Errorf("failed to queue description: %w", err)
+			}
+			lKind, lHash := md5Description(vuln.Links)
+			if vuln.Links != "" {
+				if err := mBatcher.Queue(ctx, insertDescription, lKind, lHash, vuln.Links); err != nil {
+					return uuid.Nil, false, fmt.Errorf("failed to queue links: %w", err)
+				}
+			}
+			q = insertWithDescription
+			args = append(args, dKind, dHash, lKind, lHash)
+		}
+		if err := mBatcher.Queue(ctx, q, args...); err != nil {
+			return uuid.Nil, false, fmt.Errorf("failed to queue vulnerability: %w", err)
 		}
 
-		if err := mBatcher.Queue(ctx, assoc, hashKind, hash, id); err != nil {
-			return uuid.Nil, fmt.Errorf("failed to queue association: %w", err)
+		if err := mBatcher.Queue(ctx, assoc, hashKind, hash, id, ns); err != nil {
+			return uuid.Nil, false, fmt.Errorf("failed to queue association: %w", err)
 		}
 	}
 	if err := mBatcher.Done(ctx); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to finish batch vulnerability insert: %w", err)
+		return uuid.Nil, false, fmt.Errorf("failed to finish batch vulnerability insert: %w", err)
 	}
 
 	updateVulnerabilitiesCounter.WithLabelValues("insert_batch").Add(1)
 	updateVulnerabilitiesDuration.WithLabelValues("insert_batch").Observe(time.Since(start).Seconds())
 
-	if err := tx.Commit(ctx); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	start = time.Now()
+	if _, err := tx.Exec(ctx, seen, id, opDate); err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to update last seen dates: %w", err)
 	}
+	updateVulnerabilitiesCounter.WithLabelValues("seen").Add(1)
+	updateVulnerabilitiesDuration.WithLabelValues("seen").Observe(time.Since(start).Seconds())
+
 	zlog.Debug(ctx).
 		Str("ref", ref.String()).
 		Int("skipped", skipCt).
 		Int("inserted", len(vulns)-skipCt).
-		Msg("update_operation committed")
-	return ref, nil
+		Msg("update_operation written")
+	return ref, true, nil
 }
 
 // Md5Vuln creates an md5 hash from the members of the passed-in Vulnerability,
@@ -207,6 +316,13 @@ func md5Vuln(v *claircore.Vulnerability) (string, []byte) {
 	return "md5", s[:]
 }
 
+// Md5Description returns the identifier for a description, or links, in the
+// description table. It must agree with the one BackfillDescriptions computes.
+func md5Description(d string) (string, []byte) {
+	s := md5.Sum([]byte(d))
+	return "md5", s[:]
+}
+
 func rangefmt(r *claircore.Range) (kind *string, lower, upper string) {
 	lower, upper = "{}", "{}"
 	if r == nil || r.Lower.Kind != r.Upper.Kind {
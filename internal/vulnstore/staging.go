@@ -0,0 +1,44 @@
+package vulnstore
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Staging is an interface for stores that can hold the pages of an update from
+// a driver.PaginatedUpdater across runs, so an interrupted update can resume
+// where it left off. At most one update is staged per updater.
+type Staging interface {
+	// StagedUpdate reports the update staged for "updater", or nil if there
+	// isn't one.
+	StagedUpdate(ctx context.Context, updater string) (*StagedUpdate, error)
+	// StagePage adds the vulnerabilities to the update staged for "updater",
+	// creating it if needed, and records "next" as the cursor to resume
+	// from. The vulnerabilities and cursor are recorded atomically.
+	//
+	// The "prev" Fingerprint is that of the latest completed update when the
+	// staged update was started. It's recorded when the staged update is
+	// created.
+	StagePage(ctx context.Context, updater string, prev driver.Fingerprint, next string, vulns []*claircore.Vulnerability) error
+	// StagedVulnerabilities returns the vulnerabilities staged for
+	// "updater", in the order they were staged. The caller stores them as
+	// an update and then discards the staged update.
+	StagedVulnerabilities(ctx context.Context, updater string) ([]*claircore.Vulnerability, error)
+	// DiscardStaged removes the update staged for "updater", if any.
+	DiscardStaged(ctx context.Context, updater string) error
+}
+
+// StagedUpdate describes an update in progress.
+type StagedUpdate struct {
+	// Prev is the Fingerprint of the latest completed update when this one
+	// was started.
+	Prev driver.Fingerprint
+	// Cursor is the cursor of the next page to fetch.
+	Cursor string
+	// Pages is the number of pages staged.
+	Pages int
+	// Count is the number of vulnerabilities staged.
+	Count int
+}
@@ -0,0 +1,15 @@
+package vulnstore
+
+import (
+	"context"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Stats is an interface for reporting the size of the store.
+type Stats interface {
+	// Stats reports the size of the store's tables and the number of rows GC
+	// would remove if run with the provided keep value. It's read-only, and
+	// cheap enough to call periodically.
+	Stats(ctx context.Context, keep int) (*driver.StoreStats, error)
+}
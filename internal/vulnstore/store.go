@@ -5,4 +5,6 @@ type Store interface {
 	Updater
 	Vulnerability
 	Enrichment
+	Exclusions
+	Stats
 }
@@ -18,6 +18,9 @@ type Updater interface {
 	// vulnerabilities, and ensures vulnerabilities from previous updates are
 	// not queried by clients.
 	UpdateVulnerabilities(ctx context.Context, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error)
+	// SetDataSource records the data source for the update operation "ref".
+	// It's reported in the Source member of the UpdateOperation.
+	SetDataSource(ctx context.Context, ref uuid.UUID, src claircore.DataSource) error
 	// GetUpdateOperations returns a list of UpdateOperations in date descending
 	// order for the given updaters.
 	//
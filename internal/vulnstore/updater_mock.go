@@ -151,6 +151,20 @@ func (mr *MockUpdaterMockRecorder) Initialized(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Initialized", reflect.TypeOf((*MockUpdater)(nil).Initialized), arg0)
 }
 
+// SetDataSource mocks base method
+func (m *MockUpdater) SetDataSource(arg0 context.Context, arg1 uuid.UUID, arg2 claircore.DataSource) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDataSource", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDataSource indicates an expected call of SetDataSource
+func (mr *MockUpdaterMockRecorder) SetDataSource(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDataSource", reflect.TypeOf((*MockUpdater)(nil).SetDataSource), arg0, arg1, arg2)
+}
+
 // UpdateVulnerabilities mocks base method
 func (m *MockUpdater) UpdateVulnerabilities(arg0 context.Context, arg1 string, arg2 driver.Fingerprint, arg3 []*claircore.Vulnerability) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
@@ -3,6 +3,8 @@ package vulnstore
 import (
 	"context"
 
+	"github.com/google/uuid"
+
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 )
@@ -18,6 +20,13 @@ type GetOpts struct {
 	// VersionFiltering enables filtering based on the normalized versions in
 	// the database.
 	VersionFiltering bool
+	// DistributionVersionOp is how the DistributionVersion and
+	// DistributionVersionID Matchers compare versions.
+	DistributionVersionOp driver.DistributionVersionOp
+	// UpdateRefs, if not empty, limits matching to the vulnerabilities
+	// recorded by these update operations, so updates committed after the
+	// refs were read don't change the result.
+	UpdateRefs []uuid.UUID
 }
 
 type Vulnerability interface {
@@ -26,3 +35,31 @@ type Vulnerability interface {
 	// a map of Package.ID => Vulnerabilities is returned.
 	Get(ctx context.Context, records []*claircore.IndexRecord, opts GetOpts) (map[string][]*claircore.Vulnerability, error)
 }
+
+// VulnerabilityLookup is an interface for finding vulnerabilities by name,
+// rather than by the packages they affect.
+type VulnerabilityLookup interface {
+	// GetVulnerabilities returns the vulnerabilities matching each query, in
+	// the same order as the queries.
+	GetVulnerabilities(ctx context.Context, queries []driver.VulnerabilityQuery) ([][]*claircore.Vulnerability, error)
+}
+
+// AliasResolver is an interface for finding the other names a vulnerability
+// is known by, from the aliases updaters declared for their vulnerabilities.
+type AliasResolver interface {
+	// ResolveAliases reports every name connected to each of "names" through
+	// the recorded aliases, directly or through other names, sorted. Names
+	// with no aliases aren't present in the returned map.
+	ResolveAliases(ctx context.Context, names []string) (map[string][]string, error)
+}
+
+// SeverityBackfiller is an interface for rewriting the NormalizedSeverity of
+// stored vulnerabilities from their Severity, for data stored before a change
+// to the normalization.
+type SeverityBackfiller interface {
+	// BackfillSeverity sets the NormalizedSeverity of every stored
+	// vulnerability whose Severity is recognized by
+	// claircore.NormalizeSeverity, "batch" rows at a time, and reports the
+	// number of rows changed.
+	BackfillSeverity(ctx context.Context, batch int) (int64, error)
+}
@@ -2,9 +2,11 @@ package java
 
 import (
 	"context"
+	"sort"
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
 )
 
 func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
@@ -20,19 +22,35 @@ func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts)
 		Packages:     map[string]*claircore.Package{},
 		Repositories: map[string]*claircore.Repository{},
 	}
+	// Files recorded for each copy of a package, by package ID and then
+	// package database. A copy found in a later layer replaces the earlier
+	// one: it's the same file, rewritten.
+	files := make(map[string]map[string][]string)
 
 	for _, l := range ls {
-		// If we didn't find at least one maven repo in this layer
-		// no point in searching for packages.
-		if len(l.Repos) == 0 {
-			continue
-		}
-		rs := make([]string, len(l.Repos))
-		for i, r := range l.Repos {
-			rs[i] = r.ID
-			ir.Repositories[r.ID] = r
+		// Packages are kept even if no maven repo was found in this
+		// layer: an inventory-only indexer doesn't look for them.
+		var rs []string
+		for _, r := range l.Repos {
+			rs = append(rs, r.ID)
+			ir.AddRepository(r, l.Hash)
 		}
+		// Copies seen in this layer, which are merged rather than replaced.
+		seen := make(map[[2]string]struct{})
 		for _, pkg := range l.Pkgs {
+			pkg.PURL = purl.Maven(pkg, nil, l.Repos)
+			dbs, ok := files[pkg.ID]
+			if !ok {
+				dbs = make(map[string][]string)
+				files[pkg.ID] = dbs
+			}
+			k := [2]string{pkg.ID, pkg.PackageDB}
+			if _, ok := seen[k]; ok {
+				dbs[pkg.PackageDB] = mergeFiles(dbs[pkg.PackageDB], pkg.Files)
+			} else {
+				dbs[pkg.PackageDB] = pkg.Files
+				seen[k] = struct{}{}
+			}
 			ir.Packages[pkg.ID] = pkg
 			ir.Environments[pkg.ID] = []*claircore.Environment{
 				&claircore.Environment{
@@ -43,5 +61,41 @@ func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts)
 			}
 		}
 	}
+	// Copies in different package databases share a Package, so it has the
+	// files of all of them.
+	for id, dbs := range files {
+		var fs []string
+		first := true
+		for _, f := range dbs {
+			if first {
+				fs, first = f, false
+				continue
+			}
+			fs = mergeFiles(fs, f)
+		}
+		ir.Packages[id].Files = fs
+	}
 	return ir, nil
 }
+
+// MergeFiles returns the sorted union of the file lists. If either list is
+// empty, nothing is known about one of the copies of the package, so the
+// result is empty too.
+func mergeFiles(a, b []string) []string {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(a)+len(b))
+	out := make([]string, 0, len(a)+len(b))
+	for _, fs := range [][]string{a, b} {
+		for _, f := range fs {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			out = append(out, f)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
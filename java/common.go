@@ -34,3 +34,13 @@ Outer:
 	}
 	return false
 }
+
+// IsArchiveName reports whether the named file is some form of Java archive,
+// by its extension.
+func isArchiveName(name string) bool {
+	switch path.Ext(name) {
+	case ".jar", ".war", ".ear":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package java_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/java"
+)
+
+// TestExclude checks that archives under excluded paths are skipped.
+func TestExclude(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := excludeLayer(t, map[string][]byte{
+		"opt/app/lib/included-1.0.jar":           mkJar(t, "included", "1.0"),
+		"opt/app/test/fixtures/excluded-1.0.jar": mkJar(t, "excluded", "1.0"),
+		"usr/share/doc/examples/example-1.0.jar": mkJar(t, "example", "1.0"),
+	})
+
+	tt := []struct {
+		name string
+		cfg  string
+		want []string
+	}{
+		{
+			name: "Default",
+			cfg:  `{}`,
+			want: []string{"com.example:example", "com.example:excluded", "com.example:included"},
+		},
+		{
+			name: "Exclude",
+			cfg:  `{"exclude":["opt/*/test/fixtures","/usr/share/doc"]}`,
+			want: []string{"com.example:included"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &java.Scanner{}
+			if err := s.Configure(ctx, json.NewDecoder(strings.NewReader(tc.cfg)).Decode); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := s.Scan(ctx, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(ps))
+			for i, p := range ps {
+				got[i] = p.Name
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+// MkJar returns a jar containing only the maven properties for the artifact,
+// which is enough for the scanner to identify it without the network.
+func mkJar(t *testing.T, artifact, version string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	w, err := z.Create("META-INF/maven/com.example/" + artifact + "/pom.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("groupId=com.example\nartifactId=" + artifact + "\nversion=" + version + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// ExcludeLayer writes the files to a layer tarball.
+func excludeLayer(t *testing.T, files map[string][]byte) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "java.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	for n, c := range files {
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     n,
+			Size:     int64(len(c)),
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
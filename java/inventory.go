@@ -0,0 +1,182 @@
+package java
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+)
+
+// JndiLookup is the class behind log4j's JNDI lookups. Removing it from
+// log4j-core is the mitigation Apache recommends for CVE-2021-44228.
+const jndiLookup = `org/apache/logging/log4j/core/lookup/JndiLookup.class`
+
+// TrackedFiles lists the files recorded in a package's Files when the keyed
+// artifact is found. It's kept short, because the files are stored with
+// every copy of the artifact.
+var trackedFiles = map[string][]string{
+	"org.apache.logging.log4j:log4j-core": {jndiLookup},
+}
+
+// MaxNesting bounds how deeply archives inside archives are inspected.
+const maxNesting = 4
+
+// MaxInventorySize bounds the size of an archive read into memory to take an
+// inventory of it. For nested archives, it also bounds the total size of the
+// nested archives read for one top-level archive. Larger archives are
+// skipped.
+const maxInventorySize = 64 * 1024 * 1024
+
+// Inventory returns the files to record for the tracked artifacts in the
+// archive "b", keyed by artifact name, along with the licenses declared by
+// any artifact's embedded POM.
+//
+// An artifact is found by its maven properties. Its files are the properties
+// file itself, which marks that an inventory was taken, and whichever of the
+// tracked files are in the same archive. Nested archives are inspected as
+// well, and paths are relative to the archive holding the file. A nested
+// archive that can't be inspected is logged and skipped.
+//
+// If "files" is false, only licenses are recorded.
+func inventory(ctx context.Context, b []byte, files bool) (map[string][]string, map[string]string, error) {
+	w := inventoryWalker{
+		inv:    make(map[string][]string),
+		lics:   make(map[string]string),
+		files:  files,
+		budget: maxInventorySize,
+	}
+	if err := w.walk(ctx, b, 0); err != nil {
+		return nil, nil, err
+	}
+	for n, fs := range w.inv {
+		sort.Strings(fs)
+		out := fs[:0]
+		for i, f := range fs {
+			if i == 0 || f != fs[i-1] {
+				out = append(out, f)
+			}
+		}
+		w.inv[n] = out
+	}
+	return w.inv, w.lics, nil
+}
+
+// InventoryWalker holds the results of an inventory as it walks an archive
+// and the archives nested in it.
+type inventoryWalker struct {
+	inv  map[string][]string
+	lics map[string]string
+	// Files reports whether tracked files are recorded.
+	files bool
+	// Budget is the number of bytes of nested archives that may still be
+	// read into memory.
+	budget int64
+}
+
+func (w *inventoryWalker) walk(ctx context.Context, b []byte, depth int) error {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return fmt.Errorf("java: unable to open archive: %w", err)
+	}
+	found := make(map[string]string)
+	present := make(map[string]bool)
+	for _, f := range zr.File {
+		switch {
+		case path.Base(f.Name) == "pom.properties" && w.files:
+			n, err := artifactName(f)
+			if err != nil {
+				return err
+			}
+			if _, ok := trackedFiles[n]; ok {
+				found[n] = f.Name
+			}
+		case path.Base(f.Name) == "pom.xml" && strings.HasPrefix(f.Name, pomDir):
+			n, l, err := pomLicense(f)
+			if err != nil {
+				return err
+			}
+			if _, ok := w.lics[n]; !ok && l != "" {
+				w.lics[n] = l
+			}
+		case isArchiveName(f.Name) && depth < maxNesting:
+			if err := w.nested(ctx, f, depth+1); err != nil {
+				zlog.Info(ctx).
+					Err(err).
+					Str("archive", f.Name).
+					Msg("skipping nested archive")
+			}
+		case w.files:
+			present[f.Name] = true
+		}
+	}
+	for n, props := range found {
+		w.inv[n] = append(w.inv[n], props)
+		for _, t := range trackedFiles[n] {
+			if present[t] {
+				w.inv[n] = append(w.inv[n], t)
+			}
+		}
+	}
+	return nil
+}
+
+// Nested reads the nested archive "f" into memory, within the walker's
+// budget, and walks it.
+func (w *inventoryWalker) nested(ctx context.Context, f *zip.File, depth int) error {
+	sz := int64(f.UncompressedSize64)
+	if f.UncompressedSize64 > maxInventorySize || sz > w.budget {
+		return fmt.Errorf("java: %q too large to inspect (%d bytes)", f.Name, f.UncompressedSize64)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("java: unable to open %q: %w", f.Name, err)
+	}
+	defer rc.Close()
+	// Don't trust the recorded size.
+	b, err := ioutil.ReadAll(io.LimitReader(rc, w.budget+1))
+	if err != nil {
+		return fmt.Errorf("java: unable to read %q: %w", f.Name, err)
+	}
+	if int64(len(b)) > w.budget {
+		return fmt.Errorf("java: %q too large to inspect", f.Name)
+	}
+	w.budget -= int64(len(b))
+	return w.walk(ctx, b, depth)
+}
+
+// ArtifactName returns the "groupId:artifactId" name the properties file
+// describes, as the jar parser reports it.
+func artifactName(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("java: unable to open %q: %w", f.Name, err)
+	}
+	defer rc.Close()
+	var g, a string
+	s := bufio.NewScanner(rc)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		i := strings.IndexByte(l, '=')
+		if i == -1 {
+			continue
+		}
+		switch strings.TrimSpace(l[:i]) {
+		case "groupId":
+			g = strings.TrimSpace(l[i+1:])
+		case "artifactId":
+			a = strings.TrimSpace(l[i+1:])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", fmt.Errorf("java: unable to read %q: %w", f.Name, err)
+	}
+	return g + ":" + a, nil
+}
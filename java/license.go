@@ -0,0 +1,48 @@
+package java
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/quay/claircore/internal/license"
+)
+
+// PomDir is the directory maven puts an artifact's POM in, as
+// "META-INF/maven/<groupId>/<artifactId>/pom.xml".
+const pomDir = `META-INF/maven/`
+
+// Pom is the part of a maven POM that declares licenses.
+type pom struct {
+	Licenses []struct {
+		Name string `xml:"name"`
+	} `xml:"licenses>license"`
+}
+
+// PomLicense returns the "groupId:artifactId" name of the artifact the POM
+// "f" describes, as found in its path, and the licenses it declares.
+//
+// POM license names are usually free-form, like "The Apache Software
+// License, Version 2.0", and are passed through as such.
+func pomLicense(f *zip.File) (string, string, error) {
+	a := path.Dir(strings.TrimPrefix(f.Name, pomDir))
+	n := path.Dir(a) + ":" + path.Base(a)
+	rc, err := f.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("java: unable to open %q: %w", f.Name, err)
+	}
+	defer rc.Close()
+	var p pom
+	if err := xml.NewDecoder(rc).Decode(&p); err != nil {
+		// A POM that can't be read shouldn't stop the artifact from being
+		// reported.
+		return n, "", nil
+	}
+	ls := make([]string, 0, len(p.Licenses))
+	for _, l := range p.Licenses {
+		ls = append(ls, l.Name)
+	}
+	return n, license.Join(ls), nil
+}
@@ -0,0 +1,89 @@
+package java_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/java"
+)
+
+// TestLicense checks that licenses declared in embedded POMs are recorded,
+// including for archives nested in other archives.
+func TestLicense(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	mkJar := func(files map[string][]byte) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for n, b := range files {
+			f, err := w.Create(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	artifact := func(g, a, pom string) map[string][]byte {
+		dir := "META-INF/maven/" + g + "/" + a + "/"
+		fs := map[string][]byte{
+			dir + "pom.properties": []byte("groupId=" + g + "\nartifactId=" + a + "\nversion=1.0\n"),
+		}
+		if pom != "" {
+			fs[dir+"pom.xml"] = []byte(pom)
+		}
+		return fs
+	}
+	const (
+		dual = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <licenses>
+    <license><name>EPL-2.0</name></license>
+    <license><name>GPL-2.0-only WITH Classpath-exception-2.0</name></license>
+  </licenses>
+</project>`
+		text = `<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <licenses>
+    <license>
+      <name>The Apache Software License, Version 2.0</name>
+      <url>https://www.apache.org/licenses/LICENSE-2.0.txt</url>
+    </license>
+  </licenses>
+</project>`
+	)
+	lib := mkJar(artifact("org.example", "lib", text))
+	app := artifact("com.example", "app", dual)
+	app["BOOT-INF/lib/lib-1.0.jar"] = lib
+	l := excludeLayer(t, map[string][]byte{
+		"opt/app/app-1.0.jar":     mkJar(app),
+		"opt/none/none-1.0.jar":   mkJar(artifact("org.example", "none", "")),
+		"opt/broken/broken-1.jar": mkJar(artifact("org.example", "broken", "<project><licenses>")),
+	})
+
+	ps, err := (&java.Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.License
+	}
+	want := map[string]string{
+		"com.example:app":    "EPL-2.0 AND (GPL-2.0-only WITH Classpath-exception-2.0)",
+		"org.example:lib":    "The Apache Software License, Version 2.0",
+		"org.example:none":   "",
+		"org.example:broken": "",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
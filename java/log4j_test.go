@@ -0,0 +1,242 @@
+package java_test
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/matcher"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/java"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+const (
+	log4jCore  = "org.apache.logging.log4j:log4j-core"
+	pomProps   = "META-INF/maven/org.apache.logging.log4j/log4j-core/pom.properties"
+	jndiLookup = "org/apache/logging/log4j/core/lookup/JndiLookup.class"
+)
+
+// Log4jLayer returns a layer with a stock log4j-core jar, one patched by
+// removing the JndiLookup class, and an application jar with the patched jar
+// nested inside it.
+func log4jLayer(t *testing.T) *claircore.Layer {
+	t.Helper()
+	read := func(n string) []byte {
+		b, err := ioutil.ReadFile("testdata/" + n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	return excludeLayer(t, map[string][]byte{
+		"opt/stock/lib/log4j-core-2.14.1.jar":   read("log4j-core-2.14.1.jar"),
+		"opt/patched/lib/log4j-core-2.14.1.jar": read("log4j-core-2.14.1-patched.jar"),
+		"opt/app/app-1.0.jar":                   read("app-1.0.jar"),
+	})
+}
+
+// TestInventory checks that the files recorded for log4j-core show whether
+// the JndiLookup class is present.
+func TestInventory(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ps, err := (&java.Scanner{}).Scan(ctx, log4jLayer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, p := range ps {
+		got[p.PackageDB+" "+p.Name] = p.Files
+	}
+	want := map[string][]string{
+		"maven:opt/stock/lib " + log4jCore:   {pomProps, jndiLookup},
+		"maven:opt/patched/lib " + log4jCore: {pomProps},
+		"maven:opt/app " + log4jCore:         {pomProps},
+		// Untracked artifacts have no inventory.
+		"maven:opt/app com.example:app": nil,
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// Log4jStore returns the contained vulnerabilities for every package.
+type log4jStore struct {
+	vulnstore.Vulnerability
+	vulns []*claircore.Vulnerability
+}
+
+func (s *log4jStore) Get(_ context.Context, rs []*claircore.IndexRecord, _ vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range rs {
+		out[r.Package.ID] = s.vulns
+	}
+	return out, nil
+}
+
+// TestLog4Shell checks that CVE-2021-44228 isn't reported for log4j-core jars
+// that had the JndiLookup class removed.
+func TestLog4Shell(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ps, err := (&java.Scanner{}).Scan(ctx, log4jLayer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := java.Repository
+	var rs []*claircore.IndexRecord
+	for i, p := range ps {
+		if p.Name != log4jCore {
+			continue
+		}
+		p.ID = strconv.Itoa(i)
+		rs = append(rs, &claircore.IndexRecord{Package: p, Repository: &repo})
+	}
+	log4shell := &claircore.Vulnerability{
+		ID:             "1",
+		Name:           "GHSA-jfh8-c2jp-5v3q (CVE-2021-44228)",
+		Package:        &claircore.Package{Name: log4jCore, Version: "2.0-beta9"},
+		FixedInVersion: "2.15.0",
+	}
+	// Removing JndiLookup doesn't help with this one.
+	other := &claircore.Vulnerability{
+		ID:             "2",
+		Name:           "CVE-2021-44832",
+		Package:        &claircore.Package{Name: log4jCore, Version: "2.0-beta7"},
+		FixedInVersion: "2.17.1",
+	}
+	store := &log4jStore{vulns: []*claircore.Vulnerability{log4shell, other}}
+
+	res, err := matcher.NewController(&java.Matcher{}, store).Match(ctx, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, r := range rs {
+		var ns []string
+		for _, v := range res.Vulnerabilities[r.Package.ID] {
+			ns = append(ns, v.Name)
+		}
+		sort.Strings(ns)
+		got[r.Package.PackageDB] = ns
+	}
+	want := map[string][]string{
+		"maven:opt/stock/lib":   {"CVE-2021-44832", log4shell.Name},
+		"maven:opt/patched/lib": {"CVE-2021-44832"},
+		"maven:opt/app":         {"CVE-2021-44832"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestMatcherVulnerable(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &java.Matcher{}
+	vuln := &claircore.Vulnerability{
+		Package:        &claircore.Package{Name: log4jCore, Version: "2.0-beta9"},
+		FixedInVersion: "2.15.0",
+	}
+	for v, want := range map[string]bool{
+		"1.2.17":     false,
+		"2.0-beta8":  false,
+		"2.0-beta9":  true,
+		"2.0-rc1":    true,
+		"2.0":        true,
+		"2.14.1":     true,
+		"2.15.0-rc1": true,
+		"2.15.0":     false,
+		"2.17.1":     false,
+	} {
+		r := &claircore.IndexRecord{Package: &claircore.Package{Name: log4jCore, Version: v}}
+		got, err := m.Vulnerable(ctx, r, vuln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got affected: %v, want: %v", v, got, want)
+		}
+	}
+	r := &claircore.IndexRecord{Package: &claircore.Package{Name: log4jCore}}
+	if _, err := m.Vulnerable(ctx, r, vuln); err == nil {
+		t.Error("expected error for empty version")
+	} else if _, ok := err.(*driver.VersionError); !ok {
+		t.Errorf("got error %v, want a version error", err)
+	}
+}
+
+// TestLog4ShellPatchedLayer checks that a later layer removing JndiLookup
+// from a jar, as "zip -d" does, mitigates CVE-2021-44228 for the coalesced
+// package.
+func TestLog4ShellPatchedLayer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	read := func(n string) []byte {
+		b, err := ioutil.ReadFile("testdata/" + n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	const jar = "opt/app/lib/log4j-core-2.14.1.jar"
+	ls := []*claircore.Layer{
+		excludeLayer(t, map[string][]byte{jar: read("log4j-core-2.14.1.jar")}),
+		excludeLayer(t, map[string][]byte{jar: read("log4j-core-2.14.1-patched.jar")}),
+	}
+	ls[1].Hash = claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+
+	repo := java.Repository
+	repo.ID = "1"
+	var as []*indexer.LayerArtifacts
+	for _, l := range ls {
+		ps, err := (&java.Scanner{}).Scan(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range ps {
+			// The same package in the same place gets the same ID.
+			p.ID = "1"
+		}
+		as = append(as, &indexer.LayerArtifacts{
+			Hash:  l.Hash,
+			Pkgs:  ps,
+			Repos: []*claircore.Repository{&repo},
+		})
+	}
+	c, err := java.NewCoalescer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Coalesce(ctx, as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ir.Packages["1"].Files, []string{pomProps}; !cmp.Equal(got, want) {
+		t.Fatal(cmp.Diff(got, want))
+	}
+
+	log4shell := &claircore.Vulnerability{
+		ID:             "1",
+		Name:           "CVE-2021-44228",
+		Package:        &claircore.Package{Name: log4jCore, Version: "2.0-beta9"},
+		FixedInVersion: "2.15.0",
+	}
+	store := &log4jStore{vulns: []*claircore.Vulnerability{log4shell}}
+	rs := ir.IndexRecords()
+	if len(rs) == 0 {
+		t.Fatal("no index records")
+	}
+	res, err := matcher.NewController(&java.Matcher{}, store).Match(ctx, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs := res.Vulnerabilities["1"]; len(vs) != 0 {
+		t.Errorf("unexpected vulnerabilities: %v", vs)
+	}
+}
@@ -0,0 +1,83 @@
+package java
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Matcher     = (*Matcher)(nil)
+	_ driver.FileMatcher = (*Matcher)(nil)
+)
+
+// Matcher attempts to correlate discovered maven packages with reported
+// vulnerabilities.
+//
+// A vulnerability's package version, if set, is the first affected version,
+// and its FixedInVersion is the first version that's no longer affected.
+type Matcher struct{}
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return "java-maven" }
+
+// Filter implements driver.Matcher.
+func (*Matcher) Filter(record *claircore.IndexRecord) bool {
+	return record.Repository != nil && record.Repository.URI == Repository.URI
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{driver.PackageName, driver.RepositoryName}
+}
+
+// Vulnerable implements driver.Matcher.
+func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	if vuln.Package == nil {
+		return false, nil
+	}
+	v := record.Package.Version
+	if driver.EmptyVersion(v) {
+		return false, driver.PackageVersionError(v, driver.ErrEmptyVersion)
+	}
+	if lo := vuln.Package.Version; lo != "" && compareVersions(v, lo) < 0 {
+		return false, nil
+	}
+	if fixed := vuln.FixedInVersion; fixed != "" && compareVersions(v, fixed) >= 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// FileRequirements lists the files that must be present for a package to be
+// affected by the listed vulnerabilities.
+var fileRequirements = []struct {
+	Package string
+	CVEs    []string
+	Files   []string
+}{
+	{
+		// Log4Shell is mitigated by removing the JndiLookup class from the
+		// jar.
+		Package: "org.apache.logging.log4j:log4j-core",
+		CVEs:    []string{"CVE-2021-44228"},
+		Files:   []string{jndiLookup},
+	},
+}
+
+// RequiredFiles implements driver.FileMatcher.
+func (*Matcher) RequiredFiles(record *claircore.IndexRecord, vuln *claircore.Vulnerability) []string {
+	for _, r := range fileRequirements {
+		if record.Package.Name != r.Package {
+			continue
+		}
+		for _, cve := range r.CVEs {
+			if strings.Contains(vuln.Name, cve) {
+				return r.Files
+			}
+		}
+	}
+	return nil
+}
@@ -4,12 +4,15 @@ package java
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
 	"io"
+	"io/ioutil"
 	"path/filepath"
 	"runtime/trace"
 
 	"github.com/aquasecurity/go-dep-parser/pkg/jar"
+	"github.com/aquasecurity/go-dep-parser/pkg/types"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
@@ -19,8 +22,10 @@ import (
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner    = (*Scanner)(nil)
+	_ indexer.PackageScanner      = (*Scanner)(nil)
+	_ indexer.ConfigurableScanner = (*Scanner)(nil)
+	_ indexer.InventoryScanner    = (*Scanner)(nil)
 )
 
 // Scanner implements the scanner.PackageScanner interface.
@@ -29,17 +34,53 @@ var (
 // metadata recorded there.
 //
 // The zero value is ready to use.
-type Scanner struct{}
+type Scanner struct {
+	cfg ScannerConfig
+	// InventoryOnly skips recording the files found with packages.
+	inventoryOnly bool
+}
+
+// ScannerConfig is the struct that will be passed to (*Scanner).Configure's
+// ConfigDeserializer argument.
+type ScannerConfig struct {
+	// Exclude lists patterns of files to skip, such as vendored test
+	// fixtures. See indexer.PathExclusions for the syntax. Nothing is
+	// excluded by default.
+	Exclude indexer.PathExclusions `json:"exclude" yaml:"exclude"`
+}
+
+// Configure implements indexer.ConfigurableScanner.
+func (ps *Scanner) Configure(ctx context.Context, f indexer.ConfigDeserializer) error {
+	if err := f(&ps.cfg); err != nil {
+		return err
+	}
+	return ps.cfg.Exclude.Validate()
+}
 
 // Name implements scanner.VersionedScanner.
 func (*Scanner) Name() string { return "java" }
 
 // Version implements scanner.VersionedScanner.
-func (*Scanner) Version() string { return "0.0.1" }
+func (ps *Scanner) Version() string {
+	if ps.inventoryOnly {
+		return "0.0.3-inventory"
+	}
+	return "0.0.3"
+}
 
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return "package" }
 
+// InventoryOnly implements indexer.InventoryScanner.
+//
+// The returned Scanner doesn't record the files found with packages, which
+// are only used by the matcher.
+func (ps *Scanner) InventoryOnly() indexer.PackageScanner {
+	c := *ps
+	c.inventoryOnly = true
+	return &c
+}
+
 // Scan attempts to find jar, war or ear files and record the package
 // information there.
 //
@@ -64,13 +105,18 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	defer r.Close()
 
 	var ret []*claircore.Package
+	var excluded int
 	tr := tar.NewReader(r)
 	var h *tar.Header
 	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
-		if !isArchive(ctx, h) {
+		switch {
+		case h.Typeflag == tar.TypeReg && ps.cfg.Exclude.Excluded(h.Name):
+			excluded++
+			continue
+		case !isArchive(ctx, h):
 			continue
 		}
-		packages, err := getPackagesFromJarFamily(tr, h.Name)
+		packages, err := getPackagesFromJarFamily(ctx, tr, h, !ps.inventoryOnly)
 		if err != nil {
 			return nil, err
 		}
@@ -79,15 +125,48 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	if err != io.EOF {
 		return nil, err
 	}
+	if excluded != 0 {
+		zlog.Debug(ctx).
+			Int("count", excluded).
+			Msg("skipped excluded files")
+	}
 	return ret, nil
 }
 
-func getPackagesFromJarFamily(r io.Reader, name string) ([]*claircore.Package, error) {
-	n, err := filepath.Rel("/", filepath.Join("/", name))
-	libs, err := jar.Parse(r, jar.WithFilePath(n))
+func getPackagesFromJarFamily(ctx context.Context, r io.Reader, h *tar.Header, files bool) ([]*claircore.Package, error) {
+	n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+	if h.Size > maxInventorySize {
+		// Too large to take an inventory of, so only the packages are
+		// reported. An empty file list means nothing is known about the
+		// package's files.
+		zlog.Info(ctx).
+			Str("archive", n).
+			Int64("size", h.Size).
+			Msg("archive too large to inspect, skipping inventory")
+		libs, err := jar.Parse(r, jar.WithFilePath(n))
+		if err != nil {
+			return nil, err
+		}
+		return mkPackages(n, libs, nil, nil), nil
+	}
+	// The jar parser reads the whole archive into memory anyway, so the
+	// same bytes are used for the inventory.
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	libs, err := jar.Parse(bytes.NewReader(b), jar.WithFilePath(n))
+	if err != nil {
+		return nil, err
+	}
+	inv, lics, err := inventory(ctx, b, files)
 	if err != nil {
 		return nil, err
 	}
+	return mkPackages(n, libs, inv, lics), nil
+}
+
+func mkPackages(n string, libs []types.Library, inv map[string][]string, lics map[string]string) []*claircore.Package {
 	packages := make([]*claircore.Package, len(libs))
 	for i, l := range libs {
 		packages[i] = &claircore.Package{
@@ -96,7 +175,9 @@ func getPackagesFromJarFamily(r io.Reader, name string) ([]*claircore.Package, e
 			PackageDB:      "maven:" + filepath.Join(n, ".."),
 			Kind:           claircore.BINARY,
 			RepositoryHint: Repository.URI,
+			Files:          inv[l.Name],
+			License:        lics[l.Name],
 		}
 	}
-	return packages, nil
+	return packages
 }
@@ -0,0 +1,137 @@
+package java
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This is a simplified version of maven's ComparableVersion ordering: enough
+// to order release versions and the common qualifiers, but not every corner
+// of the full algorithm.
+
+// Qualifiers maps the well-known qualifiers, and their aliases, to their
+// order. The empty qualifier is a release.
+var qualifiers = map[string]int{
+	"alpha":     0,
+	"a":         0,
+	"beta":      1,
+	"b":         1,
+	"milestone": 2,
+	"m":         2,
+	"rc":        3,
+	"cr":        3,
+	"snapshot":  4,
+	"":          5,
+	"ga":        5,
+	"final":     5,
+	"release":   5,
+	"sp":        6,
+}
+
+// Item is one component of a version: a number, or a qualifier.
+type item struct {
+	num    int64
+	str    string
+	isNum  bool
+	isNull bool
+}
+
+// ParseVersion splits a version into items on dots, dashes, and transitions
+// between digits and letters.
+func parseVersion(v string) []item {
+	var out []item
+	var cur strings.Builder
+	var digits bool
+	flush := func() {
+		s := cur.String()
+		cur.Reset()
+		if s == "" {
+			return
+		}
+		if digits {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err == nil {
+				out = append(out, item{num: n, isNum: true})
+				return
+			}
+		}
+		out = append(out, item{str: strings.ToLower(s)})
+	}
+	for _, r := range v {
+		switch {
+		case r == '.' || r == '-' || r == '_' || r == '+':
+			flush()
+		case cur.Len() != 0 && unicode.IsDigit(r) != digits:
+			flush()
+			fallthrough
+		default:
+			digits = unicode.IsDigit(r)
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
+
+// CompareVersions returns -1, 0, or 1 if "a" orders before, the same as, or
+// after "b".
+func compareVersions(a, b string) int {
+	as, bs := parseVersion(a), parseVersion(b)
+	for len(as) < len(bs) {
+		as = append(as, item{isNull: true})
+	}
+	for len(bs) < len(as) {
+		bs = append(bs, item{isNull: true})
+	}
+	for i := range as {
+		if c := compareItems(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareItems(a, b item) int {
+	switch {
+	case a.isNum && b.isNum:
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		return 0
+	case a.isNum:
+		// A number orders after any qualifier, and missing numbers are 0.
+		if b.isNull && a.num == 0 {
+			return 0
+		}
+		return 1
+	case b.isNum:
+		return -compareItems(b, a)
+	}
+	// Both are qualifiers, or missing, which orders as a release.
+	ao, aok := qualifiers[a.str]
+	bo, bok := qualifiers[b.str]
+	switch {
+	case aok && bok:
+		return sign(ao - bo)
+	case aok:
+		// Unknown qualifiers order after the known ones.
+		return -1
+	case bok:
+		return 1
+	}
+	return strings.Compare(a.str, b.str)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package java
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tt := []struct {
+		A, B string
+		Want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.0", "1", 0},
+		{"1.0-final", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"2.0-beta9", "2.0", -1},
+		{"2.0-alpha1", "2.0-beta1", -1},
+		{"2.0-beta9", "2.0-beta10", -1},
+		{"2.0-rc1", "2.0-beta9", 1},
+		{"2.0-M1", "2.0-RC1", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0-rc1", "1.0-SNAPSHOT", -1},
+		{"1.0-sp1", "1.0", 1},
+		{"1.0-sp1", "1.0.1", -1},
+		{"2.15.0-rc1", "2.15.0", -1},
+		{"2.17.1", "2.17", 1},
+		{"1.0a1", "1.0-alpha-1", 0},
+	}
+	for _, tc := range tt {
+		if got := compareVersions(tc.A, tc.B); got != tc.Want {
+			t.Errorf("%s <=> %s: got %d, want %d", tc.A, tc.B, got, tc.Want)
+		}
+		if got := compareVersions(tc.B, tc.A); got != -tc.Want {
+			t.Errorf("%s <=> %s: got %d, want %d", tc.B, tc.A, got, -tc.Want)
+		}
+	}
+}
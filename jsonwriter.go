@@ -0,0 +1,195 @@
+package claircore
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// JSONWriter holds the state for hand-encoding the large maps in reports.
+//
+// Encoding a report through encoding/json directly means copying every
+// Environment to sort it and letting the encoder sort and box every map key.
+// For reports with hundreds of thousands of packages that's most of the
+// allocation, so the top level of a report is written by hand and only the
+// leaf values go through a json.Encoder.
+//
+// JSONWriters are pooled, along with their scratch space.
+type jsonWriter struct {
+	buf  bytes.Buffer
+	enc  *json.Encoder
+	keys []string
+	envs []*Environment
+}
+
+var jsonWriterPool = sync.Pool{
+	New: func() interface{} {
+		w := new(jsonWriter)
+		w.enc = json.NewEncoder(&w.buf)
+		return w
+	},
+}
+
+func getJSONWriter() *jsonWriter {
+	w := jsonWriterPool.Get().(*jsonWriter)
+	w.buf.Reset()
+	return w
+}
+
+func (w *jsonWriter) put() {
+	for i := range w.keys {
+		w.keys[i] = ""
+	}
+	for i := range w.envs {
+		w.envs[i] = nil
+	}
+	w.keys = w.keys[:0]
+	w.envs = w.envs[:0]
+	jsonWriterPool.Put(w)
+}
+
+// Bytes returns a copy of the encoded bytes.
+func (w *jsonWriter) bytes() []byte {
+	out := make([]byte, w.buf.Len())
+	copy(out, w.buf.Bytes())
+	return out
+}
+
+// Value encodes v with encoding/json.
+func (w *jsonWriter) value(v interface{}) error {
+	if err := w.enc.Encode(v); err != nil {
+		return err
+	}
+	// Encode always adds a newline.
+	w.buf.Truncate(w.buf.Len() - 1)
+	return nil
+}
+
+// String writes s as a JSON string, escaped exactly as encoding/json does.
+func (w *jsonWriter) string(s string) error {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 0x20, c > 0x7e, c == '"', c == '\\', c == '<', c == '>', c == '&':
+			// Needs escaping, so let encoding/json do it.
+			return w.value(s)
+		}
+	}
+	w.buf.WriteByte('"')
+	w.buf.WriteString(s)
+	w.buf.WriteByte('"')
+	return nil
+}
+
+// Key writes the object key k, preceded by a comma if it's not the first.
+func (w *jsonWriter) key(k string, first bool) error {
+	if !first {
+		w.buf.WriteByte(',')
+	}
+	if err := w.string(k); err != nil {
+		return err
+	}
+	w.buf.WriteByte(':')
+	return nil
+}
+
+// SortKeys sets w.keys to the sorted keys of a map, added by the "add"
+// function. Map keys are sorted the same way encoding/json sorts them.
+func (w *jsonWriter) sortKeys(add func()) {
+	w.keys = w.keys[:0]
+	add()
+	sort.Strings(w.keys)
+}
+
+// Environments writes the map with its Environment slices, and the
+// RepositoryIDs within them, in a stable order. See sortEnvironments.
+//
+// Environments are only copied if their RepositoryIDs need sorting.
+func (w *jsonWriter) environments(m map[string][]*Environment) error {
+	if m == nil {
+		w.buf.WriteString("null")
+		return nil
+	}
+	w.sortKeys(func() {
+		for k := range m {
+			w.keys = append(w.keys, k)
+		}
+	})
+	w.buf.WriteByte('{')
+	for i, k := range w.keys {
+		if err := w.key(k, i == 0); err != nil {
+			return err
+		}
+		es := m[k]
+		if es == nil {
+			w.buf.WriteString("null")
+			continue
+		}
+		w.envs = w.envs[:0]
+		for _, e := range es {
+			if e != nil && !sort.StringsAreSorted(e.RepositoryIDs) {
+				c := *e
+				c.RepositoryIDs = append([]string(nil), e.RepositoryIDs...)
+				sort.Strings(c.RepositoryIDs)
+				e = &c
+			}
+			w.envs = append(w.envs, e)
+		}
+		s := w.envs
+		if len(s) > 1 {
+			sort.SliceStable(s, func(i, j int) bool { return envLess(s[i], s[j]) })
+		}
+		w.buf.WriteByte('[')
+		for j, e := range s {
+			if j != 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.environment(e); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+// Environment writes a single Environment, in the same form encoding/json
+// would.
+func (w *jsonWriter) environment(e *Environment) error {
+	if e == nil {
+		w.buf.WriteString("null")
+		return nil
+	}
+	w.buf.WriteString(`{"package_db":`)
+	if err := w.string(e.PackageDB); err != nil {
+		return err
+	}
+	w.buf.WriteString(`,"introduced_in":`)
+	if err := w.string(e.IntroducedIn.String()); err != nil {
+		return err
+	}
+	w.buf.WriteString(`,"distribution_id":`)
+	if err := w.string(e.DistributionID); err != nil {
+		return err
+	}
+	w.buf.WriteString(`,"repository_ids":`)
+	// Sorting makes a copy of the slice, which has always turned an empty
+	// slice into a nil one.
+	if len(e.RepositoryIDs) == 0 {
+		w.buf.WriteString("null")
+	} else {
+		w.buf.WriteByte('[')
+		for i, id := range e.RepositoryIDs {
+			if i != 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.string(id); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
@@ -18,6 +18,10 @@ type Layer struct {
 	Hash    Digest              `json:"hash"`
 	URI     string              `json:"uri"`
 	Headers map[string][]string `json:"headers"`
+	// DiffID is the digest of the layer's uncompressed content, as listed in
+	// the image config. If provided, the fetched content is verified against
+	// it; otherwise, it's filled in when the layer is fetched.
+	DiffID *Digest `json:"diff_id,omitempty"`
 
 	// path to local file containing uncompressed tar archive of the layer's content
 	localPath string
@@ -63,6 +67,26 @@ func normalizeIn(in, p string) string {
 // found.
 var ErrNotFound = errors.New("claircore: unable to find any requested files")
 
+// ErrLayerTooLarge is returned when a layer exceeds a configured resource
+// limit, such as its decompressed size or the number of entries in its
+// archive. Errors caused by the contents of a layer, rather than the
+// infrastructure fetching it, wrap this error.
+var ErrLayerTooLarge = errors.New("claircore: layer too large")
+
+// DiffIDMismatchError is returned when the uncompressed content of a layer
+// doesn't match the DiffID provided for it.
+type DiffIDMismatchError struct {
+	// Layer is the digest of the layer as fetched.
+	Layer Digest
+	// Want is the provided DiffID and Got is the digest of the content.
+	Want, Got Digest
+}
+
+// Error implements error.
+func (e *DiffIDMismatchError) Error() string {
+	return fmt.Sprintf("claircore: layer %v: diffID mismatch: got %v, want %v", e.Layer, e.Got, e.Want)
+}
+
 // Files retrieves specific files from the layer's tar archive.
 //
 // An error is returned only if none of the requested files are found.
@@ -106,7 +130,14 @@ func (l *Layer) Files(paths ...string) (map[string]*bytes.Buffer, error) {
 
 			switch hdr.Typeflag {
 			case tar.TypeLink, tar.TypeSymlink:
+				// A symlink's target is relative to its directory, but a
+				// hardlink's is relative to the root of the archive. Layers
+				// exported from ostree commits are mostly hardlinks into the
+				// ostree repository.
 				n := normalizeIn(filepath.Join("/", filepath.Dir(name)), hdr.Linkname)
+				if hdr.Typeflag == tar.TypeLink {
+					n = normalizeIn("/", hdr.Linkname)
+				}
 				if _, ok := f[n]; !ok { // If we don't already have it, add to the want set.
 					want[n] = struct{}{}
 					again = true
@@ -0,0 +1,126 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrIndexBusy is returned, possibly wrapped, by Index when admission control
+// is configured and the manifest couldn't be admitted: either the queue of
+// waiting calls was full, or the call waited longer than IndexQueueTimeout.
+//
+// Callers should retry later.
+var ErrIndexBusy = errors.New("libindex: too many manifests being indexed")
+
+var (
+	indexQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "index_queue_depth",
+			Help:      "Number of Index calls waiting to be admitted.",
+		},
+	)
+	indexQueueWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "index_queue_wait_seconds",
+			Help:      "Time Index calls spent waiting to be admitted.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+	)
+	indexRejectedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "index_rejected_total",
+			Help:      "Total number of Index calls rejected by admission control.",
+		},
+		[]string{"reason"},
+	)
+)
+
+// admission bounds the number of manifests being indexed at once.
+//
+// Calls beyond the limit wait in a bounded queue; calls that find the queue
+// full, or that wait too long, are turned away with ErrIndexBusy. A nil
+// *admission admits everything.
+type admission struct {
+	slots   chan struct{}
+	queue   chan struct{}
+	timeout time.Duration
+}
+
+// newAdmission returns an admission for the limits in "o", or nil if
+// admission control isn't configured.
+func newAdmission(o *Opts) *admission {
+	if o.MaxConcurrentIndex <= 0 {
+		return nil
+	}
+	q := o.IndexQueueSize
+	if q < 0 {
+		q = 0
+	}
+	return &admission{
+		slots:   make(chan struct{}, o.MaxConcurrentIndex),
+		queue:   make(chan struct{}, q),
+		timeout: o.IndexQueueTimeout,
+	}
+}
+
+// acquire blocks until a slot is available, the queue timeout elapses, or the
+// Context is canceled. On success, the returned function must be called to
+// release the slot.
+func (a *admission) acquire(ctx context.Context) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	select {
+	case a.slots <- struct{}{}:
+		indexQueueWait.Observe(0)
+		return a.release, nil
+	default:
+	}
+
+	select {
+	case a.queue <- struct{}{}:
+	default:
+		indexRejectedCounter.WithLabelValues("queue_full").Add(1)
+		return nil, ErrIndexBusy
+	}
+	indexQueueDepth.Inc()
+	defer func() {
+		<-a.queue
+		indexQueueDepth.Dec()
+	}()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if a.timeout > 0 {
+		t := time.NewTimer(a.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case a.slots <- struct{}{}:
+		indexQueueWait.Observe(time.Since(start).Seconds())
+		return a.release, nil
+	case <-timeout:
+		indexQueueWait.Observe(time.Since(start).Seconds())
+		indexRejectedCounter.WithLabelValues("timeout").Add(1)
+		return nil, fmt.Errorf("%w: waited %v for a slot", ErrIndexBusy, a.timeout)
+	case <-ctx.Done():
+		indexQueueWait.Observe(time.Since(start).Seconds())
+		return nil, ctx.Err()
+	}
+}
+
+func (a *admission) release() {
+	<-a.slots
+}
@@ -0,0 +1,204 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/controller"
+)
+
+// slowFactory returns a ControllerFactory standing in for a slow scan: it
+// records how many calls are running at once, reports on "entered" once a call
+// is counted, and blocks until "release" is closed, then fails so Index
+// returns without touching the store. The "entered" channel needs room for
+// every admitted call.
+func slowFactory(running, peak, calls *int32, entered chan<- struct{}, release <-chan struct{}) ControllerFactory {
+	return func(ctx context.Context, _ *Libindex, _ *Opts) (*controller.Controller, error) {
+		atomic.AddInt32(calls, 1)
+		n := atomic.AddInt32(running, 1)
+		defer atomic.AddInt32(running, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		entered <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil, errors.New("slow factory")
+	}
+}
+
+// notIndexedStore returns a Store that never has a report, so every Index call
+// misses the fast path.
+func notIndexedStore(t *testing.T) indexer.Store {
+	ctrl := gomock.NewController(t)
+	s := indexer.NewMockStore(ctrl)
+	s.EXPECT().
+		IndexReportState(gomock.Any(), gomock.Any()).
+		Return("", false, nil).
+		AnyTimes()
+	return s
+}
+
+// awaitEntered waits for "n" calls to report on "entered".
+func awaitEntered(t *testing.T, entered <-chan struct{}, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-entered:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for call %d to start", i+1)
+		}
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &claircore.Manifest{Hash: digest("manifest")}
+
+	t.Run("Flood", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		const (
+			max   = 3
+			queue = 5
+			n     = 20
+		)
+		var running, peak, calls int32
+		entered := make(chan struct{}, n)
+		release := make(chan struct{})
+		opts := &Opts{
+			MaxConcurrentIndex: max,
+			IndexQueueSize:     queue,
+			IndexQueueTimeout:  time.Minute,
+			ControllerFactory:  slowFactory(&running, &peak, &calls, entered, release),
+		}
+		l := &Libindex{
+			Opts:  opts,
+			store: notIndexedStore(t),
+			admit: newAdmission(opts),
+		}
+
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				_, err := l.Index(ctx, m)
+				errs <- err
+			}()
+		}
+		// Everything past the slots and the queue is turned away without
+		// waiting.
+		for i := 0; i < n-max-queue; i++ {
+			select {
+			case err := <-errs:
+				if !errors.Is(err, ErrIndexBusy) {
+					t.Errorf("got error %v, want %v", err, ErrIndexBusy)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for rejections")
+			}
+		}
+		// Every slot is taken, and the queued calls can't start until one is
+		// released.
+		awaitEntered(t, entered, max)
+		if got, want := atomic.LoadInt32(&running), int32(max); got != want {
+			t.Errorf("got %d running, want %d", got, want)
+		}
+		close(release)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if errors.Is(err, ErrIndexBusy) {
+				t.Errorf("admitted call failed with %v", err)
+			}
+		}
+		if got, want := atomic.LoadInt32(&peak), int32(max); got != want {
+			t.Errorf("got peak concurrency %d, want %d", got, want)
+		}
+		if got, want := atomic.LoadInt32(&calls), int32(max+queue); got != want {
+			t.Errorf("got %d admitted calls, want %d", got, want)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var running, peak, calls int32
+		entered := make(chan struct{}, 1)
+		release := make(chan struct{})
+		opts := &Opts{
+			MaxConcurrentIndex: 1,
+			IndexQueueSize:     1,
+			IndexQueueTimeout:  50 * time.Millisecond,
+			ControllerFactory:  slowFactory(&running, &peak, &calls, entered, release),
+		}
+		l := &Libindex{
+			Opts:  opts,
+			store: notIndexedStore(t),
+			admit: newAdmission(opts),
+		}
+
+		held := make(chan struct{})
+		go func() {
+			defer close(held)
+			l.Index(ctx, m)
+		}()
+		defer func() {
+			close(release)
+			<-held
+		}()
+		awaitEntered(t, entered, 1)
+		start := time.Now()
+		_, err := l.Index(ctx, m)
+		if !errors.Is(err, ErrIndexBusy) {
+			t.Errorf("got error %v, want %v", err, ErrIndexBusy)
+		}
+		if time.Since(start) < opts.IndexQueueTimeout {
+			t.Error("returned before the queue timeout")
+		}
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		const n = 10
+		var running, peak, calls int32
+		entered := make(chan struct{}, n)
+		release := make(chan struct{})
+		opts := &Opts{
+			ControllerFactory: slowFactory(&running, &peak, &calls, entered, release),
+		}
+		l := &Libindex{
+			Opts:  opts,
+			store: notIndexedStore(t),
+			admit: newAdmission(opts),
+		}
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				if _, err := l.Index(ctx, m); errors.Is(err, ErrIndexBusy) {
+					t.Error(err)
+				}
+			}()
+		}
+		// Every call runs at once.
+		awaitEntered(t, entered, n)
+		close(release)
+		wg.Wait()
+	})
+}
@@ -0,0 +1,220 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/fetcher"
+	"github.com/quay/claircore/pkg/distlock"
+)
+
+// blockingStore returns a Store whose ManifestScanned method reports on
+// "started", and then holds the operation until "release" is closed. If
+// "cancel" is set, the operation also returns once its Context is canceled.
+//
+// Manifests aren't indexed until "release" is closed. The Store expects to be
+// closed "closes" times.
+func blockingStore(t *testing.T, started chan<- struct{}, release <-chan struct{}, cancel bool, closes int) indexer.Store {
+	ctrl := gomock.NewController(t)
+	s := indexer.NewMockStore(ctrl)
+	s.EXPECT().
+		ManifestScanned(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ claircore.Digest, _ indexer.VersionedScanners) (bool, error) {
+			started <- struct{}{}
+			var done <-chan struct{}
+			if cancel {
+				done = ctx.Done()
+			}
+			select {
+			case <-release:
+				return true, nil
+			case <-done:
+				return false, ctx.Err()
+			}
+		}).
+		AnyTimes()
+	s.EXPECT().
+		IndexReportState(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, claircore.Digest) (string, bool, error) {
+			select {
+			case <-release:
+				return "", true, nil
+			default:
+				return "", false, nil
+			}
+		}).
+		AnyTimes()
+	s.EXPECT().
+		IndexReport(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			select {
+			case <-release:
+				return &claircore.IndexReport{Success: true, State: "IndexFinished"}, true, nil
+			default:
+				return nil, false, nil
+			}
+		}).
+		AnyTimes()
+	s.EXPECT().SetIndexReport(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	s.EXPECT().Close(gomock.Any()).Return(nil).Times(closes)
+	return s
+}
+
+// scratchDirs reports the number of operation directories in "root".
+func scratchDirs(t *testing.T, root string) int {
+	t.Helper()
+	ents, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, e := range ents {
+		if e.IsDir() {
+			n++
+		}
+	}
+	return n
+}
+
+func TestClose(t *testing.T) {
+	const n = 8
+	m := &claircore.Manifest{Hash: digest("manifest")}
+
+	tt := []struct {
+		Name string
+		// Finish lets the operations complete after Close is called.
+		Finish bool
+		// Abandon cancels the Context passed to Close, and has the operations
+		// ignore cancellation.
+		Abandon bool
+	}{
+		{Name: "Drain", Finish: true},
+		{Name: "Cancel"},
+		{Name: "Abandon", Abandon: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			root, err := ioutil.TempDir("", "libindex-close.")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(root) })
+			arena, err := fetcher.NewArena(ctx, root, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			started := make(chan struct{}, n)
+			release := make(chan struct{})
+			closes := 1
+			if tc.Abandon {
+				closes = 0
+			}
+			// Operations that wait to finish are never canceled by the
+			// timeout, and ones that don't are canceled at once.
+			timeout := time.Hour
+			if !tc.Finish && !tc.Abandon {
+				timeout = time.Nanosecond
+			}
+			ctrl := gomock.NewController(t)
+			l := &Libindex{
+				Opts: &Opts{
+					DrainTimeout:         timeout,
+					LayerScanConcurrency: 1,
+					ControllerFactory:    controllerFactory,
+				},
+				store:  blockingStore(t, started, release, !tc.Abandon, closes),
+				client: http.DefaultClient,
+				lockerFactoryFunc: func() distlock.Locker {
+					l := distlock.NewMockLocker(ctrl)
+					l.EXPECT().Lock(gomock.Any(), gomock.Any()).Return(nil)
+					l.EXPECT().Unlock().Return(nil)
+					return l
+				},
+				arena: arena,
+			}
+
+			type result struct {
+				ir  *claircore.IndexReport
+				err error
+			}
+			res := make(chan result, n)
+			for i := 0; i < n; i++ {
+				go func() {
+					ir, err := l.Index(ctx, m)
+					res <- result{ir, err}
+				}()
+			}
+			for i := 0; i < n; i++ {
+				<-started
+			}
+			if got := scratchDirs(t, root); got != n {
+				t.Fatalf("got %d scratch directories, want %d", got, n)
+			}
+
+			cctx := ctx
+			if tc.Abandon {
+				var cancel context.CancelFunc
+				cctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+			closed := make(chan error, 1)
+			go func() { closed <- l.Close(cctx) }()
+			if tc.Finish {
+				// New operations are refused as soon as Close is called.
+				for {
+					_, _, err := l.IndexReport(ctx, m.Hash)
+					if errors.Is(err, ErrClosed) {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+				select {
+				case err := <-closed:
+					t.Fatalf("Close returned before operations finished: %v", err)
+				default:
+				}
+				close(release)
+			}
+			err = <-closed
+			switch {
+			case tc.Abandon && !errors.Is(err, context.Canceled):
+				t.Fatalf("got: %v, want: %v", err, context.Canceled)
+			case !tc.Abandon && err != nil:
+				t.Fatal(err)
+			}
+			// The scratch space is cleaned up even if operations are still
+			// running.
+			if got := scratchDirs(t, root); got != 0 {
+				t.Errorf("got %d scratch directories, want 0", got)
+			}
+			if tc.Abandon {
+				close(release)
+			}
+
+			for i := 0; i < n; i++ {
+				r := <-res
+				switch {
+				case r.err != nil:
+					t.Errorf("unexpected error: %v", r.err)
+				case tc.Finish && !r.ir.Success:
+					t.Errorf("unexpected report: %+v", r.ir)
+				case !tc.Finish && !tc.Abandon && !strings.Contains(r.ir.Err, context.Canceled.Error()):
+					t.Errorf("got: %q, want: %v", r.ir.Err, context.Canceled)
+				}
+			}
+		})
+	}
+}
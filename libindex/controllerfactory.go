@@ -14,7 +14,17 @@ type ControllerFactory func(_ context.Context, lib *Libindex, opts *Opts) (*cont
 
 // controllerFactory is the default ControllerFactory
 func controllerFactory(ctx context.Context, lib *Libindex, opts *Opts) (*controller.Controller, error) {
-	ft := fetcher.New(lib.client, opts.LayerFetchOpt)
+	var ft indexer.Fetcher
+	var err error
+	lim := indexer.LayerLimits(opts.LayerLimits)
+	if lib.arena != nil {
+		ft, err = lib.arena.Fetcher(lib.client, opts.LayerFetchOpt, &lim, opts.Authorizer, opts.Redaction)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ft = fetcher.New(lib.client, opts.LayerFetchOpt, &lim, opts.Authorizer, opts.Redaction)
+	}
 
 	// convert libindex.Opts to indexer.Opts
 	sOpts := &indexer.Opts{
@@ -23,12 +33,14 @@ func controllerFactory(ctx context.Context, lib *Libindex, opts *Opts) (*control
 		Fetcher:       ft,
 		Ecosystems:    opts.Ecosystems,
 		Vscnrs:        lib.vscnrs,
+		State:         lib.state,
 		Client:        lib.client,
 		ScannerConfig: opts.ScannerConfig,
+		InventoryOnly: opts.InventoryOnly,
 	}
-	var err error
 	sOpts.LayerScanner, err = layerscanner.New(ctx, opts.LayerScanConcurrency, sOpts)
 	if err != nil {
+		ft.Close()
 		return nil, err
 	}
 
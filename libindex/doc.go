@@ -0,0 +1,6 @@
+// Package libindex is the indexing half of claircore: it fetches and scans
+// the layers of manifests and reports what's in them.
+//
+// The exported API of this package is kept compatible between releases. See
+// the claircore package documentation.
+package libindex
@@ -0,0 +1,232 @@
+package libindex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/log/testingadapter"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/libindex/migrations"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestExportImport scans a manifest with one instance, moves the layer
+// artifacts to an instance with a fresh database, and checks that the second
+// instance indexes the manifest to the same result without fetching or
+// scanning any layers.
+func TestExportImport(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+	const nLayers = 2
+
+	layers := test.ServeLayers(ctx, t, nLayers)
+	pkgs := test.GenUniquePackages(3)
+	src := newMockScanner(ctrl)
+	src.EXPECT().Scan(gomock.Any(), gomock.Any()).Times(nLayers).Return(pkgs, nil)
+	srcLib := newExportTestLibindex(ctx, t, src)
+	defer srcLib.Close(ctx)
+	m := &claircore.Manifest{
+		Hash:   test.RandomSHA256Digest(t),
+		Layers: layers,
+	}
+	want, err := srcLib.Index(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !want.Success {
+		t.Fatalf("index failed: %v", want.Err)
+	}
+
+	// The destination scanner has no Scan expectations, so any scan fails
+	// the test, and the layers point nowhere, so any fetch fails the index.
+	dst := newMockScanner(ctrl)
+	dstLib := newExportTestLibindex(ctx, t, dst)
+	defer dstLib.Close(ctx)
+	dm := &claircore.Manifest{Hash: m.Hash}
+	for _, l := range layers {
+		var buf bytes.Buffer
+		if err := srcLib.ExportLayer(ctx, l.Hash, &buf); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("layer %v: %d bytes", l.Hash, buf.Len())
+		if err := dstLib.ImportLayer(ctx, l.Hash, &buf); err != nil {
+			t.Fatal(err)
+		}
+		dm.Layers = append(dm.Layers, &claircore.Layer{
+			Hash: l.Hash,
+			URI:  "http://127.0.0.1:1/unreachable",
+		})
+	}
+	got, err := dstLib.Index(ctx, dm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Success {
+		t.Fatalf("index failed: %v", got.Err)
+	}
+	if g, w := reportPackages(got), reportPackages(want); !cmp.Equal(g, w) {
+		t.Error(cmp.Diff(g, w))
+	}
+
+	t.Run("WrongLayer", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var buf bytes.Buffer
+		if err := srcLib.ExportLayer(ctx, layers[0].Hash, &buf); err != nil {
+			t.Fatal(err)
+		}
+		err := dstLib.ImportLayer(ctx, layers[1].Hash, &buf)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error importing artifacts for a different layer")
+		}
+	})
+	t.Run("ScannerVersion", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var buf bytes.Buffer
+		if err := srcLib.ExportLayer(ctx, layers[0].Hash, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var e indexer.LayerEnvelope
+		if err := e.Decode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		e.Artifacts[0].ScannerVersion = "v0.0.0"
+		buf.Reset()
+		if err := e.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		err := dstLib.ImportLayer(ctx, layers[0].Hash, &buf)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error importing artifacts from a different scanner version")
+		}
+	})
+	t.Run("NotScanned", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var buf bytes.Buffer
+		err := srcLib.ExportLayer(ctx, test.RandomSHA256Digest(t), &buf)
+		t.Log(err)
+		if err == nil {
+			t.Error("expected error exporting an unknown layer")
+		}
+	})
+}
+
+func newMockScanner(ctrl *gomock.Controller) *indexer.MockPackageScanner {
+	s := indexer.NewMockPackageScanner(ctrl)
+	s.EXPECT().Name().AnyTimes().Return("export-test-scanner")
+	s.EXPECT().Version().AnyTimes().Return("v0.0.1")
+	s.EXPECT().Kind().AnyTimes().Return("package")
+	return s
+}
+
+// NewExportTestLibindex returns a Libindex using a fresh database and an
+// ecosystem consisting of only the provided scanner.
+func newExportTestLibindex(ctx context.Context, t *testing.T, s indexer.PackageScanner) *Libindex {
+	return newTestLibindex(ctx, t, newTestDB(ctx, t), s)
+}
+
+// NewTestDB creates a fresh, migrated database and returns its connection
+// string.
+func newTestDB(ctx context.Context, t *testing.T) string {
+	const dsnFmt = `host=%s port=%d database=%s user=%s password=%s sslmode=disable`
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	cfg.ConnConfig.LogLevel = pgx.LogLevelError
+	cfg.ConnConfig.Logger = testingadapter.NewLogger(t)
+	mdb := stdlib.OpenDB(*cfg.ConnConfig)
+	defer mdb.Close()
+	migrator := migrate.NewPostgresMigrator(mdb)
+	migrator.Table = migrations.MigrationTable
+	if err := migrator.Exec(migrate.Up, migrations.Migrations...); err != nil {
+		t.Fatalf("failed to perform migrations: %v", err)
+	}
+	return fmt.Sprintf(dsnFmt,
+		cfg.ConnConfig.Host,
+		cfg.ConnConfig.Port,
+		cfg.ConnConfig.Database,
+		cfg.ConnConfig.User,
+		cfg.ConnConfig.Password)
+}
+
+// NewTestLibindex returns a Libindex using the database at "dsn" and an
+// ecosystem consisting of only the provided scanner.
+func newTestLibindex(ctx context.Context, t *testing.T, dsn string, s indexer.PackageScanner) *Libindex {
+	opts := &Opts{
+		ConnString:           dsn,
+		ScanLockRetry:        time.Second,
+		LayerScanConcurrency: 1,
+		Ecosystems: []*indexer.Ecosystem{
+			{
+				Name: "export-test",
+				PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+					return []indexer.PackageScanner{s}, nil
+				},
+				DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+				RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+				Coalescer: func(context.Context) (indexer.Coalescer, error) {
+					return &exportTestCoalescer{}, nil
+				},
+			},
+		},
+	}
+	lib, err := New(ctx, opts, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to create libindex instance: %v", err)
+	}
+	return lib
+}
+
+// ExportTestCoalescer reports every package in every layer.
+type exportTestCoalescer struct{}
+
+func (*exportTestCoalescer) Coalesce(_ context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+	}
+	for _, l := range ls {
+		for _, p := range l.Pkgs {
+			ir.Packages[p.ID] = p
+			ir.Environments[p.ID] = append(ir.Environments[p.ID], &claircore.Environment{
+				PackageDB:    p.PackageDB,
+				IntroducedIn: l.Hash,
+			})
+		}
+	}
+	return ir, nil
+}
+
+// ReportPackages returns a store-independent summary of the packages in the
+// report, as "name version package_db layer" strings.
+func reportPackages(ir *claircore.IndexReport) []string {
+	var out []string
+	for id, p := range ir.Packages {
+		for _, e := range ir.Environments[id] {
+			out = append(out, fmt.Sprintf("%s %s %s %v", p.Name, p.Version, e.PackageDB, e.IntroducedIn))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
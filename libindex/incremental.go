@@ -0,0 +1,122 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"strconv"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+var (
+	// ErrPreviousNotIndexed is wrapped by errors returned from
+	// IndexIncremental when the previous manifest doesn't have a finished
+	// IndexReport produced by the current configuration.
+	ErrPreviousNotIndexed = errors.New("libindex: previous manifest not indexed")
+	// ErrLayersDiverged is wrapped by errors returned from IndexIncremental
+	// when the layers of the previous manifest aren't a prefix of the new
+	// manifest's layers.
+	ErrLayersDiverged = errors.New("libindex: manifest doesn't extend previous manifest")
+)
+
+// IndexIncremental indexes a Manifest that was built on top of a previously
+// indexed one, such as a new build of an application image on an unchanged
+// base.
+//
+// The layers of the manifest identified by "previous" must be the first
+// layers of "manifest". Those layers are neither fetched nor scanned: their
+// stored artifacts are used as-is, and only the layers after them are
+// fetched and scanned. The returned IndexReport covers every layer, the same
+// as one returned by Index.
+//
+// An error wrapping ErrPreviousNotIndexed is returned if the previous
+// manifest's IndexReport isn't stored, didn't finish successfully, or was
+// produced with a different State. An error wrapping ErrLayersDiverged is
+// returned if the previous manifest's layers aren't a prefix of the new
+// manifest's layers. Both are reported before any work is done; callers can
+// fall back to Index. If the previous IndexReport doesn't list its layers, as
+// is the case for reports stored by older versions, no layers are shared and
+// every layer is fetched and scanned.
+//
+// Otherwise, errors are reported the same way as Index.
+func (l *Libindex) IndexIncremental(ctx context.Context, manifest *claircore.Manifest, previous claircore.Digest) (*claircore.IndexReport, error) {
+	if err := checkManifest(manifest); err != nil {
+		return nil, err
+	}
+	if err := previous.Validate(); err != nil {
+		return nil, fmt.Errorf("libindex: invalid previous manifest digest: %w", err)
+	}
+	ctx, id := claircore.EnsureCorrelationID(ctx)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexIncremental"),
+		label.String("manifest", manifest.Hash.String()),
+		label.String("previous", previous.String()))
+	ctx, task := trace.NewTask(ctx, "libindex/Libindex.IndexIncremental")
+	defer task.End()
+	trace.Log(ctx, claircore.CorrelationIDKey, id)
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	zlog.Info(ctx).Msg("incremental index request start")
+	defer zlog.Info(ctx).Msg("incremental index request done")
+
+	prev, ok, err := l.indexed(ctx, previous)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, fmt.Errorf("%w: no finished report for %v with the current state", ErrPreviousNotIndexed, previous)
+	}
+	shared, err := sharedLayers(prev, manifest)
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("shared", shared).
+		Int("new", len(manifest.Layers)-shared).
+		Msg("layers shared with previous manifest")
+
+	ir, ok, err := l.indexed(ctx, manifest.Hash)
+	if err != nil {
+		return nil, err
+	}
+	indexFastPathCounter.WithLabelValues(strconv.FormatBool(ok)).Add(1)
+	if ok {
+		zlog.Info(ctx).Msg("manifest already indexed, returning stored report")
+		if err := verifyLayers(manifest, ir); err != nil {
+			return l.Opts.Redaction.IndexReport(ir), err
+		}
+		return l.Opts.Redaction.IndexReport(ir), nil
+	}
+	ir, err = l.runIndex(ctx, manifest, shared)
+	return l.Opts.Redaction.IndexReport(ir), err
+}
+
+// SharedLayers reports the number of layers "m" shares with the manifest
+// described by "prev", or an error wrapping ErrLayersDiverged if the layers
+// of "prev" aren't a prefix of the layers of "m".
+func sharedLayers(prev *claircore.IndexReport, m *claircore.Manifest) (int, error) {
+	if len(prev.Layers) > len(m.Layers) {
+		return 0, fmt.Errorf("%w: previous manifest %v has %d layers, %v has %d",
+			ErrLayersDiverged, prev.Hash, len(prev.Layers), m.Hash, len(m.Layers))
+	}
+	for i, id := range prev.Layers {
+		l := m.Layers[i]
+		if id.Hash.String() != l.Hash.String() {
+			return 0, fmt.Errorf("%w: layer %d is %v, previous manifest %v has %v",
+				ErrLayersDiverged, i, l.Hash, prev.Hash, id.Hash)
+		}
+		if l.DiffID != nil && id.DiffID != nil && l.DiffID.String() != id.DiffID.String() {
+			return 0, &claircore.DiffIDMismatchError{Layer: l.Hash, Want: *l.DiffID, Got: *id.DiffID}
+		}
+	}
+	return len(prev.Layers), nil
+}
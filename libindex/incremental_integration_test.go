@@ -0,0 +1,90 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestIndexIncremental indexes a base image and then two successive builds
+// on top of it, and checks that each build only fetches and scans its new
+// layer while reporting the contents of every layer.
+func TestIndexIncremental(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	layers := test.ServeLayers(ctx, t, 3)
+	pkgs := make(map[string][]*claircore.Package, len(layers))
+	for _, l := range layers {
+		pkgs[l.Hash.String()] = test.GenUniquePackages(2)
+	}
+	s := newMockScanner(ctrl)
+	// Every layer is scanned exactly once.
+	s.EXPECT().Scan(gomock.Any(), gomock.Any()).Times(len(layers)).
+		DoAndReturn(func(_ context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+			return pkgs[l.Hash.String()], nil
+		})
+	lib := newTestLibindex(ctx, t, newTestDB(ctx, t), s)
+	defer lib.Close(ctx)
+
+	// Shared returns a copy of the layer that can't be fetched, so the test
+	// fails if it's fetched again.
+	shared := func(l *claircore.Layer) *claircore.Layer {
+		return &claircore.Layer{Hash: l.Hash, URI: "http://127.0.0.1:1/unreachable"}
+	}
+	manifest := func(ls ...*claircore.Layer) *claircore.Manifest {
+		return &claircore.Manifest{Hash: test.RandomSHA256Digest(t), Layers: ls}
+	}
+	check := func(t *testing.T, ir *claircore.IndexReport, err error, n int) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ir.Success {
+			t.Fatalf("index failed: %v", ir.Err)
+		}
+		if got, want := len(ir.Layers), n; got != want {
+			t.Errorf("got %d layers, want %d", got, want)
+		}
+		if got, want := len(reportPackages(ir)), 2*n; got != want {
+			t.Errorf("got %d packages, want %d", got, want)
+		}
+	}
+
+	base := manifest(layers[0])
+	ir, err := lib.Index(ctx, base)
+	check(t, ir, err, 1)
+
+	app := manifest(shared(layers[0]), layers[1])
+	ir, err = lib.IndexIncremental(ctx, app, base.Hash)
+	check(t, ir, err, 2)
+
+	next := manifest(shared(layers[0]), shared(layers[1]), layers[2])
+	ir, err = lib.IndexIncremental(ctx, next, app.Hash)
+	check(t, ir, err, 3)
+
+	t.Run("NotIndexed", func(t *testing.T) {
+		_, err := lib.IndexIncremental(ctx, manifest(layers[0]), test.RandomSHA256Digest(t))
+		if !errors.Is(err, ErrPreviousNotIndexed) {
+			t.Errorf("got error %v, want %v", err, ErrPreviousNotIndexed)
+		}
+	})
+	t.Run("Diverged", func(t *testing.T) {
+		_, err := lib.IndexIncremental(ctx, manifest(layers[0], layers[2]), app.Hash)
+		if !errors.Is(err, ErrLayersDiverged) {
+			t.Errorf("got error %v, want %v", err, ErrLayersDiverged)
+		}
+	})
+}
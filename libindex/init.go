@@ -51,7 +51,7 @@ func initStore(ctx context.Context, pool *pgxpool.Pool, opts *Opts) (indexer.Sto
 		}
 	}
 
-	store := postgres.NewStore(pool)
+	store := postgres.NewStore(pool, postgres.WithNamespace(opts.Namespace))
 	return store, nil
 }
 
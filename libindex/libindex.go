@@ -8,8 +8,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"runtime/trace"
 	"sort"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
@@ -19,11 +25,24 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/controller"
+	"github.com/quay/claircore/internal/indexer/fetcher"
+	"github.com/quay/claircore/internal/inflight"
+	"github.com/quay/claircore/internal/multierr"
 	"github.com/quay/claircore/pkg/distlock"
 )
 
 const versionMagic = "libindex number: 2\n"
 
+var indexFastPathCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "claircore",
+		Subsystem: "indexer",
+		Name:      "index_fastpath_total",
+		Help:      "Total number of Index calls checked for a previously finished IndexReport.",
+	},
+	[]string{"hit"},
+)
+
 // Libindex implements the method set for scanning and indexing a Manifest.
 type Libindex struct {
 	// holds dependencies for creating a libindex instance
@@ -39,8 +58,17 @@ type Libindex struct {
 	// the same actions at the same time, the factory allows processes
 	// to grab a new lock when needed.
 	lockerFactoryFunc func() distlock.Locker
+	// tracks in-flight operations, so Close can wait for them.
+	inflight inflight.Tracker
+	// the scratch space fetchers write layers into.
+	arena *fetcher.Arena
+	// bounds the number of manifests being indexed at once.
+	admit *admission
 }
 
+// ErrClosed is returned by methods called after Close.
+var ErrClosed = inflight.ErrClosed
+
 // New creates a new instance of libindex.
 //
 // The passed http.Client will be used for fetching layers and any HTTP requests
@@ -48,21 +76,41 @@ type Libindex struct {
 func New(ctx context.Context, opts *Opts, cl *http.Client) (*Libindex, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libindex/New"))
-	err := opts.Parse(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse opts: %v", err)
-	}
+	// Check everything that can be checked before doing any work, so that
+	// every problem is reported at once.
+	errs := []error{opts.Parse(ctx)}
 	if cl == nil {
-		return nil, errors.New("invalid *http.Client")
+		errs = append(errs, errors.New("invalid *http.Client"))
 	}
 	// TODO(hank) If "airgap" is set, we should wrap the client and return
 	// errors on non-RFC1918 and non-RFC4193 addresses.
-
-	dbPool, err := initDB(ctx, opts)
-	if err != nil {
-		return nil, err
+	if checkScratchDir(opts.ScratchDir) == nil {
+		errs = append(errs, probeScratchDir(opts.ScratchDir))
+	}
+	var dbPool *pgxpool.Pool
+	if _, err := pgxpool.ParseConfig(opts.ConnString); opts.ConnString != "" && err == nil {
+		dbPool, err = initDB(ctx, opts)
+		errs = append(errs, err)
+	}
+	if err := multierr.Join(errs...); err != nil {
+		if dbPool != nil {
+			dbPool.Close()
+		}
+		return nil, fmt.Errorf("libindex: invalid configuration: %w", err)
 	}
 	zlog.Info(ctx).Msg("created database connection")
+	// Everything set up from here on is released if New fails.
+	var arena *fetcher.Arena
+	ok := false
+	defer func() {
+		if ok {
+			return
+		}
+		if arena != nil {
+			arena.Close()
+		}
+		dbPool.Close()
+	}()
 
 	store, err := initStore(ctx, dbPool, opts)
 	if err != nil {
@@ -71,11 +119,18 @@ func New(ctx context.Context, opts *Opts, cl *http.Client) (*Libindex, error) {
 
 	lockFactory := initLockFactory(ctx, dbPool, opts)
 
+	arena, err = fetcher.NewArena(ctx, opts.ScratchDir, opts.ScratchMaxAge)
+	if err != nil {
+		return nil, err
+	}
+
 	l := &Libindex{
 		Opts:              opts,
 		store:             store,
 		client:            cl,
 		lockerFactoryFunc: lockFactory,
+		arena:             arena,
+		admit:             newAdmission(opts),
 	}
 
 	// register any new scanners.
@@ -98,32 +153,173 @@ func New(ctx context.Context, opts *Opts, cl *http.Client) (*Libindex, error) {
 
 	zlog.Info(ctx).Msg("registered configured scanners")
 	l.Opts.vscnrs = vscnrs
+	zlog.Info(ctx).
+		Interface("claircore", claircore.ReadBuildInfo()).
+		Msg("libindex initialized")
+	ok = true
 	return l, nil
 }
 
+// Close stops new operations from starting, waits for in-flight operations,
+// and then releases the database connections.
+//
+// In-flight operations are canceled if they don't finish within the
+// configured DrainTimeout. If the passed Context is canceled before they
+// return, the Context's error is reported and the database connections are
+// not released, but the layers fetched by those operations are still removed.
 func (l *Libindex) Close(ctx context.Context) error {
-	l.store.Close(ctx)
-	return nil
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.Close"))
+	err := l.inflight.Close(ctx, l.DrainTimeout)
+	if l.arena != nil {
+		if err := l.arena.Close(); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("failed to clean up scratch directory")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("libindex: waiting for in-flight operations: %w", err)
+	}
+	zlog.Debug(ctx).Msg("in-flight operations drained")
+	return l.store.Close(ctx)
 }
 
 // Index performs a scan and index of each layer within the provided Manifest.
 //
+// If the manifest or any of its layers has a malformed digest, an error
+// matching claircore.ErrInvalidDigest is returned before any work is done.
 // If the index operation cannot start an error will be returned.
 // If an error occurs during scan the error will be propagated inside the IndexReport.
+//
+// If a layer has a DiffID that doesn't match its uncompressed content, the
+// IndexReport is returned along with a *claircore.DiffIDMismatchError. Layers
+// without a DiffID have theirs computed and listed in the IndexReport.
+//
+// If a layer exceeds the configured LayerLimits, the IndexReport is returned
+// along with an error wrapping claircore.ErrLayerTooLarge.
+//
+// Every log line written during the call carries the Context's correlation
+// ID, which is generated if it doesn't have one; see
+// claircore.WithCorrelationID.
 func (l *Libindex) Index(ctx context.Context, manifest *claircore.Manifest) (*claircore.IndexReport, error) {
+	if err := checkManifest(manifest); err != nil {
+		return nil, err
+	}
+	ctx, id := claircore.EnsureCorrelationID(ctx)
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libindex/Libindex.Index"),
 		label.String("manifest", manifest.Hash.String()))
+	ctx, task := trace.NewTask(ctx, "libindex/Libindex.Index")
+	defer task.End()
+	trace.Log(ctx, claircore.CorrelationIDKey, id)
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	zlog.Info(ctx).Msg("index request start")
 	defer zlog.Info(ctx).Msg("index request done")
+	start := time.Now()
+	ir, ok, err := l.indexed(ctx, manifest.Hash)
+	if err != nil {
+		return nil, err
+	}
+	indexFastPathCounter.WithLabelValues(strconv.FormatBool(ok)).Add(1)
+	if ok {
+		zlog.Info(ctx).Msg("manifest already indexed, returning stored report")
+		ir.Cost = &claircore.IndexCost{
+			Phases: []claircore.PhaseCost{
+				{Phase: controller.CheckManifest.String(), Elapsed: time.Since(start)},
+			},
+		}
+		if err := verifyLayers(manifest, ir); err != nil {
+			return l.Opts.Redaction.IndexReport(ir), err
+		}
+		return l.Opts.Redaction.IndexReport(ir), nil
+	}
+	ir, err = l.runIndex(ctx, manifest, 0)
+	return l.Opts.Redaction.IndexReport(ir), err
+}
+
+// RunIndex waits for admission and indexes the manifest with a new
+// controller, skipping the fetch and scan of the first "shared" layers.
+func (l *Libindex) runIndex(ctx context.Context, manifest *claircore.Manifest, shared int) (*claircore.IndexReport, error) {
+	release, err := l.admit.acquire(ctx)
+	if err != nil {
+		zlog.Info(ctx).Err(err).Msg("index request not admitted")
+		return nil, err
+	}
+	defer release()
 	c, err := l.ControllerFactory(ctx, l, l.Opts)
 	if err != nil {
 		return nil, fmt.Errorf("scanner factory failed to construct a scanner: %v", err)
 	}
-	rc := l.index(ctx, c, manifest)
+	rc := l.index(ctx, c, manifest, shared)
+	if err := c.Err(); returnedErr(err) {
+		return rc, err
+	}
 	return rc, nil
 }
 
+// ReturnedErr reports whether an error from indexing a manifest is returned
+// to the caller, rather than only reported in the IndexReport.
+func returnedErr(err error) bool {
+	var me *claircore.DiffIDMismatchError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &me):
+	case errors.Is(err, claircore.ErrLayerTooLarge):
+	default:
+		return false
+	}
+	return true
+}
+
+// Indexed reports the stored IndexReport for the manifest if the manifest has
+// been successfully indexed by every currently configured scanner, meaning
+// the report is the one the current State would produce.
+//
+// Reports record the State they were produced with, so that's compared when
+// present. Older reports fall back to checking each scanner against the
+// scanned manifest table. The report itself is only retrieved once these
+// checks pass.
+//
+// This check happens before a controller is constructed or the manifest lock
+// is taken, so re-submissions of the same manifest don't touch any artifact
+// tables.
+func (l *Libindex) indexed(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
+	state, finished, err := l.store.IndexReportState(ctx, hash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve index report state: %w", err)
+	}
+	if !finished {
+		return nil, false, nil
+	}
+	switch state {
+	case l.state:
+	case "":
+		ok, err := l.store.ManifestScanned(ctx, hash, l.vscnrs)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to check manifest: %w", err)
+		}
+		if !ok {
+			return nil, false, nil
+		}
+	default:
+		return nil, false, nil
+	}
+	ir, ok, err := l.store.IndexReport(ctx, hash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve index report: %w", err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return ir, true, nil
+}
+
 // State returns an opaque identifier identifying how the struct is currently
 // configured.
 //
@@ -164,7 +360,7 @@ func (l *Libindex) setState(ctx context.Context, vscnrs indexer.VersionedScanner
 	return nil
 }
 
-func (l *Libindex) index(ctx context.Context, s *controller.Controller, m *claircore.Manifest) *claircore.IndexReport {
+func (l *Libindex) index(ctx context.Context, s *controller.Controller, m *claircore.Manifest, shared int) *claircore.IndexReport {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libindex/Libindex.index"))
 	// attempt to get lock
@@ -188,14 +384,97 @@ func (l *Libindex) index(ctx context.Context, s *controller.Controller, m *clair
 	defer zlog.Debug(ctx).Msg("unlocked")
 	defer s.Unlock()
 	zlog.Debug(ctx).Msg("locked")
-	ir := s.Index(ctx, m)
+	ir := s.IndexIncremental(ctx, m, shared)
 	return ir
 }
 
+// CheckManifest reports an error if the manifest or any of its layers has a
+// malformed digest.
+func checkManifest(m *claircore.Manifest) error {
+	if m == nil {
+		return errors.New("libindex: nil manifest")
+	}
+	if err := m.Hash.Validate(); err != nil {
+		return fmt.Errorf("libindex: invalid manifest digest: %w", err)
+	}
+	for i, l := range m.Layers {
+		if l == nil {
+			return fmt.Errorf("libindex: manifest %v: nil layer %d", m.Hash, i)
+		}
+		if err := l.Hash.Validate(); err != nil {
+			return fmt.Errorf("libindex: manifest %v: invalid digest for layer %d: %w", m.Hash, i, err)
+		}
+		if l.DiffID == nil {
+			continue
+		}
+		if err := l.DiffID.Validate(); err != nil {
+			return fmt.Errorf("libindex: manifest %v: invalid diffID for layer %d: %w", m.Hash, i, err)
+		}
+	}
+	return nil
+}
+
+// VerifyLayers checks the diffIDs provided in the manifest against the ones
+// recorded in a stored IndexReport.
+func verifyLayers(m *claircore.Manifest, ir *claircore.IndexReport) error {
+	recorded := make(map[string]*claircore.Digest, len(ir.Layers))
+	for _, id := range ir.Layers {
+		recorded[id.Hash.String()] = id.DiffID
+	}
+	for _, l := range m.Layers {
+		if l.DiffID == nil {
+			continue
+		}
+		d := recorded[l.Hash.String()]
+		if d != nil && d.String() != l.DiffID.String() {
+			return &claircore.DiffIDMismatchError{Layer: l.Hash, Want: *l.DiffID, Got: *d}
+		}
+	}
+	return nil
+}
+
 // IndexReport retrieves an IndexReport for a particular manifest hash, if it exists.
 func (l *Libindex) IndexReport(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
+	if err := hash.Validate(); err != nil {
+		return nil, false, fmt.Errorf("libindex: invalid manifest digest: %w", err)
+	}
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
 	res, ok, err := l.store.IndexReport(ctx, hash)
-	return res, ok, err
+	return l.Opts.Redaction.IndexReport(res), ok, err
+}
+
+// ManifestsByDistribution returns a page of manifests indexed with the
+// distribution identified by the provided os-release ID and VERSION_ID.
+//
+// An empty versionID matches all versions of the distribution. Pass the
+// returned cursor to retrieve the next page; an empty cursor is returned when
+// there are no further pages.
+func (l *Libindex) ManifestsByDistribution(ctx context.Context, did, versionID string, limit int, cursor string) ([]claircore.Digest, string, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.ManifestsByDistribution"))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer done()
+	return l.store.ManifestsByDistribution(ctx, did, versionID, limit, cursor)
+}
+
+// ManifestCountByDistribution reports the number of manifests indexed with the
+// distribution identified by the provided os-release ID and VERSION_ID.
+func (l *Libindex) ManifestCountByDistribution(ctx context.Context, did, versionID string) (int64, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.ManifestCountByDistribution"))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	return l.store.ManifestCountByDistribution(ctx, did, versionID)
 }
 
 // AffectedManifests retrieves a list of affected manifests when provided a list of vulnerabilities.
@@ -203,6 +482,11 @@ func (l *Libindex) AffectedManifests(ctx context.Context, vulns []claircore.Vuln
 	sem := semaphore.NewWeighted(20)
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libindex/Libindex.AffectedManifests"))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 
 	affected := claircore.NewAffectedManifests()
 	errGrp, eCTX := errgroup.WithContext(ctx)
@@ -234,3 +518,108 @@ func (l *Libindex) AffectedManifests(ctx context.Context, vulns []claircore.Vuln
 	affected.Sort()
 	return &affected, nil
 }
+
+// ExportLayer writes the results of every configured scanner that has
+// scanned the layer to "w", for use with ImportLayer on another instance.
+//
+// An error is reported if no configured scanner has scanned the layer.
+func (l *Libindex) ExportLayer(ctx context.Context, hash claircore.Digest, w io.Writer) error {
+	if err := hash.Validate(); err != nil {
+		return fmt.Errorf("libindex: invalid layer digest: %w", err)
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.ExportLayer"),
+		label.String("layer", hash.String()))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	e := indexer.LayerEnvelope{Hash: hash}
+	for _, s := range l.vscnrs {
+		ok, err := l.store.LayerScanned(ctx, hash, s)
+		if err != nil {
+			return fmt.Errorf("libindex: failed to check layer: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		a := &indexer.ScannerArtifacts{
+			ScannerName:    s.Name(),
+			ScannerVersion: s.Version(),
+			ScannerKind:    s.Kind(),
+		}
+		vs := indexer.VersionedScanners{s}
+		// IDs are local to the store, so they're cleared.
+		switch s.Kind() {
+		case "package":
+			a.Packages, err = l.store.PackagesByLayer(ctx, hash, vs)
+			for _, p := range a.Packages {
+				p.ID = ""
+				if p.Source != nil {
+					p.Source.ID = ""
+				}
+			}
+		case "distribution":
+			a.Distributions, err = l.store.DistributionsByLayer(ctx, hash, vs)
+			for _, d := range a.Distributions {
+				d.ID = ""
+			}
+		case "repository":
+			a.Repositories, err = l.store.RepositoriesByLayer(ctx, hash, vs)
+			for _, r := range a.Repositories {
+				r.ID = ""
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("libindex: failed to retrieve artifacts for scanner %q: %w", s.Name(), err)
+		}
+		e.Artifacts = append(e.Artifacts, a)
+	}
+	if len(e.Artifacts) == 0 {
+		return fmt.Errorf("libindex: layer %q not scanned by any configured scanner", hash)
+	}
+	zlog.Debug(ctx).
+		Int("scanners", len(e.Artifacts)).
+		Msg("exporting layer")
+	return e.Encode(w)
+}
+
+// ImportLayer reads results written by ExportLayer and stores them as the
+// results of scanning the layer.
+//
+// The results must be for the layer "hash" and from scanners configured in
+// this instance at the same versions. Manifests containing the layer are then
+// indexed without fetching or scanning the layer, provided every configured
+// scanner is covered.
+func (l *Libindex) ImportLayer(ctx context.Context, hash claircore.Digest, r io.Reader) error {
+	if err := hash.Validate(); err != nil {
+		return fmt.Errorf("libindex: invalid layer digest: %w", err)
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.ImportLayer"),
+		label.String("layer", hash.String()))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	var e indexer.LayerEnvelope
+	if err := e.Decode(r); err != nil {
+		return fmt.Errorf("libindex: failed to decode layer artifacts: %w", err)
+	}
+	if err := e.Validate(hash, l.vscnrs); err != nil {
+		return fmt.Errorf("libindex: invalid layer artifacts: %w", err)
+	}
+	if err := l.store.ImportLayer(ctx, &e); err != nil {
+		return fmt.Errorf("libindex: failed to import layer artifacts: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("scanners", len(e.Artifacts)).
+		Msg("imported layer")
+	return nil
+}
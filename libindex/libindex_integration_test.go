@@ -173,8 +173,13 @@ func checkEqual(ctx context.Context, t *testing.T, tc testcase, lib *Libindex, i
 	}
 
 	// confirm scan report retrieved from libindex matches the one
-	// the Scan() method returned
-	want := ir
+	// the Scan() method returned. The cost is of the operation that
+	// returned the report, so it isn't stored.
+	if ir.Cost == nil {
+		t.Error("expected Cost in IndexReport")
+	}
+	want := *ir
+	want.Cost = nil
 	ir, ok, err := lib.IndexReport(ctx, hash)
 	if err != nil {
 		t.Error(err)
@@ -182,8 +187,58 @@ func checkEqual(ctx context.Context, t *testing.T, tc testcase, lib *Libindex, i
 	if !ok {
 		t.Error("expected ok return from IndexReport")
 	}
-	if got := ir; !cmp.Equal(got, want, cmp.AllowUnexported(claircore.Digest{})) {
-		t.Error(cmp.Diff(got, want))
+	if got := ir; !cmp.Equal(got, &want, cmp.AllowUnexported(claircore.Digest{})) {
+		t.Error(cmp.Diff(got, &want))
+	}
+}
+
+// CheckCost is a checkFunc that checks the cost of the index is plausible,
+// and that indexing the manifest again costs next to nothing.
+func checkCost(ctx context.Context, t *testing.T, tc testcase, lib *Libindex, ir *claircore.IndexReport) {
+	c := ir.Cost
+	if c == nil {
+		t.Fatal("expected Cost in IndexReport")
+	}
+	t.Logf("cost: %+v", c)
+	if c.FetchedBytes <= 0 {
+		t.Errorf("fetched bytes: got: %d, want: >0", c.FetchedBytes)
+	}
+	// The served layers are uncompressed, so everything fetched is written.
+	if c.ScratchBytes < c.FetchedBytes {
+		t.Errorf("scratch bytes: got: %d, want: >=%d", c.ScratchBytes, c.FetchedBytes)
+	}
+	if c.StoreRows <= 0 {
+		t.Errorf("store rows: got: %d, want: >0", c.StoreRows)
+	}
+	if got, want := len(c.Scanners), tc.Scanners; got != want {
+		t.Errorf("scanners: got: %d, want: %d", got, want)
+	}
+	for _, s := range c.Scanners {
+		if got, want := s.Layers, int64(tc.Layers); got != want {
+			t.Errorf("%s: layers: got: %d, want: %d", s.Name, got, want)
+		}
+	}
+	phases := make(map[string]time.Duration, len(c.Phases))
+	for _, p := range c.Phases {
+		phases[p.Phase] = p.Elapsed
+	}
+	for _, p := range []string{"CheckManifest", "FetchLayers", "ScanLayers", "Coalesce", "IndexManifest", "IndexFinished"} {
+		if d, ok := phases[p]; !ok || d <= 0 {
+			t.Errorf("phase %s: got: %v, want: >0", p, d)
+		}
+	}
+
+	m := &claircore.Manifest{Hash: tc.Digest()}
+	ir, err := lib.Index(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = ir.Cost
+	if c == nil {
+		t.Fatal("expected Cost in repeated IndexReport")
+	}
+	if c.FetchedBytes != 0 || c.ScratchBytes != 0 || c.StoreRows != 0 || len(c.Scanners) != 0 {
+		t.Errorf("repeat index: unexpected cost: %+v", c)
 	}
 }
 
@@ -245,3 +300,15 @@ func TestIndex(t *testing.T) {
 		})
 	}
 }
+
+// TestIndexCost tests that the cost of an index is accounted for.
+func TestIndexCost(t *testing.T) {
+	for _, tc := range testtable {
+		tc := tc
+		t.Run(tc.Name(), func(t *testing.T) {
+			ctx, done := context.WithCancel(context.Background())
+			defer done()
+			tc.Run(ctx, checkCost)(t)
+		})
+	}
+}
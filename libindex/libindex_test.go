@@ -1,15 +1,24 @@
 package libindex
 
 import (
+	"archive/tar"
 	"context"
 	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/controller"
+	"github.com/quay/claircore/pkg/distlock"
+	"github.com/quay/claircore/test"
 	"github.com/quay/zlog"
 )
 
@@ -139,3 +148,584 @@ func BenchmarkAffectedManifests(b *testing.B) {
 		}
 	}
 }
+
+// TestIndexFastPath confirms that a manifest already indexed with the current
+// scanners is served from the stored IndexReport without constructing a
+// controller or issuing any other store calls.
+func TestIndexFastPath(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	const state = "current-state"
+	m := &claircore.Manifest{Hash: digest("manifest")}
+	finished := func(state string) *claircore.IndexReport {
+		return &claircore.IndexReport{
+			Hash:         m.Hash,
+			State:        controller.IndexFinished.String(),
+			Success:      true,
+			IndexerState: state,
+		}
+	}
+	noController := func(_ context.Context, _ *Libindex, _ *Opts) (*controller.Controller, error) {
+		t.Error("controller constructed")
+		return nil, errors.New("controller constructed")
+	}
+
+	var tt = []struct {
+		name   string
+		report *claircore.IndexReport
+		// Whether the scanned manifest table should be consulted, and what
+		// it reports.
+		checkScanned, scanned bool
+		fastPath              bool
+	}{
+		{name: "Finished", report: finished(""), checkScanned: true, scanned: true, fastPath: true},
+		{name: "NotScanned", report: finished(""), checkScanned: true, scanned: false},
+		{name: "Unfinished", report: &claircore.IndexReport{Hash: m.Hash, State: controller.ScanLayers.String()}},
+		{name: "CurrentState", report: finished(state), fastPath: true},
+		{name: "StaleState", report: finished("stale-state")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			// The mock fails the test on any call not set up here, so this
+			// asserts the fast path only issues these queries.
+			ctrl := gomock.NewController(t)
+			s := indexer.NewMockStore(ctrl)
+			expect := func() {
+				s.EXPECT().IndexReportState(gomock.Any(), m.Hash).Return(tc.report.IndexerState, tc.report.Success, nil).Times(1)
+				if tc.checkScanned {
+					s.EXPECT().ManifestScanned(gomock.Any(), m.Hash, gomock.Any()).Return(tc.scanned, nil).Times(1)
+				}
+				// The report is only retrieved once it's known to be current.
+				if tc.fastPath {
+					s.EXPECT().IndexReport(gomock.Any(), m.Hash).Return(tc.report, true, nil).Times(1)
+				}
+			}
+			expect()
+			li := &Libindex{store: s, Opts: &Opts{}, state: state}
+
+			ir, ok, err := li.indexed(ctx, m.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ok, tc.fastPath; got != want {
+				t.Fatalf("fast path: got: %v, want: %v", got, want)
+			}
+			if !ok {
+				return
+			}
+			if ir != tc.report {
+				t.Errorf("got: %v, want: %v", ir, tc.report)
+			}
+
+			li.ControllerFactory = noController
+			expect()
+			ir, err = li.Index(ctx, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ir != tc.report {
+				t.Errorf("got: %v, want: %v", ir, tc.report)
+			}
+			// The returned report only cost the lookup.
+			switch c := ir.Cost; {
+			case c == nil:
+				t.Error("missing cost")
+			case c.FetchedBytes != 0 || c.ScratchBytes != 0 || c.StoreRows != 0 || len(c.Scanners) != 0:
+				t.Errorf("unexpected cost: %+v", c)
+			case len(c.Phases) != 1 || c.Phases[0].Phase != controller.CheckManifest.String():
+				t.Errorf("unexpected phases: %+v", c.Phases)
+			}
+		})
+	}
+}
+
+// TestIndexFastPathDiffID checks that diffIDs provided with an indexed
+// manifest are checked against the stored IndexReport.
+func TestIndexFastPathDiffID(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	diffID, other := digest("diffID"), digest("other")
+	report := &claircore.IndexReport{
+		Hash:    digest("manifest"),
+		State:   controller.IndexFinished.String(),
+		Success: true,
+		Layers: []claircore.LayerIdentity{
+			{Hash: digest("layer"), DiffID: &diffID},
+			{Hash: digest("old")},
+		},
+	}
+
+	var tt = []struct {
+		name   string
+		layers []*claircore.Layer
+		err    bool
+	}{
+		{name: "None", layers: []*claircore.Layer{{Hash: digest("layer")}, {Hash: digest("old")}}},
+		{name: "Match", layers: []*claircore.Layer{{Hash: digest("layer"), DiffID: &diffID}, {Hash: digest("old")}}},
+		// Nothing was recorded for the old layer, so there's nothing to
+		// check against.
+		{name: "Unrecorded", layers: []*claircore.Layer{{Hash: digest("layer")}, {Hash: digest("old"), DiffID: &other}}},
+		{name: "Mismatch", layers: []*claircore.Layer{{Hash: digest("layer"), DiffID: &other}, {Hash: digest("old")}}, err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			ctrl := gomock.NewController(t)
+			s := indexer.NewMockStore(ctrl)
+			s.EXPECT().IndexReportState(gomock.Any(), report.Hash).Return("", true, nil)
+			s.EXPECT().ManifestScanned(gomock.Any(), report.Hash, gomock.Any()).Return(true, nil)
+			s.EXPECT().IndexReport(gomock.Any(), report.Hash).Return(report, true, nil)
+			li := &Libindex{store: s, Opts: &Opts{}}
+
+			m := &claircore.Manifest{Hash: report.Hash, Layers: tc.layers}
+			ir, err := li.Index(ctx, m)
+			var me *claircore.DiffIDMismatchError
+			if got, want := errors.As(err, &me), tc.err; got != want {
+				t.Fatalf("got error %v, want mismatch: %v", err, want)
+			}
+			if ir != report {
+				t.Errorf("got: %v, want: %v", ir, report)
+			}
+		})
+	}
+}
+
+// TestIndexIncrementalPrevious checks that IndexIncremental rejects previous
+// manifests it can't build on before constructing a controller.
+func TestIndexIncrementalPrevious(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	const state = "current-state"
+	base, app := digest("base"), digest("app")
+	m := &claircore.Manifest{
+		Hash:   digest("manifest"),
+		Layers: []*claircore.Layer{{Hash: base}, {Hash: app}},
+	}
+	previous := func(state string, ls ...claircore.Digest) *claircore.IndexReport {
+		ir := &claircore.IndexReport{
+			Hash:         digest("previous"),
+			State:        controller.IndexFinished.String(),
+			Success:      true,
+			IndexerState: state,
+		}
+		for _, l := range ls {
+			ir.Layers = append(ir.Layers, claircore.LayerIdentity{Hash: l})
+		}
+		return ir
+	}
+
+	var tt = []struct {
+		name   string
+		report *claircore.IndexReport
+		err    error
+	}{
+		{name: "Missing", err: ErrPreviousNotIndexed},
+		{name: "Unfinished", report: &claircore.IndexReport{Hash: digest("previous"), State: controller.ScanLayers.String()}, err: ErrPreviousNotIndexed},
+		{name: "StaleState", report: previous("stale-state", base), err: ErrPreviousNotIndexed},
+		{name: "Diverged", report: previous(state, app), err: ErrLayersDiverged},
+		{name: "Longer", report: previous(state, base, app, digest("other")), err: ErrLayersDiverged},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			s := indexer.NewMockStore(ctrl)
+			var prevState string
+			var finished bool
+			if tc.report != nil {
+				prevState, finished = tc.report.IndexerState, tc.report.Success
+			}
+			s.EXPECT().IndexReportState(gomock.Any(), digest("previous")).Return(prevState, finished, nil)
+			if errors.Is(tc.err, ErrLayersDiverged) {
+				s.EXPECT().IndexReport(gomock.Any(), digest("previous")).Return(tc.report, true, nil)
+			}
+			li := &Libindex{
+				store: s,
+				state: state,
+				Opts: &Opts{
+					ControllerFactory: func(_ context.Context, _ *Libindex, _ *Opts) (*controller.Controller, error) {
+						t.Error("controller constructed")
+						return nil, errors.New("controller constructed")
+					},
+				},
+			}
+
+			_, err := li.IndexIncremental(ctx, m, digest("previous"))
+			t.Log(err)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+// TestInvalidDigest checks that malformed digests are rejected before the
+// store is consulted.
+func TestInvalidDigest(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+	li := &Libindex{
+		store: indexer.NewMockStore(ctrl),
+		Opts: &Opts{
+			ControllerFactory: func(_ context.Context, _ *Libindex, _ *Opts) (*controller.Controller, error) {
+				t.Error("controller constructed")
+				return nil, errors.New("controller constructed")
+			},
+		},
+	}
+	check := func(t *testing.T, err error) {
+		t.Helper()
+		if !errors.Is(err, claircore.ErrInvalidDigest) {
+			t.Errorf("got error %v, want %v", err, claircore.ErrInvalidDigest)
+		}
+	}
+
+	for _, m := range []*claircore.Manifest{
+		{},
+		{Hash: digest("manifest"), Layers: []*claircore.Layer{{Hash: digest("layer")}, {}}},
+		{Hash: digest("manifest"), Layers: []*claircore.Layer{{Hash: digest("layer"), DiffID: &claircore.Digest{}}}},
+	} {
+		_, err := li.Index(ctx, m)
+		check(t, err)
+	}
+	_, err := li.IndexIncremental(ctx, &claircore.Manifest{Hash: digest("manifest")}, claircore.Digest{})
+	check(t, err)
+	_, _, err = li.IndexReport(ctx, claircore.Digest{})
+	check(t, err)
+	check(t, li.ExportLayer(ctx, claircore.Digest{}, ioutil.Discard))
+	check(t, li.ImportLayer(ctx, claircore.Digest{}, nil))
+}
+
+// TestCorrelationID checks that log lines and store calls from concurrent
+// Index calls can be told apart by correlation ID.
+func TestCorrelationID(t *testing.T) {
+	ctx, logs := test.CaptureLogs(context.Background(), t)
+	const state = "current-state"
+	ms := []*claircore.Manifest{
+		{Hash: digest("manifest-1")},
+		{Hash: digest("manifest-2")},
+	}
+	ctrl := gomock.NewController(t)
+	s := indexer.NewMockStore(ctrl)
+	// Hold every store call until both Index calls have made one, so their
+	// logging interleaves.
+	var arrived sync.WaitGroup
+	arrived.Add(len(ms))
+	var mu sync.Mutex
+	storeIDs := make(map[string]string)
+	s.EXPECT().
+		IndexReportState(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, h claircore.Digest) (string, bool, error) {
+			mu.Lock()
+			storeIDs[h.String()] = claircore.CorrelationID(ctx)
+			mu.Unlock()
+			arrived.Done()
+			arrived.Wait()
+			return state, true, nil
+		}).
+		Times(len(ms))
+	s.EXPECT().
+		IndexReport(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, h claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{
+				Hash:         h,
+				State:        controller.IndexFinished.String(),
+				Success:      true,
+				IndexerState: state,
+			}, true, nil
+		}).
+		Times(len(ms))
+	li := &Libindex{store: s, Opts: &Opts{}, state: state}
+
+	errs := make(chan error, len(ms))
+	for i, m := range ms {
+		ctx := ctx
+		if i == 0 {
+			// The first caller provides an ID; the second has one generated.
+			ctx = claircore.WithCorrelationID(ctx, "req-1")
+		}
+		go func(ctx context.Context, m *claircore.Manifest) {
+			_, err := li.Index(ctx, m)
+			errs <- err
+		}(ctx, m)
+	}
+	for range ms {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byManifest := make(map[string]map[string]int)
+	for _, l := range logs.Lines() {
+		m, ok := l["manifest"].(string)
+		if !ok {
+			continue
+		}
+		id, _ := l[claircore.CorrelationIDKey].(string)
+		if id == "" {
+			t.Errorf("log line without correlation ID: %v", l)
+		}
+		if byManifest[m] == nil {
+			byManifest[m] = make(map[string]int)
+		}
+		byManifest[m][id]++
+	}
+	seen := make(map[string]string)
+	for _, m := range ms {
+		ids := byManifest[m.Hash.String()]
+		if len(ids) != 1 {
+			t.Errorf("%v: want exactly one correlation ID, got: %v", m.Hash, ids)
+			continue
+		}
+		for id := range ids {
+			if other, ok := seen[id]; ok {
+				t.Errorf("%v and %v share correlation ID %q", m.Hash, other, id)
+			}
+			seen[id] = m.Hash.String()
+			if got := storeIDs[m.Hash.String()]; got != id {
+				t.Errorf("%v: store call had correlation ID %q, logs had %q", m.Hash, got, id)
+			}
+		}
+	}
+	if got, want := seen["req-1"], ms[0].Hash.String(); got != want {
+		t.Errorf("caller-provided ID used for %q, want %q", got, want)
+	}
+}
+
+// TestInventoryOnly checks that an inventory-only configuration keeps the
+// default package and distribution scanners and drops the repository
+// scanners, and that the coalesced report still has every package.
+func TestInventoryOnly(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	scanners := func(t *testing.T, o *Opts) (ps, ds, rs []string) {
+		t.Helper()
+		if err := o.Parse(ctx); err != nil {
+			t.Fatal(err)
+		}
+		p, d, r, err := indexer.EcosystemsToScanners(ctx, o.Ecosystems, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range p {
+			ps = append(ps, s.Name())
+		}
+		for _, s := range d {
+			ds = append(ds, s.Name())
+		}
+		for _, s := range r {
+			rs = append(rs, s.Name())
+		}
+		return ps, ds, rs
+	}
+	fullP, fullD, fullR := scanners(t, &Opts{ConnString: "host=localhost"})
+	invP, invD, invR := scanners(t, &Opts{ConnString: "host=localhost", InventoryOnly: true})
+	if !cmp.Equal(invP, fullP) {
+		t.Error(cmp.Diff(invP, fullP))
+	}
+	if !cmp.Equal(invD, fullD) {
+		t.Error(cmp.Diff(invD, fullD))
+	}
+	if len(fullR) == 0 {
+		t.Error("no repository scanners in the default configuration")
+	}
+	if len(invR) != 0 {
+		t.Errorf("unexpected repository scanners: %v", invR)
+	}
+
+	// The coalesced reports keep the packages of every ecosystem, even
+	// without the repositories, but not the files recorded for matching.
+	l := inventoryLayer(t)
+	type pkgInfo struct {
+		Repos bool
+		Files bool
+	}
+	summary := func(ir *claircore.IndexReport) map[string]pkgInfo {
+		out := make(map[string]pkgInfo)
+		for _, r := range ir.IndexRecords() {
+			out[r.Package.Name] = pkgInfo{
+				Repos: r.Repository != nil,
+				Files: len(r.Package.Files) != 0,
+			}
+		}
+		return out
+	}
+	full := summary(coalesceLayer(ctx, t, &Opts{ConnString: "host=localhost"}, l))
+	want := map[string]pkgInfo{
+		"org.apache.logging.log4j:log4j-core": {Repos: true, Files: true},
+		"requests":                            {Repos: true},
+	}
+	if !cmp.Equal(full, want) {
+		t.Error(cmp.Diff(full, want))
+	}
+	inv := summary(coalesceLayer(ctx, t, &Opts{ConnString: "host=localhost", InventoryOnly: true}, l))
+	want = map[string]pkgInfo{
+		"org.apache.logging.log4j:log4j-core": {},
+		"requests":                            {},
+	}
+	if !cmp.Equal(inv, want) {
+		t.Error(cmp.Diff(inv, want))
+	}
+}
+
+// InventoryLayer returns a layer with a java and a python package.
+func inventoryLayer(t *testing.T) *claircore.Layer {
+	t.Helper()
+	jar, err := ioutil.ReadFile("../java/testdata/log4j-core-2.14.1.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"opt/app/lib/log4j-core-2.14.1.jar": jar,
+		"usr/lib/python3.8/site-packages/requests-2.25.1.dist-info/METADATA": []byte(
+			"Metadata-Version: 2.1\nName: requests\nVersion: 2.25.1\n\n"),
+	}
+	f, err := ioutil.TempFile(t.TempDir(), "layer.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := tar.NewWriter(f)
+	for n, b := range files {
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     n,
+			Size:     int64(len(b)),
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{Hash: digest("inventory layer")}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// CoalesceLayer scans the layer with every ecosystem the Opts configure and
+// coalesces the results, as the indexer does for a single-layer manifest.
+func coalesceLayer(ctx context.Context, t *testing.T, o *Opts, l *claircore.Layer) *claircore.IndexReport {
+	t.Helper()
+	if err := o.Parse(ctx); err != nil {
+		t.Fatal(err)
+	}
+	out := &claircore.IndexReport{
+		Packages:      map[string]*claircore.Package{},
+		Distributions: map[string]*claircore.Distribution{},
+		Repositories:  map[string]*claircore.Repository{},
+		Environments:  map[string][]*claircore.Environment{},
+	}
+	// IDs are assigned by the store, so give everything one.
+	var id int
+	nextID := func() string {
+		id++
+		return strconv.Itoa(id)
+	}
+	for _, e := range o.Ecosystems {
+		la := &indexer.LayerArtifacts{Hash: l.Hash}
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range ps {
+			pkgs, err := s.Scan(ctx, l)
+			if err != nil {
+				t.Fatalf("%s: %v", s.Name(), err)
+			}
+			for _, p := range pkgs {
+				p.ID = nextID()
+			}
+			la.Pkgs = append(la.Pkgs, pkgs...)
+		}
+		rs, err := e.RepositoryScanners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range rs {
+			repos, err := s.Scan(ctx, l)
+			if err != nil {
+				t.Fatalf("%s: %v", s.Name(), err)
+			}
+			for _, r := range repos {
+				r.ID = nextID()
+			}
+			la.Repos = append(la.Repos, repos...)
+		}
+		c, err := e.Coalescer(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ir, err := c.Coalesce(ctx, []*indexer.LayerArtifacts{la})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range ir.Packages {
+			out.Packages[k] = v
+		}
+		for k, v := range ir.Repositories {
+			out.Repositories[k] = v
+		}
+		for k, v := range ir.Environments {
+			out.Environments[k] = v
+		}
+	}
+	return out
+}
+
+// TestLayerTooLarge checks that a layer exceeding the configured limits fails
+// the Index call, and not just the IndexReport.
+func TestLayerTooLarge(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ctrl := gomock.NewController(t)
+	m := &claircore.Manifest{
+		Hash:   digest("manifest"),
+		Layers: []*claircore.Layer{{Hash: digest("layer")}},
+	}
+
+	s := indexer.NewMockStore(ctrl)
+	s.EXPECT().IndexReportState(gomock.Any(), gomock.Any()).Return("", false, nil)
+	s.EXPECT().ManifestScanned(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	s.EXPECT().PersistManifest(gomock.Any(), gomock.Any()).Return(nil)
+	s.EXPECT().LayersScanned(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	s.EXPECT().SetIndexReport(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	f := indexer.NewMockFetcher(ctrl)
+	f.EXPECT().Fetch(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("fetcher: %w: more than 1 entries", claircore.ErrLayerTooLarge))
+	f.EXPECT().Close().Return(nil)
+	lock := distlock.NewMockLocker(ctrl)
+	lock.EXPECT().Lock(gomock.Any(), gomock.Any()).Return(nil)
+	lock.EXPECT().Unlock().Return(nil)
+
+	l := &Libindex{
+		Opts: &Opts{
+			ControllerFactory: func(context.Context, *Libindex, *Opts) (*controller.Controller, error) {
+				return controller.New(&indexer.Opts{
+					Store:    s,
+					Fetcher:  f,
+					ScanLock: lock,
+				}), nil
+			},
+		},
+		store: s,
+	}
+	ir, err := l.Index(ctx, m)
+	if !errors.Is(err, claircore.ErrLayerTooLarge) {
+		t.Errorf("got: %v, want: %v", err, claircore.ErrLayerTooLarge)
+	}
+	if ir == nil || ir.Success || ir.Err == "" {
+		t.Errorf("unexpected report: %+v", ir)
+	}
+}
@@ -0,0 +1,20 @@
+package migrations
+
+const (
+	// This migration adds a namespace to manifests and layers, so that
+	// stores configured with different namespaces can share a database
+	// without seeing each other's index reports.
+	//
+	// Existing rows are put in the default, empty namespace. Every other
+	// table refers to manifests or layers by ID or holds content shared
+	// between namespaces, so nothing else changes.
+	migration4 = `
+ALTER TABLE manifest ADD COLUMN IF NOT EXISTS namespace text NOT NULL DEFAULT '';
+ALTER TABLE layer ADD COLUMN IF NOT EXISTS namespace text NOT NULL DEFAULT '';
+
+ALTER TABLE manifest DROP CONSTRAINT IF EXISTS manifest_hash_unique;
+ALTER TABLE manifest ADD CONSTRAINT manifest_namespace_hash_unique UNIQUE (namespace, hash);
+ALTER TABLE layer DROP CONSTRAINT IF EXISTS layer_hash_unique;
+ALTER TABLE layer ADD CONSTRAINT layer_namespace_hash_unique UNIQUE (namespace, hash);
+`
+)
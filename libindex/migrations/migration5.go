@@ -0,0 +1,36 @@
+package migrations
+
+const (
+	// This migration rewrites manifest and layer hashes into the canonical
+	// form claircore.Digest produces: trimmed, with a lowercase algorithm and
+	// checksum. Lookups always use the canonical form, so a row stored any
+	// other way could never be found again.
+	//
+	// Of the rows in a namespace sharing a canonical form, only the oldest is
+	// rewritten, and only if the canonical form isn't already present, so the
+	// uniqueness constraint holds. The rest are left as unreachable duplicates.
+	// Other tables refer to manifests and layers by ID, so nothing else
+	// changes. Running this again is a no-op.
+	migration5 = `
+UPDATE manifest SET hash = lower(btrim(hash))
+WHERE hash <> lower(btrim(hash))
+	AND id = (
+		SELECT min(m.id) FROM manifest AS m
+		WHERE m.namespace = manifest.namespace AND lower(btrim(m.hash)) = lower(btrim(manifest.hash))
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM manifest AS m
+		WHERE m.namespace = manifest.namespace AND m.hash = lower(btrim(manifest.hash))
+	);
+UPDATE layer SET hash = lower(btrim(hash))
+WHERE hash <> lower(btrim(hash))
+	AND id = (
+		SELECT min(l.id) FROM layer AS l
+		WHERE l.namespace = layer.namespace AND lower(btrim(l.hash)) = lower(btrim(layer.hash))
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM layer AS l
+		WHERE l.namespace = layer.namespace AND l.hash = lower(btrim(layer.hash))
+	);
+`
+)
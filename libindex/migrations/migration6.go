@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// This migration adds the files a scanner recorded for a package in a
+	// layer. Existing rows have no inventory, which is represented by NULL.
+	migration6 = `
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS files text[];
+`
+)
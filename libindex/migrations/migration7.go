@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// This migration adds the digest of a layer's uncompressed content. Layers
+	// fetched before it have none, which is represented by NULL.
+	migration7 = `
+ALTER TABLE layer ADD COLUMN IF NOT EXISTS diff_id text;
+`
+)
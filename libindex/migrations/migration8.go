@@ -0,0 +1,10 @@
+package migrations
+
+const (
+	// This migration adds the license a scanner recorded for a package in a
+	// layer. Packages without one, including those in existing rows, are
+	// represented by NULL.
+	migration8 = `
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS license text;
+`
+)
@@ -32,4 +32,39 @@ var Migrations = []migrate.Migration{
 			return err
 		},
 	},
+	{
+		ID: 4,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration4)
+			return err
+		},
+	},
+	{
+		ID: 5,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration5)
+			return err
+		},
+	},
+	{
+		ID: 6,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration6)
+			return err
+		},
+	},
+	{
+		ID: 7,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration7)
+			return err
+		},
+	},
+	{
+		ID: 8,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration8)
+			return err
+		},
+	},
 }
@@ -2,24 +2,61 @@ package libindex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/claircore"
 	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/conda"
 	"github.com/quay/claircore/dpkg"
+	"github.com/quay/claircore/flatpak"
+	"github.com/quay/claircore/gobin"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/multierr"
 	"github.com/quay/claircore/java"
 	"github.com/quay/claircore/python"
 	"github.com/quay/claircore/rhel"
 	"github.com/quay/claircore/rpm"
+	"github.com/quay/claircore/snap"
+	"github.com/quay/claircore/windows"
 )
 
 const (
 	DefaultScanLockRetry        = 5 * time.Second
 	DefaultLayerScanConcurrency = 10
 	DefaultLayerFetchOpt        = indexer.OnDisk
+	DefaultDrainTimeout         = 30 * time.Second
+	DefaultScratchMaxAge        = time.Hour
+	DefaultLayerMaxSize         = indexer.DefaultLayerMaxSize
+	DefaultLayerMaxRatio        = indexer.DefaultLayerMaxRatio
+	DefaultLayerMaxEntries      = indexer.DefaultLayerMaxEntries
+	DefaultLayerMaxFileSize     = indexer.DefaultLayerMaxFileSize
 )
 
+// LayerLimits bounds the resources a single layer may use when it's fetched
+// and decompressed. Any zero-valued member is replaced with its default.
+type LayerLimits struct {
+	// MaxSize is the maximum number of decompressed bytes in a layer. The
+	// default is DefaultLayerMaxSize.
+	MaxSize int64
+	// MaxRatio is the maximum ratio of decompressed to compressed bytes. The
+	// default is DefaultLayerMaxRatio.
+	MaxRatio int64
+	// MaxEntries is the maximum number of entries in a layer's tar archive.
+	// The default is DefaultLayerMaxEntries.
+	MaxEntries int
+	// MaxFileSize is the maximum size of any single file in a layer.
+	// Scanners may read files of up to this size into memory. The default is
+	// DefaultLayerMaxFileSize.
+	MaxFileSize int64
+}
+
 // Opts are dependencies and options for constructing an instance of libindex
 type Opts struct {
 	// the connection string for the datastore specified above
@@ -30,6 +67,23 @@ type Opts struct {
 	LayerScanConcurrency int
 	// how we store layers we fetch remotely. see LayerFetchOpt type def above for more details
 	LayerFetchOpt indexer.LayerFetchOpt
+	// LayerLimits bounds the resources a single layer may use when fetched.
+	// Manifests with layers exceeding these limits fail to index with an
+	// error wrapping claircore.ErrLayerTooLarge.
+	LayerLimits LayerLimits
+	// Authorizer, if set, supplies credentials for layer requests whose
+	// Layer doesn't carry an Authorization header. The default is to only
+	// send the credentials in each Layer's Headers. See the dockerauth
+	// package for an Authorizer that finds credentials the way the docker
+	// CLI does.
+	Authorizer indexer.Authorizer
+	// ScratchDir is the directory fetched layers are written to. It may be
+	// shared with other instances. The default is a "claircore" directory in
+	// the system's temporary directory.
+	ScratchDir string
+	// ScratchMaxAge is how long the scratch space of an operation that's no
+	// longer running is kept before it's removed.
+	ScratchMaxAge time.Duration
 	// NoLayerValidation controls whether layers are checked to actually be
 	// content-addressed. With this option toggled off, callers can trigger
 	// layers to be indexed repeatedly by changing the identifier in the
@@ -37,6 +91,25 @@ type Opts struct {
 	NoLayerValidation bool
 	// set to true to have libindex check and potentially run migrations
 	Migrations bool
+	// Namespace isolates this Libindex's manifests and layers from those of
+	// instances configured with other namespaces in the same database. The
+	// default is the shared namespace.
+	Namespace string
+	// DrainTimeout is how long Close waits for in-flight operations to finish
+	// before canceling them.
+	DrainTimeout time.Duration
+	// MaxConcurrentIndex, if positive, is the number of manifests indexed at
+	// once. Index calls for manifests that are already indexed aren't
+	// counted. The default is no limit.
+	MaxConcurrentIndex int
+	// IndexQueueSize is the number of Index calls that may wait for one of
+	// the MaxConcurrentIndex slots. Calls beyond that fail immediately with
+	// ErrIndexBusy. The default is no queue.
+	IndexQueueSize int
+	// IndexQueueTimeout is how long a queued Index call waits for a slot
+	// before failing with ErrIndexBusy. The default is to wait as long as the
+	// call's Context allows.
+	IndexQueueTimeout time.Duration
 	// provides an alternative method for creating a scanner during libindex runtime
 	// if nil the default factory will be used. useful for testing purposes
 	ControllerFactory ControllerFactory
@@ -45,6 +118,19 @@ type Opts struct {
 	// Airgap should be set to disallow any scanners that mark themselves as
 	// making network calls.
 	Airgap bool
+	// InventoryOnly leaves out the work that only exists to support
+	// vulnerability matching, so indexing for inventory is faster: the
+	// repository scanners aren't used, and scanners don't record the files
+	// found with packages. Only packages and distributions are reported.
+	//
+	// IndexReports from such an indexer are marked InventoryOnly, and
+	// libvuln refuses to Scan them.
+	InventoryOnly bool
+	// Redaction, if set, redacts the IndexReports returned by Index,
+	// IndexIncremental, and IndexReport, and the layer URIs in logs, so
+	// reports can be shared outside of where they were produced. Reports are
+	// stored unredacted. The redaction is recorded in each report.
+	Redaction *claircore.Redaction
 	// ScannerConfig holds functions that can be passed into configurable
 	// scanners. They're broken out by kind, and only used if a scanner
 	// implements the appropriate interface.
@@ -58,11 +144,10 @@ type Opts struct {
 	vscnrs indexer.VersionedScanners
 }
 
+// Parse fills in defaults for unset options and reports any problems
+// Validate finds. Defaults are filled in even if there are problems.
 func (o *Opts) Parse(ctx context.Context) error {
-	// required
-	if o.ConnString == "" {
-		return fmt.Errorf("ConnString not provided")
-	}
+	err := o.Validate()
 
 	// optional
 	if (o.ScanLockRetry == 0) || (o.ScanLockRetry < time.Second) {
@@ -71,6 +156,15 @@ func (o *Opts) Parse(ctx context.Context) error {
 	if o.LayerScanConcurrency == 0 {
 		o.LayerScanConcurrency = DefaultLayerScanConcurrency
 	}
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = DefaultDrainTimeout
+	}
+	if o.ScratchDir == "" {
+		o.ScratchDir = filepath.Join(os.TempDir(), "claircore")
+	}
+	if o.ScratchMaxAge <= 0 {
+		o.ScratchMaxAge = DefaultScratchMaxAge
+	}
 	if o.ControllerFactory == nil {
 		o.ControllerFactory = controllerFactory
 	}
@@ -82,9 +176,90 @@ func (o *Opts) Parse(ctx context.Context) error {
 			rpm.NewEcosystem(ctx),
 			python.NewEcosystem(ctx),
 			java.NewEcosystem(ctx),
+			gobin.NewEcosystem(ctx),
+			conda.NewEcosystem(ctx),
+			flatpak.NewEcosystem(ctx),
+			snap.NewEcosystem(ctx),
+			windows.NewEcosystem(ctx),
 		}
 	}
+	if o.InventoryOnly {
+		o.Ecosystems = indexer.InventoryEcosystems(o.Ecosystems)
+	}
 	o.LayerFetchOpt = DefaultLayerFetchOpt
 
-	return nil
+	return err
+}
+
+// Validate reports every problem with the options it can find without side
+// effects, as a single error listing each one. A nil error doesn't mean New
+// will succeed: it also checks that the database can be reached and that the
+// scratch directory is writable.
+func (o *Opts) Validate() error {
+	var errs []error
+	if o.ConnString == "" {
+		errs = append(errs, errors.New("ConnString not provided"))
+	} else if _, err := pgxpool.ParseConfig(o.ConnString); err != nil {
+		errs = append(errs, fmt.Errorf("ConnString invalid: %v", err))
+	}
+	if o.LayerScanConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("LayerScanConcurrency must not be negative: %d", o.LayerScanConcurrency))
+	}
+	if o.IndexQueueSize > 0 && o.MaxConcurrentIndex <= 0 {
+		errs = append(errs, errors.New("IndexQueueSize set without MaxConcurrentIndex"))
+	}
+	if o.IndexQueueTimeout < 0 {
+		errs = append(errs, fmt.Errorf("IndexQueueTimeout must not be negative: %v", o.IndexQueueTimeout))
+	}
+	if o.ScratchDir != "" {
+		if err := checkScratchDir(o.ScratchDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := o.Redaction.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("Redaction invalid: %v", err))
+	}
+	for i, e := range o.Ecosystems {
+		switch {
+		case e == nil:
+			errs = append(errs, fmt.Errorf("Ecosystems[%d] is nil", i))
+		case e.PackageScanners == nil || e.DistributionScanners == nil ||
+			e.RepositoryScanners == nil || e.Coalescer == nil:
+			errs = append(errs, fmt.Errorf("Ecosystems[%d] (%q) is missing scanner or coalescer constructors", i, e.Name))
+		}
+	}
+	return multierr.Join(errs...)
+}
+
+// CheckScratchDir reports whether "dir" is a directory, or could be created
+// as one, without creating it.
+func checkScratchDir(dir string) error {
+	for p := dir; ; p = filepath.Dir(p) {
+		fi, err := os.Stat(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && p != filepath.Dir(p):
+			continue
+		case err != nil:
+			return fmt.Errorf("ScratchDir %q unusable: %v", dir, err)
+		case !fi.IsDir() && p == dir:
+			return fmt.Errorf("ScratchDir %q is not a directory", dir)
+		case !fi.IsDir():
+			return fmt.Errorf("ScratchDir %q can't be created: %q is not a directory", dir, p)
+		}
+		return nil
+	}
+}
+
+// ProbeScratchDir checks that the scratch directory can be written to,
+// creating it if needed.
+func probeScratchDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("ScratchDir %q unusable: %v", dir, err)
+	}
+	f, err := ioutil.TempFile(dir, ".probe.")
+	if err != nil {
+		return fmt.Errorf("ScratchDir %q not writable: %v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
 }
@@ -0,0 +1,121 @@
+package libindex
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// TestValidate checks that every problem with a configuration is reported at
+// once.
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libindex.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := Opts{
+		LayerScanConcurrency: -1,
+		IndexQueueSize:       5,
+		ScratchDir:           filepath.Join(file, "scratch"),
+		Ecosystems:           []*indexer.Ecosystem{nil, {Name: "partial"}},
+		Redaction:            &claircore.Redaction{Hosts: []string{""}},
+	}
+	err = o.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	t.Log(err)
+	for _, want := range []string{
+		"ConnString not provided",
+		"LayerScanConcurrency",
+		"IndexQueueSize",
+		"ScratchDir",
+		"Ecosystems[0]",
+		`Ecosystems[1] ("partial")`,
+		"Redaction",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %q", want)
+		}
+	}
+	if got, want := len(strings.Split(err.Error(), "\n")), 7; got != want {
+		t.Errorf("got %d problems, want %d", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scratch")); !os.IsNotExist(err) {
+		t.Errorf("Validate had side effects: %v", err)
+	}
+
+	for _, tc := range []struct {
+		Name string
+		Opts Opts
+		Want string
+	}{
+		{Name: "BadConnString", Opts: Opts{ConnString: "not a dsn"}, Want: "ConnString invalid"},
+		{Name: "ScratchIsFile", Opts: Opts{ConnString: "host=localhost", ScratchDir: file}, Want: "not a directory"},
+		{Name: "OK", Opts: Opts{ConnString: "host=localhost", ScratchDir: filepath.Join(dir, "a", "b")}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Opts.Validate()
+			switch {
+			case tc.Want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tc.Want != "" && (err == nil || !strings.Contains(err.Error(), tc.Want)):
+				t.Errorf("got: %v, want an error mentioning %q", err, tc.Want)
+			}
+		})
+	}
+}
+
+// TestNewInvalid checks that New reports configuration problems along with
+// the problems only found by trying, like an unreachable database.
+func TestNewInvalid(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	dir, err := ioutil.TempDir("", "libindex.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	scratch := filepath.Join(dir, "scratch")
+	if err := os.Mkdir(scratch, 0500); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(ctx, &Opts{
+		// Nothing listens on port 1.
+		ConnString:           "host=127.0.0.1 port=1 connect_timeout=5",
+		LayerScanConcurrency: -1,
+		ScratchDir:           scratch,
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	t.Log(err)
+	want := []string{
+		"LayerScanConcurrency",
+		"invalid *http.Client",
+		"failed to create ConnPool",
+	}
+	// Root can write anywhere.
+	if os.Geteuid() != 0 {
+		want = append(want, "not writable")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error doesn't mention %q", w)
+		}
+	}
+}
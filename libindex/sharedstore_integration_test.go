@@ -0,0 +1,110 @@
+package libindex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestSharedStore indexes overlapping manifests with two instances sharing a
+// database, and checks that each layer is only scanned once and that the
+// store is consulted with a bounded number of queries.
+func TestSharedStore(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	layers := test.ServeLayers(ctx, t, 3)
+	pkgs := test.GenUniquePackages(2)
+	// Both instances use scanners with the same name and version, so every
+	// layer should be scanned exactly once between them.
+	a, b := newMockScanner(ctrl), newMockScanner(ctrl)
+	a.EXPECT().Scan(gomock.Any(), gomock.Any()).Times(2).Return(pkgs, nil)
+	b.EXPECT().Scan(gomock.Any(), gomock.Any()).Times(1).Return(pkgs, nil)
+	dsn := newTestDB(ctx, t)
+	libA := newTestLibindex(ctx, t, dsn, a)
+	defer libA.Close(ctx)
+	libB := newTestLibindex(ctx, t, dsn, b)
+	defer libB.Close(ctx)
+
+	index := func(t *testing.T, lib *Libindex, ls ...*claircore.Layer) {
+		t.Helper()
+		m := &claircore.Manifest{
+			Hash:   test.RandomSHA256Digest(t),
+			Layers: ls,
+		}
+		ir, err := lib.Index(ctx, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ir.Success {
+			t.Fatalf("index failed: %v", ir.Err)
+		}
+	}
+
+	index(t, libA, layers[0], layers[1])
+	queries := metricSum(t, "claircore_indexer_layersscanned_total")
+	cached := metricSum(t, "claircore_indexer_layer_scans_total", "result", "cached")
+	// The second manifest shares a layer with the first, so the second
+	// instance only scans the new one.
+	index(t, libB, layers[1], layers[2])
+	// One query to decide which layers to fetch, and one to decide which
+	// scanners to run on them.
+	if got, want := metricSum(t, "claircore_indexer_layersscanned_total")-queries, 2.0; got != want {
+		t.Errorf("got %v layersscanned queries, want %v", got, want)
+	}
+	if got, want := metricSum(t, "claircore_indexer_layer_scans_total", "result", "cached")-cached, 1.0; got != want {
+		t.Errorf("got %v cached scans, want %v", got, want)
+	}
+
+	// Everything's been scanned now, so neither instance scans again, and
+	// the number of queries doesn't grow with the number of layers.
+	queries = metricSum(t, "claircore_indexer_layersscanned_total")
+	cached = metricSum(t, "claircore_indexer_layer_scans_total", "result", "cached")
+	index(t, libA, layers[2], layers[1], layers[0])
+	if got, want := metricSum(t, "claircore_indexer_layersscanned_total")-queries, 2.0; got != want {
+		t.Errorf("got %v layersscanned queries, want %v", got, want)
+	}
+	if got, want := metricSum(t, "claircore_indexer_layer_scans_total", "result", "cached")-cached, 3.0; got != want {
+		t.Errorf("got %v cached scans, want %v", got, want)
+	}
+}
+
+// MetricSum returns the sum of the named counter's values in the default
+// registry, restricted to series with the label pair, if provided.
+func metricSum(t *testing.T, name string, label ...string) float64 {
+	t.Helper()
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+	Metric:
+		for _, m := range mf.GetMetric() {
+			if len(label) == 2 {
+				for _, lp := range m.GetLabel() {
+					if lp.GetName() == label[0] && lp.GetValue() != label[1] {
+						continue Metric
+					}
+				}
+			}
+			sum += m.GetCounter().GetValue()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,93 @@
+package libindex
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestIndexWarnings indexes a manifest with a layer served with an
+// unsupported media type, and checks that the layer is indexed and the
+// warning is in both the returned and the stored report.
+func TestIndexWarnings(t *testing.T) {
+	integration.NeedDB(t)
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "etc/hostname",
+		Size:     5,
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("host\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", "application/x-unexpected")
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(buf.Bytes())
+	d, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newMockScanner(ctrl)
+	s.EXPECT().Scan(gomock.Any(), gomock.Any()).Times(1).Return(test.GenUniquePackages(1), nil)
+	lib := newTestLibindex(ctx, t, newTestDB(ctx, t), s)
+	defer lib.Close(ctx)
+	m := &claircore.Manifest{
+		Hash:   test.RandomSHA256Digest(t),
+		Layers: []*claircore.Layer{{Hash: d, URI: srv.URL}},
+	}
+	ir, err := lib.Index(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %v", ir.Err)
+	}
+	stored, ok, err := lib.IndexReport(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("no stored report")
+	}
+	for _, r := range []*claircore.IndexReport{ir, stored} {
+		if len(r.Warnings) != 1 {
+			t.Fatalf("got %d warnings, want 1: %v", len(r.Warnings), r.Warnings)
+		}
+		w := r.Warnings[0]
+		if got, want := w.Code, claircore.WarningUnsupportedMediaType; got != want {
+			t.Errorf("got code %q, want %q", got, want)
+		}
+		if got, want := w.Subject, d.String(); got != want {
+			t.Errorf("got subject %q, want %q", got, want)
+		}
+	}
+}
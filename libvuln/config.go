@@ -0,0 +1,258 @@
+package libvuln
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/matchers"
+	"github.com/quay/claircore/matchers/registry"
+)
+
+// ErrNotReloadable is wrapped by errors returned from UpdateConfig when the
+// new Opts change a setting that only takes effect in New.
+var ErrNotReloadable = errors.New("libvuln: setting can't be changed at runtime")
+
+var configReloads = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "claircore",
+		Subsystem: "libvuln",
+		Name:      "config_reloads_total",
+		Help:      "Total number of calls to UpdateConfig, by result.",
+	},
+	[]string{"result"},
+)
+
+// UpdateConfig replaces the configuration used by subsequent calls to Scan.
+//
+// Only the following Opts fields are reloadable:
+//
+//	MatcherNames
+//	MatcherConfigs
+//	Matchers
+//	Enrichers
+//	EnricherConfigs
+//	ScanOptions
+//
+// Every other field must be left as it was passed to New, either before or
+// after New filled in defaults, or an error wrapping ErrNotReloadable is
+// returned. The usual way to change the configuration is to modify a copy of
+// the Opts used to construct the Libvuln.
+//
+// The new configuration is validated and its Matchers constructed before
+// anything is swapped; if that fails, the current configuration stays in use.
+// Scan calls already running keep the configuration they started with.
+//
+// Enrichers named in EnricherConfigs are configured before the swap, so they
+// should be new values rather than ones the current configuration is using.
+func (l *Libvuln) UpdateConfig(ctx context.Context, opts *Opts) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.UpdateConfig"))
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	cfg, err := l.loadConfig(ctx, opts)
+	if err != nil {
+		configReloads.WithLabelValues("error").Inc()
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("configuration not updated")
+		return err
+	}
+	l.cfgMu.Lock()
+	l.matchers = cfg.matchers
+	l.enrichers = cfg.enrichers
+	l.scanOptions = cfg.scanOptions
+	l.cfgMu.Unlock()
+	configReloads.WithLabelValues("success").Inc()
+	zlog.Info(ctx).
+		Int("matchers", len(cfg.matchers)).
+		Int("enrichers", len(cfg.enrichers)).
+		Int("scan_options", len(cfg.scanOptions)).
+		Msg("configuration updated")
+	return nil
+}
+
+// ScanConfig is the reloadable part of a Libvuln's configuration.
+type scanConfig struct {
+	matchers    []driver.Matcher
+	enrichers   []driver.Enricher
+	scanOptions []ScanOption
+}
+
+// Current returns the configuration for a Scan call to use.
+func (l *Libvuln) currentConfig() scanConfig {
+	l.cfgMu.RLock()
+	defer l.cfgMu.RUnlock()
+	return scanConfig{
+		matchers:    l.matchers,
+		enrichers:   l.enrichers,
+		scanOptions: l.scanOptions,
+	}
+}
+
+// LoadConfig checks the reloadable fields of "o" and constructs the
+// configuration they describe.
+func (l *Libvuln) loadConfig(ctx context.Context, o *Opts) (scanConfig, error) {
+	var cfg scanConfig
+	if o == nil {
+		return cfg, errors.New("libvuln: nil Opts")
+	}
+	if err := l.checkReloadable(o); err != nil {
+		return cfg, err
+	}
+
+	reg := registry.Registered()
+	var unknown []string
+	for _, n := range o.MatcherNames {
+		if _, ok := reg[n]; !ok {
+			unknown = append(unknown, n)
+		}
+	}
+	for n := range o.MatcherConfigs {
+		if _, ok := reg[n]; !ok {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return cfg, fmt.Errorf("libvuln: unknown matchers: %s", strings.Join(unknown, ", "))
+	}
+	enrichers := make(map[string]driver.Enricher, len(o.Enrichers))
+	for _, e := range o.Enrichers {
+		enrichers[e.Name()] = e
+	}
+	for n := range o.EnricherConfigs {
+		if _, ok := enrichers[n]; !ok {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return cfg, fmt.Errorf("libvuln: configuration for unknown enrichers: %s", strings.Join(unknown, ", "))
+	}
+
+	client := o.Client
+	if client == nil {
+		client = l.client
+	}
+	if err := configureEnrichers(ctx, o.Enrichers, o.EnricherConfigs, client); err != nil {
+		return cfg, err
+	}
+	ms, err := matchers.NewMatchers(ctx,
+		client,
+		matchers.WithEnabled(o.MatcherNames),
+		matchers.WithConfigs(o.MatcherConfigs),
+		matchers.WithOutOfTree(o.Matchers),
+	)
+	if err != nil {
+		return cfg, fmt.Errorf("libvuln: unable to create matchers: %w", err)
+	}
+	cfg.matchers = ms
+	cfg.enrichers = o.Enrichers
+	cfg.scanOptions = o.ScanOptions
+	return cfg, nil
+}
+
+// ConfigureEnrichers configures every Enricher that implements
+// driver.Configurable and has an entry in "cfgs".
+func configureEnrichers(ctx context.Context, es []driver.Enricher, cfgs map[string]driver.ConfigUnmarshaler, c *http.Client) error {
+	for _, e := range es {
+		f, ok := cfgs[e.Name()]
+		if !ok {
+			continue
+		}
+		ce, ok := e.(driver.Configurable)
+		if !ok {
+			zlog.Warn(ctx).
+				Str("enricher", e.Name()).
+				Msg("configuration provided for enricher that isn't configurable")
+			continue
+		}
+		if err := ce.Configure(ctx, f, c); err != nil {
+			return fmt.Errorf("libvuln: unable to configure enricher %q: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// CheckReloadable reports an error wrapping ErrNotReloadable if "o" changes
+// any setting UpdateConfig can't. A setting is unchanged if it's equal to the
+// value passed to New or the value New filled in.
+func (l *Libvuln) checkReloadable(o *Opts) error {
+	a, b := &l.given, &l.parsed
+	var changed []string
+	same := func(name string, ok bool) {
+		if !ok {
+			changed = append(changed, name)
+		}
+	}
+	same("MaxConnPool", o.MaxConnPool == a.MaxConnPool || o.MaxConnPool == b.MaxConnPool)
+	same("ConnString", o.ConnString == a.ConnString)
+	same("UpdateInterval", o.UpdateInterval == a.UpdateInterval || o.UpdateInterval == b.UpdateInterval)
+	same("Migrations", o.Migrations == a.Migrations)
+	same("UpdaterSets", (o.UpdaterSets == nil) == (a.UpdaterSets == nil) && equalStrings(o.UpdaterSets, a.UpdaterSets))
+	same("Updaters", equalStrings(updaterNames(o.Updaters), updaterNames(a.Updaters)))
+	same("PrefixDuplicateUpdaters", o.PrefixDuplicateUpdaters == a.PrefixDuplicateUpdaters)
+	same("UpdateWorkers", o.UpdateWorkers == a.UpdateWorkers || o.UpdateWorkers == b.UpdateWorkers)
+	same("UpdateRetention", o.UpdateRetention == a.UpdateRetention)
+	same("UpdateMalformedLimit", o.UpdateMalformedLimit == a.UpdateMalformedLimit)
+	same("MaxEnrichmentSize", o.MaxEnrichmentSize == a.MaxEnrichmentSize)
+	same("RejectInvalidEnrichments", o.RejectInvalidEnrichments == a.RejectInvalidEnrichments)
+	same("DisableBackgroundUpdates", o.DisableBackgroundUpdates == a.DisableBackgroundUpdates)
+	same("DrainTimeout", o.DrainTimeout == a.DrainTimeout || o.DrainTimeout == b.DrainTimeout)
+	same("UpdaterConfigs", equalStrings(configNames(o.UpdaterConfigs), configNames(a.UpdaterConfigs)))
+	same("Client", o.Client == a.Client || o.Client == b.Client)
+	same("MigrationAssist", o.MigrationAssist == a.MigrationAssist)
+	same("Namespace", o.Namespace == a.Namespace)
+	same("Snapshot", o.Snapshot == a.Snapshot)
+	same("SnapshotTopUp", o.SnapshotTopUp == a.SnapshotTopUp)
+	same("StatsInterval", o.StatsInterval == a.StatsInterval)
+	same("Store", o.Store == a.Store)
+	if len(changed) != 0 {
+		return fmt.Errorf("%w: %s", ErrNotReloadable, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func updaterNames(us []driver.Updater) []string {
+	out := make([]string, len(us))
+	for i, u := range us {
+		out[i] = u.Name()
+	}
+	return out
+}
+
+func configNames(m map[string]driver.ConfigUnmarshaler) []string {
+	out := make([]string, 0, len(m))
+	for n := range m {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
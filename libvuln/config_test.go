@@ -0,0 +1,170 @@
+package libvuln
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// BlockingStore is an exclusionStore that holds Get calls until "release" is
+// closed, after reporting them on "entered".
+type blockingStore struct {
+	*exclusionStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, rs []*claircore.IndexRecord, opts vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	s.entered <- struct{}{}
+	<-s.release
+	return s.exclusionStore.Get(ctx, rs, opts)
+}
+
+// TestUpdateConfig checks that UpdateConfig changes the matchers and options
+// used by later Scan calls, and rejects bad configurations.
+func TestUpdateConfig(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	issued := time.Now().Add(-48 * time.Hour)
+	s := &exclusionStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10", Issued: issued},
+		},
+	}
+	l := &Libvuln{
+		store:    s,
+		client:   &http.Client{},
+		matchers: []driver.Matcher{&alpine.Matcher{}},
+	}
+	findings := func(t *testing.T) int {
+		t.Helper()
+		vr, err := l.Scan(ctx, ir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(vr.PackageVulnerabilities["1"])
+	}
+	if got, want := findings(t), 1; got != want {
+		t.Fatalf("got %d findings, want %d", got, want)
+	}
+
+	t.Run("Matchers", func(t *testing.T) {
+		if err := l.UpdateConfig(ctx, &Opts{MatcherNames: []string{"debian-matcher"}}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := findings(t), 0; got != want {
+			t.Errorf("got %d findings, want %d", got, want)
+		}
+		if err := l.UpdateConfig(ctx, &Opts{MatcherNames: []string{"alpine-matcher"}}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := findings(t), 1; got != want {
+			t.Errorf("got %d findings, want %d", got, want)
+		}
+	})
+	t.Run("ScanOptions", func(t *testing.T) {
+		err := l.UpdateConfig(ctx, &Opts{
+			MatcherNames: []string{"alpine-matcher"},
+			ScanOptions:  []ScanOption{WithMaxVulnerabilityAge(24 * time.Hour)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := findings(t), 0; got != want {
+			t.Errorf("got %d findings, want %d", got, want)
+		}
+		// Options passed to Scan override the configured ones.
+		vr, err := l.Scan(ctx, ir, WithMaxVulnerabilityAge(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(vr.PackageVulnerabilities["1"]), 1; got != want {
+			t.Errorf("got %d findings, want %d", got, want)
+		}
+		if err := l.UpdateConfig(ctx, &Opts{MatcherNames: []string{"alpine-matcher"}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		tt := []struct {
+			Name string
+			Opts Opts
+		}{
+			{Name: "UnknownMatcher", Opts: Opts{MatcherNames: []string{"nope"}}},
+			{Name: "UnknownEnricher", Opts: Opts{
+				EnricherConfigs: map[string]driver.ConfigUnmarshaler{
+					"nope": func(interface{}) error { return nil },
+				},
+			}},
+			{Name: "NotReloadable", Opts: Opts{
+				MatcherNames: []string{"debian-matcher"},
+				ConnString:   "host=elsewhere",
+			}},
+		}
+		for _, tc := range tt {
+			t.Run(tc.Name, func(t *testing.T) {
+				err := l.UpdateConfig(ctx, &tc.Opts)
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				t.Log(err)
+				if tc.Name == "NotReloadable" && !errors.Is(err, ErrNotReloadable) {
+					t.Errorf("got error %v, want %v", err, ErrNotReloadable)
+				}
+				if got, want := findings(t), 1; got != want {
+					t.Errorf("configuration changed: got %d findings, want %d", got, want)
+				}
+			})
+		}
+	})
+	t.Run("InFlight", func(t *testing.T) {
+		bs := &blockingStore{
+			exclusionStore: s,
+			entered:        make(chan struct{}),
+			release:        make(chan struct{}),
+		}
+		l.store = bs
+		errs := make(chan error, 1)
+		var vr *claircore.VulnerabilityReport
+		go func() {
+			var err error
+			vr, err = l.Scan(ctx, ir)
+			errs <- err
+		}()
+		<-bs.entered
+		if err := l.UpdateConfig(ctx, &Opts{MatcherNames: []string{"debian-matcher"}}); err != nil {
+			t.Fatal(err)
+		}
+		close(bs.release)
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		l.store = s
+		if got, want := len(vr.PackageVulnerabilities["1"]), 1; got != want {
+			t.Errorf("in-flight scan: got %d findings, want %d", got, want)
+		}
+		if got, want := findings(t), 0; got != want {
+			t.Errorf("got %d findings, want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,6 @@
+// Package libvuln is the matching half of claircore: it keeps the
+// vulnerability database up to date and matches index reports against it.
+//
+// The exported API of this package is kept compatible between releases. See
+// the claircore package documentation.
+package libvuln
@@ -0,0 +1,7 @@
+// Package driver defines the interfaces implemented by updaters, matchers,
+// and enrichers, along with the helpers they share.
+//
+// The exported API of this package is kept compatible between releases, so
+// that out-of-tree drivers keep building. See the claircore
+// package documentation.
+package driver
@@ -2,8 +2,10 @@ package driver
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/json"
 	"io"
+	"sort"
 
 	"github.com/quay/claircore"
 )
@@ -18,6 +20,62 @@ type EnrichmentRecord struct {
 // This EnrichmentRecord is basically using json.RawMessage to represent "Any"
 // in a way that will be able to be queried if needed in the future.
 
+// HashEnrichment reports the kind and value of the hash a Store identifies the
+// record by. The order of the record's tags doesn't matter, and the record
+// isn't modified.
+//
+// Callers pushing enrichments can use this to check which records are already
+// stored without re-sending them.
+func HashEnrichment(r *EnrichmentRecord) (kind string, hash []byte) {
+	tags := append([]string(nil), r.Tags...)
+	sort.Strings(tags)
+	h := md5.New()
+	for _, t := range tags {
+		io.WriteString(h, t)
+		h.Write([]byte("\x00"))
+	}
+	h.Write(r.Enrichment)
+	return "md5", h.Sum(nil)
+}
+
+// MergeEnrichments groups the enrichment data in the provided records by tag.
+//
+// A record appears under every wanted tag it carries. Identical data appears
+// only once per tag, in the order first seen. Tags with no records are not
+// present in the returned map. If "wanted" is empty, every tag is wanted.
+func MergeEnrichments(records []EnrichmentRecord, wanted []string) map[string][]json.RawMessage {
+	var want map[string]struct{}
+	if len(wanted) != 0 {
+		want = make(map[string]struct{}, len(wanted))
+		for _, t := range wanted {
+			want[t] = struct{}{}
+		}
+	}
+	out := make(map[string][]json.RawMessage)
+	seen := make(map[string]map[string]struct{})
+	for _, r := range records {
+		for _, t := range r.Tags {
+			if want != nil {
+				if _, ok := want[t]; !ok {
+					continue
+				}
+			}
+			s, ok := seen[t]
+			if !ok {
+				s = make(map[string]struct{})
+				seen[t] = s
+			}
+			k := string(r.Enrichment)
+			if _, ok := s[k]; ok {
+				continue
+			}
+			s[k] = struct{}{}
+			out[t] = append(out[t], r.Enrichment)
+		}
+	}
+	return out
+}
+
 // EnrichmentUpdater fetches an Enrichment data source, parses its contents,
 // and returns individual EnrichmentRecords.
 type EnrichmentUpdater interface {
@@ -38,6 +96,18 @@ type EnrichmentUpdater interface {
 	ParseEnrichment(context.Context, io.ReadCloser) ([]EnrichmentRecord, error)
 }
 
+// EnrichmentValidator is an additional interface an EnrichmentUpdater may
+// implement to check its records before they're stored, such as against the
+// schema of its enrichment type.
+//
+// Records that fail validation are treated as malformed: they're skipped and
+// counted, and the update fails if too many of them are, as described by
+// RecordPolicy.
+type EnrichmentValidator interface {
+	// ValidateEnrichment reports an error if the record shouldn't be stored.
+	ValidateEnrichment(context.Context, *EnrichmentRecord) error
+}
+
 // NoopUpdater is designed to be embedded into other Updater types so they can
 // be used in the original updater machinery.
 //
@@ -69,6 +139,41 @@ type EnrichmentGetter interface {
 	GetEnrichment(context.Context, []string) ([]EnrichmentRecord, error)
 }
 
+// VulnerabilityQuery identifies a vulnerability in a package, to be looked up
+// across all releases of a distribution.
+type VulnerabilityQuery struct {
+	// Name is the name of the vulnerability, such as a CVE ID.
+	Name string
+	// Package is the name of the affected package.
+	Package string
+	// DistributionID is the os-release "ID" of the distribution.
+	DistributionID string
+}
+
+// VulnerabilityGetter is a handle to look up vulnerabilities in the
+// vulnerability store, for Enrichers that use the store's vulnerability data
+// as their data source.
+//
+// The EnrichmentGetter provided to an Enricher also implements this interface
+// if the store supports it.
+type VulnerabilityGetter interface {
+	// GetVulnerabilities returns the vulnerabilities matching each query, in
+	// the same order as the queries.
+	GetVulnerabilities(context.Context, []VulnerabilityQuery) ([][]*claircore.Vulnerability, error)
+}
+
+// AliasResolver is a handle to look up the other names vulnerabilities are
+// known by, such as the CVEs an advisory addresses, as declared by updaters.
+//
+// The EnrichmentGetter provided to an Enricher also implements this interface
+// if the store supports it.
+type AliasResolver interface {
+	// ResolveAliases reports every name connected to each of the provided
+	// names through the recorded aliases, directly or through other names.
+	// Names with no aliases aren't present in the returned map.
+	ResolveAliases(context.Context, []string) (map[string][]string, error)
+}
+
 // Enricher is the interface for enriching a vulnerability report.
 //
 // Enrichers are called after the VulnerabilityReport is constructed.
@@ -88,3 +193,35 @@ type Enricher interface {
 	// explaining to the client how to interpret the data.
 	Enrich(context.Context, EnrichmentGetter, *claircore.VulnerabilityReport) (string, []json.RawMessage, error)
 }
+
+// IndexReportEnricher is an additional interface an Enricher may implement to
+// be provided the IndexReport the VulnerabilityReport was constructed from.
+//
+// If an Enricher implements this interface, EnrichWithIndexReport is called
+// instead of Enrich.
+type IndexReportEnricher interface {
+	Enricher
+	// EnrichWithIndexReport is like Enrich, but additionally receives the
+	// IndexReport describing the contents of the manifest.
+	//
+	// Enrichers may not modify the passed IndexReport or VulnerabilityReport.
+	// Doing so may panic the program.
+	EnrichWithIndexReport(context.Context, EnrichmentGetter, *claircore.IndexReport, *claircore.VulnerabilityReport) (string, []json.RawMessage, error)
+}
+
+// RiskScorer computes the RiskHint for a finding when a VulnerabilityReport is
+// assembled.
+type RiskScorer interface {
+	// Name is a unique name for this RiskScorer. It's recorded in the hints
+	// it computes.
+	Name() string
+	// Score computes the hint for the vulnerability "v", given the
+	// enrichments reported for it, keyed by enrichment type. Only enrichments
+	// that are maps keyed by vulnerability ID are provided, and only this
+	// vulnerability's entries of them.
+	//
+	// If the returned bool is false, no hint is recorded for the
+	// vulnerability. The Derived and Scorer members of the returned hint are
+	// filled in by the caller.
+	Score(ctx context.Context, v *claircore.Vulnerability, es map[string][]json.RawMessage) (claircore.RiskHint, bool)
+}
@@ -0,0 +1,117 @@
+package driver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeEnrichments(t *testing.T) {
+	a := json.RawMessage(`{"a":1}`)
+	b := json.RawMessage(`{"b":2}`)
+	c := json.RawMessage(`{"c":3}`)
+	tt := []struct {
+		Name    string
+		Records []EnrichmentRecord
+		Wanted  []string
+		Want    map[string][]json.RawMessage
+	}{
+		{
+			Name:    "Empty",
+			Records: nil,
+			Wanted:  []string{"CVE-1"},
+			Want:    map[string][]json.RawMessage{},
+		},
+		{
+			Name: "Simple",
+			Records: []EnrichmentRecord{
+				{Tags: []string{"CVE-1"}, Enrichment: a},
+				{Tags: []string{"CVE-2"}, Enrichment: b},
+			},
+			Wanted: []string{"CVE-1", "CVE-2"},
+			Want: map[string][]json.RawMessage{
+				"CVE-1": {a},
+				"CVE-2": {b},
+			},
+		},
+		{
+			Name: "MultiTag",
+			Records: []EnrichmentRecord{
+				{Tags: []string{"CVE-1", "CVE-2"}, Enrichment: a},
+				{Tags: []string{"CVE-2"}, Enrichment: b},
+			},
+			Wanted: []string{"CVE-1", "CVE-2"},
+			Want: map[string][]json.RawMessage{
+				"CVE-1": {a},
+				"CVE-2": {a, b},
+			},
+		},
+		{
+			Name: "Duplicate",
+			Records: []EnrichmentRecord{
+				{Tags: []string{"CVE-1"}, Enrichment: a},
+				{Tags: []string{"CVE-1", "CVE-2"}, Enrichment: a},
+				{Tags: []string{"CVE-1"}, Enrichment: b},
+				{Tags: []string{"CVE-1"}, Enrichment: a},
+			},
+			Wanted: []string{"CVE-1", "CVE-2"},
+			Want: map[string][]json.RawMessage{
+				"CVE-1": {a, b},
+				"CVE-2": {a},
+			},
+		},
+		{
+			Name: "Unwanted",
+			Records: []EnrichmentRecord{
+				{Tags: []string{"CVE-1", "CVE-3"}, Enrichment: a},
+				{Tags: []string{"CVE-3"}, Enrichment: c},
+			},
+			Wanted: []string{"CVE-1", "CVE-2"},
+			Want: map[string][]json.RawMessage{
+				"CVE-1": {a},
+			},
+		},
+		{
+			Name: "AllWanted",
+			Records: []EnrichmentRecord{
+				{Tags: []string{"CVE-1", "CVE-3"}, Enrichment: a},
+				{Tags: []string{"CVE-3"}, Enrichment: c},
+			},
+			Wanted: nil,
+			Want: map[string][]json.RawMessage{
+				"CVE-1": {a},
+				"CVE-3": {a, c},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := MergeEnrichments(tc.Records, tc.Wanted)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+func TestHashEnrichment(t *testing.T) {
+	a := EnrichmentRecord{Tags: []string{"CVE-2", "CVE-1"}, Enrichment: json.RawMessage(`{"a":1}`)}
+	b := EnrichmentRecord{Tags: []string{"CVE-1", "CVE-2"}, Enrichment: json.RawMessage(`{"a":1}`)}
+	c := EnrichmentRecord{Tags: []string{"CVE-1"}, Enrichment: json.RawMessage(`{"a":1}`)}
+	ka, ha := HashEnrichment(&a)
+	kb, hb := HashEnrichment(&b)
+	_, hc := HashEnrichment(&c)
+	if ka != "md5" || kb != ka {
+		t.Errorf("unexpected hash kinds: %q, %q", ka, kb)
+	}
+	if !cmp.Equal(ha, hb) {
+		t.Error("tag order changed the hash")
+	}
+	if cmp.Equal(ha, hc) {
+		t.Error("different tags hashed the same")
+	}
+	if got, want := a.Tags, []string{"CVE-2", "CVE-1"}; !cmp.Equal(got, want) {
+		t.Error("record modified:", cmp.Diff(got, want))
+	}
+}
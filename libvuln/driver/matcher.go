@@ -2,6 +2,7 @@ package driver
 
 import (
 	"context"
+	"strings"
 
 	"github.com/quay/claircore"
 )
@@ -39,8 +40,43 @@ const (
 	DistributionPrettyName
 	// should match claircore.Package.Repository.Name => claircore.Vulnerability.Package.Repository.Name
 	RepositoryName
+	// should match the part, vendor, and product of the CPE in
+	// claircore.Package.Repository.Name => those of the CPE in
+	// claircore.Vulnerability.Package.Repository.Name, leaving the rest of
+	// the CPEs to be compared by the Matcher. Repository names that aren't CPEs
+	// naming a vendor and product are matched like RepositoryName.
+	RepositoryCPEProduct
 )
 
+// DistributionVersionOp is how a record's distribution version is compared to
+// a vulnerability's for the DistributionVersion and DistributionVersionID
+// constraints.
+type DistributionVersionOp int
+
+const (
+	// DistributionVersionEqual requires the versions to be equal. This is the
+	// default.
+	DistributionVersionEqual DistributionVersionOp = iota
+	// DistributionVersionSeries also allows either version to name a series
+	// containing the other, so "8" matches "8.6" in either direction. A series
+	// ends at a dot: "8" doesn't match "9" or "80".
+	DistributionVersionSeries
+)
+
+// Match reports whether the record's version "rv" matches the vulnerability's
+// version "vv" under the operator.
+func (op DistributionVersionOp) Match(rv, vv string) bool {
+	if rv == vv {
+		return true
+	}
+	switch op {
+	case DistributionVersionSeries:
+		return strings.HasPrefix(rv, vv+".") || strings.HasPrefix(vv, rv+".")
+	default:
+		return false
+	}
+}
+
 // Matcher is an interface which a Controller uses to query the vulnstore for vulnerabilities.
 type Matcher interface {
 	// a unique name for the matcher
@@ -55,6 +91,45 @@ type Matcher interface {
 	Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error)
 }
 
+// DistributionMapper is an additional interface that a Matcher can implement
+// to have the vulnstore queried as though a record was found on a different
+// distribution. This allows distributions without their own vulnerability data
+// to use the data of the distribution they're derived from.
+type DistributionMapper interface {
+	// MapDistribution returns the distribution to query with in place of the
+	// record's, and annotations to add to every vulnerability matched this
+	// way. A nil Distribution means the record is used as-is.
+	MapDistribution(*claircore.IndexRecord) (*claircore.Distribution, map[string]string)
+}
+
+// PackageMapper is an additional interface that a Matcher can implement to
+// have the vulnstore queried as though a record was a different package. This
+// allows packages whose vulnerabilities are filed under another name, like
+// kernels with the ABI in their package names, to be matched.
+//
+// The record passed to Vulnerable is always the original.
+type PackageMapper interface {
+	// MapPackage returns the package to query with in place of the record's.
+	// It must have the same ID as the record's package. A nil Package means
+	// the record is used as-is.
+	MapPackage(*claircore.IndexRecord) *claircore.Package
+}
+
+// FileMatcher is an additional interface that a Matcher can implement to only
+// report a vulnerability if certain files were found with the package, for
+// vulnerabilities that depend on how a package is put together or
+// configured.
+//
+// The check is made after matching, against the package's Files. Packages
+// without recorded files are always reported, because their contents aren't
+// known.
+type FileMatcher interface {
+	// RequiredFiles returns path.Match patterns, at least one of which must
+	// match one of the package's Files for the vulnerability to be reported.
+	// A nil slice means no files are required.
+	RequiredFiles(*claircore.IndexRecord, *claircore.Vulnerability) []string
+}
+
 // VersionFilter is an additional interface that a Matcher can implment to
 // opt-in to using normalized version information in database queries.
 type VersionFilter interface {
@@ -66,3 +141,15 @@ type VersionFilter interface {
 	// be completely normalized into a claircore.Version.
 	VersionAuthoritative() bool
 }
+
+// DistributionVersionRanger is an additional interface that a Matcher can
+// implement to have its DistributionVersion and DistributionVersionID
+// constraints compared as something other than equality. This is for
+// data that names distribution versions at a different specificity than
+// images do, like advisories for a major version covering every point
+// release.
+type DistributionVersionRanger interface {
+	// DistributionVersionOp returns the operator to compare distribution
+	// versions with.
+	DistributionVersionOp() DistributionVersionOp
+}
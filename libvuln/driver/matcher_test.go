@@ -0,0 +1,27 @@
+package driver
+
+import "testing"
+
+func TestDistributionVersionOp(t *testing.T) {
+	tt := []struct {
+		Op           DistributionVersionOp
+		Record, Vuln string
+		Want         bool
+	}{
+		{DistributionVersionEqual, "8", "8", true},
+		{DistributionVersionEqual, "8.6", "8", false},
+		{DistributionVersionSeries, "8.6", "8", true},
+		{DistributionVersionSeries, "8", "8.6", true},
+		{DistributionVersionSeries, "8.6", "8.6", true},
+		{DistributionVersionSeries, "8.6", "8.4", false},
+		{DistributionVersionSeries, "8.6", "9", false},
+		{DistributionVersionSeries, "80", "8", false},
+		{DistributionVersionSeries, "20.04", "20", true},
+		{DistributionVersionSeries, "", "8", false},
+	}
+	for _, tc := range tt {
+		if got, want := tc.Op.Match(tc.Record, tc.Vuln), tc.Want; got != want {
+			t.Errorf("%d: %q ⋚ %q: got: %v, want: %v", tc.Op, tc.Record, tc.Vuln, got, want)
+		}
+	}
+}
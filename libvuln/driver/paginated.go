@@ -0,0 +1,47 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// PaginatedUpdater is an interface an Updater may implement to be fetched a
+// page at a time, for sources that can't be fetched in a single request, such
+// as paginated REST or GraphQL APIs. If an Updater implements this
+// interface, FetchPage is called instead of Fetch and Parse, which can be
+// provided by embedding NoopUpdater.
+//
+// Pages are fetched in order, starting from the empty cursor, until a page
+// reports no next cursor; the vulnerabilities from all the pages make up the
+// update. If the store supports it, progress is saved after every page and an
+// interrupted pass resumes from the last saved cursor on the next run. The
+// update operation is only created once a pass completes.
+//
+// A source that pages by modification time returns an advisory edited during a
+// pass again on a later page. Consecutive vulnerabilities with the same Name
+// are treated as one copy of an advisory, and for each advisory, package, and
+// repository only the vulnerabilities from the last copy are stored.
+type PaginatedUpdater interface {
+	Updater
+	// FetchPage fetches and parses the page at "cursor": the Next member of
+	// the previous page, or the empty string for the first page of a pass.
+	//
+	// The Fingerprint is that of the latest completed update, as with Fetch.
+	// If the source hasn't changed since then, FetchPage should report
+	// Unchanged for the first page.
+	//
+	// Cursors are saved and may be used in a later run, possibly by another
+	// process, so they must not depend on any in-memory state.
+	FetchPage(ctx context.Context, fp Fingerprint, cursor string) (*Page, error)
+}
+
+// Page is one page of a PaginatedUpdater's source.
+type Page struct {
+	Vulnerabilities []*claircore.Vulnerability
+	// Next is the cursor of the following page. It's empty on the last page.
+	Next string
+	// Fingerprint identifies the contents of the completed pass. Only the
+	// last page's is used.
+	Fingerprint Fingerprint
+}
@@ -0,0 +1,28 @@
+package driver
+
+// Progress is a report of how far an Updater has gotten in one phase of an
+// update.
+type Progress struct {
+	// Phase is a short, updater-defined name for what's being done, such as
+	// "decode" or "parse".
+	Phase string
+	// Items is the number of items, such as advisories, processed so far in
+	// the phase.
+	Items int64
+	// Bytes is the number of bytes of the database read so far.
+	Bytes int64
+}
+
+// ProgressFunc is called with progress reports.
+//
+// It may be called very often and must not block.
+type ProgressFunc func(Progress)
+
+// ProgressReporter is an interface Updaters can implement to report their
+// progress through long-running updates.
+type ProgressReporter interface {
+	// SetProgress arranges for progress in subsequent Fetch and Parse calls
+	// to be reported to the provided function. A nil function disables
+	// reporting.
+	SetProgress(ProgressFunc)
+}
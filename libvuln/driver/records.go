@@ -0,0 +1,121 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quay/zlog"
+)
+
+// DefaultMalformedLimit is the fraction of a database's records that may be
+// malformed before a parse fails, if no RecordPolicy says otherwise.
+const DefaultMalformedLimit = 0.1
+
+// ErrMalformed is wrapped by the error a parse returns when too many of a
+// database's records are malformed.
+var ErrMalformed = errors.New("too many malformed records")
+
+// RecordPolicy controls how malformed records in a database are treated.
+type RecordPolicy struct {
+	// Limit is the fraction of records that may be malformed before the
+	// whole database is considered corrupt. A Limit of 0 means
+	// DefaultMalformedLimit; use a negative Limit to allow no malformed
+	// records at all.
+	Limit float64
+	// Malformed, if not nil, is called with every malformed record.
+	Malformed func(id string, err error)
+}
+
+type recordPolicyKey struct{}
+
+// WithRecordPolicy returns a Context that arranges for Records created from
+// it to follow the RecordPolicy.
+//
+// The Manager does this for the Context passed to Parse and ParseEnrichment.
+func WithRecordPolicy(ctx context.Context, p RecordPolicy) context.Context {
+	return context.WithValue(ctx, recordPolicyKey{}, p)
+}
+
+// RecordError is a record that couldn't be parsed.
+type RecordError struct {
+	// ID identifies the record, such as an advisory ID or the name of the
+	// file it came from.
+	ID  string
+	Err error
+}
+
+// Error implements error.
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %q: %v", e.ID, e.Err)
+}
+
+// Unwrap enables errors.Is and errors.As.
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Records keeps account of the records in a database as a parser works
+// through them, so a malformed record can be skipped instead of losing the
+// whole database to it.
+//
+// Parsers should call Ok for every record parsed and Malformed for every one
+// skipped, then return the error from Err, which is only non-nil if so many
+// records were malformed that the database itself is suspect.
+type Records struct {
+	ctx    context.Context
+	policy RecordPolicy
+	ok     int
+	errs   []RecordError
+}
+
+// NewRecords returns a Records following the RecordPolicy in the Context, if
+// any.
+func NewRecords(ctx context.Context) *Records {
+	p, _ := ctx.Value(recordPolicyKey{}).(RecordPolicy)
+	return &Records{ctx: ctx, policy: p}
+}
+
+// Ok counts a record that parsed.
+func (r *Records) Ok() {
+	r.ok++
+}
+
+// Malformed counts a record that didn't parse.
+func (r *Records) Malformed(id string, err error) {
+	r.errs = append(r.errs, RecordError{ID: id, Err: err})
+	zlog.Warn(r.ctx).
+		Err(err).
+		Str("record", id).
+		Msg("malformed record, skipping")
+	if f := r.policy.Malformed; f != nil {
+		f(id, err)
+	}
+}
+
+// Errors returns the malformed records.
+func (r *Records) Errors() []RecordError {
+	return r.errs
+}
+
+// Err reports an error wrapping ErrMalformed if more than the allowed
+// fraction of records were malformed.
+func (r *Records) Err() error {
+	bad := len(r.errs)
+	if bad == 0 {
+		return nil
+	}
+	limit := r.policy.Limit
+	if limit == 0 {
+		limit = DefaultMalformedLimit
+	}
+	total := bad + r.ok
+	if float64(bad)/float64(total) <= limit {
+		zlog.Info(r.ctx).
+			Int("malformed", bad).
+			Int("total", total).
+			Msg("skipped malformed records")
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d (first: %v)", ErrMalformed, bad, total, &r.errs[0])
+}
@@ -0,0 +1,55 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+func TestRecords(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Limit    float64
+		Ok, Bad  int
+		WantFail bool
+	}{
+		{Name: "Clean", Ok: 10},
+		{Name: "UnderDefault", Ok: 19, Bad: 1},
+		{Name: "AtDefault", Ok: 9, Bad: 1},
+		{Name: "OverDefault", Ok: 8, Bad: 2, WantFail: true},
+		{Name: "AllBad", Bad: 3, WantFail: true},
+		{Name: "Raised", Limit: 0.5, Ok: 5, Bad: 5},
+		{Name: "Strict", Limit: -1, Ok: 100, Bad: 1, WantFail: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var seen int
+			ctx := zlog.Test(context.Background(), t)
+			ctx = WithRecordPolicy(ctx, RecordPolicy{
+				Limit:     tc.Limit,
+				Malformed: func(string, error) { seen++ },
+			})
+			r := NewRecords(ctx)
+			for i := 0; i < tc.Ok; i++ {
+				r.Ok()
+			}
+			for i := 0; i < tc.Bad; i++ {
+				r.Malformed(fmt.Sprintf("record-%d", i), errors.New("bad"))
+			}
+			if got, want := seen, tc.Bad; got != want {
+				t.Errorf("got %d callbacks, want %d", got, want)
+			}
+			if got, want := len(r.Errors()), tc.Bad; got != want {
+				t.Errorf("got %d errors, want %d", got, want)
+			}
+			err := r.Err()
+			t.Log(err)
+			if got, want := errors.Is(err, ErrMalformed), tc.WantFail; got != want {
+				t.Errorf("got failure: %v, want: %v", got, want)
+			}
+		})
+	}
+}
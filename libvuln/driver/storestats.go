@@ -0,0 +1,41 @@
+package driver
+
+import "time"
+
+// StoreStats describes the size of a vulnerability store, for spotting bloat
+// before it becomes a problem.
+type StoreStats struct {
+	// Tables has an entry for every table the store uses, sorted by name.
+	//
+	// Tables are shared by every namespace in the database, so these numbers
+	// cover all of them.
+	Tables []TableStats `json:"tables"`
+	// ExpiredUpdateOperations is the number of update operations in the
+	// store's namespace past the retention limit, which GC would delete.
+	ExpiredUpdateOperations int64 `json:"expired_update_operations"`
+	// OrphanedVulnerabilities is the number of vulnerabilities in the store's
+	// namespace not referenced by any update operation, which GC would
+	// delete.
+	OrphanedVulnerabilities int64 `json:"orphaned_vulnerabilities"`
+	// UnreachableEnrichments is the number of enrichment records that can
+	// never be returned. GC reports these, but doesn't delete them.
+	UnreachableEnrichments int64 `json:"unreachable_enrichments"`
+}
+
+// TableStats describes a single table. The row counts are the database's
+// estimates, which are cheap to get but only as current as its statistics.
+type TableStats struct {
+	Name string `json:"name"`
+	// Rows is the estimated number of live rows.
+	Rows int64 `json:"rows"`
+	// DeadRows is the estimated number of dead rows waiting to be vacuumed.
+	DeadRows int64 `json:"dead_rows"`
+	// TotalBytes is the size of the table on disk, including its indexes
+	// and TOAST data.
+	TotalBytes int64 `json:"total_bytes"`
+	// IndexBytes is the size of the table's indexes on disk.
+	IndexBytes int64 `json:"index_bytes"`
+	// LastVacuum is the last time the table was vacuumed, manually or
+	// automatically. It's nil if it never has been.
+	LastVacuum *time.Time `json:"last_vacuum,omitempty"`
+}
@@ -23,6 +23,8 @@ type UpdateOperation struct {
 	Fingerprint Fingerprint `json:"fingerprint"`
 	Date        time.Time   `json:"date"`
 	Kind        UpdateKind  `json:"kind"`
+	// Source is the data source the updater declared, if any.
+	Source *claircore.DataSource `json:"source,omitempty"`
 }
 
 // UpdateDiff represents added or removed vulnerabilities between update operations
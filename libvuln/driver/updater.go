@@ -59,3 +59,11 @@ type ConfigUnmarshaler func(interface{}) error
 type Configurable interface {
 	Configure(context.Context, ConfigUnmarshaler, *http.Client) error
 }
+
+// DataSourcer is an interface that Updaters can implement to describe where
+// their data comes from. The DataSource is recorded with every update
+// operation and reported in the attribution of VulnerabilityReports the
+// data contributed to.
+type DataSourcer interface {
+	DataSource() claircore.DataSource
+}
@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEmptyVersion is the cause of a VersionError for a version that's empty or
+// all whitespace.
+var ErrEmptyVersion = errors.New("empty version")
+
+// VersionError is the result of a Matcher failing to parse a version, meaning
+// it can't be determined whether a record is affected by a vulnerability.
+//
+// Matchers return a VersionError from Vulnerable instead of guessing or
+// failing the whole match. The finding is then reported in a
+// VulnerabilityReport's Indeterminate section, with the error as the reason.
+type VersionError struct {
+	// Version is the version that couldn't be parsed.
+	Version string
+	// Vulnerability is set if Version came from the vulnerability, rather
+	// than the record's package.
+	Vulnerability bool
+	// Err is the parser's error.
+	Err error
+}
+
+// Error implements error.
+func (e *VersionError) Error() string {
+	what := "package"
+	if e.Vulnerability {
+		what = "vulnerability"
+	}
+	return fmt.Sprintf("unparseable %s version %q: %v", what, e.Version, e.Err)
+}
+
+// Unwrap implements the errors unwrapping interface.
+func (e *VersionError) Unwrap() error { return e.Err }
+
+// PackageVersionError returns a VersionError for the package version "v"
+// failing to parse with "err".
+func PackageVersionError(v string, err error) error {
+	return &VersionError{Version: v, Err: err}
+}
+
+// VulnerabilityVersionError returns a VersionError for the vulnerability
+// version "v" failing to parse with "err".
+func VulnerabilityVersionError(v string, err error) error {
+	return &VersionError{Version: v, Vulnerability: true, Err: err}
+}
+
+// EmptyVersion reports whether "v" is empty or all whitespace. Some version
+// parsers accept such versions, so Matchers should check this first and
+// report ErrEmptyVersion.
+func EmptyVersion(v string) bool {
+	return strings.TrimSpace(v) == ""
+}
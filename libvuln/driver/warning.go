@@ -0,0 +1,34 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+)
+
+// WarningFunc is called with non-fatal anomalies found while parsing a
+// database, such as a record that had to be skipped because it referenced
+// something missing from the feed.
+type WarningFunc func(msg string)
+
+type warningKey struct{}
+
+// WithWarnings returns a Context that arranges for warnings reported with
+// Warnf to be passed to "f".
+//
+// The Manager does this for the Context passed to Parse and ParseEnrichment.
+func WithWarnings(ctx context.Context, f WarningFunc) context.Context {
+	return context.WithValue(ctx, warningKey{}, f)
+}
+
+// Warnf reports a parse warning to the function registered with WithWarnings,
+// if any.
+//
+// Warnings are for problems that don't stop the parse; errors should still
+// be returned as errors.
+func Warnf(ctx context.Context, format string, args ...interface{}) {
+	f, ok := ctx.Value(warningKey{}).(WarningFunc)
+	if !ok || f == nil {
+		return
+	}
+	f(fmt.Sprintf(format, args...))
+}
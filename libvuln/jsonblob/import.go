@@ -0,0 +1,58 @@
+package jsonblob
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ vulnstore.Importer = (*Store)(nil)
+
+// ImportUpdates implements vulnstore.Importer.
+//
+// The updates are only added to the Store once "f" returns.
+func (s *Store) ImportUpdates(ctx context.Context, f func(vulnstore.ImportFunc) error) error {
+	type pending struct {
+		ref uuid.UUID
+		e   *Entry
+	}
+	var ps []pending
+	add := func(_ context.Context, im *vulnstore.Import) (uuid.UUID, error) {
+		e := Entry{
+			Vuln: im.Vulns,
+		}
+		e.Date = im.Date
+		if e.Date.IsZero() {
+			e.Date = time.Now()
+		}
+		e.Updater = im.Updater
+		e.Fingerprint = im.Fingerprint
+		e.Source = im.Source
+		ref := uuid.New()
+		ps = append(ps, pending{ref: ref, e: &e})
+		return ref, nil
+	}
+	if err := f(add); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, p := range ps {
+		s.latest[driver.VulnerabilityKind] = p.ref
+		s.entry[p.ref] = p.e
+		s.ops[p.e.Updater] = append([]driver.UpdateOperation{{
+			Ref:         p.ref,
+			Date:        p.e.Date,
+			Fingerprint: p.e.Fingerprint,
+			Updater:     p.e.Updater,
+			Kind:        driver.VulnerabilityKind,
+			Source:      p.e.Source,
+		}}, s.ops[p.e.Updater]...)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 	"sync"
@@ -24,6 +25,7 @@ func New() (*Store, error) {
 	s.ops = make(map[string][]driver.UpdateOperation)
 	s.entry = make(map[uuid.UUID]*Entry)
 	s.latest = make(map[driver.UpdateKind]uuid.UUID)
+	s.staged = make(map[string]*stage)
 	return &s, nil
 }
 
@@ -33,6 +35,8 @@ type Store struct {
 	entry  map[uuid.UUID]*Entry
 	ops    map[string][]driver.UpdateOperation
 	latest map[driver.UpdateKind]uuid.UUID
+	// staged updates aren't written out by Store.
+	staged map[string]*stage
 }
 
 // Load reads in all the records serialized in the provided Reader.
@@ -72,6 +76,7 @@ func (l *Loader) Next() bool {
 			l.next.Updater = l.de.Updater
 			l.next.Fingerprint = l.de.Fingerprint
 			l.next.Date = l.de.Date
+			l.next.Source = l.de.Source
 		}
 		l.next.Vuln = append(l.next.Vuln, l.de.Vuln)
 		l.de.Vuln = nil // Needed to ensure the Decoder allocates new backing memory.
@@ -137,6 +142,7 @@ type CommonEntry struct {
 	Updater     string
 	Fingerprint driver.Fingerprint
 	Date        time.Time
+	Source      *claircore.DataSource `json:",omitempty"`
 }
 
 // DiskEntry is a single vulnerability. It's made from unpacking an Entry's
@@ -183,6 +189,24 @@ func (s *Store) UpdateVulnerabilities(_ context.Context, updater string, fingerp
 	return ref, nil
 }
 
+// SetDataSource records the data source for the update operation "ref".
+func (s *Store) SetDataSource(_ context.Context, ref uuid.UUID, src claircore.DataSource) error {
+	s.Lock()
+	defer s.Unlock()
+	e, ok := s.entry[ref]
+	if !ok {
+		return fmt.Errorf("jsonblob: no update operation %v", ref)
+	}
+	e.Source = &src
+	ops := s.ops[e.Updater]
+	for i := range ops {
+		if ops[i].Ref == ref {
+			ops[i].Source = &src
+		}
+	}
+	return nil
+}
+
 // Copyops assumes all locks are taken care of.
 func (s *Store) copyops(ty driver.UpdateKind, us ...string) map[string][]driver.UpdateOperation {
 	ns := make(map[string]struct{})
@@ -288,3 +312,38 @@ func (s *Store) UpdateEnrichments(ctx context.Context, kind string, fp driver.Fi
 	}}, s.ops[kind]...)
 	return ref, nil
 }
+
+// EnrichmentExists reports whether the record with the provided hash is in
+// the named updater's most recent update.
+func (s *Store) EnrichmentExists(ctx context.Context, updater, hashKind string, hash []byte) (bool, error) {
+	missing, err := s.MissingEnrichments(ctx, updater, hashKind, [][]byte{hash})
+	if err != nil {
+		return false, err
+	}
+	return len(missing) == 0, nil
+}
+
+// MissingEnrichments returns the provided hashes whose records aren't in the
+// named updater's most recent update, in the order provided.
+func (s *Store) MissingEnrichments(_ context.Context, updater, hashKind string, hashes [][]byte) ([][]byte, error) {
+	have := make(map[string]struct{})
+	s.RLock()
+	if ops := s.ops[updater]; len(ops) != 0 && ops[0].Kind == driver.EnrichmentKind {
+		if e, ok := s.entry[ops[0].Ref]; ok {
+			for i := range e.Enrichment {
+				k, h := driver.HashEnrichment(&e.Enrichment[i])
+				if k == hashKind {
+					have[string(h)] = struct{}{}
+				}
+			}
+		}
+	}
+	s.RUnlock()
+	var missing [][]byte
+	for _, h := range hashes {
+		if _, ok := have[string(h)]; !ok {
+			missing = append(missing, h)
+		}
+	}
+	return missing, nil
+}
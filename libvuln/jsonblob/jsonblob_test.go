@@ -2,6 +2,7 @@ package jsonblob
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/test"
 )
 
@@ -45,8 +47,13 @@ func TestRoundtrip(t *testing.T) {
 		t.Error(err)
 	}
 	t.Logf("ref: %v", ref)
+	src := claircore.DataSource{Name: "test", License: "CC0-1.0"}
+	if err := a.SetDataSource(ctx, ref, src); err != nil {
+		t.Error(err)
+	}
 
 	var got []*claircore.Vulnerability
+	var gotSrc []claircore.DataSource
 	r, w := io.Pipe()
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error { defer w.Close(); return a.Store(w) })
@@ -56,7 +63,11 @@ func TestRoundtrip(t *testing.T) {
 			return err
 		}
 		for l.Next() {
-			got = append(got, l.Entry().Vuln...)
+			e := l.Entry()
+			got = append(got, e.Vuln...)
+			if e.Source != nil {
+				gotSrc = append(gotSrc, *e.Source)
+			}
 		}
 		if err := l.Err(); err != nil {
 			return err
@@ -70,4 +81,51 @@ func TestRoundtrip(t *testing.T) {
 	if !cmp.Equal(got, vs) {
 		t.Error(cmp.Diff(got, vs))
 	}
+	if want := []claircore.DataSource{src}; !cmp.Equal(gotSrc, want) {
+		t.Error(cmp.Diff(gotSrc, want))
+	}
+}
+
+func TestMissingEnrichments(t *testing.T) {
+	ctx := context.Background()
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := []driver.EnrichmentRecord{
+		{Tags: []string{"CVE-2021-00001"}, Enrichment: json.RawMessage(`{"score":1}`)},
+		{Tags: []string{"CVE-2021-00002"}, Enrichment: json.RawMessage(`{"score":2}`)},
+	}
+	absent := driver.EnrichmentRecord{Tags: []string{"CVE-2021-00003"}, Enrichment: json.RawMessage(`{"score":3}`)}
+	if _, err := s.UpdateEnrichments(ctx, "test", "", es); err != nil {
+		t.Fatal(err)
+	}
+	kind, present := driver.HashEnrichment(&es[0])
+	_, missing := driver.HashEnrichment(&absent)
+
+	for _, tc := range []struct {
+		Name    string
+		Updater string
+		Hash    []byte
+		Want    bool
+	}{
+		{Name: "Present", Updater: "test", Hash: present, Want: true},
+		{Name: "Absent", Updater: "test", Hash: missing, Want: false},
+		{Name: "WrongUpdater", Updater: "other", Hash: present, Want: false},
+	} {
+		got, err := s.EnrichmentExists(ctx, tc.Updater, kind, tc.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%s: got %v, want %v", tc.Name, got, tc.Want)
+		}
+	}
+	got, err := s.MissingEnrichments(ctx, "test", kind, [][]byte{present, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{missing}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
 }
@@ -0,0 +1,65 @@
+package jsonblob
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ vulnstore.Staging = (*Store)(nil)
+
+// Stage is an update in progress.
+type stage struct {
+	vulnstore.StagedUpdate
+	vulns []*claircore.Vulnerability
+}
+
+// StagedUpdate implements vulnstore.Staging.
+func (s *Store) StagedUpdate(_ context.Context, updater string) (*vulnstore.StagedUpdate, error) {
+	s.RLock()
+	defer s.RUnlock()
+	st, ok := s.staged[updater]
+	if !ok {
+		return nil, nil
+	}
+	u := st.StagedUpdate
+	return &u, nil
+}
+
+// StagePage implements vulnstore.Staging.
+func (s *Store) StagePage(_ context.Context, updater string, prev driver.Fingerprint, next string, vulns []*claircore.Vulnerability) error {
+	s.Lock()
+	defer s.Unlock()
+	st, ok := s.staged[updater]
+	if !ok {
+		st = &stage{}
+		st.Prev = prev
+		s.staged[updater] = st
+	}
+	st.vulns = append(st.vulns, vulns...)
+	st.Cursor = next
+	st.Pages++
+	st.Count += len(vulns)
+	return nil
+}
+
+// StagedVulnerabilities implements vulnstore.Staging.
+func (s *Store) StagedVulnerabilities(_ context.Context, updater string) ([]*claircore.Vulnerability, error) {
+	s.RLock()
+	defer s.RUnlock()
+	st, ok := s.staged[updater]
+	if !ok {
+		return nil, nil
+	}
+	return append([]*claircore.Vulnerability(nil), st.vulns...), nil
+}
+
+// DiscardStaged implements vulnstore.Staging.
+func (s *Store) DiscardStaged(_ context.Context, updater string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.staged, updater)
+	return nil
+}
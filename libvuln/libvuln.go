@@ -2,7 +2,12 @@ package libvuln
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"runtime/trace"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -11,10 +16,13 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/inflight"
 	"github.com/quay/claircore/internal/matcher"
+	"github.com/quay/claircore/internal/multierr"
 	"github.com/quay/claircore/internal/vulnstore"
 	"github.com/quay/claircore/internal/vulnstore/postgres"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/risk"
 	"github.com/quay/claircore/libvuln/updates"
 	"github.com/quay/claircore/matchers"
 )
@@ -25,40 +33,118 @@ import (
 // Libvuln also runs background updaters which keep the vulnerability
 // database consistent.
 type Libvuln struct {
-	store           vulnstore.Store
+	store vulnstore.Store
+	// nil if the store was provided in Opts.
 	pool            *pgxpool.Pool
-	matchers        []driver.Matcher
-	enrichers       []driver.Enricher
+	client          *http.Client
 	updateRetention int
 	updaters        *updates.Manager
+	drainTimeout    time.Duration
+	// tracks in-flight operations, so Close can wait for them.
+	inflight inflight.Tracker
+	// stops the background updater, if running.
+	stopUpdates func()
+	updatesDone chan struct{}
+	// stops the background stats refresh, if running.
+	stopStats func()
+	statsDone chan struct{}
+	// build date of the snapshot loaded at construction, if any.
+	snapshotDate time.Time
+	// Opts as passed to New and after defaults were filled in, for
+	// UpdateConfig to compare against.
+	given, parsed Opts
+
+	// guards the reloadable configuration; see UpdateConfig.
+	cfgMu       sync.RWMutex
+	matchers    []driver.Matcher
+	enrichers   []driver.Enricher
+	scanOptions []ScanOption
 }
 
+// ErrClosed is returned by methods called after Close.
+var ErrClosed = inflight.ErrClosed
+
+// ErrInventoryOnly is returned by Scan for IndexReports from an indexer
+// configured for inventory only, which lack the artifacts matching needs.
+var ErrInventoryOnly = errors.New("libvuln: index report is inventory only")
+
 // New creates a new instance of the Libvuln library
 func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libvuln/New"))
 
-	err := opts.parse(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	zlog.Info(ctx).
-		Int32("count", opts.MaxConnPool).
-		Msg("initializing store")
-	if err := opts.migrations(ctx); err != nil {
-		return nil, err
+	given := *opts
+	// Check everything that can be checked before doing any work, so that
+	// every problem is reported at once.
+	errs := []error{opts.parse(ctx)}
+	var pool *pgxpool.Pool
+	if _, err := pgxpool.ParseConfig(opts.ConnString); opts.Store == nil && opts.ConnString != "" && err == nil {
+		pool, err = opts.pool(ctx)
+		errs = append(errs, err)
 	}
-	pool, err := opts.pool(ctx)
-	if err != nil {
-		return nil, err
+	if err := multierr.Join(errs...); err != nil {
+		if pool != nil {
+			pool.Close()
+		}
+		return nil, fmt.Errorf("libvuln: invalid configuration: %w", err)
 	}
 
 	l := &Libvuln{
-		store:           postgres.NewVulnStore(pool),
-		pool:            pool,
+		client:          opts.Client,
 		updateRetention: opts.UpdateRetention,
 		enrichers:       opts.Enrichers,
+		scanOptions:     opts.ScanOptions,
+		drainTimeout:    opts.DrainTimeout,
+		given:           given,
+		parsed:          *opts,
+	}
+	var err error
+	var locks updates.LockSource
+	switch {
+	case opts.Store != nil:
+		zlog.Info(ctx).
+			Msg("using provided store")
+		l.store = opts.Store
+		locks = updates.LocalLockSource()
+	default:
+		zlog.Info(ctx).
+			Int32("count", opts.MaxConnPool).
+			Msg("initializing store")
+		if err := opts.migrations(ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		storeOpts := []postgres.Option{
+			postgres.WithNamespace(opts.Namespace),
+		}
+		if opts.MigrationAssist {
+			storeOpts = append(storeOpts, postgres.WithMigrationAssist(0))
+		}
+		if opts.MaxEnrichmentSize != 0 {
+			storeOpts = append(storeOpts, postgres.WithMaxEnrichmentSize(opts.MaxEnrichmentSize))
+		}
+		if opts.RejectInvalidEnrichments {
+			storeOpts = append(storeOpts, postgres.WithRejectInvalidEnrichments())
+		}
+		l.store = postgres.NewVulnStore(pool, storeOpts...)
+		l.pool = pool
+		locks, err = updates.PoolLockSource(pool, 0)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+	if err := configureEnrichers(ctx, opts.Enrichers, opts.EnricherConfigs, opts.Client); err != nil {
+		l.closePool()
+		return nil, err
+	}
+
+	if opts.Snapshot != "" {
+		l.snapshotDate, err = loadSnapshot(ctx, l.store, opts.Snapshot, opts.SnapshotTopUp)
+		if err != nil {
+			l.closePool()
+			return nil, err
+		}
 	}
 
 	// create matchers based on the provided config.
@@ -70,53 +156,404 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 	)
 	zlog.Info(ctx).Int("len", len(l.matchers)).Msg("matchers created")
 	if err != nil {
+		l.closePool()
 		return nil, err
 	}
 
 	// create update manager
-	locks, err := updates.PoolLockSource(pool, 0)
-	if err != nil {
-		return nil, err
-	}
-	l.updaters, err = updates.NewManager(ctx,
-		l.store,
-		locks,
-		opts.Client,
+	mgrOpts := []updates.ManagerOption{
 		updates.WithBatchSize(opts.UpdateWorkers),
 		updates.WithInterval(opts.UpdateInterval),
 		updates.WithEnabled(opts.UpdaterSets),
 		updates.WithConfigs(opts.UpdaterConfigs),
 		updates.WithOutOfTree(opts.Updaters),
 		updates.WithGC(opts.UpdateRetention),
+		updates.WithMalformedLimit(opts.UpdateMalformedLimit),
+	}
+	if opts.PrefixDuplicateUpdaters {
+		mgrOpts = append(mgrOpts, updates.WithPrefixedDuplicates())
+	}
+	l.updaters, err = updates.NewManager(ctx,
+		l.store,
+		locks,
+		opts.Client,
+		mgrOpts...,
 	)
 	if err != nil {
+		l.closePool()
 		return nil, err
 	}
 
 	// launch background updater
 	if !opts.DisableBackgroundUpdates {
-		go l.updaters.Start(ctx)
+		uctx, stop := context.WithCancel(ctx)
+		l.stopUpdates = stop
+		l.updatesDone = make(chan struct{})
+		go func() {
+			defer close(l.updatesDone)
+			l.updaters.Start(uctx)
+		}()
 	}
-	zlog.Info(ctx).Msg("libvuln initialized")
+	if opts.StatsInterval > 0 {
+		sctx, stop := context.WithCancel(ctx)
+		l.stopStats = stop
+		l.statsDone = make(chan struct{})
+		go func() {
+			defer close(l.statsDone)
+			l.reportStats(sctx, opts.StatsInterval)
+		}()
+	}
+	zlog.Info(ctx).
+		Interface("claircore", claircore.ReadBuildInfo()).
+		Msg("libvuln initialized")
 	return l, nil
 }
 
+// Close stops new operations from starting, stops background updates and
+// stats refreshes, waits for in-flight operations, and then releases the
+// database connections.
+//
+// In-flight operations are canceled if they don't finish within the
+// configured DrainTimeout. If the passed Context is canceled before they
+// return, resources are not released and the Context's error is reported.
+func (l *Libvuln) Close(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.Close"))
+	if l.stopUpdates != nil {
+		l.stopUpdates()
+		select {
+		case <-l.updatesDone:
+		case <-ctx.Done():
+			return fmt.Errorf("libvuln: waiting for background updates: %w", ctx.Err())
+		}
+	}
+	if l.stopStats != nil {
+		l.stopStats()
+		select {
+		case <-l.statsDone:
+		case <-ctx.Done():
+			return fmt.Errorf("libvuln: waiting for stats refresh: %w", ctx.Err())
+		}
+	}
+	if err := l.inflight.Close(ctx, l.drainTimeout); err != nil {
+		return fmt.Errorf("libvuln: waiting for in-flight operations: %w", err)
+	}
+	zlog.Debug(ctx).Msg("in-flight operations drained")
+	l.closePool()
+	return nil
+}
+
+// ClosePool closes the database connections, if Libvuln opened any.
+func (l *Libvuln) closePool() {
+	if l.pool != nil {
+		l.pool.Close()
+	}
+}
+
+// SnapshotDate reports the build date of the snapshot loaded into the store
+// when this Libvuln was constructed: the date of the newest update it
+// contains. This is the age of the vulnerability data until updaters have run.
+//
+// A zero Time is returned if no snapshot was loaded, including when the store
+// already had vulnerabilities and SnapshotTopUp wasn't set.
+func (l *Libvuln) SnapshotDate() time.Time {
+	return l.snapshotDate
+}
+
 // FetchUpdates runs configured updaters.
 func (l *Libvuln) FetchUpdates(ctx context.Context) error {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return l.updaters.Run(ctx)
 }
 
+// OnUpdate registers a function to be called after every successful update.
+//
+// See updates.Manager.OnUpdate for details.
+func (l *Libvuln) OnUpdate(f func(context.Context, updates.UpdateEvent)) {
+	l.updaters.OnUpdate(f)
+}
+
 // Scan creates a VulnerabilityReport given a manifest's IndexReport.
-func (l *Libvuln) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+//
+// Findings matched by an unexpired Exclusion are moved to the report's
+// Suppressed section. Findings that couldn't be evaluated because of an
+// unparseable version are listed in the Indeterminate section; see
+// WithDropIndeterminate.
+//
+// The report's Warnings include the IndexReport's, and flag distributions
+// past their end of life if the eol enricher is configured; see also
+// WithStaleDataWarning.
+//
+// The Opts' ScanOptions are applied before "opts". The matchers, enrichers,
+// and ScanOptions in use are those current when the call starts; see
+// UpdateConfig.
+//
+// If the IndexReport's manifest digest is malformed, an error matching
+// claircore.ErrInvalidDigest is returned before any work is done. An
+// IndexReport marked InventoryOnly is refused with an error wrapping
+// ErrInventoryOnly, rather than producing an incomplete report.
+//
+// Every log line written during the call carries the Context's correlation
+// ID, which is generated if it doesn't have one; see
+// claircore.WithCorrelationID.
+func (l *Libvuln) Scan(ctx context.Context, ir *claircore.IndexReport, opts ...ScanOption) (*claircore.VulnerabilityReport, error) {
+	if ir == nil {
+		return nil, errors.New("libvuln: nil index report")
+	}
+	if err := ir.Hash.Validate(); err != nil {
+		return nil, fmt.Errorf("libvuln: invalid manifest digest: %w", err)
+	}
+	if ir.InventoryOnly {
+		return nil, fmt.Errorf("%w: %v", ErrInventoryOnly, ir.Hash)
+	}
+	ctx, id := claircore.EnsureCorrelationID(ctx)
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.Scan"),
+		label.String("manifest", ir.Hash.String()))
+	ctx, task := trace.NewTask(ctx, "libvuln/Libvuln.Scan")
+	defer task.End()
+	trace.Log(ctx, claircore.CorrelationIDKey, id)
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	cfg := l.currentConfig()
+	var so scanOpts
+	for _, f := range cfg.scanOptions {
+		f(&so)
+	}
+	for _, f := range opts {
+		f(&so)
+	}
+	if err := so.redaction.Validate(); err != nil {
+		return nil, fmt.Errorf("libvuln: %w", err)
+	}
+	now := time.Now()
+	var mo []matcher.Option
+	if so.maxAge > 0 {
+		mo = append(mo, matcher.WithIssuedCutoff(now.Add(-so.maxAge)))
+	}
+	if so.dropIndeterminate {
+		mo = append(mo, matcher.WithDropIndeterminate())
+	}
+	if so.staleAfter > 0 {
+		mo = append(mo, matcher.WithStaleCutoff(now.Add(-so.staleAfter)))
+	}
+	if so.renormalize {
+		mo = append(mo, matcher.WithRenormalizedSeverity())
+	}
+	if so.riskHints {
+		rs := so.riskScorer
+		if rs == nil {
+			rs = risk.Default{}
+		}
+		mo = append(mo, matcher.WithRiskScorer(rs))
+	}
+	es, err := l.store.ListExclusions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("libvuln: unable to list exclusions: %w", err)
+	}
+	active := es[:0]
+	for _, e := range es {
+		if e.Active(now) {
+			active = append(active, e)
+		}
+	}
+	if len(active) != 0 {
+		mo = append(mo, matcher.WithExclusions(active))
+	}
+	var vr *claircore.VulnerabilityReport
 	if s, ok := l.store.(matcher.Store); ok {
-		return matcher.EnrichedMatch(ctx, ir, l.matchers, l.enrichers, s)
+		vr, err = matcher.EnrichedMatch(ctx, ir, cfg.matchers, cfg.enrichers, s, mo...)
+	} else {
+		vr, err = matcher.Match(ctx, ir, cfg.matchers, l.store, mo...)
 	}
-	return matcher.Match(ctx, ir, l.matchers, l.store)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case so.redaction != nil:
+		vr = so.redaction.VulnerabilityReport(vr)
+	case ir.Redaction != nil:
+		// The report's contents came from a redacted IndexReport.
+		if vr.Metadata == nil {
+			vr.Metadata = &claircore.ReportMetadata{}
+		}
+		vr.Metadata.Redaction = ir.Redaction
+	}
+	return vr, nil
+}
+
+// EnrichAffectedManifests runs the configured enrichers once over all the
+// vulnerabilities in "a", as returned by libindex's AffectedManifests, and
+// stores the results in its Enrichments. The enrichers see the same data a
+// Scan started at the same time would, so a notification built from "a"
+// carries the same context as the reports for the affected manifests.
+//
+// Enrichers implementing driver.IndexReportEnricher are run without an
+// IndexReport. "a" must not be modified concurrently.
+func (l *Libvuln) EnrichAffectedManifests(ctx context.Context, a *claircore.AffectedManifests) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.EnrichAffectedManifests"))
+	if a == nil {
+		return errors.New("libvuln: nil affected manifests")
+	}
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	cfg := l.currentConfig()
+	if len(cfg.enrichers) == 0 || len(a.Vulnerabilities) == 0 {
+		return nil
+	}
+	// The enrichers only look at the vulnerabilities, so a report holding
+	// just those stands in for the affected manifests.
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: a.Vulnerabilities,
+	}
+	if err := matcher.Enrich(ctx, vr, cfg.enrichers, l.store); err != nil {
+		return fmt.Errorf("libvuln: unable to enrich affected manifests: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("vulnerabilities", len(a.Vulnerabilities)).
+		Int("kinds", len(vr.Enrichments)).
+		Msg("enriched affected manifests")
+	a.Enrichments = vr.Enrichments
+	return nil
+}
+
+// ScanOption configures a single call to Scan.
+type ScanOption func(*scanOpts)
+
+type scanOpts struct {
+	maxAge            time.Duration
+	dropIndeterminate bool
+	riskHints         bool
+	riskScorer        driver.RiskScorer
+	staleAfter        time.Duration
+	renormalize       bool
+	redaction         *claircore.Redaction
+}
+
+// WithMaxVulnerabilityAge leaves findings for vulnerabilities issued more
+// than "d" ago out of the report. Vulnerabilities without an issued date are
+// always reported.
+//
+// The cutoff and the number of findings left out are recorded in the report's
+// Metadata. A zero duration, the default, reports everything.
+func WithMaxVulnerabilityAge(d time.Duration) ScanOption {
+	return func(o *scanOpts) {
+		o.maxAge = d
+	}
+}
+
+// WithDropIndeterminate leaves findings that couldn't be evaluated, because
+// the package's or the vulnerability's version couldn't be parsed, out of the
+// report. By default they're listed in the report's Indeterminate section.
+//
+// The number of findings left out is recorded in the report's Metadata.
+func WithDropIndeterminate() ScanOption {
+	return func(o *scanOpts) {
+		o.dropIndeterminate = true
+	}
+}
+
+// WithRiskHints records a RiskHint for every vulnerability in the report,
+// computed by "rs" from the vulnerability's severity and enrichments. If "rs"
+// is nil, risk.Default is used.
+//
+// Hints are derived, not reported by any data source, and are only meant for
+// ordering findings.
+func WithRiskHints(rs driver.RiskScorer) ScanOption {
+	return func(o *scanOpts) {
+		o.riskHints = true
+		o.riskScorer = rs
+	}
+}
+
+// WithStaleDataWarning adds a warning with the code claircore.WarningStaleData
+// to the report for every updater that hasn't completed an update in the last
+// "d". A zero duration, the default, adds no warnings.
+func WithStaleDataWarning(d time.Duration) ScanOption {
+	return func(o *scanOpts) {
+		o.staleAfter = d
+	}
+}
+
+// WithRenormalizedSeverity re-derives the NormalizedSeverity of every
+// reported vulnerability from its Severity using claircore.NormalizeSeverity,
+// rather than reporting the value stored when its updater ran. This makes
+// reports consistent when the normalization has changed since older data was
+// stored; see also BackfillSeverity, which rewrites the stored values.
+func WithRenormalizedSeverity() ScanOption {
+	return func(o *scanOpts) {
+		o.renormalize = true
+	}
+}
+
+// WithRedaction redacts the report with "r" before it's returned, so it can
+// be shared outside of where it was produced. The redaction is recorded in the
+// report's Metadata.
+//
+// Reports for IndexReports that were already redacted record the
+// IndexReport's redaction if this option isn't used.
+func WithRedaction(r *claircore.Redaction) ScanOption {
+	return func(o *scanOpts) {
+		o.redaction = r
+	}
+}
+
+// AddExclusion stores an Exclusion, suppressing its findings in reports
+// created by Scan until it expires or is deleted. The stored Exclusion is
+// returned with its ID and creation time set.
+//
+// An error wrapping claircore.ErrInvalidExclusion is returned if the
+// Exclusion is incomplete.
+func (l *Libvuln) AddExclusion(ctx context.Context, e claircore.Exclusion) (*claircore.Exclusion, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return l.store.AddExclusion(ctx, e)
+}
+
+// ListExclusions returns every stored Exclusion, including expired ones,
+// oldest first.
+func (l *Libvuln) ListExclusions(ctx context.Context) ([]claircore.Exclusion, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return l.store.ListExclusions(ctx)
+}
+
+// DeleteExclusion removes the Exclusion with the provided ID, reporting
+// whether it existed.
+func (l *Libvuln) DeleteExclusion(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+	return l.store.DeleteExclusion(ctx, id)
 }
 
 // UpdateOperations returns UpdateOperations in date descending order keyed by the
 // Updater name
 func (l *Libvuln) UpdateOperations(ctx context.Context, kind driver.UpdateKind, updaters ...string) (map[string][]driver.UpdateOperation, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	return l.store.GetUpdateOperations(ctx, kind, updaters...)
 }
 
@@ -126,12 +563,22 @@ func (l *Libvuln) UpdateOperations(ctx context.Context, kind driver.UpdateKind,
 //
 // The number of UpdateOperations deleted is returned.
 func (l *Libvuln) DeleteUpdateOperations(ctx context.Context, ref ...uuid.UUID) (int64, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
 	return l.store.DeleteUpdateOperations(ctx, ref...)
 }
 
 // UpdateDiff returns an UpdateDiff describing the changes between prev
 // and cur.
 func (l *Libvuln) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	return l.store.GetUpdateDiff(ctx, prev, cur)
 }
 
@@ -140,6 +587,11 @@ func (l *Libvuln) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.
 //
 // These references are okay to expose externally.
 func (l *Libvuln) LatestUpdateOperations(ctx context.Context, kind driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	return l.store.GetLatestUpdateRefs(ctx, kind)
 }
 
@@ -148,15 +600,74 @@ func (l *Libvuln) LatestUpdateOperations(ctx context.Context, kind driver.Update
 // This can be used by clients to determine if a call to Scan is likely to
 // return new results.
 func (l *Libvuln) LatestUpdateOperation(ctx context.Context, kind driver.UpdateKind) (uuid.UUID, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
 	return l.store.GetLatestUpdateRef(ctx, kind)
 }
 
+// DataSources reports the data source declared in the latest update operation
+// of every updater, keyed by updater name. Updaters that don't declare one are
+// not present.
+//
+// Reports created by Scan list the subset of these that contributed to them
+// in their metadata's Attribution.
+func (l *Libvuln) DataSources(ctx context.Context) (map[string]claircore.DataSource, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ops, err := l.store.GetLatestUpdateRefs(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]claircore.DataSource, len(ops))
+	for u, ops := range ops {
+		if len(ops) != 0 && ops[0].Source != nil {
+			out[u] = *ops[0].Source
+		}
+	}
+	return out, nil
+}
+
+// EnrichmentExists reports whether the enrichment record with the provided
+// hash is in the named updater's most recent update. Hashes are computed with
+// driver.HashEnrichment.
+func (l *Libvuln) EnrichmentExists(ctx context.Context, updater, hashKind string, hash []byte) (bool, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+	return l.store.EnrichmentExists(ctx, updater, hashKind, hash)
+}
+
+// MissingEnrichments returns the provided hashes whose enrichment records
+// aren't in the named updater's most recent update, in the order provided.
+// Hashes are computed with driver.HashEnrichment.
+func (l *Libvuln) MissingEnrichments(ctx context.Context, updater, hashKind string, hashes [][]byte) ([][]byte, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return l.store.MissingEnrichments(ctx, updater, hashKind, hashes)
+}
+
 // GC will cleanup any update operations older then the configured UpdatesRetention value.
 // GC is throttled and ensure its a good citizen to the database.
 //
 // The returned int is the number of outstanding UpdateOperations not deleted due to throttling.
 // To run GC to completion use the GCFull method.
 func (l *Libvuln) GC(ctx context.Context) (int64, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
 	if l.updateRetention == 0 {
 		return 0, fmt.Errorf("gc is disabled")
 	}
@@ -170,6 +681,11 @@ func (l *Libvuln) GC(ctx context.Context) (int64, error) {
 // GCFull may return an error accompanied by its other return value,
 // the number of oustanding update operations not deleted.
 func (l *Libvuln) GCFull(ctx context.Context) (int64, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
 	if l.updateRetention == 0 {
 		return 0, fmt.Errorf("gc is disabled")
 	}
@@ -188,7 +704,33 @@ func (l *Libvuln) GCFull(ctx context.Context) (int64, error) {
 	return i, err
 }
 
+// BackfillSeverity rewrites the NormalizedSeverity of stored vulnerabilities
+// from their Severity using claircore.NormalizeSeverity, in batches, and
+// reports the number of vulnerabilities changed. It's safe to interrupt and
+// run again.
+//
+// An error is returned if the configured store doesn't implement
+// datastore.SeverityBackfiller.
+func (l *Libvuln) BackfillSeverity(ctx context.Context) (int64, error) {
+	const batch = 10000
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	b, ok := l.store.(vulnstore.SeverityBackfiller)
+	if !ok {
+		return 0, fmt.Errorf("libvuln: store %T does not support backfilling severities", l.store)
+	}
+	return b.BackfillSeverity(ctx, batch)
+}
+
 // Initialized reports whether the backing vulnerability store is initialized.
 func (l *Libvuln) Initialized(ctx context.Context) (bool, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer done()
 	return l.store.Initialized(ctx)
 }
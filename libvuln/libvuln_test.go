@@ -0,0 +1,540 @@
+package libvuln
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/enricher/cvss"
+	"github.com/quay/claircore/enricher/eol"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/test"
+)
+
+// ExclusionStore returns the contained vulnerabilities for every package and
+// the contained exclusions. Calling other methods panics.
+type exclusionStore struct {
+	vulnstore.Store
+	vulns      []*claircore.Vulnerability
+	exclusions []claircore.Exclusion
+}
+
+func (s *exclusionStore) Get(_ context.Context, rs []*claircore.IndexRecord, _ vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range rs {
+		out[r.Package.ID] = s.vulns
+	}
+	return out, nil
+}
+
+func (s *exclusionStore) GetEnrichment(context.Context, string, []string) ([]driver.EnrichmentRecord, error) {
+	return nil, nil
+}
+
+func (s *exclusionStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return map[string][]driver.UpdateOperation{}, nil
+}
+
+func (s *exclusionStore) ListExclusions(context.Context) ([]claircore.Exclusion, error) {
+	return append([]claircore.Exclusion(nil), s.exclusions...), nil
+}
+
+// TestScanExclusions checks that Scan suppresses findings matched by
+// unexpired exclusions.
+func TestScanExclusions(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	s := &exclusionStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10"},
+			{ID: "b", Name: "CVE-2020-0002", FixedInVersion: "1.1.24-r10"},
+			{ID: "c", Name: "CVE-2020-0003", FixedInVersion: "1.1.24-r10"},
+		},
+		exclusions: []claircore.Exclusion{
+			{ID: uuid.New(), Vulnerability: "CVE-2020-0001", Package: "musl", Reason: "forever"},
+			{ID: uuid.New(), Vulnerability: "CVE-2020-0002", Package: "musl", Reason: "until later", Expires: &future},
+			{ID: uuid.New(), Vulnerability: "CVE-2020-0003", Package: "musl", Reason: "expired", Expires: &past},
+		},
+	}
+	l := &Libvuln{
+		store:    s,
+		matchers: []driver.Matcher{&alpine.Matcher{}},
+	}
+
+	vr, err := l.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vr.PackageVulnerabilities["1"], []string{"c"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	want := map[string][]claircore.Suppression{
+		"1": {
+			{VulnerabilityID: "a", Exclusion: s.exclusions[0].ID, Reason: "forever"},
+			{VulnerabilityID: "b", Exclusion: s.exclusions[1].ID, Reason: "until later"},
+		},
+	}
+	if got := vr.Suppressed; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// Once everything's expired, nothing's suppressed.
+	s.exclusions[0].Expires = &past
+	s.exclusions[1].Expires = &past
+	vr, err = l.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vr.PackageVulnerabilities["1"]), 3; got != want {
+		t.Errorf("got %d findings, want %d", got, want)
+	}
+	if len(vr.Suppressed) != 0 {
+		t.Errorf("unexpected suppressions: %v", vr.Suppressed)
+	}
+}
+
+// TestScanInvalidDigest checks that Scan rejects a report with a malformed
+// manifest digest before consulting the store.
+func TestScanInvalidDigest(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := &Libvuln{store: &exclusionStore{}}
+	_, err := l.Scan(ctx, &claircore.IndexReport{})
+	if !errors.Is(err, claircore.ErrInvalidDigest) {
+		t.Errorf("got error %v, want %v", err, claircore.ErrInvalidDigest)
+	}
+}
+
+// TestScanInventoryOnly checks that Scan refuses reports from an indexer
+// configured for inventory only.
+func TestScanInventoryOnly(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := &Libvuln{
+		store:    &exclusionStore{},
+		matchers: []driver.Matcher{&alpine.Matcher{}},
+	}
+	ir := &claircore.IndexReport{
+		Hash:          claircore.MustParseDigest(`sha256:` + strings.Repeat("1", 64)),
+		Packages:      map[string]*claircore.Package{"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"}},
+		InventoryOnly: true,
+	}
+	_, err := l.Scan(ctx, ir)
+	t.Log(err)
+	if !errors.Is(err, ErrInventoryOnly) {
+		t.Errorf("got error %v, want %v", err, ErrInventoryOnly)
+	}
+
+	ir.InventoryOnly = false
+	if _, err := l.Scan(ctx, ir); err != nil {
+		t.Error(err)
+	}
+}
+
+// CorrelationStore is an exclusionStore that records the correlation ID of
+// every Get call, keyed by the first package's name, and holds each call until
+// the expected number have arrived.
+type correlationStore struct {
+	*exclusionStore
+	arrived sync.WaitGroup
+	mu      sync.Mutex
+	ids     map[string]string
+}
+
+func (s *correlationStore) Get(ctx context.Context, rs []*claircore.IndexRecord, opts vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	s.mu.Lock()
+	s.ids[rs[0].Package.Name] = claircore.CorrelationID(ctx)
+	s.mu.Unlock()
+	s.arrived.Done()
+	s.arrived.Wait()
+	return s.exclusionStore.Get(ctx, rs, opts)
+}
+
+// TestScanCorrelationID checks that log lines and store calls from concurrent
+// Scan calls can be told apart by correlation ID.
+func TestScanCorrelationID(t *testing.T) {
+	ctx, logs := test.CaptureLogs(context.Background(), t)
+	report := func(m, pkg string) *claircore.IndexReport {
+		return &claircore.IndexReport{
+			Hash: claircore.MustParseDigest(`sha256:` + m),
+			Packages: map[string]*claircore.Package{
+				"1": {ID: "1", Name: pkg, Version: "1.1.24-r2"},
+			},
+			Distributions: map[string]*claircore.Distribution{
+				"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+			},
+			Environments: map[string][]*claircore.Environment{
+				"1": {{DistributionID: "1"}},
+			},
+		}
+	}
+	irs := []*claircore.IndexReport{
+		report(strings.Repeat("1", 64), "musl"),
+		report(strings.Repeat("2", 64), "busybox"),
+	}
+	s := &correlationStore{
+		exclusionStore: &exclusionStore{},
+		ids:            make(map[string]string),
+	}
+	s.arrived.Add(len(irs))
+	l := &Libvuln{
+		store:    s,
+		matchers: []driver.Matcher{&alpine.Matcher{}},
+	}
+
+	errs := make(chan error, len(irs))
+	for i, ir := range irs {
+		ctx := ctx
+		if i == 0 {
+			// The first caller provides an ID; the second has one generated.
+			ctx = claircore.WithCorrelationID(ctx, "req-1")
+		}
+		go func(ctx context.Context, ir *claircore.IndexReport) {
+			_, err := l.Scan(ctx, ir)
+			errs <- err
+		}(ctx, ir)
+	}
+	for range irs {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byManifest := make(map[string]map[string]int)
+	for _, l := range logs.Lines() {
+		m, ok := l["manifest"].(string)
+		if !ok {
+			continue
+		}
+		id, _ := l[claircore.CorrelationIDKey].(string)
+		if id == "" {
+			t.Errorf("log line without correlation ID: %v", l)
+		}
+		if byManifest[m] == nil {
+			byManifest[m] = make(map[string]int)
+		}
+		byManifest[m][id]++
+	}
+	seen := make(map[string]string)
+	for _, ir := range irs {
+		ids := byManifest[ir.Hash.String()]
+		if len(ids) != 1 {
+			t.Errorf("%v: want exactly one correlation ID, got: %v", ir.Hash, ids)
+			continue
+		}
+		for id := range ids {
+			if other, ok := seen[id]; ok {
+				t.Errorf("%v and %v share correlation ID %q", ir.Hash, other, id)
+			}
+			seen[id] = ir.Hash.String()
+			if got := s.ids[ir.Packages["1"].Name]; got != id {
+				t.Errorf("%v: store call had correlation ID %q, logs had %q", ir.Hash, got, id)
+			}
+		}
+	}
+	if got, want := seen["req-1"], irs[0].Hash.String(); got != want {
+		t.Errorf("caller-provided ID used for %q, want %q", got, want)
+	}
+}
+
+// EnrichmentStore is an exclusionStore that returns the contained records
+// with any of the requested tags, and counts the calls.
+type enrichmentStore struct {
+	*exclusionStore
+	mu    sync.Mutex
+	calls int
+	recs  []driver.EnrichmentRecord
+}
+
+func (s *enrichmentStore) GetEnrichment(_ context.Context, _ string, tags []string) ([]driver.EnrichmentRecord, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	var out []driver.EnrichmentRecord
+	for _, r := range s.recs {
+	Tags:
+		for _, t := range r.Tags {
+			for _, want := range tags {
+				if t == want {
+					out = append(out, r)
+					break Tags
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// TestEnrichAffectedManifests checks that enrichments are attached to affected
+// manifests for the vulnerabilities that have some, with one lookup per
+// vulnerability.
+func TestEnrichAffectedManifests(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := &enrichmentStore{
+		exclusionStore: &exclusionStore{},
+		recs: []driver.EnrichmentRecord{
+			{Tags: []string{"CVE-2021-0001"}, Enrichment: json.RawMessage(`{"baseScore":9.8}`)},
+			{Tags: []string{"CVE-2021-0003"}, Enrichment: json.RawMessage(`{"baseScore":4.3}`)},
+		},
+	}
+	l := &Libvuln{
+		store:     s,
+		enrichers: []driver.Enricher{&cvss.Enricher{}},
+	}
+	vs := []claircore.Vulnerability{
+		{ID: "1", Name: "CVE-2021-0001"},
+		{ID: "2", Name: "CVE-2021-0002"},
+		{ID: "3", Name: "RHSA-2021:0003", Links: "https://access.redhat.com/security/cve/CVE-2021-0003"},
+		{ID: "4", Name: "GHSA-xxxx-yyyy-zzzz"},
+	}
+	a := claircore.NewAffectedManifests()
+	for i := range vs {
+		a.Add(&vs[i], claircore.MustParseDigest(`sha256:`+strings.Repeat("a", 64)))
+	}
+
+	if err := l.EnrichAffectedManifests(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	msgs := a.Enrichments[cvss.Type]
+	if len(msgs) != 1 {
+		t.Fatalf("got %d enrichments, want 1: %v", len(msgs), a.Enrichments)
+	}
+	var got map[string][]json.RawMessage
+	if err := json.Unmarshal(msgs[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]json.RawMessage{
+		"1": {json.RawMessage(`{"baseScore":9.8}`)},
+		"3": {json.RawMessage(`{"baseScore":4.3}`)},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	// Only vulnerabilities mentioning a CVE are looked up, once each.
+	if got, want := s.calls, 3; got != want {
+		t.Errorf("got %d lookups, want %d", got, want)
+	}
+
+	b, err := json.Marshal(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out claircore.AffectedManifests
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.Enrichments[cvss.Type]; !ok {
+		t.Errorf("enrichments missing from JSON: %s", b)
+	}
+
+	// Without enrichers, nothing is attached.
+	a.Enrichments = nil
+	l.enrichers = nil
+	if err := l.EnrichAffectedManifests(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Enrichments != nil {
+		t.Errorf("unexpected enrichments: %v", a.Enrichments)
+	}
+}
+
+// UpdateStore is an exclusionStore that reports the contained update
+// operations.
+type updateStore struct {
+	*exclusionStore
+	ops map[string][]driver.UpdateOperation
+}
+
+func (s *updateStore) GetLatestUpdateRefs(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return s.ops, nil
+}
+
+// TestScanWarnings checks that Scan reports warnings for end-of-life
+// distributions and stale updaters, and passes along the IndexReport's.
+func TestScanWarnings(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	layer := `sha256:` + strings.Repeat("a", 64)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+		Warnings: []claircore.Warning{
+			{Code: claircore.WarningUnsupportedMediaType, Subject: layer, Message: "treated as gzip"},
+		},
+	}
+	now := time.Now()
+	s := &updateStore{
+		exclusionStore: &exclusionStore{},
+		ops: map[string][]driver.UpdateOperation{
+			"alpine-community-v3.12-updater": {{Ref: uuid.New(), Date: now.Add(-72 * time.Hour)}},
+			"alpine-main-v3.12-updater":      {{Ref: uuid.New(), Date: now.Add(-time.Hour)}},
+		},
+	}
+	l := &Libvuln{
+		store:     s,
+		matchers:  []driver.Matcher{&alpine.Matcher{}},
+		enrichers: []driver.Enricher{&eol.Enricher{}},
+	}
+	vr, err := l.Scan(ctx, ir, WithStaleDataWarning(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[claircore.WarningCode]string)
+	for _, w := range vr.Warnings {
+		t.Logf("%s %s: %s", w.Code, w.Subject, w.Message)
+		if _, ok := got[w.Code]; ok {
+			t.Errorf("duplicate warning: %v", w)
+		}
+		got[w.Code] = w.Subject
+	}
+	want := map[claircore.WarningCode]string{
+		claircore.WarningUnsupportedMediaType: layer,
+		claircore.WarningEOLDistribution:      "1",
+		claircore.WarningStaleData:            "alpine-community-v3.12-updater",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// Without the option, stale data isn't reported.
+	vr, err = l.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range vr.Warnings {
+		if w.Code == claircore.WarningStaleData {
+			t.Errorf("unexpected warning: %v", w)
+		}
+	}
+}
+
+// TestScanRedaction checks that redacted IndexReports still match, and that
+// the redaction is recorded in the report.
+func TestScanRedaction(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	red := &claircore.Redaction{
+		Paths: []claircore.PathRedaction{{Prefix: "builds/internal", Mode: claircore.PathHash}},
+	}
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2", PackageDB: "builds/internal/lib/apk/db/installed"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{PackageDB: "builds/internal/lib/apk/db/installed", DistributionID: "1"}},
+		},
+	}
+	l := &Libvuln{
+		store: &exclusionStore{
+			vulns: []*claircore.Vulnerability{
+				{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10"},
+			},
+		},
+		matchers: []driver.Matcher{&alpine.Matcher{}},
+	}
+
+	for _, tc := range []struct {
+		name string
+		ir   *claircore.IndexReport
+		opts []ScanOption
+	}{
+		{name: "Option", ir: ir, opts: []ScanOption{WithRedaction(red)}},
+		{name: "Redacted", ir: red.IndexReport(ir)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			vr, err := l.Scan(ctx, tc.ir, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := vr.PackageVulnerabilities["1"], []string{"a"}; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			if vr.Metadata == nil || !cmp.Equal(vr.Metadata.Redaction, red.Info()) {
+				t.Errorf("redaction not recorded: %+v", vr.Metadata)
+			}
+			if db := vr.Environments["1"][0].PackageDB; strings.Contains(db, "builds/internal") {
+				t.Errorf("path not redacted: %q", db)
+			}
+		})
+	}
+
+	bad := &claircore.Redaction{Paths: []claircore.PathRedaction{{Prefix: "/"}}}
+	if _, err := l.Scan(ctx, ir, WithRedaction(bad)); !errors.Is(err, claircore.ErrInvalidRedaction) {
+		t.Errorf("got error %v, want %v", err, claircore.ErrInvalidRedaction)
+	}
+}
+
+// TestNewWithStore checks that a Libvuln can be constructed around a provided
+// store, without a database.
+func TestNewWithStore(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := &exclusionStore{
+		vulns: []*claircore.Vulnerability{
+			{ID: "a", Name: "CVE-2020-0001", FixedInVersion: "1.1.24-r10"},
+		},
+	}
+	l, err := New(ctx, &Opts{
+		Store:                    s,
+		UpdaterSets:              []string{},
+		MatcherNames:             []string{"alpine-matcher"},
+		DisableBackgroundUpdates: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := l.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	vr, err := l.Scan(ctx, &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vr.PackageVulnerabilities["1"]), 1; got != want {
+		t.Errorf("got %d findings, want %d", got, want)
+	}
+}
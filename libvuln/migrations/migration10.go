@@ -0,0 +1,29 @@
+package migrations
+
+const (
+	// This migration adds tables for staging the pages of an update from a
+	// paginated updater until the pass completes. There's at most one staged
+	// update per updater in a namespace; its vulnerabilities are kept as JSON,
+	// in the order they were staged, and only inserted into the vuln table when
+	// the update is committed.
+	migration10 = `
+CREATE TABLE IF NOT EXISTS update_stage (
+    id          BIGSERIAL PRIMARY KEY,
+    namespace   text NOT NULL DEFAULT '',
+    updater     text NOT NULL,
+    prev        text NOT NULL DEFAULT '',
+    cursor      text NOT NULL DEFAULT '',
+    pages       integer NOT NULL DEFAULT 0,
+    count       integer NOT NULL DEFAULT 0,
+    updated     timestamptz NOT NULL DEFAULT transaction_timestamp(),
+    UNIQUE (namespace, updater)
+);
+CREATE TABLE IF NOT EXISTS update_stage_vuln (
+    id    BIGSERIAL PRIMARY KEY,
+    stage bigint NOT NULL REFERENCES update_stage (id) ON DELETE CASCADE,
+    page  integer NOT NULL,
+    vuln  jsonb NOT NULL
+);
+CREATE INDEX IF NOT EXISTS update_stage_vuln_stage_idx ON update_stage_vuln (stage);
+`
+)
@@ -0,0 +1,18 @@
+package migrations
+
+const (
+	// This migration adds a table of the aliases updaters declare for their
+	// vulnerabilities, such as the CVEs an advisory addresses. Each row links
+	// two names; an updater's rows are replaced on every update.
+	migration11 = `
+CREATE TABLE IF NOT EXISTS vuln_alias (
+    namespace text NOT NULL DEFAULT '',
+    updater   text NOT NULL,
+    name      text NOT NULL,
+    alias     text NOT NULL,
+    UNIQUE (namespace, updater, name, alias)
+);
+CREATE INDEX IF NOT EXISTS vuln_alias_name_idx ON vuln_alias (namespace, name);
+CREATE INDEX IF NOT EXISTS vuln_alias_alias_idx ON vuln_alias (namespace, alias);
+`
+)
@@ -0,0 +1,21 @@
+package migrations
+
+const (
+	// This migration records when each vulnerability row was first and last
+	// reported by an update operation. Existing rows are backfilled from the
+	// operations that still reference them, so the first date is only as old
+	// as the oldest operation GC has kept.
+	migration12 = `
+ALTER TABLE vuln
+    ADD COLUMN IF NOT EXISTS first_seen TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS last_seen  TIMESTAMP WITH TIME ZONE;
+UPDATE vuln
+SET first_seen = seen.first, last_seen = seen.last
+FROM (
+    SELECT uo_vuln.vuln AS id, min(update_operation.date) AS first, max(update_operation.date) AS last
+    FROM uo_vuln JOIN update_operation ON (uo_vuln.uo = update_operation.id)
+    GROUP BY uo_vuln.vuln
+) AS seen
+WHERE vuln.id = seen.id AND vuln.first_seen IS NULL;
+`
+)
@@ -0,0 +1,12 @@
+package migrations
+
+const (
+	// This migration adds an optional key identifying the data an update
+	// operation was created from, so that a retried update can find the
+	// operation an earlier attempt committed instead of creating another.
+	migration13 = `
+ALTER TABLE update_operation
+    ADD COLUMN IF NOT EXISTS idempotency_key text;
+CREATE UNIQUE INDEX IF NOT EXISTS uo_namespace_idempotency_key_idx ON update_operation (namespace, idempotency_key);
+`
+)
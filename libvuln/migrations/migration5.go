@@ -0,0 +1,37 @@
+package migrations
+
+const (
+	// This migration adds the tables for storing vulnerability descriptions
+	// once per distinct text, and a settings table for the store's
+	// migration-assist mode.
+	//
+	// Nothing is moved: existing descriptions stay in the vuln table until
+	// the store is stepped through the migration phases and backfilled. See
+	// the vulnstore documentation for the phases.
+	migration5 = `
+CREATE TABLE IF NOT EXISTS description
+(
+    id        BIGSERIAL PRIMARY KEY,
+    hash_kind text  NOT NULL,
+    hash      bytea NOT NULL,
+    text      text  NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS description_hash_idx ON description (hash_kind, hash);
+
+-- The constraint isn't validated here so that adding it doesn't scan the vuln
+-- table while holding its lock. Every value is NULL at this point anyway.
+ALTER TABLE vuln
+    ADD COLUMN IF NOT EXISTS description_id BIGINT;
+ALTER TABLE vuln
+    ADD CONSTRAINT vuln_description_id_fkey FOREIGN KEY (description_id) REFERENCES description (id) NOT VALID;
+
+CREATE TABLE IF NOT EXISTS settings
+(
+    key   text PRIMARY KEY,
+    value text NOT NULL
+);
+INSERT INTO settings (key, value)
+VALUES ('description_phase', 'old')
+ON CONFLICT DO NOTHING;
+`
+)
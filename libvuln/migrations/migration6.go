@@ -0,0 +1,23 @@
+package migrations
+
+const (
+	// This migration adds a namespace to update operations and
+	// vulnerabilities, so that Stores configured with different namespaces
+	// can share a database without seeing each other's data.
+	//
+	// Existing rows are put in the default, empty namespace. Enrichment
+	// records are shared between namespaces, as they're only ever reached
+	// through an update operation.
+	migration6 = `
+ALTER TABLE update_operation
+    ADD COLUMN IF NOT EXISTS namespace text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS uo_namespace_updater_idx ON update_operation (namespace, updater);
+
+ALTER TABLE vuln
+    ADD COLUMN IF NOT EXISTS namespace text NOT NULL DEFAULT '';
+ALTER TABLE vuln
+    DROP CONSTRAINT IF EXISTS vuln_hash_kind_hash_key;
+ALTER TABLE vuln
+    ADD CONSTRAINT vuln_namespace_hash_key UNIQUE (namespace, hash_kind, hash);
+`
+)
@@ -0,0 +1,21 @@
+package migrations
+
+const (
+	// This migration lets vulnerability links be stored once per distinct
+	// text in the description table, which is content-addressed and so
+	// doesn't care what kind of text it holds, and lowers the tuple size at
+	// which Postgres starts compressing the long text columns of the vuln
+	// and description tables.
+	//
+	// As with migration 5, nothing is moved. The toast_tuple_target setting
+	// only applies to rows written after it's set.
+	migration7 = `
+ALTER TABLE vuln
+    ADD COLUMN IF NOT EXISTS links_id BIGINT;
+ALTER TABLE vuln
+    ADD CONSTRAINT vuln_links_id_fkey FOREIGN KEY (links_id) REFERENCES description (id) NOT VALID;
+
+ALTER TABLE vuln SET (toast_tuple_target = 128);
+ALTER TABLE description SET (toast_tuple_target = 128);
+`
+)
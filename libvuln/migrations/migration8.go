@@ -0,0 +1,23 @@
+package migrations
+
+const (
+	// This migration adds a table of exclusions, which suppress findings of a
+	// vulnerability on a package. Exclusions are namespaced like update
+	// operations, and expired ones are kept for auditing until deleted.
+	migration8 = `
+CREATE TABLE IF NOT EXISTS exclusion (
+    id            BIGSERIAL PRIMARY KEY,
+    ref           uuid UNIQUE NOT NULL DEFAULT uuid_generate_v4(),
+    namespace     text NOT NULL DEFAULT '',
+    vulnerability text NOT NULL,
+    package       text NOT NULL,
+    version       text NOT NULL DEFAULT '',
+    manifest      text NOT NULL DEFAULT '',
+    reason        text NOT NULL,
+    author        text NOT NULL DEFAULT '',
+    created       timestamptz NOT NULL DEFAULT transaction_timestamp(),
+    expires       timestamptz
+);
+CREATE INDEX IF NOT EXISTS exclusion_namespace_idx ON exclusion (namespace);
+`
+)
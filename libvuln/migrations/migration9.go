@@ -0,0 +1,10 @@
+package migrations
+
+const (
+	// This migration records the data source an updater declared with each
+	// update operation, for attribution. Operations from updaters that don't
+	// declare one, and operations from before this migration, have none.
+	migration9 = `
+ALTER TABLE update_operation ADD COLUMN IF NOT EXISTS source jsonb;
+`
+)
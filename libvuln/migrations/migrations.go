@@ -39,4 +39,67 @@ var Migrations = []migrate.Migration{
 			return err
 		},
 	},
+	{
+		ID: 5,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration5)
+			return err
+		},
+	},
+	{
+		ID: 6,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration6)
+			return err
+		},
+	},
+	{
+		ID: 7,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration7)
+			return err
+		},
+	},
+	{
+		ID: 8,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration8)
+			return err
+		},
+	},
+	{
+		ID: 9,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration9)
+			return err
+		},
+	},
+	{
+		ID: 10,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration10)
+			return err
+		},
+	},
+	{
+		ID: 11,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration11)
+			return err
+		},
+	},
+	{
+		ID: 12,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration12)
+			return err
+		},
+	},
+	{
+		ID: 13,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration13)
+			return err
+		},
+	},
 }
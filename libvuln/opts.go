@@ -3,9 +3,12 @@ package libvuln
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
+	"sort"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -17,8 +20,13 @@ import (
 
 	"github.com/quay/zlog"
 
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/internal/multierr"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/libvuln/migrations"
+	"github.com/quay/claircore/libvuln/updates"
+	"github.com/quay/claircore/matchers/registry"
+	"github.com/quay/claircore/updater"
 )
 
 const (
@@ -26,6 +34,7 @@ const (
 	DefaultUpdateWorkers   = 10
 	DefaultMaxConnPool     = 50
 	DefaultUpdateRetention = 2
+	DefaultDrainTimeout    = 30 * time.Second
 )
 
 type Opts struct {
@@ -50,6 +59,7 @@ type Opts struct {
 	// "alpine"
 	// "aws"
 	// "debian"
+	// "govulndb"
 	// "oracle"
 	// "photon"
 	// "pyupio"
@@ -64,6 +74,9 @@ type Opts struct {
 	// If you desire no updaters to run do not add an updater
 	// into this slice.
 	Updaters []driver.Updater
+	// PrefixDuplicateUpdaters runs updaters from different sets that share a
+	// name as "<set>/<name>". By default, New fails if any names collide.
+	PrefixDuplicateUpdaters bool
 	// A slice of strings representing which
 	// matchers will be used.
 	//
@@ -95,6 +108,14 @@ type Opts struct {
 	// requests.
 	Enrichers []driver.Enricher
 
+	// EnricherConfigs holds configuration blocks for Enrichers that implement
+	// driver.Configurable, keyed by name.
+	EnricherConfigs map[string]driver.ConfigUnmarshaler
+
+	// ScanOptions are applied to every call to Scan, before the options
+	// passed to the call.
+	ScanOptions []ScanOption
+
 	// UpdateWorkers controls the number of update workers running concurrently.
 	// If less than or equal to zero, a sensible default will be used.
 	UpdateWorkers int
@@ -106,31 +127,91 @@ type Opts struct {
 	// purposes.
 	UpdateRetention int
 
+	// UpdateMalformedLimit is the fraction of an updater's records that may
+	// fail to parse before its update fails. Records that fail to parse below
+	// the limit are skipped. If zero, driver.DefaultMalformedLimit is used; if
+	// negative, no malformed records are allowed.
+	UpdateMalformedLimit float64
+
+	// MaxEnrichmentSize is the largest enrichment record data, in bytes, the
+	// store accepts. If zero, postgres.DefaultMaxEnrichmentSize from the
+	// datastore/postgres package is used; if negative, records of any size
+	// are accepted.
+	MaxEnrichmentSize int
+	// RejectInvalidEnrichments makes an enrichment update fail if any of its
+	// records is invalid: one without usable tags, larger than
+	// MaxEnrichmentSize, or not valid JSON. By default, invalid records are
+	// skipped and counted in the
+	// claircore_vulnstore_updateenrichments_invalid_total metric.
+	RejectInvalidEnrichments bool
+
 	// If set to true, there will not be a goroutine launched to periodically
 	// run updaters.
 	DisableBackgroundUpdates bool
 
+	// DrainTimeout is how long Close waits for in-flight operations to finish
+	// before canceling them. If zero, a sensible default will be used.
+	DrainTimeout time.Duration
+
 	// UpdaterConfigs is a map of functions for configuration of Updaters.
 	UpdaterConfigs map[string]driver.ConfigUnmarshaler
 
 	// Client is an http.Client for use by all updaters. If unset,
 	// http.DefaultClient will be used.
 	Client *http.Client
+
+	// MigrationAssist makes the store follow the schema migration phase
+	// recorded in the database, so that migrations that move data can be
+	// stepped through without downtime. All instances sharing a database
+	// should set this before any phase is changed.
+	MigrationAssist bool
+
+	// Namespace isolates this Libvuln's update operations and
+	// vulnerabilities from those of instances configured with other
+	// namespaces in the same database. The default is the shared namespace.
+	Namespace string
+
+	// Snapshot is the path to a vulnerability snapshot, in the format written
+	// by an offline updater, to load into the store when Libvuln is
+	// constructed. It's only loaded if the store has no vulnerabilities,
+	// unless SnapshotTopUp is set. Compressed and uncompressed snapshots are
+	// both accepted.
+	//
+	// This allows a deployment to ship vulnerability data with its image and
+	// serve Scan requests without running any updaters.
+	Snapshot string
+	// SnapshotTopUp loads the Snapshot into a store that already has
+	// vulnerabilities, importing the updates in it that are newer than the
+	// store's latest update from the same updater.
+	SnapshotTopUp bool
+
+	// StatsInterval is how often the store's table sizes and pending GC
+	// work are reported through the claircore_vulnstore_table_* and
+	// claircore_vulnstore_gc_pending_rows gauges. If zero, the gauges aren't
+	// refreshed; the numbers are always available from Libvuln.Stats.
+	StatsInterval time.Duration
+
+	// Store, if not nil, is used as the vulnerability store instead of the
+	// PostgreSQL database at ConnString, which may then be empty. The
+	// MaxConnPool, Migrations, Namespace, MigrationAssist, MaxEnrichmentSize,
+	// and RejectInvalidEnrichments options only apply to the database at
+	// ConnString.
+	//
+	// Without the database, updaters are coordinated with in-process locks,
+	// so only one Libvuln should run updaters against the Store.
+	Store datastore.MatcherStore
 }
 
 // parse is an internal method for constructing
 // the necessary Updaters and Matchers for Libvuln
 // usage
+//
+// Defaults are filled in even if Validate reports problems, which are
+// returned.
 func (o *Opts) parse(ctx context.Context) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libvuln/Opts.parse"))
-	// required
-	if o.ConnString == "" {
-		return fmt.Errorf("no connection string provided")
-	}
-	if o.UpdateRetention == 1 || o.UpdateRetention < 0 {
-		return fmt.Errorf("update retention must be 0 or greater then 1")
-	}
+	err := o.Validate()
 
 	if o.UpdateInterval == 0 || o.UpdateInterval < time.Minute {
 		o.UpdateInterval = DefaultUpdateInterval
@@ -148,6 +229,9 @@ func (o *Opts) parse(ctx context.Context) error {
 	if o.UpdateWorkers <= 0 {
 		o.UpdateWorkers = DefaultUpdateWorkers
 	}
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = DefaultDrainTimeout
+	}
 
 	if o.Client == nil {
 		zlog.Warn(ctx).
@@ -158,7 +242,100 @@ func (o *Opts) parse(ctx context.Context) error {
 		o.UpdaterConfigs = make(map[string]driver.ConfigUnmarshaler)
 	}
 
-	return nil
+	return err
+}
+
+// Validate reports every problem with the options it can find without side
+// effects, as a single error listing each one. A nil error doesn't mean New
+// will succeed: it also checks that the database can be reached.
+//
+// Matcher and updater set names are checked against the registered
+// factories, so packages registering their own must be imported first.
+func (o *Opts) Validate() error {
+	var errs []error
+	switch {
+	case o.Store != nil:
+	case o.ConnString == "":
+		errs = append(errs, errors.New("no connection string provided"))
+	default:
+		if _, err := pgxpool.ParseConfig(o.ConnString); err != nil {
+			errs = append(errs, fmt.Errorf("invalid connection string: %v", err))
+		}
+	}
+	if o.UpdateRetention == 1 || o.UpdateRetention < 0 {
+		errs = append(errs, fmt.Errorf("update retention must be 0 or greater then 1"))
+	}
+	if o.MaxConnPool < 0 {
+		errs = append(errs, fmt.Errorf("MaxConnPool must not be negative: %d", o.MaxConnPool))
+	}
+	if o.StatsInterval < 0 {
+		errs = append(errs, fmt.Errorf("StatsInterval must not be negative: %v", o.StatsInterval))
+	}
+	matchers := registry.Registered()
+	for _, n := range o.MatcherNames {
+		if _, ok := matchers[n]; !ok {
+			errs = append(errs, fmt.Errorf("unknown matcher %q", n))
+		}
+	}
+	for _, n := range sortedKeys(o.MatcherConfigs) {
+		if _, ok := matchers[n]; !ok {
+			errs = append(errs, fmt.Errorf("configuration for unknown matcher %q", n))
+		}
+	}
+	enrichers := make(map[string]struct{}, len(o.Enrichers))
+	for _, e := range o.Enrichers {
+		enrichers[e.Name()] = struct{}{}
+	}
+	for _, n := range sortedKeys(o.EnricherConfigs) {
+		if _, ok := enrichers[n]; !ok {
+			errs = append(errs, fmt.Errorf("configuration for unknown enricher %q", n))
+		}
+	}
+	if len(o.UpdaterSets) != 0 {
+		known := updater.Registered()
+		for _, n := range o.UpdaterSets {
+			if _, ok := known[n]; !ok {
+				errs = append(errs, fmt.Errorf("unknown updater set %q", n))
+			}
+		}
+	}
+	// Names from updater sets aren't known until the sets are constructed,
+	// but the out-of-tree updaters are all here.
+	seen := make(map[string]bool, len(o.Updaters))
+	for _, u := range o.Updaters {
+		n := u.Name()
+		if seen[n] {
+			errs = append(errs, fmt.Errorf("%w: %q is used by more than one out-of-tree updater", updates.ErrDuplicateUpdater, n))
+		}
+		seen[n] = true
+	}
+	if o.Snapshot != "" {
+		switch fi, err := os.Stat(o.Snapshot); {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("unusable snapshot: %v", err))
+		case !fi.Mode().IsRegular():
+			errs = append(errs, fmt.Errorf("unusable snapshot: %q is not a regular file", o.Snapshot))
+		}
+	}
+	return multierr.Join(errs...)
+}
+
+// SortedKeys returns the keys of "m", which must be a map with string keys,
+// in order.
+func sortedKeys(m interface{}) []string {
+	var ks []string
+	switch m := m.(type) {
+	case map[string]driver.MatcherConfigUnmarshaler:
+		for k := range m {
+			ks = append(ks, k)
+		}
+	case map[string]driver.ConfigUnmarshaler:
+		for k := range m {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	return ks
 }
 
 // Pool creates and returns a configured pxgpool.Pool.
@@ -179,7 +356,7 @@ func (o *Opts) pool(ctx context.Context) (*pgxpool.Pool, error) {
 // Migrations performs migrations if the configuration asks for it.
 func (o *Opts) migrations(_ context.Context) error {
 	// The migrate package doesn't use the context, which is... disconcerting.
-	if !o.Migrations {
+	if !o.Migrations || o.Store != nil {
 		return nil
 	}
 	cfg, err := pgx.ParseConfig(o.ConnString)
@@ -0,0 +1,95 @@
+package libvuln
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/updates"
+)
+
+// TestValidate checks that every problem with a configuration is reported at
+// once.
+func TestValidate(t *testing.T) {
+	o := Opts{
+		UpdateRetention: 1,
+		MatcherNames:    []string{"alpine-matcher", "no-such-matcher"},
+		UpdaterSets:     []string{"no-such-set"},
+		Snapshot:        "testdata/no-such-snapshot.json.zst",
+	}
+	err := o.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	t.Log(err)
+	for _, want := range []string{
+		"no connection string provided",
+		"update retention",
+		`unknown matcher "no-such-matcher"`,
+		`unknown updater set "no-such-set"`,
+		"unusable snapshot",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %q", want)
+		}
+	}
+	if got, want := len(strings.Split(err.Error(), "\n")), 5; got != want {
+		t.Errorf("got %d problems, want %d", got, want)
+	}
+
+	o = Opts{ConnString: "not a dsn"}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "invalid connection string") {
+		t.Errorf("got: %v, want an invalid connection string error", err)
+	}
+	o = Opts{Store: new(exclusionStore), MatcherNames: []string{"alpine-matcher"}}
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o = Opts{
+		Store:    new(exclusionStore),
+		Updaters: []driver.Updater{namedUpdater{name: "a"}, namedUpdater{name: "b"}, namedUpdater{name: "a"}},
+	}
+	if err := o.Validate(); !errors.Is(err, updates.ErrDuplicateUpdater) || !strings.Contains(err.Error(), `"a"`) {
+		t.Errorf("got: %v, want a duplicate updater error for %q", err, "a")
+	}
+}
+
+// NamedUpdater is a driver.Updater that only has a name. Calling any other
+// method panics.
+type namedUpdater struct {
+	driver.Updater
+	name string
+}
+
+func (u namedUpdater) Name() string { return u.name }
+
+// TestNewInvalid checks that New reports configuration problems along with
+// an unreachable database.
+func TestNewInvalid(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	_, err := New(ctx, &Opts{
+		// Nothing listens on port 1.
+		ConnString:      "host=127.0.0.1 port=1 connect_timeout=5",
+		UpdateRetention: 1,
+		MatcherNames:    []string{"no-such-matcher"},
+		Client:          &http.Client{},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	t.Log(err)
+	for _, want := range []string{
+		"update retention",
+		`unknown matcher "no-such-matcher"`,
+		"failed to create Pool",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %q", want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package risk computes hints for ordering the findings in a
+// VulnerabilityReport by risk.
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/enricher/cvss"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// EPSSType is the enrichment type carrying EPSS (Exploit Prediction Scoring
+// System) scores.
+//
+// Each vulnerability's entry is an object, or an array of objects, with an
+// "epss" member holding the probability of exploitation as a number between 0
+// and 1, or a string of one, as published by FIRST.
+const EPSSType = `message/vnd.clair.map.vulnerability; enricher=clair.epss`
+
+// These are the names of the inputs recorded by the Default scorer.
+const (
+	InputCVSS     = "cvss"
+	InputEPSS     = "epss"
+	InputSeverity = "severity"
+)
+
+var _ driver.RiskScorer = Default{}
+
+// Default is the default RiskScorer.
+//
+// Hints order findings by CVSS base score, then by EPSS score, then by
+// normalized severity: a finding with a higher CVSS score always has a higher
+// hint, and the other inputs only break ties. A missing input counts as zero,
+// so a finding with neither enrichment is ordered by severity alone. If a
+// vulnerability has more than one record of an enrichment, the highest score
+// is used.
+//
+// Hints range from 0 to just over 1,000,000 and aren't meant to be read as
+// anything but an ordering; the inputs are recorded alongside.
+type Default struct{}
+
+// Name implements driver.RiskScorer.
+func (Default) Name() string { return "clair.default" }
+
+// Score implements driver.RiskScorer.
+func (Default) Score(ctx context.Context, v *claircore.Vulnerability, es map[string][]json.RawMessage) (claircore.RiskHint, bool) {
+	var h claircore.RiskHint
+	var score float64
+	if c, ok := cvssScore(ctx, es[cvss.Type]); ok {
+		h.Inputs = append(h.Inputs, claircore.RiskInput{
+			Name:       InputCVSS,
+			Value:      c,
+			Enrichment: cvss.Type,
+		})
+		score += math.Round(c*10) * 10000
+	}
+	if e, ok := epssScore(ctx, es[EPSSType]); ok {
+		h.Inputs = append(h.Inputs, claircore.RiskInput{
+			Name:       InputEPSS,
+			Value:      e,
+			Enrichment: EPSSType,
+		})
+		score += math.Round(e*999) * 10
+	}
+	sev := v.NormalizedSeverity
+	h.Inputs = append(h.Inputs, claircore.RiskInput{
+		Name:  InputSeverity,
+		Value: float64(sev),
+	})
+	score += float64(sev)
+	h.Score = score
+	return h, true
+}
+
+// CvssScore returns the highest base score in the CVSS enrichment entries.
+func cvssScore(ctx context.Context, msgs []json.RawMessage) (float64, bool) {
+	var max float64
+	var found bool
+	for _, msg := range msgs {
+		var rs []struct {
+			BaseScore *float64 `json:"baseScore"`
+		}
+		if err := json.Unmarshal(msg, &rs); err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Msg("unable to decode cvss entry")
+			continue
+		}
+		for _, r := range rs {
+			if r.BaseScore == nil {
+				continue
+			}
+			if s := clamp(*r.BaseScore, 10); !found || s > max {
+				max, found = s, true
+			}
+		}
+	}
+	return max, found
+}
+
+// EpssScore returns the highest score in the EPSS enrichment entries.
+func epssScore(ctx context.Context, msgs []json.RawMessage) (float64, bool) {
+	var max float64
+	var found bool
+	for _, msg := range msgs {
+		var rs []epssRecord
+		msg = bytes.TrimSpace(msg)
+		if len(msg) != 0 && msg[0] == '{' {
+			rs = make([]epssRecord, 1)
+			err := json.Unmarshal(msg, &rs[0])
+			if err != nil {
+				zlog.Debug(ctx).
+					Err(err).
+					Msg("unable to decode epss entry")
+				continue
+			}
+		} else if err := json.Unmarshal(msg, &rs); err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Msg("unable to decode epss entry")
+			continue
+		}
+		for _, r := range rs {
+			s, ok := r.score()
+			if !ok {
+				continue
+			}
+			if !found || s > max {
+				max, found = s, true
+			}
+		}
+	}
+	return max, found
+}
+
+type epssRecord struct {
+	EPSS json.RawMessage `json:"epss"`
+}
+
+// Score decodes the record's score, which may be a number or a string.
+func (r *epssRecord) score() (float64, bool) {
+	if len(r.EPSS) == 0 {
+		return 0, false
+	}
+	var f float64
+	if err := json.Unmarshal(r.EPSS, &f); err == nil {
+		return clamp(f, 1), true
+	}
+	var s string
+	if err := json.Unmarshal(r.EPSS, &s); err != nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return clamp(f, 1), true
+}
+
+// Clamp limits "f" to the range [0, max].
+func clamp(f, max float64) float64 {
+	switch {
+	case math.IsNaN(f) || f < 0:
+		return 0
+	case f > max:
+		return max
+	}
+	return f
+}
@@ -0,0 +1,96 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/enricher/cvss"
+)
+
+func TestDefault(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	raw := func(ss ...string) []json.RawMessage {
+		out := make([]json.RawMessage, len(ss))
+		for i, s := range ss {
+			out[i] = json.RawMessage(s)
+		}
+		return out
+	}
+	tt := []struct {
+		Name     string
+		Severity claircore.Severity
+		In       map[string][]json.RawMessage
+		Want     float64
+	}{
+		{
+			Name:     "Neither",
+			Severity: claircore.Medium,
+			Want:     3,
+		},
+		{
+			Name: "CVSS",
+			In: map[string][]json.RawMessage{
+				cvss.Type: raw(`[{"baseScore":7.5},{"baseScore":9.1}]`, `[{"baseScore":4}]`),
+			},
+			Severity: claircore.High,
+			Want:     910004,
+		},
+		{
+			Name: "EPSS",
+			In: map[string][]json.RawMessage{
+				EPSSType: raw(`{"epss":"0.1"}`, `[{"epss":0.2},{"epss":"bogus"}]`),
+			},
+			Want: 2000,
+		},
+		{
+			Name: "Both",
+			In: map[string][]json.RawMessage{
+				cvss.Type: raw(`[{"baseScore":10}]`),
+				EPSSType:  raw(`{"epss":1}`),
+			},
+			Severity: claircore.Critical,
+			Want:     1009995,
+		},
+		{
+			Name: "Malformed",
+			In: map[string][]json.RawMessage{
+				cvss.Type: raw(`{"baseScore":10}`, `[{"baseScore":-1}]`),
+				EPSSType:  raw(`"0.5"`, `{"epss":42}`),
+			},
+			Want: 9990,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			v := &claircore.Vulnerability{NormalizedSeverity: tc.Severity}
+			h, ok := Default{}.Score(ctx, v, tc.In)
+			if !ok {
+				t.Fatal("no hint")
+			}
+			if got, want := h.Score, tc.Want; got != want {
+				t.Errorf("got: %v, want: %v (inputs: %+v)", got, want, h.Inputs)
+			}
+		})
+	}
+
+	t.Run("Order", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		score := func(c string, sev claircore.Severity) float64 {
+			in := map[string][]json.RawMessage{
+				cvss.Type: raw(c),
+				EPSSType:  raw(`{"epss":1}`),
+			}
+			h, _ := Default{}.Score(ctx, &claircore.Vulnerability{NormalizedSeverity: sev}, in)
+			return h.Score
+		}
+		// A higher CVSS score outranks everything else.
+		if lo, hi := score(`[{"baseScore":9.9}]`, claircore.Critical), score(`[{"baseScore":10}]`, claircore.Unknown); lo >= hi {
+			t.Errorf("%v >= %v", lo, hi)
+		}
+	})
+}
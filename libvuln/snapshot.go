@@ -0,0 +1,171 @@
+package libvuln
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/jsonblob"
+)
+
+// LoadSnapshot imports the snapshot at "path" into the store, if the store is
+// empty or "topUp" is set, and reports the snapshot's build date: the date of
+// its newest update. A zero Time is returned if the snapshot wasn't read.
+//
+// When topping up a store that has vulnerabilities, only updates newer than
+// the store's latest update for the same updater are imported.
+//
+// If the store is a vulnstore.Importer, the imported update operations keep
+// the dates recorded in the snapshot, and either the whole snapshot is
+// imported or none of it is.
+func loadSnapshot(ctx context.Context, s vulnstore.Updater, path string, topUp bool) (time.Time, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/loadSnapshot"),
+		label.String("snapshot", path))
+	init, err := s.Initialized(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("libvuln: unable to check store: %w", err)
+	}
+	if init && !topUp {
+		zlog.Info(ctx).Msg("store initialized, skipping snapshot")
+		return time.Time{}, nil
+	}
+	latest := make(map[string]time.Time)
+	if init {
+		ops, err := s.GetLatestUpdateRefs(ctx, driver.VulnerabilityKind)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("libvuln: unable to get update operations: %w", err)
+		}
+		for u, us := range ops {
+			for _, op := range us {
+				if op.Date.After(latest[u]) {
+					latest[u] = op.Date
+				}
+			}
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("libvuln: unable to open snapshot: %w", err)
+	}
+	defer f.Close()
+	// Snapshots as written by an offline updater are compressed, but one
+	// that's been unpacked into an image is fine, too.
+	var r io.Reader
+	br := bufio.NewReader(f)
+	if b, err := br.Peek(2); err == nil && b[0] == 0x1f && b[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("libvuln: unable to read snapshot: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	l, err := jsonblob.Load(ctx, r)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("libvuln: unable to read snapshot: %w", err)
+	}
+
+	imp, ok := s.(vulnstore.Importer)
+	if !ok {
+		zlog.Warn(ctx).Msg("store can't import updates: snapshot dates won't be kept, and a failed load won't be undone")
+		imp = updaterImporter{s}
+	}
+	var built time.Time
+	var imported, skipped int
+	err = imp.ImportUpdates(ctx, func(add vulnstore.ImportFunc) error {
+		for l.Next() {
+			e := l.Entry()
+			if e == nil { // Empty snapshot.
+				break
+			}
+			if e.Date.After(built) {
+				built = e.Date
+			}
+			if init && !e.Date.After(latest[e.Updater]) {
+				zlog.Debug(ctx).
+					Str("updater", e.Updater).
+					Msg("store has newer data, skipping")
+				skipped++
+				continue
+			}
+			if _, err := importEntry(ctx, add, e); err != nil {
+				return fmt.Errorf("libvuln: unable to import snapshot: %w", err)
+			}
+			imported++
+		}
+		if err := l.Err(); err != nil {
+			return fmt.Errorf("libvuln: unable to read snapshot: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	zlog.Info(ctx).
+		Int("imported", imported).
+		Int("skipped", skipped).
+		Time("built", built).
+		Msg("snapshot loaded")
+	return built, nil
+}
+
+// importEntry records the vulnerabilities and data source in the Entry as a
+// new update operation using "add".
+func importEntry(ctx context.Context, add vulnstore.ImportFunc, e *jsonblob.Entry) (uuid.UUID, error) {
+	ref, err := add(ctx, &vulnstore.Import{
+		Updater:     e.Updater,
+		Fingerprint: e.Fingerprint,
+		Date:        e.Date,
+		Source:      e.Source,
+		Vulns:       e.Vuln,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	zlog.Info(ctx).
+		Str("updater", e.Updater).
+		Str("ref", ref.String()).
+		Int("count", len(e.Vuln)).
+		Msg("update imported")
+	return ref, nil
+}
+
+// updaterImporter imports updates into a store that isn't a
+// vulnstore.Importer, one update operation at a time. The operations are
+// dated when they're imported.
+type updaterImporter struct {
+	vulnstore.Updater
+}
+
+// ImportUpdates implements vulnstore.Importer.
+func (u updaterImporter) ImportUpdates(ctx context.Context, f func(vulnstore.ImportFunc) error) error {
+	return f(u.add)
+}
+
+func (u updaterImporter) add(ctx context.Context, im *vulnstore.Import) (uuid.UUID, error) {
+	ref, err := u.UpdateVulnerabilities(ctx, im.Updater, im.Fingerprint, im.Vulns)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if im.Source != nil {
+		if err := u.SetDataSource(ctx, ref, *im.Source); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return ref, nil
+}
@@ -0,0 +1,79 @@
+package libvuln
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestSnapshotScan checks that a fresh store loaded from a snapshot serves
+// Scan requests without any updater running.
+func TestSnapshotScan(t *testing.T) {
+	const dsnFmt = `host=%s port=%d database=%s user=%s password=%s sslmode=disable`
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close(ctx, t)
+	cfg := db.Config()
+	built := time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	l, err := New(ctx, &Opts{
+		ConnString: fmt.Sprintf(dsnFmt,
+			cfg.ConnConfig.Host,
+			cfg.ConnConfig.Port,
+			cfg.ConnConfig.Database,
+			cfg.ConnConfig.User,
+			cfg.ConnConfig.Password),
+		Migrations:               true,
+		UpdaterSets:              []string{},
+		MatcherNames:             []string{"alpine"},
+		DisableBackgroundUpdates: true,
+		Snapshot:                 writeSnapshot(t, map[string]time.Time{"alpine-main-v3.12-updater": built}, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(ctx)
+	if got := l.SnapshotDate(); !got.Equal(built) {
+		t.Errorf("got snapshot date %v, want %v", got, built)
+	}
+
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "musl", Version: "1.1.24-r2"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {
+				ID:         "1",
+				DID:        snapshotDist.DID,
+				Name:       snapshotDist.Name,
+				VersionID:  snapshotDist.VersionID,
+				PrettyName: snapshotDist.PrettyName,
+			},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+		},
+	}
+	vr, err := l.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := vr.PackageVulnerabilities["1"]
+	if len(ids) != 1 {
+		t.Fatalf("got %d findings, want 1", len(ids))
+	}
+	if got, want := vr.Vulnerabilities[ids[0]].Name, "CVE-2020-28928"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
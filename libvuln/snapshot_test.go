@@ -0,0 +1,222 @@
+package libvuln
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/jsonblob"
+)
+
+var snapshotDist = &claircore.Distribution{
+	Name:       "Alpine Linux",
+	DID:        "alpine",
+	VersionID:  "3.12",
+	PrettyName: "Alpine Linux v3.12",
+}
+
+// WriteSnapshot writes a snapshot with one update per updater, dated as
+// provided, and returns its path.
+func writeSnapshot(t *testing.T, dates map[string]time.Time, compress bool) string {
+	t.Helper()
+	ctx := context.Background()
+	s, err := jsonblob.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for u := range dates {
+		_, err := s.UpdateVulnerabilities(ctx, u, driver.Fingerprint(u), []*claircore.Vulnerability{
+			{
+				Updater:        u,
+				Name:           "CVE-2020-28928",
+				Package:        &claircore.Package{Name: "musl"},
+				Dist:           snapshotDist,
+				FixedInVersion: "1.1.24-r10",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range s.Entries() {
+		e.Date = dates[e.Updater]
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	p := filepath.Join(dir, "snapshot.json.gz")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.WriteCloser = f
+	if compress {
+		w = gzip.NewWriter(f)
+	}
+	if err := s.Store(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	old := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	built := old.Add(24 * time.Hour)
+	dates := map[string]time.Time{"a": old, "b": built}
+	updaters := func(t *testing.T, s *jsonblob.Store) map[string]int {
+		ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[string]int)
+		for u, us := range ops {
+			out[u] = len(us)
+		}
+		return out
+	}
+	// Seeded returns a store that already has an update for "a".
+	seeded := func(t *testing.T) *jsonblob.Store {
+		s, err := jsonblob.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateVulnerabilities(ctx, "a", "", []*claircore.Vulnerability{{Name: "seed"}}); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	for _, compress := range []bool{true, false} {
+		name := "Uncompressed"
+		if compress {
+			name = "Compressed"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			s, err := jsonblob.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadSnapshot(ctx, s, writeSnapshot(t, dates, compress), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(built) {
+				t.Errorf("got build date %v, want %v", got, built)
+			}
+			if got, want := updaters(t, s), map[string]int{"a": 1, "b": 1}; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			// The update operations keep the snapshot's dates.
+			ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for u, us := range ops {
+				if got, want := us[0].Date, dates[u]; !got.Equal(want) {
+					t.Errorf("%s: got date %v, want %v", u, got, want)
+				}
+			}
+		})
+	}
+	t.Run("Truncated", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s, err := jsonblob.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := writeSnapshot(t, dates, false)
+		f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteString(`{"Updater":`); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSnapshot(ctx, s, p, false); err == nil {
+			t.Error("expected error")
+		}
+		// Nothing from the snapshot is kept, so the next load tries again.
+		ok, err := s.Initialized(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("store initialized by a failed load")
+		}
+	})
+	t.Run("Initialized", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := seeded(t)
+		got, err := loadSnapshot(ctx, s, writeSnapshot(t, dates, true), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.IsZero() {
+			t.Errorf("got build date %v, want zero", got)
+		}
+		if got, want := updaters(t, s), map[string]int{"a": 1}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("TopUp", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s := seeded(t)
+		got, err := loadSnapshot(ctx, s, writeSnapshot(t, dates, true), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(built) {
+			t.Errorf("got build date %v, want %v", got, built)
+		}
+		// The seeded update for "a" is newer than the snapshot's.
+		if got, want := updaters(t, s), map[string]int{"a": 1, "b": 1}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Empty", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s, err := jsonblob.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadSnapshot(ctx, s, writeSnapshot(t, nil, true), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.IsZero() {
+			t.Errorf("got build date %v, want zero", got)
+		}
+	})
+	t.Run("Missing", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		s, err := jsonblob.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSnapshot(ctx, s, filepath.Join(os.TempDir(), "does-not-exist.json.gz"), false); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
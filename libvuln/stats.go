@@ -0,0 +1,113 @@
+package libvuln
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// These gauges are only refreshed if Opts.StatsInterval is set.
+var (
+	tableRowsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "table_rows",
+			Help:      "Estimated number of live rows in each table, as of the last stats refresh.",
+		},
+		[]string{"table"},
+	)
+	tableDeadRowsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "table_dead_rows",
+			Help:      "Estimated number of dead rows in each table, as of the last stats refresh.",
+		},
+		[]string{"table"},
+	)
+	tableBytesGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "table_bytes",
+			Help:      "Size on disk of each table, including indexes, as of the last stats refresh.",
+		},
+		[]string{"table"},
+	)
+	tableIndexBytesGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "table_index_bytes",
+			Help:      "Size on disk of each table's indexes, as of the last stats refresh.",
+		},
+		[]string{"table"},
+	)
+	gcPendingGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "vulnstore",
+			Name:      "gc_pending_rows",
+			Help:      "Number of rows GC would remove, by kind, as of the last stats refresh.",
+		},
+		[]string{"kind"},
+	)
+)
+
+// Stats reports the size of the vulnerability store's tables, and the number
+// of rows GC would remove with the configured UpdateRetention.
+//
+// Stats only reads from the database, and is cheap enough to call hourly.
+func (l *Libvuln) Stats(ctx context.Context) (*driver.StoreStats, error) {
+	ctx, done, err := l.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return l.store.Stats(ctx, l.updateRetention)
+}
+
+// ReportStats refreshes the store gauges every "interval" until the Context
+// is canceled.
+func (l *Libvuln) reportStats(ctx context.Context, interval time.Duration) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.reportStats"))
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		st, err := l.Stats(ctx)
+		switch {
+		case err == nil:
+			setStatsGauges(st)
+		case ctx.Err() != nil:
+		default:
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("unable to refresh store stats")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func setStatsGauges(st *driver.StoreStats) {
+	for _, t := range st.Tables {
+		tableRowsGauge.WithLabelValues(t.Name).Set(float64(t.Rows))
+		tableDeadRowsGauge.WithLabelValues(t.Name).Set(float64(t.DeadRows))
+		tableBytesGauge.WithLabelValues(t.Name).Set(float64(t.TotalBytes))
+		tableIndexBytesGauge.WithLabelValues(t.Name).Set(float64(t.IndexBytes))
+	}
+	gcPendingGauge.WithLabelValues("update_operation").Set(float64(st.ExpiredUpdateOperations))
+	gcPendingGauge.WithLabelValues("vuln").Set(float64(st.OrphanedVulnerabilities))
+}
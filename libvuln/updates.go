@@ -30,7 +30,9 @@ func OfflineImport(ctx context.Context, pool *pgxpool.Pool, in io.Reader) error
 	}
 	defer gz.Close()
 
-	s := postgres.NewVulnStore(pool)
+	// The import is a one-off, so it always writes according to the
+	// recorded migration phase.
+	s := postgres.NewVulnStore(pool, postgres.WithMigrationAssist(0))
 	l, err := jsonblob.Load(ctx, gz)
 	if err != nil {
 		return err
@@ -54,15 +56,9 @@ Update:
 				continue Update
 			}
 		}
-		ref, err := s.UpdateVulnerabilities(ctx, e.Updater, e.Fingerprint, e.Vuln)
-		if err != nil {
+		if _, err := importEntry(ctx, updaterImporter{s}.add, e); err != nil {
 			return err
 		}
-		zlog.Info(ctx).
-			Str("updater", e.Updater).
-			Str("ref", ref.String()).
-			Int("count", len(e.Vuln)).
-			Msg("update imported")
 	}
 	if err := l.Err(); err != nil {
 		return err
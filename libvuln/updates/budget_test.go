@@ -0,0 +1,95 @@
+package updates
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// StallUpdater is an eventUpdater whose Fetch waits for its Context to be
+// done, if "stall" is set.
+type stallUpdater struct {
+	eventUpdater
+	stall bool
+}
+
+func (u stallUpdater) Fetch(ctx context.Context, fp driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	if u.stall {
+		<-ctx.Done()
+		return nil, "", ctx.Err()
+	}
+	return u.eventUpdater.Fetch(ctx, fp)
+}
+
+// StallStore is an eventStore whose UpdateVulnerabilities waits for its
+// Context to be done.
+type stallStore struct {
+	eventStore
+}
+
+func (s *stallStore) UpdateVulnerabilities(ctx context.Context, _ string, _ driver.Fingerprint, _ []*claircore.Vulnerability) (uuid.UUID, error) {
+	<-ctx.Done()
+	return uuid.Nil, ctx.Err()
+}
+
+// TestBudget checks that an updater that runs out of time reports the phase
+// it was in, and that a stalled fetch leaves time for the store.
+func TestBudget(t *testing.T) {
+	tt := []struct {
+		name  string
+		store vulnstore.Updater
+		stall bool
+		want  string
+	}{
+		{
+			name:  "Fetch",
+			store: &eventStore{},
+			stall: true,
+			want:  "allotment exceeded by phase fetch",
+		},
+		{
+			name:  "Store",
+			store: &stallStore{},
+			want:  "deadline exceeded during phase store",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			m, err := NewManager(ctx, tc.store, LocalLockSource(), &http.Client{},
+				WithEnabled([]string{}),
+				WithOutOfTree([]driver.Updater{stallUpdater{stall: tc.stall}}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			err = m.Run(ctx)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			t.Log(err)
+			if !strings.Contains(err.Error(), "event-updater") {
+				t.Errorf("error doesn't name the updater: %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error doesn't contain %q: %v", tc.want, err)
+			}
+			// A stalled fetch should give up before the caller's deadline.
+			if tc.stall && ctx.Err() != nil {
+				t.Error("fetch used the whole deadline")
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// ErrDuplicateUpdater is returned, wrapped, when updaters from different
+// factories share a name.
+//
+// The store keys update operations on the updater name, so two updaters
+// with the same name would overwrite each other's data.
+var ErrDuplicateUpdater = errors.New("duplicate updater name")
+
+// NamedUpdater is an updater and the name it's run and stored under.
+//
+// The name is the updater's own, unless it's been prefixed with its factory's
+// name to tell it apart from another factory's updater.
+type namedUpdater struct {
+	name    string
+	factory string
+	u       driver.Updater
+}
+
+// Config returns the configuration for the updater, looking it up by the
+// prefixed name and then the updater's own name.
+func (n *namedUpdater) config(cfgs Configs) driver.ConfigUnmarshaler {
+	if cfg, ok := cfgs[n.name]; ok && cfg != nil {
+		return cfg
+	}
+	if cfg, ok := cfgs[n.u.Name()]; ok && cfg != nil {
+		return cfg
+	}
+	return noopConfig
+}
+
+// CollectUpdaters constructs the updater sets from the provided factories and
+// names every updater.
+//
+// Factories that fail to construct are logged and skipped. If updaters from
+// different factories share a name, they're either prefixed with
+// "<factory>/" or left out of the returned slice and reported in an error
+// wrapping ErrDuplicateUpdater. The rest of the updaters are returned either
+// way.
+func collectUpdaters(ctx context.Context, factories map[string]driver.UpdaterSetFactory, prefix bool) ([]namedUpdater, error) {
+	fns := make([]string, 0, len(factories))
+	for fn := range factories {
+		fns = append(fns, fn)
+	}
+	sort.Strings(fns)
+
+	var all []namedUpdater
+	byName := make(map[string][]string)
+	for _, fn := range fns {
+		set, err := factories[fn].UpdaterSet(ctx)
+		if err != nil {
+			zlog.Error(ctx).
+				Err(err).
+				Str("factory", fn).
+				Msg("failed constructing factory, skipping")
+			continue
+		}
+		for _, u := range set.Updaters() {
+			n := u.Name()
+			all = append(all, namedUpdater{name: n, factory: fn, u: u})
+			byName[n] = append(byName[n], fn)
+		}
+	}
+
+	var dups []string
+	out := all[:0]
+	for _, nu := range all {
+		if len(byName[nu.name]) == 1 {
+			out = append(out, nu)
+			continue
+		}
+		if prefix {
+			nu.name = nu.factory + "/" + nu.name
+			out = append(out, nu)
+			continue
+		}
+		if byName[nu.name][0] == nu.factory {
+			dups = append(dups, fmt.Sprintf("%q (factories: %s)", nu.name, strings.Join(byName[nu.name], ", ")))
+		}
+	}
+	if len(dups) != 0 {
+		sort.Strings(dups)
+		return out, fmt.Errorf("updates: %w: %s", ErrDuplicateUpdater, strings.Join(dups, "; "))
+	}
+	return out, nil
+}
@@ -0,0 +1,142 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// NameStore is a vulnstore.Updater that records the updater names it's
+// handed. Calling methods other than the vulnerability update ones panics.
+type nameStore struct {
+	vulnstore.Updater
+	mu    sync.Mutex
+	names []string
+}
+
+func (s *nameStore) GetUpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error) {
+	return nil, nil
+}
+
+func (s *nameStore) UpdateVulnerabilities(_ context.Context, name string, _ driver.Fingerprint, _ []*claircore.Vulnerability) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names = append(s.names, name)
+	return uuid.New(), nil
+}
+
+// DupUpdater is an updater with a settable name.
+type dupUpdater string
+
+func (u dupUpdater) Name() string { return string(u) }
+
+func (dupUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", nil
+}
+
+func (dupUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	return nil, nil
+}
+
+func dupFactories(t *testing.T) map[string]driver.UpdaterSetFactory {
+	t.Helper()
+	mk := func(names ...string) driver.UpdaterSetFactory {
+		us := driver.NewUpdaterSet()
+		for _, n := range names {
+			if err := us.Add(dupUpdater(n)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return driver.StaticSet(us)
+	}
+	return map[string]driver.UpdaterSetFactory{
+		"first":  mk("shared", "only-first"),
+		"second": mk("shared", "only-second"),
+		"third":  mk("only-third"),
+	}
+}
+
+func TestDuplicateUpdaters(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("Fail", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		_, err := NewManager(ctx, &nameStore{}, LocalLockSource(), &http.Client{},
+			WithFactories(dupFactories(t)),
+		)
+		t.Log(err)
+		if !errors.Is(err, ErrDuplicateUpdater) {
+			t.Fatalf("got error %v, want %v", err, ErrDuplicateUpdater)
+		}
+		for _, s := range []string{`"shared"`, "first", "second"} {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("error doesn't mention %s", s)
+			}
+		}
+		if strings.Contains(err.Error(), "third") {
+			t.Error("error mentions a factory without duplicates")
+		}
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		store := &nameStore{}
+		m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+			WithFactories(dupFactories(t)),
+			WithPrefixedDuplicates(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(store.names)
+		want := []string{"first/shared", "only-first", "only-second", "only-third", "second/shared"}
+		if !cmp.Equal(store.names, want) {
+			t.Error(cmp.Diff(store.names, want))
+		}
+	})
+
+	// A factory that starts emitting a colliding name after construction
+	// shouldn't stop the other updaters.
+	t.Run("Run", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		store := &nameStore{}
+		fs := dupFactories(t)
+		delete(fs, "second")
+		m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+			WithFactories(fs),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		us := driver.NewUpdaterSet()
+		if err := us.Add(dupUpdater("shared")); err != nil {
+			t.Fatal(err)
+		}
+		fs["second"] = driver.StaticSet(us)
+		// Run reports all its errors as one string.
+		if err := m.Run(ctx); err == nil || !strings.Contains(err.Error(), ErrDuplicateUpdater.Error()) {
+			t.Errorf("got error %v, want %v", err, ErrDuplicateUpdater)
+		}
+		sort.Strings(store.names)
+		want := []string{"only-first", "only-third"}
+		if !cmp.Equal(store.names, want) {
+			t.Error(cmp.Diff(store.names, want))
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package updates
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// UpdateEvent describes a successfully committed update.
+type UpdateEvent struct {
+	// Updater is the name of the updater that ran.
+	Updater string
+	// Kind is the kind of update operation created.
+	Kind driver.UpdateKind
+	// Ref is the reference of the new update operation.
+	Ref uuid.UUID
+	// Count is the number of vulnerabilities or enrichment records in the
+	// update. Computing what was added or removed requires a query; use
+	// GetUpdateDiff with the Ref if that's needed.
+	Count int
+}
+
+// EventQueueSize is the number of events buffered for a single subscriber.
+// Events sent to a subscriber with a full queue are dropped.
+const eventQueueSize = 64
+
+// OnUpdate registers a function to be called after every successful update.
+//
+// Functions are called asynchronously, so a slow function does not delay
+// updates. Events are delivered to each function in order, but if a function
+// falls too far behind, events are dropped. A panic in a function is logged
+// and otherwise ignored.
+//
+// The Context passed to the function is the one the update ran with, and may
+// be canceled by the time the function is called.
+func (m *Manager) OnUpdate(f func(context.Context, UpdateEvent)) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	m.subs = append(m.subs, &subscriber{f: f})
+}
+
+// Publish dispatches the event to all subscribers without blocking.
+func (m *Manager) publish(ctx context.Context, ev UpdateEvent) {
+	m.subMu.Lock()
+	subs := m.subs
+	m.subMu.Unlock()
+	for _, s := range subs {
+		s.send(ctx, ev)
+	}
+}
+
+// Subscriber is a per-function event queue.
+//
+// A goroutine is only running while there are events queued.
+type subscriber struct {
+	f func(context.Context, UpdateEvent)
+
+	mu      sync.Mutex
+	queue   []queued
+	running bool
+}
+
+type queued struct {
+	ctx context.Context
+	ev  UpdateEvent
+}
+
+func (s *subscriber) send(ctx context.Context, ev UpdateEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) >= eventQueueSize {
+		zlog.Warn(ctx).
+			Str("updater", ev.Updater).
+			Stringer("ref", ev.Ref).
+			Msg("update subscriber queue full, dropping event")
+		return
+	}
+	s.queue = append(s.queue, queued{ctx: ctx, ev: ev})
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+}
+
+func (s *subscriber) run() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		q := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		s.call(q.ctx, q.ev)
+	}
+}
+
+// Call invokes the subscriber function, recovering any panic.
+func (s *subscriber) call(ctx context.Context, ev UpdateEvent) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/updates/subscriber.call"))
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Error(ctx).
+				Str("updater", ev.Updater).
+				Interface("panic", r).
+				Msg("update subscriber panicked")
+		}
+	}()
+	s.f(ctx, ev)
+}
@@ -0,0 +1,169 @@
+package updates
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// EventStore is a vulnstore.Updater that only supports vulnerability
+// updates. Calling any other method panics.
+type eventStore struct {
+	vulnstore.Updater
+	mu   sync.Mutex
+	refs []uuid.UUID
+}
+
+func (s *eventStore) GetUpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error) {
+	return nil, nil
+}
+
+func (s *eventStore) UpdateVulnerabilities(context.Context, string, driver.Fingerprint, []*claircore.Vulnerability) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := uuid.New()
+	s.refs = append(s.refs, ref)
+	return ref, nil
+}
+
+// EventUpdater always reports two vulnerabilities.
+type eventUpdater struct{}
+
+func (eventUpdater) Name() string { return "event-updater" }
+
+func (eventUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", nil
+}
+
+func (eventUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	return []*claircore.Vulnerability{{Name: "1"}, {Name: "2"}}, nil
+}
+
+func TestOnUpdate(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const runs = 3
+	store := &eventStore{}
+	m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+		WithEnabled([]string{}),
+		WithOutOfTree([]driver.Updater{eventUpdater{}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two well-behaved subscribers, one that panics, and one that blocks
+	// until the end of the test.
+	got := make([]chan UpdateEvent, 2)
+	for i := range got {
+		ch := make(chan UpdateEvent, runs)
+		got[i] = ch
+		m.OnUpdate(func(_ context.Context, ev UpdateEvent) { ch <- ev })
+	}
+	// The panicking subscriber returns normally for the last event, which it
+	// gets after the earlier panics are logged, so the logging doesn't
+	// outlive the test.
+	recovered := make(chan struct{})
+	var panics int
+	m.OnUpdate(func(context.Context, UpdateEvent) {
+		if panics++; panics < runs {
+			panic("oops")
+		}
+		close(recovered)
+	})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	m.OnUpdate(func(context.Context, UpdateEvent) { <-unblock })
+
+	for i := 0; i < runs; i++ {
+		done := make(chan error, 1)
+		go func() { done <- m.Run(ctx) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("run blocked by subscriber")
+		}
+	}
+
+	defer func() {
+		select {
+		case <-recovered:
+		case <-time.After(10 * time.Second):
+			t.Error("panicking subscriber never recovered")
+		}
+	}()
+
+	for i, ch := range got {
+		for j := 0; j < runs; j++ {
+			var ev UpdateEvent
+			select {
+			case ev = <-ch:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("subscriber %d: timed out waiting for event %d", i, j)
+			}
+			t.Logf("subscriber %d: %+v", i, ev)
+			if got, want := ev.Updater, "event-updater"; got != want {
+				t.Errorf("updater: got: %q, want: %q", got, want)
+			}
+			if got, want := ev.Kind, driver.VulnerabilityKind; got != want {
+				t.Errorf("kind: got: %q, want: %q", got, want)
+			}
+			if got, want := ev.Ref, store.refs[j]; got != want {
+				t.Errorf("ref: got: %v, want: %v", got, want)
+			}
+			if got, want := ev.Count, 2; got != want {
+				t.Errorf("count: got: %d, want: %d", got, want)
+			}
+		}
+	}
+}
+
+func TestSubscriberQueueFull(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	unblock := make(chan struct{})
+	calls := make(chan string, eventQueueSize*2+1)
+	s := &subscriber{f: func(_ context.Context, ev UpdateEvent) {
+		<-unblock
+		calls <- ev.Updater
+	}}
+	// One event is taken by the running goroutine, then the queue fills.
+	for i := 0; i < eventQueueSize*2; i++ {
+		s.send(ctx, UpdateEvent{Updater: "test"})
+	}
+	close(unblock)
+	next := func() string {
+		select {
+		case u := <-calls:
+			return u
+		case <-time.After(10 * time.Second):
+			t.Fatal("subscriber never finished")
+		}
+		return ""
+	}
+	// At least the queued events are delivered. Once they have been, there's
+	// room for a last event, and everything before it has been delivered
+	// when it is.
+	ct := 0
+	for ; ct < eventQueueSize; ct++ {
+		next()
+	}
+	s.send(ctx, UpdateEvent{Updater: "last"})
+	for next() != "last" {
+		ct++
+	}
+	if ct > eventQueueSize+1 {
+		t.Errorf("got %d calls, want at most %d", ct, eventQueueSize+1)
+	}
+}
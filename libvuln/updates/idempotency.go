@@ -0,0 +1,82 @@
+package updates
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// OperationKey returns the idempotency key for an update by the updater
+// "name" with the Fingerprint "fp" and the "n" items returned by "item".
+//
+// The key is a hash of the name, the Fingerprint, and the items in any order,
+// as updaters don't promise to return their data in a stable order.
+func operationKey(name string, fp driver.Fingerprint, n int, item func(int) interface{}) (string, error) {
+	hs := make([][sha256.Size]byte, n)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range hs {
+		buf.Reset()
+		if err := enc.Encode(item(i)); err != nil {
+			return "", err
+		}
+		hs[i] = sha256.Sum256(buf.Bytes())
+	}
+	sort.Slice(hs, func(i, j int) bool { return bytes.Compare(hs[i][:], hs[j][:]) < 0 })
+	h := sha256.New()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(fp))
+	h.Write([]byte{0})
+	for i := range hs {
+		h.Write(hs[i][:])
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UpdateVulnerabilities stores the vulnerabilities as an update operation for
+// "name".
+//
+// If the store implements vulnstore.Idempotent, the update carries an
+// operation key, so that storing data an earlier attempt already committed
+// returns that attempt's reference instead of creating a duplicate operation.
+func (m *Manager) updateVulnerabilities(ctx context.Context, name string, fp driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+	s, ok := m.store.(vulnstore.Idempotent)
+	if !ok {
+		return m.store.UpdateVulnerabilities(ctx, name, fp, vulns)
+	}
+	key, err := operationKey(name, fp, len(vulns), func(i int) interface{} { return vulns[i] })
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to compute operation key")
+		return m.store.UpdateVulnerabilities(ctx, name, fp, vulns)
+	}
+	return s.UpdateVulnerabilitiesOnce(ctx, key, name, fp, vulns)
+}
+
+// UpdateEnrichments is like updateVulnerabilities, for enrichment records.
+func (m *Manager) updateEnrichments(ctx context.Context, name string, fp driver.Fingerprint, ers []driver.EnrichmentRecord) (uuid.UUID, error) {
+	s, ok := m.store.(vulnstore.Idempotent)
+	if !ok {
+		return m.store.UpdateEnrichments(ctx, name, fp, ers)
+	}
+	key, err := operationKey(name, fp, len(ers), func(i int) interface{} { return &ers[i] })
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to compute operation key")
+		return m.store.UpdateEnrichments(ctx, name, fp, ers)
+	}
+	return s.UpdateEnrichmentsOnce(ctx, key, name, fp, ers)
+}
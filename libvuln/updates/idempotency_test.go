@@ -0,0 +1,113 @@
+package updates
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+func TestOperationKey(t *testing.T) {
+	vs := []*claircore.Vulnerability{{Name: "1"}, {Name: "2"}, {Name: "3"}}
+	key := func(name string, fp driver.Fingerprint, vs []*claircore.Vulnerability) string {
+		t.Helper()
+		k, err := operationKey(name, fp, len(vs), func(i int) interface{} { return vs[i] })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	want := key("updater", "fp", vs)
+	if got := key("updater", "fp", []*claircore.Vulnerability{vs[2], vs[0], vs[1]}); got != want {
+		t.Errorf("order changed the key: got: %q, want: %q", got, want)
+	}
+	for _, got := range []string{
+		key("other", "fp", vs),
+		key("updater", "other", vs),
+		key("updater", "fp", vs[:2]),
+		key("updater", "fp", []*claircore.Vulnerability{vs[0], vs[1], {Name: "4"}}),
+		// The separators keep the name and fingerprint apart.
+		key("updaterf", "p", vs),
+	} {
+		if got == want {
+			t.Errorf("expected a different key than %q", want)
+		}
+	}
+}
+
+// IdempotentStore is a vulnstore.Updater that records update operations by
+// key. The first call commits and then reports a timeout, like a commit that
+// succeeded after the client gave up.
+type idempotentStore struct {
+	vulnstore.Updater
+	mu    sync.Mutex
+	ops   map[string]uuid.UUID
+	calls int
+}
+
+var _ vulnstore.Idempotent = (*idempotentStore)(nil)
+
+func (s *idempotentStore) GetUpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error) {
+	return nil, nil
+}
+
+func (s *idempotentStore) UpdateVulnerabilitiesOnce(_ context.Context, key string, _ string, _ driver.Fingerprint, _ []*claircore.Vulnerability) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	ref, ok := s.ops[key]
+	if !ok {
+		ref = uuid.New()
+		s.ops[key] = ref
+	}
+	if s.calls == 1 {
+		return uuid.Nil, context.DeadlineExceeded
+	}
+	return ref, nil
+}
+
+func (s *idempotentStore) UpdateEnrichmentsOnce(context.Context, string, string, driver.Fingerprint, []driver.EnrichmentRecord) (uuid.UUID, error) {
+	panic("unexpected call")
+}
+
+func TestRetryAfterCommit(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	store := &idempotentStore{ops: make(map[string]uuid.UUID)}
+	m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+		WithEnabled([]string{}),
+		WithOutOfTree([]driver.Updater{eventUpdater{}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evs []UpdateEvent
+	done := make(chan struct{}, 1)
+	m.OnUpdate(func(_ context.Context, ev UpdateEvent) {
+		evs = append(evs, ev)
+		done <- struct{}{}
+	})
+
+	if err := m.Run(ctx); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if got, want := len(store.ops), 1; got != want {
+		t.Errorf("got %d update operations, want %d", got, want)
+	}
+	for _, ref := range store.ops {
+		if got := evs[0].Ref; got != ref {
+			t.Errorf("event ref: got: %v, want: %v", got, ref)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +19,7 @@ import (
 	"golang.org/x/sync/semaphore"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/budget"
 	"github.com/quay/claircore/internal/vulnstore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/distlock"
@@ -31,6 +34,31 @@ var (
 	DefaultBatchSize = runtime.GOMAXPROCS(0)
 )
 
+// These are the phases of a run and of a single update, as reported in
+// errors when a phase runs out of time.
+const (
+	phaseCollect = "collect"
+	phaseUpdate  = "update"
+	phaseGC      = "gc"
+	phaseFetch   = "fetch"
+	phaseStore   = "store"
+)
+
+// RunPhases is how the deadline of a Run is divided. GC has a floor so that
+// slow updaters don't keep it from ever running.
+var runPhases = []budget.Phase{
+	{Name: phaseCollect, Weight: 1},
+	{Name: phaseUpdate, Weight: 8},
+	{Name: phaseGC, Weight: 1, Floor: 30 * time.Second},
+}
+
+// UpdatePhases is how the time an updater has is divided. Storing has a floor
+// so a slow fetch can't leave no time to write what was fetched.
+var updatePhases = []budget.Phase{
+	{Name: phaseFetch, Weight: 3},
+	{Name: phaseStore, Weight: 2, Floor: 10 * time.Second},
+}
+
 type Configs map[string]driver.ConfigUnmarshaler
 
 // LockSource abstracts over how locks are implemented.
@@ -62,6 +90,23 @@ type Manager struct {
 	locks  LockSource
 	client *http.Client
 	store  vulnstore.Updater
+
+	// called with progress reports from updaters, and the interval at which
+	// they're logged.
+	progress         func(string, driver.Progress)
+	progressInterval time.Duration
+
+	// subscribers registered with OnUpdate.
+	subMu sync.Mutex
+	subs  []*subscriber
+
+	// if set, updaters from different factories that share a name are
+	// prefixed with the factory name instead of being an error.
+	prefixDuplicates bool
+
+	// fraction of an updater's records that may be malformed before its
+	// update fails.
+	malformedLimit float64
 }
 
 // NewManager will return a manager ready to have its Start or Run methods called.
@@ -77,6 +122,8 @@ func NewManager(ctx context.Context, store vulnstore.Updater, locks LockSource,
 		batchSize: runtime.GOMAXPROCS(0),
 		interval:  DefaultInterval,
 		client:    client,
+
+		progressInterval: DefaultProgressInterval,
 	}
 
 	// these options can be ran order independent.
@@ -93,6 +140,11 @@ func NewManager(ctx context.Context, store vulnstore.Updater, locks LockSource,
 		return nil, fmt.Errorf("failed to configure updater set factory: %w", err)
 	}
 
+	// Catch colliding updater names now, rather than on every run.
+	if _, err := collectUpdaters(ctx, m.factories, m.prefixDuplicates); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
@@ -145,38 +197,42 @@ func (m *Manager) Run(ctx context.Context) error {
 		label.String("component", "libvuln/updates/Manager.Run"),
 	)
 
-	updaters := []driver.Updater{}
+	phases := runPhases
+	if m.updateRetention == 0 {
+		// Don't set time aside for GC if it's not going to run.
+		phases = phases[:len(phases)-1]
+	}
+	b := budget.New(ctx, phases...)
+
 	// Constructing updater sets may require network access
 	// depending on the factory.
 	// If construction fails, we will simply ignore those updater
-	// sets.
-	for _, factory := range m.factories {
-		set, err := factory.UpdaterSet(ctx)
-		if err != nil {
-			zlog.Error(ctx).Err(err).Msg("failed constructing factory, excluding from run")
-			continue
-		}
-		updaters = append(updaters, set.Updaters()...)
+	// sets. Factories may change what they construct between runs, so
+	// colliding names are excluded from this run and reported.
+	cctx, collected := b.Start(ctx, phaseCollect)
+	updaters, dupErr := collectUpdaters(cctx, m.factories, m.prefixDuplicates)
+	if dupErr != nil {
+		zlog.Error(ctx).Err(dupErr).Msg("excluding duplicate updaters from run")
 	}
 
 	// configure updaters
-	toRun := make([]driver.Updater, 0, len(updaters))
-	for _, u := range updaters {
-		if f, ok := u.(driver.Configurable); ok {
-			name := u.Name()
-			cfg := m.configs[name]
-			if cfg == nil {
-				cfg = noopConfig
-			}
-			if err := f.Configure(ctx, cfg, m.client); err != nil {
+	toRun := make([]namedUpdater, 0, len(updaters))
+	for _, nu := range updaters {
+		if f, ok := nu.u.(driver.Configurable); ok {
+			if err := f.Configure(cctx, nu.config(m.configs), m.client); err != nil {
 				zlog.Warn(ctx).
 					Err(err).
-					Str("updater", name).
+					Str("updater", nu.name).
 					Msg("failed configuring updater, excluding from current run")
 				continue
 			}
 		}
-		toRun = append(toRun, u)
+		toRun = append(toRun, nu)
+	}
+	if err := collected(cctx.Err()); err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("constructing updaters ran out of time")
 	}
 
 	zlog.Info(ctx).
@@ -184,10 +240,14 @@ func (m *Manager) Run(ctx context.Context) error {
 		Int("batchSize", m.batchSize).
 		Msg("running updaters")
 
+	uctx, updated := b.Start(ctx, phaseUpdate)
 	sem := semaphore.NewWeighted(int64(m.batchSize))
-	errChan := make(chan error, len(toRun)+1) // +1 for a potential ctx error
+	errChan := make(chan error, len(toRun)+2) // +2 for a potential ctx error and duplicates
+	if dupErr != nil {
+		errChan <- dupErr
+	}
 	for i := range toRun {
-		err := sem.Acquire(ctx, 1)
+		err := sem.Acquire(uctx, 1)
 		if err != nil {
 			zlog.Error(ctx).
 				Err(err).
@@ -195,30 +255,30 @@ func (m *Manager) Run(ctx context.Context) error {
 			break
 		}
 
-		go func(u driver.Updater) {
+		go func(nu namedUpdater) {
 			defer sem.Release(1)
 
-			if err := ctx.Err(); err != nil {
+			if err := uctx.Err(); err != nil {
 				return
 			}
 
 			lock := m.locks.NewLock()
-			ok, err := lock.TryLock(ctx, u.Name())
+			ok, err := lock.TryLock(uctx, nu.name)
 			if err != nil {
 				errChan <- err
 				return
 			}
 			if !ok {
 				zlog.Debug(ctx).
-					Str("updater", u.Name()).
+					Str("updater", nu.name).
 					Msg("another process running updater, excluding from run")
 				return
 			}
 			defer lock.Unlock()
 
-			err = m.driveUpdater(ctx, u)
+			err = m.driveUpdater(uctx, nu.name, nu.u)
 			if err != nil {
-				errChan <- fmt.Errorf("%v: %w", u.Name(), err)
+				errChan <- fmt.Errorf("%v: %w", nu.name, err)
 			}
 		}(toRun[i])
 	}
@@ -227,10 +287,18 @@ func (m *Manager) Run(ctx context.Context) error {
 	// The use of context.Background and lack of error checking is intentional.
 	// All in-flight goroutines are guaranteed to release their semaphores.
 	sem.Acquire(context.Background(), int64(m.batchSize))
+	// Report updaters being cut off by the phase's allotment, but not a
+	// canceled run.
+	var be *budget.Error
+	if err := updated(uctx.Err()); errors.As(err, &be) {
+		errChan <- err
+	}
 
 	if m.updateRetention != 0 {
 		zlog.Info(ctx).Int("retention", m.updateRetention).Msg("GC started")
-		i, err := m.store.GC(ctx, m.updateRetention)
+		gctx, gcDone := b.Start(ctx, phaseGC)
+		i, err := m.store.GC(gctx, m.updateRetention)
+		err = gcDone(err)
 		if err != nil {
 			zlog.Error(ctx).Err(err).Msg("error while performing GC")
 		} else {
@@ -254,9 +322,9 @@ func (m *Manager) Run(ctx context.Context) error {
 }
 
 // DriveUpdater performs the business logic of fetching, parsing, and loading
-// vulnerabilities discovered by an updater into the database.
-func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
-	name := u.Name()
+// vulnerabilities discovered by an updater into the database, under the
+// provided name.
+func (m *Manager) driveUpdater(ctx context.Context, name string, u driver.Updater) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libvuln/updates/Manager.driveUpdater"),
 		label.String("updater", name),
@@ -272,6 +340,13 @@ func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
 		uoKind = driver.EnrichmentKind
 	}
 
+	if pr, ok := u.(driver.ProgressReporter); ok {
+		p := m.newProgress(ctx, name)
+		pr.SetProgress(p.report)
+		defer p.done()
+		defer pr.SetProgress(nil)
+	}
+
 	var prevFP driver.Fingerprint
 	opmap, err := m.store.GetUpdateOperations(ctx, uoKind, name)
 	if err != nil {
@@ -282,13 +357,49 @@ func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
 		prevFP = s[0].Fingerprint
 	}
 
+	if pu, ok := u.(driver.PaginatedUpdater); ok && !euOK {
+		ref, ct, err := m.drivePaginated(ctx, name, pu, prevFP)
+		switch {
+		case err == nil:
+		case errors.Is(err, driver.Unchanged):
+			zlog.Info(ctx).
+				Str("kind", string(uoKind)).
+				Msg("database unchanged")
+			return nil
+		default:
+			return err
+		}
+		m.finishUpdate(ctx, name, u, uoKind, ref, ct)
+		return nil
+	}
+
+	var warnings int64
+	ctx = driver.WithWarnings(ctx, func(msg string) {
+		atomic.AddInt64(&warnings, 1)
+		zlog.Debug(ctx).
+			Str("warning", msg).
+			Msg("parse warning")
+	})
+	defer func() {
+		if n := atomic.LoadInt64(&warnings); n != 0 {
+			zlog.Warn(ctx).
+				Int64("count", n).
+				Msg("database parsed with warnings")
+		}
+	}()
+	ctx = m.recordPolicy(ctx, name)
+	b := budget.New(ctx, updatePhases...)
+
+	// The database is parsed in the fetch phase, as the reader returned by a
+	// Fetch may still depend on its Context.
+	fctx, fetched := b.Start(ctx, phaseFetch)
 	var vulnDB io.ReadCloser
 	var newFP driver.Fingerprint
 	switch {
 	case euOK:
-		vulnDB, newFP, err = eu.FetchEnrichment(ctx, prevFP)
+		vulnDB, newFP, err = eu.FetchEnrichment(fctx, prevFP)
 	default:
-		vulnDB, newFP, err = u.Fetch(ctx, prevFP)
+		vulnDB, newFP, err = u.Fetch(fctx, prevFP)
 	}
 	if vulnDB != nil {
 		defer vulnDB.Close()
@@ -296,38 +407,77 @@ func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
 	switch {
 	case err == nil:
 	case errors.Is(err, driver.Unchanged):
-		zlog.Info(ctx).Msg("vulnerability database unchanged")
+		fetched(nil)
+		// Enrichers backed by the store's own data always report Unchanged,
+		// so this is also the normal path for them.
+		zlog.Info(ctx).
+			Str("kind", string(uoKind)).
+			Msg("database unchanged")
 		return nil
 	default:
-		return err
+		return fetched(err)
 	}
 
-	var ref uuid.UUID
+	var ers []driver.EnrichmentRecord
+	var vulns []*claircore.Vulnerability
 	switch {
 	case euOK:
-		var ers []driver.EnrichmentRecord
-		ers, err = eu.ParseEnrichment(ctx, vulnDB)
+		ers, err = eu.ParseEnrichment(fctx, vulnDB)
 		if err != nil {
-			return fmt.Errorf("enrichment database parse failed: %v", err)
+			return fetched(fmt.Errorf("enrichment database parse failed: %v", err))
 		}
-
-		ref, err = m.store.UpdateEnrichments(ctx, name, newFP, ers)
 	default:
-		var vulns []*claircore.Vulnerability
-		vulns, err = u.Parse(ctx, vulnDB)
+		vulns, err = u.Parse(fctx, vulnDB)
 		if err != nil {
-			return fmt.Errorf("vulnerability database parse failed: %v", err)
+			return fetched(fmt.Errorf("vulnerability database parse failed: %v", err))
 		}
+	}
+	fetched(nil)
 
-		ref, err = m.store.UpdateVulnerabilities(ctx, name, newFP, vulns)
+	var ref uuid.UUID
+	var ct int
+	sctx, stored := b.Start(ctx, phaseStore)
+	switch {
+	case euOK:
+		ers, err = validateEnrichments(sctx, eu, ers)
+		if err != nil {
+			stored(nil)
+			return fmt.Errorf("enrichment database validation failed: %w", err)
+		}
+		ct = len(ers)
+		ref, err = m.updateEnrichments(sctx, name, newFP, ers)
+	default:
+		ct = len(vulns)
+		ref, err = m.updateVulnerabilities(sctx, name, newFP, vulns)
 	}
-	if err != nil {
-		return fmt.Errorf("failed to update: %v", err)
+	if err := stored(err); err != nil {
+		return fmt.Errorf("failed to update: %w", err)
+	}
+	m.finishUpdate(ctx, name, u, uoKind, ref, ct)
+	return nil
+}
+
+// FinishUpdate records the data source of a successful update and announces
+// it to subscribers.
+func (m *Manager) finishUpdate(ctx context.Context, name string, u driver.Updater, uoKind driver.UpdateKind, ref uuid.UUID, ct int) {
+	if ds, ok := u.(driver.DataSourcer); ok {
+		// The update itself succeeded, so don't fail it over attribution.
+		if err := m.store.SetDataSource(ctx, ref, ds.DataSource()); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("ref", ref.String()).
+				Msg("unable to record data source")
+		}
 	}
 	zlog.Info(ctx).
 		Str("ref", ref.String()).
 		Msg("successful update")
-	return nil
+	m.publish(ctx, UpdateEvent{
+		Updater: name,
+		Kind:    uoKind,
+		Ref:     ref,
+		Count:   ct,
+	})
 }
 
 // NoopConfig is used when an explicit config is not provided.
@@ -99,3 +99,44 @@ func WithFactories(f map[string]driver.UpdaterSetFactory) ManagerOption {
 		m.factories = f
 	}
 }
+
+// WithProgress arranges for "f" to be called with the name of the updater
+// and its report whenever an updater implementing driver.ProgressReporter
+// reports progress.
+//
+// The function is called synchronously from the updater and must not block.
+func WithProgress(f func(updater string, p driver.Progress)) ManagerOption {
+	return func(m *Manager) {
+		m.progress = f
+	}
+}
+
+// WithProgressInterval configures how often updater progress is logged.
+func WithProgressInterval(interval time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.progressInterval = interval
+	}
+}
+
+// WithPrefixedDuplicates configures the Manager to run updaters from
+// different factories that share a name under "<factory>/<name>", instead of
+// refusing to construct.
+//
+// Configs are looked up by the prefixed name first, then by the updater's own
+// name.
+func WithPrefixedDuplicates() ManagerOption {
+	return func(m *Manager) {
+		m.prefixDuplicates = true
+	}
+}
+
+// WithMalformedLimit configures the fraction of an updater's records that may
+// be malformed before its update fails. Malformed records below the limit are
+// skipped and counted.
+//
+// See driver.RecordPolicy for the meaning of zero and negative limits.
+func WithMalformedLimit(f float64) ManagerOption {
+	return func(m *Manager) {
+		m.malformedLimit = f
+	}
+}
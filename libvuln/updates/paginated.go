@@ -0,0 +1,172 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// DrivePaginated fetches every page of a PaginatedUpdater's source and creates
+// an update operation from them, returning its reference and the number of
+// vulnerabilities in it. It reports driver.Unchanged if the first page of a
+// pass does. Advisories repeated across pages are removed with
+// dedupeAdvisories before the update is stored.
+//
+// If the store implements vulnstore.Staging, each page is staged as soon as
+// it's fetched and a pass interrupted by an error resumes from the last staged
+// page on the next call. Otherwise, pages are held in memory and an error
+// means starting over.
+func (m *Manager) drivePaginated(ctx context.Context, name string, u driver.PaginatedUpdater, prevFP driver.Fingerprint) (uuid.UUID, int, error) {
+	var warnings int64
+	ctx = driver.WithWarnings(ctx, func(msg string) {
+		atomic.AddInt64(&warnings, 1)
+		zlog.Debug(ctx).
+			Str("warning", msg).
+			Msg("parse warning")
+	})
+	defer func() {
+		if n := atomic.LoadInt64(&warnings); n != 0 {
+			zlog.Warn(ctx).
+				Int64("count", n).
+				Msg("database parsed with warnings")
+		}
+	}()
+	ctx = m.recordPolicy(ctx, name)
+
+	stage, staging := m.store.(vulnstore.Staging)
+	var cursor string
+	var pages int
+	if staging {
+		su, err := stage.StagedUpdate(ctx, name)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+		switch {
+		case su == nil:
+		case su.Prev != prevFP:
+			// An update finished since this one was started, so the staged
+			// pages may be from a different version of the source.
+			zlog.Info(ctx).
+				Int("pages", su.Pages).
+				Msg("discarding outdated staged update")
+			if err := stage.DiscardStaged(ctx, name); err != nil {
+				return uuid.Nil, 0, err
+			}
+		default:
+			zlog.Info(ctx).
+				Int("pages", su.Pages).
+				Int("count", su.Count).
+				Msg("resuming staged update")
+			cursor = su.Cursor
+			pages = su.Pages
+		}
+	}
+
+	var held []*claircore.Vulnerability
+	for {
+		p, err := u.FetchPage(ctx, prevFP, cursor)
+		switch {
+		case err == nil:
+		case errors.Is(err, driver.Unchanged) && cursor == "":
+			return uuid.Nil, 0, err
+		default:
+			return uuid.Nil, 0, fmt.Errorf("page %d fetch failed: %w", pages+1, err)
+		}
+		pages++
+		zlog.Debug(ctx).
+			Int("page", pages).
+			Int("count", len(p.Vulnerabilities)).
+			Msg("fetched page")
+		if p.Next == "" {
+			all := held
+			if staging {
+				all, err = stage.StagedVulnerabilities(ctx, name)
+				if err != nil {
+					return uuid.Nil, 0, err
+				}
+			}
+			vs := dedupeAdvisories(append(all, p.Vulnerabilities...))
+			if n := len(all) + len(p.Vulnerabilities) - len(vs); n != 0 {
+				zlog.Debug(ctx).
+					Int("count", n).
+					Msg("removed repeated advisories")
+			}
+			ref, err := m.updateVulnerabilities(ctx, name, p.Fingerprint, vs)
+			if err != nil {
+				return uuid.Nil, 0, fmt.Errorf("failed to update: %v", err)
+			}
+			if staging {
+				// The update itself succeeded, so don't fail it. A leftover
+				// staged update is discarded on the next run, because its
+				// Prev no longer matches the latest update.
+				if err := stage.DiscardStaged(ctx, name); err != nil {
+					zlog.Warn(ctx).
+						Err(err).
+						Msg("unable to discard committed staged update")
+				}
+			}
+			return ref, len(vs), nil
+		}
+		if p.Next == cursor {
+			return uuid.Nil, 0, fmt.Errorf("page %d: next cursor %q is the current cursor", pages, cursor)
+		}
+		if staging {
+			if err := stage.StagePage(ctx, name, prevFP, p.Next, p.Vulnerabilities); err != nil {
+				return uuid.Nil, 0, fmt.Errorf("failed to stage page %d: %w", pages, err)
+			}
+		} else {
+			held = append(held, p.Vulnerabilities...)
+		}
+		cursor = p.Next
+	}
+}
+
+// dedupeAdvisories removes the earlier copies of advisories that appear more
+// than once in a paginated update, in place.
+//
+// Sources that page by modification time return an advisory edited during a
+// pass again on a later page. A run of consecutive vulnerabilities with the
+// same Name is one copy of an advisory, and only the last copy is kept for
+// each advisory, package, and repository. Keeping the whole run preserves an
+// advisory's multiple ranges for a package, including when a page boundary
+// splits it.
+func dedupeAdvisories(vs []*claircore.Vulnerability) []*claircore.Vulnerability {
+	type key struct {
+		name, pkg, repo string
+	}
+	mk := func(v *claircore.Vulnerability) key {
+		k := key{name: v.Name}
+		if v.Package != nil {
+			k.pkg = v.Package.Name
+		}
+		if v.Repo != nil {
+			k.repo = v.Repo.Name + "\x00" + v.Repo.URI
+		}
+		return k
+	}
+	run := make([]int, len(vs))
+	last := make(map[key]int)
+	r := 0
+	for i, v := range vs {
+		if i != 0 && v.Name != vs[i-1].Name {
+			r++
+		}
+		run[i] = r
+		last[mk(v)] = r
+	}
+	out := vs[:0]
+	for i, v := range vs {
+		if last[mk(v)] == run[i] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
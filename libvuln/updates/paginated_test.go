@@ -0,0 +1,323 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/jsonblob"
+)
+
+// PageUpdater serves "pages" pages of two vulnerabilities each, failing on
+// the page numbers in "fail" the first time they're fetched.
+type pageUpdater struct {
+	driver.NoopUpdater
+	pages   int
+	fp      driver.Fingerprint
+	fail    map[int]bool
+	fetched []string
+}
+
+var _ driver.PaginatedUpdater = (*pageUpdater)(nil)
+
+func (u *pageUpdater) Name() string { return "page-updater" }
+
+func (u *pageUpdater) FetchPage(_ context.Context, fp driver.Fingerprint, cursor string) (*driver.Page, error) {
+	if cursor == "" && fp == u.fp {
+		return nil, driver.Unchanged
+	}
+	n := 0
+	if cursor != "" {
+		var err error
+		n, err = strconv.Atoi(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	u.fetched = append(u.fetched, cursor)
+	if u.fail[n] {
+		delete(u.fail, n)
+		return nil, errors.New("injected failure")
+	}
+	p := &driver.Page{
+		Vulnerabilities: []*claircore.Vulnerability{
+			{Name: strconv.Itoa(n) + "-a"},
+			{Name: strconv.Itoa(n) + "-b"},
+		},
+		Fingerprint: u.fp,
+	}
+	if n+1 < u.pages {
+		p.Next = strconv.Itoa(n + 1)
+	}
+	return p, nil
+}
+
+// Names reports the names of the vulnerabilities in the store.
+func names(t *testing.T, s *jsonblob.Store) []string {
+	t.Helper()
+	var out []string
+	for _, e := range s.Entries() {
+		for _, v := range e.Vuln {
+			out = append(out, v.Name)
+		}
+	}
+	return out
+}
+
+func TestPaginated(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// Each case runs the manager once per element of Runs, which reports
+	// whether that run should succeed.
+	tt := []struct {
+		Name      string
+		Unstaged  bool
+		Fail      []int
+		Runs      []bool
+		WantFetch []string
+	}{
+		{
+			Name:      "Clean",
+			Runs:      []bool{true},
+			WantFetch: []string{"", "1", "2"},
+		},
+		{
+			Name:      "Resume",
+			Fail:      []int{2},
+			Runs:      []bool{false, true},
+			WantFetch: []string{"", "1", "2", "2"},
+		},
+		{
+			Name:      "ResumeTwice",
+			Fail:      []int{1, 2},
+			Runs:      []bool{false, false, true},
+			WantFetch: []string{"", "1", "1", "2", "2"},
+		},
+		{
+			Name:      "FirstPage",
+			Fail:      []int{0},
+			Runs:      []bool{false, true},
+			WantFetch: []string{"", "", "1", "2"},
+		},
+		{
+			Name:      "Unstaged",
+			Unstaged:  true,
+			Fail:      []int{2},
+			Runs:      []bool{false, true},
+			WantFetch: []string{"", "1", "2", "", "1", "2"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			s, err := jsonblob.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := &pageUpdater{pages: 3, fp: "v1", fail: make(map[int]bool)}
+			for _, n := range tc.Fail {
+				u.fail[n] = true
+			}
+			var store vulnstore.Updater = s
+			if tc.Unstaged {
+				store = struct{ vulnstore.Updater }{s}
+			}
+			m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+				WithEnabled([]string{}),
+				WithOutOfTree([]driver.Updater{u}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, ok := range tc.Runs {
+				err := m.Run(ctx)
+				switch {
+				case ok && err != nil:
+					t.Fatalf("run %d: %v", i, err)
+				case !ok && err == nil:
+					t.Fatalf("run %d: expected error", i)
+				}
+				if got, want := len(s.Entries()), 0; !ok && got != want {
+					t.Fatalf("run %d: got %d update operations, want %d", i, got, want)
+				}
+			}
+
+			if got, want := u.fetched, tc.WantFetch; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			if got, want := len(s.Entries()), 1; got != want {
+				t.Fatalf("got %d update operations, want %d", got, want)
+			}
+			want := []string{"0-a", "0-b", "1-a", "1-b", "2-a", "2-b"}
+			if got := names(t, s); !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			if su, err := s.StagedUpdate(ctx, u.Name()); err != nil || su != nil {
+				t.Errorf("staged update left behind: %+v, %v", su, err)
+			}
+
+			// The source hasn't changed, so the next run does nothing.
+			u.fetched = nil
+			if err := m.Run(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := u.fetched, []string(nil); !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+			if got, want := len(s.Entries()), 1; got != want {
+				t.Errorf("got %d update operations, want %d", got, want)
+			}
+		})
+	}
+}
+
+// TestPaginatedOutdated checks that pages staged against an older update are
+// thrown away.
+func TestPaginatedOutdated(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s, err := jsonblob.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &pageUpdater{pages: 2, fp: "v1", fail: make(map[int]bool)}
+	if err := s.StagePage(ctx, u.Name(), "v0", "1", []*claircore.Vulnerability{{Name: "stale"}}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(ctx, s, LocalLockSource(), &http.Client{},
+		WithEnabled([]string{}),
+		WithOutOfTree([]driver.Updater{u}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.fetched, []string{"", "1"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	want := []string{"0-a", "0-b", "1-a", "1-b"}
+	if got := names(t, s); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// editedUpdater serves fixed pages, as a source that pages by modification
+// time does when advisories are edited during a pass.
+type editedUpdater struct {
+	driver.NoopUpdater
+	pages [][]*claircore.Vulnerability
+}
+
+var _ driver.PaginatedUpdater = (*editedUpdater)(nil)
+
+func (u *editedUpdater) Name() string { return "edited-updater" }
+
+func (u *editedUpdater) FetchPage(_ context.Context, _ driver.Fingerprint, cursor string) (*driver.Page, error) {
+	n := 0
+	if cursor != "" {
+		var err error
+		n, err = strconv.Atoi(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p := &driver.Page{
+		Vulnerabilities: u.pages[n],
+		Fingerprint:     "v1",
+	}
+	if n+1 < len(u.pages) {
+		p.Next = strconv.Itoa(n + 1)
+	}
+	return p, nil
+}
+
+// onceStore records the keys of the updates it's asked to store.
+type onceStore struct {
+	*jsonblob.Store
+	keys []string
+}
+
+func (s *onceStore) UpdateVulnerabilitiesOnce(ctx context.Context, key, updater string, fp driver.Fingerprint, vs []*claircore.Vulnerability) (uuid.UUID, error) {
+	s.keys = append(s.keys, key)
+	return s.UpdateVulnerabilities(ctx, updater, fp, vs)
+}
+
+func (s *onceStore) UpdateEnrichmentsOnce(context.Context, string, string, driver.Fingerprint, []driver.EnrichmentRecord) (uuid.UUID, error) {
+	panic("unexpected call")
+}
+
+// TestPaginatedRepeated checks that an advisory returned again on a later page
+// is only stored once, from its latest copy.
+func TestPaginatedRepeated(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	v := func(name, pkg, desc string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Name:        name,
+			Description: desc,
+			Package:     &claircore.Package{Name: pkg},
+		}
+	}
+	pages := [][]*claircore.Vulnerability{
+		{v("A", "x", "old"), v("A", "x", "old"), v("A", "y", "old"), v("B", "x", "")},
+		// "C" is split by the page boundary, and "A" was edited after the
+		// first page was fetched.
+		{v("C", "x", "1")},
+		{v("C", "x", "2"), v("A", "x", "new"), v("D", "x", "")},
+	}
+	// Only packages in the later copy of "A" are replaced.
+	want := []string{"A/y/old", "B/x/", "C/x/1", "C/x/2", "A/x/new", "D/x/"}
+
+	for _, staged := range []bool{true, false} {
+		name := "Staged"
+		if !staged {
+			name = "Unstaged"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			s, err := jsonblob.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			once := &onceStore{Store: s}
+			var store vulnstore.Updater = once
+			if !staged {
+				store = struct {
+					vulnstore.Updater
+					vulnstore.Idempotent
+				}{once, once}
+			}
+			m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+				WithEnabled([]string{}),
+				WithOutOfTree([]driver.Updater{&editedUpdater{pages: pages}}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Run(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(once.keys), 1; got != want {
+				t.Errorf("got %d idempotent updates, want %d", got, want)
+			}
+			var got []string
+			for _, e := range s.Entries() {
+				for _, v := range e.Vuln {
+					got = append(got, v.Name+"/"+v.Package.Name+"/"+v.Description)
+				}
+			}
+			if !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		})
+	}
+}
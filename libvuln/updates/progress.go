@@ -0,0 +1,101 @@
+package updates
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// DefaultProgressInterval is how often the progress of an updater is logged
+// while it runs.
+const DefaultProgressInterval = 30 * time.Second
+
+var (
+	progressItems = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "updater",
+			Name:      "progress_items",
+			Help:      "Items processed in the current phase of a running update.",
+		},
+		[]string{"updater"},
+	)
+	progressBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "claircore",
+			Subsystem: "updater",
+			Name:      "progress_bytes",
+			Help:      "Bytes of the database read by a running update.",
+		},
+		[]string{"updater"},
+	)
+)
+
+// Progress receives the reports of a single updater that implements
+// driver.ProgressReporter.
+//
+// Every report updates the gauges and is passed to the Manager's progress
+// function, if any. Reports are logged at most once per interval, except
+// that the first report of each phase is always logged.
+type progress struct {
+	ctx      context.Context
+	name     string
+	interval time.Duration
+	now      func() time.Time
+	next     func(string, driver.Progress)
+
+	mu    sync.Mutex
+	last  time.Time
+	phase string
+	seen  bool
+}
+
+func (m *Manager) newProgress(ctx context.Context, name string) *progress {
+	return &progress{
+		ctx:      ctx,
+		name:     name,
+		interval: m.progressInterval,
+		now:      time.Now,
+		next:     m.progress,
+	}
+}
+
+// Report is a driver.ProgressFunc.
+func (p *progress) report(r driver.Progress) {
+	progressItems.WithLabelValues(p.name).Set(float64(r.Items))
+	progressBytes.WithLabelValues(p.name).Set(float64(r.Bytes))
+	if p.throttle(r) {
+		zlog.Info(p.ctx).
+			Str("phase", r.Phase).
+			Int64("items", r.Items).
+			Int64("bytes", r.Bytes).
+			Msg("update progress")
+	}
+	if p.next != nil {
+		p.next(p.name, r)
+	}
+}
+
+// Throttle reports whether the report should be logged.
+func (p *progress) throttle(r driver.Progress) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if p.seen && r.Phase == p.phase && now.Sub(p.last) < p.interval {
+		return false
+	}
+	p.seen, p.phase, p.last = true, r.Phase, now
+	return true
+}
+
+// Done removes the updater's gauges.
+func (p *progress) done() {
+	progressItems.DeleteLabelValues(p.name)
+	progressBytes.DeleteLabelValues(p.name)
+}
@@ -0,0 +1,123 @@
+package updates
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// ProgressUpdater reports progress for every one of its vulnerabilities.
+type progressUpdater struct {
+	mu sync.Mutex
+	f  driver.ProgressFunc
+	n  int
+}
+
+var _ driver.ProgressReporter = (*progressUpdater)(nil)
+
+func (*progressUpdater) Name() string { return "progress-updater" }
+
+func (u *progressUpdater) SetProgress(f driver.ProgressFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.f = f
+}
+
+func (*progressUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", nil
+}
+
+func (u *progressUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	u.mu.Lock()
+	f := u.f
+	u.mu.Unlock()
+	vs := make([]*claircore.Vulnerability, u.n)
+	for i := range vs {
+		vs[i] = &claircore.Vulnerability{}
+		if f != nil {
+			f(driver.Progress{Phase: "parse", Items: int64(i + 1), Bytes: int64(i+1) * 100})
+		}
+	}
+	return vs, nil
+}
+
+func TestProgress(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u := &progressUpdater{n: 1000}
+	var got []driver.Progress
+	m, err := NewManager(ctx, &eventStore{}, LocalLockSource(), &http.Client{},
+		WithEnabled([]string{}),
+		WithOutOfTree([]driver.Updater{u}),
+		WithProgress(func(name string, p driver.Progress) {
+			if name != u.Name() {
+				t.Errorf("got updater %q, want %q", name, u.Name())
+			}
+			got = append(got, p)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != u.n {
+		t.Fatalf("got %d reports, want %d", len(got), u.n)
+	}
+	if p := got[len(got)-1]; p.Items != int64(u.n) || p.Bytes != int64(u.n)*100 {
+		t.Errorf("unexpected last report: %+v", p)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.f != nil {
+		t.Error("progress function not removed after update")
+	}
+}
+
+func TestProgressThrottle(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	now := time.Unix(0, 0)
+	p := &progress{
+		ctx:      ctx,
+		name:     "test",
+		interval: 10 * time.Second,
+		now:      func() time.Time { return now },
+	}
+	defer p.done()
+
+	tt := []struct {
+		phase string
+		n     int
+		step  time.Duration
+		want  int
+	}{
+		// The first report is logged, then one every interval.
+		{phase: "decode", n: 100, step: time.Second, want: 10},
+		// A new phase is logged immediately.
+		{phase: "parse", n: 1, step: time.Second, want: 1},
+		// Reports faster than the interval are dropped.
+		{phase: "parse", n: 1000, step: time.Millisecond, want: 0},
+		// Reports slower than the interval are all logged.
+		{phase: "parse", n: 5, step: time.Minute, want: 5},
+	}
+	for i, tc := range tt {
+		var logged int
+		for j := 0; j < tc.n; j++ {
+			now = now.Add(tc.step)
+			if p.throttle(driver.Progress{Phase: tc.phase, Items: int64(j)}) {
+				logged++
+			}
+		}
+		if logged != tc.want {
+			t.Errorf("%d: logged %d of %d reports, want %d", i, logged, tc.n, tc.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package updates
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var malformedRecords = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "claircore",
+		Subsystem: "updater",
+		Name:      "malformed_records_total",
+		Help:      "Records skipped by updaters because they couldn't be parsed.",
+	},
+	[]string{"updater"},
+)
+
+// RecordPolicy returns a Context carrying the Manager's driver.RecordPolicy
+// for the named updater.
+func (m *Manager) recordPolicy(ctx context.Context, name string) context.Context {
+	c := malformedRecords.WithLabelValues(name)
+	return driver.WithRecordPolicy(ctx, driver.RecordPolicy{
+		Limit:     m.malformedLimit,
+		Malformed: func(string, error) { c.Inc() },
+	})
+}
+
+// ValidateEnrichments returns the records that pass the updater's
+// driver.EnrichmentValidator, or all of them if it doesn't implement one.
+// Records that fail are counted as malformed, following the
+// driver.RecordPolicy in the Context.
+func validateEnrichments(ctx context.Context, u driver.EnrichmentUpdater, ers []driver.EnrichmentRecord) ([]driver.EnrichmentRecord, error) {
+	v, ok := u.(driver.EnrichmentValidator)
+	if !ok {
+		return ers, nil
+	}
+	rec := driver.NewRecords(ctx)
+	out := ers[:0:0]
+	for i := range ers {
+		if err := v.ValidateEnrichment(ctx, &ers[i]); err != nil {
+			id := strings.Join(ers[i].Tags, ",")
+			if id == "" {
+				id = strconv.Itoa(i)
+			}
+			rec.Malformed(id, err)
+			continue
+		}
+		rec.Ok()
+		out = append(out, ers[i])
+	}
+	if err := rec.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,204 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// MalformedUpdater has "bad" malformed records among "good" ones.
+type malformedUpdater struct {
+	name      string
+	good, bad int
+}
+
+func (u *malformedUpdater) Name() string { return u.name }
+
+func (*malformedUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", nil
+}
+
+func (u *malformedUpdater) Parse(ctx context.Context, _ io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	rec := driver.NewRecords(ctx)
+	var vs []*claircore.Vulnerability
+	for i := 0; i < u.good; i++ {
+		rec.Ok()
+		vs = append(vs, &claircore.Vulnerability{})
+	}
+	for i := 0; i < u.bad; i++ {
+		rec.Malformed(fmt.Sprintf("bad-%d", i), errors.New("malformed"))
+	}
+	if err := rec.Err(); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+func TestMalformedRecords(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name    string
+		Opts    []ManagerOption
+		Updater *malformedUpdater
+		Fail    bool
+	}{
+		{
+			Name:    "Skipped",
+			Updater: &malformedUpdater{name: "malformed-skipped", good: 99, bad: 1},
+		},
+		{
+			Name:    "TooMany",
+			Updater: &malformedUpdater{name: "malformed-toomany", good: 1, bad: 3},
+			Fail:    true,
+		},
+		{
+			Name:    "Limit",
+			Opts:    []ManagerOption{WithMalformedLimit(0.8)},
+			Updater: &malformedUpdater{name: "malformed-limit", good: 1, bad: 3},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			opts := append([]ManagerOption{
+				WithEnabled([]string{}),
+				WithOutOfTree([]driver.Updater{tc.Updater}),
+			}, tc.Opts...)
+			m, err := NewManager(ctx, &eventStore{}, LocalLockSource(), &http.Client{}, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The counter is global, so only the change is checked.
+			c := malformedRecords.WithLabelValues(tc.Updater.Name())
+			before := testutil.ToFloat64(c)
+			err = m.Run(ctx)
+			if got, want := err != nil, tc.Fail; got != want {
+				t.Errorf("got error: %v, want failure: %v", err, want)
+			}
+			if got, want := testutil.ToFloat64(c)-before, float64(tc.Updater.bad); got != want {
+				t.Errorf("got %v malformed records counted, want %v", got, want)
+			}
+		})
+	}
+}
+
+// EnrichmentStore is an eventStore that records the enrichments it's passed.
+type enrichmentStore struct {
+	eventStore
+	records []driver.EnrichmentRecord
+}
+
+func (s *enrichmentStore) UpdateEnrichments(_ context.Context, _ string, _ driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = es
+	return uuid.New(), nil
+}
+
+// ValidatingUpdater reports "good" valid and "bad" invalid records, and
+// rejects the invalid ones in ValidateEnrichment.
+type validatingUpdater struct {
+	driver.NoopUpdater
+	name      string
+	good, bad int
+}
+
+func (u *validatingUpdater) Name() string { return u.name }
+
+func (*validatingUpdater) FetchEnrichment(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", nil
+}
+
+func (u *validatingUpdater) ParseEnrichment(context.Context, io.ReadCloser) ([]driver.EnrichmentRecord, error) {
+	var out []driver.EnrichmentRecord
+	for i := 0; i < u.good; i++ {
+		out = append(out, driver.EnrichmentRecord{
+			Tags:       []string{fmt.Sprintf("good-%d", i)},
+			Enrichment: []byte(`{"ok":true}`),
+		})
+	}
+	for i := 0; i < u.bad; i++ {
+		out = append(out, driver.EnrichmentRecord{
+			Tags:       []string{fmt.Sprintf("bad-%d", i)},
+			Enrichment: []byte(`{"ok":false}`),
+		})
+	}
+	return out, nil
+}
+
+func (*validatingUpdater) ValidateEnrichment(_ context.Context, r *driver.EnrichmentRecord) error {
+	if string(r.Enrichment) != `{"ok":true}` {
+		return errors.New("schema mismatch")
+	}
+	return nil
+}
+
+func TestEnrichmentValidator(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name    string
+		Updater *validatingUpdater
+		Fail    bool
+	}{
+		{
+			Name:    "Valid",
+			Updater: &validatingUpdater{name: "validator-valid", good: 3},
+		},
+		{
+			Name:    "Skipped",
+			Updater: &validatingUpdater{name: "validator-skipped", good: 19, bad: 1},
+		},
+		{
+			Name:    "TooMany",
+			Updater: &validatingUpdater{name: "validator-toomany", good: 1, bad: 3},
+			Fail:    true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			store := &enrichmentStore{}
+			m, err := NewManager(ctx, store, LocalLockSource(), &http.Client{},
+				WithEnabled([]string{}),
+				WithOutOfTree([]driver.Updater{tc.Updater}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The counter is global, so only the change is checked.
+			c := malformedRecords.WithLabelValues(tc.Updater.Name())
+			before := testutil.ToFloat64(c)
+			err = m.Run(ctx)
+			if got, want := err != nil, tc.Fail; got != want {
+				t.Errorf("got error: %v, want failure: %v", err, want)
+			}
+			if got, want := testutil.ToFloat64(c)-before, float64(tc.Updater.bad); got != want {
+				t.Errorf("got %v invalid records counted, want %v", got, want)
+			}
+			if tc.Fail {
+				if store.records != nil {
+					t.Error("records stored after failed validation")
+				}
+				return
+			}
+			if got, want := len(store.records), tc.Updater.good; got != want {
+				t.Errorf("got %d records stored, want %d", got, want)
+			}
+			for _, r := range store.records {
+				if err := tc.Updater.ValidateEnrichment(ctx, &r); err != nil {
+					t.Errorf("invalid record stored: %v", r.Tags)
+				}
+			}
+		})
+	}
+}
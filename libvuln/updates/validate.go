@@ -0,0 +1,220 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/updater"
+)
+
+const (
+	// DefaultValidateSamples is the number of sample records a Validation
+	// holds.
+	DefaultValidateSamples = 5
+	// MaxValidateWarnings is the number of warning messages a Validation
+	// holds. Further warnings are only counted.
+	MaxValidateWarnings = 100
+)
+
+// Validation is a summary of a single updater's database, as fetched and
+// parsed by Validate.
+type Validation struct {
+	Updater     string             `json:"updater"`
+	Kind        driver.UpdateKind  `json:"kind"`
+	Fingerprint driver.Fingerprint `json:"fingerprint"`
+	// Records is the number of vulnerabilities or enrichment records parsed.
+	Records int `json:"records"`
+	// Severities counts vulnerabilities by their normalized severity.
+	Severities map[string]int `json:"severities,omitempty"`
+	// Samples and EnrichmentSamples are records spread evenly through the
+	// parsed database.
+	Samples           []*claircore.Vulnerability `json:"samples,omitempty"`
+	EnrichmentSamples []driver.EnrichmentRecord  `json:"enrichment_samples,omitempty"`
+	// Warnings holds the first MaxValidateWarnings warnings reported during
+	// the parse, and WarningCount the total.
+	Warnings     []string `json:"warnings"`
+	WarningCount int      `json:"warning_count"`
+}
+
+// ValidateOption specifies optional configuration for Validate.
+type ValidateOption func(*validateOpts)
+
+type validateOpts struct {
+	factories map[string]driver.UpdaterSetFactory
+	configs   Configs
+	client    *http.Client
+	samples   int
+}
+
+// ValidateFactories sets the UpdaterSetFactories searched for the named
+// updater. By default, the registered factories are used.
+func ValidateFactories(f map[string]driver.UpdaterSetFactory) ValidateOption {
+	return func(o *validateOpts) {
+		o.factories = f
+	}
+}
+
+// ValidateConfigs provides configuration for factories and updaters, as
+// WithConfigs does for a Manager.
+func ValidateConfigs(cfgs Configs) ValidateOption {
+	return func(o *validateOpts) {
+		o.configs = cfgs
+	}
+}
+
+// ValidateClient sets the http.Client used to fetch the database. By
+// default, http.DefaultClient is used.
+func ValidateClient(c *http.Client) ValidateOption {
+	return func(o *validateOpts) {
+		o.client = c
+	}
+}
+
+// ValidateSamples sets the number of sample records returned.
+func ValidateSamples(n int) ValidateOption {
+	return func(o *validateOpts) {
+		o.samples = n
+	}
+}
+
+// Validate fetches and parses the database of the named updater, without
+// consulting or modifying a store, and summarizes the result.
+//
+// This is meant for catching changes to a vendor's database format before
+// they break updates, by running it against the vendor's feed on a schedule.
+// An error is returned if the updater can't be found, configured, fetched,
+// or parsed; problems the updater could skip over are reported as warnings
+// in the Validation.
+func Validate(ctx context.Context, name string, opts ...ValidateOption) (*Validation, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/updates/Validate"),
+		label.String("updater", name))
+	o := validateOpts{
+		factories: updater.Registered(),
+		client:    http.DefaultClient,
+		samples:   DefaultValidateSamples,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	u, err := findUpdater(ctx, name, &o)
+	if err != nil {
+		return nil, err
+	}
+	v := Validation{
+		Updater:  name,
+		Kind:     driver.VulnerabilityKind,
+		Warnings: []string{},
+	}
+	var mu sync.Mutex
+	ctx = driver.WithWarnings(ctx, func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		v.WarningCount++
+		if len(v.Warnings) < MaxValidateWarnings {
+			v.Warnings = append(v.Warnings, msg)
+		}
+	})
+
+	eu, euOK := u.(driver.EnrichmentUpdater)
+	if euOK {
+		v.Kind = driver.EnrichmentKind
+		rc, fp, err := eu.FetchEnrichment(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("updates: %s: fetch failed: %w", name, err)
+		}
+		if rc != nil {
+			defer rc.Close()
+		}
+		v.Fingerprint = fp
+		ers, err := eu.ParseEnrichment(ctx, rc)
+		if err != nil {
+			return nil, fmt.Errorf("updates: %s: parse failed: %w", name, err)
+		}
+		ers, err = validateEnrichments(ctx, eu, ers)
+		if err != nil {
+			return nil, fmt.Errorf("updates: %s: validation failed: %w", name, err)
+		}
+		v.Records = len(ers)
+		for _, i := range sampleIndexes(len(ers), o.samples) {
+			v.EnrichmentSamples = append(v.EnrichmentSamples, ers[i])
+		}
+	} else {
+		rc, fp, err := u.Fetch(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("updates: %s: fetch failed: %w", name, err)
+		}
+		if rc != nil {
+			defer rc.Close()
+		}
+		v.Fingerprint = fp
+		vs, err := u.Parse(ctx, rc)
+		if err != nil {
+			return nil, fmt.Errorf("updates: %s: parse failed: %w", name, err)
+		}
+		v.Records = len(vs)
+		v.Severities = make(map[string]int)
+		for _, vuln := range vs {
+			v.Severities[vuln.NormalizedSeverity.String()]++
+		}
+		for _, i := range sampleIndexes(len(vs), o.samples) {
+			v.Samples = append(v.Samples, vs[i])
+		}
+	}
+	zlog.Info(ctx).
+		Int("records", v.Records).
+		Int("warnings", v.WarningCount).
+		Msg("validated")
+	return &v, nil
+}
+
+// FindUpdater constructs the updater sets from the configured factories and
+// returns the configured updater with the provided name.
+//
+// Updaters whose names collide with another factory's can't be found.
+func findUpdater(ctx context.Context, name string, o *validateOpts) (driver.Updater, error) {
+	if err := updater.Configure(ctx, o.factories, o.configs, o.client); err != nil {
+		return nil, fmt.Errorf("updates: failed to configure updater set factory: %w", err)
+	}
+	us, dupErr := collectUpdaters(ctx, o.factories, false)
+	for _, nu := range us {
+		if nu.name != name {
+			continue
+		}
+		if f, ok := nu.u.(driver.Configurable); ok {
+			if err := f.Configure(ctx, nu.config(o.configs), o.client); err != nil {
+				return nil, fmt.Errorf("updates: %s: failed configuring updater: %w", name, err)
+			}
+		}
+		return nu.u, nil
+	}
+	if dupErr != nil {
+		return nil, dupErr
+	}
+	return nil, fmt.Errorf("updates: no updater named %q", name)
+}
+
+// SampleIndexes returns up to n indexes spread evenly over a slice of length
+// l.
+func sampleIndexes(l, n int) []int {
+	if n > l {
+		n = l
+	}
+	if n <= 0 {
+		return nil
+	}
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i * l / n
+	}
+	return out
+}
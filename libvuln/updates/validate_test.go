@@ -0,0 +1,84 @@
+package updates
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// ValidateUpdater parses one vulnerability per line of its database, and
+// warns about blank lines.
+type validateUpdater struct{}
+
+func (validateUpdater) Name() string { return "validate-updater" }
+
+func (validateUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	db := "CVE-1 High\n\nCVE-2 Low\nCVE-3 High\n\nCVE-4 Unknown\n"
+	return ioutil.NopCloser(strings.NewReader(db)), "fp", nil
+}
+
+func (validateUpdater) Parse(ctx context.Context, rc io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	var vs []*claircore.Vulnerability
+	for i, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		fs := strings.Fields(l)
+		if len(fs) != 2 {
+			driver.Warnf(ctx, "line %d: malformed record", i+1)
+			continue
+		}
+		v := &claircore.Vulnerability{Name: fs[0]}
+		if err := v.NormalizedSeverity.UnmarshalText([]byte(fs[1])); err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func TestValidate(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	us := driver.NewUpdaterSet()
+	if err := us.Add(validateUpdater{}); err != nil {
+		t.Fatal(err)
+	}
+	fs := map[string]driver.UpdaterSetFactory{"test": driver.StaticSet(us)}
+
+	v, err := Validate(ctx, "validate-updater",
+		ValidateFactories(fs),
+		ValidateClient(&http.Client{}),
+		ValidateSamples(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Kind != driver.VulnerabilityKind || v.Fingerprint != "fp" {
+		t.Errorf("unexpected kind or fingerprint: %q, %q", v.Kind, v.Fingerprint)
+	}
+	if got, want := v.Records, 4; got != want {
+		t.Errorf("got %d records, want %d", got, want)
+	}
+	if got := v.Severities; got["High"] != 2 || got["Low"] != 1 || got["Unknown"] != 1 {
+		t.Errorf("unexpected severities: %v", got)
+	}
+	if len(v.Samples) != 2 || v.Samples[0].Name != "CVE-1" || v.Samples[1].Name != "CVE-3" {
+		t.Errorf("unexpected samples: %v", v.Samples)
+	}
+	if got, want := v.WarningCount, 2; got != want || len(v.Warnings) != want {
+		t.Errorf("got %d warnings (%v), want %d", got, v.Warnings, want)
+	}
+
+	if _, err := Validate(ctx, "missing-updater", ValidateFactories(fs)); err == nil {
+		t.Error("validated a missing updater")
+	}
+}
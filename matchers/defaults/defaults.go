@@ -9,12 +9,16 @@ import (
 	"github.com/quay/claircore/alpine"
 	"github.com/quay/claircore/aws"
 	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/flatpak"
+	"github.com/quay/claircore/gobin"
+	"github.com/quay/claircore/java"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/matchers/registry"
 	"github.com/quay/claircore/oracle"
 	"github.com/quay/claircore/photon"
 	"github.com/quay/claircore/python"
 	"github.com/quay/claircore/rhel"
+	"github.com/quay/claircore/snap"
 	"github.com/quay/claircore/suse"
 	"github.com/quay/claircore/ubuntu"
 )
@@ -43,10 +47,14 @@ var defaultMatchers = []driver.Matcher{
 	&alpine.Matcher{},
 	&aws.Matcher{},
 	&debian.Matcher{},
+	&flatpak.Matcher{},
+	&gobin.Matcher{},
+	&java.Matcher{},
 	&oracle.Matcher{},
 	&photon.Matcher{},
 	&python.Matcher{},
 	&rhel.Matcher{},
+	&snap.Matcher{},
 	&suse.Matcher{},
 	&ubuntu.Matcher{},
 }
@@ -56,5 +64,7 @@ func inner(ctx context.Context) error {
 		mf := driver.MatcherStatic(m)
 		registry.Register(m.Name(), mf)
 	}
+	// The derivative matcher is opt-in, and returns nothing until configured.
+	registry.Register("debian-derivative", &debian.DerivativeFactory{})
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -23,11 +24,14 @@ import (
 
 const (
 	scannerName    = "os-release"
-	scannerVersion = "v0.0.2"
+	scannerVersion = "v0.0.3"
 	scannerKind    = "distribution"
 )
 
-const fpath = `etc/os-release`
+const (
+	fpath    = `etc/os-release`
+	usrfpath = `usr/lib/os-release`
+)
 
 var _ indexer.DistributionScanner = (*Scanner)(nil)
 var _ indexer.VersionedScanner = (*Scanner)(nil)
@@ -93,6 +97,27 @@ func (s *Scanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Di
 		return nil, ctx.Err()
 	}
 
+	// Layers exported from ostree commits, like Fedora CoreOS, have their
+	// os-release file as a link into the ostree repository, which the walk
+	// above doesn't follow.
+	fs, err := l.Files(fpath, usrfpath)
+	switch {
+	case err == nil:
+		for _, p := range []string{fpath, usrfpath} {
+			b, ok := fs[p]
+			if !ok {
+				continue
+			}
+			d, err := parse(ctx, b)
+			if err == nil {
+				return []*claircore.Distribution{d}, nil
+			}
+		}
+	case errors.Is(err, claircore.ErrNotFound): // OK
+	default:
+		return nil, fmt.Errorf("osrelease: unable to read layer: %w", err)
+	}
+
 	zlog.Debug(ctx).Msg("didn't find an os-release file")
 	return nil, nil
 }
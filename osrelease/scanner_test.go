@@ -187,3 +187,30 @@ func TestLayer(t *testing.T) {
 		t.Run(tc.Name, tc.Test)
 	}
 }
+
+// TestOstree checks that an os-release file that's a link into an ostree
+// repository is found.
+func TestOstree(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := &claircore.Layer{}
+	if err := l.SetLocal(filepath.Join("testdata", "fcos.tar")); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Distribution{
+		&claircore.Distribution{
+			DID:        "fedora",
+			Name:       "Fedora Linux",
+			Version:    "38.20231027.3.2 (CoreOS)",
+			VersionID:  "38",
+			PrettyName: "Fedora",
+			CPE:        cpe.MustUnbind("cpe:/o:fedoraproject:fedora:38"),
+		},
+	}
+	if !cmp.Equal(ds, want) {
+		t.Fatal(cmp.Diff(ds, want))
+	}
+}
@@ -29,6 +29,22 @@ type Package struct {
 	Arch string `json:"arch,omitempty"`
 	// CPE name for package
 	CPE cpe.WFN `json:"cpe,omitempty"`
+	// PURL is the package URL for the package, generated by the coalescer
+	// from the package and the distribution and repositories it was found
+	// with.
+	PURL string `json:"purl,omitempty"`
+	// Files lists files found with the package that matchers may need to
+	// know about, such as classes in a Java archive. Paths are relative to
+	// the package: for an archive, they're paths inside it.
+	//
+	// Only scanners that take an inventory of a package set this, and an
+	// empty list means nothing is known about the package's files.
+	Files []string `json:"files,omitempty"`
+	// License is the license the package declares, as recorded by the
+	// scanner that found it. It's an SPDX license expression where the
+	// package's metadata provides one, and the metadata's free-form text
+	// otherwise.
+	License string `json:"license,omitempty"`
 }
 
 const (
@@ -0,0 +1,80 @@
+// Package appmap maps applications installed by application stores, like
+// Flatpak and Snap, to the packages vulnerability databases file their
+// vulnerabilities under.
+//
+// Application stores have their own namespaces, and no vulnerability data of
+// their own. An application that bundles a project published elsewhere, like
+// a snap of a python tool, can still be matched as that project when the
+// mapping is known and the application's version follows the project's.
+package appmap
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/python"
+)
+
+// Target is the package an application is matched as.
+type Target struct {
+	// Name is the package's name in the vulnerability data.
+	Name string
+	// Repository is the URI of the repository the vulnerability data is
+	// filed under. Only vulnerabilities from this repository are reported.
+	Repository string
+}
+
+// Table maps application names to the packages they're matched as.
+type Table map[string]Target
+
+// Comparators are the version comparisons for each supported repository. The
+// Matchers here only have their Vulnerable method called.
+var comparators = map[string]driver.Matcher{
+	python.Repository.URI: &python.Matcher{},
+}
+
+// Lookup returns the Target for the application "name", and reports whether
+// there's one that can be matched.
+func (t Table) Lookup(name string) (Target, bool) {
+	tgt, ok := t[name]
+	if !ok || tgt.Name == "" {
+		return Target{}, false
+	}
+	if _, ok := comparators[tgt.Repository]; !ok {
+		return Target{}, false
+	}
+	return tgt, true
+}
+
+// Package returns a copy of "p" named as its Target, or nil if the
+// application isn't mapped.
+func (t Table) Package(p *claircore.Package) *claircore.Package {
+	tgt, ok := t.Lookup(p.Name)
+	if !ok {
+		return nil
+	}
+	return &claircore.Package{
+		ID:      p.ID,
+		Name:    tgt.Name,
+		Version: p.Version,
+		Kind:    claircore.BINARY,
+		Arch:    p.Arch,
+	}
+}
+
+// Vulnerable reports whether the application in "record" is affected by
+// "vuln", comparing versions the way the Target's repository does.
+//
+// Vulnerabilities from other repositories are never reported, so a name
+// shared across ecosystems doesn't cause false positives.
+func (t Table) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	tgt, ok := t.Lookup(record.Package.Name)
+	if !ok {
+		return false, nil
+	}
+	if vuln.Repo == nil || vuln.Repo.URI != tgt.Repository {
+		return false, nil
+	}
+	return comparators[tgt.Repository].Vulnerable(ctx, record, vuln)
+}
@@ -0,0 +1,76 @@
+package appmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/python"
+)
+
+func TestTable(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tbl := Table{
+		"certbot": {Name: "certbot", Repository: python.Repository.URI},
+		"unknown": {Name: "unknown", Repository: "https://example.com"},
+	}
+	if _, ok := tbl.Lookup("unknown"); ok {
+		t.Error("mapped to a repository without a comparator")
+	}
+	if p := tbl.Package(&claircore.Package{Name: "firefox"}); p != nil {
+		t.Errorf("unexpected mapping: %+v", p)
+	}
+	p := tbl.Package(&claircore.Package{ID: "1", Name: "certbot", Version: "1.21.0"})
+	if p == nil || p.ID != "1" || p.Name != "certbot" || p.Kind != claircore.BINARY {
+		t.Fatalf("unexpected mapping: %+v", p)
+	}
+
+	rec := &claircore.IndexRecord{Package: &claircore.Package{Name: "certbot", Version: "1.21.0"}}
+	tt := []struct {
+		Name string
+		Vuln *claircore.Vulnerability
+		Want bool
+	}{
+		{
+			Name: "Affected",
+			Vuln: &claircore.Vulnerability{
+				Package: &claircore.Package{Name: "certbot", Version: "<1.22.0"},
+				Repo:    &python.Repository,
+			},
+			Want: true,
+		},
+		{
+			Name: "Fixed",
+			Vuln: &claircore.Vulnerability{
+				Package: &claircore.Package{Name: "certbot", Version: "<1.20.0"},
+				Repo:    &python.Repository,
+			},
+		},
+		{
+			Name: "OtherRepository",
+			Vuln: &claircore.Vulnerability{
+				Package: &claircore.Package{Name: "certbot", Version: "<1.22.0"},
+				Repo:    &claircore.Repository{Name: "debian"},
+			},
+		},
+		{
+			Name: "NoRepository",
+			Vuln: &claircore.Vulnerability{
+				Package: &claircore.Package{Name: "certbot", Version: "<1.22.0"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := tbl.Vulnerable(ctx, rec, tc.Vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got: %v, want: %v", got, tc.Want)
+			}
+		})
+	}
+}
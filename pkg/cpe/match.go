@@ -0,0 +1,202 @@
+package cpe
+
+import (
+	"strings"
+)
+
+// Relation is the relation between a source and target attribute value, as
+// defined by the CPE Name Matching spec:
+// https://nvlpubs.nist.gov/nistpubs/Legacy/IR/nistir7696.pdf
+type Relation uint
+
+//go:generate stringer -type Relation
+
+// These are the possible relations between two attribute values.
+const (
+	Disjoint Relation = iota
+	Subset
+	Superset
+	Equal
+	Undefined
+)
+
+// Relations is the set of attribute comparisons between two WFNs.
+type Relations [NumAttr]Relation
+
+// Compare reports the relations between the attributes of the source and
+// target WFNs, according to section 6.2 of the matching spec.
+//
+// Unset values are treated as ANY.
+func Compare(src, tgt WFN) (r Relations) {
+	for i := 0; i < NumAttr; i++ {
+		r[i] = compareValues(&src.Attr[i], &tgt.Attr[i])
+	}
+	return r
+}
+
+// IsDisjoint reports whether any attribute relation is DISJOINT.
+func (r Relations) IsDisjoint() bool {
+	for _, v := range r {
+		if v == Disjoint {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEqual reports whether all attribute relations are EQUAL.
+func (r Relations) IsEqual() bool {
+	for _, v := range r {
+		if v != Equal {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubset reports whether the source is a subset of (or equal to) the
+// target, meaning every attribute relation is SUBSET or EQUAL.
+func (r Relations) IsSubset() bool {
+	for _, v := range r {
+		if v != Subset && v != Equal {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset reports whether the source is a superset of (or equal to) the
+// target, meaning every attribute relation is SUPERSET or EQUAL.
+func (r Relations) IsSuperset() bool {
+	for _, v := range r {
+		if v != Superset && v != Equal {
+			return false
+		}
+	}
+	return true
+}
+
+func compareValues(src, tgt *Value) Relation {
+	sk, tk := src.Kind, tgt.Kind
+	if sk == ValueUnset {
+		sk = ValueAny
+	}
+	if tk == ValueUnset {
+		tk = ValueAny
+	}
+	if sk == ValueSet && tk == ValueSet && hasWildcard(tgt.V) {
+		// Wildcards are only defined in the source.
+		return Undefined
+	}
+	switch sk {
+	case ValueAny:
+		if tk == ValueAny {
+			return Equal
+		}
+		return Superset
+	case ValueNA:
+		switch tk {
+		case ValueAny:
+			return Subset
+		case ValueNA:
+			return Equal
+		}
+		return Disjoint
+	}
+	switch tk {
+	case ValueAny:
+		return Subset
+	case ValueNA:
+		return Disjoint
+	}
+	if strings.EqualFold(src.V, tgt.V) {
+		return Equal
+	}
+	if hasWildcard(src.V) && compareStrings(strings.ToLower(src.V), strings.ToLower(tgt.V)) {
+		return Superset
+	}
+	return Disjoint
+}
+
+// HasWildcard reports whether the string contains an unquoted special
+// character.
+func hasWildcard(s string) bool {
+	esc := false
+	for _, r := range s {
+		switch {
+		case esc:
+			esc = false
+		case r == '\\':
+			esc = true
+		case r == '*' || r == '?':
+			return true
+		}
+	}
+	return false
+}
+
+// CompareStrings implements the "compareStrings" function from section 6.1.2.3
+// of the matching spec, reporting whether the source, which may contain
+// leading or trailing special characters, matches the target.
+func compareStrings(src, tgt string) bool {
+	start, end := 0, len(src)
+	begins, ends := 0, 0
+	if strings.HasPrefix(src, "*") {
+		start = 1
+		begins = -1
+	} else {
+		for start < len(src) && src[start] == '?' {
+			start++
+			begins++
+		}
+	}
+	if strings.HasSuffix(src, "*") && evenEscapes(src, end-1) {
+		end--
+		ends = -1
+	} else {
+		for end > start && src[end-1] == '?' && evenEscapes(src, end-1) {
+			end--
+			ends++
+		}
+	}
+	src = src[start:end]
+	for idx := strings.Index(tgt, src); idx != -1; {
+		if begins != -1 && idx-countEscapes(tgt[:idx]) > begins {
+			return false
+		}
+		leftover := len(tgt) - idx - len(src)
+		leftover -= countEscapes(tgt[idx+len(src):])
+		if leftover == 0 || ends == -1 || leftover <= ends {
+			return true
+		}
+		next := strings.Index(tgt[idx+1:], src)
+		if next == -1 {
+			break
+		}
+		idx += next + 1
+	}
+	return false
+}
+
+// EvenEscapes reports whether the character at index i is preceded by an
+// even number of escape characters, meaning it is not itself quoted.
+func evenEscapes(s string, i int) bool {
+	n := 0
+	for i--; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 0
+}
+
+// CountEscapes reports the number of escape characters in the string, not
+// counting escaped escape characters.
+func countEscapes(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			n++
+			i++
+		}
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package cpe
+
+import (
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tt := []struct {
+		Source, Target string
+		Disjoint       bool
+		Equal          bool
+		Subset         bool
+		Superset       bool
+	}{
+		{
+			Source: `cpe:/o:redhat:enterprise_linux:8`,
+			Target: `cpe:/o:redhat:enterprise_linux:8`,
+			Equal:  true, Subset: true, Superset: true,
+		},
+		{
+			Source:   `cpe:/o:redhat:enterprise_linux:8`,
+			Target:   `cpe:/o:redhat:enterprise_linux:8::baseos`,
+			Superset: true,
+		},
+		{
+			Source: `cpe:/o:redhat:enterprise_linux:8::baseos`,
+			Target: `cpe:/o:redhat:enterprise_linux:8`,
+			Subset: true,
+		},
+		{
+			Source:   `cpe:/o:redhat:enterprise_linux:8`,
+			Target:   `cpe:/o:redhat:enterprise_linux:7`,
+			Disjoint: true,
+		},
+		{
+			Source:   `cpe:/a:redhat:enterprise_linux:8::appstream`,
+			Target:   `cpe:/o:redhat:enterprise_linux:8::baseos`,
+			Disjoint: true,
+		},
+		{
+			Source:   `cpe:/a:redhat:enterprise_linux:8::appstream`,
+			Target:   `cpe:/a:redhat:enterprise_linux:8::baseos`,
+			Disjoint: true,
+		},
+		{
+			Source:   `cpe:2.3:a:microsoft:internet_explorer:8.*:sp?:*:*:*:*:*:*`,
+			Target:   `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:sp1:*:*:*:*:*:*`,
+			Superset: true,
+		},
+		{
+			Source:   `cpe:2.3:a:microsoft:internet_explorer:8.*:sp?:*:*:*:*:*:*`,
+			Target:   `cpe:2.3:a:microsoft:internet_explorer:9.0:sp1:*:*:*:*:*:*`,
+			Disjoint: true,
+		},
+		{
+			Source:   `cpe:2.3:a:microsoft:internet_explorer:8.*:sp?:*:*:*:*:*:*`,
+			Target:   `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:sp10:*:*:*:*:*:*`,
+			Disjoint: true,
+		},
+		{
+			Source: `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:-:*:*:*:*:*:*`,
+			Target: `cpe:2.3:a:microsoft:internet_explorer:*:*:*:*:*:*:*:*`,
+			Subset: true,
+		},
+		{
+			Source:   `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:-:*:*:*:*:*:*`,
+			Target:   `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:beta:*:*:*:*:*:*`,
+			Disjoint: true,
+		},
+		{
+			// Wildcards in the target make the comparison undefined, which is
+			// none of the relations.
+			Source: `cpe:2.3:a:microsoft:internet_explorer:8.0.6001:*:*:*:*:*:*:*`,
+			Target: `cpe:2.3:a:microsoft:internet_explorer:8.*:*:*:*:*:*:*:*`,
+		},
+	}
+
+	for _, tc := range tt {
+		src, tgt := MustUnbind(tc.Source), MustUnbind(tc.Target)
+		r := Compare(src, tgt)
+		t.Logf("%s ⋚ %s: %v", tc.Source, tc.Target, r)
+		if got, want := r.IsDisjoint(), tc.Disjoint; got != want {
+			t.Errorf("disjoint: got: %v, want: %v", got, want)
+		}
+		if got, want := r.IsEqual(), tc.Equal; got != want {
+			t.Errorf("equal: got: %v, want: %v", got, want)
+		}
+		if got, want := r.IsSubset(), tc.Subset; got != want {
+			t.Errorf("subset: got: %v, want: %v", got, want)
+		}
+		if got, want := r.IsSuperset(), tc.Superset; got != want {
+			t.Errorf("superset: got: %v, want: %v", got, want)
+		}
+	}
+}
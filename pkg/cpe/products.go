@@ -0,0 +1,101 @@
+package cpe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VendorProduct is a vendor and product pair as used in the NVD CPE dictionary.
+type VendorProduct struct {
+	Vendor  string
+	Product string
+}
+
+// Products is a table of component names, as scanners that identify software
+// by name report them, to the dictionary's vendor and product pairs.
+//
+// Names that the dictionary files under more than one vendor map to every
+// pair, because vulnerabilities may be recorded against any of them.
+var products = map[string][]VendorProduct{
+	"bash":     {{"gnu", "bash"}},
+	"bind":     {{"isc", "bind"}},
+	"busybox":  {{"busybox", "busybox"}},
+	"bzip2":    {{"bzip", "bzip2"}},
+	"curl":     {{"haxx", "curl"}},
+	"expat":    {{"libexpat_project", "libexpat"}},
+	"gnupg":    {{"gnupg", "gnupg"}},
+	"gnutls":   {{"gnu", "gnutls"}},
+	"jq":       {{"jqlang", "jq"}, {"stedolan", "jq"}},
+	"libcurl":  {{"haxx", "libcurl"}},
+	"libexpat": {{"libexpat_project", "libexpat"}},
+	"libpng":   {{"libpng", "libpng"}},
+	"libxml2":  {{"xmlsoft", "libxml2"}},
+	"nginx":    {{"f5", "nginx"}, {"nginx", "nginx"}},
+	"openssh":  {{"openbsd", "openssh"}},
+	"openssl":  {{"openssl", "openssl"}},
+	"pcre2":    {{"pcre", "pcre2"}},
+	"sqlite":   {{"sqlite", "sqlite"}},
+	"sudo":     {{"sudo_project", "sudo"}},
+	"xz":       {{"tukaani", "xz"}},
+	"zlib":     {{"zlib", "zlib"}},
+}
+
+// Products reports the dictionary's vendor and product pairs for the named
+// component, or nil if the name isn't known.
+//
+// Names are matched case-insensitively.
+func Products(name string) []VendorProduct {
+	ps := products[strings.ToLower(name)]
+	if len(ps) == 0 {
+		return nil
+	}
+	out := make([]VendorProduct, len(ps))
+	copy(out, ps)
+	return out
+}
+
+// Candidates returns an application CPE for every vendor and product pair the
+// named component maps to, with the version set to "version". Callers can
+// tell which candidate matched by its vendor and product.
+//
+// If the name isn't known, (nil, nil) is returned.
+func Candidates(name, version string) ([]WFN, error) {
+	ps := Products(name)
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	out := make([]WFN, 0, len(ps))
+	for _, p := range ps {
+		var w WFN
+		w.Attr[Part] = Value{Kind: ValueSet, V: "a"}
+		w.Attr[Vendor] = Value{Kind: ValueSet, V: quote(p.Vendor)}
+		w.Attr[Product] = Value{Kind: ValueSet, V: quote(p.Product)}
+		switch version {
+		case "":
+			w.Attr[Version] = Value{Kind: ValueAny}
+		default:
+			w.Attr[Version] = Value{Kind: ValueSet, V: quote(version)}
+		}
+		for i := int(Update); i < NumAttr; i++ {
+			w.Attr[i] = Value{Kind: ValueAny}
+		}
+		if err := w.Valid(); err != nil {
+			return nil, fmt.Errorf("cpe: unable to construct candidate for %q: %w", name, err)
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+// Quote escapes the reserved characters in "s" so it can be used as a WFN
+// attribute value.
+func quote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if reserved(r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
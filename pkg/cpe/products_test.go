@@ -0,0 +1,89 @@
+package cpe
+
+import (
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Version string
+		Want    []string
+	}{
+		{
+			Name:    "openssl",
+			Version: "1.1.1k",
+			Want:    []string{`cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*`},
+		},
+		{
+			Name:    "zlib",
+			Version: "1.2.11",
+			Want:    []string{`cpe:2.3:a:zlib:zlib:1.2.11:*:*:*:*:*:*:*`},
+		},
+		{
+			Name:    "ZLib",
+			Version: "",
+			Want:    []string{`cpe:2.3:a:zlib:zlib:*:*:*:*:*:*:*:*`},
+		},
+		{
+			Name:    "jq",
+			Version: "1.6",
+			Want: []string{
+				`cpe:2.3:a:jqlang:jq:1.6:*:*:*:*:*:*:*`,
+				`cpe:2.3:a:stedolan:jq:1.6:*:*:*:*:*:*:*`,
+			},
+		},
+		{
+			Name:    "expat",
+			Version: "2.2.10",
+			Want:    []string{`cpe:2.3:a:libexpat_project:libexpat:2.2.10:*:*:*:*:*:*:*`},
+		},
+		{
+			Name:    "not-a-real-component",
+			Version: "1.0",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ws, err := Candidates(tc.Name, tc.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(ws), len(tc.Want); got != want {
+				t.Fatalf("got %d candidates, want %d: %v", got, want, ws)
+			}
+			for i, w := range ws {
+				if got, want := w.String(), tc.Want[i]; got != want {
+					t.Errorf("got %q, want %q", got, want)
+				}
+				// The candidate should match the dictionary entry it came from.
+				tgt := MustUnbind(tc.Want[i])
+				if r := Compare(w, tgt); !r.IsEqual() {
+					t.Errorf("%v: not equal to %v: %v", w, tgt, r)
+				}
+			}
+		})
+	}
+}
+
+func TestCandidatesAmbiguous(t *testing.T) {
+	ws, err := Candidates("jq", "1.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A vulnerability recorded against either vendor is found, and the
+	// candidate that hit identifies which.
+	vuln := MustUnbind(`cpe:2.3:a:stedolan:jq:1.6:*:*:*:*:*:*:*`)
+	var hit []VendorProduct
+	for _, w := range ws {
+		if Compare(vuln, w).IsEqual() {
+			hit = append(hit, VendorProduct{w.Attr[Vendor].V, w.Attr[Product].V})
+		}
+	}
+	if got, want := len(hit), 1; got != want {
+		t.Fatalf("got %d hits, want %d", got, want)
+	}
+	if got, want := hit[0], (VendorProduct{"stedolan", "jq"}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
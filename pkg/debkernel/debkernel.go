@@ -0,0 +1,170 @@
+// Package debkernel knows how Debian and Ubuntu package their kernels.
+//
+// Kernel binary packages embed the kernel ABI in their names, like
+// "linux-image-5.15.0-89-generic", and are built from wrapper source packages
+// like "linux-signed" and "linux-meta". Vulnerability data is filed against
+// the kernel source package instead: "linux", or a variant like "linux-aws"
+// or "linux-hwe-5.15". The functions here map between the two.
+package debkernel
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Prefixes of the binary packages that carry a kernel, or depend on one.
+var binaryPrefixes = []string{
+	"linux-image-",
+	"linux-modules-",
+}
+
+// Wrapper source packages, which build packages for a kernel source package
+// without being one.
+var wrappers = []string{
+	"-signed",
+	"-meta",
+}
+
+// Debian's signed kernels come from a "linux-signed-<arch>" source package
+// per architecture, all for the "linux" source package.
+var debianArches = map[string]struct{}{
+	"amd64": {},
+	"arm64": {},
+	"i386":  {},
+}
+
+// Source reports the kernel source package for the binary package "name",
+// whose dpkg "Source" field is "source". The field may include a version in
+// parentheses.
+//
+// The boolean reports whether the binary package is a kernel image, module,
+// or image meta package.
+func Source(name, source string) (string, bool) {
+	kernel := false
+	for _, p := range binaryPrefixes {
+		if strings.HasPrefix(name, p) {
+			kernel = true
+			break
+		}
+	}
+	if !kernel {
+		return "", false
+	}
+	if i := strings.IndexByte(source, ' '); i != -1 {
+		source = source[:i]
+	}
+	// Packages built from the kernel source itself, like the unsigned
+	// images, don't need their Source field.
+	if source == "" {
+		source = "linux"
+	}
+	if source != "linux" && !strings.HasPrefix(source, "linux-") {
+		return "", false
+	}
+	// Out-of-tree modules, like the Nvidia drivers, are packaged as
+	// "linux-modules-*" but have their own source.
+	if strings.HasPrefix(source, "linux-restricted-") {
+		return "", false
+	}
+	for _, w := range wrappers {
+		rest := strings.TrimPrefix(source, "linux")
+		if strings.HasPrefix(rest, w) && (len(rest) == len(w) || rest[len(w)] == '-') {
+			source = "linux" + rest[len(w):]
+		}
+	}
+	if arch := strings.TrimPrefix(source, "linux-"); arch != source {
+		if _, ok := debianArches[arch]; ok {
+			source = "linux"
+		}
+	}
+	return source, true
+}
+
+// Version returns the version of a kernel binary package in a form that
+// compares against its source package's versions.
+//
+// Image and module packages have their source's version already. Ubuntu's
+// meta packages have versions like "5.15.0.89.86", which are rewritten to
+// "5.15.0-89": just the upstream version and ABI. See ABI.
+func Version(v string) string {
+	base := v
+	if i := strings.IndexByte(base, '~'); i != -1 {
+		base = base[:i]
+	}
+	if strings.ContainsAny(base, "-:") {
+		return v
+	}
+	fs := strings.Split(base, ".")
+	if len(fs) != 5 {
+		return v
+	}
+	for _, f := range fs {
+		if f == "" || strings.Trim(f, "0123456789") != "" {
+			return v
+		}
+	}
+	return strings.Join(fs[:3], ".") + "-" + fs[3]
+}
+
+// ABI trims a kernel source version like "5.15.0-91.101~20.04.1" to its
+// upstream version and ABI, "5.15.0-91".
+//
+// Versions with nothing to trim are returned as-is.
+func ABI(v string) string {
+	i := strings.LastIndexByte(v, '-')
+	if i == -1 {
+		return v
+	}
+	if j := strings.IndexAny(v[i:], ".~+"); j != -1 {
+		return v[:i+j]
+	}
+	return v
+}
+
+// Package returns the package to look up vulnerabilities for a kernel binary
+// package with, or nil if "p" isn't a kernel package.
+//
+// The returned package has the ID of "p", and the name and version of its
+// kernel source package. It's a binary package, because that's how the Debian
+// and Ubuntu updaters record vulnerabilities.
+func Package(p *claircore.Package) *claircore.Package {
+	if p == nil {
+		return nil
+	}
+	var src string
+	if p.Source != nil {
+		src = p.Source.Name
+	}
+	name, ok := Source(p.Name, src)
+	if !ok {
+		return nil
+	}
+	return &claircore.Package{
+		ID:      p.ID,
+		Name:    name,
+		Version: Version(p.Version),
+		Kind:    claircore.BINARY,
+		Arch:    p.Arch,
+		Source:  &claircore.Package{},
+	}
+}
+
+// Versions returns the installed and fixed versions to compare for a record
+// and a vulnerability found through the record's kernel source package.
+//
+// If the installed version was rewritten from a meta package's, the fixed
+// version is trimmed to match, so that the ABIs are compared. The boolean is
+// false if the record isn't a kernel package or the vulnerability isn't for
+// its kernel source package.
+func Versions(record *claircore.IndexRecord, vuln *claircore.Vulnerability) (string, string, bool) {
+	k := Package(record.Package)
+	if k == nil || vuln.Package == nil || vuln.Package.Name != k.Name {
+		return "", "", false
+	}
+	fixed := vuln.FixedInVersion
+	if k.Version != record.Package.Version && fixed != "" {
+		fixed = ABI(fixed)
+	}
+	return k.Version, fixed, true
+}
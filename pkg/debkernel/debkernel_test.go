@@ -0,0 +1,141 @@
+package debkernel
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestSource(t *testing.T) {
+	tt := []struct {
+		name, source string
+		want         string
+		ok           bool
+	}{
+		{"linux-image-5.15.0-89-generic", "linux-signed", "linux", true},
+		{"linux-image-unsigned-5.15.0-89-generic", "linux", "linux", true},
+		{"linux-modules-5.15.0-89-generic", "linux", "linux", true},
+		{"linux-modules-extra-5.15.0-89-generic", "linux", "linux", true},
+		{"linux-image-generic", "linux-meta", "linux", true},
+		{"linux-image-5.15.0-89-generic", "linux-signed-hwe-5.15", "linux-hwe-5.15", true},
+		{"linux-image-generic-hwe-20.04", "linux-meta-hwe-5.15", "linux-hwe-5.15", true},
+		{"linux-image-5.15.0-1049-aws", "linux-signed-aws", "linux-aws", true},
+		{"linux-image-5.15.0-1052-azure", "linux-signed-azure (5.15.0-1052.60)", "linux-azure", true},
+		{"linux-image-aws", "linux-meta-aws", "linux-aws", true},
+		{"linux-image-6.1.0-13-amd64", "linux-signed-amd64 (6.1.55+1)", "linux", true},
+		{"linux-image-amd64", "linux-signed-amd64 (6.1.55+1)", "linux", true},
+		{"linux-image-6.1.0-13-amd64-unsigned", "linux", "linux", true},
+		// Already mapped, as the dpkg scanner records it.
+		{"linux-image-5.15.0-89-generic", "linux-hwe-5.15", "linux-hwe-5.15", true},
+
+		{"linux-modules-nvidia-525-5.15.0-89-generic", "linux-restricted-modules", "", false},
+		{"linux-firmware", "", "", false},
+		{"linux-base", "", "", false},
+		{"linux-headers-5.15.0-89-generic", "linux", "", false},
+		{"libc6", "glibc", "", false},
+	}
+	for _, tc := range tt {
+		got, ok := Source(tc.name, tc.source)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s (%s): got (%q, %v), want (%q, %v)", tc.name, tc.source, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tt := []struct {
+		in, want string
+	}{
+		{"5.15.0.89.86", "5.15.0-89"},
+		{"5.15.0.89.99~20.04.47", "5.15.0-89"},
+		{"5.15.0.1049.48", "5.15.0-1049"},
+		{"5.15.0-89.99", "5.15.0-89.99"},
+		{"5.15.0-89.99~20.04.1", "5.15.0-89.99~20.04.1"},
+		{"6.1.55-1", "6.1.55-1"},
+		{"6.1.55+1", "6.1.55+1"},
+	}
+	for _, tc := range tt {
+		if got := Version(tc.in); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestABI(t *testing.T) {
+	tt := []struct {
+		in, want string
+	}{
+		{"5.15.0-91.101", "5.15.0-91"},
+		{"5.15.0-91.101~20.04.1", "5.15.0-91"},
+		{"5.15.0-1051.56", "5.15.0-1051"},
+		{"5.15.0-91", "5.15.0-91"},
+		{"6.1.64-1", "6.1.64-1"},
+		{"0", "0"},
+	}
+	for _, tc := range tt {
+		if got := ABI(tc.in); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	rec := func(name, src, v string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{Package: &claircore.Package{
+			ID:      "1",
+			Name:    name,
+			Version: v,
+			Source:  &claircore.Package{Name: src},
+		}}
+	}
+	vuln := func(name, fixed string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Package:        &claircore.Package{Name: name},
+			FixedInVersion: fixed,
+		}
+	}
+	tt := []struct {
+		name             string
+		record           *claircore.IndexRecord
+		vuln             *claircore.Vulnerability
+		installed, fixed string
+		ok               bool
+	}{
+		{
+			name:      "Image",
+			record:    rec("linux-image-5.15.0-89-generic", "linux", "5.15.0-89.99"),
+			vuln:      vuln("linux", "5.15.0-91.101"),
+			installed: "5.15.0-89.99", fixed: "5.15.0-91.101", ok: true,
+		},
+		{
+			name:      "Meta",
+			record:    rec("linux-image-generic", "linux", "5.15.0.89.86"),
+			vuln:      vuln("linux", "5.15.0-89.99"),
+			installed: "5.15.0-89", fixed: "5.15.0-89", ok: true,
+		},
+		{
+			name:      "MetaUnmapped",
+			record:    rec("linux-image-generic", "linux-meta", "5.15.0.89.86"),
+			vuln:      vuln("linux", "5.15.0-91.101"),
+			installed: "5.15.0-89", fixed: "5.15.0-91", ok: true,
+		},
+		{
+			name:   "OtherVariant",
+			record: rec("linux-image-5.15.0-1049-aws", "linux-aws", "5.15.0-1049.54"),
+			vuln:   vuln("linux", "5.15.0-91.101"),
+		},
+		{
+			name:   "NotKernel",
+			record: rec("libc6", "glibc", "2.35-0ubuntu3.4"),
+			vuln:   vuln("glibc", "2.35-0ubuntu3.5"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			installed, fixed, ok := Versions(tc.record, tc.vuln)
+			if installed != tc.installed || fixed != tc.fixed || ok != tc.ok {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", installed, fixed, ok, tc.installed, tc.fixed, tc.ok)
+			}
+		})
+	}
+}
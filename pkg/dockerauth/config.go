@@ -0,0 +1,185 @@
+package dockerauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Config is the subset of a docker config.json file used to find registry
+// credentials.
+type Config struct {
+	// Auths holds credentials stored in the file itself, keyed by registry.
+	Auths map[string]AuthConfig `json:"auths"`
+	// CredHelpers names the credential helper to use for a registry.
+	CredHelpers map[string]string `json:"credHelpers"`
+	// CredsStore names the credential helper to use for registries not in
+	// CredHelpers.
+	CredsStore string `json:"credsStore"`
+}
+
+// AuthConfig is an entry in a Config's Auths.
+type AuthConfig struct {
+	// Auth is the base64 encoding of "username:password".
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// DefaultConfigPath reports where the docker CLI looks for its
+// configuration: config.json in $DOCKER_CONFIG if set, or in ~/.docker.
+func DefaultConfigPath() (string, error) {
+	if d := os.Getenv("DOCKER_CONFIG"); d != "" {
+		return filepath.Join(d, "config.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("dockerauth: unable to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// LoadConfig reads the config.json file at "path". A missing file is not an
+// error: the returned Config has no credentials, and only anonymous tokens
+// are requested.
+func LoadConfig(path string) (*Config, error) {
+	var cfg Config
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return &cfg, nil
+	default:
+		return nil, fmt.Errorf("dockerauth: unable to read config: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("dockerauth: unable to parse config %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Credentials are what's used to authenticate to a registry's token service,
+// or to the registry itself if it uses basic authentication.
+type credentials struct {
+	Username string
+	Password string
+	// IdentityToken is an OAuth2 refresh token, used instead of the username
+	// and password.
+	IdentityToken string
+}
+
+// The docker CLI stores credentials for Docker Hub under its old index
+// address, rather than the host the registry is served from.
+const (
+	hubServer = "https://index.docker.io/v1/"
+	hubHost   = "index.docker.io"
+)
+
+// ServerName reports the name "host" is stored under in Config and
+// credential helpers.
+func serverName(host string) string {
+	switch host {
+	case hubHost, "registry-1.docker.io", "docker.io":
+		return hubServer
+	}
+	return host
+}
+
+// Hostname reduces a key of a Config's Auths, which may be a URL, to its
+// host.
+func hostname(key string) string {
+	if i := strings.Index(key, "://"); i != -1 {
+		key = key[i+3:]
+	}
+	if i := strings.IndexByte(key, '/'); i != -1 {
+		key = key[:i]
+	}
+	return key
+}
+
+// Credentials finds the credentials for "host". If there are none, nil is
+// returned.
+func (c *Config) credentials(ctx context.Context, host string) (*credentials, error) {
+	name := serverName(host)
+	helper := c.CredHelpers[name]
+	if helper == "" {
+		helper = c.CredHelpers[host]
+	}
+	if helper == "" {
+		helper = c.CredsStore
+	}
+	if helper != "" {
+		return runHelper(ctx, helper, name)
+	}
+
+	want := hostname(name)
+	for k, a := range c.Auths {
+		if hostname(k) != want {
+			continue
+		}
+		cr := credentials{
+			Username:      a.Username,
+			Password:      a.Password,
+			IdentityToken: a.IdentityToken,
+		}
+		if a.Auth != "" {
+			b, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("dockerauth: bad auth for %q: %w", k, err)
+			}
+			i := bytes.IndexByte(b, ':')
+			if i == -1 {
+				return nil, fmt.Errorf("dockerauth: bad auth for %q: missing separator", k)
+			}
+			cr.Username, cr.Password = string(b[:i]), string(b[i+1:])
+		}
+		if cr == (credentials{}) {
+			return nil, nil
+		}
+		return &cr, nil
+	}
+	return nil, nil
+}
+
+// HelperNotFound is the message credential helpers report when they have no
+// credentials for a server.
+const helperNotFound = "credentials not found in native keychain"
+
+// RunHelper asks the credential helper "docker-credential-<name>" for the
+// credentials for "server", using the protocol described at
+// https://github.com/docker/docker-credential-helpers.
+func runHelper(ctx context.Context, name, server string) (*credentials, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+name, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stdout.String(), helperNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("dockerauth: credential helper %q: %w (%s)",
+			name, err, strings.TrimSpace(stderr.String()+stdout.String()))
+	}
+	var out struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("dockerauth: credential helper %q: bad output: %w", name, err)
+	}
+	// Helpers return identity tokens with this special username.
+	if out.Username == "<token>" {
+		return &credentials{IdentityToken: out.Secret}, nil
+	}
+	return &credentials{Username: out.Username, Password: out.Secret}, nil
+}
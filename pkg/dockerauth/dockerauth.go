@@ -0,0 +1,366 @@
+// Package dockerauth provides an indexer.Authorizer that finds registry
+// credentials the way the docker and podman CLIs do, for callers of libindex
+// that don't have their own way of supplying them.
+//
+// Credentials come from a docker config.json file: from a credential helper
+// named in it, or from the file itself. Registries using the token flow
+// described in the distribution specification are asked for a token scoped
+// to pulling the one repository a request is for, and tokens are cached per
+// registry and repository until they expire.
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// DefaultTokenLifetime is how long a token is used when the token service
+// doesn't say. It's the lifetime the distribution specification says to
+// assume.
+const defaultTokenLifetime = 60 * time.Second
+
+// ExpiryMargin is how long before its expiry a token stops being used, so
+// that it doesn't expire in flight.
+const expiryMargin = 10 * time.Second
+
+// Authorizer is an indexer.Authorizer using the credentials in a Config.
+//
+// Authorizer is safe for concurrent use.
+type Authorizer struct {
+	client *http.Client
+	cfg    *Config
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]entry
+	sf    singleflight.Group
+}
+
+// Entry is a cached Authorization header.
+type entry struct {
+	header string
+	// Expires is the zero Time for credentials that don't expire.
+	expires time.Time
+}
+
+var _ indexer.Authorizer = (*Authorizer)(nil)
+
+// New returns an Authorizer using the credentials in "cfg" and requesting
+// tokens with "client". If "client" is nil, http.DefaultClient is used.
+func New(client *http.Client, cfg *Config) *Authorizer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Authorizer{
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+		cache:  make(map[string]entry),
+	}
+}
+
+// NewDefault returns an Authorizer using the configuration file the docker
+// CLI would.
+func NewDefault(client *http.Client) (*Authorizer, error) {
+	p, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(p)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, cfg), nil
+}
+
+// Authorize implements indexer.Authorizer.
+//
+// Only credentials acquired by a previous call to Refresh for the same
+// registry and repository are added.
+func (a *Authorizer) Authorize(ctx context.Context, req *http.Request) error {
+	k := cacheKey(req.URL)
+	a.mu.Lock()
+	e, ok := a.cache[k]
+	a.mu.Unlock()
+	if ok && a.valid(e) {
+		req.Header.Set("Authorization", e.header)
+	}
+	return nil
+}
+
+// Refresh implements indexer.Authorizer.
+//
+// The response's WWW-Authenticate challenge determines how credentials are
+// acquired. For a Bearer challenge, a token for pulling the request's
+// repository is requested from the challenge's realm, authenticating with the
+// configured credentials if there are any. For a Basic challenge, the
+// configured credentials are used directly, and Refresh reports false if
+// there are none.
+func (a *Authorizer) Refresh(ctx context.Context, resp *http.Response) (bool, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/dockerauth/Authorizer.Refresh"))
+	if resp.Request == nil || resp.StatusCode != http.StatusUnauthorized {
+		return false, nil
+	}
+	u := resp.Request.URL
+	k := cacheKey(u)
+	sent := resp.Request.Header.Get("Authorization")
+	v, err, _ := a.sf.Do(k, func() (interface{}, error) {
+		// If another request already replaced the credentials this one was
+		// refused with, use those.
+		a.mu.Lock()
+		e, ok := a.cache[k]
+		a.mu.Unlock()
+		if ok && e.header != sent && a.valid(e) {
+			return true, nil
+		}
+		e, ok, err := a.acquire(ctx, u, resp.Header.Values("WWW-Authenticate"))
+		if err != nil || !ok {
+			return false, err
+		}
+		a.mu.Lock()
+		a.cache[k] = e
+		a.mu.Unlock()
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Valid reports whether the cached entry may still be used.
+func (a *Authorizer) valid(e entry) bool {
+	return e.expires.IsZero() || a.now().Add(expiryMargin).Before(e.expires)
+}
+
+// Acquire answers one of the challenges for a request to "u".
+func (a *Authorizer) acquire(ctx context.Context, u *url.URL, hs []string) (entry, bool, error) {
+	for _, h := range hs {
+		c, ok := parseChallenge(h)
+		if !ok {
+			continue
+		}
+		switch c.scheme {
+		case "bearer":
+			e, err := a.token(ctx, u, c)
+			if err != nil {
+				return entry{}, false, err
+			}
+			return e, true, nil
+		case "basic":
+			cr, err := a.cfg.credentials(ctx, u.Host)
+			if err != nil {
+				return entry{}, false, err
+			}
+			if cr == nil || cr.Username == "" {
+				return entry{}, false, nil
+			}
+			return entry{header: "Basic " + basicAuth(cr.Username, cr.Password)}, true, nil
+		}
+	}
+	zlog.Debug(ctx).
+		Strs("challenges", hs).
+		Msg("no supported challenge")
+	return entry{}, false, nil
+}
+
+// Token requests a token for pulling the repository "u" is in from the token
+// service named in the challenge.
+func (a *Authorizer) token(ctx context.Context, u *url.URL, c challenge) (entry, error) {
+	realm, err := url.Parse(c.params["realm"])
+	if err != nil || realm.Host == "" {
+		return entry{}, fmt.Errorf("dockerauth: bad realm %q", c.params["realm"])
+	}
+	// Only ask for what's needed to fetch the blob, rather than whatever the
+	// registry offered.
+	scope := c.params["scope"]
+	if repo := repository(u); repo != "" {
+		scope = "repository:" + repo + ":pull"
+	}
+	service := c.params["service"]
+	cr, err := a.cfg.credentials(ctx, u.Host)
+	if err != nil {
+		return entry{}, err
+	}
+
+	var req *http.Request
+	if cr != nil && cr.IdentityToken != "" {
+		// Identity tokens are OAuth2 refresh tokens.
+		f := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cr.IdentityToken},
+			"service":       {service},
+			"client_id":     {"claircore"},
+		}
+		if scope != "" {
+			f.Set("scope", scope)
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, realm.String(), strings.NewReader(f.Encode()))
+		if err != nil {
+			return entry{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		q := realm.Query()
+		if service != "" {
+			q.Set("service", service)
+		}
+		if scope != "" {
+			q.Set("scope", scope)
+		}
+		realm.RawQuery = q.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return entry{}, err
+		}
+		if cr != nil && cr.Username != "" {
+			req.SetBasicAuth(cr.Username, cr.Password)
+		}
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return entry{}, fmt.Errorf("dockerauth: token request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 256))
+		return entry{}, fmt.Errorf("dockerauth: token request failed: %s (body starts: %q)", res.Status, b)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return entry{}, fmt.Errorf("dockerauth: bad token response: %w", err)
+	}
+	t := tok.Token
+	if t == "" {
+		t = tok.AccessToken
+	}
+	if t == "" {
+		return entry{}, fmt.Errorf("dockerauth: token response without token")
+	}
+	life := defaultTokenLifetime
+	if tok.ExpiresIn > 0 {
+		life = time.Duration(tok.ExpiresIn) * time.Second
+	}
+	zlog.Debug(ctx).
+		Str("realm", realm.Host).
+		Str("scope", scope).
+		Bool("credentials", cr != nil).
+		Dur("lifetime", life).
+		Msg("acquired token")
+	return entry{
+		header:  "Bearer " + t,
+		expires: a.now().Add(life),
+	}, nil
+}
+
+// Repository reports the repository a registry API URL is for, or "" if "u"
+// isn't a blob or manifest URL.
+func repository(u *url.URL) string {
+	p := u.EscapedPath()
+	if !strings.HasPrefix(p, "/v2/") {
+		return ""
+	}
+	p = p[len("/v2/"):]
+	for _, sep := range []string{"/blobs/", "/manifests/"} {
+		if i := strings.LastIndex(p, sep); i > 0 {
+			return p[:i]
+		}
+	}
+	return ""
+}
+
+// CacheKey is the key credentials for a request to "u" are cached under: the
+// registry and repository, or just the host for other URLs.
+func cacheKey(u *url.URL) string {
+	return u.Host + "/" + repository(u)
+}
+
+func basicAuth(user, pass string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+}
+
+// Challenge is a parsed WWW-Authenticate challenge.
+type challenge struct {
+	// Scheme is lowercased.
+	scheme string
+	// Params are keyed by lowercased name.
+	params map[string]string
+}
+
+// ParseChallenge parses a single challenge of the form:
+//
+//	Bearer realm="https://auth.example.com/token",service="registry.example.com"
+func parseChallenge(h string) (challenge, bool) {
+	h = strings.TrimSpace(h)
+	i := strings.IndexByte(h, ' ')
+	if i == -1 {
+		i = len(h)
+	}
+	c := challenge{
+		scheme: strings.ToLower(h[:i]),
+		params: make(map[string]string),
+	}
+	if c.scheme == "" {
+		return c, false
+	}
+	rest := h[i:]
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		if rest == "" {
+			return c, true
+		}
+		eq := strings.IndexByte(rest, '=')
+		if eq == -1 {
+			return c, false
+		}
+		k := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+		var v string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(rest) && rest[j] != '"'; j++ {
+				if rest[j] == '\\' && j+1 < len(rest) {
+					j++
+				}
+				b.WriteByte(rest[j])
+			}
+			if j == len(rest) {
+				return c, false
+			}
+			v, rest = b.String(), rest[j+1:]
+		} else {
+			j := strings.IndexByte(rest, ',')
+			if j == -1 {
+				j = len(rest)
+			}
+			v, rest = strings.TrimSpace(rest[:j]), rest[j:]
+		}
+		c.params[k] = v
+	}
+}
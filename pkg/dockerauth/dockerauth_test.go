@@ -0,0 +1,436 @@
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+)
+
+// The test binary doubles as a credential helper: when run under the name
+// "docker-credential-fake", it answers for the server named in
+// $FAKE_HELPER_SERVER.
+const (
+	fakeHelper    = "fake"
+	helperEnv     = "FAKE_HELPER_SERVER"
+	helperUser    = "helper-user"
+	helperSecret  = "helper-secret"
+	identityToken = "identity-token"
+)
+
+func TestMain(m *testing.M) {
+	if strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == "docker-credential-"+fakeHelper {
+		os.Exit(credentialHelper())
+	}
+	os.Exit(m.Run())
+}
+
+func credentialHelper() int {
+	if len(os.Args) != 2 || os.Args[1] != "get" {
+		fmt.Fprintln(os.Stderr, "unsupported command")
+		return 2
+	}
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	server := strings.TrimSpace(string(b))
+	if server != os.Getenv(helperEnv) {
+		fmt.Println(helperNotFound)
+		return 1
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]string{
+		"ServerURL": server,
+		"Username":  helperUser,
+		"Secret":    helperSecret,
+	})
+	return 0
+}
+
+// InstallHelper puts the fake credential helper first in $PATH.
+func installHelper(t *testing.T, server string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("helper installation not supported on windows")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "dockerauth.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.Symlink(exe, filepath.Join(dir, "docker-credential-"+fakeHelper)); err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, "PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	setenv(t, helperEnv, server)
+}
+
+func setenv(t *testing.T, k, v string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(k)
+	if err := os.Setenv(k, v); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(k, prev)
+		} else {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+// FakeRegistry implements the token authentication flow: blob requests
+// without a valid token are refused with a challenge naming the token
+// service, which issues tokens for the requested scope.
+type fakeRegistry struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	// User and pass, if set, are required by the token service. Identity,
+	// if set, is accepted as a refresh token.
+	user, pass string
+	identity   string
+
+	mu     sync.Mutex
+	scopes []string
+	issued map[string]string // token -> scope
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	f := &fakeRegistry{t: t, issued: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", f.serveToken)
+	mux.HandleFunc("/v2/", f.serveBlob)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeRegistry) host() string {
+	u, _ := url.Parse(f.srv.URL)
+	return u.Host
+}
+
+func (f *fakeRegistry) serveToken(w http.ResponseWriter, r *http.Request) {
+	var scope string
+	switch r.Method {
+	case http.MethodGet:
+		if f.user != "" {
+			u, p, ok := r.BasicAuth()
+			if !ok || u != f.user || p != f.pass {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+		}
+		scope = r.URL.Query().Get("scope")
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil ||
+			r.PostForm.Get("grant_type") != "refresh_token" ||
+			r.PostForm.Get("refresh_token") != f.identity {
+			http.Error(w, "bad refresh token", http.StatusUnauthorized)
+			return
+		}
+		scope = r.PostForm.Get("scope")
+	}
+	if r.URL.Query().Get("service") != "fake" && r.PostForm.Get("service") != "fake" {
+		http.Error(w, "bad service", http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	tok := fmt.Sprintf("token-%d", len(f.scopes))
+	f.scopes = append(f.scopes, scope)
+	f.issued[tok] = scope
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      tok,
+		"expires_in": 300,
+	})
+}
+
+func (f *fakeRegistry) serveBlob(w http.ResponseWriter, r *http.Request) {
+	repo := repository(r.URL)
+	want := "repository:" + repo + ":pull"
+	tok := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	f.mu.Lock()
+	scope, ok := f.issued[tok]
+	f.mu.Unlock()
+	if !ok || scope != want {
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="%s,push"`, f.srv.URL, want))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte("blob"))
+}
+
+func (f *fakeRegistry) requested() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.scopes...)
+}
+
+// Get requests "u" the way the fetcher does.
+func get(ctx context.Context, t *testing.T, a *Authorizer, u string) int {
+	t.Helper()
+	for try := 0; try < 2; try++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Authorize(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized || try == 1 {
+			return res.StatusCode
+		}
+		ok, err := a.Refresh(ctx, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return res.StatusCode
+		}
+	}
+	panic("unreachable")
+}
+
+func TestAuthorizer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const blob = "/v2/library/app/blobs/sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tt := []struct {
+		name  string
+		setup func(*testing.T, *fakeRegistry) *Config
+	}{
+		{
+			name:  "Anonymous",
+			setup: func(*testing.T, *fakeRegistry) *Config { return &Config{} },
+		},
+		{
+			name: "Config",
+			setup: func(t *testing.T, f *fakeRegistry) *Config {
+				f.user, f.pass = "user", "pass"
+				return &Config{Auths: map[string]AuthConfig{
+					"https://" + f.host(): {Auth: base64.StdEncoding.EncodeToString([]byte("user:pass"))},
+				}}
+			},
+		},
+		{
+			name: "IdentityToken",
+			setup: func(t *testing.T, f *fakeRegistry) *Config {
+				f.user, f.identity = "nobody", identityToken
+				return &Config{Auths: map[string]AuthConfig{
+					f.host(): {IdentityToken: identityToken},
+				}}
+			},
+		},
+		{
+			name: "Helper",
+			setup: func(t *testing.T, f *fakeRegistry) *Config {
+				installHelper(t, f.host())
+				f.user, f.pass = helperUser, helperSecret
+				return &Config{CredHelpers: map[string]string{f.host(): fakeHelper}}
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			f := newFakeRegistry(t)
+			a := New(nil, tc.setup(t, f))
+
+			if got, want := get(ctx, t, a, f.srv.URL+blob), http.StatusOK; got != want {
+				t.Fatalf("got status %d, want %d", got, want)
+			}
+			// Cached.
+			if got, want := get(ctx, t, a, f.srv.URL+blob), http.StatusOK; got != want {
+				t.Fatalf("got status %d, want %d", got, want)
+			}
+			// Another repository needs its own token.
+			other := strings.Replace(blob, "library/app", "library/other", 1)
+			if got, want := get(ctx, t, a, f.srv.URL+other), http.StatusOK; got != want {
+				t.Fatalf("got status %d, want %d", got, want)
+			}
+			want := []string{"repository:library/app:pull", "repository:library/other:pull"}
+			if got := f.requested(); !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		})
+	}
+}
+
+// TestBadCredentials checks that a rejected token request is reported.
+func TestBadCredentials(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := newFakeRegistry(t)
+	f.user, f.pass = "user", "pass"
+	a := New(nil, &Config{Auths: map[string]AuthConfig{
+		f.host(): {Username: "user", Password: "wrong"},
+	}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.srv.URL+"/v2/app/blobs/sha256:00", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if _, err := a.Refresh(ctx, res); err == nil {
+		t.Error("expected error")
+	}
+}
+
+// TestExpiry checks that tokens are requested again once they expire.
+func TestExpiry(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := newFakeRegistry(t)
+	a := New(nil, nil)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	u := f.srv.URL + "/v2/app/blobs/sha256:00"
+
+	get(ctx, t, a, u)
+	now = now.Add(295 * time.Second)
+	get(ctx, t, a, u)
+	if got, want := len(f.requested()), 2; got != want {
+		t.Errorf("requested %d tokens, want %d", got, want)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	cfg := &Config{
+		Auths: map[string]AuthConfig{
+			hubServer:                          {Auth: base64.StdEncoding.EncodeToString([]byte("hub:secret"))},
+			"https://registry.example.com/v1/": {Username: "u", Password: "p"},
+			"empty.example.com":                {},
+		},
+	}
+	tt := []struct {
+		host string
+		want *credentials
+	}{
+		{"registry-1.docker.io", &credentials{Username: "hub", Password: "secret"}},
+		{"registry.example.com", &credentials{Username: "u", Password: "p"}},
+		{"empty.example.com", nil},
+		{"other.example.com", nil},
+	}
+	for _, tc := range tt {
+		got, err := cfg.credentials(ctx, tc.host)
+		if err != nil {
+			t.Errorf("%s: %v", tc.host, err)
+			continue
+		}
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("%s: %v", tc.host, cmp.Diff(got, tc.want))
+		}
+	}
+
+	t.Run("HelperNotFound", func(t *testing.T) {
+		installHelper(t, "registry.example.com")
+		cfg := &Config{CredsStore: fakeHelper}
+		got, err := cfg.credentials(ctx, "other.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != nil {
+			t.Errorf("got %v, want no credentials", got)
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerauth.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "config.json")
+
+	cfg, err := LoadConfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(cfg, &Config{}) {
+		t.Errorf("missing file: got %+v", cfg)
+	}
+
+	const doc = `{"auths":{"quay.io":{"auth":"dTpw"}},"credHelpers":{"gcr.io":"gcloud"},"credsStore":"desktop"}`
+	if err := ioutil.WriteFile(p, []byte(doc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{
+		Auths:       map[string]AuthConfig{"quay.io": {Auth: "dTpw"}},
+		CredHelpers: map[string]string{"gcr.io": "gcloud"},
+		CredsStore:  "desktop",
+	}
+	if !cmp.Equal(cfg, want) {
+		t.Error(cmp.Diff(cfg, want))
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tt := []struct {
+		in   string
+		want challenge
+		ok   bool
+	}{
+		{
+			in: `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`,
+			want: challenge{scheme: "bearer", params: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+				"scope":   "repository:a/b:pull,push",
+			}},
+			ok: true,
+		},
+		{
+			in:   `Basic realm=registry`,
+			want: challenge{scheme: "basic", params: map[string]string{"realm": "registry"}},
+			ok:   true,
+		},
+		{
+			in:   `Bearer realm="unterminated`,
+			want: challenge{scheme: "bearer", params: map[string]string{}},
+		},
+	}
+	for _, tc := range tt {
+		got, ok := parseChallenge(tc.in)
+		if ok != tc.ok {
+			t.Errorf("%q: got ok %v, want %v", tc.in, ok, tc.ok)
+		}
+		if !cmp.Equal(got, tc.want, cmp.AllowUnexported(challenge{})) {
+			t.Errorf("%q: %v", tc.in, cmp.Diff(got, tc.want, cmp.AllowUnexported(challenge{})))
+		}
+	}
+}
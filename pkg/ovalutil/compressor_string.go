@@ -11,11 +11,14 @@ func _() {
 	_ = x[CompressionNone-0]
 	_ = x[CompressionGzip-1]
 	_ = x[CompressionBzip2-2]
+	_ = x[CompressionXz-3]
+	_ = x[CompressionZstd-4]
+	_ = x[CompressionDetect-5]
 }
 
-const _Compressor_name = "nonegzipbzip2"
+const _Compressor_name = "nonegzipbzip2xzzstddetect"
 
-var _Compressor_index = [...]uint8{0, 4, 8, 13}
+var _Compressor_index = [...]uint8{0, 4, 8, 13, 15, 19, 25}
 
 func (i Compressor) String() string {
 	if i >= Compressor(len(_Compressor_index)-1) {
@@ -0,0 +1,160 @@
+package ovalutil
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/quay/goval-parser/oval"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Decode decodes an OVAL document, skipping definitions that fail to decode.
+//
+// Skipped definitions are accounted for with a driver.Records, so an error
+// wrapping driver.ErrMalformed is returned if too many of them are
+// malformed. An error in the structure of the document itself is returned
+// as-is.
+func Decode(ctx context.Context, r io.Reader) (*oval.Root, error) {
+	root := &oval.Root{}
+	// The tables are decoded in place, as they can't be copied.
+	doc := document{
+		Definitions: definitions{ctx: ctx},
+		Tests:       &root.Tests,
+		Objects:     &root.Objects,
+		States:      &root.States,
+		Variables:   &root.Variables,
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	root.XMLName = doc.XMLName
+	root.Generator = doc.Generator
+	root.Definitions.XMLName = doc.Definitions.XMLName
+	root.Definitions.Definitions = doc.Definitions.Definitions
+	return root, nil
+}
+
+// document is an oval.Root that decodes its definitions with definitions.
+type document struct {
+	XMLName     xml.Name        `xml:"oval_definitions"`
+	Generator   oval.Generator  `xml:"generator"`
+	Definitions definitions     `xml:"definitions"`
+	Tests       *oval.Tests     `xml:"tests"`
+	Objects     *oval.Objects   `xml:"objects"`
+	States      *oval.States    `xml:"states"`
+	Variables   *oval.Variables `xml:"variables"`
+}
+
+// definitions decodes a definitions element one definition at a time,
+// skipping and accounting for the malformed ones. Only the tokens of the
+// definition being decoded are held in memory.
+type definitions struct {
+	ctx         context.Context
+	XMLName     xml.Name
+	Definitions []oval.Definition
+}
+
+var _ xml.Unmarshaler = (*definitions)(nil)
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (ds *definitions) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	ds.XMLName = start.Name
+	rec := driver.NewRecords(ds.ctx)
+	var raw rawDefinition
+	for i := 0; ; {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		var se xml.StartElement
+		switch t := t.(type) {
+		case xml.StartElement:
+			se = t
+		case xml.EndElement:
+			return rec.Err()
+		default:
+			continue
+		}
+		if se.Name.Local != "definition" {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		raw.reset()
+		if err := raw.UnmarshalXML(d, se); err != nil {
+			return err
+		}
+		var def oval.Definition
+		if err := xml.NewTokenDecoder(&raw).Decode(&def); err != nil {
+			rec.Malformed(raw.ident(i), err)
+		} else {
+			rec.Ok()
+			ds.Definitions = append(ds.Definitions, def)
+		}
+		i++
+	}
+}
+
+// rawDefinition holds the tokens of a definition element so it can be
+// decoded on its own. It's an xml.TokenReader that replays them.
+type rawDefinition struct {
+	toks []xml.Token
+	pos  int
+}
+
+var (
+	_ xml.Unmarshaler = (*rawDefinition)(nil)
+	_ xml.TokenReader = (*rawDefinition)(nil)
+)
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (r *rawDefinition) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	r.toks = append(r.toks, start.Copy())
+	for depth := 1; depth > 0; {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		r.toks = append(r.toks, xml.CopyToken(t))
+	}
+	return nil
+}
+
+// reset empties the rawDefinition for reuse.
+func (r *rawDefinition) reset() {
+	r.toks = r.toks[:0]
+	r.pos = 0
+}
+
+// Token implements xml.TokenReader.
+func (r *rawDefinition) Token() (xml.Token, error) {
+	if r.pos == len(r.toks) {
+		return nil, io.EOF
+	}
+	t := r.toks[r.pos]
+	r.pos++
+	return t, nil
+}
+
+// ident returns the definition's "id" attribute, or its position in the
+// document if it has none.
+func (r *rawDefinition) ident(i int) string {
+	if len(r.toks) != 0 {
+		for _, a := range r.toks[0].(xml.StartElement).Attr {
+			if a.Name.Local == "id" {
+				return a.Value
+			}
+		}
+	}
+	return fmt.Sprintf("definition #%d", i)
+}
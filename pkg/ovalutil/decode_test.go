@@ -0,0 +1,75 @@
+package ovalutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+func TestDecode(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var bad []string
+	ctx = driver.WithRecordPolicy(ctx, driver.RecordPolicy{
+		Malformed: func(id string, _ error) { bad = append(bad, id) },
+	})
+
+	f, err := os.Open(filepath.Join("testdata", "one-malformed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	root, err := Decode(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bad, []string{"oval:test:def:7"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	defs := root.Definitions.Definitions
+	if got, want := len(defs), 19; got != want {
+		t.Fatalf("got %d definitions, want %d", got, want)
+	}
+	// The definitions on either side of the malformed one are intact.
+	for i, want := range map[int]string{5: "CVE-2021-0006", 6: "CVE-2021-0008"} {
+		if got := defs[i].Title; got != want {
+			t.Errorf("definition %d: got %q, want %q", i, got, want)
+		}
+	}
+	if got, want := defs[6].Advisory.Issued.Date, time.Date(2021, 9, 9, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got issued date %v, want %v", got, want)
+	}
+}
+
+func TestDecodeMostlyMalformed(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, err := os.Open(filepath.Join("testdata", "mostly-malformed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = Decode(ctx, f)
+	if !errors.Is(err, driver.ErrMalformed) {
+		t.Fatalf("got error %v, want %v", err, driver.ErrMalformed)
+	}
+	t.Log(err)
+}
+
+func TestDecodeBrokenDocument(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	_, err := Decode(ctx, strings.NewReader(`<oval_definitions><definitions><definition>`))
+	switch {
+	case err == nil:
+		t.Fatal("expected error")
+	case errors.Is(err, driver.ErrMalformed):
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package ovalutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// DefaultDecompressLimit is the largest document, after decompression, a
+// Fetcher accepts if its DecompressLimit isn't set.
+const DefaultDecompressLimit = 2 << 30 // 2 GiB
+
+// ErrDecompressLimit is returned by Fetch when a document is larger than the
+// Fetcher's limit once decompressed.
+var ErrDecompressLimit = errors.New("ovalutil: decompressed document exceeds size limit")
+
+// Magic numbers for the compression schemes that have them, tried in order.
+var magic = []struct {
+	c Compressor
+	b []byte
+}{
+	{CompressionGzip, []byte{0x1F, 0x8B}},
+	{CompressionBzip2, []byte("BZh")},
+	{CompressionXz, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}},
+	{CompressionZstd, []byte{0x28, 0xB5, 0x2F, 0xFD}},
+}
+
+// DetectCompressor reports the Compressor for a document that starts with the
+// bytes "b" and is named "name", usually the path of the URL it was fetched
+// from.
+//
+// A magic number in "b" takes precedence over the extension of "name". If
+// neither indicates a compression scheme, CompressionNone is returned.
+func DetectCompressor(b []byte, name string) Compressor {
+	for _, m := range magic {
+		if bytes.HasPrefix(b, m.b) {
+			return m.c
+		}
+	}
+	if c, err := ParseCompressor(strings.TrimPrefix(path.Ext(name), ".")); err == nil {
+		return c
+	}
+	return CompressionNone
+}
+
+// Decompress returns a ReadCloser that decompresses "r" according to "c".
+//
+// If "c" is CompressionDetect, the compression is detected from the start of
+// "r" and "name" as with DetectCompressor. The detected Compressor is
+// returned alongside the ReadCloser.
+//
+// Closing the returned ReadCloser does not close "r".
+func Decompress(r io.Reader, c Compressor, name string) (io.ReadCloser, Compressor, error) {
+	if c == CompressionDetect {
+		br := bufio.NewReader(r)
+		// A short read here just means a short document; Peek returns what
+		// there is.
+		b, _ := br.Peek(6)
+		c = DetectCompressor(b, name)
+		r = br
+	}
+	switch c {
+	case CompressionNone:
+		return ioutil.NopCloser(r), c, nil
+	case CompressionGzip:
+		z, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, c, err
+		}
+		return z, c, nil
+	case CompressionBzip2:
+		return ioutil.NopCloser(bzip2.NewReader(r)), c, nil
+	case CompressionXz:
+		z, err := xz.NewReader(r)
+		if err != nil {
+			return nil, c, err
+		}
+		return ioutil.NopCloser(z), c, nil
+	case CompressionZstd:
+		z, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, c, err
+		}
+		return zstdCloser{z}, c, nil
+	}
+	panic(fmt.Sprintf("ovalutil: programmer error: unknown compression scheme: %v", c))
+}
+
+// ZstdCloser adapts a zstd.Decoder, whose Close method doesn't return an
+// error, to an io.ReadCloser.
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+// Close implements io.Closer.
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// CopyLimit copies "src" to "dst", returning ErrDecompressLimit if there are
+// more than "limit" bytes. A negative limit disables the check.
+func copyLimit(dst io.Writer, src io.Reader, limit int64) error {
+	if limit < 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	switch {
+	case err != nil:
+		return err
+	case n > limit:
+		return fmt.Errorf("%w (%d bytes)", ErrDecompressLimit, limit)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ovalutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+// TestFetchCompression checks that Fetch decompresses every supported scheme,
+// whether configured or detected, and enforces the decompression limit.
+func TestFetchCompression(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	dir := filepath.Join("testdata", "compression")
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	want, err := ioutil.ReadFile(filepath.Join(dir, "document.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(t *testing.T, name string, c Compressor, limit int64) ([]byte, error) {
+		u, err := url.Parse(srv.URL + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := Fetcher{
+			Compression:     c,
+			URL:             u,
+			Client:          srv.Client(),
+			DecompressLimit: limit,
+		}
+		rc, _, err := f.Fetch(zlog.Test(ctx, t), "")
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+
+	for _, tc := range []struct {
+		Name string
+		Compressor
+	}{
+		{"document.xml", CompressionNone},
+		{"document.xml.gz", CompressionGzip},
+		{"document.xml.bz2", CompressionBzip2},
+		{"document.xml.xz", CompressionXz},
+		{"document.xml.zst", CompressionZstd},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, c := range []Compressor{tc.Compressor, CompressionDetect} {
+				got, err := fetch(t, tc.Name, c, 0)
+				if err != nil {
+					t.Fatalf("%v: %v", c, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%v: got: %q, want: %q", c, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("Bomb", func(t *testing.T) {
+		for _, name := range []string{"bomb.xz", "bomb.zst"} {
+			// Each bomb decompresses to 4 MiB.
+			_, err := fetch(t, name, CompressionDetect, 1<<20)
+			if !errors.Is(err, ErrDecompressLimit) {
+				t.Errorf("%s: got: %v, want: %v", name, err, ErrDecompressLimit)
+			}
+			if _, err := fetch(t, name, CompressionDetect, 8<<20); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	})
+}
+
+func TestDetectCompressor(t *testing.T) {
+	for _, tc := range []struct {
+		Bytes []byte
+		Name  string
+		Want  Compressor
+	}{
+		{[]byte{0x1F, 0x8B, 0x08}, "", CompressionGzip},
+		{[]byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, "", CompressionXz},
+		{[]byte{0x28, 0xB5, 0x2F, 0xFD}, "", CompressionZstd},
+		// The magic number wins over the extension.
+		{[]byte("BZh9"), "suse.linux.enterprise.15.xml.gz", CompressionBzip2},
+		// Too short to sniff, so the extension is used.
+		{[]byte{0x28}, "/oval/suse.linux.enterprise.15.xml.zst", CompressionZstd},
+		{[]byte("<?xml"), "/oval/suse.linux.enterprise.15.xml", CompressionNone},
+	} {
+		if got := DetectCompressor(tc.Bytes, tc.Name); got != tc.Want {
+			t.Errorf("%q, %q: got: %v, want: %v", tc.Bytes, tc.Name, got, tc.Want)
+		}
+	}
+}
@@ -11,18 +11,25 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
 )
 
 // DpkgDefsToVulns iterates over the definitions in an oval root and assumes DpkgInfo objects and states.
 //
 // Each Criterion encountered with an EVR string will be translated into a claircore.Vulnerability
 func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsFunc) ([]*claircore.Vulnerability, error) {
+	return (&Document{Root: root}).DpkgDefsToVulns(ctx, protoVulns)
+}
+
+// DpkgDefsToVulns is like the package-level DpkgDefsToVulns, but also uses
+// spilled tests, objects, and states.
+func (d *Document) DpkgDefsToVulns(ctx context.Context, protoVulns ProtoVulnsFunc) ([]*claircore.Vulnerability, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "ovalutil/DpkgDefsToVulns"))
 	vulns := make([]*claircore.Vulnerability, 0, 10000)
 	pkgcache := map[string]*claircore.Package{}
 	cris := []*oval.Criterion{}
-	for _, def := range root.Definitions.Definitions {
+	for _, def := range d.Definitions.Definitions {
 		// create our prototype vulnerability
 		protoVulns, err := protoVulns(def)
 		if err != nil {
@@ -30,6 +37,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 				Err(err).
 				Str("def_id", def.ID).
 				Msg("could not create prototype vulnerabilities")
+			driver.Warnf(ctx, "definition %q: skipped: %v", def.ID, err)
 			continue
 		}
 		// recursively collect criterions for this definition
@@ -37,7 +45,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 		walkCriterion(ctx, &def.Criteria, &cris)
 		// unpack criterions into vulnerabilities
 		for _, criterion := range cris {
-			test, err := TestLookup(root, criterion.TestRef, func(kind string) bool {
+			test, err := d.TestLookup(criterion.TestRef, func(kind string) bool {
 				if kind != "dpkginfo_test" {
 					return false
 				}
@@ -49,6 +57,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 				continue
 			default:
 				zlog.Debug(ctx).Str("test_ref", criterion.TestRef).Msg("test ref lookup failure. moving to next criterion")
+				driver.Warnf(ctx, "definition %q: criterion skipped: test %q: %v", def.ID, criterion.TestRef, err)
 				continue
 			}
 
@@ -62,7 +71,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 			// thus we *should* only need to care about a single dpkginfo_object and optionally a state object providing the package's fixed-in version.
 
 			objRef := objRefs[0].ObjectRef
-			object, err := dpkgObjectLookup(root, objRef)
+			object, err := d.dpkgObjectLookup(objRef)
 			switch {
 			case errors.Is(err, nil):
 			case errors.Is(err, errObjectSkip):
@@ -74,6 +83,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 						Err(err).
 						Str("object_ref", objRef).
 						Msg("failed object lookup. moving to next criterion")
+					driver.Warnf(ctx, "definition %q: criterion skipped: object %q: %v", def.ID, objRef, err)
 					continue
 				}
 			}
@@ -81,12 +91,15 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 			var state *oval.DpkgInfoState
 			if len(stateRefs) > 0 {
 				stateRef := stateRefs[0].StateRef
-				state, err = dpkgStateLookup(root, stateRef)
+				state, err = d.dpkgStateLookup(stateRef)
 				if err != nil {
 					zlog.Debug(ctx).
 						Err(err).
 						Str("state_ref", stateRef).
 						Msg("failed state lookup. moving to next criterion")
+					if !errors.Is(err, errStateSkip) {
+						driver.Warnf(ctx, "definition %q: criterion skipped: state %q: %v", def.ID, stateRef, err)
+					}
 					continue
 				}
 				// if EVR tag not present this is not a linux package
@@ -106,12 +119,13 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 				// by the vuln and that package's name is in name.Body.
 				var ns []string
 				if len(name.Ref) > 0 {
-					_, i, err := root.Variables.Lookup(name.Ref)
+					_, i, err := d.Variables.Lookup(name.Ref)
 					if err != nil {
 						zlog.Error(ctx).Err(err).Msg("could not lookup variable id")
+						driver.Warnf(ctx, "definition %q: criterion skipped: variable %q: %v", def.ID, name.Ref, err)
 						continue
 					}
-					consts := root.Variables.ConstantVariables[i]
+					consts := d.Variables.ConstantVariables[i]
 					for _, v := range consts.Values {
 						ns = append(ns, v.Body)
 					}
@@ -146,24 +160,38 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 	return vulns, nil
 }
 
-func dpkgStateLookup(root *oval.Root, ref string) (*oval.DpkgInfoState, error) {
-	kind, i, err := root.States.Lookup(ref)
+func (d *Document) dpkgStateLookup(ref string) (*oval.DpkgInfoState, error) {
+	if d.states != nil {
+		var s oval.DpkgInfoState
+		if err := d.states.get(ref, oval.OvalState, "dpkginfo_state", errStateSkip, &s); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+	kind, i, err := d.States.Lookup(ref)
 	if err != nil {
 		return nil, err
 	}
 	if kind != "dpkginfo_state" {
 		return nil, fmt.Errorf("oval: got kind %q: %w", kind, errStateSkip)
 	}
-	return &root.States.DpkgInfoStates[i], nil
+	return &d.States.DpkgInfoStates[i], nil
 }
 
-func dpkgObjectLookup(root *oval.Root, ref string) (*oval.DpkgInfoObject, error) {
-	kind, i, err := root.Objects.Lookup(ref)
+func (d *Document) dpkgObjectLookup(ref string) (*oval.DpkgInfoObject, error) {
+	if d.objects != nil {
+		var o oval.DpkgInfoObject
+		if err := d.objects.get(ref, oval.OvalObject, "dpkginfo_object", errObjectSkip, &o); err != nil {
+			return nil, err
+		}
+		return &o, nil
+	}
+	kind, i, err := d.Objects.Lookup(ref)
 	if err != nil {
 		return nil, err
 	}
 	if kind != "dpkginfo_object" {
 		return nil, fmt.Errorf("oval: got kind %q: %w", kind, errObjectSkip)
 	}
-	return &root.Objects.DpkgInfoObjects[i], nil
+	return &d.Objects.DpkgInfoObjects[i], nil
 }
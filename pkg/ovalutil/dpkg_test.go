@@ -0,0 +1,61 @@
+package ovalutil
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// TestDpkgDefsToVulnsWarnings checks that problems in a document are
+// reported as warnings and skipped, rather than failing the parse.
+func TestDpkgDefsToVulnsWarnings(t *testing.T) {
+	var warnings []string
+	ctx := zlog.Test(context.Background(), t)
+	ctx = driver.WithWarnings(ctx, func(msg string) {
+		warnings = append(warnings, msg)
+	})
+
+	f, err := os.Open(filepath.Join("testdata", "malformed-dpkg.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var root oval.Root
+	if err := xml.NewDecoder(f).Decode(&root); err != nil {
+		t.Fatal(err)
+	}
+	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
+		if def.Title == "" {
+			return nil, errors.New("definition has no title")
+		}
+		return []*claircore.Vulnerability{{Name: def.Title}}, nil
+	}
+
+	vulns, err := DpkgDefsToVulns(ctx, &root, protoVulns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vulns), 1; got != want {
+		t.Fatalf("got %d vulnerabilities, want %d", got, want)
+	}
+	if v := vulns[0]; v.Name != "CVE-2021-0001" || v.Package.Name != "openssl" || v.FixedInVersion != "1.1.1d-0" {
+		t.Errorf("unexpected vulnerability: %+v", v)
+	}
+	// One each for the missing test, object, state, and variable, and one
+	// for the untitled definition.
+	if got, want := len(warnings), 5; got != want {
+		t.Errorf("got %d warnings, want %d", got, want)
+		for _, w := range warnings {
+			t.Log(w)
+		}
+	}
+}
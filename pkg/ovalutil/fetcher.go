@@ -1,8 +1,6 @@
 package ovalutil
 
 import (
-	"compress/bzip2"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -28,6 +26,11 @@ const (
 	CompressionNone  Compressor = iota // none
 	CompressionGzip                    // gzip
 	CompressionBzip2                   // bzip2
+	CompressionXz                      // xz
+	CompressionZstd                    // zstd
+	// CompressionDetect detects the compression of a fetched document from
+	// its magic number or, failing that, the extension of its URL.
+	CompressionDetect // detect
 )
 
 // ParseCompressor reports the Compressor indicated by the passed in string.
@@ -37,6 +40,12 @@ func ParseCompressor(s string) (c Compressor, err error) {
 		c = CompressionGzip
 	case "bz2", "bzip2":
 		c = CompressionBzip2
+	case "xz":
+		c = CompressionXz
+	case "zst", "zstd":
+		c = CompressionZstd
+	case "detect":
+		c = CompressionDetect
 	case "", "none":
 		c = CompressionNone
 	default:
@@ -53,6 +62,14 @@ type Fetcher struct {
 	Compression Compressor
 	URL         *url.URL
 	Client      *http.Client
+	// DecodeOptions are for users that embed a Fetcher to use when decoding
+	// the fetched document with DecodeDocument. The zero value keeps the
+	// whole document in memory.
+	DecodeOptions DecodeOptions
+	// DecompressLimit is the largest document, in bytes after
+	// decompression, that Fetch accepts. If zero, DefaultDecompressLimit is
+	// used. A negative limit disables the check.
+	DecompressLimit int64
 }
 
 // Configure implements driver.Configurable.
@@ -85,6 +102,24 @@ func (f *Fetcher) Configure(ctx context.Context, cf driver.ConfigUnmarshaler, c
 			Msg("configured database compression")
 	}
 
+	if cfg.DecompressLimit != 0 {
+		f.DecompressLimit = cfg.DecompressLimit
+		zlog.Info(ctx).
+			Int64("limit", cfg.DecompressLimit).
+			Msg("configured decompression limit")
+	}
+
+	if cfg.SpillThreshold != 0 {
+		if cfg.SpillThreshold < 0 {
+			return fmt.Errorf("ovalutil: bad spill threshold: %d", cfg.SpillThreshold)
+		}
+		f.DecodeOptions.SpillThreshold = cfg.SpillThreshold
+		f.DecodeOptions.SpillDir = cfg.SpillDir
+		zlog.Info(ctx).
+			Int("threshold", cfg.SpillThreshold).
+			Msg("configured spilling to disk")
+	}
+
 	f.Client = c
 	zlog.Info(ctx).
 		Msg("configured HTTP client")
@@ -99,13 +134,21 @@ func (f *Fetcher) Configure(ctx context.Context, cf driver.ConfigUnmarshaler, c
 type FetcherConfig struct {
 	URL         string `json:"url" yaml:"url"`
 	Compression string `json:"compression" yaml:"compression"`
+	// SpillThreshold and SpillDir set the Fetcher's DecodeOptions. Setting a
+	// threshold bounds the memory used for a document's tests, objects, and
+	// states, for memory-constrained deployments.
+	SpillThreshold int    `json:"spill_threshold" yaml:"spill_threshold"`
+	SpillDir       string `json:"spill_dir" yaml:"spill_dir"`
+	// DecompressLimit sets the Fetcher's DecompressLimit.
+	DecompressLimit int64 `json:"decompress_limit" yaml:"decompress_limit"`
 }
 
 // Fetch fetches the resource as specified by Fetcher.URL and
 // Fetcher.Compression, using the client provided as Fetcher.Client.
 //
 // Fetch makes GET requests, and will make conditional requests using the
-// passed-in hint.
+// passed-in hint. A document larger than Fetcher.DecompressLimit once
+// decompressed is rejected with ErrDecompressLimit.
 //
 // Tmp.File is used to return a ReadCloser that outlives the passed-in context.
 func (f *Fetcher) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
@@ -147,22 +190,13 @@ func (f *Fetcher) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCl
 	}
 	zlog.Debug(ctx).Msg("request ok")
 
-	var r io.Reader
-	switch f.Compression {
-	case CompressionNone:
-		r = res.Body
-	case CompressionGzip:
-		r, err = gzip.NewReader(res.Body)
-		if err != nil {
-			return nil, hint, err
-		}
-	case CompressionBzip2:
-		r = bzip2.NewReader(res.Body)
-	default:
-		panic(fmt.Sprintf("ovalutil: programmer error: unknown compression scheme: %v", f.Compression))
+	r, c, err := Decompress(res.Body, f.Compression, f.URL.Path)
+	if err != nil {
+		return nil, hint, err
 	}
+	defer r.Close()
 	zlog.Debug(ctx).
-		Str("compression", f.Compression.String()).
+		Str("compression", c.String()).
 		Msg("found compression scheme")
 
 	tf, err := tmp.NewFile("", "fetcher.")
@@ -182,7 +216,11 @@ func (f *Fetcher) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCl
 		}
 	}()
 
-	if _, err := io.Copy(tf, r); err != nil {
+	limit := f.DecompressLimit
+	if limit == 0 {
+		limit = DefaultDecompressLimit
+	}
+	if err := copyLimit(tf, r, limit); err != nil {
 		return nil, hint, err
 	}
 	if o, err := tf.Seek(0, io.SeekStart); err != nil || o != 0 {
@@ -24,3 +24,25 @@ func Links(def oval.Definition) string {
 	s := strings.Join(links, " ")
 	return s
 }
+
+// Aliases returns the identifiers the definition references, such as the
+// advisory ID and the CVEs it addresses, without duplicates.
+func Aliases(def oval.Definition) []string {
+	var out []string
+	seen := make(map[string]struct{})
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if _, ok := seen[id]; ok || id == "" {
+			return
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	for _, ref := range def.References {
+		add(ref.RefID)
+	}
+	for _, cve := range def.Advisory.Cves {
+		add(cve.CveID)
+	}
+	return out
+}
@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
 )
 
 const (
@@ -40,11 +41,17 @@ type ProtoVulnsFunc func(def oval.Definition) ([]*claircore.Vulnerability, error
 //
 // Each Criterion encountered with an EVR string will be translated into a claircore.Vulnerability
 func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsFunc) ([]*claircore.Vulnerability, error) {
+	return (&Document{Root: root}).RPMDefsToVulns(ctx, protoVulns)
+}
+
+// RPMDefsToVulns is like the package-level RPMDefsToVulns, but also uses
+// spilled tests, objects, and states.
+func (d *Document) RPMDefsToVulns(ctx context.Context, protoVulns ProtoVulnsFunc) ([]*claircore.Vulnerability, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "ovalutil/RPMDefsToVulns"))
 	vulns := make([]*claircore.Vulnerability, 0, 10000)
 	cris := []*oval.Criterion{}
-	for _, def := range root.Definitions.Definitions {
+	for _, def := range d.Definitions.Definitions {
 		// create our prototype vulnerability
 		protoVulns, err := protoVulns(def)
 		if err != nil {
@@ -52,6 +59,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 				Err(err).
 				Str("def_id", def.ID).
 				Msg("could not create prototype vulnerabilities")
+			driver.Warnf(ctx, "definition %q: skipped: %v", def.ID, err)
 			continue
 		}
 		// recursively collect criterions for this definition
@@ -66,7 +74,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 		for _, criterion := range cris {
 			// if test object is not rmpinfo_test the provided test is not
 			// associated with a package. this criterion will be skipped.
-			test, err := TestLookup(root, criterion.TestRef, func(kind string) bool {
+			test, err := d.TestLookup(criterion.TestRef, func(kind string) bool {
 				if kind != "rpminfo_test" {
 					return false
 				}
@@ -78,6 +86,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 				continue
 			default:
 				zlog.Debug(ctx).Str("test_ref", criterion.TestRef).Msg("test ref lookup failure. moving to next criterion")
+				driver.Warnf(ctx, "definition %q: criterion skipped: test %q: %v", def.ID, criterion.TestRef, err)
 				continue
 			}
 
@@ -91,7 +100,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 			// thus we *should* only need to care about a single rpminfo_object and optionally a state object providing the package's fixed-in version.
 
 			objRef := objRefs[0].ObjectRef
-			object, err := rpmObjectLookup(root, objRef)
+			object, err := d.rpmObjectLookup(objRef)
 			switch {
 			case errors.Is(err, nil):
 			case errors.Is(err, errObjectSkip):
@@ -102,6 +111,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 					Err(err).
 					Str("object_ref", objRef).
 					Msg("failed object lookup. moving to next criterion")
+				driver.Warnf(ctx, "definition %q: criterion skipped: object %q: %v", def.ID, objRef, err)
 				continue
 			}
 
@@ -111,12 +121,15 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 			var state *oval.RPMInfoState
 			if len(stateRefs) > 0 {
 				stateRef := stateRefs[0].StateRef
-				state, err = rpmStateLookup(root, stateRef)
+				state, err = d.rpmStateLookup(stateRef)
 				if err != nil {
 					zlog.Debug(ctx).
 						Err(err).
 						Str("state_ref", stateRef).
 						Msg("failed state lookup. moving to next criterion")
+					if !errors.Is(err, errStateSkip) {
+						driver.Warnf(ctx, "definition %q: criterion skipped: state %q: %v", def.ID, stateRef, err)
+					}
 					continue
 				}
 				// if we find a state, but this state does not contain an EVR,
@@ -191,26 +204,40 @@ func getEnabledModules(cris []*oval.Criterion) []string {
 	return enabledModules
 }
 
-func rpmObjectLookup(root *oval.Root, ref string) (*oval.RPMInfoObject, error) {
-	kind, index, err := root.Objects.Lookup(ref)
+func (d *Document) rpmObjectLookup(ref string) (*oval.RPMInfoObject, error) {
+	if d.objects != nil {
+		var o oval.RPMInfoObject
+		if err := d.objects.get(ref, oval.OvalObject, "rpminfo_object", errObjectSkip, &o); err != nil {
+			return nil, err
+		}
+		return &o, nil
+	}
+	kind, index, err := d.Objects.Lookup(ref)
 	if err != nil {
 		return nil, err
 	}
 	if kind != "rpminfo_object" {
 		return nil, fmt.Errorf("oval: got kind %q: %w", kind, errObjectSkip)
 	}
-	return &root.Objects.RPMInfoObjects[index], nil
+	return &d.Objects.RPMInfoObjects[index], nil
 }
 
-func rpmStateLookup(root *oval.Root, ref string) (*oval.RPMInfoState, error) {
-	kind, index, err := root.States.Lookup(ref)
+func (d *Document) rpmStateLookup(ref string) (*oval.RPMInfoState, error) {
+	if d.states != nil {
+		var s oval.RPMInfoState
+		if err := d.states.get(ref, oval.OvalState, "rpminfo_state", nil, &s); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+	kind, index, err := d.States.Lookup(ref)
 	if err != nil {
 		return nil, err
 	}
 	if kind != "rpminfo_state" {
 		return nil, fmt.Errorf("bad kind: %s", kind)
 	}
-	return &root.States.RPMInfoStates[index], nil
+	return &d.States.RPMInfoStates[index], nil
 }
 
 // GetDefinitionType parses an OVAL definition and extracts its type from ID.
@@ -0,0 +1,473 @@
+package ovalutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+var (
+	spilledEntries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "ovalutil",
+			Name:      "spilled_entries_total",
+			Help:      "Total number of OVAL tests, objects, and states moved to disk while decoding, by table.",
+		},
+		[]string{"table"},
+	)
+	spilledBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "ovalutil",
+			Name:      "spilled_bytes_total",
+			Help:      "Total number of bytes of OVAL tests, objects, and states moved to disk while decoding, by table.",
+		},
+		[]string{"table"},
+	)
+)
+
+// DecodeOptions configures DecodeDocument.
+type DecodeOptions struct {
+	// SpillThreshold is the number of entries a document's tests, objects,
+	// or states may have before they're moved out of memory into a temporary
+	// file. Zero means they're always kept in memory.
+	//
+	// Spilled entries are decoded every time they're looked up, so this
+	// trades CPU time for memory.
+	SpillThreshold int
+	// SpillDir is the directory the temporary file is created in. If empty,
+	// the default directory for temporary files is used.
+	SpillDir string
+}
+
+// Document is a decoded OVAL document whose tests, objects, and states may
+// be held on disk.
+//
+// If a table was spilled, the corresponding member of the embedded Root is
+// empty, so it must be accessed through the Document's methods. Close must
+// be called to remove the temporary file.
+type Document struct {
+	*oval.Root
+	spill                  *spillFile
+	tests, objects, states *table
+}
+
+// DecodeDocument is like Decode, but moves the tests, objects, or states to a
+// temporary file if there are more than opts.SpillThreshold of them.
+//
+// The temporary file is removed if an error is returned.
+func DecodeDocument(ctx context.Context, r io.Reader, opts DecodeOptions) (*Document, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/ovalutil/DecodeDocument"))
+	if opts.SpillThreshold <= 0 {
+		root, err := Decode(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return &Document{Root: root}, nil
+	}
+	d := &Document{Root: &oval.Root{}}
+	if err := d.decode(ctx, r, opts); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Close removes the Document's temporary file, if any. The spilled tables
+// can't be used afterwards.
+func (d *Document) Close() error {
+	if d.spill == nil {
+		return nil
+	}
+	err := d.spill.f.Close()
+	d.spill = nil
+	return err
+}
+
+// Decode reads the document, one top-level section at a time.
+func (d *Document) decode(ctx context.Context, r io.Reader, opts DecodeOptions) error {
+	rec := &recorder{r: bufio.NewReader(r)}
+	dec := xml.NewDecoder(rec)
+	var start xml.StartElement
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			start = se
+			break
+		}
+	}
+	if start.Name.Local != "oval_definitions" {
+		return fmt.Errorf("expected element type <oval_definitions> but have <%s>", start.Name.Local)
+	}
+	d.XMLName = start.Name
+
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var se xml.StartElement
+		switch t := t.(type) {
+		case xml.StartElement:
+			se = t
+		case xml.EndElement:
+			return d.finish(ctx)
+		default:
+			continue
+		}
+		switch se.Name.Local {
+		case "generator":
+			err = dec.DecodeElement(&d.Generator, &se)
+		case "definitions":
+			defs := definitions{ctx: ctx}
+			if err := dec.DecodeElement(&defs, &se); err != nil {
+				return err
+			}
+			d.Definitions.XMLName = defs.XMLName
+			d.Definitions.Definitions = defs.Definitions
+		case "tests":
+			d.tests, err = d.section(dec, rec, se, &d.Tests, opts)
+		case "objects":
+			d.objects, err = d.section(dec, rec, se, &d.Objects, opts)
+		case "states":
+			d.states, err = d.section(dec, rec, se, &d.States, opts)
+		case "variables":
+			err = dec.DecodeElement(&d.Variables, &se)
+		default:
+			err = dec.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Finish makes the spilled tables ready for lookups.
+func (d *Document) finish(ctx context.Context) error {
+	if d.spill == nil {
+		return nil
+	}
+	if err := d.spill.w.Flush(); err != nil {
+		return err
+	}
+	ev := zlog.Debug(ctx).
+		Str("file", d.spill.f.Name()).
+		Int64("bytes", d.spill.off)
+	for _, t := range []*table{d.tests, d.objects, d.states} {
+		if t == nil {
+			continue
+		}
+		sort.Slice(t.idx, func(i, j int) bool { return t.idx[i].hash < t.idx[j].hash })
+		ev = ev.Int(t.name, len(t.idx))
+	}
+	ev.Msg("spilled tables")
+	return nil
+}
+
+// Section reads a tests, objects, or states element. Its entries are decoded
+// into "dst" unless there are more than the threshold, in which case they're
+// written to the spill file and the returned table is used to look them up.
+func (d *Document) section(dec *xml.Decoder, rec *recorder, start xml.StartElement, dst interface{}, opts DecodeOptions) (*table, error) {
+	if err := rec.start(dec.InputOffset()); err != nil {
+		return nil, err
+	}
+	defer rec.stop()
+	var held []entry
+	var t *table
+	for {
+		off := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+			e := entry{
+				kind: tok.Name.Local,
+				raw:  append([]byte(nil), rec.slice(off, dec.InputOffset())...),
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Local == "id" {
+					e.key = a.Value
+					break
+				}
+			}
+			switch {
+			case t != nil:
+				err = t.add(e)
+			case len(held) == opts.SpillThreshold:
+				if t, err = d.table(start.Name.Local, opts); err != nil {
+					return nil, err
+				}
+				for _, h := range append(held, e) {
+					if err := t.add(h); err != nil {
+						return nil, err
+					}
+				}
+				held = nil
+			default:
+				held = append(held, e)
+			}
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if t != nil {
+				return t, nil
+			}
+			// Below the threshold, so decode the entries as if the
+			// section had been read as a whole.
+			var b []byte
+			b = append(b, '<')
+			b = append(b, start.Name.Local...)
+			b = append(b, '>')
+			for _, h := range held {
+				b = append(b, h.raw...)
+			}
+			b = append(b, "</"...)
+			b = append(b, start.Name.Local...)
+			b = append(b, '>')
+			return nil, xml.Unmarshal(b, dst)
+		}
+		rec.discard(dec.InputOffset())
+	}
+}
+
+// Table returns a new table in the Document's spill file, creating the file
+// if needed.
+func (d *Document) table(name string, opts DecodeOptions) (*table, error) {
+	if d.spill == nil {
+		f, err := tmp.NewFile(opts.SpillDir, "ovalutil.spill.*")
+		if err != nil {
+			return nil, fmt.Errorf("ovalutil: unable to create spill file: %w", err)
+		}
+		d.spill = &spillFile{f: f, w: bufio.NewWriter(f)}
+	}
+	return &table{name: name, file: d.spill}, nil
+}
+
+// Recorder is an io.ByteReader that keeps the bytes read while recording, so
+// that an xml.Decoder's input offsets can be used to recover the text of an
+// element.
+type recorder struct {
+	r    *bufio.Reader
+	rec  bool
+	n    int64
+	last byte
+	// Base is the input offset of buf[0].
+	base int64
+	buf  []byte
+}
+
+var _ io.ByteReader = (*recorder)(nil)
+
+// Read implements io.Reader. The xml package doesn't use it, because recorder
+// is an io.ByteReader.
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.last = p[n-1]
+		if r.rec {
+			r.buf = append(r.buf, p[:n]...)
+		}
+	}
+	return n, err
+}
+
+// ReadByte implements io.ByteReader.
+func (r *recorder) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	r.n++
+	r.last = b
+	if r.rec {
+		r.buf = append(r.buf, b)
+	}
+	return b, nil
+}
+
+// Start begins recording at the input offset "off", which must be at most one
+// byte before the bytes read so far: the xml package looks ahead by at most a
+// byte.
+func (r *recorder) start(off int64) error {
+	r.rec = true
+	r.buf = r.buf[:0]
+	r.base = off
+	switch r.n - off {
+	case 0:
+	case 1:
+		r.buf = append(r.buf, r.last)
+	default:
+		return fmt.Errorf("ovalutil: decoder is %d bytes ahead of input offset", r.n-off)
+	}
+	return nil
+}
+
+func (r *recorder) stop() {
+	r.rec = false
+	r.buf = r.buf[:0]
+}
+
+// Slice returns the recorded bytes between the input offsets. It's only valid
+// until the next read.
+func (r *recorder) slice(from, to int64) []byte {
+	return r.buf[from-r.base : to-r.base]
+}
+
+// Discard drops the recorded bytes before the input offset "to".
+func (r *recorder) discard(to int64) {
+	n := copy(r.buf, r.buf[to-r.base:])
+	r.buf = r.buf[:n]
+	r.base = to
+}
+
+// Entry is an element of a tests, objects, or states section.
+type entry struct {
+	key  string
+	kind string
+	raw  []byte
+}
+
+// SpillFile is the temporary file a Document's tables are written to.
+type spillFile struct {
+	f   *tmp.File
+	w   *bufio.Writer
+	off int64
+}
+
+// Table is a spilled tests, objects, or states section. The entries are in
+// the spill file, and the index of their locations is sorted by the hash of
+// their key, so only the index is held in memory.
+type table struct {
+	name string
+	file *spillFile
+	idx  []location
+}
+
+type location struct {
+	hash uint64
+	off  int64
+	n    int
+}
+
+func hashKey(k string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, k)
+	return h.Sum64()
+}
+
+// Add appends an entry to the spill file. Entries are laid out as the
+// uvarint-prefixed key, kind, and element text.
+func (t *table) add(e entry) error {
+	var hdr [3 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(hdr[:], uint64(len(e.key)))
+	n += binary.PutUvarint(hdr[n:], uint64(len(e.kind)))
+	n += binary.PutUvarint(hdr[n:], uint64(len(e.raw)))
+	w := t.file.w
+	w.Write(hdr[:n])
+	w.WriteString(e.key)
+	w.WriteString(e.kind)
+	if _, err := w.Write(e.raw); err != nil {
+		return fmt.Errorf("ovalutil: unable to write spill file: %w", err)
+	}
+	sz := n + len(e.key) + len(e.kind) + len(e.raw)
+	t.idx = append(t.idx, location{hash: hashKey(e.key), off: t.file.off, n: sz})
+	t.file.off += int64(sz)
+	spilledEntries.WithLabelValues(t.name).Inc()
+	spilledBytes.WithLabelValues(t.name).Add(float64(sz))
+	return nil
+}
+
+// Lookup finds the entry for "ref", reporting errors for missing entries the
+// same way the oval package's Lookup methods do.
+func (t *table) lookup(ref string, typ oval.IDType) (string, []byte, error) {
+	h := hashKey(ref)
+	i := sort.Search(len(t.idx), func(i int) bool { return t.idx[i].hash >= h })
+	for ; i < len(t.idx) && t.idx[i].hash == h; i++ {
+		loc := t.idx[i]
+		b := make([]byte, loc.n)
+		if _, err := t.file.f.ReadAt(b, loc.off); err != nil {
+			return "", nil, fmt.Errorf("ovalutil: unable to read spill file: %w", err)
+		}
+		e, err := decodeEntry(b)
+		if err != nil {
+			return "", nil, err
+		}
+		if e.key == ref {
+			return e.kind, e.raw, nil
+		}
+	}
+
+	// We didn't find it, maybe we can say why.
+	id, err := oval.ParseID(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	if id.Type != typ {
+		return "", nil, fmt.Errorf("oval: wrong identifier type %q", id.Type)
+	}
+	return "", nil, oval.ErrNotFound(ref)
+}
+
+// Get looks up "ref" and decodes it into "v" if it's of the kind "want". If
+// it's not, the returned error wraps "skip", if provided.
+func (t *table) get(ref string, typ oval.IDType, want string, skip error, v interface{}) error {
+	kind, raw, err := t.lookup(ref, typ)
+	if err != nil {
+		return err
+	}
+	if kind != want {
+		if skip == nil {
+			return fmt.Errorf("bad kind: %s", kind)
+		}
+		return fmt.Errorf("oval: got kind %q: %w", kind, skip)
+	}
+	return xml.Unmarshal(raw, v)
+}
+
+var errBadEntry = errors.New("ovalutil: malformed spill file entry")
+
+func decodeEntry(b []byte) (entry, error) {
+	var ls [3]uint64
+	for i := range ls {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return entry{}, errBadEntry
+		}
+		ls[i], b = v, b[n:]
+	}
+	if uint64(len(b)) != ls[0]+ls[1]+ls[2] {
+		return entry{}, errBadEntry
+	}
+	return entry{
+		key:  string(b[:ls[0]]),
+		kind: string(b[ls[0] : ls[0]+ls[1]]),
+		raw:  b[ls[0]+ls[1]:],
+	}, nil
+}
@@ -0,0 +1,120 @@
+package ovalutil
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// TestDecodeDocumentSpill checks that a spilled document produces the same
+// vulnerabilities and warnings as one held in memory, and that the spill file
+// is removed on Close.
+func TestDecodeDocumentSpill(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
+		if def.Title == "" {
+			return nil, errors.New("definition has no title")
+		}
+		return []*claircore.Vulnerability{{Name: def.Title}}, nil
+	}
+	run := func(t *testing.T, opts DecodeOptions) ([]*claircore.Vulnerability, []string, *Document) {
+		var warnings []string
+		ctx := driver.WithWarnings(zlog.Test(ctx, t), func(msg string) {
+			warnings = append(warnings, msg)
+		})
+		f, err := os.Open(filepath.Join("testdata", "malformed-dpkg.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		doc, err := DecodeDocument(ctx, f, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs, err := doc.DpkgDefsToVulns(ctx, protoVulns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vs, warnings, doc
+	}
+
+	wantVulns, wantWarnings, doc := run(t, DecodeOptions{})
+	if doc.spill != nil {
+		t.Error("spilled without a threshold")
+	}
+	// The document has 5 tests, 3 objects, and 1 state.
+	for n, want := range map[int][3]bool{
+		1: {true, true, false},
+		3: {true, false, false},
+		5: {false, false, false},
+	} {
+		dir, err := ioutil.TempDir("", "ovalutil.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		vs, ws, doc := run(t, DecodeOptions{SpillThreshold: n, SpillDir: dir})
+		got := [3]bool{doc.tests != nil, doc.objects != nil, doc.states != nil}
+		if got != want {
+			t.Errorf("threshold %d: got spilled tables %v, want %v", n, got, want)
+		}
+		if held := len(doc.Tests.DpkgInfoTests) != 0; held == want[0] {
+			t.Errorf("threshold %d: tests in Root: %v", n, held)
+		}
+		if !cmp.Equal(vs, wantVulns) {
+			t.Errorf("threshold %d: %s", n, cmp.Diff(vs, wantVulns))
+		}
+		if !cmp.Equal(ws, wantWarnings) {
+			t.Errorf("threshold %d: %s", n, cmp.Diff(ws, wantWarnings))
+		}
+		if err := doc.Close(); err != nil {
+			t.Error(err)
+		}
+		if fs, _ := ioutil.ReadDir(dir); len(fs) != 0 {
+			t.Errorf("threshold %d: spill file left behind: %v", n, fs[0].Name())
+		}
+	}
+}
+
+// TestDecodeDocumentSpillError checks that the spill file is removed when the
+// document can't be decoded.
+func TestDecodeDocumentSpillError(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "malformed-dpkg.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut the document off after the tests were spilled.
+	i := strings.Index(string(b), "<objects>")
+	for name, doc := range map[string]string{
+		"Truncated": string(b[:i+len("<objects>")]),
+		"Malformed": string(b[:i]) + "<objects><dpkginfo_object id=\"x\"></objects>",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "ovalutil.")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			_, err = DecodeDocument(ctx, strings.NewReader(doc), DecodeOptions{SpillThreshold: 1, SpillDir: dir})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			t.Log(err)
+			if fs, _ := ioutil.ReadDir(dir); len(fs) != 0 {
+				t.Errorf("spill file left behind: %v", fs[0].Name())
+			}
+		})
+	}
+}
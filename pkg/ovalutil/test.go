@@ -1,6 +1,7 @@
 package ovalutil
 
 import (
+	"encoding/xml"
 	"fmt"
 
 	"github.com/quay/goval-parser/oval"
@@ -36,3 +37,41 @@ func TestLookup(root *oval.Root, ref string, f func(kind string) bool) (oval.Tes
 	}
 	return nil, fmt.Errorf("unknown kind: %q", kind)
 }
+
+// TestLookup is like the package-level TestLookup, but also finds spilled
+// tests.
+func (d *Document) TestLookup(ref string, f func(kind string) bool) (oval.Test, error) {
+	if d.tests == nil {
+		return TestLookup(d.Root, ref, f)
+	}
+	kind, raw, err := d.tests.lookup(ref, oval.OvalTest)
+	if err != nil {
+		return nil, err
+	}
+	if f != nil && !f(kind) {
+		return nil, fmt.Errorf("disallowed kind %q: %w", kind, errTestSkip)
+	}
+	var t oval.Test
+	switch kind {
+	case "dpkginfo_test":
+		t = new(oval.DpkgInfoTest)
+	case "line_test":
+		t = new(oval.LineTest)
+	case "rpminfo_test":
+		t = new(oval.RPMInfoTest)
+	case "rpmverifyfile_test":
+		t = new(oval.RPMVerifyFileTest)
+	case "textfilecontent54_test":
+		t = new(oval.TextfileContent54Test)
+	case "uname_test":
+		t = new(oval.UnameTest)
+	case "version55_test":
+		t = new(oval.Version55Test)
+	default:
+		return nil, fmt.Errorf("unknown kind: %q", kind)
+	}
+	if err := xml.Unmarshal(raw, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
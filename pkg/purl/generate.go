@@ -0,0 +1,199 @@
+package purl
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// These are the Generators for the ecosystems claircore knows about.
+var (
+	_ Generator = RPM
+	_ Generator = Deb
+	_ Generator = APK
+	_ Generator = PyPI
+	_ Generator = Maven
+	_ Generator = Conda
+)
+
+// These are the default repositories for ecosystems that have one. Package URLs
+// only carry a repository_url qualifier when a package came from somewhere
+// else.
+const (
+	defaultPyPI  = `https://pypi.org/simple`
+	defaultMaven = `https://repo1.maven.apache.org/maven2`
+)
+
+// RpmVendor maps os-release IDs to the vendor names used as rpm namespaces,
+// where they differ.
+var rpmVendor = map[string]string{
+	"rhel": "redhat",
+	"ol":   "oracle",
+	"sles": "suse",
+}
+
+// RPM generates package URLs for rpm packages:
+//
+//	pkg:rpm/redhat/openssl@1.1.1g-15.el8_3?arch=x86_64&distro=rhel-8&epoch=1
+//
+// The epoch is removed from the version and reported as a qualifier.
+func RPM(p *claircore.Package, d *claircore.Distribution, _ []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:    "rpm",
+		Name:    p.Name,
+		Version: p.Version,
+		Qualifiers: map[string]string{
+			"arch": p.Arch,
+		},
+	}
+	if i := strings.IndexByte(u.Version, ':'); i != -1 {
+		if e := u.Version[:i]; e != "0" {
+			u.Qualifiers["epoch"] = e
+		}
+		u.Version = u.Version[i+1:]
+	}
+	if d != nil && d.DID != "" {
+		u.Namespace = d.DID
+		if v, ok := rpmVendor[d.DID]; ok {
+			u.Namespace = v
+		}
+		if d.VersionID != "" {
+			u.Qualifiers["distro"] = d.DID + "-" + d.VersionID
+		}
+	}
+	return u.String()
+}
+
+// Deb generates package URLs for dpkg packages:
+//
+//	pkg:deb/debian/curl@7.64.0-4+deb10u1?arch=amd64&distro=buster
+//
+// The distro qualifier is the release's code name, if known.
+func Deb(p *claircore.Package, d *claircore.Distribution, _ []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:    "deb",
+		Name:    p.Name,
+		Version: p.Version,
+		Qualifiers: map[string]string{
+			"arch": p.Arch,
+		},
+	}
+	if d != nil {
+		u.Namespace = d.DID
+		u.Qualifiers["distro"] = d.VersionCodeName
+		if d.VersionCodeName == "" {
+			u.Qualifiers["distro"] = d.VersionID
+		}
+	}
+	return u.String()
+}
+
+// APK generates package URLs for apk packages:
+//
+//	pkg:apk/alpine/musl@1.1.24-r2?arch=x86_64&distro=3.12
+func APK(p *claircore.Package, d *claircore.Distribution, _ []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:      "apk",
+		Namespace: "alpine",
+		Name:      p.Name,
+		Version:   p.Version,
+		Qualifiers: map[string]string{
+			"arch": p.Arch,
+		},
+	}
+	if d != nil {
+		if d.DID != "" {
+			u.Namespace = d.DID
+		}
+		u.Qualifiers["distro"] = d.VersionID
+	}
+	return u.String()
+}
+
+// PyPI generates package URLs for python packages:
+//
+//	pkg:pypi/django-allauth@0.44.0
+//
+// Names are normalized as the spec requires.
+func PyPI(p *claircore.Package, _ *claircore.Distribution, rs []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:    "pypi",
+		Name:    strings.ReplaceAll(strings.ToLower(p.Name), "_", "-"),
+		Version: p.Version,
+		Qualifiers: map[string]string{
+			"repository_url": repositoryURL(rs, defaultPyPI),
+		},
+	}
+	return u.String()
+}
+
+// Maven generates package URLs for java packages, whose names are expected to
+// be "group:artifact":
+//
+//	pkg:maven/org.apache.xmlgraphics/batik-anim@1.9.1
+func Maven(p *claircore.Package, _ *claircore.Distribution, rs []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:    "maven",
+		Name:    p.Name,
+		Version: p.Version,
+		Qualifiers: map[string]string{
+			"repository_url": repositoryURL(rs, defaultMaven),
+		},
+	}
+	if i := strings.LastIndexByte(p.Name, ':'); i != -1 {
+		u.Namespace, u.Name = p.Name[:i], p.Name[i+1:]
+	}
+	return u.String()
+}
+
+// Conda generates package URLs for packages installed by Conda, whose versions
+// are expected to be "version-build":
+//
+//	pkg:conda/zlib@1.2.11?build=h7b6447c_3&channel=https://repo.anaconda.com/pkgs/main&subdir=linux-64
+//
+// Conda doesn't allow dashes in versions or build strings, so the build is
+// split off at the last one.
+func Conda(p *claircore.Package, _ *claircore.Distribution, rs []*claircore.Repository) string {
+	if p.Name == "" {
+		return ""
+	}
+	u := PackageURL{
+		Type:    "conda",
+		Name:    p.Name,
+		Version: p.Version,
+		Qualifiers: map[string]string{
+			"channel": repositoryURL(rs, ""),
+			"subdir":  p.Arch,
+		},
+	}
+	if i := strings.LastIndexByte(u.Version, '-'); i != -1 {
+		u.Version, u.Qualifiers["build"] = u.Version[:i], u.Version[i+1:]
+	}
+	return u.String()
+}
+
+// RepositoryURL returns the URI of the first repository that isn't the
+// default one, or an empty string.
+func repositoryURL(rs []*claircore.Repository, def string) string {
+	for _, r := range rs {
+		if r.URI != "" && r.URI != def {
+			return r.URI
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+// Package purl generates package URLs for claircore Packages.
+//
+// The format is described in the purl spec:
+// https://github.com/package-url/purl-spec
+//
+// Package URLs depend on the distribution and repository a package was found
+// in, so they're generated by coalescers rather than individual scanners.
+package purl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Generator returns the package URL for a package, given the distribution
+// and repositories it was found with. Either may be nil or empty.
+//
+// An empty string is returned if no package URL can be generated.
+type Generator func(*claircore.Package, *claircore.Distribution, []*claircore.Repository) string
+
+// PackageURL is the components of a package URL.
+type PackageURL struct {
+	Type       string
+	Namespace  string
+	Name       string
+	Version    string
+	Qualifiers map[string]string
+	Subpath    string
+}
+
+// String returns the canonical form of the package URL.
+func (u *PackageURL) String() string {
+	var b strings.Builder
+	b.WriteString("pkg:")
+	b.WriteString(strings.ToLower(u.Type))
+	b.WriteByte('/')
+	if u.Namespace != "" {
+		for _, s := range strings.Split(u.Namespace, "/") {
+			if s == "" {
+				continue
+			}
+			b.WriteString(escape(s, false))
+			b.WriteByte('/')
+		}
+	}
+	b.WriteString(escape(u.Name, false))
+	if u.Version != "" {
+		b.WriteByte('@')
+		b.WriteString(escape(u.Version, false))
+	}
+	ks := make([]string, 0, len(u.Qualifiers))
+	for k, v := range u.Qualifiers {
+		if v == "" {
+			continue
+		}
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for i, k := range ks {
+		if i == 0 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(strings.ToLower(k))
+		b.WriteByte('=')
+		b.WriteString(escape(u.Qualifiers[k], true))
+	}
+	if u.Subpath != "" {
+		b.WriteByte('#')
+		first := true
+		for _, s := range strings.Split(u.Subpath, "/") {
+			if s == "" || s == "." || s == ".." {
+				continue
+			}
+			if !first {
+				b.WriteByte('/')
+			}
+			first = false
+			b.WriteString(escape(s, false))
+		}
+	}
+	return b.String()
+}
+
+const hex = "0123456789ABCDEF"
+
+// Escape percent-encodes the string for use in a package URL. The separator
+// characters are always encoded. Slashes are left alone in qualifier values.
+func escape(s string, qualifier bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '.' || c == '_' || c == '~' || c == ':':
+		case c == '/' && qualifier:
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xF])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,236 @@
+package purl
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+// These are the canonical examples from the purl-spec test suite.
+func TestString(t *testing.T) {
+	tt := []struct {
+		In   PackageURL
+		Want string
+	}{
+		{
+			In:   PackageURL{Type: "maven", Namespace: "org.apache.commons", Name: "io"},
+			Want: "pkg:maven/org.apache.commons/io",
+		},
+		{
+			In:   PackageURL{Type: "maven", Namespace: "org.apache.xmlgraphics", Name: "batik-anim", Version: "1.9.1"},
+			Want: "pkg:maven/org.apache.xmlgraphics/batik-anim@1.9.1",
+		},
+		{
+			In: PackageURL{
+				Type: "maven", Namespace: "org.apache.xmlgraphics", Name: "batik-anim", Version: "1.9.1",
+				Qualifiers: map[string]string{"repository_url": "repo.spring.io/release", "classifier": "sources"},
+			},
+			Want: "pkg:maven/org.apache.xmlgraphics/batik-anim@1.9.1?classifier=sources&repository_url=repo.spring.io/release",
+		},
+		{
+			In:   PackageURL{Type: "pypi", Name: "django", Version: "1.11.1"},
+			Want: "pkg:pypi/django@1.11.1",
+		},
+		{
+			In: PackageURL{
+				Type: "rpm", Namespace: "fedora", Name: "curl", Version: "7.50.3-1.fc25",
+				Qualifiers: map[string]string{"arch": "i386", "distro": "fedora-25"},
+			},
+			Want: "pkg:rpm/fedora/curl@7.50.3-1.fc25?arch=i386&distro=fedora-25",
+		},
+		{
+			In: PackageURL{
+				Type: "rpm", Name: "centerim", Version: "4.22.10-1.el6",
+				Qualifiers: map[string]string{"arch": "i686", "epoch": "1", "distro": "fedora-25"},
+			},
+			Want: "pkg:rpm/centerim@4.22.10-1.el6?arch=i686&distro=fedora-25&epoch=1",
+		},
+		{
+			In: PackageURL{
+				Type: "deb", Namespace: "debian", Name: "curl", Version: "7.50.3-1",
+				Qualifiers: map[string]string{"arch": "i386", "distro": "jessie"},
+			},
+			Want: "pkg:deb/debian/curl@7.50.3-1?arch=i386&distro=jessie",
+		},
+		{
+			In: PackageURL{
+				Type: "deb", Namespace: "debian", Name: "dpkg", Version: "1.19.0.4",
+				Qualifiers: map[string]string{"arch": "amd64", "distro": "stretch"},
+			},
+			Want: "pkg:deb/debian/dpkg@1.19.0.4?arch=amd64&distro=stretch",
+		},
+		{
+			In:   PackageURL{Type: "npm", Namespace: "@angular", Name: "animation", Version: "12.3.1"},
+			Want: "pkg:npm/%40angular/animation@12.3.1",
+		},
+		{
+			In:   PackageURL{Type: "golang", Namespace: "google.golang.org", Name: "genproto", Subpath: "googleapis/api/annotations"},
+			Want: "pkg:golang/google.golang.org/genproto#googleapis/api/annotations",
+		},
+		{
+			In:   PackageURL{Type: "gem", Name: "jruby-launcher", Version: "1.1.2", Qualifiers: map[string]string{"Platform": "java"}},
+			Want: "pkg:gem/jruby-launcher@1.1.2?platform=java",
+		},
+		{
+			In:   PackageURL{Type: "generic", Name: "bitwarderl", Qualifiers: map[string]string{"vcs_url": "git+https://git.fsfe.org/dxtr/bitwarderl@cc55108da32"}},
+			Want: "pkg:generic/bitwarderl?vcs_url=git%2Bhttps://git.fsfe.org/dxtr/bitwarderl%40cc55108da32",
+		},
+		{
+			In:   PackageURL{Type: "MAVEN", Namespace: "org.apache.commons", Name: "io", Qualifiers: map[string]string{"empty": ""}},
+			Want: "pkg:maven/org.apache.commons/io",
+		},
+	}
+	for _, tc := range tt {
+		if got := tc.In.String(); got != tc.Want {
+			t.Errorf("got: %q, want: %q", got, tc.Want)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	debian := &claircore.Distribution{DID: "debian", VersionID: "10", VersionCodeName: "buster"}
+	ubuntu := &claircore.Distribution{DID: "ubuntu", VersionID: "20.04"}
+	rhel := &claircore.Distribution{DID: "rhel", VersionID: "8"}
+	fedora := &claircore.Distribution{DID: "fedora", VersionID: "25"}
+	alpine := &claircore.Distribution{DID: "alpine", VersionID: "3.12"}
+	pypi := []*claircore.Repository{{Name: "pypi", URI: "https://pypi.org/simple"}}
+	maven := []*claircore.Repository{{Name: "maven", URI: "https://repo1.maven.apache.org/maven2"}}
+
+	tt := []struct {
+		Name string
+		Gen  Generator
+		Pkg  *claircore.Package
+		Dist *claircore.Distribution
+		Repo []*claircore.Repository
+		Want string
+	}{
+		{
+			Name: "Deb",
+			Gen:  Deb,
+			Pkg:  &claircore.Package{Name: "curl", Version: "7.64.0-4+deb10u1", Arch: "amd64"},
+			Dist: debian,
+			Want: "pkg:deb/debian/curl@7.64.0-4%2Bdeb10u1?arch=amd64&distro=buster",
+		},
+		{
+			Name: "DebEpoch",
+			Gen:  Deb,
+			Pkg:  &claircore.Package{Name: "libc6", Version: "1:2.31-0ubuntu9", Arch: "amd64"},
+			Dist: ubuntu,
+			Want: "pkg:deb/ubuntu/libc6@1:2.31-0ubuntu9?arch=amd64&distro=20.04",
+		},
+		{
+			Name: "DebNoDist",
+			Gen:  Deb,
+			Pkg:  &claircore.Package{Name: "curl", Version: "7.50.3-1", Arch: "i386"},
+			Want: "pkg:deb/curl@7.50.3-1?arch=i386",
+		},
+		{
+			Name: "RPM",
+			Gen:  RPM,
+			Pkg:  &claircore.Package{Name: "curl", Version: "7.50.3-1.fc25", Arch: "i386"},
+			Dist: fedora,
+			Want: "pkg:rpm/fedora/curl@7.50.3-1.fc25?arch=i386&distro=fedora-25",
+		},
+		{
+			Name: "RPMEpoch",
+			Gen:  RPM,
+			Pkg:  &claircore.Package{Name: "openssl", Version: "1:1.1.1g-15.el8_3", Arch: "x86_64"},
+			Dist: rhel,
+			Want: "pkg:rpm/redhat/openssl@1.1.1g-15.el8_3?arch=x86_64&distro=rhel-8&epoch=1",
+		},
+		{
+			Name: "RPMZeroEpoch",
+			Gen:  RPM,
+			Pkg:  &claircore.Package{Name: "bash", Version: "0:4.4.19-12.el8", Arch: "x86_64"},
+			Dist: rhel,
+			Want: "pkg:rpm/redhat/bash@4.4.19-12.el8?arch=x86_64&distro=rhel-8",
+		},
+		{
+			Name: "RPMNoDist",
+			Gen:  RPM,
+			Pkg:  &claircore.Package{Name: "centerim", Version: "1:4.22.10-1.el6", Arch: "i686"},
+			Want: "pkg:rpm/centerim@4.22.10-1.el6?arch=i686&epoch=1",
+		},
+		{
+			Name: "APK",
+			Gen:  APK,
+			Pkg:  &claircore.Package{Name: "musl", Version: "1.1.24-r2", Arch: "x86_64"},
+			Dist: alpine,
+			Want: "pkg:apk/alpine/musl@1.1.24-r2?arch=x86_64&distro=3.12",
+		},
+		{
+			Name: "APKNoDist",
+			Gen:  APK,
+			Pkg:  &claircore.Package{Name: "curl", Version: "7.83.0-r0", Arch: "x86"},
+			Want: "pkg:apk/alpine/curl@7.83.0-r0?arch=x86",
+		},
+		{
+			Name: "PyPI",
+			Gen:  PyPI,
+			Pkg:  &claircore.Package{Name: "django", Version: "1.11.1"},
+			Repo: pypi,
+			Want: "pkg:pypi/django@1.11.1",
+		},
+		{
+			Name: "PyPINormalize",
+			Gen:  PyPI,
+			Pkg:  &claircore.Package{Name: "Django_Allauth", Version: "0.44.0"},
+			Repo: pypi,
+			Want: "pkg:pypi/django-allauth@0.44.0",
+		},
+		{
+			Name: "PyPIRepository",
+			Gen:  PyPI,
+			Pkg:  &claircore.Package{Name: "requests", Version: "2.25.1"},
+			Repo: []*claircore.Repository{{Name: "pypi", URI: "https://pypi.example.com/simple"}},
+			Want: "pkg:pypi/requests@2.25.1?repository_url=https://pypi.example.com/simple",
+		},
+		{
+			Name: "Maven",
+			Gen:  Maven,
+			Pkg:  &claircore.Package{Name: "org.apache.xmlgraphics:batik-anim", Version: "1.9.1"},
+			Repo: maven,
+			Want: "pkg:maven/org.apache.xmlgraphics/batik-anim@1.9.1",
+		},
+		{
+			Name: "Conda",
+			Gen:  Conda,
+			Pkg:  &claircore.Package{Name: "zlib", Version: "1.2.11-h7b6447c_3", Arch: "linux-64"},
+			Repo: []*claircore.Repository{{Name: "conda", URI: "https://repo.anaconda.com/pkgs/main"}},
+			Want: "pkg:conda/zlib@1.2.11?build=h7b6447c_3&channel=https://repo.anaconda.com/pkgs/main&subdir=linux-64",
+		},
+		{
+			Name: "CondaNoBuild",
+			Gen:  Conda,
+			Pkg:  &claircore.Package{Name: "tzdata", Version: "2021a", Arch: "noarch"},
+			Want: "pkg:conda/tzdata@2021a?subdir=noarch",
+		},
+		{
+			Name: "MavenRepository",
+			Gen:  Maven,
+			Pkg:  &claircore.Package{Name: "org.apache.xmlgraphics:batik-anim", Version: "1.9.1"},
+			Repo: []*claircore.Repository{{Name: "maven", URI: "repo.spring.io/release"}},
+			Want: "pkg:maven/org.apache.xmlgraphics/batik-anim@1.9.1?repository_url=repo.spring.io/release",
+		},
+		{
+			Name: "MavenNoGroup",
+			Gen:  Maven,
+			Pkg:  &claircore.Package{Name: "gremlin-console", Version: "3.4.6"},
+			Repo: maven,
+			Want: "pkg:maven/gremlin-console@3.4.6",
+		},
+		{
+			Name: "Empty",
+			Gen:  RPM,
+			Pkg:  &claircore.Package{},
+			Want: "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := tc.Gen(tc.Pkg, tc.Dist, tc.Repo); got != tc.Want {
+				t.Errorf("got: %q, want: %q", got, tc.Want)
+			}
+		})
+	}
+}
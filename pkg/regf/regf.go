@@ -0,0 +1,347 @@
+// Package regf is a minimal, read-only reader for Windows registry hive files.
+//
+// Only what's needed to look up values by key path is implemented: key
+// nodes, subkey lists, and values stored inline or in a single cell. Big data
+// values, security descriptors, and transaction logs are not supported.
+package regf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNotFound is returned when a requested key or value doesn't exist.
+var ErrNotFound = errors.New("regf: not found")
+
+// ErrMalformed is wrapped by errors caused by a hive that doesn't parse.
+var ErrMalformed = errors.New("regf: malformed hive")
+
+const (
+	baseBlockSize = 4096
+	// Bound the recursion through indirect subkey lists.
+	maxDepth = 8
+)
+
+var le = binary.LittleEndian
+
+// Hive is a parsed registry hive.
+type Hive struct {
+	b    []byte
+	root uint32
+}
+
+// Parse returns a Hive backed by "b", which should be the entire contents of
+// a hive file. The slice must not be modified while the Hive is in use.
+func Parse(b []byte) (*Hive, error) {
+	if len(b) < baseBlockSize || !bytes.Equal(b[:4], []byte("regf")) {
+		return nil, fmt.Errorf("%w: bad base block", ErrMalformed)
+	}
+	h := &Hive{
+		b:    b[baseBlockSize:],
+		root: le.Uint32(b[0x24:]),
+	}
+	if _, err := h.key(h.root); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Root returns the hive's root key.
+func (h *Hive) Root() (*Key, error) {
+	return h.key(h.root)
+}
+
+// Open returns the key at "path", a backslash-separated list of key names
+// relative to the root key. Names are matched case-insensitively.
+func (h *Hive) Open(path string) (*Key, error) {
+	k, err := h.Root()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range strings.Split(path, `\`) {
+		if n == "" {
+			continue
+		}
+		k, err = k.Subkey(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Cell returns the data of the cell at offset "off".
+func (h *Hive) cell(off uint32) ([]byte, error) {
+	o := int64(off)
+	if o+4 > int64(len(h.b)) {
+		return nil, fmt.Errorf("%w: cell offset %#x out of range", ErrMalformed, off)
+	}
+	sz := int64(int32(le.Uint32(h.b[o:])))
+	if sz < 0 {
+		// Allocated cells have a negative size.
+		sz = -sz
+	}
+	if sz < 4 || o+sz > int64(len(h.b)) {
+		return nil, fmt.Errorf("%w: bad cell size at %#x", ErrMalformed, off)
+	}
+	return h.b[o+4 : o+sz], nil
+}
+
+func (h *Hive) key(off uint32) (*Key, error) {
+	c, err := h.cell(off)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) < 0x4c || string(c[:2]) != "nk" {
+		return nil, fmt.Errorf("%w: no key node at %#x", ErrMalformed, off)
+	}
+	n := int(le.Uint16(c[0x48:]))
+	if 0x4c+n > len(c) {
+		return nil, fmt.Errorf("%w: key name out of range at %#x", ErrMalformed, off)
+	}
+	return &Key{
+		h:    h,
+		c:    c,
+		name: decodeName(c[0x4c:0x4c+n], le.Uint16(c[2:])&keyCompName != 0),
+	}, nil
+}
+
+const (
+	keyCompName   = 0x0020
+	valueCompName = 0x0001
+)
+
+// DecodeName decodes a key or value name, which is either Latin-1 or
+// UTF-16LE.
+func decodeName(b []byte, compressed bool) string {
+	if compressed {
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r)
+	}
+	return decodeUTF16(b)
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = le.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// Key is a registry key.
+type Key struct {
+	h    *Hive
+	c    []byte
+	name string
+}
+
+// Name returns the key's name.
+func (k *Key) Name() string { return k.name }
+
+// Subkey returns the immediate subkey "name", matched case-insensitively.
+func (k *Key) Subkey(name string) (*Key, error) {
+	if le.Uint32(k.c[0x14:]) == 0 {
+		return nil, fmt.Errorf("%w: key %q", ErrNotFound, name)
+	}
+	var found *Key
+	err := k.h.walkList(le.Uint32(k.c[0x1c:]), 0, func(sk *Key) bool {
+		if strings.EqualFold(sk.name, name) {
+			found = sk
+			return false
+		}
+		return true
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case found == nil:
+		return nil, fmt.Errorf("%w: key %q", ErrNotFound, name)
+	}
+	return found, nil
+}
+
+// Subkeys returns the names of the key's immediate subkeys.
+func (k *Key) Subkeys() ([]string, error) {
+	var out []string
+	if le.Uint32(k.c[0x14:]) == 0 {
+		return out, nil
+	}
+	err := k.h.walkList(le.Uint32(k.c[0x1c:]), 0, func(sk *Key) bool {
+		out = append(out, sk.name)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalkList calls "f" with each key in the subkey list at "off", until it
+// returns false.
+func (h *Hive) walkList(off uint32, depth int, f func(*Key) bool) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: subkey lists nested too deeply", ErrMalformed)
+	}
+	c, err := h.cell(off)
+	if err != nil {
+		return err
+	}
+	if len(c) < 4 {
+		return fmt.Errorf("%w: short subkey list at %#x", ErrMalformed, off)
+	}
+	n := int(le.Uint16(c[2:]))
+	var stride int
+	switch string(c[:2]) {
+	case "lf", "lh":
+		// Offset and name hash.
+		stride = 8
+	case "li", "ri":
+		stride = 4
+	default:
+		return fmt.Errorf("%w: unknown subkey list %q at %#x", ErrMalformed, c[:2], off)
+	}
+	if 4+n*stride > len(c) {
+		return fmt.Errorf("%w: subkey list out of range at %#x", ErrMalformed, off)
+	}
+	for i := 0; i < n; i++ {
+		o := le.Uint32(c[4+i*stride:])
+		if string(c[:2]) == "ri" {
+			var stop bool
+			if err := h.walkList(o, depth+1, func(k *Key) bool {
+				stop = !f(k)
+				return !stop
+			}); err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+			continue
+		}
+		k, err := h.key(o)
+		if err != nil {
+			return err
+		}
+		if !f(k) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Value returns the key's value "name", matched case-insensitively. The
+// default value has the empty name.
+func (k *Key) Value(name string) (*Value, error) {
+	n := int(le.Uint32(k.c[0x24:]))
+	if n == 0 {
+		return nil, fmt.Errorf("%w: value %q", ErrNotFound, name)
+	}
+	list, err := k.h.cell(le.Uint32(k.c[0x28:]))
+	if err != nil {
+		return nil, err
+	}
+	if n*4 > len(list) {
+		return nil, fmt.Errorf("%w: value list out of range", ErrMalformed)
+	}
+	for i := 0; i < n; i++ {
+		v, err := k.h.value(le.Uint32(list[i*4:]))
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(v.Name, name) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: value %q", ErrNotFound, name)
+}
+
+func (h *Hive) value(off uint32) (*Value, error) {
+	c, err := h.cell(off)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) < 0x14 || string(c[:2]) != "vk" {
+		return nil, fmt.Errorf("%w: no value at %#x", ErrMalformed, off)
+	}
+	n := int(le.Uint16(c[2:]))
+	if 0x14+n > len(c) {
+		return nil, fmt.Errorf("%w: value name out of range at %#x", ErrMalformed, off)
+	}
+	v := &Value{
+		Name: decodeName(c[0x14:0x14+n], le.Uint16(c[0x10:])&valueCompName != 0),
+		Type: Type(le.Uint32(c[0x0c:])),
+	}
+	sz := le.Uint32(c[0x04:])
+	switch {
+	case sz&0x80000000 != 0:
+		// Small data is stored in the offset field.
+		sz &^= 0x80000000
+		if sz > 4 {
+			return nil, fmt.Errorf("%w: bad inline data size at %#x", ErrMalformed, off)
+		}
+		v.Data = c[0x08 : 0x08+sz]
+	case sz == 0:
+	default:
+		d, err := h.cell(le.Uint32(c[0x08:]))
+		if err != nil {
+			return nil, err
+		}
+		if int64(sz) > int64(len(d)) {
+			return nil, fmt.Errorf("regf: value %q: big data values are unsupported", v.Name)
+		}
+		v.Data = d[:sz]
+	}
+	return v, nil
+}
+
+// Type is a registry value type.
+type Type uint32
+
+// These are the value types.
+const (
+	None     Type = 0
+	SZ       Type = 1
+	ExpandSZ Type = 2
+	Binary   Type = 3
+	DWORD    Type = 4
+	MultiSZ  Type = 7
+	QWORD    Type = 11
+)
+
+// Value is a registry value.
+type Value struct {
+	Name string
+	Type Type
+	Data []byte
+}
+
+// String returns the value of a string value.
+func (v *Value) String() (string, error) {
+	if v.Type != SZ && v.Type != ExpandSZ {
+		return "", fmt.Errorf("regf: value %q: not a string (type %d)", v.Name, v.Type)
+	}
+	s := decodeUTF16(v.Data)
+	if i := strings.IndexByte(s, 0); i != -1 {
+		s = s[:i]
+	}
+	return s, nil
+}
+
+// Uint64 returns the value of a DWORD or QWORD value.
+func (v *Value) Uint64() (uint64, error) {
+	switch {
+	case v.Type == DWORD && len(v.Data) >= 4:
+		return uint64(le.Uint32(v.Data)), nil
+	case v.Type == QWORD && len(v.Data) >= 8:
+		return le.Uint64(v.Data), nil
+	}
+	return 0, fmt.Errorf("regf: value %q: not an integer (type %d)", v.Name, v.Type)
+}
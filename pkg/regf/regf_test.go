@@ -0,0 +1,132 @@
+package regf
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func openTestdata(t *testing.T) *Hive {
+	t.Helper()
+	b, err := ioutil.ReadFile("testdata/SOFTWARE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestOpen(t *testing.T) {
+	h := openTestdata(t)
+	k, err := h.Open(`Microsoft\Windows NT\CurrentVersion`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := k.Name(), "CurrentVersion"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+
+	v, err := k.Value("ProductName")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := v.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s, "Windows Server 2019 Datacenter"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Names are case-insensitive, and small values are stored inline.
+	v, err = k.Value("ubr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := v.Uint64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, uint64(1697); got != want {
+		t.Errorf("got %d, want %d", got, want)
+	}
+	if _, err := v.String(); err == nil {
+		t.Error("DWORD decoded as a string")
+	}
+}
+
+func TestSubkeys(t *testing.T) {
+	h := openTestdata(t)
+	// The "Microsoft" key uses an indirect list, and one of its subkeys has
+	// a UTF-16 name.
+	k, err := h.Open(`microsoft`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := k.Subkeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Cryptography", "DirectX", "Ole", "Café", "Windows", "Windows NT"}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if _, err := k.Subkey("CAFÉ"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	h := openTestdata(t)
+	if _, err := h.Open(`Microsoft\Windows NT\Nope`); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+	k, err := h.Open(`Microsoft\Windows`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Subkey("Anything"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := k.Value("Anything"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+// TestMalformed checks that truncated and garbage hives return errors instead
+// of panicking.
+func TestMalformed(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/SOFTWARE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(b[:100]); !errors.Is(err, ErrMalformed) {
+		t.Errorf("got error %v, want %v", err, ErrMalformed)
+	}
+	for _, n := range []int{baseBlockSize + 64, baseBlockSize + 1024, len(b) / 2} {
+		h, err := Parse(b[:n])
+		if err != nil {
+			continue
+		}
+		if _, err := h.Open(`Microsoft\Windows NT\CurrentVersion`); err == nil {
+			t.Errorf("%d bytes: opened key in truncated hive", n)
+		}
+	}
+	// Scribble over every byte of the bins in turn; nothing should panic.
+	for i := baseBlockSize; i < len(b); i += 7 {
+		c := append([]byte(nil), b...)
+		c[i] ^= 0xff
+		h, err := Parse(c)
+		if err != nil {
+			continue
+		}
+		if k, err := h.Open(`Microsoft\Windows NT\CurrentVersion`); err == nil {
+			k.Value("ProductName")
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package pypa
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+// Advisory is the subset of the OSV schema this package uses.
+//
+// See https://ossf.github.io/osv-schema/ for the full schema.
+type advisory struct {
+	ID         string      `yaml:"id"`
+	Published  string      `yaml:"published"`
+	Withdrawn  string      `yaml:"withdrawn"`
+	Aliases    []string    `yaml:"aliases"`
+	Summary    string      `yaml:"summary"`
+	Details    string      `yaml:"details"`
+	Affected   []affected  `yaml:"affected"`
+	References []reference `yaml:"references"`
+}
+
+type affected struct {
+	Package struct {
+		Ecosystem string `yaml:"ecosystem"`
+		Name      string `yaml:"name"`
+	} `yaml:"package"`
+	Ranges   []affectedRange `yaml:"ranges"`
+	Versions []string        `yaml:"versions"`
+}
+
+type affectedRange struct {
+	Type   string  `yaml:"type"`
+	Events []event `yaml:"events"`
+}
+
+type event struct {
+	Introduced   string `yaml:"introduced"`
+	Fixed        string `yaml:"fixed"`
+	LastAffected string `yaml:"last_affected"`
+	Limit        string `yaml:"limit"`
+}
+
+type reference struct {
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+}
+
+// Interval is a span of affected versions.
+//
+// An empty "lower" means there's no lower bound, and an empty "upper" means
+// there's no upper bound. If "exact" is set, only the single version "lower"
+// is affected.
+type interval struct {
+	lower     string
+	upper     string
+	inclusive bool
+	exact     bool
+}
+
+// Spec returns the PEP 440 specifier for the interval.
+func (i interval) Spec() string {
+	if i.exact {
+		return "==" + i.lower
+	}
+	var s []string
+	if i.lower != "" && i.lower != "0" {
+		s = append(s, ">="+i.lower)
+	}
+	switch {
+	case i.upper == "":
+	case i.inclusive:
+		s = append(s, "<="+i.upper)
+	default:
+		s = append(s, "<"+i.upper)
+	}
+	if len(s) == 0 {
+		return ">=0"
+	}
+	return strings.Join(s, ",")
+}
+
+// Range returns the normalized version range for the interval.
+func (i interval) Range() (*claircore.Range, error) {
+	r := claircore.Range{
+		Lower: claircore.Version{Kind: "pep440"},
+		Upper: claircore.Version{Kind: "pep440"},
+	}
+	if i.lower != "" {
+		v, err := pep440.Parse(i.lower)
+		if err != nil {
+			return nil, err
+		}
+		r.Lower = v.Version()
+	}
+	upper, inclusive := i.upper, i.inclusive
+	if i.exact {
+		upper, inclusive = i.lower, true
+	}
+	switch {
+	case upper == "":
+		// No fixed version, so everything later is affected.
+		r.Upper.V[0] = math.MaxInt32
+	default:
+		v, err := pep440.Parse(upper)
+		if err != nil {
+			return nil, err
+		}
+		r.Upper = v.Version()
+		if inclusive {
+			// Range is half-open, so bump the least significant component to
+			// get a bound just past the last affected version.
+			r.Upper.V[len(r.Upper.V)-1]++
+		}
+	}
+	return &r, nil
+}
+
+// Intervals returns the affected intervals described by the ranges and the
+// list of versions.
+//
+// Versions that aren't covered by any range, such as yanked releases that
+// ranges were written around, are reported as single-version intervals.
+func (a *affected) Intervals(ctx context.Context) []interval {
+	var ret []interval
+	for _, r := range a.Ranges {
+		if r.Type != "ECOSYSTEM" {
+			continue
+		}
+		var cur interval
+		open := false
+		for _, e := range r.Events {
+			switch {
+			case e.Introduced != "":
+				if open {
+					// Two introduced events in a row: the first is subsumed.
+					continue
+				}
+				cur, open = interval{lower: e.Introduced}, true
+			case e.Fixed != "" && open:
+				cur.upper = e.Fixed
+				ret, open = append(ret, cur), false
+			case e.LastAffected != "" && open:
+				cur.upper, cur.inclusive = e.LastAffected, true
+				ret, open = append(ret, cur), false
+			}
+		}
+		if open {
+			ret = append(ret, cur)
+		}
+	}
+
+	rs := make([]*claircore.Range, 0, len(ret))
+	for _, i := range ret {
+		r, err := i.Range()
+		if err != nil {
+			continue
+		}
+		rs = append(rs, r)
+	}
+Versions:
+	for _, s := range a.Versions {
+		v, err := pep440.Parse(s)
+		if err != nil {
+			zlog.Debug(ctx).
+				Str("version", s).
+				Msg("unparsable version")
+			continue
+		}
+		nv := v.Version()
+		for _, r := range rs {
+			if r.Contains(&nv) {
+				continue Versions
+			}
+		}
+		ret = append(ret, interval{lower: s, exact: true})
+	}
+	return ret
+}
+
+// Vulnerabilities returns a Vulnerability for every affected interval of
+// every PyPI package in the advisory.
+func (a *advisory) Vulnerabilities(ctx context.Context, repo *claircore.Repository, updater string) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pypa/advisory.Vulnerabilities"),
+		label.String("advisory", a.ID))
+	if a.Withdrawn != "" {
+		zlog.Debug(ctx).Msg("advisory withdrawn, skipping")
+		return nil, nil
+	}
+
+	name := a.ID
+	var cves []string
+	for _, id := range a.Aliases {
+		if strings.HasPrefix(id, "CVE-") {
+			cves = append(cves, id)
+		}
+	}
+	if len(cves) != 0 {
+		name += " (" + strings.Join(cves, ", ") + ")"
+	}
+	aliases := append([]string{a.ID}, a.Aliases...)
+	desc := a.Details
+	if desc == "" {
+		desc = a.Summary
+	}
+	links := make([]string, 0, len(a.References))
+	for _, r := range a.References {
+		links = append(links, r.URL)
+	}
+	var issued time.Time
+	if a.Published != "" {
+		var err error
+		issued, err = time.Parse(time.RFC3339, a.Published)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Msg("unparsable published date")
+		}
+	}
+
+	var ret []*claircore.Vulnerability
+	var mungeCt int
+	for i := range a.Affected {
+		af := &a.Affected[i]
+		if !strings.EqualFold(af.Package.Ecosystem, "PyPI") {
+			continue
+		}
+		for _, iv := range af.Intervals(ctx) {
+			r, err := iv.Range()
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("spec", iv.Spec()).
+					Msg("malformed version in advisory")
+				mungeCt++
+				continue
+			}
+			v := &claircore.Vulnerability{
+				Name:        name,
+				Updater:     updater,
+				Description: desc,
+				Issued:      issued,
+				Links:       strings.Join(links, " "),
+				Aliases:     aliases,
+				Package: &claircore.Package{
+					Name: strings.ToLower(af.Package.Name),
+					Kind: claircore.BINARY,
+					// Like pyupio, the "version" is a specifier the python
+					// matcher checks installed versions against.
+					Version: iv.Spec(),
+				},
+				Repo:  repo,
+				Range: r,
+			}
+			if !iv.inclusive && !iv.exact {
+				v.FixedInVersion = iv.upper
+			}
+			ret = append(ret, v)
+		}
+	}
+	if mungeCt > 0 {
+		zlog.Debug(ctx).
+			Int("count", mungeCt).
+			Msg("skipped some malformed ranges")
+	}
+	return ret, nil
+}
@@ -0,0 +1,296 @@
+// Package pypa provides an updater for importing vulnerability information
+// from the PyPA advisory database.
+//
+// The database is published at github.com/pypa/advisory-database as a set of
+// OSV-format records.
+package pypa
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+const defaultURL = `https://github.com/pypa/advisory-database/archive/main.tar.gz`
+
+var (
+	_ driver.Updater      = (*Updater)(nil)
+	_ driver.Configurable = (*Updater)(nil)
+
+	defaultRepo = claircore.Repository{
+		Name: "pypi",
+		URI:  "https://pypi.org/simple",
+	}
+)
+
+// Updater reads the PyPA advisory database for vulnerabilities.
+//
+// The zero value is not safe to use.
+type Updater struct {
+	url    *url.URL
+	client *http.Client
+	repo   *claircore.Repository
+	name   string
+}
+
+// NewUpdater returns a configured Updater or reports an error.
+func NewUpdater(opt ...Option) (*Updater, error) {
+	u := Updater{}
+	for _, f := range opt {
+		if err := f(&u); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.url == nil {
+		var err error
+		u.url, err = url.Parse(defaultURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if u.client == nil {
+		u.client = http.DefaultClient // TODO(hank) Remove DefaultClient
+	}
+	if u.repo == nil {
+		u.repo = &defaultRepo
+	}
+	if u.name == "" {
+		u.name = "pypa"
+	}
+
+	return &u, nil
+}
+
+// Option controls the configuration of an Updater.
+type Option func(*Updater) error
+
+// WithClient sets the http.Client that the updater should use for requests.
+//
+// If not passed to NewUpdater, http.DefaultClient will be used.
+func WithClient(c *http.Client) Option {
+	return func(u *Updater) error {
+		u.client = c
+		return nil
+	}
+}
+
+// WithRepo sets the repository information that will be associated with all the
+// vulnerabilities found.
+//
+// If not passed to NewUpdater, a default Repository will be used.
+func WithRepo(r *claircore.Repository) Option {
+	return func(u *Updater) error {
+		u.repo = r
+		return nil
+	}
+}
+
+// WithURL sets the URL the updater should fetch.
+//
+// The URL should point to a gzip compressed tarball containing OSV-format
+// YAML or JSON files in a "vulns" directory.
+//
+// If not passed to NewUpdater, the main branch of
+// github.com/pypa/advisory-database will be fetched.
+func WithURL(uri string) Option {
+	u, err := url.Parse(uri)
+	return func(up *Updater) error {
+		if err != nil {
+			return err
+		}
+		up.url = u
+		return nil
+	}
+}
+
+// WithName sets the name the updater reports.
+//
+// This is used to run the updater in place of another one, such as pyupio.
+// If not passed to NewUpdater, the name is "pypa".
+func WithName(n string) Option {
+	return func(u *Updater) error {
+		u.name = n
+		return nil
+	}
+}
+
+// Config is the configuration for the updater.
+//
+// By convention, this is in a map called "pypa".
+type Config struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pypa/Updater.Configure"))
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+
+	if cfg.URL != "" {
+		uri, err := url.Parse(cfg.URL)
+		if err != nil {
+			return err
+		}
+		u.url = uri
+		zlog.Info(ctx).
+			Msg("configured URL")
+	}
+	u.client = c
+	zlog.Info(ctx).
+		Msg("configured HTTP client")
+	return nil
+}
+
+// Name implements driver.Updater.
+func (u *Updater) Name() string { return u.name }
+
+// Fetch implements driver.Updater.
+func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pypa/Updater.Fetch"))
+	zlog.Info(ctx).Str("database", u.url.String()).Msg("starting fetch")
+	req := http.Request{
+		Method:     http.MethodGet,
+		Header:     http.Header{"User-Agent": {"claircore/pypa/Updater"}},
+		URL:        u.url,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Host:       u.url.Host,
+	}
+	if hint != "" {
+		zlog.Debug(ctx).
+			Str("hint", string(hint)).
+			Msg("using hint")
+		req.Header.Set("if-none-match", string(hint))
+	}
+
+	res, err := u.client.Do(req.WithContext(ctx))
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, hint, err
+	}
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return nil, hint, driver.Unchanged
+	case http.StatusOK:
+		// break
+	default:
+		return nil, hint, fmt.Errorf("pypa: fetcher got unexpected HTTP response: %d (%s)", res.StatusCode, res.Status)
+	}
+	zlog.Debug(ctx).Msg("request ok")
+
+	r, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return nil, hint, err
+	}
+
+	tf, err := tmp.NewFile("", "pypa.")
+	if err != nil {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).
+		Str("path", tf.Name()).
+		Msg("using tempfile")
+	success := false
+	defer func() {
+		if !success {
+			zlog.Debug(ctx).Msg("unsuccessful, cleaning up tempfile")
+			if err := tf.Close(); err != nil {
+				zlog.Warn(ctx).Err(err).Msg("failed to close tempfile")
+			}
+		}
+	}()
+
+	if _, err := io.Copy(tf, r); err != nil {
+		return nil, hint, err
+	}
+	if o, err := tf.Seek(0, io.SeekStart); err != nil || o != 0 {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).Msg("decompressed and buffered database")
+
+	if t := res.Header.Get("etag"); t != "" {
+		zlog.Debug(ctx).
+			Str("hint", t).
+			Msg("using new hint")
+		hint = driver.Fingerprint(t)
+	}
+	success = true
+	return tf, hint, nil
+}
+
+// Parse implements driver.Updater.
+func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pypa/Updater.Parse"))
+	zlog.Info(ctx).Msg("parse start")
+	defer r.Close()
+	defer zlog.Info(ctx).Msg("parse done")
+
+	var ret []*claircore.Vulnerability
+	var ct int
+	rec := driver.NewRecords(ctx)
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg || !strings.Contains(h.Name, "/vulns/") {
+			continue
+		}
+		switch path.Ext(h.Name) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		ct++
+		// JSON is a subset of YAML, so one decoder handles both.
+		var a advisory
+		if err := yaml.NewDecoder(tr).Decode(&a); err != nil {
+			rec.Malformed(h.Name, err)
+			continue
+		}
+		vs, err := a.Vulnerabilities(ctx, u.repo, u.name)
+		if err != nil {
+			rec.Malformed(h.Name, err)
+			continue
+		}
+		rec.Ok()
+		ret = append(ret, vs...)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("count", ct).
+		Int("skipped", len(rec.Errors())).
+		Msg("found raw entries")
+	if err := rec.Err(); err != nil {
+		return nil, fmt.Errorf("pypa: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("count", len(ret)).
+		Msg("found vulnerabilities")
+	return ret, nil
+}
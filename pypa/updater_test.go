@@ -0,0 +1,255 @@
+package pypa
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	pyversion "github.com/aquasecurity/go-pep440-version"
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/pep440"
+)
+
+type advisoryTestcase struct {
+	Name string
+	// Want is the expected specifiers and fixed versions.
+	Want []want
+	// Affected and Unaffected are versions to check against every returned
+	// vulnerability's package.
+	Affected   []string
+	Unaffected []string
+}
+
+type want struct {
+	Package, Spec, Fixed string
+}
+
+func (tc advisoryTestcase) Run(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, err := os.Open(filepath.Join("testdata", tc.Name+".yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var a advisory
+	if err := yaml.NewDecoder(f).Decode(&a); err != nil {
+		t.Fatal(err)
+	}
+	vs, err := a.Vulnerabilities(ctx, &defaultRepo, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]want, len(vs))
+	for i, v := range vs {
+		got[i] = want{Package: v.Package.Name, Spec: v.Package.Version, Fixed: v.FixedInVersion}
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Spec < got[j].Spec })
+	sort.Slice(tc.Want, func(i, j int) bool { return tc.Want[i].Spec < tc.Want[j].Spec })
+	if !cmp.Equal(got, tc.Want) {
+		t.Error(cmp.Diff(got, tc.Want))
+	}
+
+	// Check that the specifier and the normalized range agree, and that they
+	// give the expected answer.
+	check := func(s string, wantAffected bool) {
+		t.Helper()
+		pv, err := pyversion.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := pep440.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nv := v.Version()
+		var bySpec, byRange bool
+		for _, vuln := range vs {
+			spec, err := pyversion.NewSpecifiers(vuln.Package.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inSpec, inRange := spec.Check(pv), vuln.Range.Contains(&nv)
+			if inSpec != inRange {
+				t.Errorf("%s: specifier %q says %v, range %v says %v",
+					s, vuln.Package.Version, inSpec, vuln.Range, inRange)
+			}
+			bySpec = bySpec || inSpec
+			byRange = byRange || inRange
+		}
+		if bySpec != wantAffected {
+			t.Errorf("%s: got affected: %v, want: %v", s, bySpec, wantAffected)
+		}
+	}
+	for _, s := range tc.Affected {
+		check(s, true)
+	}
+	for _, s := range tc.Unaffected {
+		check(s, false)
+	}
+}
+
+func TestAdvisory(t *testing.T) {
+	tt := []advisoryTestcase{
+		{
+			Name: "PYSEC-2021-0001",
+			Want: []want{
+				{Package: "examplepkg", Spec: "<1.11.19", Fixed: "1.11.19"},
+				{Package: "examplepkg", Spec: ">=2.0,<2.0.10", Fixed: "2.0.10"},
+				// Listed in "versions", but not covered by a range.
+				{Package: "examplepkg", Spec: "==1.11.20"},
+			},
+			Affected:   []string{"0.1", "1.11.18", "1.11.20", "2.0", "2.0.9"},
+			Unaffected: []string{"1.11.19", "1.11.21", "2.0.10", "3.0"},
+		},
+		{
+			Name: "PYSEC-2021-0002",
+			Want: []want{
+				{Package: "otherpkg", Spec: ">=1.0,<=1.4"},
+				{Package: "otherpkg", Spec: ">=3.0"},
+			},
+			Affected:   []string{"1.0", "1.2", "1.4", "3.0", "99.0"},
+			Unaffected: []string{"0.9", "1.4.1", "1.5", "2.9"},
+		},
+		{
+			Name: "PYSEC-2021-0003",
+			Want: []want{
+				{Package: "epochpkg", Spec: "<1!2021.8", Fixed: "1!2021.8"},
+			},
+			// Epoch-less versions sort before any version with an epoch, no
+			// matter how large.
+			Affected:   []string{"0.1", "2022.1", "9999.0", "1!2021.7"},
+			Unaffected: []string{"1!2021.8", "1!2021.9", "2!0.1"},
+		},
+		{
+			Name: "PYSEC-2021-0004",
+			Want: []want{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, tc.Run)
+	}
+}
+
+func TestParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ms, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := archive(t, ms)
+
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, ioutil.NopCloser(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vs), 6; got != want {
+		t.Errorf("got %d vulnerabilities, want %d", got, want)
+	}
+	for _, v := range vs {
+		if v.Name != "PYSEC-2021-0001 (CVE-2021-00001)" {
+			continue
+		}
+		want := &claircore.Vulnerability{
+			Name:        "PYSEC-2021-0001 (CVE-2021-00001)",
+			Updater:     "pypa",
+			Description: "Examplepkg before 1.11.19 and 2.x before 2.0.10 allows remote attackers to do something bad.",
+			Issued:      time.Date(2021, 5, 20, 8, 15, 0, 0, time.UTC),
+			Links:       "https://example.com/advisories/1 https://github.com/example/examplepkg/commit/abc123",
+			Aliases:     []string{"PYSEC-2021-0001", "CVE-2021-00001", "GHSA-xxxx-yyyy-zzzz"},
+			Package:     v.Package,
+			Repo:        &defaultRepo,
+			Range:       v.Range,
+		}
+		want.FixedInVersion = v.FixedInVersion
+		if !cmp.Equal(v, want) {
+			t.Error(cmp.Diff(v, want))
+		}
+	}
+}
+
+// Archive lays the named fixtures out like the GitHub archive. A name may be
+// repeated, in which case each copy gets its own path.
+func archive(t *testing.T, ms []string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for i, m := range ms {
+		b, err := ioutil.ReadFile(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     fmt.Sprintf("advisory-database-main/vulns/pkg%d/%s", i, filepath.Base(m)),
+			Size:     int64(len(b)),
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// TestParseMalformed checks that a malformed advisory is skipped, unless most
+// of the database is malformed.
+func TestParseMalformed(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	good, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join("testdata", "malformed", "PYSEC-2021-9999.yaml")
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("One", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var ms []string
+		for i := 0; i < 5; i++ {
+			ms = append(ms, good...)
+		}
+		ms = append(ms, bad)
+		vs, err := u.Parse(ctx, ioutil.NopCloser(archive(t, ms)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(vs), 30; got != want {
+			t.Errorf("got %d vulnerabilities, want %d", got, want)
+		}
+	})
+	t.Run("Most", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		ms := []string{good[0], bad, bad, bad}
+		_, err := u.Parse(ctx, ioutil.NopCloser(archive(t, ms)))
+		if !errors.Is(err, driver.ErrMalformed) {
+			t.Fatalf("got error %v, want %v", err, driver.ErrMalformed)
+		}
+		t.Log(err)
+	})
+}
@@ -0,0 +1,27 @@
+package pypa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/python"
+)
+
+// UpdaterSet returns an UpdaterSet containing a PyPA Updater.
+//
+// This set isn't registered by default; the pyupio updater can be configured
+// to use the PyPA database instead. See pyupio.Config.
+func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
+	us := driver.NewUpdaterSet()
+	repo := python.Repository
+	u, err := NewUpdater(WithRepo(&repo))
+	if err != nil {
+		return us, fmt.Errorf("failed to create pypa updater: %v", err)
+	}
+	err = us.Add(u)
+	if err != nil {
+		return us, err
+	}
+	return us, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
 )
 
 func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
@@ -22,17 +23,15 @@ func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts)
 	}
 
 	for _, l := range ls {
-		// If we didn't find at least one pip repo in this layer
-		// no point in searching for packages.
-		if len(l.Repos) == 0 {
-			continue
-		}
-		rs := make([]string, len(l.Repos))
-		for i, r := range l.Repos {
-			rs[i] = r.ID
-			ir.Repositories[r.ID] = r
+		// Packages are kept even if no pip repo was found in this
+		// layer: an inventory-only indexer doesn't look for them.
+		var rs []string
+		for _, r := range l.Repos {
+			rs = append(rs, r.ID)
+			ir.AddRepository(r, l.Hash)
 		}
 		for _, pkg := range l.Pkgs {
+			pkg.PURL = purl.PyPI(pkg, nil, l.Repos)
 			ir.Packages[pkg.ID] = pkg
 			ir.Environments[pkg.ID] = []*claircore.Environment{
 				&claircore.Environment{
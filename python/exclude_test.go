@@ -0,0 +1,97 @@
+package python_test
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/python"
+)
+
+// TestExclude checks that packages under excluded paths are skipped.
+func TestExclude(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := excludeLayer(t, map[string]string{
+		"usr/lib/python3.8/site-packages/included-1.0.dist-info/METADATA": "Name: included\nVersion: 1.0\n\n",
+		"usr/share/doc/fixture/excluded-2.0.dist-info/METADATA":           "Name: excluded\nVersion: 2.0\n\n",
+		"srv/node_modules/vendored-3.0.egg-info/PKG-INFO":                 "Name: vendored\nVersion: 3.0\n\n",
+	})
+
+	tt := []struct {
+		name string
+		cfg  string
+		want []string
+	}{
+		{name: "Default", cfg: `{}`, want: []string{"excluded", "included", "vendored"}},
+		{name: "Exclude", cfg: `{"exclude":["/usr/share/doc","*/node_modules"]}`, want: []string{"included"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &python.Scanner{}
+			if err := s.Configure(ctx, json.NewDecoder(strings.NewReader(tc.cfg)).Decode); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := s.Scan(ctx, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(ps))
+			for i, p := range ps {
+				got[i] = p.Name
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+
+	s := &python.Scanner{}
+	if err := s.Configure(ctx, json.NewDecoder(strings.NewReader(`{"exclude":["[usr"]}`)).Decode); err == nil {
+		t.Error("malformed pattern accepted")
+	}
+}
+
+// ExcludeLayer writes the files to a layer tarball.
+func excludeLayer(t *testing.T, files map[string]string) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "python.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	for n, c := range files {
+		if err := w.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     n,
+			Size:     int64(len(c)),
+			Mode:     0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
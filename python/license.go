@@ -0,0 +1,80 @@
+package python
+
+import (
+	"net/textproto"
+	"strings"
+
+	"github.com/quay/claircore/internal/license"
+)
+
+// ClassifierPrefix is the prefix of the trove classifiers that declare a
+// license.
+const classifierPrefix = `License :: `
+
+// Classifiers maps license classifiers to the SPDX identifier they
+// unambiguously correspond to. Classifiers that don't name a single license,
+// like "BSD License", aren't listed.
+var classifiers = map[string]string{
+	"OSI Approved :: MIT License":                                             "MIT",
+	"OSI Approved :: ISC License (ISCL)":                                      "ISC",
+	"OSI Approved :: Apache Software License 2.0 (Apache-2.0)":                "Apache-2.0",
+	"OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                    "MPL-2.0",
+	"OSI Approved :: GNU General Public License v2 (GPLv2)":                   "GPL-2.0-only",
+	"OSI Approved :: GNU General Public License v2 or later (GPLv2+)":         "GPL-2.0-or-later",
+	"OSI Approved :: GNU General Public License v3 (GPLv3)":                   "GPL-3.0-only",
+	"OSI Approved :: GNU General Public License v3 or later (GPLv3+)":         "GPL-3.0-or-later",
+	"OSI Approved :: GNU Lesser General Public License v2 (LGPLv2)":           "LGPL-2.0-only",
+	"OSI Approved :: GNU Lesser General Public License v2 or later (LGPLv2+)": "LGPL-2.0-or-later",
+	"OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":           "LGPL-3.0-only",
+	"OSI Approved :: GNU Lesser General Public License v3 or later (LGPLv3+)": "LGPL-3.0-or-later",
+	"OSI Approved :: GNU Affero General Public License v3":                    "AGPL-3.0-only",
+	"OSI Approved :: Python Software Foundation License":                      "PSF-2.0",
+	"OSI Approved :: The Unlicense (Unlicense)":                               "Unlicense",
+	"CC0 1.0 Universal (CC0 1.0) Public Domain Dedication":                    "CC0-1.0",
+}
+
+// MetadataLicense returns the license declared in a package's metadata.
+//
+// An SPDX expression, from the "License-Expression" key or a "License" key
+// that holds one, is preferred. Otherwise, the license classifiers are used
+// if they all map to SPDX identifiers or there's no "License" key, and the
+// "License" key is used verbatim if not.
+func metadataLicense(hdr textproto.MIMEHeader) string {
+	if e := strings.TrimSpace(hdr.Get("License-Expression")); e != "" {
+		return e
+	}
+	lic := strings.TrimSpace(hdr.Get("License"))
+	if lic == "UNKNOWN" {
+		lic = ""
+	}
+	if lic != "" && license.IsExpression(lic) {
+		return lic
+	}
+	var cs []string
+	mapped := true
+	for _, c := range hdr.Values("Classifier") {
+		c = strings.TrimSpace(c)
+		if !strings.HasPrefix(c, classifierPrefix) {
+			continue
+		}
+		c = strings.TrimPrefix(c, classifierPrefix)
+		if c == "OSI Approved" {
+			continue
+		}
+		if id, ok := classifiers[c]; ok {
+			cs = append(cs, id)
+			continue
+		}
+		mapped = false
+		// Use the most specific part of the classifier, like "Apache
+		// Software License" for "OSI Approved :: Apache Software License".
+		if i := strings.LastIndex(c, " :: "); i != -1 {
+			c = c[i+len(" :: "):]
+		}
+		cs = append(cs, c)
+	}
+	if len(cs) != 0 && (mapped || lic == "") {
+		return license.Join(cs)
+	}
+	return lic
+}
@@ -0,0 +1,54 @@
+package python_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/python"
+)
+
+// TestLicense checks the licenses recorded from package metadata.
+func TestLicense(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const dir = "usr/lib/python3.8/site-packages/"
+	l := excludeLayer(t, map[string]string{
+		dir + "expression-1.0.dist-info/METADATA": "Name: expression\nVersion: 1.0\n" +
+			"License-Expression: MIT OR Apache-2.0\nLicense: MIT\n\n",
+		dir + "spdx-1.0.dist-info/METADATA": "Name: spdx\nVersion: 1.0\n" +
+			"License: BSD-3-Clause\nClassifier: License :: OSI Approved :: BSD License\n\n",
+		dir + "classifiers-1.0.dist-info/METADATA": "Name: classifiers\nVersion: 1.0\n" +
+			"License: Dual licensed, see LICENSE\n" +
+			"Classifier: License :: OSI Approved :: MIT License\n" +
+			"Classifier: License :: OSI Approved :: Apache Software License 2.0 (Apache-2.0)\n" +
+			"Classifier: Programming Language :: Python :: 3\n\n",
+		dir + "text-1.0.dist-info/METADATA": "Name: text\nVersion: 1.0\n" +
+			"License: Apache License, Version 2.0\n" +
+			"Classifier: License :: OSI Approved\n" +
+			"Classifier: License :: OSI Approved :: Apache Software License\n\n",
+		dir + "unmapped-1.0.egg-info/PKG-INFO": "Name: unmapped\nVersion: 1.0\nLicense: UNKNOWN\n" +
+			"Classifier: License :: OSI Approved :: BSD License\n\n",
+		dir + "none-1.0.dist-info/METADATA": "Name: none\nVersion: 1.0\n\n",
+	})
+	ps, err := (&python.Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.License
+	}
+	want := map[string]string{
+		"expression":  "MIT OR Apache-2.0",
+		"spdx":        "BSD-3-Clause",
+		"classifiers": "MIT AND Apache-2.0",
+		"text":        "Apache License, Version 2.0",
+		"unmapped":    "BSD License",
+		"none":        "",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -20,8 +20,12 @@ type Matcher struct{}
 func (*Matcher) Name() string { return "python" }
 
 // Filter implements driver.Matcher.
+//
+// Packages from PyPI are considered even if their version couldn't be
+// normalized, so that they're reported as indeterminate rather than dropped.
 func (*Matcher) Filter(record *claircore.IndexRecord) bool {
-	return record.Package.NormalizedVersion.Kind == "pep440"
+	return record.Package.NormalizedVersion.Kind == "pep440" ||
+		record.Package.RepositoryHint == Repository.URI
 }
 
 // Query implements driver.Matcher.
@@ -37,14 +41,18 @@ func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, v
 		return false, nil
 	}
 
-	v, err := pep440.Parse(record.Package.Version)
+	pv := record.Package.Version
+	if driver.EmptyVersion(pv) {
+		return false, driver.PackageVersionError(pv, driver.ErrEmptyVersion)
+	}
+	v, err := pep440.Parse(pv)
 	if err != nil {
-		return false, nil
+		return false, driver.PackageVersionError(pv, err)
 	}
 
 	spec, err := pep440.NewSpecifiers(vuln.Package.Version)
 	if err != nil {
-		return false, nil
+		return false, driver.VulnerabilityVersionError(vuln.Package.Version, err)
 	}
 
 	if spec.Check(v) {
@@ -7,6 +7,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/python"
+	"github.com/quay/claircore/test"
 )
 
 type matcherTestcase struct {
@@ -84,3 +85,45 @@ func TestMatcher(t *testing.T) {
 		t.Run(tc.Name, tc.Run)
 	}
 }
+
+func TestBadVersions(t *testing.T) {
+	test.BadVersionTestcase{
+		Matcher: &python.Matcher{},
+		Record: func(v string) *claircore.IndexRecord {
+			return &claircore.IndexRecord{
+				Package: &claircore.Package{
+					Name:           "requests",
+					Version:        v,
+					RepositoryHint: python.Repository.URI,
+				},
+			}
+		},
+		Vulnerability: func(v string) *claircore.Vulnerability {
+			return &claircore.Vulnerability{
+				Package: &claircore.Package{Name: "requests", Version: v},
+			}
+		},
+		Good:  "2.25.1",
+		Extra: []string{"v", "1..2", "3f2a9c1d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f", "1.0.0-"},
+	}.Run(t)
+}
+
+// TestFilterUnnormalized checks that PyPI packages whose versions couldn't be
+// normalized are still considered, so they're reported as indeterminate.
+func TestFilterUnnormalized(t *testing.T) {
+	m := &python.Matcher{}
+	r := &claircore.IndexRecord{
+		Package: &claircore.Package{
+			Name:           "requests",
+			Version:        "latest",
+			RepositoryHint: python.Repository.URI,
+		},
+	}
+	if !m.Filter(r) {
+		t.Error("package from PyPI not considered")
+	}
+	r.Package.RepositoryHint = ""
+	if m.Filter(r) {
+		t.Error("unknown package considered")
+	}
+}
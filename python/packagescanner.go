@@ -23,8 +23,9 @@ import (
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner    = (*Scanner)(nil)
+	_ indexer.PackageScanner      = (*Scanner)(nil)
+	_ indexer.ConfigurableScanner = (*Scanner)(nil)
 )
 
 // Scanner implements the scanner.PackageScanner interface.
@@ -33,13 +34,32 @@ var (
 // metadata recorded there.
 //
 // The zero value is ready to use.
-type Scanner struct{}
+type Scanner struct {
+	cfg ScannerConfig
+}
+
+// ScannerConfig is the struct that will be passed to (*Scanner).Configure's
+// ConfigDeserializer argument.
+type ScannerConfig struct {
+	// Exclude lists patterns of files to skip, such as vendored test
+	// fixtures. See indexer.PathExclusions for the syntax. Nothing is
+	// excluded by default.
+	Exclude indexer.PathExclusions `json:"exclude" yaml:"exclude"`
+}
+
+// Configure implements indexer.ConfigurableScanner.
+func (ps *Scanner) Configure(ctx context.Context, f indexer.ConfigDeserializer) error {
+	if err := f(&ps.cfg); err != nil {
+		return err
+	}
+	return ps.cfg.Exclude.Validate()
+}
 
 // Name implements scanner.VersionedScanner.
 func (*Scanner) Name() string { return "python" }
 
 // Version implements scanner.VersionedScanner.
-func (*Scanner) Version() string { return "0.1.0" }
+func (*Scanner) Version() string { return "0.2.0" }
 
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return "package" }
@@ -75,6 +95,7 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	}
 
 	var ret []*claircore.Package
+	var excluded int
 	tr := tar.NewReader(rd)
 	var h *tar.Header
 	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
@@ -86,6 +107,9 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 		case h.Typeflag != tar.TypeReg:
 			// Should we chase symlinks with the correct name?
 			continue
+		case ps.cfg.Exclude.Excluded(n):
+			excluded++
+			continue
 		case strings.HasSuffix(n, `.egg-info/PKG-INFO`):
 			zlog.Debug(ctx).Str("file", n).Msg("found egg")
 		case strings.HasSuffix(n, `.dist-info/METADATA`):
@@ -104,23 +128,37 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 				Msg("unable to read metadata, skipping")
 			continue
 		}
-		v, err := pep440.Parse(hdr.Get("Version"))
-		if err != nil {
-			return nil, err
-		}
-		ret = append(ret, &claircore.Package{
-			Name:              strings.ToLower(hdr.Get("Name")),
-			Version:           v.String(),
-			PackageDB:         "python:" + filepath.Join(n, "..", ".."),
-			Kind:              claircore.BINARY,
-			NormalizedVersion: v.Version(),
+		pkg := &claircore.Package{
+			Name:      strings.ToLower(hdr.Get("Name")),
+			Version:   hdr.Get("Version"),
+			PackageDB: "python:" + filepath.Join(n, "..", ".."),
+			Kind:      claircore.BINARY,
 			// TODO Is there some way to pick up on where a wheel or egg was
 			// found?
 			RepositoryHint: "https://pypi.org/simple",
-		})
+			License:        metadataLicense(hdr),
+		}
+		// Packages with unparseable versions are kept as found, so matching
+		// can report them as indeterminate.
+		if v, err := pep440.Parse(pkg.Version); err == nil {
+			pkg.Version = v.String()
+			pkg.NormalizedVersion = v.Version()
+		} else {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("path", n).
+				Str("version", pkg.Version).
+				Msg("unable to parse package version")
+		}
+		ret = append(ret, pkg)
 	}
 	if err != io.EOF {
 		return nil, err
 	}
+	if excluded != 0 {
+		zlog.Debug(ctx).
+			Int("count", excluded).
+			Msg("skipped excluded files")
+	}
 	return ret, nil
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/tmp"
+	"github.com/quay/claircore/pypa"
 )
 
 const defaultURL = `https://github.com/pyupio/safety-db/archive/master.tar.gz`
@@ -29,6 +30,7 @@ const defaultURL = `https://github.com/pyupio/safety-db/archive/master.tar.gz`
 var (
 	_ driver.Updater      = (*Updater)(nil)
 	_ driver.Configurable = (*Updater)(nil)
+	_ driver.DataSourcer  = (*Updater)(nil)
 
 	defaultRepo = claircore.Repository{
 		Name: "pypi",
@@ -43,6 +45,8 @@ type Updater struct {
 	url    *url.URL
 	client *http.Client
 	repo   *claircore.Repository
+	// If set, fetching and parsing is delegated to this updater.
+	pypa *pypa.Updater
 }
 
 // NewUpdater returns a configured Updater or reports an error.
@@ -118,8 +122,20 @@ func WithURL(uri string) Option {
 // By convention, this is in a map called "pyupio".
 type Config struct {
 	URL string `json:"url" yaml:"url"`
+	// Source selects the database to use: "pyupio" (the default) or "pypa".
+	//
+	// With "pypa", the PyPA advisory database is used in place of the
+	// safety-db database, and URL, if set, should point to an archive of the
+	// PyPA database. The safety-db source is deprecated.
+	Source string `json:"source" yaml:"source"`
 }
 
+// These are the valid values for Config.Source.
+const (
+	SourcePyupio = "pyupio"
+	SourcePyPA   = "pypa"
+)
+
 // Configure implements driver.Configurable.
 func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
 	ctx = baggage.ContextWithValues(ctx,
@@ -129,6 +145,32 @@ func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *
 		return err
 	}
 
+	switch cfg.Source {
+	case "", SourcePyupio:
+		u.pypa = nil
+		zlog.Info(ctx).
+			Msg("the pyupio source is deprecated, consider setting source to \"pypa\"")
+	case SourcePyPA:
+		opts := []pypa.Option{
+			pypa.WithName(u.Name()),
+			pypa.WithRepo(u.repo),
+			pypa.WithClient(c),
+		}
+		if cfg.URL != "" {
+			opts = append(opts, pypa.WithURL(cfg.URL))
+		}
+		p, err := pypa.NewUpdater(opts...)
+		if err != nil {
+			return err
+		}
+		u.pypa = p
+		zlog.Info(ctx).
+			Msg("configured to use PyPA advisory database")
+		return nil
+	default:
+		return fmt.Errorf("pyupio: unknown source %q", cfg.Source)
+	}
+
 	if cfg.URL != "" {
 		uri, err := url.Parse(cfg.URL)
 		if err != nil {
@@ -147,10 +189,22 @@ func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *
 // Name implements driver.Updater.
 func (*Updater) Name() string { return "pyupio" }
 
+// DataSource implements driver.DataSourcer.
+func (*Updater) DataSource() claircore.DataSource {
+	return claircore.DataSource{
+		Name:    "pyup.io Safety DB",
+		License: "CC-BY-NC-SA-4.0",
+		URL:     "https://github.com/pyupio/safety-db",
+	}
+}
+
 // Fetch implements driver.Updater.
 func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "pyupio/Updater.Fetch"))
+	if u.pypa != nil {
+		return u.pypa.Fetch(ctx, hint)
+	}
 	zlog.Info(ctx).Str("database", u.url.String()).Msg("starting fetch")
 	req := http.Request{
 		Method:     http.MethodGet,
@@ -229,6 +283,9 @@ func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCl
 func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "pyupio/Updater.Parse"))
+	if u.pypa != nil {
+		return u.pypa.Parse(ctx, r)
+	}
 	zlog.Info(ctx).Msg("parse start")
 	defer r.Close()
 	defer zlog.Info(ctx).Msg("parse done")
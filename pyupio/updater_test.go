@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,6 +15,7 @@ import (
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
 )
 
 func TestDB(t *testing.T) {
@@ -118,3 +120,49 @@ func (tc dbTestcase) Run(t *testing.T) {
 		t.Error(cmp.Diff(tc.Want, got))
 	}
 }
+
+func TestConfigureSource(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	cfg := func(c Config) driver.ConfigUnmarshaler {
+		return func(v interface{}) error {
+			*v.(*Config) = c
+			return nil
+		}
+	}
+	t.Run("PyPA", func(t *testing.T) {
+		u, err := NewUpdater()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Configure(ctx, cfg(Config{Source: SourcePyPA}), http.DefaultClient); err != nil {
+			t.Fatal(err)
+		}
+		if u.pypa == nil {
+			t.Fatal("expected delegation to pypa updater")
+		}
+		if got, want := u.pypa.Name(), u.Name(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("Default", func(t *testing.T) {
+		u, err := NewUpdater()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Configure(ctx, cfg(Config{}), http.DefaultClient); err != nil {
+			t.Fatal(err)
+		}
+		if u.pypa != nil {
+			t.Error("unexpected delegation to pypa updater")
+		}
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		u, err := NewUpdater()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Configure(ctx, cfg(Config{Source: "osv"}), http.DefaultClient); err == nil {
+			t.Error("expected error for unknown source")
+		}
+	})
+}
@@ -0,0 +1,444 @@
+package claircore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redaction removes internal paths and hostnames from reports, so they can be
+// shared outside of where they were produced.
+//
+// Redacted values are replaced consistently: the same value is redacted the
+// same way in every report redacted with the same Redaction, so redacted
+// reports can still be matched and diffed against each other. Package
+// database kinds, such as the "go:" in "go:usr/bin/app", are kept, as
+// matchers depend on them.
+//
+// Methods on a nil *Redaction leave everything as-is.
+type Redaction struct {
+	// StripCredentials removes the user information and query from URIs,
+	// which is where credentials and signed tokens usually are.
+	StripCredentials bool `json:"strip_credentials,omitempty" yaml:"strip_credentials,omitempty"`
+	// Hosts are the hostnames to replace with a hash. A name starting with a
+	// "." matches the domain and all of its subdomains. Hosts of public
+	// package repositories shouldn't be listed, as matchers compare
+	// repository URIs against them.
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Paths are the path prefixes to redact, tried in order.
+	Paths []PathRedaction `json:"paths,omitempty" yaml:"paths,omitempty"`
+	// Salt, if set, is mixed into the hashes, so redacted values can't be
+	// recovered by hashing guesses. Reports redacted with different salts
+	// can't be diffed against each other.
+	Salt string `json:"salt,omitempty" yaml:"salt,omitempty"`
+}
+
+// PathRedaction redacts a path prefix.
+type PathRedaction struct {
+	// Prefix is the leading path components to redact, like
+	// "/builds/internal-project". Paths are compared with and without a
+	// leading "/".
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// Mode is how the prefix is redacted.
+	Mode PathMode `json:"mode" yaml:"mode"`
+}
+
+// PathMode is how a PathRedaction redacts a prefix.
+type PathMode string
+
+// These are the PathModes.
+const (
+	// PathHash replaces the prefix with a hash of it, so different prefixes
+	// stay distinct.
+	PathHash PathMode = "hash"
+	// PathTruncate removes the prefix, leaving a relative path.
+	PathTruncate PathMode = "truncate"
+)
+
+// RedactionInfo records that a report was redacted.
+//
+// The rules themselves aren't recorded, as they'd reveal what was redacted.
+type RedactionInfo struct {
+	// Credentials is whether credentials were stripped from URIs.
+	Credentials bool `json:"credentials,omitempty"`
+	// Hosts and Paths are the number of host and path rules.
+	Hosts int `json:"hosts,omitempty"`
+	Paths int `json:"paths,omitempty"`
+	// Fingerprint identifies the Redaction. Only reports with the same
+	// fingerprint were redacted the same way and can be diffed.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// ErrInvalidRedaction is returned, wrapped, for Redactions that can't be used.
+var ErrInvalidRedaction = errors.New("claircore: invalid redaction")
+
+// Validate reports an error wrapping ErrInvalidRedaction if any of the
+// Redaction's rules are empty, or a path rule has an unknown mode.
+func (r *Redaction) Validate() error {
+	if r == nil {
+		return nil
+	}
+	for i, h := range r.Hosts {
+		if strings.Trim(h, ".") == "" {
+			return fmt.Errorf("%w: host %d is empty", ErrInvalidRedaction, i)
+		}
+	}
+	for i, p := range r.Paths {
+		if strings.Trim(p.Prefix, "/") == "" {
+			return fmt.Errorf("%w: path %d has an empty prefix", ErrInvalidRedaction, i)
+		}
+		switch p.Mode {
+		case PathHash, PathTruncate:
+		default:
+			return fmt.Errorf("%w: path %d has unknown mode %q", ErrInvalidRedaction, i, p.Mode)
+		}
+	}
+	return nil
+}
+
+// Info returns the RedactionInfo recorded in reports redacted with the
+// Redaction, or nil for a nil Redaction.
+func (r *Redaction) Info() *RedactionInfo {
+	if r == nil {
+		return nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil { // Can't happen, it's all strings.
+		panic(fmt.Sprintf("claircore: programmer error: %v", err))
+	}
+	sum := sha256.Sum256(b)
+	return &RedactionInfo{
+		Credentials: r.StripCredentials,
+		Hosts:       len(r.Hosts),
+		Paths:       len(r.Paths),
+		Fingerprint: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+}
+
+// Token returns the replacement for the redacted value "v".
+func (r *Redaction) token(v string) string {
+	h := sha256.New()
+	io.WriteString(h, r.Salt)
+	h.Write([]byte{0})
+	io.WriteString(h, v)
+	return "redacted-" + hex.EncodeToString(h.Sum(nil)[:6])
+}
+
+// Host returns the hostname "h", replaced with a hash if it matches one of
+// the Redaction's hosts.
+func (r *Redaction) Host(h string) string {
+	if r == nil || h == "" {
+		return h
+	}
+	l := strings.ToLower(h)
+	for _, rule := range r.Hosts {
+		rule = strings.ToLower(rule)
+		if l == strings.TrimPrefix(rule, ".") ||
+			(strings.HasPrefix(rule, ".") && strings.HasSuffix(l, rule)) {
+			return r.token(l)
+		}
+	}
+	return h
+}
+
+// Path returns the path "p" with the first matching path rule applied. A
+// leading package database kind, like "python:", is kept.
+func (r *Redaction) Path(p string) string {
+	if r == nil || len(r.Paths) == 0 {
+		return p
+	}
+	var kind string
+	if i := strings.IndexByte(p, ':'); i > 0 && isKind(p[:i]) && !strings.HasPrefix(p[i:], "://") {
+		kind, p = p[:i+1], p[i+1:]
+	}
+	rel := strings.TrimPrefix(p, "/")
+	for _, rule := range r.Paths {
+		pre := strings.Trim(rule.Prefix, "/")
+		if rel != pre && !strings.HasPrefix(rel, pre+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(rel[len(pre):], "/")
+		switch rule.Mode {
+		case PathTruncate:
+			return kind + rest
+		default:
+			if rest == "" {
+				return kind + r.token(pre)
+			}
+			return kind + r.token(pre) + "/" + rest
+		}
+	}
+	return kind + p
+}
+
+// IsKind reports whether "s" looks like a package database kind.
+func isKind(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// URI returns the URI "u" with the Redaction applied to its credentials,
+// host, and path. Strings that aren't absolute URIs are returned as-is.
+func (r *Redaction) URI(u string) string {
+	if r == nil || u == "" {
+		return u
+	}
+	p, err := url.Parse(u)
+	if err != nil || p.Scheme == "" {
+		return u
+	}
+	changed := false
+	if r.StripCredentials && (p.User != nil || p.RawQuery != "" || p.ForceQuery) {
+		p.User = nil
+		p.RawQuery = ""
+		p.ForceQuery = false
+		changed = true
+	}
+	if h := p.Hostname(); h != "" {
+		if rh := r.Host(h); rh != h {
+			if port := p.Port(); port != "" {
+				rh += ":" + port
+			}
+			p.Host = rh
+			changed = true
+		}
+	}
+	if p.Path != "" {
+		if rp := r.Path(p.Path); rp != p.Path {
+			if !strings.HasPrefix(rp, "/") && p.Host != "" {
+				rp = "/" + rp
+			}
+			p.Path, p.RawPath = rp, ""
+			changed = true
+		}
+	}
+	if !changed {
+		return u
+	}
+	return p.String()
+}
+
+var (
+	uriRegexp  = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+	hostRegexp = regexp.MustCompile(`[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)+`)
+)
+
+// Text returns the free-form text "s", such as an error message, with the
+// Redaction applied to any URIs, hostnames, and paths in it.
+func (r *Redaction) Text(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	s = uriRegexp.ReplaceAllStringFunc(s, r.URI)
+	if len(r.Hosts) != 0 {
+		s = hostRegexp.ReplaceAllStringFunc(s, r.Host)
+	}
+	for _, rule := range r.Paths {
+		pre := "/" + strings.Trim(rule.Prefix, "/")
+		to := ""
+		if rule.Mode != PathTruncate {
+			to = r.token(strings.TrimPrefix(pre, "/")) + "/"
+		}
+		s = strings.Replace(s, pre+"/", to, -1)
+	}
+	return s
+}
+
+// Package returns a redacted copy of the Package "p" and its source.
+func (r *Redaction) Package(p *Package) *Package {
+	if r == nil || p == nil {
+		return p
+	}
+	c := *p
+	c.PackageDB = r.Path(p.PackageDB)
+	c.RepositoryHint = r.URI(p.RepositoryHint)
+	c.PURL = r.purl(p.PURL)
+	c.Source = r.Package(p.Source)
+	return &c
+}
+
+// Purl returns the package URL "u" with the Redaction applied to its
+// qualifier values, where package URLs keep repository URIs, like the
+// "repository_url" and "channel" qualifiers.
+func (r *Redaction) purl(u string) string {
+	q := strings.IndexByte(u, '?')
+	if q == -1 {
+		return u
+	}
+	end := len(u)
+	if i := strings.IndexByte(u[q:], '#'); i != -1 {
+		end = q + i
+	}
+	kvs := strings.Split(u[q+1:end], "&")
+	changed := false
+	for i, kv := range kvs {
+		eq := strings.IndexByte(kv, '=')
+		if eq == -1 {
+			continue
+		}
+		v, err := url.PathUnescape(kv[eq+1:])
+		if err != nil {
+			continue
+		}
+		if rv := r.URI(v); rv != v {
+			kvs[i] = kv[:eq+1] + purlEscape(rv)
+			changed = true
+		}
+	}
+	if !changed {
+		return u
+	}
+	return u[:q+1] + strings.Join(kvs, "&") + u[end:]
+}
+
+// PurlEscape percent-encodes a qualifier value the way pkg/purl does.
+func purlEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '.' || c == '_' || c == '~' || c == ':' || c == '/':
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xF])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Repository returns a redacted copy of the Repository "repo".
+func (r *Redaction) Repository(repo *Repository) *Repository {
+	if r == nil || repo == nil {
+		return repo
+	}
+	c := *repo
+	c.URI = r.URI(repo.URI)
+	return &c
+}
+
+func (r *Redaction) packages(m map[string]*Package) map[string]*Package {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]*Package, len(m))
+	for k, p := range m {
+		out[k] = r.Package(p)
+	}
+	return out
+}
+
+func (r *Redaction) repositories(m map[string]*Repository) map[string]*Repository {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]*Repository, len(m))
+	for k, repo := range m {
+		out[k] = r.Repository(repo)
+	}
+	return out
+}
+
+func (r *Redaction) environments(m map[string][]*Environment) map[string][]*Environment {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string][]*Environment, len(m))
+	for k, es := range m {
+		if es == nil {
+			out[k] = nil
+			continue
+		}
+		s := make([]*Environment, len(es))
+		for i, e := range es {
+			if e == nil {
+				continue
+			}
+			c := *e
+			c.PackageDB = r.Path(e.PackageDB)
+			s[i] = &c
+		}
+		out[k] = s
+	}
+	return out
+}
+
+func (r *Redaction) warnings(ws []Warning) []Warning {
+	if ws == nil {
+		return nil
+	}
+	out := make([]Warning, len(ws))
+	for i, w := range ws {
+		w.Message = r.Text(w.Message)
+		out[i] = w
+	}
+	return out
+}
+
+// IndexReport returns a redacted copy of the IndexReport "ir", with its
+// Redaction recorded. The copy shares the members that aren't redacted with
+// "ir", and "ir" isn't modified.
+func (r *Redaction) IndexReport(ir *IndexReport) *IndexReport {
+	if r == nil || ir == nil {
+		return ir
+	}
+	c := *ir
+	c.Packages = r.packages(ir.Packages)
+	c.Repositories = r.repositories(ir.Repositories)
+	c.Environments = r.environments(ir.Environments)
+	c.Err = r.Text(ir.Err)
+	c.LimitedSupport = r.Text(ir.LimitedSupport)
+	c.Warnings = r.warnings(ir.Warnings)
+	c.Redaction = r.Info()
+	return &c
+}
+
+// VulnerabilityReport returns a redacted copy of the VulnerabilityReport
+// "vr", with its Redaction recorded in the Metadata. The copy shares the
+// members that aren't redacted with "vr", and "vr" isn't modified.
+//
+// The vulnerabilities' packages and repositories are redacted the same way as
+// the report's, so they still correspond.
+func (r *Redaction) VulnerabilityReport(vr *VulnerabilityReport) *VulnerabilityReport {
+	if r == nil || vr == nil {
+		return vr
+	}
+	c := *vr
+	c.Packages = r.packages(vr.Packages)
+	c.Repositories = r.repositories(vr.Repositories)
+	c.Environments = r.environments(vr.Environments)
+	c.Warnings = r.warnings(vr.Warnings)
+	if vr.Vulnerabilities != nil {
+		c.Vulnerabilities = make(map[string]*Vulnerability, len(vr.Vulnerabilities))
+		for k, v := range vr.Vulnerabilities {
+			if v != nil {
+				cv := *v
+				cv.Package = r.Package(v.Package)
+				cv.Repo = r.Repository(v.Repo)
+				v = &cv
+			}
+			c.Vulnerabilities[k] = v
+		}
+	}
+	md := ReportMetadata{}
+	if vr.Metadata != nil {
+		md = *vr.Metadata
+	}
+	md.Redaction = r.Info()
+	c.Metadata = &md
+	return &c
+}
@@ -0,0 +1,270 @@
+package claircore
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// ReportDiff is the difference in findings between two VulnerabilityReports.
+//
+// A finding is a vulnerability affecting a package. Findings are the same
+// across reports if they're for the same package and have the same
+// vulnerability name and updater.
+type ReportDiff struct {
+	// Added are findings only in the newer report.
+	Added []Finding `json:"added"`
+	// Removed are findings only in the older report.
+	Removed []Finding `json:"removed"`
+	// SeverityChanged are findings in both reports whose severity differs.
+	SeverityChanged []FindingChange `json:"severity_changed"`
+	// EnrichmentsChanged are the enrichment types whose contents differ
+	// between the reports. It's only populated if requested.
+	EnrichmentsChanged []string `json:"enrichments_changed,omitempty"`
+}
+
+// Finding is a vulnerability affecting a package in a VulnerabilityReport.
+type Finding struct {
+	Package       *Package       `json:"package"`
+	Vulnerability *Vulnerability `json:"vulnerability"`
+}
+
+// FindingChange is a finding as it appears in both an older and a newer
+// report.
+type FindingChange struct {
+	Old Finding `json:"old"`
+	New Finding `json:"new"`
+}
+
+// DiffVulnerabilityReports reports the findings added, removed, and changed in
+// severity between the reports "prev" and "cur". Either report may be nil,
+// which is treated as a report with no findings.
+//
+// See ReportDiffer for how packages are matched between reports.
+func DiffVulnerabilityReports(prev, cur *VulnerabilityReport) ReportDiff {
+	return ReportDiffer{}.Diff(prev, cur)
+}
+
+// ReportDiffer computes ReportDiffs.
+//
+// Package IDs are only meaningful within the indexer that assigned them, so a
+// package is matched to the package with the same ID in the other report only
+// when both have the same name and version. Packages that can't be matched
+// by ID are matched by name and version, in ID order. An upgraded package is
+// a different package: its findings are removed from the old version and
+// added to the new one, even if the same vulnerability applies to both.
+type ReportDiffer struct {
+	// Enrichments reports enrichment types whose contents changed.
+	//
+	// Enrichments are compared by their compacted JSON encodings, ignoring
+	// order. Enrichments that refer to vulnerabilities by ID will appear
+	// changed whenever the reports' vulnerability IDs differ.
+	Enrichments bool
+}
+
+// Diff reports the difference between the reports "prev" and "cur". See
+// DiffVulnerabilityReports.
+func (d ReportDiffer) Diff(prev, cur *VulnerabilityReport) ReportDiff {
+	if prev == nil {
+		prev = &VulnerabilityReport{}
+	}
+	if cur == nil {
+		cur = &VulnerabilityReport{}
+	}
+	var out ReportDiff
+	pairs, gone, added := pairPackages(prev, cur)
+	for _, id := range gone {
+		out.Removed = append(out.Removed, findings(prev, id)...)
+	}
+	for _, id := range added {
+		out.Added = append(out.Added, findings(cur, id)...)
+	}
+	for _, p := range pairs {
+		fs := make(map[findingKey]Finding)
+		for _, f := range findings(prev, p.prev) {
+			fs[keyFinding(f)] = f
+		}
+		for _, f := range findings(cur, p.cur) {
+			k := keyFinding(f)
+			o, ok := fs[k]
+			if !ok {
+				out.Added = append(out.Added, f)
+				continue
+			}
+			delete(fs, k)
+			if o.Vulnerability.NormalizedSeverity != f.Vulnerability.NormalizedSeverity ||
+				o.Vulnerability.Severity != f.Vulnerability.Severity {
+				out.SeverityChanged = append(out.SeverityChanged, FindingChange{Old: o, New: f})
+			}
+		}
+		for _, f := range fs {
+			out.Removed = append(out.Removed, f)
+		}
+	}
+	sortFindings(out.Added)
+	sortFindings(out.Removed)
+	sort.Slice(out.SeverityChanged, func(i, j int) bool {
+		return lessFinding(out.SeverityChanged[i].New, out.SeverityChanged[j].New)
+	})
+	if d.Enrichments {
+		out.EnrichmentsChanged = diffEnrichments(prev.Enrichments, cur.Enrichments)
+	}
+	return out
+}
+
+// PackagePair is a package ID in an older and a newer report.
+type packagePair struct {
+	prev, cur string
+}
+
+// PairPackages matches the packages of two reports, reporting the pairs,
+// the unmatched package IDs of the older report, and the unmatched package
+// IDs of the newer report.
+func pairPackages(prev, cur *VulnerabilityReport) (pairs []packagePair, gone, added []string) {
+	type nv struct{ name, version string }
+	key := func(p *Package) nv { return nv{p.Name, p.Version} }
+	matched := make(map[string]bool)
+	for id, p := range cur.Packages {
+		if o, ok := prev.Packages[id]; ok && p != nil && o != nil && key(o) == key(p) {
+			pairs = append(pairs, packagePair{prev: id, cur: id})
+			matched[id] = true
+		}
+	}
+	byKey := make(map[nv][]string)
+	for _, id := range sortedIDs(prev.Packages) {
+		if matched[id] || prev.Packages[id] == nil {
+			continue
+		}
+		k := key(prev.Packages[id])
+		byKey[k] = append(byKey[k], id)
+	}
+	for _, id := range sortedIDs(cur.Packages) {
+		if matched[id] || cur.Packages[id] == nil {
+			continue
+		}
+		k := key(cur.Packages[id])
+		if ids := byKey[k]; len(ids) != 0 {
+			pairs = append(pairs, packagePair{prev: ids[0], cur: id})
+			byKey[k] = ids[1:]
+			continue
+		}
+		added = append(added, id)
+	}
+	for _, ids := range byKey {
+		gone = append(gone, ids...)
+	}
+	return pairs, gone, added
+}
+
+func sortedIDs(m map[string]*Package) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Findings returns the findings for the package "id" in the report.
+//
+// A vulnerability can be in a report more than once with the same name and
+// updater, such as when an updater has records for several repositories.
+// Only the most severe is returned, so each finding key appears once.
+func findings(r *VulnerabilityReport, id string) []Finding {
+	p := r.Packages[id]
+	if p == nil {
+		return nil
+	}
+	seen := make(map[findingKey]int)
+	var out []Finding
+	for _, vid := range r.PackageVulnerabilities[id] {
+		v := r.Vulnerabilities[vid]
+		if v == nil {
+			continue
+		}
+		f := Finding{Package: p, Vulnerability: v}
+		k := keyFinding(f)
+		i, ok := seen[k]
+		switch {
+		case !ok:
+			seen[k] = len(out)
+			out = append(out, f)
+		case v.NormalizedSeverity > out[i].Vulnerability.NormalizedSeverity:
+			out[i] = f
+		}
+	}
+	return out
+}
+
+// FindingKey identifies a finding within a package.
+type findingKey struct {
+	name, updater string
+}
+
+func keyFinding(f Finding) findingKey {
+	return findingKey{name: f.Vulnerability.Name, updater: f.Vulnerability.Updater}
+}
+
+func lessFinding(a, b Finding) bool {
+	switch {
+	case a.Package.Name != b.Package.Name:
+		return a.Package.Name < b.Package.Name
+	case a.Package.Version != b.Package.Version:
+		return a.Package.Version < b.Package.Version
+	case a.Package.ID != b.Package.ID:
+		return a.Package.ID < b.Package.ID
+	case a.Vulnerability.Name != b.Vulnerability.Name:
+		return a.Vulnerability.Name < b.Vulnerability.Name
+	}
+	return a.Vulnerability.Updater < b.Vulnerability.Updater
+}
+
+func sortFindings(fs []Finding) {
+	sort.Slice(fs, func(i, j int) bool { return lessFinding(fs[i], fs[j]) })
+}
+
+// DiffEnrichments reports the sorted enrichment types whose contents differ.
+func diffEnrichments(prev, cur map[string][]json.RawMessage) []string {
+	var out []string
+	for t, es := range cur {
+		if !sameEnrichments(prev[t], es) {
+			out = append(out, t)
+		}
+	}
+	for t, es := range prev {
+		if _, ok := cur[t]; !ok && len(es) != 0 {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameEnrichments(a, b []json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ca, cb := compactAll(a), compactAll(b)
+	for i := range ca {
+		if !bytes.Equal(ca[i], cb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompactAll returns the sorted, compacted encodings of the messages. Invalid
+// messages are used as-is.
+func compactAll(ms []json.RawMessage) [][]byte {
+	out := make([][]byte, len(ms))
+	for i, m := range ms {
+		var b bytes.Buffer
+		if err := json.Compact(&b, m); err != nil {
+			out[i] = m
+			continue
+		}
+		out[i] = b.Bytes()
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i], out[j]) < 0 })
+	return out
+}
@@ -0,0 +1,313 @@
+package claircore_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+// DiffReport builds a VulnerabilityReport from a table of package IDs to
+// packages and the vulnerability IDs affecting them.
+type diffReport struct {
+	pkgs  map[string]*claircore.Package
+	vulns map[string][]string
+	enr   map[string][]json.RawMessage
+}
+
+var diffVulns = map[string]*claircore.Vulnerability{
+	"a-high":   {ID: "a-high", Name: "CVE-2021-0001", Updater: "rhel", NormalizedSeverity: claircore.High, Severity: "Important"},
+	"a-low":    {ID: "a-low", Name: "CVE-2021-0001", Updater: "rhel", NormalizedSeverity: claircore.Low, Severity: "Low"},
+	"a-osv":    {ID: "a-osv", Name: "CVE-2021-0001", Updater: "osv", NormalizedSeverity: claircore.High, Severity: "HIGH"},
+	"b-fix-l":  {ID: "b-fix-l", Name: "CVE-2021-0002", Updater: "rhel", NormalizedSeverity: claircore.Medium, Severity: "Moderate", FixedInVersion: "1.1.1l"},
+	"c-fix-n":  {ID: "c-fix-n", Name: "CVE-2021-0003", Updater: "rhel", NormalizedSeverity: claircore.Medium, Severity: "Moderate", FixedInVersion: "1.1.1n"},
+	"c-fix-o":  {ID: "c-fix-o", Name: "CVE-2021-0003", Updater: "rhel", NormalizedSeverity: claircore.Medium, Severity: "Moderate", FixedInVersion: "1.1.1o"},
+	"d-new":    {ID: "d-new", Name: "CVE-2021-0004", Updater: "rhel", NormalizedSeverity: claircore.Critical, Severity: "Critical"},
+	"a-high-2": {ID: "a-high-2", Name: "CVE-2021-0001", Updater: "rhel", NormalizedSeverity: claircore.High, Severity: "Important"},
+}
+
+func (d diffReport) report() *claircore.VulnerabilityReport {
+	r := claircore.VulnerabilityReport{
+		Packages:               make(map[string]*claircore.Package),
+		Vulnerabilities:        make(map[string]*claircore.Vulnerability),
+		PackageVulnerabilities: d.vulns,
+		Enrichments:            d.enr,
+	}
+	for id, p := range d.pkgs {
+		p := *p
+		p.ID = id
+		r.Packages[id] = &p
+	}
+	for _, ids := range d.vulns {
+		for _, id := range ids {
+			r.Vulnerabilities[id] = diffVulns[id]
+		}
+	}
+	return &r
+}
+
+func pkg(name, version string) *claircore.Package {
+	return &claircore.Package{Name: name, Version: version, Kind: claircore.BINARY}
+}
+
+// Summary is a condensed ReportDiff for comparisons.
+type diffSummary struct {
+	Added, Removed, Changed, Enrichments []string
+}
+
+func summarize(d claircore.ReportDiff) diffSummary {
+	f := func(f claircore.Finding) string {
+		return fmt.Sprintf("%s/%s %s %s (%s)", f.Package.Name, f.Package.Version, f.Vulnerability.Name, f.Vulnerability.Updater, f.Vulnerability.Severity)
+	}
+	var s diffSummary
+	for _, x := range d.Added {
+		s.Added = append(s.Added, f(x))
+	}
+	for _, x := range d.Removed {
+		s.Removed = append(s.Removed, f(x))
+	}
+	for _, x := range d.SeverityChanged {
+		s.Changed = append(s.Changed, f(x.Old)+" -> "+f(x.New))
+	}
+	s.Enrichments = d.EnrichmentsChanged
+	return s
+}
+
+func TestDiffVulnerabilityReports(t *testing.T) {
+	openssl := pkg("openssl", "1.1.1k")
+	tt := []struct {
+		name        string
+		prev, cur   *diffReport
+		enrichments bool
+		want        diffSummary
+	}{
+		{
+			name: "Nil",
+		},
+		{
+			name: "NilPrevious",
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high", "b-fix-l"}},
+			},
+			want: diffSummary{
+				Added: []string{
+					"openssl/1.1.1k CVE-2021-0001 rhel (Important)",
+					"openssl/1.1.1k CVE-2021-0002 rhel (Moderate)",
+				},
+			},
+		},
+		{
+			name: "NilCurrent",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high"}},
+			},
+			want: diffSummary{
+				Removed: []string{"openssl/1.1.1k CVE-2021-0001 rhel (Important)"},
+			},
+		},
+		{
+			name: "Identical",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high", "b-fix-l"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"b-fix-l", "a-high"}},
+			},
+		},
+		{
+			name: "RenumberedPackages",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl, "2": pkg("zlib", "1.2.11")},
+				vulns: map[string][]string{"1": {"a-high"}, "2": {"b-fix-l"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"2": openssl, "7": pkg("zlib", "1.2.11")},
+				vulns: map[string][]string{"2": {"a-high"}, "7": {"b-fix-l"}},
+			},
+		},
+		{
+			name: "RenumberedVulnerabilities",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high-2"}},
+			},
+		},
+		{
+			name: "SeverityChanged",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-low", "b-fix-l"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high", "b-fix-l"}},
+			},
+			want: diffSummary{
+				Changed: []string{"openssl/1.1.1k CVE-2021-0001 rhel (Low) -> openssl/1.1.1k CVE-2021-0001 rhel (Important)"},
+			},
+		},
+		{
+			name: "AddedAndRemoved",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high", "b-fix-l"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high", "d-new"}},
+			},
+			want: diffSummary{
+				Added:   []string{"openssl/1.1.1k CVE-2021-0004 rhel (Critical)"},
+				Removed: []string{"openssl/1.1.1k CVE-2021-0002 rhel (Moderate)"},
+			},
+		},
+		{
+			name: "DifferentUpdater",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-osv"}},
+			},
+			want: diffSummary{
+				Added:   []string{"openssl/1.1.1k CVE-2021-0001 osv (HIGH)"},
+				Removed: []string{"openssl/1.1.1k CVE-2021-0001 rhel (Important)"},
+			},
+		},
+		{
+			// The upgrade fixes CVE-2021-0002, and CVE-2021-0003 now applies
+			// through a different record. The new version is a different
+			// package, so all its findings are new.
+			name: "Upgrade",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"b-fix-l", "c-fix-n"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": pkg("openssl", "1.1.1m")},
+				vulns: map[string][]string{"1": {"c-fix-o"}},
+			},
+			want: diffSummary{
+				Added: []string{"openssl/1.1.1m CVE-2021-0003 rhel (Moderate)"},
+				Removed: []string{
+					"openssl/1.1.1k CVE-2021-0002 rhel (Moderate)",
+					"openssl/1.1.1k CVE-2021-0003 rhel (Moderate)",
+				},
+			},
+		},
+		{
+			// Two copies of a package, such as in different package
+			// databases, are matched up even if their IDs change.
+			name: "DuplicatePackages",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl, "2": openssl},
+				vulns: map[string][]string{"1": {"a-high"}, "2": {"a-high"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"3": openssl, "4": openssl},
+				vulns: map[string][]string{"3": {"a-high"}, "4": {"a-high"}},
+			},
+		},
+		{
+			name: "DuplicatePackageRemoved",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl, "2": openssl},
+				vulns: map[string][]string{"1": {"a-high"}, "2": {"a-high"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"2": openssl},
+				vulns: map[string][]string{"2": {"a-high"}},
+			},
+			want: diffSummary{
+				Removed: []string{"openssl/1.1.1k CVE-2021-0001 rhel (Important)"},
+			},
+		},
+		{
+			// A package ID reused for a different package isn't a match.
+			name: "ReusedID",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl, "2": pkg("zlib", "1.2.11")},
+				vulns: map[string][]string{"1": {"a-high"}, "2": {"b-fix-l"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": pkg("zlib", "1.2.11"), "2": openssl},
+				vulns: map[string][]string{"1": {"b-fix-l"}, "2": {"a-high"}},
+			},
+		},
+		{
+			// The most severe of a vulnerability's records is used.
+			name: "DuplicateVulnerabilities",
+			prev: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-low", "a-high"}},
+			},
+			cur: &diffReport{
+				pkgs:  map[string]*claircore.Package{"1": openssl},
+				vulns: map[string][]string{"1": {"a-high"}},
+			},
+		},
+		{
+			name: "EnrichmentsIgnored",
+			prev: &diffReport{
+				enr: map[string][]json.RawMessage{"cvss": {json.RawMessage(`{"a":1}`)}},
+			},
+			cur: &diffReport{
+				enr: map[string][]json.RawMessage{"cvss": {json.RawMessage(`{"a":2}`)}},
+			},
+		},
+		{
+			name: "Enrichments",
+			prev: &diffReport{
+				enr: map[string][]json.RawMessage{
+					"cvss":    {json.RawMessage(`{"a":1}`), json.RawMessage(`{"b":1}`)},
+					"epss":    {json.RawMessage(`{"a":0.5}`)},
+					"release": {json.RawMessage(`{}`)},
+				},
+			},
+			cur: &diffReport{
+				enr: map[string][]json.RawMessage{
+					"cvss": {json.RawMessage(`{"b": 1}`), json.RawMessage(`{"a": 1}`)},
+					"epss": {json.RawMessage(`{"a":0.6}`)},
+					"kev":  {json.RawMessage(`{}`)},
+				},
+			},
+			enrichments: true,
+			want: diffSummary{
+				Enrichments: []string{"epss", "kev", "release"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var prev, cur *claircore.VulnerabilityReport
+			if tc.prev != nil {
+				prev = tc.prev.report()
+			}
+			if tc.cur != nil {
+				cur = tc.cur.report()
+			}
+			got := summarize(claircore.ReportDiffer{Enrichments: tc.enrichments}.Diff(prev, cur))
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+			if !tc.enrichments {
+				if got := summarize(claircore.DiffVulnerabilityReports(prev, cur)); !cmp.Equal(got, tc.want) {
+					t.Error(cmp.Diff(got, tc.want))
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/purl"
 )
 
 // Coalescer takes individual layer artifacts and coalesces them to form the final image's
@@ -34,13 +35,15 @@ func (c *Coalescer) Coalesce(ctx context.Context, artifacts []*indexer.LayerArti
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	// Share repositories with layers where definition is missing
-	c.shareRepos(ctx, artifacts)
+	// Record the layer each repository is actually found in before sharing
+	// them, so shared repositories are attributed to their introducing layer.
 	for _, a := range artifacts {
 		for _, repo := range a.Repos {
-			c.ir.Repositories[repo.ID] = repo
+			c.ir.AddRepository(repo, a.Hash)
 		}
 	}
+	// Share repositories with layers where definition is missing
+	c.shareRepos(ctx, artifacts)
 	// In our coalescing logic if a Distribution is found in layer (n) all packages found
 	// in layers 0-(n) will be associated with this layer. This is a heuristic.
 	// Let's do a search for the first Distribution we find and use a variable
@@ -52,7 +55,7 @@ func (c *Coalescer) Coalesce(ctx context.Context, artifacts []*indexer.LayerArti
 	for _, a := range artifacts {
 		if len(a.Dist) != 0 {
 			currDist = a.Dist[0]
-			c.ir.Distributions[currDist.ID] = currDist
+			c.ir.AddDistribution(currDist, a.Hash)
 			break
 		}
 	}
@@ -71,7 +74,7 @@ func (c *Coalescer) Coalesce(ctx context.Context, artifacts []*indexer.LayerArti
 		// check if we need to update our currDist
 		if len(layerArtifacts.Dist) != 0 {
 			currDist = layerArtifacts.Dist[0]
-			c.ir.Distributions[currDist.ID] = currDist
+			c.ir.AddDistribution(currDist, layerArtifacts.Hash)
 		}
 		// associate packages with their environments
 		if len(layerArtifacts.Pkgs) != 0 {
@@ -113,21 +116,38 @@ func (c *Coalescer) Coalesce(ctx context.Context, artifacts []*indexer.LayerArti
 	// from list of packages
 	// If a package is available in all layers it means that it should be added
 	// to list of packages and associate an environment for it.
+	// Packages are tracked per package database, so that a package present in
+	// more than one database gets an environment for each of them.
+	type dbPackage struct {
+		db, id string
+	}
+	processed := make(map[dbPackage]struct{})
+	layerDBs := make([]map[string]struct{}, len(artifacts))
+	for i, a := range artifacts {
+		layerDBs[i] = make(map[string]struct{})
+		for _, pkg := range a.Pkgs {
+			layerDBs[i][pkg.PackageDB] = struct{}{}
+		}
+	}
 	for i := 0; i < len(artifacts); i++ {
 		currentLayerArtifacts := artifacts[i]
 		if len(currentLayerArtifacts.Pkgs) == 0 {
 			continue
 		}
 		for _, currentPkg := range currentLayerArtifacts.Pkgs {
-			if _, ok := c.ir.Packages[currentPkg.ID]; ok {
+			key := dbPackage{db: currentPkg.PackageDB, id: currentPkg.ID}
+			if _, ok := processed[key]; ok {
 				// the package was already processed in previous layers
 				continue
 			}
+			processed[key] = struct{}{}
 			// for each package let's find out if it is also available in other layers dbs
 			found := true
 			for j := i + 1; j < len(artifacts); j++ {
 				nextLayerArtifacts := artifacts[j]
-				if len(nextLayerArtifacts.Pkgs) == 0 {
+				// Only a layer with a copy of this package database can
+				// have changed it.
+				if _, ok := layerDBs[j][currentPkg.PackageDB]; !ok {
 					continue
 				}
 				found = false
@@ -139,8 +159,10 @@ func (c *Coalescer) Coalesce(ctx context.Context, artifacts []*indexer.LayerArti
 				}
 			}
 			if found {
+				env := dbs[currentPkg.PackageDB].environments[currentPkg.ID]
+				currentPkg.PURL = purl.RPM(currentPkg, c.ir.Distributions[env.DistributionID], nil)
 				c.ir.Packages[currentPkg.ID] = currentPkg
-				c.ir.Environments[currentPkg.ID] = append(c.ir.Environments[currentPkg.ID], dbs[currentPkg.PackageDB].environments[currentPkg.ID])
+				c.ir.Environments[currentPkg.ID] = append(c.ir.Environments[currentPkg.ID], env)
 			}
 		}
 	}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -157,6 +158,64 @@ func TestCoalescerCPERepos(t *testing.T) {
 	}
 }
 
+// TestCoalescerIntroducedIn checks that repositories and distributions are
+// attributed to the layer they're first found in, including repositories
+// shared with layers that lack a definition of their own.
+func TestCoalescerIntroducedIn(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	coalescer := NewCoalescer()
+	base := &claircore.Repository{
+		ID:   "1",
+		Name: "rhel-8-for-x86_64-baseos-rpms",
+		Key:  RedHatRepositoryKey,
+	}
+	app := &claircore.Repository{
+		ID:   "2",
+		Name: "rhel-8-for-x86_64-appstream-rpms",
+		Key:  RedHatRepositoryKey,
+	}
+	pkgs := test.GenUniquePackages(3)
+	dists := test.GenUniqueDistributions(2) // we will discard dist 0 due to zero value ambiguity
+	// A base image with a definition for its repository, an application
+	// layer without one, and a layer adding a repository.
+	layerArtifacts := []*indexer.LayerArtifacts{
+		{
+			Hash:  test.RandomSHA256Digest(t),
+			Pkgs:  pkgs[0:1],
+			Dist:  dists[1:],
+			Repos: []*claircore.Repository{base},
+		},
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: pkgs[0:2],
+		},
+		{
+			Hash:  test.RandomSHA256Digest(t),
+			Pkgs:  pkgs[0:3],
+			Repos: []*claircore.Repository{app},
+		},
+	}
+	ir, err := coalescer.Coalesce(ctx, layerArtifacts)
+	if err != nil {
+		t.Fatalf("received error from coalesce method: %v", err)
+	}
+	want := map[string]claircore.Digest{
+		base.ID: layerArtifacts[0].Hash,
+		app.ID:  layerArtifacts[2].Hash,
+	}
+	if got := ir.RepositoriesIntroducedIn; !cmp.Equal(got, want, cmp.AllowUnexported(claircore.Digest{})) {
+		t.Error(cmp.Diff(got, want, cmp.AllowUnexported(claircore.Digest{})))
+	}
+	// The application layer's package is still associated with the shared
+	// repository.
+	if got := ir.Environments["1"][0].RepositoryIDs; len(got) != 1 || got[0] != base.ID {
+		t.Errorf("expected repository ids [%s] but got %v", base.ID, got)
+	}
+	if got, want := ir.DistributionsIntroducedIn[dists[1].ID], layerArtifacts[0].Hash; got.String() != want.String() {
+		t.Errorf("distribution introduced in %v, want %v", got, want)
+	}
+}
+
 func TestCoalescerUpdatedPackage(t *testing.T) {
 	ctx := zlog.Test(context.Background(), t)
 	coalescer := NewCoalescer()
@@ -339,3 +398,86 @@ func TestCoalescerRemovedPackage(t *testing.T) {
 		t.Fatalf("Package %v was removed, but it is still available in environment", pkg1)
 	}
 }
+
+// TestCoalescerPackageDatabases checks that the same package in different
+// package databases is tracked separately, and that changes to one database
+// don't affect the other.
+func TestCoalescerPackageDatabases(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	coalescer := NewCoalescer()
+	const (
+		hostDB   = "/var/lib/rpm"
+		chrootDB = "/mnt/sysimage/var/lib/rpm"
+	)
+	hostFoo := &claircore.Package{
+		ID:        "1",
+		Name:      "foo",
+		Version:   "1.0-1",
+		PackageDB: hostDB,
+	}
+	chrootFoo := &claircore.Package{
+		ID:        "1",
+		Name:      "foo",
+		Version:   "1.0-1",
+		PackageDB: chrootDB,
+	}
+	chrootBar := &claircore.Package{
+		ID:        "2",
+		Name:      "bar",
+		Version:   "1.0-1",
+		PackageDB: chrootDB,
+	}
+	hostBaz := &claircore.Package{
+		ID:        "3",
+		Name:      "baz",
+		Version:   "1.0-1",
+		PackageDB: hostDB,
+	}
+	layerArtifacts := []*indexer.LayerArtifacts{
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{hostFoo, chrootFoo, hostBaz},
+		},
+		{
+			// This layer only touches the chroot's database, removing "foo"
+			// from it.
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{chrootBar},
+		},
+	}
+	ir, err := coalescer.Coalesce(ctx, layerArtifacts)
+	if err != nil {
+		t.Fatalf("received error from coalesce method: %v", err)
+	}
+	want := map[string][]string{
+		"1": {hostDB},
+		"2": {chrootDB},
+		"3": {hostDB},
+	}
+	for id, dbs := range want {
+		envs := ir.Environments[id]
+		if len(envs) != len(dbs) {
+			t.Errorf("package %s: got %d environments, want %d", id, len(envs), len(dbs))
+			continue
+		}
+		for i, env := range envs {
+			if env.PackageDB != dbs[i] {
+				t.Errorf("package %s: got database %q, want %q", id, env.PackageDB, dbs[i])
+			}
+		}
+	}
+
+	// With both databases untouched, "foo" has an environment for each.
+	coalescer = NewCoalescer()
+	ir, err = coalescer.Coalesce(ctx, layerArtifacts[:1])
+	if err != nil {
+		t.Fatalf("received error from coalesce method: %v", err)
+	}
+	got := map[string]bool{}
+	for _, env := range ir.Environments["1"] {
+		got[env.PackageDB] = true
+	}
+	if !got[hostDB] || !got[chrootDB] || len(got) != 2 {
+		t.Errorf("got databases %v, want %q and %q", got, hostDB, chrootDB)
+	}
+}
@@ -0,0 +1,122 @@
+package rhel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// Ostree-based systems (Red Hat Enterprise Linux CoreOS) ship os-release in
+// /usr and point /etc/os-release at it, and don't carry the content manifests
+// that container images do.
+const usrOSReleasePath = `usr/lib/os-release`
+
+// CoreOS is what an os-release file says about a Red Hat Enterprise Linux
+// CoreOS system.
+type coreOS struct {
+	// Release is the RHEL major version the system is built from.
+	release Release
+	// Openshift is the OpenShift version the system ships with, if known.
+	openshift string
+}
+
+// ParseCoreOS reports the RHCOS system described by the os-release file
+// contents "b", or nil if "b" doesn't describe one.
+func parseCoreOS(b []byte) *coreOS {
+	kv := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(l, '=')
+		if i == -1 {
+			continue
+		}
+		v := l[i+1:]
+		if u, err := strconv.Unquote(v); err == nil {
+			v = u
+		} else {
+			v = strings.Trim(v, `"'`)
+		}
+		kv[l[:i]] = v
+	}
+	switch {
+	case kv["ID"] == "rhcos":
+	case kv["ID"] == "rhel" && kv["VARIANT_ID"] == "coreos":
+	default:
+		return nil
+	}
+
+	c := coreOS{openshift: kv["OPENSHIFT_VERSION"]}
+	// Prefer the explicit RHEL version, then the CPE, then the platform.
+	var v string
+	switch {
+	case kv["RHEL_VERSION"] != "":
+		v = kv["RHEL_VERSION"]
+	case strings.HasPrefix(kv["CPE_NAME"], "cpe:/o:redhat:enterprise_linux:"):
+		v = strings.TrimPrefix(kv["CPE_NAME"], "cpe:/o:redhat:enterprise_linux:")
+	case strings.HasPrefix(kv["PLATFORM_ID"], "platform:el"):
+		v = strings.TrimPrefix(kv["PLATFORM_ID"], "platform:el")
+	}
+	if i := strings.IndexAny(v, ".:"); i != -1 {
+		v = v[:i]
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return nil
+	}
+	c.release = Release(n)
+	return &c
+}
+
+// CPEs returns the CPEs of the repositories an RHCOS system is built from.
+func (c *coreOS) CPEs() []string {
+	r := int(c.release)
+	cpes := []string{
+		fmt.Sprintf("cpe:/o:redhat:enterprise_linux:%d::baseos", r),
+		fmt.Sprintf("cpe:/a:redhat:enterprise_linux:%d::appstream", r),
+	}
+	if c.openshift != "" {
+		cpes = append(cpes, fmt.Sprintf("cpe:/a:redhat:openshift:%s::el%d", c.openshift, r))
+	}
+	return cpes
+}
+
+// FindCoreOS looks for an RHCOS os-release file in the layer.
+//
+// A nil *coreOS and error are returned if there isn't one.
+func findCoreOS(ctx context.Context, l *claircore.Layer) (*coreOS, error) {
+	files, err := l.Files(osReleasePath, usrOSReleasePath)
+	switch {
+	case err == nil:
+	case errors.Is(err, claircore.ErrNotFound):
+		return nil, nil
+	default:
+		return nil, err
+	}
+	for _, p := range []string{osReleasePath, usrOSReleasePath} {
+		b, ok := files[p]
+		if !ok {
+			continue
+		}
+		if c := parseCoreOS(b.Bytes()); c != nil {
+			zlog.Debug(ctx).
+				Str("path", p).
+				Int("release", int(c.release)).
+				Str("openshift", c.openshift).
+				Msg("found RHCOS os-release")
+			return c, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+package rhel
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+// TestCoreOS scans an RHCOS layer exported from an ostree commit and checks
+// that its packages match RHEL advisories for the RHEL release it's built
+// from.
+func TestCoreOS(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := &claircore.Layer{}
+	if err := l.SetLocal("testdata/layer-rhcos.tar"); err != nil {
+		t.Fatal(err)
+	}
+
+	dists, err := (&DistributionScanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*claircore.Distribution{rhel9Dist}; !cmp.Equal(dists, want) {
+		t.Error(cmp.Diff(dists, want))
+	}
+
+	// The scanner isn't configured, so the layer's os-release is the only
+	// source of repositories.
+	repos, err := (&RepositoryScanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	var want []*claircore.Repository
+	for _, n := range []string{
+		"cpe:/a:redhat:enterprise_linux:9::appstream",
+		"cpe:/a:redhat:openshift:4.14::el9",
+		"cpe:/o:redhat:enterprise_linux:9::baseos",
+	} {
+		want = append(want, &claircore.Repository{
+			Name: n,
+			Key:  RedHatRepositoryKey,
+			CPE:  cpe.MustUnbind(n),
+		})
+	}
+	if !cmp.Equal(repos, want) {
+		t.Fatal(cmp.Diff(repos, want))
+	}
+
+	m := &Matcher{}
+	advisory := func(repo, fixed string) *claircore.Vulnerability {
+		return &claircore.Vulnerability{
+			Package:        &claircore.Package{Name: "openssl"},
+			FixedInVersion: fixed,
+			Repo: &claircore.Repository{
+				Name: repo,
+				Key:  RedHatRepositoryKey,
+			},
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		v    *claircore.Vulnerability
+		want bool
+	}{
+		{"Fixed", advisory("cpe:/o:redhat:enterprise_linux:9::baseos", "1:3.0.7-18.el9_2"), true},
+		{"FixedMinor", advisory("cpe:/o:redhat:enterprise_linux:9.2::baseos", "1:3.0.7-18.el9_2"), true},
+		{"AlreadyFixed", advisory("cpe:/o:redhat:enterprise_linux:9::baseos", "1:3.0.7-16.el9_2"), false},
+		{"Openshift", advisory("cpe:/a:redhat:openshift:4.14::el9", "1:3.0.7-18.el9_2"), true},
+		{"OtherOpenshift", advisory("cpe:/a:redhat:openshift:4.13::el9", "1:3.0.7-18.el9_2"), false},
+		{"RHEL8", advisory("cpe:/o:redhat:enterprise_linux:8::baseos", "1:3.0.7-18.el9_2"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bool
+			for _, r := range repos {
+				record := &claircore.IndexRecord{
+					Package: &claircore.Package{
+						Name:    "openssl",
+						Version: "1:3.0.7-16.el9_2",
+					},
+					Distribution: dists[0],
+					Repository:   r,
+				}
+				if !m.Filter(record) {
+					t.Fatalf("record filtered out: %+v", r)
+				}
+				ok, err := m.Vulnerable(ctx, record, tc.v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = got || ok
+			}
+			if got != tc.want {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
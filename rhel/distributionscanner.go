@@ -19,7 +19,7 @@ const rhReleasePath = `etc/redhat-release`
 
 const (
 	scannerName    = "rhel"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
@@ -58,6 +58,10 @@ var rhelRegexes = []rhelRegex{
 		release: RHEL8,
 		regexp:  regexp.MustCompile(`Red Hat Enterprise Linux (Server)?\s*(release)?\s*8(\.\d)?`),
 	},
+	{
+		release: RHEL9,
+		regexp:  regexp.MustCompile(`Red Hat Enterprise Linux (Server)?\s*(release)?\s*9(\.\d)?`),
+	},
 }
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
@@ -89,7 +93,7 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath, rhReleasePath)
+	files, err := l.Files(osReleasePath, usrOSReleasePath, rhReleasePath)
 	if err != nil {
 		zlog.Debug(ctx).Msg("didn't find an os-release or redhat-release file")
 		return nil, nil
@@ -108,6 +112,11 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 //
 // separated into its own method to aid testing.
 func (ds *DistributionScanner) parse(buff *bytes.Buffer) *claircore.Distribution {
+	// RHCOS calls itself "Red Hat Enterprise Linux CoreOS" and is versioned
+	// with OpenShift, so use the RHEL release it's built from.
+	if c := parseCoreOS(buff.Bytes()); c != nil {
+		return releaseToDist(c.release)
+	}
 	for _, ur := range rhelRegexes {
 		if ur.regexp.Match(buff.Bytes()) {
 			return releaseToDist(ur.release)
@@ -46,6 +46,35 @@ REDHAT_BUGZILLA_PRODUCT="Red Hat Enterprise Linux 8"
 REDHAT_BUGZILLA_PRODUCT_VERSION=8.1
 REDHAT_SUPPORT_PRODUCT="Red Hat Enterprise Linux"
 REDHAT_SUPPORT_PRODUCT_VERSION="8.1"`)
+var rhel9RHRelease []byte = []byte(`Red Hat Enterprise Linux release 9.2 (Plow)`)
+var rhcos412OSRelease []byte = []byte(`NAME="Red Hat Enterprise Linux CoreOS"
+VERSION="412.86.202306132230-0"
+ID="rhcos"
+ID_LIKE="rhel fedora"
+VERSION_ID="4.12"
+PLATFORM_ID="platform:el8"
+PRETTY_NAME="Red Hat Enterprise Linux CoreOS 412.86.202306132230-0 (Ootpa)"
+ANSI_COLOR="0;31"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:8::coreos"
+HOME_URL="https://www.redhat.com/"
+BUG_REPORT_URL="https://bugzilla.redhat.com/"
+REDHAT_BUGZILLA_PRODUCT="OpenShift Container Platform"
+REDHAT_BUGZILLA_PRODUCT_VERSION="4.12"
+REDHAT_SUPPORT_PRODUCT="OpenShift Container Platform"
+REDHAT_SUPPORT_PRODUCT_VERSION="4.12"
+OPENSHIFT_VERSION="4.12"
+RHEL_VERSION="8.6"
+OSTREE_VERSION="412.86.202306132230-0"`)
+var rhcos414OSRelease []byte = []byte(`NAME="Red Hat Enterprise Linux CoreOS"
+ID="rhcos"
+ID_LIKE="rhel fedora"
+VERSION_ID="4.14"
+VARIANT="CoreOS"
+VARIANT_ID=coreos
+PLATFORM_ID="platform:el9"
+PRETTY_NAME="Red Hat Enterprise Linux CoreOS 414.92.202310210434-0 (Plow)"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:9::coreos"
+OPENSHIFT_VERSION="4.14"`)
 
 func TestDistributionScanner(t *testing.T) {
 	table := []struct {
@@ -93,6 +122,21 @@ func TestDistributionScanner(t *testing.T) {
 			release: RHEL8,
 			file:    rhel8OSRelease,
 		},
+		{
+			name:    "RHEL9",
+			release: RHEL9,
+			file:    rhel9RHRelease,
+		},
+		{
+			name:    "RHCOS 4.12",
+			release: RHEL8,
+			file:    rhcos412OSRelease,
+		},
+		{
+			name:    "RHCOS 4.14",
+			release: RHEL9,
+			file:    rhcos414OSRelease,
+		},
 	}
 	for _, tt := range table {
 		t.Run(tt.name, func(t *testing.T) {
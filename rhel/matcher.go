@@ -2,11 +2,13 @@ package rhel
 
 import (
 	"context"
+	"strings"
 
 	version "github.com/knqyf263/go-rpm-version"
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/cpe"
 )
 
 // Matcher implements driver.Matcher.
@@ -29,12 +31,23 @@ func (*Matcher) Filter(record *claircore.IndexRecord) bool {
 func (*Matcher) Query() []driver.MatchConstraint {
 	return []driver.MatchConstraint{
 		driver.PackageModule,
-		driver.RepositoryName,
+		driver.RepositoryCPEProduct,
 	}
 }
 
 // Vulnerable implements driver.Matcher.
+//
+// The database only narrows the vulnerabilities down to those for the
+// repository's product. The rest of the repository's CPE is compared to the
+// vulnerability's here, so that repositories and advisories naming the same
+// product at different specificities still match.
 func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	if vuln.Repo == nil || vuln.Repo.Key != RedHatRepositoryKey {
+		return false, nil
+	}
+	if !cpeMatch(record.Repository, vuln.Repo) {
+		return false, nil
+	}
 	pkgVer, vulnVer := version.NewVersion(record.Package.Version), version.NewVersion(vuln.Package.Version)
 	// Assume the vulnerability record we have is for the last known vulnerable
 	// version, so greater versions aren't vulnerable.
@@ -51,3 +64,50 @@ func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord,
 	// compare version and architecture
 	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.Cmp(record.Package.Arch, vuln.Package.Arch), nil
 }
+
+// CpeMatch reports whether the repository's CPE and the advisory's CPE name the
+// same product according to CPE name matching, allowing either one to be more
+// specific than the other.
+//
+// If either CPE only has a major version, the other's version is compared at
+// the same specificity, so "enterprise_linux:8" matches advisories for
+// "enterprise_linux:8.2", and "enterprise_linux:8.6" matches advisories for
+// all of "enterprise_linux:8".
+func cpeMatch(repo, advisory *claircore.Repository) bool {
+	r, a := repoCPE(repo), repoCPE(advisory)
+	if r.Valid() != nil || a.Valid() != nil {
+		return repo.Name == advisory.Name
+	}
+	rv, av := &r.Attr[cpe.Version], &a.Attr[cpe.Version]
+	if rv.Kind == cpe.ValueSet && av.Kind == cpe.ValueSet {
+		switch {
+		case !strings.Contains(rv.V, `\.`):
+			av.V = majorVersion(av.V)
+		case !strings.Contains(av.V, `\.`):
+			rv.V = majorVersion(rv.V)
+		}
+	}
+	rel := cpe.Compare(r, a)
+	return rel.IsSuperset() || rel.IsSubset()
+}
+
+// MajorVersion returns the major version of the CPE-escaped version "v".
+func majorVersion(v string) string {
+	if i := strings.Index(v, `\.`); i != -1 {
+		return v[:i]
+	}
+	return v
+}
+
+// RepoCPE returns the repository's CPE, unbinding it from the repository's
+// name if the CPE isn't populated.
+func repoCPE(r *claircore.Repository) cpe.WFN {
+	if err := r.CPE.Valid(); err == nil {
+		return r.CPE
+	}
+	w, err := cpe.Unbind(r.Name)
+	if err != nil {
+		return cpe.WFN{}
+	}
+	return w
+}
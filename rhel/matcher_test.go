@@ -91,34 +91,53 @@ type vulnerableTestCase struct {
 }
 
 func TestVulnerable(t *testing.T) {
+	repo := &claircore.Repository{
+		Name: "cpe:/o:redhat:enterprise_linux:8::baseos",
+		Key:  RedHatRepositoryKey,
+	}
 	record := &claircore.IndexRecord{
 		Package: &claircore.Package{
 			Version: "0.33.0-6.el8",
 		},
+		Repository: repo,
 	}
 	fixedVulnPast := &claircore.Vulnerability{
 		Package: &claircore.Package{
 			Version: "",
 		},
 		FixedInVersion: "0.33.0-5.el8",
+		Repo:           repo,
 	}
 	fixedVulnCurrent := &claircore.Vulnerability{
 		Package: &claircore.Package{
 			Version: "",
 		},
 		FixedInVersion: "0.33.0-6.el8",
+		Repo:           repo,
 	}
 	fixedVulnFuture := &claircore.Vulnerability{
 		Package: &claircore.Package{
 			Version: "",
 		},
 		FixedInVersion: "0.33.0-7.el8",
+		Repo:           repo,
 	}
 	unfixedVuln := &claircore.Vulnerability{
 		Package: &claircore.Package{
 			Version: "",
 		},
 		FixedInVersion: "",
+		Repo:           repo,
+	}
+	otherRepoVuln := &claircore.Vulnerability{
+		Package: &claircore.Package{
+			Version: "",
+		},
+		FixedInVersion: "0.33.0-7.el8",
+		Repo: &claircore.Repository{
+			Name: "cpe:/o:redhat:enterprise_linux:7",
+			Key:  RedHatRepositoryKey,
+		},
 	}
 
 	var testCases = []vulnerableTestCase{
@@ -126,6 +145,7 @@ func TestVulnerable(t *testing.T) {
 		{ir: record, v: fixedVulnCurrent, want: false, name: "vuln fixed in current version"},
 		{ir: record, v: fixedVulnFuture, want: true, name: "outdated package"},
 		{ir: record, v: unfixedVuln, want: true, name: "unfixed vuln"},
+		{ir: record, v: otherRepoVuln, want: false, name: "vuln in other repository"},
 	}
 
 	m := &Matcher{}
@@ -140,3 +160,41 @@ func TestVulnerable(t *testing.T) {
 		}
 	}
 }
+
+func TestCPEMatch(t *testing.T) {
+	// These combinations are taken from the repository-to-cpe mapping and the
+	// CPEs present in the OVAL data.
+	tt := []struct {
+		Repo, Advisory string
+		Want           bool
+	}{
+		{"cpe:/o:redhat:enterprise_linux:8::baseos", "cpe:/o:redhat:enterprise_linux:8::baseos", true},
+		{"cpe:/o:redhat:enterprise_linux:8::baseos", "cpe:/o:redhat:enterprise_linux:8", true},
+		{"cpe:/a:redhat:enterprise_linux:8::appstream", "cpe:/a:redhat:enterprise_linux:8", true},
+		{"cpe:/a:redhat:enterprise_linux:8::appstream", "cpe:/a:redhat:enterprise_linux:8::baseos", false},
+		{"cpe:/a:redhat:enterprise_linux:8::appstream", "cpe:/o:redhat:enterprise_linux:8", false},
+		{"cpe:/o:redhat:enterprise_linux:9", "cpe:/o:redhat:enterprise_linux:9::baseos", true},
+		{"cpe:/o:redhat:enterprise_linux:9", "cpe:/a:redhat:enterprise_linux:9::appstream", false},
+		{"cpe:/o:redhat:enterprise_linux:7::server", "cpe:/o:redhat:enterprise_linux:7", true},
+		{"cpe:/o:redhat:enterprise_linux:7::server", "cpe:/o:redhat:enterprise_linux:7::client", false},
+		{"cpe:/o:redhat:enterprise_linux:7", "cpe:/o:redhat:enterprise_linux:8", false},
+		{"cpe:/o:redhat:enterprise_linux:8", "cpe:/o:redhat:enterprise_linux:8.2::baseos", true},
+		{"cpe:/o:redhat:enterprise_linux:8.4", "cpe:/o:redhat:enterprise_linux:8.2", false},
+		{"cpe:/o:redhat:enterprise_linux:8.4", "cpe:/o:redhat:enterprise_linux:8", true},
+		{"cpe:/o:redhat:enterprise_linux:8.6::baseos", "cpe:/o:redhat:enterprise_linux:8", true},
+		{"cpe:/o:redhat:enterprise_linux:8.6::baseos", "cpe:/o:redhat:enterprise_linux:9", false},
+		{"cpe:/o:redhat:enterprise_linux:8.6::baseos", "cpe:/o:redhat:enterprise_linux:8::appstream", false},
+		{"cpe:/a:redhat:rhel_eus:8.2::appstream", "cpe:/a:redhat:rhel_eus:8.2::appstream", true},
+		{"cpe:/a:redhat:rhel_eus:8.2::appstream", "cpe:/a:redhat:rhel_eus:8.4::appstream", false},
+		{"cpe:/a:redhat:rhel_eus:8.2::appstream", "cpe:/a:redhat:enterprise_linux:8::appstream", false},
+		{"cpe:/a:redhat:rhel_software_collections:3::el7", "cpe:/a:redhat:rhel_software_collections:3", true},
+		{"cpe:/a:redhat:rhel_software_collections:3::el7", "cpe:/a:redhat:rhel_software_collections:3::el8", false},
+	}
+	for _, tc := range tt {
+		repo := &claircore.Repository{Name: tc.Repo, Key: RedHatRepositoryKey}
+		advisory := &claircore.Repository{Name: tc.Advisory, Key: RedHatRepositoryKey}
+		if got, want := cpeMatch(repo, advisory), tc.Want; got != want {
+			t.Errorf("%s ⋚ %s: got: %v, want: %v", tc.Repo, tc.Advisory, got, want)
+		}
+	}
+}
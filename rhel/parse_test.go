@@ -9,6 +9,8 @@ import (
 
 	"github.com/quay/goval-parser/oval"
 	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/libvuln/driver"
 )
 
 func TestParse(t *testing.T) {
@@ -47,6 +49,58 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// TestParseProgress checks that Parse reports decoding and then every
+// definition.
+func TestParseProgress(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+
+	u, err := NewUpdater(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ps []driver.Progress
+	u.SetProgress(func(p driver.Progress) { ps = append(ps, p) })
+	f, err := os.Open("testdata/com.redhat.rhsa-20201980.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Parse(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) == 0 {
+		t.Fatal("no progress reported")
+	}
+	var decoded int64
+	var defs int64
+	for i, p := range ps {
+		switch p.Phase {
+		case "decode":
+			if defs != 0 {
+				t.Errorf("%d: decode reported after parse", i)
+			}
+			decoded = p.Bytes
+		case "parse":
+			if got, want := p.Items, defs+1; got != want {
+				t.Errorf("%d: got %d items, want %d", i, got, want)
+			}
+			defs = p.Items
+		default:
+			t.Errorf("%d: unexpected phase %q", i, p.Phase)
+		}
+	}
+	if got, want := decoded, fi.Size(); got != want {
+		t.Errorf("decoded %d bytes, want %d", got, want)
+	}
+	if defs == 0 {
+		t.Error("no definitions reported")
+	}
+}
+
 // Here's a giant restructured struct for reference and tests.
 var ovalDef = oval.Definition{XMLName: xml.Name{Space: "http://oval.mitre.org/XMLSchema/oval-definitions-5", Local: "definition"},
 	ID:    "oval:com.redhat.rhsa:def:20100401",
@@ -2,7 +2,6 @@ package rhel
 
 import (
 	"context"
-	"encoding/xml"
 	"fmt"
 	"io"
 
@@ -12,6 +11,7 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/cpe"
 	"github.com/quay/claircore/pkg/ovalutil"
 )
@@ -21,12 +21,17 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		label.String("component", "rhel/Updater.Parse"))
 	zlog.Info(ctx).Msg("starting parse")
 	defer r.Close()
-	root := oval.Root{}
-	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+	pr := &progressReader{r: r, u: u}
+	doc, err := ovalutil.DecodeDocument(ctx, pr, u.Fetcher.DecodeOptions)
+	if err != nil {
 		return nil, fmt.Errorf("rhel: unable to decode OVAL document: %w", err)
 	}
+	defer doc.Close()
 	zlog.Debug(ctx).Msg("xml decoded")
+	var defs int64
 	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
+		defs++
+		u.report(driver.Progress{Phase: "parse", Items: defs, Bytes: pr.n})
 		vs := []*claircore.Vulnerability{}
 
 		defType, err := ovalutil.GetDefinitionType(def)
@@ -60,6 +65,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 				Description:        def.Description,
 				Issued:             def.Advisory.Issued.Date,
 				Links:              ovalutil.Links(def),
+				Aliases:            ovalutil.Aliases(def),
 				Severity:           def.Advisory.Severity,
 				NormalizedSeverity: NormalizeSeverity(def.Advisory.Severity),
 				Repo: &claircore.Repository{
@@ -76,7 +82,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		}
 		return vs, nil
 	}
-	vulns, err := ovalutil.RPMDefsToVulns(ctx, &root, protoVulns)
+	vulns, err := doc.RPMDefsToVulns(ctx, protoVulns)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,38 @@
+package rhel
+
+import (
+	"io"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var _ driver.ProgressReporter = (*Updater)(nil)
+
+// SetProgress implements driver.ProgressReporter.
+//
+// Parse reports the bytes read while decoding the OVAL document in the
+// "decode" phase, then the definitions handled in the "parse" phase.
+func (u *Updater) SetProgress(f driver.ProgressFunc) {
+	u.progress = f
+}
+
+// Report calls the progress function, if any.
+func (u *Updater) report(p driver.Progress) {
+	if u.progress != nil {
+		u.progress(p)
+	}
+}
+
+// ProgressReader reports the bytes read through it.
+type progressReader struct {
+	r io.Reader
+	u *Updater
+	n int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	p.u.report(driver.Progress{Phase: "decode", Bytes: p.n})
+	return n, err
+}
@@ -60,7 +60,7 @@ const RedHatRepositoryKey = "rhel-cpe-repository"
 func (*RepositoryScanner) Name() string { return "rhel-repository-scanner" }
 
 // Version implements scanner.VersionedScanner.
-func (*RepositoryScanner) Version() string { return "1.1" }
+func (*RepositoryScanner) Version() string { return "1.2" }
 
 // Kind implements scanner.VersionedScanner.
 func (*RepositoryScanner) Kind() string { return "repository" }
@@ -179,6 +179,17 @@ func (r *RepositoryScanner) Scan(ctx context.Context, l *claircore.Layer) (repos
 	if err != nil {
 		return []*claircore.Repository{}, err
 	}
+	if CPEs == nil {
+		// RHCOS layers don't have content manifests, but the os-release
+		// file says which RHEL and OpenShift releases they're built from.
+		c, err := findCoreOS(ctx, l)
+		if err != nil {
+			return []*claircore.Repository{}, err
+		}
+		if c != nil {
+			CPEs = c.CPEs()
+		}
+	}
 	if CPEs == nil && r.apiFetcher != nil {
 		// Embedded content-sets are available only for new images.
 		// For old images, use fallback option and query Red Hat Container API.
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/ovalutil"
 )
@@ -15,6 +16,7 @@ const dbURL = `https://www.redhat.com/security/data/oval/com.redhat.rhsa-RHEL%d.
 var (
 	_ driver.Updater      = (*Updater)(nil)
 	_ driver.Configurable = (*Updater)(nil)
+	_ driver.DataSourcer  = (*Updater)(nil)
 )
 
 // Updater fetches and parses RHEL-flavored OVAL databases.
@@ -22,6 +24,7 @@ type Updater struct {
 	ovalutil.Fetcher // fetch method promoted via embed
 	name             string
 	release          Release
+	progress         driver.ProgressFunc
 }
 
 // NewUpdater returns an Updater.
@@ -77,6 +80,14 @@ func WithClient(c *http.Client) Option {
 	}
 }
 
+// WithDecodeOptions sets the options used when decoding the OVAL database.
+func WithDecodeOptions(o ovalutil.DecodeOptions) Option {
+	return func(u *Updater) error {
+		u.Fetcher.DecodeOptions = o
+		return nil
+	}
+}
+
 func WithName(n string) Option {
 	return func(u *Updater) error {
 		u.name = n
@@ -88,3 +99,12 @@ func WithName(n string) Option {
 func (u *Updater) Name() string {
 	return u.name
 }
+
+// DataSource implements driver.DataSourcer.
+func (*Updater) DataSource() claircore.DataSource {
+	return claircore.DataSource{
+		Name:    "Red Hat Product Security",
+		License: "CC-BY-4.0",
+		URL:     "https://access.redhat.com/security/data",
+	}
+}
@@ -0,0 +1,100 @@
+package rhel
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/pkg/ovalutil"
+)
+
+// TestParseSpill checks that spilling the RHEL 8 database's tables to disk
+// doesn't change the parsed vulnerabilities.
+func TestParseSpill(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	parse := func(opts ovalutil.DecodeOptions) int {
+		u, err := NewUpdater(8, WithDecodeOptions(opts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open("testdata/com.redhat.rhsa-RHEL8.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs, err := u.Parse(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(vs)
+	}
+	dir, err := ioutil.TempDir("", "rhel.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := parse(ovalutil.DecodeOptions{})
+	got := parse(ovalutil.DecodeOptions{SpillThreshold: 100, SpillDir: dir})
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %d vulnerabilities, want %d", got, want)
+	}
+	if fs, _ := ioutil.ReadDir(dir); len(fs) != 0 {
+		t.Errorf("spill file left behind: %v", fs[0].Name())
+	}
+}
+
+// BenchmarkDecodeSpill reports the heap in use while holding the decoded RHEL
+// 8 database, for several spill thresholds.
+func BenchmarkDecodeSpill(b *testing.B) {
+	ctx := zlog.Test(context.Background(), b)
+	dir, err := ioutil.TempDir("", "rhel.")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, bc := range []struct {
+		name      string
+		threshold int
+	}{
+		{"InMemory", 0},
+		{"Threshold1000", 1000},
+		{"Threshold100", 100},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var ms runtime.MemStats
+			var heap uint64
+			for i := 0; i < b.N; i++ {
+				f, err := os.Open("testdata/com.redhat.rhsa-RHEL8.xml")
+				if err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				before := ms.HeapAlloc
+				doc, err := ovalutil.DecodeDocument(ctx, f, ovalutil.DecodeOptions{
+					SpillThreshold: bc.threshold,
+					SpillDir:       dir,
+				})
+				f.Close()
+				if err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				if ms.HeapAlloc > before {
+					heap += ms.HeapAlloc - before
+				}
+				runtime.KeepAlive(doc)
+				doc.Close()
+			}
+			b.ReportMetric(float64(heap)/float64(b.N), "heap-B/op")
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/ovalutil"
 	"github.com/quay/claircore/rhel/pulp"
 )
 
@@ -19,6 +20,7 @@ var rhelReleases = []Release{
 	RHEL6,
 	RHEL7,
 	RHEL8,
+	RHEL9,
 }
 
 // DefaultManifest is the url for the Red Hat OVAL pulp repository.
@@ -58,6 +60,10 @@ type Factory struct {
 // By convention, this should be in a map called "rhel".
 type FactoryConfig struct {
 	URL string `json:"url" yaml:"url"`
+	// SpillThreshold and SpillDir are used as the DecodeOptions of every
+	// created Updater. See ovalutil.DecodeOptions.
+	SpillThreshold int    `json:"spill_threshold" yaml:"spill_threshold"`
+	SpillDir       string `json:"spill_dir" yaml:"spill_dir"`
 }
 
 var _ driver.Configurable = (*Factory)(nil)
@@ -83,6 +89,19 @@ func (f *Factory) Configure(ctx context.Context, cfg driver.ConfigUnmarshaler, c
 		f.url = u
 	}
 
+	if fc.SpillThreshold != 0 {
+		if fc.SpillThreshold < 0 {
+			return fmt.Errorf("rhel: bad spill threshold: %d", fc.SpillThreshold)
+		}
+		zlog.Info(ctx).
+			Int("threshold", fc.SpillThreshold).
+			Msg("configured spilling to disk")
+		f.updaterOpts = append(f.updaterOpts, WithDecodeOptions(ovalutil.DecodeOptions{
+			SpillThreshold: fc.SpillThreshold,
+			SpillDir:       fc.SpillDir,
+		}))
+	}
+
 	if c != nil {
 		zlog.Info(ctx).
 			Msg("configured HTTP client")
@@ -154,6 +173,8 @@ func (f *Factory) UpdaterSet(ctx context.Context) (driver.UpdaterSet, error) {
 		p := uri.Path
 		var r Release
 		switch {
+		case strings.Contains(p, "RHEL9"):
+			r = RHEL9
 		case strings.Contains(p, "RHEL8"):
 			r = RHEL8
 		case strings.Contains(p, "RHEL7"):
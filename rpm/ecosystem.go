@@ -8,6 +8,7 @@ import (
 	"github.com/quay/claircore/internal/indexer/linux"
 	"github.com/quay/claircore/oracle"
 	"github.com/quay/claircore/photon"
+	"github.com/quay/claircore/pkg/purl"
 	"github.com/quay/claircore/suse"
 )
 
@@ -29,7 +30,7 @@ func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 			return []indexer.RepositoryScanner{}, nil
 		},
 		Coalescer: func(ctx context.Context) (indexer.Coalescer, error) {
-			return linux.NewCoalescer(), nil
+			return linux.NewCoalescer(purl.RPM), nil
 		},
 	}
 }
@@ -13,6 +13,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime/trace"
+	"sort"
 	"strings"
 
 	"github.com/quay/zlog"
@@ -26,26 +27,65 @@ import (
 const (
 	pkgName    = "rpm"
 	pkgKind    = "package"
-	pkgVersion = "v0.0.2"
+	pkgVersion = "v0.0.4"
 )
 
-// DbNames is a set of files that make up an rpm database.
+// DbNames is the set of files that mark a directory as an rpm database: the
+// BerkeleyDB database older releases use, and the sqlite database newer ones,
+// including rpm-ostree systems like Fedora CoreOS and RHCOS, use.
 var dbnames = map[string]struct{}{
-	"Packages": {},
+	"Packages":     {},
+	"rpmdb.sqlite": {},
+}
+
+// StandardPaths is the set of directories rpm databases are expected to be
+// found in, relative to the root of the filesystem.
+var standardPaths = map[string]struct{}{
+	"var/lib/rpm":          {},
+	"usr/lib/sysimage/rpm": {},
+	"usr/share/rpm":        {},
+}
+
+// OstreePaths are directories on rpm-ostree systems whose contents don't
+// describe the installed system, so they're never examined: the database of
+// the base image from before packages were layered onto it, and the ostree
+// repository, whose objects the installed tree is hardlinked to.
+var ostreePaths = []string{
+	"usr/lib/sysimage/rpm-ostree-base-db",
+	"ostree/repo",
+	"sysroot/ostree/repo",
 }
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner    = (*Scanner)(nil)
+	_ indexer.PackageScanner      = (*Scanner)(nil)
+	_ indexer.ConfigurableScanner = (*Scanner)(nil)
 )
 
 // Scanner implements the scanner.PackageScanner interface.
 //
 // This looks for directories that look like rpm databases and examines the
-// files it finds there.
+// files it finds there. Every database in a layer is examined, and packages
+// report the database they were found in via their PackageDB member.
 //
 // The zero value is ready to use.
-type Scanner struct{}
+type Scanner struct {
+	cfg ScannerConfig
+}
+
+// ScannerConfig is the struct that will be passed to (*Scanner).Configure's
+// ConfigDeserializer argument.
+type ScannerConfig struct {
+	// StandardPathsOnly restricts the Scanner to rpm databases in the
+	// standard locations, ignoring databases in places like an installer's
+	// chroot.
+	StandardPathsOnly bool `json:"standard_paths_only" yaml:"standard_paths_only"`
+}
+
+// Configure implements indexer.ConfigurableScanner.
+func (ps *Scanner) Configure(ctx context.Context, f indexer.ConfigDeserializer) error {
+	return f(&ps.cfg)
+}
 
 // Name implements scanner.VersionedScanner.
 func (*Scanner) Name() string { return pkgName }
@@ -87,33 +127,11 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 		return nil, errors.New("rpm: cannot seek on returned layer Reader")
 	}
 
-	// Map of directory to confidence score. Confidence of len(dbnames) means
-	// it's almost certainly an rpm database.
-	possible := make(map[string]int)
-	tr := tar.NewReader(rd)
-	// Find possible rpm dbs
-	// If none found, return
-	var h *tar.Header
-	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
-		n := filepath.Base(h.Name)
-		d := filepath.Dir(h.Name)
-		if _, ok := dbnames[n]; ok {
-			possible[d]++
-		}
-	}
-	if err != io.EOF {
+	found, err := findDBs(rd, ps.cfg.StandardPathsOnly)
+	if err != nil {
 		return nil, err
 	}
-	if len(possible) == 0 {
-		return nil, nil
-	}
-	found := make([]string, 0)
-	for k, score := range possible {
-		if score == len(dbnames) {
-			found = append(found, filepath.Join("/", k))
-		}
-	}
-	zlog.Debug(ctx).Int("count", len(found)).Msg("found possible databases")
+	zlog.Debug(ctx).Strs("found", found).Msg("found possible databases")
 	if len(found) == 0 {
 		return nil, nil
 	}
@@ -233,6 +251,47 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	return pkgs, nil
 }
 
+// FindDBs reports the directories in the layer that look like rpm databases,
+// as absolute paths in lexical order.
+//
+// If "standard" is set, only databases in the standard locations are
+// reported.
+//
+// On rpm-ostree systems, the database is usually a hardlink into the ostree
+// repository; it's found by its name in the installed tree like any other.
+func findDBs(r io.Reader, standard bool) ([]string, error) {
+	possible := make(map[string]struct{})
+	tr := tar.NewReader(r)
+	var h *tar.Header
+	var err error
+Header:
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		n := filepath.Base(h.Name)
+		d := strings.TrimPrefix(filepath.Clean(filepath.Dir(h.Name)), "/")
+		if _, ok := dbnames[n]; !ok {
+			continue
+		}
+		if _, ok := standardPaths[d]; standard && !ok {
+			continue
+		}
+		for _, p := range ostreePaths {
+			if d == p || strings.HasPrefix(d, p+"/") {
+				continue Header
+			}
+		}
+		possible[d] = struct{}{}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	found := make([]string, 0, len(possible))
+	for k := range possible {
+		found = append(found, filepath.Join("/", k))
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
 // This is the query format we're using to get data out of rpm.
 //
 // There's XML output, but it's all jacked up.
@@ -243,6 +302,7 @@ const queryFmt = `%{name}\n` +
 	`%{sourcerpm}\n` +
 	`%{RPMTAG_MODULARITYLABEL}\n` +
 	`%{ARCH}\n` +
+	`%{LICENSE}\n` +
 	`.\n`
 const delim = "\n.\n"
 
@@ -327,6 +387,10 @@ func parsePackage(ctx context.Context, src map[string]*claircore.Package, buf *b
 			}
 		case 6:
 			p.Arch = line
+		case 7:
+			// Newer packages use SPDX expressions, older ones use the
+			// distribution's own syntax. Either way, it's recorded as found.
+			p.License = line
 		}
 		switch err {
 		case nil:
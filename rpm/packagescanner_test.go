@@ -1,13 +1,18 @@
 package rpm
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
+	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -1616,7 +1621,111 @@ func TestScan(t *testing.T) {
 		t.Fatal(err)
 	}
 	t.Logf("found %d packages", len(got))
-	if !cmp.Equal(got, want) {
-		t.Fatal(cmp.Diff(got, want))
+	// The expected packages predate recording licenses, which are checked
+	// by TestParsePackageLicense.
+	opt := cmpopts.IgnoreFields(claircore.Package{}, "License")
+	if !cmp.Equal(got, want, opt) {
+		t.Fatal(cmp.Diff(got, want, opt))
+	}
+}
+
+// TestFindDBs uses a layer with a second rpm database in an installer-style
+// chroot, and a layer exported from an rpm-ostree system with packages layered
+// on. The databases are placeholders, so this only exercises discovery.
+func TestFindDBs(t *testing.T) {
+	tt := []struct {
+		Name     string
+		File     string
+		Standard bool
+		Want     []string
+	}{
+		{
+			Name: "All",
+			File: "testdata/multidb.tar",
+			Want: []string{"/mnt/sysimage/var/lib/rpm", "/var/lib/rpm"},
+		},
+		{
+			Name:     "StandardOnly",
+			File:     "testdata/multidb.tar",
+			Standard: true,
+			Want:     []string{"/var/lib/rpm"},
+		},
+		{
+			// The database is hardlinked into the ostree repository, and the
+			// base image's database is ignored.
+			Name: "Ostree",
+			File: "testdata/ostree.tar",
+			Want: []string{"/usr/share/rpm"},
+		},
+		{
+			Name:     "OstreeStandardOnly",
+			File:     "testdata/ostree.tar",
+			Standard: true,
+			Want:     []string{"/usr/share/rpm"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			f, err := os.Open(tc.File)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := findDBs(f, tc.Standard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var s Scanner
+	err := s.Configure(ctx, json.NewDecoder(strings.NewReader(`{"standard_paths_only":true}`)).Decode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.cfg.StandardPathsOnly {
+		t.Error("configuration not applied")
+	}
+}
+
+func TestParsePackageLicense(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name string
+		In   string
+		Want string
+	}{
+		{
+			Name: "SPDX",
+			In:   "bash\n5.1.8-6.el9\n8:abc\n(none)\nbash-5.1.8-6.el9.src.rpm\n(none)\nx86_64\nGPL-3.0-or-later",
+			Want: "GPL-3.0-or-later",
+		},
+		{
+			Name: "Legacy",
+			In:   "openssl-libs\n1:1.1.1k-7.el8\n8:abc\n(none)\nopenssl-1.1.1k-7.el8.src.rpm\n(none)\nx86_64\nOpenSSL and ASL 2.0",
+			Want: "OpenSSL and ASL 2.0",
+		},
+		{
+			Name: "None",
+			In:   "gpg-pubkey\nfd431d51-4ae0493b\n8:abc\n(none)\n(none)\n(none)\n(none)\n(none)",
+			Want: "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := parsePackage(ctx, make(map[string]*claircore.Package), bytes.NewBufferString(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := p.License, tc.Want; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql/driver"
 	"fmt"
+	"strings"
 )
 
 type Severity uint
@@ -59,3 +60,29 @@ func (s *Severity) Scan(i interface{}) error {
 	}
 	return nil
 }
+
+// SeverityTable maps the lowercased severity strings vendors use to the
+// Severity they correspond to. Vendors' spellings don't conflict, so one table
+// serves all of them.
+var severityTable = map[string]Severity{
+	"unknown":     Unknown,
+	"none":        Unknown,
+	"n/a":         Unknown,
+	"negligible":  Negligible,
+	"unimportant": Negligible,
+	"low":         Low,
+	"medium":      Medium,
+	"moderate":    Medium,
+	"high":        High,
+	"important":   High,
+	"critical":    Critical,
+}
+
+// NormalizeSeverity maps a vendor's severity string, like "Important" or
+// "MODERATE", to a Severity using the table shared by all updaters. The
+// reported bool is false if the string isn't in the table, in which case
+// Unknown is returned.
+func NormalizeSeverity(s string) (Severity, bool) {
+	sev, ok := severityTable[strings.ToLower(strings.TrimSpace(s))]
+	return sev, ok
+}
@@ -0,0 +1,43 @@
+package claircore_test
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/aws"
+	"github.com/quay/claircore/oracle"
+	"github.com/quay/claircore/photon"
+	"github.com/quay/claircore/rhel"
+	"github.com/quay/claircore/suse"
+)
+
+// TestNormalizeSeverity checks that the shared table agrees with the
+// updaters' own mappings for the strings they produce.
+func TestNormalizeSeverity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(string) claircore.Severity
+		in   []string
+	}{
+		{"aws", aws.NormalizeSeverity, []string{aws.Low, aws.Medium, aws.Important, aws.Critical}},
+		{"oracle", oracle.NormalizeSeverity, []string{oracle.NA, oracle.Low, oracle.Moderate, oracle.Important, oracle.Critical}},
+		{"photon", photon.NormalizeSeverity, []string{photon.Low, photon.Moderate, photon.Important, photon.Critical}},
+		{"rhel", rhel.NormalizeSeverity, []string{rhel.None, rhel.Low, rhel.Moderate, rhel.Important, rhel.Critical}},
+		{"suse", suse.NormalizeSeverity, []string{suse.None, suse.Low, suse.Moderate, suse.Important, suse.Critical}},
+	} {
+		for _, s := range tc.in {
+			got, ok := claircore.NormalizeSeverity(s)
+			if !ok {
+				t.Errorf("%s: %q not in table", tc.name, s)
+			}
+			if want := tc.f(s); got != want {
+				t.Errorf("%s: %q: got %v, want %v", tc.name, s, got, want)
+			}
+		}
+	}
+	for _, s := range []string{"", "not yet assigned", "7.5"} {
+		if got, ok := claircore.NormalizeSeverity(s); ok || got != claircore.Unknown {
+			t.Errorf("%q: got (%v, %v), want (Unknown, false)", s, got, ok)
+		}
+	}
+}
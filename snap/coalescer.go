@@ -0,0 +1,59 @@
+package snap
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewCoalescer returns the coalescer for snaps.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// Snaps from the Snap Store are associated with it; sideloaded snaps aren't
+// associated with any repository. A snap refreshed or reinstalled in a later
+// layer is reported as the later version only.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+		Repositories: map[string]*claircore.Repository{},
+	}
+	// Snap name to package ID.
+	installed := make(map[string]string)
+	for _, l := range ls {
+		// Snapd's state is a single file, so a layer holding it replaces
+		// the snaps recorded by earlier layers entirely.
+		if len(l.Pkgs) != 0 {
+			for _, id := range installed {
+				delete(ir.Packages, id)
+				delete(ir.Environments, id)
+			}
+			installed = make(map[string]string)
+		}
+		byURI := make(map[string]*claircore.Repository, len(l.Repos))
+		for _, r := range l.Repos {
+			byURI[r.URI] = r
+		}
+		for _, pkg := range l.Pkgs {
+			installed[pkg.Name] = pkg.ID
+			env := &claircore.Environment{
+				PackageDB:    pkg.PackageDB,
+				IntroducedIn: l.Hash,
+			}
+			if r, ok := byURI[pkg.RepositoryHint]; ok {
+				ir.AddRepository(r, l.Hash)
+				env.RepositoryIDs = []string{r.ID}
+			}
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = []*claircore.Environment{env}
+		}
+	}
+	return ir, nil
+}
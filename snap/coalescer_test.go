@@ -0,0 +1,63 @@
+package snap
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// TestCoalesce checks that a snap removed in a later layer isn't reported.
+func TestCoalesce(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	store := &claircore.Repository{ID: "1", Name: "snap", URI: "https://snapcraft.io"}
+	db := "snap:" + statePath
+	ls := []*indexer.LayerArtifacts{
+		{
+			Hash: claircore.MustParseDigest("sha256:" + "1111111111111111111111111111111111111111111111111111111111111111"),
+			Pkgs: []*claircore.Package{
+				{ID: "1", Name: "certbot", Version: "1.20.0", PackageDB: db, RepositoryHint: store.URI},
+				{ID: "2", Name: "lxd", Version: "5.0.2", PackageDB: db, RepositoryHint: store.URI},
+			},
+			Repos: []*claircore.Repository{store},
+		},
+		{
+			Hash: claircore.MustParseDigest("sha256:" + "2222222222222222222222222222222222222222222222222222222222222222"),
+		},
+		{
+			Hash: claircore.MustParseDigest("sha256:" + "3333333333333333333333333333333333333333333333333333333333333333"),
+			Pkgs: []*claircore.Package{
+				{ID: "3", Name: "certbot", Version: "1.21.0", PackageDB: db, RepositoryHint: store.URI},
+				{ID: "4", Name: "hello-local", Version: "0.3", PackageDB: db},
+			},
+			Repos: []*claircore.Repository{store},
+		},
+	}
+	c, err := NewCoalescer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Coalesce(ctx, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for id := range ir.Packages {
+		got = append(got, id)
+	}
+	sort.Strings(got)
+	if want := []string{"3", "4"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if envs := ir.Environments["3"]; len(envs) != 1 || !cmp.Equal(envs[0].RepositoryIDs, []string{store.ID}) {
+		t.Errorf("got environments %+v, want repository %s", envs, store.ID)
+	}
+	if envs := ir.Environments["4"]; len(envs) != 1 || len(envs[0].RepositoryIDs) != 0 {
+		t.Errorf("got environments %+v, want no repository", envs)
+	}
+}
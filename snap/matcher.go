@@ -0,0 +1,74 @@
+package snap
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/appmap"
+	"github.com/quay/claircore/python"
+)
+
+var (
+	_ driver.Matcher       = (*Matcher)(nil)
+	_ driver.PackageMapper = (*Matcher)(nil)
+)
+
+// Applications is the default mapping of snaps to the packages they're
+// matched as.
+//
+// Only snaps whose versions follow the project they package are listed.
+var Applications = appmap.Table{
+	"certbot": {Name: "certbot", Repository: python.Repository.URI},
+	"yt-dlp":  {Name: "yt-dlp", Repository: python.Repository.URI},
+}
+
+// Matcher matches snaps from the Snap Store that are known to package a
+// project with vulnerability data of its own.
+//
+// Sideloaded snaps are never matched: their names aren't assigned by the
+// store, so they can't be trusted to name the project.
+//
+// The zero value uses the Applications table.
+type Matcher struct {
+	// Applications overrides the default mapping, if not nil.
+	Applications appmap.Table
+}
+
+func (m *Matcher) table() appmap.Table {
+	if m.Applications != nil {
+		return m.Applications
+	}
+	return Applications
+}
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return "snap" }
+
+// Filter implements driver.Matcher.
+func (m *Matcher) Filter(record *claircore.IndexRecord) bool {
+	if !strings.HasPrefix(record.Package.PackageDB, "snap:") ||
+		record.Package.RepositoryHint != storeRepository.URI {
+		return false
+	}
+	_, ok := m.table().Lookup(record.Package.Name)
+	return ok
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{}
+}
+
+// MapPackage implements driver.PackageMapper.
+//
+// Snaps are queried as the package they're mapped to.
+func (m *Matcher) MapPackage(record *claircore.IndexRecord) *claircore.Package {
+	return m.table().Package(record.Package)
+}
+
+// Vulnerable implements driver.Matcher.
+func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	return m.table().Vulnerable(ctx, record, vuln)
+}
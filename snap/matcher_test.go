@@ -0,0 +1,69 @@
+package snap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/appmap"
+	"github.com/quay/claircore/python"
+)
+
+func TestMatcher(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var m Matcher
+	store := func(name, version string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{Package: &claircore.Package{
+			ID:             "1",
+			Name:           name,
+			Version:        version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "snap:" + statePath,
+			RepositoryHint: storeRepository.URI,
+		}}
+	}
+	sideloaded := store("certbot", "1.21.0")
+	sideloaded.Package.RepositoryHint = ""
+
+	for _, tc := range []struct {
+		Name   string
+		Record *claircore.IndexRecord
+		Want   bool
+	}{
+		{"Mapped", store("certbot", "1.21.0"), true},
+		{"Unmapped", store("lxd", "5.0.2"), false},
+		{"Sideloaded", sideloaded, false},
+		{"NotSnap", &claircore.IndexRecord{Package: &claircore.Package{Name: "certbot", RepositoryHint: python.Repository.URI}}, false},
+	} {
+		if got := m.Filter(tc.Record); got != tc.Want {
+			t.Errorf("%s: got: %v, want: %v", tc.Name, got, tc.Want)
+		}
+	}
+
+	rec := store("certbot", "1.21.0")
+	p := m.MapPackage(rec)
+	if p == nil || p.Name != "certbot" || p.ID != rec.Package.ID {
+		t.Fatalf("unexpected mapping: %+v", p)
+	}
+	vuln := &claircore.Vulnerability{
+		Package: &claircore.Package{Name: "certbot", Version: "<1.22.0"},
+		Repo:    &python.Repository,
+	}
+	ok, err := m.Vulnerable(ctx, rec, vuln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("expected vulnerable")
+	}
+
+	// An overridden table replaces the default one.
+	m.Applications = appmap.Table{
+		"lxd": {Name: "lxd", Repository: python.Repository.URI},
+	}
+	if m.Filter(store("certbot", "1.21.0")) || !m.Filter(store("lxd", "5.0.2")) {
+		t.Error("Applications not used")
+	}
+}
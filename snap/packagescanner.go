@@ -0,0 +1,72 @@
+package snap
+
+import (
+	"context"
+	"runtime/trace"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner = (*Scanner)(nil)
+	_ indexer.PackageScanner   = (*Scanner)(nil)
+)
+
+// Scanner implements the scanner.PackageScanner interface.
+//
+// It reads the snaps snapd records as installed in its state. Snaps
+// installed from the Snap Store are reported with the store as their
+// repository; sideloaded snaps are reported without one.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*Scanner) Name() string { return "snap" }
+
+// Version implements scanner.VersionedScanner.
+func (*Scanner) Version() string { return "0.1.0" }
+
+// Kind implements scanner.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Scan attempts to find snapd's state and record the snaps installed.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "snap/Scanner.Scan"),
+		label.String("version", ps.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rs, err := records(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*claircore.Package
+	for _, r := range rs {
+		pkg := &claircore.Package{
+			Name:      r.Name,
+			Version:   r.Version,
+			Kind:      claircore.BINARY,
+			PackageDB: "snap:" + statePath,
+		}
+		if repo := r.repository(); repo != nil {
+			pkg.RepositoryHint = repo.URI
+		}
+		ret = append(ret, pkg)
+	}
+	return ret, nil
+}
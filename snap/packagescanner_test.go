@@ -0,0 +1,121 @@
+package snap_test
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/snap"
+)
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/state")
+
+	got, err := new(snap.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		db    = "snap:var/lib/snapd/state.json"
+		store = "https://snapcraft.io"
+	)
+	want := []*claircore.Package{
+		{Name: "certbot", Version: "1.21.0", Kind: claircore.BINARY, PackageDB: db, RepositoryHint: store},
+		{Name: "core20", Version: "20230126", Kind: claircore.BINARY, PackageDB: db, RepositoryHint: store},
+		// Sideloaded, so not from the store.
+		{Name: "hello-local", Version: "0.3", Kind: claircore.BINARY, PackageDB: db},
+		// Disabled, and without its files in the layer.
+		{Name: "lxd", Kind: claircore.BINARY, PackageDB: db, RepositoryHint: store},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestRepoScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/state")
+
+	got, err := new(snap.RepoScanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Repository{
+		{Name: "snap", URI: "https://snapcraft.io"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+// TestEmpty checks that a layer without snapd's state reports nothing, even
+// with a snap's files in it.
+func TestEmpty(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := dirLayer(t, "testdata/state/snap")
+
+	ps, err := new(snap.Scanner).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 0 {
+		t.Errorf("unexpected packages: %v", ps)
+	}
+}
+
+// DirLayer writes the contents of the directory "dir" to a layer tarball.
+func dirLayer(t *testing.T, dir string) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "snap.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		n, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		h, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(n)
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("d", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
@@ -0,0 +1,62 @@
+package snap
+
+import (
+	"context"
+	"runtime/trace"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner  = (*RepoScanner)(nil)
+	_ indexer.RepositoryScanner = (*RepoScanner)(nil)
+)
+
+// RepoScanner reports the Snap Store, as a repository named "snap", if any
+// installed snap came from it.
+//
+// The zero value is ready to use.
+type RepoScanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*RepoScanner) Name() string { return "snap-store" }
+
+// Version implements scanner.VersionedScanner.
+func (*RepoScanner) Version() string { return "0.1.0" }
+
+// Kind implements scanner.VersionedScanner.
+func (*RepoScanner) Kind() string { return "repository" }
+
+// Scan attempts to find snapd's state and record the store snaps were
+// installed from.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (rs *RepoScanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Repository, error) {
+	defer trace.StartRegion(ctx, "RepoScanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "snap/RepoScanner.Scan"),
+		label.String("version", rs.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	recs, err := records(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range recs {
+		if repo := r.repository(); repo != nil {
+			return []*claircore.Repository{repo}, nil
+		}
+	}
+	return nil, nil
+}
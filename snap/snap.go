@@ -0,0 +1,207 @@
+// Package snap contains components for interrogating snaps installed by
+// snapd in container layers.
+package snap
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quay/claircore"
+)
+
+// StatePath is where snapd keeps its state, including the installed snaps.
+const statePath = `var/lib/snapd/state.json`
+
+// MountDirs are the directories snaps are mounted under, depending on the
+// distribution. Images only have a snap's files here if they were unpacked
+// into the image.
+var mountDirs = []string{
+	"snap",
+	"var/lib/snapd/snap",
+}
+
+// StoreRepository is the repository reported for snaps installed from the
+// Snap Store.
+var storeRepository = claircore.Repository{
+	Name: "snap",
+	URI:  "https://snapcraft.io",
+}
+
+// State is the subset of snapd's state used here.
+type state struct {
+	Data struct {
+		Snaps map[string]*snapState `json:"snaps"`
+	} `json:"data"`
+}
+
+// SnapState is snapd's record of an installed snap.
+type snapState struct {
+	Type     string     `json:"type"`
+	Sequence []sideInfo `json:"sequence"`
+	Active   bool       `json:"active"`
+	Current  string     `json:"current"`
+	Channel  string     `json:"channel"`
+}
+
+// SideInfo is snapd's record of a revision of a snap.
+type sideInfo struct {
+	Name     string `json:"name"`
+	SnapID   string `json:"snap-id"`
+	Revision string `json:"revision"`
+	Channel  string `json:"channel"`
+}
+
+// Meta is the subset of a snap's "meta/snap.yaml" used here.
+type meta struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// Record is an installed snap.
+type record struct {
+	Name     string
+	Version  string
+	Revision string
+	SnapID   string
+	Type     string
+}
+
+// Sideloaded reports whether the snap was installed from a file rather than
+// the Snap Store. Sideloaded snaps have local revisions, like "x1", and no
+// snap ID.
+func (r *record) sideloaded() bool {
+	return r.SnapID == "" || strings.HasPrefix(r.Revision, "x")
+}
+
+// Repository returns the repository the snap was installed from, or nil if
+// it was sideloaded.
+func (r *record) repository() *claircore.Repository {
+	if r.sideloaded() {
+		return nil
+	}
+	repo := storeRepository
+	return &repo
+}
+
+// Records reads the snaps installed in the layer, in name order.
+//
+// Only the current revision of each snap is reported. Its version is read
+// from the snap's metadata if the snap's files are in the layer, and is
+// empty otherwise: snapd doesn't record versions in its state.
+func records(ctx context.Context, layer *claircore.Layer) ([]*record, error) {
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rd, ok := r.(interface {
+		io.ReadCloser
+		io.Seeker
+	})
+	if !ok {
+		return nil, errors.New("snap: cannot seek on returned layer Reader")
+	}
+
+	var st *state
+	// Snap name and revision to version.
+	versions := make(map[[2]string]string)
+	tr := tar.NewReader(rd)
+	var h *tar.Header
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if n == statePath {
+			st = new(state)
+			if err := json.NewDecoder(tr).Decode(st); err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("path", n).
+					Msg("unable to read snapd state, skipping")
+				st = nil
+			}
+			continue
+		}
+		name, rev, ok := metaPath(n)
+		if !ok {
+			continue
+		}
+		var m meta
+		if err := yaml.NewDecoder(tr).Decode(&m); err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("path", n).
+				Msg("unable to read snap metadata, skipping")
+			continue
+		}
+		versions[[2]string{name, rev}] = m.Version
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	if st == nil {
+		return nil, nil
+	}
+
+	var rs []*record
+	for name, s := range st.Data.Snaps {
+		var cur *sideInfo
+		for i := range s.Sequence {
+			if s.Sequence[i].Revision == s.Current {
+				cur = &s.Sequence[i]
+				break
+			}
+		}
+		if cur == nil {
+			zlog.Debug(ctx).
+				Str("name", name).
+				Str("revision", s.Current).
+				Msg("current revision not in sequence, skipping")
+			continue
+		}
+		if cur.Name != "" {
+			name = cur.Name
+		}
+		rs = append(rs, &record{
+			Name:     name,
+			Version:  versions[[2]string{name, cur.Revision}],
+			Revision: cur.Revision,
+			SnapID:   cur.SnapID,
+			Type:     s.Type,
+		})
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Name < rs[j].Name })
+	return rs, nil
+}
+
+// MetaPath reports the snap name and revision if "n" is the metadata of a
+// mounted snap.
+func metaPath(n string) (name, rev string, ok bool) {
+	if path.Base(n) != "snap.yaml" || path.Base(path.Dir(n)) != "meta" {
+		return "", "", false
+	}
+	d := path.Dir(path.Dir(n))
+	rev = path.Base(d)
+	d = path.Dir(d)
+	name = path.Base(d)
+	for _, m := range mountDirs {
+		if path.Dir(d) == m {
+			return name, rev, true
+		}
+	}
+	return "", "", false
+}
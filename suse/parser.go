@@ -2,7 +2,6 @@ package suse
 
 import (
 	"context"
-	"encoding/xml"
 	"fmt"
 	"io"
 
@@ -23,10 +22,11 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		label.String("component", "suse/Updater.Parse"))
 	zlog.Info(ctx).Msg("starting parse")
 	defer r.Close()
-	root := oval.Root{}
-	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+	doc, err := ovalutil.DecodeDocument(ctx, r, u.Fetcher.DecodeOptions)
+	if err != nil {
 		return nil, fmt.Errorf("suse: unable to decode OVAL document: %w", err)
 	}
+	defer doc.Close()
 	zlog.Debug(ctx).Msg("xml decoded")
 	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
 		return []*claircore.Vulnerability{
@@ -43,7 +43,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 				Dist: releaseToDist(u.release),
 			}}, nil
 	}
-	vulns, err := ovalutil.RPMDefsToVulns(ctx, &root, protoVulns)
+	vulns, err := doc.RPMDefsToVulns(ctx, protoVulns)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +37,9 @@ func NewUpdater(r Release, opts ...Option) (*Updater, error) {
 	u := &Updater{
 		release: r,
 	}
+	// Mirrors commonly republish the databases compressed with xz or zstd,
+	// so detect the compression unless told otherwise.
+	u.Fetcher.Compression = ovalutil.CompressionDetect
 	for _, o := range opts {
 		if err := o(u); err != nil {
 			return nil, err
@@ -59,7 +62,13 @@ func NewUpdater(r Release, opts ...Option) (*Updater, error) {
 type Option func(*Updater) error
 
 // WithURL overrides the default URL to fetch an OVAL database.
+//
+// If "compression" is empty, the compression is detected from the fetched
+// database.
 func WithURL(uri, compression string) Option {
+	if compression == "" {
+		compression = "detect"
+	}
 	c, cerr := ovalutil.ParseCompressor(compression)
 	u, uerr := url.Parse(uri)
 	return func(up *Updater) error {
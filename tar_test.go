@@ -15,7 +15,9 @@ type tarTestCase struct {
 	File [][2]string
 	// Symlink is a slice of name, target pairs.
 	Symlink [][2]string
-	Check   func(*testing.T, *Layer)
+	// Hardlink is a slice of name, target pairs.
+	Hardlink [][2]string
+	Check    func(*testing.T, *Layer)
 }
 
 func (tc tarTestCase) filename() string {
@@ -82,6 +84,17 @@ func (tc tarTestCase) Generate(t *testing.T) {
 		}
 		t.Logf("wrote %q", pair[0])
 	}
+	for _, pair := range tc.Hardlink {
+		h = tar.Header{
+			Typeflag: tar.TypeLink,
+			Name:     pair[0],
+			Linkname: pair[1],
+		}
+		if err := w.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %q", pair[0])
+	}
 }
 
 func (tc tarTestCase) Layer(t *testing.T) *Layer {
@@ -191,6 +204,40 @@ func TestTar(t *testing.T) {
 				}
 			},
 		},
+		{
+			// Laid out like a layer exported from an ostree commit: the file
+			// is in the repository, the tree hardlinks to it, and a symlink
+			// points at the hardlink.
+			Name: "Hardlink",
+			File: [][2]string{
+				{"sysroot/ostree/repo/objects/3a/4f0c.file", "contents\n"},
+			},
+			Symlink: [][2]string{
+				{"etc/os-release", "../usr/lib/os-release"},
+			},
+			Hardlink: [][2]string{
+				{"usr/lib/os-release", "sysroot/ostree/repo/objects/3a/4f0c.file"},
+			},
+			Check: func(t *testing.T, l *Layer) {
+				var want = "contents\n"
+				var names = []string{`etc/os-release`, `usr/lib/os-release`}
+				t.Logf("%+#v", l)
+
+				m, err := l.Files(names...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, name := range names {
+					r, ok := m[name]
+					if !ok {
+						t.Fatalf("file not found: %q", name)
+					}
+					if got := r.String(); got != want {
+						t.Fatalf("got: %q, want: %q", got, want)
+					}
+				}
+			},
+		},
 		{
 			Name: "DanglingSymlink",
 			Symlink: [][2]string{
@@ -0,0 +1,41 @@
+// Apisurface regenerates the API surface snapshots checked by the
+// internal/apisurface tests.
+//
+// Run it from the module root after an intentional change to the exported API
+// of a covered package, and commit the result along with the change:
+//
+//	go run ./test/apisurface
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/claircore/internal/apisurface"
+)
+
+func main() {
+	root := flag.String("root", ".", "module root")
+	out := flag.String("out", filepath.Join("internal", "apisurface", "testdata"), "snapshot directory, relative to the module root")
+	flag.Parse()
+
+	dir := filepath.Join(*root, *out)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for _, pkg := range apisurface.Packages {
+		ls, err := apisurface.Surface(filepath.Join(*root, pkg))
+		if err != nil {
+			log.Fatalf("%s: %v", pkg, err)
+		}
+		n := filepath.Join(dir, apisurface.SnapshotName(pkg))
+		if err := ioutil.WriteFile(n, []byte(strings.Join(ls, "\n")+"\n"), 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s (%d lines)", n, len(ls))
+	}
+}
@@ -0,0 +1,249 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// CoalescerSets is the names of the canned layer artifact sets a
+// CoalescerTestcase runs against:
+//
+//	Empty		no layers
+//	EmptyLayers	three layers with no artifacts
+//	Single		one layer with two packages
+//	Upgrade		two packages in the base layer, an empty layer, then the
+//			package database again with "a" upgraded
+//	Removal		two packages in the base layer, then the package database
+//			again without "b"
+var CoalescerSets = []string{"Empty", "EmptyLayers", "Single", "Upgrade", "Removal"}
+
+// CoalescerTestcase checks a Coalescer against canned sets of layer artifacts,
+// built from what an ecosystem's scanners would report.
+//
+// Every set is checked for the invariants any coalesced IndexReport must
+// satisfy. Check can add expectations specific to the ecosystem's merge rules.
+type CoalescerTestcase struct {
+	// Coalescer returns the Coalescer under test. It's called once per run.
+	Coalescer func(context.Context) (indexer.Coalescer, error)
+	// PackageDB is the package database the generated packages are found in.
+	PackageDB string
+	// Distribution and Repository, if not nil, are reported in every layer
+	// with packages.
+	Distribution *claircore.Distribution
+	Repository   *claircore.Repository
+	// Check, if not nil, is called with the name of the set, the artifacts
+	// the Coalescer was called with, and the resulting IndexReport.
+	Check func(t *testing.T, set string, ls []*indexer.LayerArtifacts, ir *claircore.IndexReport)
+}
+
+// Run returns a function suitable for using with (*testing.T).Run.
+func (tc CoalescerTestcase) Run(ctx context.Context) func(*testing.T) {
+	return func(t *testing.T) {
+		for _, set := range CoalescerSets {
+			set := set
+			t.Run(set, func(t *testing.T) {
+				ctx := zlog.Test(ctx, t)
+				ls := tc.Layers(set)
+				ir := tc.coalesce(ctx, t, ls)
+				checkCoalesced(t, ls, ir)
+				if set == "Upgrade" {
+					checkUpgrade(t, ls, ir)
+				}
+				if tc.Check != nil {
+					tc.Check(t, set, ls, ir)
+				}
+
+				// Coalescers are allowed to modify the artifacts, so use a
+				// fresh set.
+				again := tc.coalesce(ctx, t, tc.Layers(set))
+				a, err := json.Marshal(ir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := json.Marshal(again)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(a, b) {
+					t.Errorf("results differ between runs:\n%s\n%s", a, b)
+				}
+			})
+		}
+	}
+}
+
+func (tc CoalescerTestcase) coalesce(ctx context.Context, t *testing.T, ls []*indexer.LayerArtifacts) *claircore.IndexReport {
+	t.Helper()
+	c, err := tc.Coalescer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Coalesce(ctx, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ir == nil {
+		t.Fatal("got nil IndexReport")
+	}
+	return ir
+}
+
+// Layers returns a fresh copy of the named canned set.
+//
+// Identical packages share an ID across layers, as they would coming out of
+// the store. Panics if the set is unknown.
+func (tc CoalescerTestcase) Layers(set string) []*indexer.LayerArtifacts {
+	pkg := func(name, version string) *claircore.Package {
+		return &claircore.Package{
+			ID:             name + "-" + version,
+			Name:           name,
+			Version:        version,
+			Kind:           claircore.BINARY,
+			PackageDB:      tc.PackageDB,
+			RepositoryHint: repositoryHint(tc.Repository),
+		}
+	}
+	var pkgs [][]*claircore.Package
+	switch set {
+	case "Empty":
+	case "EmptyLayers":
+		pkgs = make([][]*claircore.Package, 3)
+	case "Single":
+		pkgs = [][]*claircore.Package{
+			{pkg("a", "1.0.0"), pkg("b", "1.0.0")},
+		}
+	case "Upgrade":
+		pkgs = [][]*claircore.Package{
+			{pkg("a", "1.0.0"), pkg("b", "1.0.0")},
+			nil,
+			{pkg("a", "2.0.0"), pkg("b", "1.0.0")},
+		}
+	case "Removal":
+		pkgs = [][]*claircore.Package{
+			{pkg("a", "1.0.0"), pkg("b", "1.0.0")},
+			{pkg("a", "1.0.0")},
+		}
+	default:
+		panic("unknown coalescer set: " + set)
+	}
+
+	ls := make([]*indexer.LayerArtifacts, len(pkgs))
+	for i, ps := range pkgs {
+		sum := sha256.Sum256([]byte("layer-" + strconv.Itoa(i)))
+		d, err := claircore.NewDigest("sha256", sum[:])
+		if err != nil {
+			panic(err)
+		}
+		l := &indexer.LayerArtifacts{
+			Hash: d,
+			Pkgs: ps,
+		}
+		if len(ps) != 0 {
+			if tc.Distribution != nil {
+				d := *tc.Distribution
+				l.Dist = []*claircore.Distribution{&d}
+			}
+			if tc.Repository != nil {
+				r := *tc.Repository
+				l.Repos = []*claircore.Repository{&r}
+			}
+		}
+		ls[i] = l
+	}
+	return ls
+}
+
+func repositoryHint(r *claircore.Repository) string {
+	if r == nil {
+		return ""
+	}
+	return r.URI
+}
+
+// CheckCoalesced reports violations of the invariants of a coalesced
+// IndexReport: every package has an environment and came from one of the
+// layers, and every environment refers to a layer and to distributions and
+// repositories present in the report.
+func checkCoalesced(t *testing.T, ls []*indexer.LayerArtifacts, ir *claircore.IndexReport) {
+	t.Helper()
+	layers := make(map[string]bool, len(ls))
+	found := make(map[string]bool)
+	for _, l := range ls {
+		layers[l.Hash.String()] = true
+		for _, p := range l.Pkgs {
+			found[p.ID] = true
+		}
+	}
+	for id, p := range ir.Packages {
+		if p.ID != id {
+			t.Errorf("package %q: keyed as %q", p.ID, id)
+		}
+		if !found[id] {
+			t.Errorf("package %q: not in any layer", id)
+		}
+		if len(ir.Environments[id]) == 0 {
+			t.Errorf("package %q: no environments", id)
+		}
+	}
+	for id, envs := range ir.Environments {
+		if _, ok := ir.Packages[id]; !ok {
+			t.Errorf("environments for missing package %q", id)
+		}
+		for _, env := range envs {
+			if !layers[env.IntroducedIn.String()] {
+				t.Errorf("package %q: introduced in unknown layer %v", id, env.IntroducedIn)
+			}
+			if env.DistributionID != "" {
+				if _, ok := ir.Distributions[env.DistributionID]; !ok {
+					t.Errorf("package %q: missing distribution %q", id, env.DistributionID)
+				}
+			}
+			for _, r := range env.RepositoryIDs {
+				if _, ok := ir.Repositories[r]; !ok {
+					t.Errorf("package %q: missing repository %q", id, r)
+				}
+			}
+		}
+	}
+	for id, d := range ir.DistributionsIntroducedIn {
+		if _, ok := ir.Distributions[id]; !ok {
+			t.Errorf("introducing layer for missing distribution %q", id)
+		}
+		if !layers[d.String()] {
+			t.Errorf("distribution %q: introduced in unknown layer %v", id, d)
+		}
+	}
+	for id, d := range ir.RepositoriesIntroducedIn {
+		if _, ok := ir.Repositories[id]; !ok {
+			t.Errorf("introducing layer for missing repository %q", id)
+		}
+		if !layers[d.String()] {
+			t.Errorf("repository %q: introduced in unknown layer %v", id, d)
+		}
+	}
+}
+
+// CheckUpgrade reports if the upgraded package in the "Upgrade" set isn't
+// reported as introduced in the upgrading layer.
+func checkUpgrade(t *testing.T, ls []*indexer.LayerArtifacts, ir *claircore.IndexReport) {
+	t.Helper()
+	const id = "a-2.0.0"
+	if _, ok := ir.Packages[id]; !ok {
+		t.Fatalf("upgraded package %q missing", id)
+	}
+	want := ls[len(ls)-1].Hash.String()
+	for _, env := range ir.Environments[id] {
+		if got := env.IntroducedIn.String(); got != want {
+			t.Errorf("upgraded package introduced in %s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Package generator produces deterministic, synthetic datasets for benchmarks
+// and integration tests.
+//
+// The same Config always produces the same Dataset, so a performance problem
+// can be reproduced by sharing the Config. The shape of the data is meant to
+// resemble what's seen in the wild: a handful of packages account for most
+// vulnerabilities, most vulnerabilities have a fix, and image sizes follow a
+// histogram of real-world package counts.
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Config describes the size of a Dataset.
+type Config struct {
+	// Seed seeds the random number generator.
+	Seed int64
+	// Updaters is the number of updaters, each for a distinct distribution.
+	Updaters int
+	// Vulnerabilities is the number of vulnerabilities per updater.
+	Vulnerabilities int
+	// Manifests is the number of IndexReports.
+	Manifests int
+	// Enrichments is the number of CVSS enrichment records.
+	Enrichments int
+}
+
+// Dataset is a generated set of vulnerabilities, IndexReports, and
+// enrichments.
+type Dataset struct {
+	Config      Config
+	Updaters    []*Updater
+	Reports     []*claircore.IndexReport
+	Enrichments []driver.EnrichmentRecord
+}
+
+// Updater is the output of a single synthetic updater.
+type Updater struct {
+	Name            string
+	Distribution    *claircore.Distribution
+	Vulnerabilities []*claircore.Vulnerability
+
+	// Package names, most vulnerable first.
+	pkgs []string
+}
+
+// Records returns the IndexRecords for every report in the Dataset.
+func (d *Dataset) Records() []*claircore.IndexRecord {
+	var out []*claircore.IndexRecord
+	for _, r := range d.Reports {
+		out = append(out, r.IndexRecords()...)
+	}
+	return out
+}
+
+// Distributions the updaters are modeled on.
+var dists = []claircore.Distribution{
+	{DID: "debian", Name: "Debian GNU/Linux", VersionID: "10", VersionCodeName: "buster"},
+	{DID: "ubuntu", Name: "Ubuntu", VersionID: "20.04", VersionCodeName: "focal"},
+	{DID: "alpine", Name: "Alpine Linux", VersionID: "3.12"},
+	{DID: "rhel", Name: "Red Hat Enterprise Linux Server", VersionID: "8"},
+	{DID: "debian", Name: "Debian GNU/Linux", VersionID: "11", VersionCodeName: "bullseye"},
+	{DID: "ubuntu", Name: "Ubuntu", VersionID: "18.04", VersionCodeName: "bionic"},
+	{DID: "alpine", Name: "Alpine Linux", VersionID: "3.13"},
+	{DID: "rhel", Name: "Red Hat Enterprise Linux Server", VersionID: "7"},
+}
+
+// PackageCounts is an approximate histogram of the number of packages in
+// images found in the wild, from minimal images up to images with large
+// language dependency trees.
+var packageCounts = []struct {
+	min, max int
+	weight   float64
+}{
+	{10, 30, 0.20},
+	{80, 150, 0.35},
+	{150, 250, 0.25},
+	{250, 500, 0.15},
+	{500, 1500, 0.05},
+}
+
+// Severities and their approximate share of vulnerabilities.
+var severities = []struct {
+	name   string
+	sev    claircore.Severity
+	weight float64
+}{
+	{"Low", claircore.Low, 0.25},
+	{"Medium", claircore.Medium, 0.45},
+	{"High", claircore.High, 0.22},
+	{"Critical", claircore.Critical, 0.05},
+	{"Unknown", claircore.Unknown, 0.03},
+}
+
+const (
+	// Share of vulnerabilities with a fixed version.
+	fixedRatio = 0.8
+	// Share of vulnerabilities with a version range.
+	rangeRatio = 0.3
+	// Share of a report's packages drawn from the packages with
+	// vulnerabilities.
+	vulnerableRatio = 0.6
+	// Share of binary packages built from a differently named source.
+	splitSourceRatio = 0.3
+	// Bound on distinct CVE names, well under the number possible.
+	maxNames = 1 << 20
+)
+
+var words = strings.Fields(`
+a an the in of to and or via when with without before after allows could may
+remote local attacker user crafted malicious request packet file header buffer
+overflow underflow use-after-free double free null pointer dereference
+integer out-of-bounds read write memory corruption denial service arbitrary
+code execution privilege escalation information disclosure cross-site
+scripting injection validation improper handling certificate signature
+parser decoder encoder library function component module server client`)
+
+var syllables = []string{
+	"ssl", "xml", "z", "png", "jpeg", "curl", "ssh", "krb", "gnu", "tls",
+	"sql", "yaml", "db", "pcre", "ffi", "idn", "tiff", "gcrypt", "nss", "uv",
+	"event", "cap", "acl", "attr", "audit", "blk", "bz", "lz", "magic", "pam",
+}
+
+// Generate returns the Dataset for the Config.
+func Generate(c Config) *Dataset {
+	g := &gen{r: rand.New(rand.NewSource(c.Seed))}
+	d := &Dataset{Config: c}
+	n := c.Updaters*c.Vulnerabilities/2 + c.Enrichments + 1
+	if n > maxNames {
+		n = maxNames
+	}
+	names := g.cveNames(n)
+	for i := 0; i < c.Updaters; i++ {
+		d.Updaters = append(d.Updaters, g.updater(i, c.Vulnerabilities, names))
+	}
+	for i := 0; i < c.Manifests && len(d.Updaters) != 0; i++ {
+		d.Reports = append(d.Reports, g.report(i, d.Updaters[g.r.Intn(len(d.Updaters))]))
+	}
+	for i := 0; i < c.Enrichments; i++ {
+		d.Enrichments = append(d.Enrichments, g.enrichment(names[i%len(names)]))
+	}
+	return d
+}
+
+type gen struct {
+	r *rand.Rand
+}
+
+// CveNames returns "n" distinct CVE names, weighted towards recent years.
+func (g *gen) cveNames(n int) []string {
+	seen := make(map[string]struct{}, n)
+	out := make([]string, 0, n)
+	for len(out) < n {
+		// Squaring skews towards 1, so towards the most recent year.
+		f := 1 - g.r.Float64()*g.r.Float64()
+		year := 1999 + int(f*22)
+		num := 1 + g.r.Intn(99999)
+		name := fmt.Sprintf("CVE-%d-%04d", year, num)
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	return out
+}
+
+func (g *gen) packageName() string {
+	n := syllables[g.r.Intn(len(syllables))]
+	switch g.r.Intn(4) {
+	case 0:
+		n = "lib" + n
+	case 1:
+		n = n + "-utils"
+	case 2:
+		n = "lib" + n + strconv.Itoa(1+g.r.Intn(3))
+	}
+	return n + "-" + strconv.Itoa(g.r.Intn(1000))
+}
+
+func (g *gen) version() (string, [3]int32) {
+	v := [3]int32{int32(g.r.Intn(10)), int32(g.r.Intn(20)), int32(g.r.Intn(40))}
+	return fmt.Sprintf("%d.%d.%d-%d", v[0], v[1], v[2], 1+g.r.Intn(5)), v
+}
+
+// Description returns text whose length is roughly log-normally distributed,
+// with a median of about 180 characters.
+func (g *gen) description() string {
+	n := int(math.Exp(math.Log(180) + 0.6*g.r.NormFloat64()))
+	switch {
+	case n < 20:
+		n = 20
+	case n > 4000:
+		n = 4000
+	}
+	var b strings.Builder
+	for b.Len() < n {
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(words[g.r.Intn(len(words))])
+	}
+	b.WriteByte('.')
+	return b.String()
+}
+
+func (g *gen) severity() (string, claircore.Severity) {
+	f := g.r.Float64()
+	for _, s := range severities {
+		if f < s.weight {
+			return s.name, s.sev
+		}
+		f -= s.weight
+	}
+	s := severities[len(severities)-1]
+	return s.name, s.sev
+}
+
+func (g *gen) updater(i, n int, names []string) *Updater {
+	dist := dists[i%len(dists)]
+	u := &Updater{
+		Name:         fmt.Sprintf("generated-%s-%s-%d", dist.DID, dist.VersionID, i),
+		Distribution: &dist,
+	}
+	pool := n/4 + 1
+	for j := 0; j < pool; j++ {
+		u.pkgs = append(u.pkgs, g.packageName())
+	}
+	// A few packages account for most vulnerabilities.
+	z := rand.NewZipf(g.r, 1.2, 1, uint64(pool-1))
+	for j := 0; j < n; j++ {
+		pkg := u.pkgs[z.Uint64()]
+		sev, norm := g.severity()
+		name := names[g.r.Intn(len(names))]
+		year, _ := strconv.Atoi(name[4:8])
+		d := dist
+		v := &claircore.Vulnerability{
+			Updater:            u.Name,
+			Name:               name,
+			Description:        g.description(),
+			Issued:             time.Date(year, time.Month(1+g.r.Intn(12)), 1+g.r.Intn(28), 0, 0, 0, 0, time.UTC),
+			Links:              "https://nvd.nist.gov/vuln/detail/" + name,
+			Severity:           sev,
+			NormalizedSeverity: norm,
+			Package: &claircore.Package{
+				Name: pkg,
+				Kind: claircore.SOURCE,
+			},
+			Dist: &d,
+			Repo: &claircore.Repository{},
+		}
+		if g.r.Float64() < fixedRatio {
+			fixed, fv := g.version()
+			v.FixedInVersion = fixed
+			if g.r.Float64() < rangeRatio {
+				v.Range = &claircore.Range{
+					Upper: claircore.Version{Kind: "semver", V: [10]int32{0, fv[0], fv[1], fv[2]}},
+				}
+			}
+		}
+		u.Vulnerabilities = append(u.Vulnerabilities, v)
+	}
+	return u
+}
+
+func (g *gen) packageCount() int {
+	f := g.r.Float64()
+	for _, b := range packageCounts {
+		if f < b.weight {
+			return b.min + g.r.Intn(b.max-b.min+1)
+		}
+		f -= b.weight
+	}
+	b := packageCounts[len(packageCounts)-1]
+	return b.min + g.r.Intn(b.max-b.min+1)
+}
+
+func (g *gen) report(i int, u *Updater) *claircore.IndexReport {
+	sum := fmt.Sprintf("%064x", uint64(i)+1)
+	d := *u.Distribution
+	d.ID = "1"
+	ir := &claircore.IndexReport{
+		Hash:          claircore.MustParseDigest("sha256:" + sum),
+		State:         "IndexFinished",
+		Success:       true,
+		Packages:      map[string]*claircore.Package{},
+		Distributions: map[string]*claircore.Distribution{d.ID: &d},
+		Repositories:  map[string]*claircore.Repository{},
+		Environments:  map[string][]*claircore.Environment{},
+	}
+	layer := claircore.MustParseDigest("sha256:" + sum)
+	n := g.packageCount()
+	for j := 0; j < n; j++ {
+		var src string
+		if g.r.Float64() < vulnerableRatio {
+			src = u.pkgs[g.r.Intn(len(u.pkgs))]
+		} else {
+			src = g.packageName()
+		}
+		name := src
+		if g.r.Float64() < splitSourceRatio {
+			name = src + "-" + words[g.r.Intn(len(words))]
+		}
+		ver, _ := g.version()
+		id := strconv.Itoa(j + 1)
+		ir.Packages[id] = &claircore.Package{
+			ID:      id,
+			Name:    name,
+			Version: ver,
+			Kind:    claircore.BINARY,
+			Arch:    "x86_64",
+			Source: &claircore.Package{
+				Name:    src,
+				Version: ver,
+				Kind:    claircore.SOURCE,
+			},
+		}
+		ir.Environments[id] = []*claircore.Environment{{
+			PackageDB:      "var/lib/dpkg/status",
+			IntroducedIn:   layer,
+			DistributionID: d.ID,
+		}}
+	}
+	return ir
+}
+
+var cvssMetrics = []struct {
+	name   string
+	values string
+}{
+	{"AV", "NALP"},
+	{"AC", "LH"},
+	{"PR", "NLH"},
+	{"UI", "NR"},
+	{"S", "UC"},
+	{"C", "HLN"},
+	{"I", "HLN"},
+	{"A", "HLN"},
+}
+
+func (g *gen) enrichment(name string) driver.EnrichmentRecord {
+	var b strings.Builder
+	b.WriteString("CVSS:3.1")
+	for _, m := range cvssMetrics {
+		b.WriteString("/" + m.name + ":")
+		b.WriteByte(m.values[g.r.Intn(len(m.values))])
+	}
+	score := math.Round(g.r.Float64()*100) / 10
+	e, err := json.Marshal(struct {
+		Version      string  `json:"version"`
+		VectorString string  `json:"vectorString"`
+		BaseScore    float64 `json:"baseScore"`
+	}{"3.1", b.String(), score})
+	if err != nil {
+		panic(err)
+	}
+	return driver.EnrichmentRecord{
+		Tags:       []string{name},
+		Enrichment: e,
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeterministic(t *testing.T) {
+	c := Config{Seed: 1, Updaters: 3, Vulnerabilities: 200, Manifests: 5, Enrichments: 50}
+	enc := func(d *Dataset) string {
+		b, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	a, b := enc(Generate(c)), enc(Generate(c))
+	if a != b {
+		t.Error("same config generated different datasets")
+	}
+	c.Seed = 2
+	if enc(Generate(c)) == a {
+		t.Error("different seeds generated the same dataset")
+	}
+}
+
+func TestShape(t *testing.T) {
+	c := Config{Seed: 1, Updaters: 4, Vulnerabilities: 1000, Manifests: 200, Enrichments: 100}
+	d := Generate(c)
+	if got, want := len(d.Updaters), c.Updaters; got != want {
+		t.Errorf("got %d updaters, want %d", got, want)
+	}
+	names := make(map[string]bool)
+	for _, u := range d.Updaters {
+		names[u.Name] = true
+		if got, want := len(u.Vulnerabilities), c.Vulnerabilities; got != want {
+			t.Errorf("%s: got %d vulnerabilities, want %d", u.Name, got, want)
+		}
+		// Vulnerabilities should be concentrated in a few packages.
+		count := make(map[string]int)
+		var fixed int
+		for _, v := range u.Vulnerabilities {
+			count[v.Package.Name]++
+			if v.FixedInVersion != "" {
+				fixed++
+			}
+		}
+		if top := count[u.pkgs[0]]; top < c.Vulnerabilities/20 {
+			t.Errorf("%s: most vulnerable package only has %d vulnerabilities", u.Name, top)
+		}
+		if r := float64(fixed) / float64(c.Vulnerabilities); r < 0.7 || r > 0.9 {
+			t.Errorf("%s: fixed ratio %.2f", u.Name, r)
+		}
+	}
+	if len(names) != len(d.Updaters) {
+		t.Error("updater names not unique")
+	}
+	if got, want := len(d.Reports), c.Manifests; got != want {
+		t.Errorf("got %d reports, want %d", got, want)
+	}
+	small, large := 0, 0
+	for _, r := range d.Reports {
+		n := len(r.Packages)
+		if n < packageCounts[0].min || n > packageCounts[len(packageCounts)-1].max {
+			t.Errorf("report with %d packages", n)
+		}
+		switch {
+		case n <= 30:
+			small++
+		case n >= 250:
+			large++
+		}
+	}
+	if small == 0 || large == 0 {
+		t.Errorf("package counts not spread out: %d small, %d large", small, large)
+	}
+	if got, want := len(d.Enrichments), c.Enrichments; got != want {
+		t.Errorf("got %d enrichments, want %d", got, want)
+	}
+	if len(d.Records()) == 0 {
+		t.Error("no records")
+	}
+}
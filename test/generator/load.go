@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// EnrichmentUpdater is the name enrichments are loaded under.
+const EnrichmentUpdater = "generated-cvss"
+
+// LoadVulnerabilities runs an update operation for each of the Dataset's
+// updaters.
+func LoadVulnerabilities(ctx context.Context, s vulnstore.Updater, d *Dataset) error {
+	for _, u := range d.Updaters {
+		if _, err := s.UpdateVulnerabilities(ctx, u.Name, d.fingerprint(u.Name), u.Vulnerabilities); err != nil {
+			return fmt.Errorf("generator: loading %q: %w", u.Name, err)
+		}
+	}
+	return nil
+}
+
+// LoadEnrichments runs an update operation for the Dataset's enrichments, as
+// EnrichmentUpdater.
+func LoadEnrichments(ctx context.Context, s vulnstore.EnrichmentUpdater, d *Dataset) error {
+	if _, err := s.UpdateEnrichments(ctx, EnrichmentUpdater, d.fingerprint(EnrichmentUpdater), d.Enrichments); err != nil {
+		return fmt.Errorf("generator: loading enrichments: %w", err)
+	}
+	return nil
+}
+
+// Fingerprint returns a fingerprint identifying the Dataset's data for the
+// named updater.
+func (d *Dataset) fingerprint(name string) driver.Fingerprint {
+	return driver.Fingerprint(fmt.Sprintf("%s@%d", name, d.Config.Seed))
+}
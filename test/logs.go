@@ -0,0 +1,49 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+// CaptureLogs is like zlog.Test, but additionally records every log line
+// written using the returned Context, so tests can make assertions about
+// them. Lines are still reported to "t".
+func CaptureLogs(ctx context.Context, t testing.TB) (context.Context, *LogCapture) {
+	t.Helper()
+	c := &LogCapture{TB: t}
+	return zlog.Test(ctx, c), c
+}
+
+// LogCapture records log lines. See CaptureLogs.
+type LogCapture struct {
+	testing.TB
+	mu    sync.Mutex
+	lines []map[string]interface{}
+}
+
+// Log implements testing.TB.
+//
+// The zlog test sink writes each log line as a single argument.
+func (c *LogCapture) Log(args ...interface{}) {
+	c.TB.Helper()
+	var l map[string]interface{}
+	if err := json.Unmarshal([]byte(fmt.Sprint(args...)), &l); err == nil {
+		c.mu.Lock()
+		c.lines = append(c.lines, l)
+		c.mu.Unlock()
+	}
+	c.TB.Log(args...)
+}
+
+// Lines returns the decoded log lines recorded so far, in the order they were
+// written.
+func (c *LogCapture) Lines() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]map[string]interface{}(nil), c.lines...)
+}
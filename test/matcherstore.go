@@ -0,0 +1,503 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/datastore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// MatcherStoreTestcase checks a datastore.MatcherStore implementation
+// against the behavior libvuln relies on.
+//
+// Every check is run as a subtest against its own empty store.
+type MatcherStoreTestcase struct {
+	// New returns an empty MatcherStore. It's called once per subtest and
+	// should arrange for any cleanup with the passed T.
+	New func(context.Context, *testing.T) datastore.MatcherStore
+}
+
+// Run returns a function suitable for using with (*testing.T).Run.
+func (tc MatcherStoreTestcase) Run(ctx context.Context) func(*testing.T) {
+	checks := []struct {
+		Name  string
+		Check func(context.Context, *testing.T, datastore.MatcherStore)
+	}{
+		{"Initialized", checkInitialized},
+		{"UpdateOperations", checkUpdateOperations},
+		{"Get", checkGet},
+		{"UpdateDiff", checkUpdateDiff},
+		{"DeleteUpdateOperations", checkDeleteUpdateOperations},
+		{"GC", checkGC},
+		{"Enrichments", checkEnrichments},
+		{"Exclusions", checkExclusions},
+		{"Stats", checkStats},
+		{"Import", checkImport},
+		{"Idempotent", checkIdempotent},
+	}
+	return func(t *testing.T) {
+		for _, c := range checks {
+			c := c
+			t.Run(c.Name, func(t *testing.T) {
+				ctx := zlog.Test(ctx, t)
+				c.Check(ctx, t, tc.New(ctx, t))
+			})
+		}
+	}
+}
+
+const testStoreUpdater = "test-store-updater"
+
+// StoreVulns returns "n" distinct vulnerabilities, all affecting the package
+// "test-store-package" and numbered from "off".
+func storeVulns(off, n int) []*claircore.Vulnerability {
+	vs := make([]*claircore.Vulnerability, n)
+	for i := range vs {
+		vs[i] = &claircore.Vulnerability{
+			Updater:        testStoreUpdater,
+			Name:           "CVE-" + string(rune('A'+off+i)),
+			Description:    "test vulnerability",
+			Severity:       "High",
+			FixedInVersion: "2.0.0",
+			Package: &claircore.Package{
+				Name: "test-store-package",
+				Kind: claircore.BINARY,
+			},
+			Dist: &claircore.Distribution{},
+			Repo: &claircore.Repository{},
+		}
+	}
+	return vs
+}
+
+func updateVulns(ctx context.Context, t *testing.T, s datastore.MatcherStore, fp string, vs []*claircore.Vulnerability) uuid.UUID {
+	t.Helper()
+	ref, err := s.UpdateVulnerabilities(ctx, testStoreUpdater, driver.Fingerprint(fp), vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref == uuid.Nil {
+		t.Fatal("UpdateVulnerabilities returned a nil ref")
+	}
+	return ref
+}
+
+func vulnNames(vs []claircore.Vulnerability) map[string]bool {
+	out := make(map[string]bool, len(vs))
+	for _, v := range vs {
+		out[v.Name] = true
+	}
+	return out
+}
+
+func checkInitialized(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	ok, err := s.Initialized(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("empty store reports initialized")
+	}
+	updateVulns(ctx, t, s, "1", storeVulns(0, 1))
+	ok, err = s.Initialized(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("store with vulnerabilities reports uninitialized")
+	}
+}
+
+func checkUpdateOperations(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	first := updateVulns(ctx, t, s, "1", storeVulns(0, 1))
+	second := updateVulns(ctx, t, s, "2", storeVulns(0, 2))
+	if err := s.SetDataSource(ctx, second, claircore.DataSource{URL: "https://example.com/data"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ops[testStoreUpdater]
+	if len(got) != 2 {
+		t.Fatalf("got %d update operations, want 2", len(got))
+	}
+	// Newest first.
+	if got[0].Ref != second || got[1].Ref != first {
+		t.Errorf("got refs %v, %v; want %v, %v", got[0].Ref, got[1].Ref, second, first)
+	}
+	if got, want := got[0].Fingerprint, driver.Fingerprint("2"); got != want {
+		t.Errorf("got fingerprint %q, want %q", got, want)
+	}
+	if src := got[0].Source; src == nil || src.URL != "https://example.com/data" {
+		t.Errorf("data source not recorded: %+v", src)
+	}
+	ops, err = s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ops[testStoreUpdater]); got != 2 {
+		t.Errorf("all updaters: got %d update operations, want 2", got)
+	}
+	ops, err = s.GetUpdateOperations(ctx, driver.EnrichmentKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ops[testStoreUpdater]); got != 0 {
+		t.Errorf("enrichment kind: got %d update operations, want 0", got)
+	}
+
+	ref, err := s.GetLatestUpdateRef(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != second {
+		t.Errorf("got latest ref %v, want %v", ref, second)
+	}
+	latest, err := s.GetLatestUpdateRefs(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := latest[testStoreUpdater]; len(l) == 0 || l[0].Ref != second {
+		t.Errorf("got latest refs %+v, want %v first", l, second)
+	}
+}
+
+func checkGet(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	updateVulns(ctx, t, s, "1", storeVulns(0, 2))
+	rs := []*claircore.IndexRecord{
+		{
+			Package:      &claircore.Package{ID: "1", Name: "test-store-package", Version: "1.0.0", Kind: claircore.BINARY},
+			Distribution: &claircore.Distribution{},
+			Repository:   &claircore.Repository{},
+		},
+		{
+			Package:      &claircore.Package{ID: "2", Name: "unaffected-package", Version: "1.0.0", Kind: claircore.BINARY},
+			Distribution: &claircore.Distribution{},
+			Repository:   &claircore.Repository{},
+		},
+	}
+	res, err := s.Get(ctx, rs, datastore.GetOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := res["1"]
+	if len(got) != 2 {
+		t.Fatalf("got %d vulnerabilities, want 2", len(got))
+	}
+	for _, v := range got {
+		if v.ID == "" {
+			t.Errorf("vulnerability %q has no ID", v.Name)
+		}
+		if v.Package == nil || v.Package.Name != "test-store-package" {
+			t.Errorf("vulnerability %q has package %+v", v.Name, v.Package)
+		}
+		if got, want := v.FixedInVersion, "2.0.0"; got != want {
+			t.Errorf("vulnerability %q: got fixed version %q, want %q", v.Name, got, want)
+		}
+	}
+	if got := len(res["2"]); got != 0 {
+		t.Errorf("unaffected package: got %d vulnerabilities, want 0", got)
+	}
+}
+
+func checkUpdateDiff(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	// The second update drops the first vulnerability and adds a third.
+	prev := updateVulns(ctx, t, s, "1", storeVulns(0, 2))
+	cur := updateVulns(ctx, t, s, "2", storeVulns(1, 2))
+	diff, err := s.GetUpdateDiff(ctx, prev, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil {
+		t.Fatal("got nil diff")
+	}
+	if diff.Prev.Ref != prev || diff.Cur.Ref != cur {
+		t.Errorf("got refs %v → %v, want %v → %v", diff.Prev.Ref, diff.Cur.Ref, prev, cur)
+	}
+	if got := vulnNames(diff.Added); len(got) != 1 || !got["CVE-C"] {
+		t.Errorf("got added %v, want CVE-C", got)
+	}
+	if got := vulnNames(diff.Removed); len(got) != 1 || !got["CVE-A"] {
+		t.Errorf("got removed %v, want CVE-A", got)
+	}
+}
+
+func checkDeleteUpdateOperations(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	first := updateVulns(ctx, t, s, "1", storeVulns(0, 1))
+	second := updateVulns(ctx, t, s, "2", storeVulns(0, 1))
+	n, err := s.DeleteUpdateOperations(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("deleted %d update operations, want 1", n)
+	}
+	n, err = s.DeleteUpdateOperations(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("deleted %d update operations again, want 0", n)
+	}
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops[testStoreUpdater]; len(got) != 1 || got[0].Ref != second {
+		t.Errorf("got update operations %+v, want only %v", got, second)
+	}
+}
+
+func checkGC(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	const keep = 2
+	for i, fp := range []string{"1", "2", "3", "4"} {
+		updateVulns(ctx, t, s, fp, storeVulns(i, 1))
+	}
+	// Implementations may do the work in batches, but shouldn't need many
+	// for this.
+	for i := 0; ; i++ {
+		left, err := s.GC(ctx, keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if left == 0 {
+			break
+		}
+		if i == 10 {
+			t.Fatalf("GC not finished after %d calls: %d remaining", i+1, left)
+		}
+	}
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ops[testStoreUpdater]); got != keep {
+		t.Errorf("got %d update operations after GC, want %d", got, keep)
+	}
+}
+
+func checkEnrichments(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	const name = "test-store-enricher"
+	rs := []driver.EnrichmentRecord{
+		{Tags: []string{"CVE-A"}, Enrichment: json.RawMessage(`{"score":1}`)},
+		{Tags: []string{"CVE-B", "CVE-C"}, Enrichment: json.RawMessage(`{"score":2}`)},
+	}
+	ref, err := s.UpdateEnrichments(ctx, name, driver.Fingerprint("1"), rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref == uuid.Nil {
+		t.Fatal("UpdateEnrichments returned a nil ref")
+	}
+
+	got, err := s.GetEnrichment(ctx, name, []string{"CVE-C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d enrichment records, want 1", len(got))
+	}
+	var v struct{ Score int }
+	if err := json.Unmarshal(got[0].Enrichment, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Score != 2 {
+		t.Errorf("got record %s, want score 2", got[0].Enrichment)
+	}
+	got, err = s.GetEnrichment(ctx, name, []string{"CVE-Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unknown tag: got %d enrichment records, want 0", len(got))
+	}
+
+	kind, have := driver.HashEnrichment(&rs[0])
+	ok, err := s.EnrichmentExists(ctx, name, kind, have)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("stored enrichment reported missing")
+	}
+	absent := driver.EnrichmentRecord{Tags: []string{"CVE-Z"}, Enrichment: json.RawMessage(`{}`)}
+	_, missing := driver.HashEnrichment(&absent)
+	m, err := s.MissingEnrichments(ctx, name, kind, [][]byte{have, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || string(m[0]) != string(missing) {
+		t.Errorf("got %d missing hashes, want only the absent record's", len(m))
+	}
+
+	ops, err := s.GetUpdateOperations(ctx, driver.EnrichmentKind, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops[name]; len(got) != 1 || got[0].Ref != ref {
+		t.Errorf("got update operations %+v, want only %v", got, ref)
+	}
+}
+
+func checkExclusions(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	e, err := s.AddExclusion(ctx, claircore.Exclusion{
+		Vulnerability: "CVE-A",
+		Package:       "test-store-package",
+		Reason:        "not reachable",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == uuid.Nil || e.Created.IsZero() {
+		t.Errorf("ID or creation time not set: %+v", e)
+	}
+	if _, err := s.AddExclusion(ctx, claircore.Exclusion{Package: "test-store-package"}); err == nil {
+		t.Error("invalid exclusion accepted")
+	}
+	es, err := s.ListExclusions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != e.ID {
+		t.Errorf("got exclusions %+v, want only %v", es, e.ID)
+	}
+	ok, err := s.DeleteExclusion(ctx, e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("stored exclusion not deleted")
+	}
+	ok, err = s.DeleteExclusion(ctx, e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("deleted exclusion deleted again")
+	}
+}
+
+func checkStats(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	updateVulns(ctx, t, s, "1", storeVulns(0, 1))
+	st, err := s.Stats(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st == nil {
+		t.Fatal("got nil stats")
+	}
+	if st.ExpiredUpdateOperations < 0 || st.OrphanedVulnerabilities < 0 || st.UnreachableEnrichments < 0 {
+		t.Errorf("negative counts: %+v", st)
+	}
+}
+
+func checkImport(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	imp, ok := s.(datastore.Importer)
+	if !ok {
+		t.Skip("store doesn't implement Importer")
+	}
+	date := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ref uuid.UUID
+	err := imp.ImportUpdates(ctx, func(add datastore.ImportFunc) error {
+		var err error
+		ref, err = add(ctx, &datastore.Import{
+			Updater:     testStoreUpdater,
+			Fingerprint: driver.Fingerprint("1"),
+			Date:        date,
+			Vulns:       storeVulns(0, 2),
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A failed import records nothing.
+	errAbort := errors.New("abort")
+	err = imp.ImportUpdates(ctx, func(add datastore.ImportFunc) error {
+		if _, err := add(ctx, &datastore.Import{
+			Updater:     testStoreUpdater,
+			Fingerprint: driver.Fingerprint("2"),
+			Date:        date.Add(time.Hour),
+			Vulns:       storeVulns(2, 1),
+		}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("got error %v, want %v", err, errAbort)
+	}
+
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ops[testStoreUpdater]
+	if len(got) != 1 {
+		t.Fatalf("got %d update operations, want 1", len(got))
+	}
+	if got[0].Ref != ref {
+		t.Errorf("got ref %v, want %v", got[0].Ref, ref)
+	}
+	if !got[0].Date.Equal(date) {
+		t.Errorf("got date %v, want %v", got[0].Date, date)
+	}
+	rs := []*claircore.IndexRecord{{
+		Package:      &claircore.Package{ID: "1", Name: "test-store-package", Version: "1.0.0", Kind: claircore.BINARY},
+		Distribution: &claircore.Distribution{},
+		Repository:   &claircore.Repository{},
+	}}
+	res, err := s.Get(ctx, rs, datastore.GetOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, v := range res["1"] {
+		names[v.Name] = true
+	}
+	if len(names) != 2 || !names["CVE-A"] || !names["CVE-B"] {
+		t.Errorf("got vulnerabilities %v, want CVE-A and CVE-B", names)
+	}
+}
+
+func checkIdempotent(ctx context.Context, t *testing.T, s datastore.MatcherStore) {
+	idem, ok := s.(datastore.Idempotent)
+	if !ok {
+		t.Skip("store doesn't implement Idempotent")
+	}
+	first, err := idem.UpdateVulnerabilitiesOnce(ctx, "key-1", testStoreUpdater, driver.Fingerprint("1"), storeVulns(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := idem.UpdateVulnerabilitiesOnce(ctx, "key-1", testStoreUpdater, driver.Fingerprint("1"), storeVulns(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Errorf("retried update: got ref %v, want %v", again, first)
+	}
+	second, err := idem.UpdateVulnerabilitiesOnce(ctx, "key-2", testStoreUpdater, driver.Fingerprint("2"), storeVulns(0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Error("update with a new key returned the old ref")
+	}
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, testStoreUpdater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ops[testStoreUpdater]); got != 2 {
+		t.Errorf("got %d update operations, want 2", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -57,8 +58,11 @@ func (tc ScannerTestcase) Run(ctx context.Context) func(*testing.T) {
 			t.Fatal(err)
 		}
 		t.Logf("found %d packages", len(got))
-		if !cmp.Equal(tc.Want, got) {
-			t.Error(cmp.Diff(tc.Want, got))
+		// Testcases may predate recording licenses, which scanners check
+		// with their own fixtures.
+		opt := cmpopts.IgnoreFields(claircore.Package{}, "License")
+		if !cmp.Equal(tc.Want, got, opt) {
+			t.Error(cmp.Diff(tc.Want, got, opt))
 		}
 	}
 }
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// BadVersions is a corpus of versions found in the wild that no Matcher should
+// be able to parse: empty strings, placeholders, and branch or commit names.
+var BadVersions = []string{
+	"",
+	" ",
+	"\t",
+	"latest",
+	"master",
+	"HEAD",
+	"(devel)",
+	"unknown",
+	"null",
+	"N/A",
+	"!!!",
+	"~",
+	"deadbeefcafe",
+}
+
+// BadVersionTestcase checks that a Matcher reports unparseable versions with
+// a driver.VersionError, on both the package and the vulnerability side.
+type BadVersionTestcase struct {
+	Matcher driver.Matcher
+	// Record returns a record for a package with the version "v".
+	Record func(v string) *claircore.IndexRecord
+	// Vulnerability returns a vulnerability with the version "v" in whatever
+	// field the Matcher compares against.
+	Vulnerability func(v string) *claircore.Vulnerability
+	// Good is a version that parses for both Record and Vulnerability.
+	Good string
+	// Extra is checked in addition to BadVersions, for versions only some
+	// schemes reject.
+	Extra []string
+}
+
+// Run returns a function suitable for using with (*testing.T).Run.
+func (tc BadVersionTestcase) Run(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	vs := append(append([]string(nil), BadVersions...), tc.Extra...)
+	check := func(t *testing.T, r *claircore.IndexRecord, v *claircore.Vulnerability, bad string, vuln bool) {
+		t.Helper()
+		ok, err := tc.Matcher.Vulnerable(ctx, r, v)
+		if ok {
+			t.Errorf("%q: reported vulnerable", bad)
+		}
+		var verr *driver.VersionError
+		if !errors.As(err, &verr) {
+			t.Errorf("%q: got error %v, want a *driver.VersionError", bad, err)
+			return
+		}
+		if verr.Version != bad || verr.Vulnerability != vuln {
+			t.Errorf("%q: got %+v", bad, verr)
+		}
+	}
+	t.Run("Package", func(t *testing.T) {
+		for _, bad := range vs {
+			check(t, tc.Record(bad), tc.Vulnerability(tc.Good), bad, false)
+		}
+	})
+	t.Run("Vulnerability", func(t *testing.T) {
+		for _, bad := range vs {
+			if driver.EmptyVersion(bad) {
+				// An empty vulnerability version usually means every
+				// version is affected, so it's not checked.
+				continue
+			}
+			check(t, tc.Record(tc.Good), tc.Vulnerability(bad), bad, true)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+// Package http provides http.Handlers for the core Libindex and Libvuln
+// operations, for embedders to mount in their own servers.
+//
+// The handlers only translate between HTTP and the library calls: there's no
+// authentication, and nothing here listens on a socket. Request and response
+// bodies are the JSON encodings of the claircore types, and the routes are
+// described in the OpenAPI document "openapi.yaml" alongside this package.
+//
+// Every route is rooted, so handlers are expected to be mounted with
+// http.StripPrefix if they're not served at the root of a server. The package
+// is usually imported under another name, to not collide with net/http:
+//
+//	import httptransport "github.com/quay/claircore/transport/http"
+//
+//	mux.Handle("/indexer/", http.StripPrefix("/indexer", httptransport.NewIndexerHandler(lib)))
+//
+// Reports returned from GET requests carry an ETag fingerprinting the encoded
+// report, and conditional requests using If-None-Match are answered with
+// "304 Not Modified" when the report is unchanged. Errors are reported with a
+// JSON body decodable as an Error.
+package http
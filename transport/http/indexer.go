@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+// These are the routes served by an IndexerHandler.
+const (
+	IndexPath       = "/index_report"
+	IndexReportPath = "/index_report/"
+	IndexStatePath  = "/index_state"
+)
+
+// Indexer is the subset of Libindex's methods used by an IndexerHandler.
+type Indexer interface {
+	Index(context.Context, *claircore.Manifest) (*claircore.IndexReport, error)
+	IndexReport(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error)
+	State(context.Context) (string, error)
+}
+
+// IndexerHandler serves the Indexer operations:
+//
+//	POST /index_report            index the Manifest in the body
+//	GET  /index_report/{digest}   retrieve a manifest's IndexReport
+//	GET  /index_state             retrieve the indexer's State
+type IndexerHandler struct {
+	indexer Indexer
+	mux     *http.ServeMux
+}
+
+// NewIndexerHandler returns an IndexerHandler for "i", usually a
+// *libindex.Libindex.
+func NewIndexerHandler(i Indexer) *IndexerHandler {
+	h := &IndexerHandler{
+		indexer: i,
+		mux:     http.NewServeMux(),
+	}
+	h.mux.HandleFunc(IndexPath, h.index)
+	h.mux.HandleFunc(IndexReportPath, h.indexReport)
+	h.mux.HandleFunc(IndexStatePath, h.state)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *IndexerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// IndexStateResponse is the body of a response from IndexStatePath.
+type IndexStateResponse struct {
+	State string `json:"state"`
+}
+
+func (h *IndexerHandler) index(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "transport/http/IndexerHandler.index"))
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	var m claircore.Manifest
+	if !decodeBody(w, r, &m) {
+		return
+	}
+	if m.Hash.Checksum() == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "manifest is missing a hash")
+		return
+	}
+	ir, err := h.indexer.Index(ctx, &m)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	w.Header().Set("location", IndexReportPath+m.Hash.String())
+	writeReport(ctx, w, r, http.StatusCreated, ir)
+}
+
+func (h *IndexerHandler) indexReport(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "transport/http/IndexerHandler.indexReport"))
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	d, ok := parseDigest(w, r.URL.Path, IndexReportPath)
+	if !ok {
+		return
+	}
+	ir, ok, err := h.indexer.IndexReport(ctx, d)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no index report for %q", d.String())
+		return
+	}
+	writeReport(ctx, w, r, http.StatusOK, ir)
+}
+
+func (h *IndexerHandler) state(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "transport/http/IndexerHandler.state"))
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	s, err := h.indexer.State(ctx)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	writeReport(ctx, w, r, http.StatusOK, &IndexStateResponse{State: s})
+}
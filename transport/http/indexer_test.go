@@ -0,0 +1,268 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/inflight"
+	"github.com/quay/claircore/libindex"
+)
+
+var _ Indexer = (*libindex.Libindex)(nil)
+
+const (
+	testDigest    = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	unknownDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+// FakeIndexer serves IndexReports from a map.
+type fakeIndexer struct {
+	reports map[string]*claircore.IndexReport
+	err     error
+}
+
+func (f *fakeIndexer) Index(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	ir := &claircore.IndexReport{
+		Hash:    m.Hash,
+		State:   "IndexFinished",
+		Success: true,
+	}
+	f.reports[m.Hash.String()] = ir
+	return ir, nil
+}
+
+func (f *fakeIndexer) IndexReport(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	ir, ok := f.reports[d.String()]
+	return ir, ok, nil
+}
+
+func (f *fakeIndexer) State(context.Context) (string, error) {
+	return "test-state", f.err
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{
+		reports: map[string]*claircore.IndexReport{
+			testDigest: {
+				Hash:    claircore.MustParseDigest(testDigest),
+				State:   "IndexFinished",
+				Success: true,
+				Packages: map[string]*claircore.Package{
+					"1": {ID: "1", Name: "bash", Version: "5.0"},
+				},
+			},
+		},
+	}
+}
+
+// Do serves a request through "h" and returns the response.
+func do(t *testing.T, h http.Handler, req *http.Request) *http.Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// CheckError checks that "res" is an Error response with the status and
+// code.
+func checkError(t *testing.T, res *http.Response, status int, code string) {
+	t.Helper()
+	if got, want := res.StatusCode, status; got != want {
+		t.Errorf("got status %d, want %d", got, want)
+	}
+	if got, want := res.Header.Get("content-type"), contentType; got != want {
+		t.Errorf("got content-type %q, want %q", got, want)
+	}
+	var e Error
+	if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", e)
+	if got, want := e.Code, code; got != want {
+		t.Errorf("got code %q, want %q", got, want)
+	}
+}
+
+func TestIndexerHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("Index", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		idx := newFakeIndexer()
+		h := NewIndexerHandler(idx)
+		body := `{"hash":"` + unknownDigest + `","layers":[]}`
+		req := httptest.NewRequest(http.MethodPost, IndexPath, strings.NewReader(body)).WithContext(ctx)
+		req.Header.Set("content-type", "application/json; charset=utf-8")
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusCreated; got != want {
+			t.Fatalf("got status %d, want %d", got, want)
+		}
+		if got, want := res.Header.Get("location"), IndexReportPath+unknownDigest; got != want {
+			t.Errorf("got location %q, want %q", got, want)
+		}
+		var ir claircore.IndexReport
+		if err := json.NewDecoder(res.Body).Decode(&ir); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ir.Hash.String(), unknownDigest; got != want {
+			t.Errorf("got hash %q, want %q", got, want)
+		}
+		if _, ok := idx.reports[unknownDigest]; !ok {
+			t.Error("manifest not indexed")
+		}
+	})
+
+	t.Run("IndexReport", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		idx := newFakeIndexer()
+		h := NewIndexerHandler(idx)
+		req := httptest.NewRequest(http.MethodGet, IndexReportPath+testDigest, nil).WithContext(ctx)
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got status %d, want %d", got, want)
+		}
+		if got, want := res.Header.Get("content-type"), contentType; got != want {
+			t.Errorf("got content-type %q, want %q", got, want)
+		}
+		var got claircore.IndexReport
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := idx.reports[testDigest]
+		if !cmp.Equal(&got, want, cmp.AllowUnexported(claircore.Digest{})) {
+			t.Error(cmp.Diff(&got, want, cmp.AllowUnexported(claircore.Digest{})))
+		}
+
+		tag := res.Header.Get("etag")
+		if tag == "" {
+			t.Fatal("missing etag")
+		}
+		req = httptest.NewRequest(http.MethodGet, IndexReportPath+testDigest, nil).WithContext(ctx)
+		req.Header.Set("if-none-match", `"stale", W/`+tag)
+		res = do(t, h, req)
+		if got, want := res.StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("got status %d, want %d", got, want)
+		}
+
+		// A changed report gets a new tag.
+		want.Success = false
+		req = httptest.NewRequest(http.MethodGet, IndexReportPath+testDigest, nil).WithContext(ctx)
+		req.Header.Set("if-none-match", tag)
+		res = do(t, h, req)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got status %d, want %d", got, want)
+		}
+		if res.Header.Get("etag") == tag {
+			t.Error("etag unchanged")
+		}
+	})
+
+	t.Run("State", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewIndexerHandler(newFakeIndexer())
+		req := httptest.NewRequest(http.MethodGet, IndexStatePath, nil).WithContext(ctx)
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got status %d, want %d", got, want)
+		}
+		var s IndexStateResponse
+		if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := s.State, "test-state"; got != want {
+			t.Errorf("got state %q, want %q", got, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewIndexerHandler(newFakeIndexer())
+		for _, p := range []string{
+			IndexReportPath + unknownDigest,
+			IndexReportPath,
+			IndexReportPath + testDigest + "/extra",
+		} {
+			req := httptest.NewRequest(http.MethodGet, p, nil).WithContext(ctx)
+			checkError(t, do(t, h, req), http.StatusNotFound, CodeNotFound)
+		}
+	})
+
+	t.Run("MalformedDigest", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewIndexerHandler(newFakeIndexer())
+		for _, d := range []string{
+			"nodigest",
+			"sha256:zz",
+			"sha256:00",
+			"md5:d41d8cd98f00b204e9800998ecf8427e",
+		} {
+			req := httptest.NewRequest(http.MethodGet, IndexReportPath+d, nil).WithContext(ctx)
+			checkError(t, do(t, h, req), http.StatusBadRequest, CodeBadRequest)
+		}
+		for _, body := range []string{
+			`{"hash":"sha256:zz","layers":[]}`,
+			`{"layers":[]}`,
+			`{`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, IndexPath, strings.NewReader(body)).WithContext(ctx)
+			checkError(t, do(t, h, req), http.StatusBadRequest, CodeBadRequest)
+		}
+	})
+
+	t.Run("Method", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewIndexerHandler(newFakeIndexer())
+		req := httptest.NewRequest(http.MethodDelete, IndexReportPath+testDigest, nil).WithContext(ctx)
+		res := do(t, h, req)
+		checkError(t, res, http.StatusMethodNotAllowed, CodeMethodNotAllowed)
+		if got, want := res.Header.Get("allow"), http.MethodGet; got != want {
+			t.Errorf("got allow %q, want %q", got, want)
+		}
+	})
+
+	t.Run("ContentType", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewIndexerHandler(newFakeIndexer())
+		req := httptest.NewRequest(http.MethodPost, IndexPath, strings.NewReader(`<manifest/>`)).WithContext(ctx)
+		req.Header.Set("content-type", "application/xml")
+		checkError(t, do(t, h, req), http.StatusUnsupportedMediaType, CodeUnsupportedType)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		tt := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{inflight.ErrClosed, http.StatusServiceUnavailable, CodeUnavailable},
+			{fmt.Errorf("wrapped: %w", libindex.ErrIndexBusy), http.StatusServiceUnavailable, CodeUnavailable},
+			{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+			{&claircore.DiffIDMismatchError{}, http.StatusUnprocessableEntity, CodeLayerMismatch},
+			{errors.New("database on fire"), http.StatusInternalServerError, CodeInternal},
+		}
+		for _, tc := range tt {
+			idx := newFakeIndexer()
+			idx.err = tc.err
+			h := NewIndexerHandler(idx)
+			req := httptest.NewRequest(http.MethodGet, IndexReportPath+testDigest, nil).WithContext(ctx)
+			checkError(t, do(t, h, req), tc.status, tc.code)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln"
+)
+
+// These are the routes served by a MatcherHandler.
+const (
+	VulnerabilityPath       = "/vulnerability_report"
+	VulnerabilityReportPath = "/vulnerability_report/"
+)
+
+// Matcher is the subset of Libvuln's methods used by a MatcherHandler.
+type Matcher interface {
+	Scan(context.Context, *claircore.IndexReport, ...libvuln.ScanOption) (*claircore.VulnerabilityReport, error)
+}
+
+// MatcherHandler serves the Matcher operations:
+//
+//	POST /vulnerability_report            match the IndexReport in the body
+//	GET  /vulnerability_report/{digest}   match a manifest's stored IndexReport
+//
+// The GET route is only served if the MatcherHandler has an Indexer to
+// retrieve IndexReports from. Both routes accept a "max_age" query
+// parameter, a Go duration passed to libvuln.WithMaxVulnerabilityAge.
+type MatcherHandler struct {
+	matcher Matcher
+	indexer Indexer
+	mux     *http.ServeMux
+}
+
+// NewMatcherHandler returns a MatcherHandler for "m", usually a
+// *libvuln.Libvuln. The Indexer "i" may be nil.
+func NewMatcherHandler(m Matcher, i Indexer) *MatcherHandler {
+	h := &MatcherHandler{
+		matcher: m,
+		indexer: i,
+		mux:     http.NewServeMux(),
+	}
+	h.mux.HandleFunc(VulnerabilityPath, h.match)
+	if i != nil {
+		h.mux.HandleFunc(VulnerabilityReportPath, h.vulnerabilityReport)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MatcherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *MatcherHandler) match(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "transport/http/MatcherHandler.match"))
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	opts, ok := scanOptions(w, r)
+	if !ok {
+		return
+	}
+	var ir claircore.IndexReport
+	if !decodeBody(w, r, &ir) {
+		return
+	}
+	if ir.Hash.Checksum() == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "index report is missing a manifest hash")
+		return
+	}
+	vr, err := h.matcher.Scan(ctx, &ir, opts...)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	writeReport(ctx, w, r, http.StatusOK, vr)
+}
+
+func (h *MatcherHandler) vulnerabilityReport(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "transport/http/MatcherHandler.vulnerabilityReport"))
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	d, ok := parseDigest(w, r.URL.Path, VulnerabilityReportPath)
+	if !ok {
+		return
+	}
+	opts, ok := scanOptions(w, r)
+	if !ok {
+		return
+	}
+	ir, ok, err := h.indexer.IndexReport(ctx, d)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no index report for %q", d.String())
+		return
+	}
+	vr, err := h.matcher.Scan(ctx, ir, opts...)
+	if err != nil {
+		apiError(ctx, w, r, err)
+		return
+	}
+	writeReport(ctx, w, r, http.StatusOK, vr)
+}
+
+// ScanOptions returns the ScanOptions in the request's query parameters,
+// writing an Error response if they're malformed.
+func scanOptions(w http.ResponseWriter, r *http.Request) ([]libvuln.ScanOption, bool) {
+	var opts []libvuln.ScanOption
+	if v := r.URL.Query().Get("max_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "malformed max_age %q", v)
+			return nil, false
+		}
+		opts = append(opts, libvuln.WithMaxVulnerabilityAge(d))
+	}
+	return opts, true
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln"
+)
+
+var _ Matcher = (*libvuln.Libvuln)(nil)
+
+// FakeMatcher reports every package as vulnerable to one vulnerability.
+type fakeMatcher struct {
+	calls int
+	opts  int
+	err   error
+}
+
+func (f *fakeMatcher) Scan(_ context.Context, ir *claircore.IndexReport, opts ...libvuln.ScanOption) (*claircore.VulnerabilityReport, error) {
+	f.calls++
+	f.opts = len(opts)
+	if f.err != nil {
+		return nil, f.err
+	}
+	vr := &claircore.VulnerabilityReport{
+		Hash:     ir.Hash,
+		Packages: ir.Packages,
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"v": {ID: "v", Name: "CVE-2021-0001"},
+		},
+		PackageVulnerabilities: make(map[string][]string),
+	}
+	for id := range ir.Packages {
+		vr.PackageVulnerabilities[id] = []string{"v"}
+	}
+	return vr, nil
+}
+
+func TestMatcherHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("Match", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		m := &fakeMatcher{}
+		h := NewMatcherHandler(m, nil)
+		body := `{"manifest_hash":"` + testDigest + `","packages":{"1":{"id":"1","name":"bash","version":"5.0"}}}`
+		req := httptest.NewRequest(http.MethodPost, VulnerabilityPath+"?max_age=8760h", strings.NewReader(body)).WithContext(ctx)
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got status %d, want %d", got, want)
+		}
+		if res.Header.Get("etag") != "" {
+			t.Error("unexpected etag on POST")
+		}
+		var vr claircore.VulnerabilityReport
+		if err := json.NewDecoder(res.Body).Decode(&vr); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.PackageVulnerabilities["1"], []string{"v"}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := m.opts, 1; got != want {
+			t.Errorf("got %d options, want %d", got, want)
+		}
+	})
+
+	t.Run("VulnerabilityReport", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		m := &fakeMatcher{}
+		h := NewMatcherHandler(m, newFakeIndexer())
+		req := httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+testDigest, nil).WithContext(ctx)
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got status %d, want %d", got, want)
+		}
+		var vr claircore.VulnerabilityReport
+		if err := json.NewDecoder(res.Body).Decode(&vr); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.Hash.String(), testDigest; got != want {
+			t.Errorf("got hash %q, want %q", got, want)
+		}
+
+		tag := res.Header.Get("etag")
+		req = httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+testDigest, nil).WithContext(ctx)
+		req.Header.Set("if-none-match", tag)
+		res = do(t, h, req)
+		if got, want := res.StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("got status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		m := &fakeMatcher{}
+		h := NewMatcherHandler(m, newFakeIndexer())
+		req := httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+unknownDigest, nil).WithContext(ctx)
+		checkError(t, do(t, h, req), http.StatusNotFound, CodeNotFound)
+		if m.calls != 0 {
+			t.Error("matcher called for a missing report")
+		}
+
+		// Without an Indexer, there's nothing to look reports up in.
+		h = NewMatcherHandler(m, nil)
+		req = httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+testDigest, nil).WithContext(ctx)
+		res := do(t, h, req)
+		if got, want := res.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("MalformedDigest", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		h := NewMatcherHandler(&fakeMatcher{}, newFakeIndexer())
+		req := httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+"sha256:nothex", nil).WithContext(ctx)
+		checkError(t, do(t, h, req), http.StatusBadRequest, CodeBadRequest)
+
+		body := `{"manifest_hash":"sha256:00"}`
+		req = httptest.NewRequest(http.MethodPost, VulnerabilityPath, strings.NewReader(body)).WithContext(ctx)
+		checkError(t, do(t, h, req), http.StatusBadRequest, CodeBadRequest)
+
+		req = httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+testDigest+"?max_age=forever", nil).WithContext(ctx)
+		checkError(t, do(t, h, req), http.StatusBadRequest, CodeBadRequest)
+	})
+
+	t.Run("InventoryOnly", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		m := &fakeMatcher{err: fmt.Errorf("%w: %v", libvuln.ErrInventoryOnly, testDigest)}
+		h := NewMatcherHandler(m, newFakeIndexer())
+		req := httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+testDigest, nil).WithContext(ctx)
+		checkError(t, do(t, h, req), http.StatusUnprocessableEntity, CodeInventoryOnly)
+	})
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/inflight"
+	"github.com/quay/claircore/libindex"
+	"github.com/quay/claircore/libvuln"
+)
+
+const contentType = "application/json"
+
+// Error is the body of every error response.
+type Error struct {
+	// Code is a short, machine-readable description of the error, like
+	// "not-found".
+	Code string `json:"code"`
+	// Message is a human-readable description of the error.
+	Message string `json:"message"`
+}
+
+// These are the Codes used in Errors.
+const (
+	CodeBadRequest       = "bad-request"
+	CodeNotFound         = "not-found"
+	CodeMethodNotAllowed = "method-not-allowed"
+	CodeUnsupportedType  = "unsupported-media-type"
+	CodeLayerMismatch    = "layer-mismatch"
+	CodeInventoryOnly    = "inventory-only"
+	CodeUnavailable      = "unavailable"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal-error"
+)
+
+// WriteError writes an Error response.
+func writeError(w http.ResponseWriter, status int, code, format string, args ...interface{}) {
+	h := w.Header()
+	h.Del("etag")
+	h.Set("content-type", contentType)
+	h.Set("x-content-type-options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ApiError maps an error returned from a library call to an Error response.
+//
+// If the request's Context is done, the client isn't waiting for a response
+// and none is written.
+func apiError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Msg("request canceled")
+		return
+	}
+	var de *claircore.DigestError
+	var me *claircore.DiffIDMismatchError
+	switch {
+	case errors.As(err, &de):
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "%v", err)
+	case errors.As(err, &me):
+		writeError(w, http.StatusUnprocessableEntity, CodeLayerMismatch, "%v", err)
+	case errors.Is(err, libvuln.ErrInventoryOnly):
+		writeError(w, http.StatusUnprocessableEntity, CodeInventoryOnly, "%v", err)
+	case errors.Is(err, inflight.ErrClosed), errors.Is(err, libindex.ErrIndexBusy):
+		w.Header().Set("retry-after", "1")
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "%v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, CodeTimeout, "%v", err)
+	default:
+		zlog.Error(ctx).
+			Err(err).
+			Msg("request failed")
+		writeError(w, http.StatusInternalServerError, CodeInternal, "%v", err)
+	}
+}
+
+// WriteReport encodes "v" and writes it as a response with the provided
+// status.
+//
+// For GET requests, an ETag fingerprinting the encoding is added, and if the
+// request's If-None-Match matches it, only "304 Not Modified" is written.
+func writeReport(ctx context.Context, w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		apiError(ctx, w, r, fmt.Errorf("unable to encode response: %w", err))
+		return
+	}
+	h := w.Header()
+	if r.Method == http.MethodGet {
+		sum := sha256.Sum256(buf.Bytes())
+		tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		h.Set("etag", tag)
+		if etagMatch(r.Header.Get("if-none-match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	h.Set("content-type", contentType)
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		zlog.Debug(ctx).
+			Err(err).
+			Msg("unable to write response")
+	}
+}
+
+// EtagMatch reports whether the If-None-Match header "h" matches "tag".
+//
+// Per RFC 7232, If-None-Match uses weak comparison.
+func etagMatch(h, tag string) bool {
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDigest parses the digest in "path" after "prefix", writing an Error
+// response if it's missing or malformed.
+func parseDigest(w http.ResponseWriter, path, prefix string) (claircore.Digest, bool) {
+	s := strings.TrimPrefix(path, prefix)
+	if s == "" || strings.Contains(s, "/") {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no such path: %q", path)
+		return claircore.Digest{}, false
+	}
+	d, err := claircore.ParseDigest(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "malformed digest %q: %v", s, err)
+		return claircore.Digest{}, false
+	}
+	return d, true
+}
+
+// CheckMethod writes an Error response and reports false if the request's
+// method isn't one of "allow".
+func checkMethod(w http.ResponseWriter, r *http.Request, allow ...string) bool {
+	for _, m := range allow {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("allow", strings.Join(allow, ", "))
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method %q not allowed", r.Method)
+	return false
+}
+
+// DecodeBody decodes the JSON request body into "v", writing an Error
+// response if it can't be.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if ct := r.Header.Get("content-type"); ct != "" {
+		if i := strings.IndexByte(ct, ';'); i != -1 {
+			ct = ct[:i]
+		}
+		if strings.TrimSpace(ct) != contentType {
+			writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedType, "unsupported content-type %q", ct)
+			return false
+		}
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "unable to decode request: %v", err)
+		return false
+	}
+	return true
+}
+
+// MaxBodySize bounds request bodies. Index reports, the largest bodies
+// accepted, can be sizeable for images with many packages.
+const maxBodySize = 64 << 20 // 64 MiB
@@ -7,6 +7,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/debkernel"
 )
 
 const (
@@ -14,7 +15,11 @@ const (
 	OSReleaseName = "Ubuntu"
 )
 
-var _ driver.Matcher = (*Matcher)(nil)
+var (
+	_ driver.Matcher                   = (*Matcher)(nil)
+	_ driver.PackageMapper             = (*Matcher)(nil)
+	_ driver.DistributionVersionRanger = (*Matcher)(nil)
+)
 
 type Matcher struct{}
 
@@ -41,23 +46,45 @@ func (*Matcher) Query() []driver.MatchConstraint {
 	return []driver.MatchConstraint{
 		driver.DistributionDID,
 		driver.DistributionName,
-		driver.DistributionVersion,
+		driver.DistributionVersionID,
 	}
 }
 
+// DistributionVersionOp implements driver.DistributionVersionRanger.
+//
+// Vulnerabilities are recorded per release, like "20.04", but some derived
+// images only report the series, like "20".
+func (*Matcher) DistributionVersionOp() driver.DistributionVersionOp {
+	return driver.DistributionVersionSeries
+}
+
+// MapPackage implements driver.PackageMapper.
+//
+// Kernel packages are queried as their kernel source package.
+func (*Matcher) MapPackage(record *claircore.IndexRecord) *claircore.Package {
+	return debkernel.Package(record.Package)
+}
+
 func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
-	if vuln.FixedInVersion == "" {
-		return true, nil
+	installed, fixed := record.Package.Version, vuln.FixedInVersion
+	if kv, kf, ok := debkernel.Versions(record, vuln); ok {
+		installed, fixed = kv, kf
 	}
-
-	v1, err := version.NewVersion(record.Package.Version)
+	if driver.EmptyVersion(installed) {
+		return false, driver.PackageVersionError(installed, driver.ErrEmptyVersion)
+	}
+	v1, err := version.NewVersion(installed)
 	if err != nil {
-		return false, err
+		return false, driver.PackageVersionError(installed, err)
+	}
+
+	if fixed == "" {
+		return true, nil
 	}
 
-	v2, err := version.NewVersion(vuln.FixedInVersion)
+	v2, err := version.NewVersion(fixed)
 	if err != nil {
-		return false, err
+		return false, driver.VulnerabilityVersionError(fixed, err)
 	}
 
 	if v2.String() == "0" {
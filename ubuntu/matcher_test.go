@@ -0,0 +1,152 @@
+package ubuntu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+)
+
+// TestKernelMatch checks that kernel packages, as recorded by the dpkg
+// scanner, are queried as their kernel source package and compared against
+// its fixed versions.
+func TestKernelMatch(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := &Matcher{}
+	pkg := func(name, version, src, srcVersion string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{
+			Package: &claircore.Package{
+				ID:      name,
+				Name:    name,
+				Version: version,
+				Kind:    claircore.BINARY,
+				Source: &claircore.Package{
+					Name:    src,
+					Version: srcVersion,
+					Kind:    claircore.SOURCE,
+				},
+			},
+			Distribution: &claircore.Distribution{DID: OSReleaseID},
+		}
+	}
+	type check struct {
+		fixed      string
+		vulnerable bool
+	}
+	tt := []struct {
+		name   string
+		record *claircore.IndexRecord
+		query  string
+		checks []check
+	}{
+		{
+			name:   "GenericImage",
+			record: pkg("linux-image-5.15.0-89-generic", "5.15.0-89.99", "linux", "5.15.0-89.99"),
+			query:  "linux",
+			checks: []check{{"5.15.0-91.101", true}, {"5.15.0-89.99", false}, {"5.15.0-88.98", false}, {"", true}},
+		},
+		{
+			name:   "GenericMeta",
+			record: pkg("linux-image-generic", "5.15.0.89.86", "linux", "5.15.0-89"),
+			query:  "linux",
+			checks: []check{{"5.15.0-91.101", true}, {"5.15.0-89.99", false}, {"5.15.0-88.98", false}},
+		},
+		{
+			name:   "HWE",
+			record: pkg("linux-image-5.15.0-89-generic", "5.15.0-89.99~20.04.1", "linux-hwe-5.15", "5.15.0-89.99~20.04.1"),
+			query:  "linux-hwe-5.15",
+			checks: []check{{"5.15.0-91.101~20.04.1", true}, {"5.15.0-89.99~20.04.1", false}},
+		},
+		{
+			name:   "HWEMeta",
+			record: pkg("linux-image-generic-hwe-20.04", "5.15.0.89.99~20.04.47", "linux-hwe-5.15", "5.15.0-89"),
+			query:  "linux-hwe-5.15",
+			checks: []check{{"5.15.0-91.101~20.04.1", true}, {"5.15.0-89.99~20.04.1", false}},
+		},
+		{
+			name:   "AWS",
+			record: pkg("linux-image-5.15.0-1049-aws", "5.15.0-1049.54", "linux-aws", "5.15.0-1049.54"),
+			query:  "linux-aws",
+			checks: []check{{"5.15.0-1051.56", true}, {"5.15.0-1049.54", false}},
+		},
+		{
+			name:   "AWSMeta",
+			record: pkg("linux-image-aws", "5.15.0.1049.48", "linux-aws", "5.15.0-1049"),
+			query:  "linux-aws",
+			checks: []check{{"5.15.0-1051.56", true}, {"5.15.0-1049.54", false}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			q := m.MapPackage(tc.record)
+			if q == nil {
+				t.Fatal("not mapped")
+			}
+			if got, want := q.Name, tc.query; got != want {
+				t.Errorf("queried as %q, want %q", got, want)
+			}
+			if got, want := q.ID, tc.record.Package.ID; got != want {
+				t.Errorf("got ID %q, want %q", got, want)
+			}
+			for _, c := range tc.checks {
+				v := &claircore.Vulnerability{
+					Package:        &claircore.Package{Name: tc.query, Kind: claircore.BINARY},
+					FixedInVersion: c.fixed,
+				}
+				got, err := m.Vulnerable(ctx, tc.record, v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != c.vulnerable {
+					t.Errorf("fixed in %q: got vulnerable %v, want %v", c.fixed, got, c.vulnerable)
+				}
+			}
+		})
+	}
+
+	if q := m.MapPackage(pkg("libc6", "2.35-0ubuntu3.4", "glibc", "2.35-0ubuntu3.4")); q != nil {
+		t.Errorf("mapped a non-kernel package: %+v", q)
+	}
+}
+
+func TestBadVersions(t *testing.T) {
+	test.BadVersionTestcase{
+		Matcher: &Matcher{},
+		Record: func(v string) *claircore.IndexRecord {
+			return &claircore.IndexRecord{
+				Package: &claircore.Package{Name: "bash", Version: v},
+			}
+		},
+		Vulnerability: func(v string) *claircore.Vulnerability {
+			return &claircore.Vulnerability{FixedInVersion: v}
+		},
+		Good: "5.0-6ubuntu1",
+	}.Run(t)
+}
+
+// TestDistributionVersion checks that records are matched to the data for
+// their release by version, including records only naming the series.
+func TestDistributionVersion(t *testing.T) {
+	m := &Matcher{}
+	op := m.DistributionVersionOp()
+	tt := []struct {
+		Record string
+		Dist   *claircore.Distribution
+		Want   bool
+	}{
+		{"20.04", focalDist, true},
+		{"20", focalDist, true},
+		{"20", eoanDist, false},
+		{"18.04", focalDist, false},
+		{"18", bionicDist, true},
+	}
+	for _, tc := range tt {
+		if got, want := op.Match(tc.Record, tc.Dist.VersionID), tc.Want; got != want {
+			t.Errorf("%q ⋚ %q: got: %v, want: %v", tc.Record, tc.Dist.VersionID, got, want)
+		}
+	}
+}
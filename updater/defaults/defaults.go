@@ -12,6 +12,9 @@ import (
 	"github.com/quay/claircore/aws"
 	"github.com/quay/claircore/debian"
 	"github.com/quay/claircore/enricher/cvss"
+	"github.com/quay/claircore/enricher/eol"
+	"github.com/quay/claircore/enricher/rhcatalog"
+	"github.com/quay/claircore/gobin"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/oracle"
 	"github.com/quay/claircore/photon"
@@ -50,6 +53,7 @@ func inner(ctx context.Context) error {
 	updater.Register("alpine", driver.UpdaterSetFactoryFunc(alpine.UpdaterSet))
 	updater.Register("aws", driver.UpdaterSetFactoryFunc(aws.UpdaterSet))
 	updater.Register("debian", driver.UpdaterSetFactoryFunc(debian.UpdaterSet))
+	updater.Register("govulndb", driver.UpdaterSetFactoryFunc(gobin.UpdaterSet))
 	updater.Register("oracle", driver.UpdaterSetFactoryFunc(oracle.UpdaterSet))
 	updater.Register("photon", driver.UpdaterSetFactoryFunc(photon.UpdaterSet))
 	updater.Register("pyupio", driver.UpdaterSetFactoryFunc(pyupio.UpdaterSet))
@@ -59,5 +63,13 @@ func inner(ctx context.Context) error {
 	cvssSet.Add(&cvss.Enricher{})
 	updater.Register("clair.cvss", driver.StaticSet(cvssSet))
 
+	eolSet := driver.NewUpdaterSet()
+	eolSet.Add(&eol.Enricher{})
+	updater.Register("clair.eol", driver.StaticSet(eolSet))
+
+	rhcatalogSet := driver.NewUpdaterSet()
+	rhcatalogSet.Add(&rhcatalog.Enricher{})
+	updater.Register("clair.rhcatalog", driver.StaticSet(rhcatalogSet))
+
 	return nil
 }
@@ -26,17 +26,18 @@ func (v *Version) MarshalText() ([]byte, error) {
 	if v.Kind == "" {
 		return []byte{}, nil
 	}
-	var buf bytes.Buffer
-	b := make([]byte, 0, 16) // 16 byte wide scratch buffer
-	buf.WriteString(v.Kind)
-	buf.WriteByte(':')
+	// Room for the kind and ten single-digit components, which is enough
+	// for most versions to need only the one allocation.
+	b := make([]byte, 0, len(v.Kind)+1+20)
+	b = append(b, v.Kind...)
+	b = append(b, ':')
 	for i := 0; i < 10; i++ {
 		if i != 0 {
-			buf.WriteByte('.')
+			b = append(b, '.')
 		}
-		buf.Write(strconv.AppendInt(b, int64(v.V[i]), 10))
+		b = strconv.AppendInt(b, int64(v.V[i]), 10)
 	}
-	return buf.Bytes(), nil
+	return b, nil
 }
 
 // UnmarshalText implments encoding.TextUnmarshaler.
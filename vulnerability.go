@@ -37,4 +37,19 @@ type Vulnerability struct {
 	// ArchOperation indicates how the affected Package's "arch" should be
 	// compared.
 	ArchOperation ArchOp `json:"arch_op,omitempty"`
+	// Annotations are notes added at match time describing how the
+	// vulnerability was matched. They are not persisted.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Aliases are other names for this vulnerability, such as the CVEs an
+	// advisory addresses or the advisory's bare ID. Updaters provide the ones
+	// their data source declares; in a VulnerabilityReport, they're every
+	// name the vulnerability is connected to through any updater's aliases.
+	Aliases []string `json:"aliases,omitempty"`
+	// FirstSeen and LastSeen are the dates of the first and latest update
+	// operations that reported this revision of the vulnerability. They're
+	// set by stores that track them, and kept when the first operation is
+	// garbage collected. A changed record is a new revision, first seen
+	// when the change was.
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
 }
@@ -1,6 +1,13 @@
 package claircore
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // VulnerabilityReport provides a report of packages and their
 // associated vulnerabilities.
@@ -19,6 +26,172 @@ type VulnerabilityReport struct {
 	Vulnerabilities map[string]*Vulnerability `json:"vulnerabilities"`
 	// a lookup table associating package ids with 1 or more vulnerability ids. keyed by package id
 	PackageVulnerabilities map[string][]string `json:"package_vulnerabilities"`
+	// findings moved out of PackageVulnerabilities by an Exclusion, keyed by
+	// package id. the suppressed vulnerabilities are still present in
+	// Vulnerabilities.
+	Suppressed map[string][]Suppression `json:"suppressed,omitempty"`
+	// findings that couldn't be evaluated because a version involved couldn't
+	// be parsed, keyed by package id. the vulnerabilities are still present in
+	// Vulnerabilities.
+	Indeterminate map[string][]Indeterminate `json:"indeterminate,omitempty"`
 	// a map of enrichments keyed by a type.
 	Enrichments map[string][]json.RawMessage `json:"enrichments"`
+	// risk hints for ordering the findings, keyed by vulnerability id. only
+	// present if requested; see RiskHint.
+	RiskHints map[string]RiskHint `json:"risk_hints,omitempty"`
+	// information about the vulnerability data used to create the report
+	Metadata *ReportMetadata `json:"metadata,omitempty"`
+	// anomalies that didn't stop the report from being created, such as
+	// end-of-life distributions or stale vulnerability data. the warnings of
+	// the IndexReport the report was created from are included.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// ReportMetadata records the state of the vulnerability data when a
+// VulnerabilityReport was created, and any findings deliberately left out of
+// it, so that the report can be audited or reproduced later.
+type ReportMetadata struct {
+	// UpdateOperations is the latest update operation for every updater when
+	// matching ran, keyed by updater name. Updaters that have never run are
+	// not present.
+	UpdateOperations map[string]UpdateRef `json:"update_operations"`
+	// IssuedCutoff is set if findings for vulnerabilities issued before it
+	// were left out of the report.
+	IssuedCutoff *time.Time `json:"issued_cutoff,omitempty"`
+	// FilteredByAge is the number of findings left out of the report because
+	// of IssuedCutoff.
+	FilteredByAge int `json:"filtered_by_age,omitempty"`
+	// DroppedIndeterminate is the number of indeterminate findings left out
+	// of the report instead of being listed in its Indeterminate section.
+	DroppedIndeterminate int `json:"dropped_indeterminate,omitempty"`
+	// Attribution lists the data sources of the updaters that contributed
+	// findings or enrichments to the report, sorted by name. Updaters that
+	// don't describe their data source are not present.
+	Attribution []DataSource `json:"attribution,omitempty"`
+	// Claircore describes the claircore that created the report.
+	Claircore *BuildInfo `json:"claircore,omitempty"`
+	// Redaction is set if the report was redacted; see Redaction.
+	Redaction *RedactionInfo `json:"redaction,omitempty"`
+}
+
+// Indeterminate is a finding that couldn't be evaluated, because the
+// package's version or the vulnerability's version couldn't be parsed. The
+// package may or may not be affected.
+type Indeterminate struct {
+	// VulnerabilityID is the vulnerability's key in the report's
+	// Vulnerabilities.
+	VulnerabilityID string `json:"vulnerability_id"`
+	// Version is the version that couldn't be parsed.
+	Version string `json:"version"`
+	// Reason describes why the version couldn't be parsed.
+	Reason string `json:"reason"`
+}
+
+// RiskHint is a number for ordering findings by risk, derived from a
+// vulnerability's severity and any enrichments reported for it. It's computed
+// by claircore when the report is assembled and isn't reported by any data
+// source.
+//
+// Scores are only comparable with scores from the same Scorer. Higher scores
+// are riskier.
+type RiskHint struct {
+	// Score is the hint itself.
+	Score float64 `json:"score"`
+	// Derived is always true, to mark the hint as computed rather than
+	// reported.
+	Derived bool `json:"derived"`
+	// Scorer is the name of the scorer that computed the hint.
+	Scorer string `json:"scorer"`
+	// Inputs are the values the hint was computed from.
+	Inputs []RiskInput `json:"inputs"`
+}
+
+// RiskInput is a value a RiskHint was computed from.
+type RiskInput struct {
+	// Name is the kind of value, such as "cvss", "epss", or "severity".
+	Name string `json:"name"`
+	// Value is the value used.
+	Value float64 `json:"value"`
+	// Enrichment is the type of the enrichment the value came from, if it
+	// came from one. It's a key in the report's Enrichments.
+	Enrichment string `json:"enrichment,omitempty"`
+}
+
+// UpdateRef identifies an update operation.
+type UpdateRef struct {
+	Ref  uuid.UUID `json:"ref"`
+	Date time.Time `json:"date"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Slices in the report are encoded in a stable order, so identical reports
+// always encode to identical bytes: vulnerability IDs, suppressions,
+// indeterminate findings, warnings, and environments are sorted, and
+// enrichments are sorted by their compacted encoding.
+func (r VulnerabilityReport) MarshalJSON() ([]byte, error) {
+	type plain VulnerabilityReport // Plain has no methods, to avoid recursing.
+	c := plain(r)
+	c.Environments = sortEnvironments(r.Environments)
+	c.Warnings = sortWarnings(r.Warnings)
+	if r.PackageVulnerabilities != nil {
+		c.PackageVulnerabilities = make(map[string][]string, len(r.PackageVulnerabilities))
+		for k, ids := range r.PackageVulnerabilities {
+			if ids != nil {
+				ids = append([]string(nil), ids...)
+				sort.Strings(ids)
+			}
+			c.PackageVulnerabilities[k] = ids
+		}
+	}
+	if r.Suppressed != nil {
+		c.Suppressed = make(map[string][]Suppression, len(r.Suppressed))
+		for k, ss := range r.Suppressed {
+			if ss != nil {
+				ss = append([]Suppression(nil), ss...)
+				sort.Slice(ss, func(i, j int) bool {
+					if ss[i].VulnerabilityID != ss[j].VulnerabilityID {
+						return ss[i].VulnerabilityID < ss[j].VulnerabilityID
+					}
+					return ss[i].Exclusion.String() < ss[j].Exclusion.String()
+				})
+			}
+			c.Suppressed[k] = ss
+		}
+	}
+	if r.Indeterminate != nil {
+		c.Indeterminate = make(map[string][]Indeterminate, len(r.Indeterminate))
+		for k, is := range r.Indeterminate {
+			if is != nil {
+				is = append([]Indeterminate(nil), is...)
+				sort.Slice(is, func(i, j int) bool {
+					return is[i].VulnerabilityID < is[j].VulnerabilityID
+				})
+			}
+			c.Indeterminate[k] = is
+		}
+	}
+	if r.Enrichments != nil {
+		c.Enrichments = make(map[string][]json.RawMessage, len(r.Enrichments))
+		for k, es := range r.Enrichments {
+			if es == nil {
+				c.Enrichments[k] = nil
+				continue
+			}
+			s := make([]json.RawMessage, len(es))
+			for i, e := range es {
+				if e == nil {
+					continue
+				}
+				var b bytes.Buffer
+				if err := json.Compact(&b, e); err != nil {
+					return nil, err
+				}
+				s[i] = b.Bytes()
+			}
+			sort.Slice(s, func(i, j int) bool { return bytes.Compare(s[i], s[j]) < 0 })
+			c.Enrichments[k] = s
+		}
+	}
+	return json.Marshal(&c)
 }
@@ -0,0 +1,237 @@
+package claircore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// Golden compares the provided bytes to the named file in testdata, writing
+// the file instead if the "-update" flag is provided.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	p := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(p, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\ngot:  %s\nwant: %s", p, got, want)
+	}
+}
+
+// Shuffle permutes all the slices in the provided maps.
+func shuffleEnvironments(rng *rand.Rand, envs map[string][]*claircore.Environment) {
+	for _, es := range envs {
+		rng.Shuffle(len(es), func(i, j int) { es[i], es[j] = es[j], es[i] })
+		for _, e := range es {
+			ids := e.RepositoryIDs
+			rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		}
+	}
+}
+
+func reportEnvironments() map[string][]*claircore.Environment {
+	l1 := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	l2 := claircore.MustParseDigest(`sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`)
+	return map[string][]*claircore.Environment{
+		"1": {
+			{PackageDB: "var/lib/dpkg/status", IntroducedIn: l1, DistributionID: "1", RepositoryIDs: []string{"2", "1", "3"}},
+			{PackageDB: "var/lib/dpkg/status", IntroducedIn: l2, DistributionID: "1", RepositoryIDs: []string{"1"}},
+			{PackageDB: "usr/lib/python3/site-packages", IntroducedIn: l1, DistributionID: "1"},
+		},
+		"2": {
+			{PackageDB: "var/lib/dpkg/status", IntroducedIn: l2, DistributionID: "1", RepositoryIDs: []string{"3", "2"}},
+		},
+	}
+}
+
+func vulnerabilityReport() *claircore.VulnerabilityReport {
+	return &claircore.VulnerabilityReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "bash", Version: "5.0-4"},
+			"2": {ID: "2", Name: "openssl", Version: "1.1.1d-0"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", DID: "debian", VersionID: "10"},
+		},
+		Repositories: map[string]*claircore.Repository{
+			"1": {ID: "1", Name: "main"},
+			"2": {ID: "2", Name: "contrib"},
+			"3": {ID: "3", Name: "non-free"},
+		},
+		Environments: reportEnvironments(),
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {ID: "10", Name: "CVE-2019-18276", Issued: time.Date(2019, 11, 28, 0, 0, 0, 0, time.UTC)},
+			"9":  {ID: "9", Name: "CVE-2019-1551", Issued: time.Date(2019, 12, 6, 0, 0, 0, 0, time.UTC)},
+			"11": {ID: "11", Name: "CVE-2020-1967", Issued: time.Date(2020, 4, 21, 0, 0, 0, 0, time.UTC)},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"10"},
+			"2": {"9", "11"},
+		},
+		Enrichments: map[string][]json.RawMessage{
+			"message/vnd.clair.map.vulnerability; enricher=test": {
+				json.RawMessage(`{"9": [{"score": 5.3}]}`),
+				json.RawMessage(`{"10":[{"score":7.8}]}`),
+				json.RawMessage(`{"11":[{"score":7.5}]}`),
+			},
+		},
+	}
+}
+
+// reportSections are optional parts of a VulnerabilityReport, each encoded to
+// its own golden file along with the base report.
+var reportSections = []struct {
+	Name   string
+	Golden string
+	Add    func(*claircore.VulnerabilityReport)
+}{
+	{
+		Name:   "Suppressed",
+		Golden: "vulnerabilityreport_suppressed.golden.json",
+		Add: func(r *claircore.VulnerabilityReport) {
+			r.Vulnerabilities["12"] = &claircore.Vulnerability{ID: "12", Name: "CVE-2019-9924", Issued: time.Date(2019, 3, 22, 0, 0, 0, 0, time.UTC)}
+			r.Vulnerabilities["13"] = &claircore.Vulnerability{ID: "13", Name: "CVE-2019-18224", Issued: time.Date(2019, 10, 21, 0, 0, 0, 0, time.UTC)}
+			r.Suppressed = map[string][]claircore.Suppression{
+				"1": {
+					{VulnerabilityID: "12", Exclusion: uuid.MustParse("3e8ef0d4-4f5c-4a37-8f0e-6a2f4d5e2b10"), Reason: "not reachable"},
+					{VulnerabilityID: "13", Exclusion: uuid.MustParse("9a4c1a0e-0b7e-4f3e-9d7f-2c5a7e1b8f21"), Reason: "fixed by vendor patch"},
+				},
+			}
+		},
+	},
+	{
+		Name:   "Indeterminate",
+		Golden: "vulnerabilityreport_indeterminate.golden.json",
+		Add: func(r *claircore.VulnerabilityReport) {
+			r.Vulnerabilities["14"] = &claircore.Vulnerability{ID: "14", Name: "CVE-2021-3711", Issued: time.Date(2021, 8, 24, 0, 0, 0, 0, time.UTC)}
+			r.Vulnerabilities["15"] = &claircore.Vulnerability{ID: "15", Name: "CVE-2021-3712", Issued: time.Date(2021, 8, 24, 0, 0, 0, 0, time.UTC)}
+			r.Indeterminate = map[string][]claircore.Indeterminate{
+				"2": {
+					{VulnerabilityID: "15", Version: "1.1.1x", Reason: `unparseable vulnerability version "1.1.1x": invalid version`},
+					{VulnerabilityID: "14", Version: "1.1.1x", Reason: `unparseable vulnerability version "1.1.1x": invalid version`},
+				},
+			}
+		},
+	},
+	{
+		Name:   "Warnings",
+		Golden: "vulnerabilityreport_warnings.golden.json",
+		Add: func(r *claircore.VulnerabilityReport) {
+			r.Warnings = []claircore.Warning{
+				{Code: claircore.WarningStaleData, Subject: "debian/updater/buster", Message: "vulnerability data last updated 2021-08-01"},
+				{Code: claircore.WarningEOLDistribution, Subject: "1", Message: "Debian 10 reached end of life"},
+				{Code: claircore.WarningStaleData, Subject: "debian/updater/bullseye", Message: "vulnerability data last updated 2021-08-01"},
+			}
+		},
+	},
+}
+
+// fullVulnerabilityReport returns the base report with every section in
+// reportSections added.
+func fullVulnerabilityReport() *claircore.VulnerabilityReport {
+	r := vulnerabilityReport()
+	for _, s := range reportSections {
+		s.Add(r)
+	}
+	return r
+}
+
+func shuffleVulnerabilityReport(rng *rand.Rand, r *claircore.VulnerabilityReport) {
+	shuffleEnvironments(rng, r.Environments)
+	for _, ids := range r.PackageVulnerabilities {
+		rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	}
+	for _, ss := range r.Suppressed {
+		rng.Shuffle(len(ss), func(i, j int) { ss[i], ss[j] = ss[j], ss[i] })
+	}
+	for _, is := range r.Indeterminate {
+		rng.Shuffle(len(is), func(i, j int) { is[i], is[j] = is[j], is[i] })
+	}
+	for _, es := range r.Enrichments {
+		rng.Shuffle(len(es), func(i, j int) { es[i], es[j] = es[j], es[i] })
+	}
+	ws := r.Warnings
+	rng.Shuffle(len(ws), func(i, j int) { ws[i], ws[j] = ws[j], ws[i] })
+}
+
+func TestVulnerabilityReportJSON(t *testing.T) {
+	t.Run("Base", func(t *testing.T) {
+		testVulnerabilityReportJSON(t, "vulnerabilityreport.golden.json", vulnerabilityReport)
+	})
+	for _, sec := range reportSections {
+		add := sec.Add
+		t.Run(sec.Name, func(t *testing.T) {
+			testVulnerabilityReportJSON(t, sec.Golden, func() *claircore.VulnerabilityReport {
+				r := vulnerabilityReport()
+				add(r)
+				return r
+			})
+		})
+	}
+}
+
+// testVulnerabilityReportJSON checks the encoding of the reports returned by
+// "mk" against the named golden file.
+func testVulnerabilityReportJSON(t *testing.T, name string, mk func() *claircore.VulnerabilityReport) {
+	want, err := json.Marshal(mk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, name, want)
+
+	t.Run("Shuffled", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for i := 0; i < 100; i++ {
+			r := mk()
+			shuffleVulnerabilityReport(rng, r)
+			got, err := json.Marshal(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got: %s\nwant: %s", got, want)
+			}
+		}
+	})
+	t.Run("RoundTrip", func(t *testing.T) {
+		var r claircore.VulnerabilityReport
+		if err := json.Unmarshal(want, &r); err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got: %s\nwant: %s", got, want)
+		}
+	})
+	t.Run("Unmodified", func(t *testing.T) {
+		r := mk()
+		if _, err := json.Marshal(r); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.PackageVulnerabilities["2"][0], "9"; got != want {
+			t.Errorf("marshaling modified the report: got: %q, want: %q", got, want)
+		}
+	})
+}
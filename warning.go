@@ -0,0 +1,61 @@
+package claircore
+
+import "sort"
+
+// WarningCode identifies the kind of anomaly a Warning describes.
+//
+// The values are stable and are part of the serialized reports; new codes
+// may be added, but existing ones won't change.
+type WarningCode string
+
+// These are the WarningCodes reported by claircore.
+const (
+	// WarningUnsupportedMediaType is reported when a layer was served with a
+	// media type the indexer doesn't support, but its format could be
+	// determined from its contents. The subject is the layer's digest.
+	WarningUnsupportedMediaType WarningCode = "unsupported-layer-media-type"
+	// WarningEOLDistribution is reported when a distribution in the report is
+	// past its end of life, so vulnerability data may no longer be published
+	// for it. The subject is the distribution's ID in the report.
+	WarningEOLDistribution WarningCode = "eol-distribution"
+	// WarningStaleData is reported when the vulnerability data from an
+	// updater hasn't been updated recently. The subject is the updater's
+	// name.
+	WarningStaleData WarningCode = "stale-data"
+	// WarningPhaseOverrun is reported when a phase of an operation ran past
+	// its share of the caller's deadline, leaving less time for the phases
+	// after it. The subject is the phase's name, and the message has its
+	// timing.
+	WarningPhaseOverrun WarningCode = "phase-overrun"
+)
+
+// Warning describes an anomaly that didn't stop a report from being produced,
+// but that a consumer of the report may want to know about.
+type Warning struct {
+	// Code identifies the kind of anomaly.
+	Code WarningCode `json:"code"`
+	// Subject is what the warning is about, such as a layer digest or a
+	// scanner name. What it names depends on the Code.
+	Subject string `json:"subject,omitempty"`
+	// Message is a human-readable explanation.
+	Message string `json:"message"`
+}
+
+// SortWarnings returns a sorted copy of "ws", for encoding reports in a
+// stable order.
+func sortWarnings(ws []Warning) []Warning {
+	if ws == nil {
+		return nil
+	}
+	out := append([]Warning(nil), ws...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Code != out[j].Code {
+			return out[i].Code < out[j].Code
+		}
+		if out[i].Subject != out[j].Subject {
+			return out[i].Subject < out[j].Subject
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out
+}
@@ -0,0 +1,244 @@
+// Package windows detects Windows container layers and reports the version of
+// Windows they contain.
+//
+// Only detection is supported: no packages are reported and there's no
+// matcher, so manifests containing Windows layers are indexed with limited
+// support.
+package windows
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"runtime/trace"
+	"strconv"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/regf"
+)
+
+// DID is the distribution ID reported for Windows.
+const DID = "windows"
+
+const (
+	scannerName    = "windows"
+	scannerVersion = "1"
+	scannerKind    = "distribution"
+)
+
+// Windows layers keep the filesystem under "Files" and registry deltas under
+// "Hives". The base layer also carries the full registry hives.
+const (
+	system32      = `files/windows/system32/`
+	softwareHive  = `files/windows/system32/config/software`
+	softwareDelta = `hives/software_delta`
+	currentKey    = `Microsoft\Windows NT\CurrentVersion`
+)
+
+// MaxHiveSize bounds the size of a hive read into memory.
+const maxHiveSize = 512 << 20 // 512 MiB
+
+var (
+	_ indexer.DistributionScanner = (*DistributionScanner)(nil)
+	_ indexer.VersionedScanner    = (*DistributionScanner)(nil)
+	_ indexer.LimitedScanner      = (*DistributionScanner)(nil)
+)
+
+// DistributionScanner detects Windows layers.
+//
+// A layer is a Windows layer if it has a "Files/Windows/System32" directory.
+// The version is read from the "SOFTWARE" registry hive if the layer has one;
+// a Windows layer without a readable hive is still reported, without a
+// version.
+//
+// The zero value is ready to use.
+type DistributionScanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*DistributionScanner) Name() string { return scannerName }
+
+// Version implements scanner.VersionedScanner.
+func (*DistributionScanner) Version() string { return scannerVersion }
+
+// Kind implements scanner.VersionedScanner.
+func (*DistributionScanner) Kind() string { return scannerKind }
+
+// LimitedSupport implements indexer.LimitedScanner.
+//
+// Windows layers are only detected, so a report with a Windows distribution
+// has none of the Windows contents.
+func (*DistributionScanner) LimitedSupport(d *claircore.Distribution) string {
+	if d.DID == DID {
+		return "windows: only the distribution is indexed"
+	}
+	return ""
+}
+
+// Scan implements indexer.DistributionScanner.
+//
+// A return of (nil, nil) is expected if the layer isn't a Windows layer.
+func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "windows/DistributionScanner.Scan"),
+		label.String("version", ds.Version()),
+		label.String("layer", l.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+
+	found, hives, err := walk(l)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	zlog.Info(ctx).Msg("found windows layer")
+	for _, n := range []string{softwareHive, softwareDelta} {
+		b, ok := hives[n]
+		if !ok {
+			continue
+		}
+		d, err := parse(b)
+		if err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("hive", n).
+				Msg("unable to read registry hive")
+			continue
+		}
+		return []*claircore.Distribution{d}, nil
+	}
+	return []*claircore.Distribution{{
+		DID:        DID,
+		Name:       "Windows",
+		PrettyName: "Windows",
+	}}, nil
+}
+
+// Walk reports whether the layer is a Windows layer, along with the contents
+// of any registry hives it has, keyed by lower-cased path.
+//
+// It stops at the first entry that couldn't be in a Windows layer, so other
+// layers are rejected cheaply.
+func walk(l *claircore.Layer) (bool, map[string][]byte, error) {
+	rc, err := l.Reader()
+	if err != nil {
+		return false, nil, err
+	}
+	defer rc.Close()
+	var found bool
+	hives := make(map[string][]byte)
+	tr := tar.NewReader(rc)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		n := strings.ToLower(path.Clean("/" + h.Name))[1:]
+		switch {
+		case n == "":
+			continue
+		case n == "files" || n == "hives" || n == "utilityvm":
+		case strings.HasPrefix(n, "files/") ||
+			strings.HasPrefix(n, "hives/") ||
+			strings.HasPrefix(n, "utilityvm/"):
+		default:
+			return false, nil, nil
+		}
+		if strings.HasPrefix(n+"/", system32) {
+			found = true
+		}
+		if (n != softwareHive && n != softwareDelta) || h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if h.Size > maxHiveSize {
+			return false, nil, fmt.Errorf("windows: hive %q too large: %d bytes", h.Name, h.Size)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return false, nil, fmt.Errorf("windows: unable to read %q: %w", h.Name, err)
+		}
+		hives[n] = b
+	}
+	if err != io.EOF {
+		return false, nil, err
+	}
+	return found, hives, nil
+}
+
+// Parse reads the Windows version out of a SOFTWARE hive.
+func parse(b []byte) (*claircore.Distribution, error) {
+	h, err := regf.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	k, err := h.Open(currentKey)
+	if err != nil {
+		return nil, err
+	}
+	str := func(n string) string {
+		v, err := k.Value(n)
+		if err != nil {
+			return ""
+		}
+		s, _ := v.String()
+		return strings.TrimSpace(s)
+	}
+	num := func(n string) (uint64, bool) {
+		v, err := k.Value(n)
+		if err != nil {
+			return 0, false
+		}
+		i, err := v.Uint64()
+		return i, err == nil
+	}
+
+	build := str("CurrentBuildNumber")
+	if build == "" {
+		build = str("CurrentBuild")
+	}
+	if build == "" {
+		return nil, fmt.Errorf("windows: no build number in %q", currentKey)
+	}
+	// CurrentVersion is stuck at "6.3" for compatibility; newer releases
+	// record the real version separately.
+	ver := str("CurrentVersion")
+	if major, ok := num("CurrentMajorVersionNumber"); ok {
+		minor, _ := num("CurrentMinorVersionNumber")
+		ver = strconv.FormatUint(major, 10) + "." + strconv.FormatUint(minor, 10)
+	}
+	versionID := build
+	if ver != "" {
+		versionID = ver + "." + build
+	}
+	full := versionID
+	if ubr, ok := num("UBR"); ok {
+		full += "." + strconv.FormatUint(ubr, 10)
+	}
+
+	d := &claircore.Distribution{
+		DID:       DID,
+		Name:      str("ProductName"),
+		Version:   str("DisplayVersion"),
+		VersionID: versionID,
+	}
+	if d.Name == "" {
+		d.Name = "Windows"
+	}
+	if d.Version == "" {
+		d.Version = str("ReleaseId")
+	}
+	d.PrettyName = d.Name
+	if d.Version != "" {
+		d.PrettyName += " " + d.Version
+	}
+	d.PrettyName += " (" + full + ")"
+	return d, nil
+}
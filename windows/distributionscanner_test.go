@@ -0,0 +1,191 @@
+package windows
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// File is an entry in a test layer. A nil body makes a directory.
+type file struct {
+	name string
+	body []byte
+}
+
+// TarLayer writes the files to a layer tarball.
+func tarLayer(t *testing.T, fs []file) *claircore.Layer {
+	t.Helper()
+	f, err := ioutil.TempFile("", "windows.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	w := tar.NewWriter(f)
+	for _, e := range fs {
+		h := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     e.name,
+			Size:     int64(len(e.body)),
+			Mode:     0644,
+		}
+		if e.body == nil {
+			h.Typeflag = tar.TypeDir
+			h.Mode = 0755
+		}
+		if err := w.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+	}
+	if err := l.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// BaseLayer returns the entries of a Windows base layer with the named hive
+// fixture as its SOFTWARE hive.
+func baseLayer(t *testing.T, hive string) []file {
+	t.Helper()
+	b, err := ioutil.ReadFile("testdata/" + hive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []file{
+		{name: "Files/"},
+		{name: "Files/Windows/"},
+		{name: "Files/Windows/System32/"},
+		{name: "Files/Windows/System32/config/"},
+		{name: "Files/Windows/System32/config/SOFTWARE", body: b},
+		{name: "Files/Windows/System32/kernel32.dll", body: []byte("MZ")},
+		{name: "Hives/"},
+		{name: "UtilityVM/"},
+	}
+}
+
+func TestDistributionScanner(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name  string
+		Files func(*testing.T) []file
+		Want  []*claircore.Distribution
+	}{
+		{
+			Name:  "ltsc2016",
+			Files: func(t *testing.T) []file { return baseLayer(t, "ltsc2016.SOFTWARE") },
+			Want: []*claircore.Distribution{{
+				DID:        "windows",
+				Name:       "Windows Server 2016 Datacenter",
+				Version:    "1607",
+				VersionID:  "10.0.14393",
+				PrettyName: "Windows Server 2016 Datacenter 1607 (10.0.14393.4046)",
+			}},
+		},
+		{
+			Name:  "ltsc2019",
+			Files: func(t *testing.T) []file { return baseLayer(t, "ltsc2019.SOFTWARE") },
+			Want: []*claircore.Distribution{{
+				DID:        "windows",
+				Name:       "Windows Server 2019 Datacenter",
+				Version:    "1809",
+				VersionID:  "10.0.17763",
+				PrettyName: "Windows Server 2019 Datacenter 1809 (10.0.17763.1697)",
+			}},
+		},
+		{
+			Name:  "ltsc2022",
+			Files: func(t *testing.T) []file { return baseLayer(t, "ltsc2022.SOFTWARE") },
+			Want: []*claircore.Distribution{{
+				DID:        "windows",
+				Name:       "Windows Server 2022 Datacenter",
+				Version:    "21H2",
+				VersionID:  "10.0.20348",
+				PrettyName: "Windows Server 2022 Datacenter 21H2 (10.0.20348.707)",
+			}},
+		},
+		{
+			Name: "DeltaHive",
+			Files: func(t *testing.T) []file {
+				b, err := ioutil.ReadFile("testdata/ltsc2019.SOFTWARE")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return []file{
+					{name: "Files/Windows/System32/drivers/"},
+					{name: "Hives/Software_Delta", body: b},
+				}
+			},
+			Want: []*claircore.Distribution{{
+				DID:        "windows",
+				Name:       "Windows Server 2019 Datacenter",
+				Version:    "1809",
+				VersionID:  "10.0.17763",
+				PrettyName: "Windows Server 2019 Datacenter 1809 (10.0.17763.1697)",
+			}},
+		},
+		{
+			Name: "UnreadableHive",
+			Files: func(t *testing.T) []file {
+				return []file{
+					{name: "Files/Windows/System32/config/SOFTWARE", body: []byte("not a hive")},
+				}
+			},
+			Want: []*claircore.Distribution{{
+				DID:        "windows",
+				Name:       "Windows",
+				PrettyName: "Windows",
+			}},
+		},
+		{
+			// An application layer on top of a Windows base.
+			Name: "NoSystem32",
+			Files: func(t *testing.T) []file {
+				return []file{
+					{name: "Files/app/"},
+					{name: "Files/app/app.exe", body: []byte("MZ")},
+					{name: "Hives/Software_Delta", body: []byte("not a hive")},
+				}
+			},
+		},
+		{
+			Name: "Linux",
+			Files: func(t *testing.T) []file {
+				return []file{
+					{name: "etc/"},
+					{name: "etc/os-release", body: []byte("ID=debian\n")},
+					{name: "Files/Windows/System32/config/SOFTWARE", body: []byte("not a hive")},
+				}
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			l := tarLayer(t, tc.Files(t))
+			got, err := (&DistributionScanner{}).Scan(ctx, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}